@@ -0,0 +1,148 @@
+// Command geodata-extract builds a small geoip.dat and geosite.dat from
+// selected lists of full geodata files and custom list files, to be
+// embedded into the node with the geodata_embed build tag.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/remnawave/node-go/internal/geodata"
+)
+
+// listFlags collects repeated "CODE=path" flags.
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlags) Set(value string) error {
+	if _, _, ok := strings.Cut(value, "="); !ok {
+		return fmt.Errorf("expected CODE=path, got %q", value)
+	}
+	*l = append(*l, value)
+	return nil
+}
+
+func main() {
+	var (
+		geoipPath    string
+		geositePath  string
+		geoipCodes   string
+		geositeCodes string
+		outDir       string
+		ipLists      listFlags
+		siteLists    listFlags
+	)
+
+	flag.StringVar(&geoipPath, "geoip", "", "Source geoip.dat")
+	flag.StringVar(&geositePath, "geosite", "", "Source geosite.dat")
+	flag.StringVar(&geoipCodes, "geoip-codes", "private", "Comma-separated geoip lists to keep")
+	flag.StringVar(&geositeCodes, "geosite-codes", "private", "Comma-separated geosite lists to keep")
+	flag.Var(&ipLists, "ip-list", "Custom geoip list as CODE=path to a file of CIDRs (repeatable)")
+	flag.Var(&siteLists, "site-list", "Custom geosite list as CODE=path to a file of domain rules (repeatable)")
+	flag.StringVar(&outDir, "out", "internal/geodata/embedded", "Output directory")
+	flag.Parse()
+
+	if err := run(geoipPath, geositePath, splitCodes(geoipCodes), splitCodes(geositeCodes), ipLists, siteLists, outDir); err != nil {
+		fmt.Fprintf(os.Stderr, "geodata-extract: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(geoipPath, geositePath string, geoipCodes, geositeCodes []string, ipLists, siteLists []string, outDir string) error {
+	// Without a source file, custom lists are added to the builtin
+	// private list.
+	geoipData, _ := geodata.Embedded("geoip.dat")
+	if geoipPath != "" {
+		var err error
+		if geoipData, err = os.ReadFile(geoipPath); err != nil {
+			return err
+		}
+	}
+	geoip, err := geodata.FilterGeoIP(geoipData, geoipCodes)
+	if err != nil {
+		return err
+	}
+	for _, item := range ipLists {
+		code, path, _ := strings.Cut(item, "=")
+		lines, err := readLines(path)
+		if err != nil {
+			return err
+		}
+		entry, err := geodata.ParseCIDRs(code, lines)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		geoip.Entry = append(geoip.Entry, entry)
+	}
+
+	geositeData, _ := geodata.Embedded("geosite.dat")
+	if geositePath != "" {
+		if geositeData, err = os.ReadFile(geositePath); err != nil {
+			return err
+		}
+	}
+	geosite, err := geodata.FilterGeoSite(geositeData, geositeCodes)
+	if err != nil {
+		return err
+	}
+	for _, item := range siteLists {
+		code, path, _ := strings.Cut(item, "=")
+		lines, err := readLines(path)
+		if err != nil {
+			return err
+		}
+		entry, err := geodata.ParseDomains(code, lines)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		geosite.Entry = append(geosite.Entry, entry)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	if err := write(filepath.Join(outDir, "geoip.dat"), geoip); err != nil {
+		return err
+	}
+	if err := write(filepath.Join(outDir, "geosite.dat"), geosite); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d geoip and %d geosite lists to %s\n", len(geoip.Entry), len(geosite.Entry), outDir)
+	return nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return geodata.ReadLines(f)
+}
+
+func write(path string, list proto.Message) error {
+	data, err := proto.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func splitCodes(codes string) []string {
+	var result []string
+	for _, code := range strings.Split(codes, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			result = append(result, code)
+		}
+	}
+	return result
+}
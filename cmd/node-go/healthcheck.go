@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/config"
+)
+
+// runHealthcheck implements the healthcheck subcommand: it asks the internal
+// server of the running node for its health and returns the exit code, 0
+// when healthy and 1 otherwise, for Docker HEALTHCHECK and exec probes in
+// images without curl. The node is found through the same configuration
+// the node itself loads, without its SECRET_KEY, so probes do not fetch it
+// from a secret manager.
+func runHealthcheck(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 5*time.Second, "How long to wait for the node")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	if *configPath != "" {
+		os.Setenv("CONFIG_PATH", *configPath)
+	}
+	cfg, err := config.LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	resp, err := fetchHealthcheck(cfg, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Healthcheck failed: %v\n", err)
+		return 1
+	}
+
+	if !resp.IsHealthy {
+		for _, check := range resp.Checks {
+			if !check.Healthy && !check.Skipped && check.Error != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", check.Name, *check.Error)
+			}
+		}
+		fmt.Println("unhealthy")
+		return 1
+	}
+
+	fmt.Println("healthy")
+	return 0
+}
+
+// internalClient returns a client for the internal server, on its unix
+// socket or localhost port, and the base URL of its endpoints.
+func internalClient(cfg *config.Config, timeout time.Duration) (*http.Client, string) {
+	client := &http.Client{Timeout: timeout}
+	if cfg.InternalSocketPath == "" {
+		return client, fmt.Sprintf("http://127.0.0.1:%d", cfg.InternalRestPort)
+	}
+
+	socketPath := cfg.InternalSocketPath
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return client, "http://unix"
+}
+
+// fetchHealthcheck requests /internal/healthcheck of the internal server.
+func fetchHealthcheck(cfg *config.Config, timeout time.Duration) (*controller.HealthcheckResponse, error) {
+	client, baseURL := internalClient(cfg, timeout)
+	resp, err := client.Get(baseURL + "/internal/healthcheck")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var health controller.HealthcheckResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &health, nil
+}
@@ -1,24 +1,36 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/remnawave/node-go/internal/api"
 	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/limits"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/version"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
-var (
-	Version   = "dev"
-	BuildTime = "unknown"
-)
-
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "healthcheck":
+			os.Exit(runHealthcheck(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "secret":
+			os.Exit(runSecret(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		}
+	}
+
 	var (
 		configPath  string
 		showVersion bool
@@ -29,7 +41,7 @@ func main() {
 	flag.Parse()
 
 	if showVersion {
-		fmt.Printf("remnawave-node-go version %s (built %s)\n", Version, BuildTime)
+		fmt.Printf("remnawave-node-go version %s\n", version.String())
 		os.Exit(0)
 	}
 
@@ -42,6 +54,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
 	logLevel := logger.LevelInfo
 	switch cfg.LogLevel {
@@ -53,14 +69,60 @@ func main() {
 		logLevel = logger.LevelError
 	}
 
+	var logBuffer *logger.Buffer
+	if cfg.LogBufferSize > 0 {
+		logBuffer = logger.NewBuffer(cfg.LogBufferSize)
+	}
+
 	log := logger.New(logger.Config{
 		Level:  logLevel,
 		Format: logger.FormatJSON,
+		Buffer: logBuffer,
 	})
 
-	log.Info(fmt.Sprintf("Starting remnawave-node-go version %s", Version))
+	log.WithField("commit", version.Commit).
+		WithField("buildTime", version.BuildTime).
+		Info(fmt.Sprintf("Starting remnawave-node-go version %s", version.Version))
+
+	if cfg.GoMaxProcsAuto {
+		if err := limits.SetMaxProcs(func(format string, args ...interface{}) {
+			log.Info(fmt.Sprintf(format, args...))
+		}); err != nil {
+			log.Warn(fmt.Sprintf("Failed to set GOMAXPROCS from the CPU quota: %v", err))
+		}
+	}
+	if cfg.GoMemoryLimit != "" {
+		limit, err := limits.SetMemoryLimit(cfg.GoMemoryLimit)
+		switch {
+		case errors.Is(err, limits.ErrNoContainerLimit):
+			log.Warn(fmt.Sprintf("GO_MEMORY_LIMIT %s ignored: %v", cfg.GoMemoryLimit, err))
+		case err != nil:
+			log.Error(fmt.Sprintf("Invalid GO_MEMORY_LIMIT: %v", err))
+			os.Exit(1)
+		case limit == 0:
+			log.Info("GO_MEMORY_LIMIT ignored, GOMEMLIMIT is set")
+		default:
+			log.Info(fmt.Sprintf("Go memory limit set to %d MiB", limit>>20))
+		}
+	}
 
-	core := xray.NewCore(log)
+	var core *xray.Core
+	switch cfg.XrayMode {
+	case "embedded":
+		core = xray.NewCore(log)
+	case "external":
+		core, err = xray.NewExternalCore(log, xray.ExternalOptions{
+			Binary:  cfg.XrayBinary,
+			Version: cfg.XrayVersion,
+		})
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to set up external xray: %v", err))
+			os.Exit(1)
+		}
+	default:
+		log.Error(fmt.Sprintf("Unknown XRAY_MODE %q, expected embedded or external", cfg.XrayMode))
+		os.Exit(1)
+	}
 	configMgr := xray.NewConfigManager(log)
 
 	server, err := api.NewServer(cfg, log, core, configMgr)
@@ -75,14 +137,95 @@ func main() {
 	}
 
 	log.Info(fmt.Sprintf("Main HTTPS server listening on :%d", cfg.NodePort))
-	log.Info(fmt.Sprintf("Internal HTTP server listening on 127.0.0.1:%d", cfg.InternalRestPort))
+	if cfg.InternalSocketPath != "" {
+		log.Info(fmt.Sprintf("Internal HTTP server listening on %s", cfg.InternalSocketPath))
+	} else {
+		log.Info(fmt.Sprintf("Internal HTTP server listening on 127.0.0.1:%d", cfg.InternalRestPort))
+	}
+
+	// SIGHUP reloads the node certificate, CA and JWT keys, and applies the
+	// settings that can change at runtime.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := server.ReloadCredentials(); err != nil {
+				log.Error(fmt.Sprintf("Failed to reload credentials: %v", err))
+			}
+			if _, err := server.ReloadConfig(); err != nil {
+				log.Error(fmt.Sprintf("Failed to reload configuration: %v", err))
+			}
+		}
+	}()
+
+	// SIGUSR1 switches to debug logging, and back to LOG_LEVEL the next
+	// time. /internal/set-log-level changes the level and format as well.
+	toggle := make(chan os.Signal, 1)
+	notifyToggleDebug(toggle)
+	go func() {
+		for range toggle {
+			level := logger.LevelDebug
+			if log.Level() == logger.LevelDebug {
+				level = server.LogLevel()
+				if level == logger.LevelDebug {
+					level = logger.LevelInfo
+				}
+			}
+			log.SetLevel(level)
+			log.WithField("level", string(level)).Warn("Log level changed by SIGUSR1")
+		}
+	}()
+
+	// SIGUSR2 hands the node over to a new process of its binary, e.g.
+	// after it was replaced by a newer version, then stops this one. With
+	// a maintenance window, the upgrade waits for it.
+	upgraded := make(chan struct{})
+	if cfg.GracefulUpgrade {
+		upgrade := make(chan os.Signal, 1)
+		notifyUpgrade(upgrade)
+		go func() {
+			for range upgrade {
+				server.Defer("upgrade", func() {
+					select {
+					case <-upgraded:
+						return
+					default:
+					}
+					log.Info("Upgrading: starting a new process")
+					pid, err := server.Upgrade()
+					if err != nil {
+						log.Error(fmt.Sprintf("Failed to upgrade: %v", err))
+						return
+					}
+					log.Info(fmt.Sprintf("Process %d took over", pid))
+					close(upgraded)
+				})
+			}
+		}()
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case <-upgraded:
+		// The new process takes the new connections; give the ones this
+		// process still serves time to finish.
+		log.Info("Draining xray connections...")
+		select {
+		case <-quit:
+		case <-time.After(api.UpgradeDrainTimeout):
+		}
+	}
 
 	log.Info("Shutting down servers...")
 
+	// Stop the server first so it can checkpoint traffic counters
+	// while the xray core is still running.
+	if err := server.Stop(); err != nil {
+		log.Error(fmt.Sprintf("Failed to stop server: %v", err))
+	}
+
 	if core.IsRunning() {
 		log.Info("Stopping xray core...")
 		if err := core.Stop(); err != nil {
@@ -90,9 +233,5 @@ func main() {
 		}
 	}
 
-	if err := server.Stop(); err != nil {
-		log.Error(fmt.Sprintf("Failed to stop server: %v", err))
-	}
-
 	log.Info("Servers stopped gracefully")
 }
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/provision"
+)
+
+const secretUsage = `Usage:
+  remnawave-node-go secret gen [-out DIR] [-cn NAME] [-host HOST,...] [-days N]
+  remnawave-node-go secret inspect [-json] [-file PATH | SECRET_KEY | -]`
+
+// runSecret implements the secret subcommand: gen creates the credentials
+// of a node for setups without a panel issuing them, inspect decodes a
+// SECRET_KEY to debug why the panel cannot connect.
+func runSecret(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, secretUsage)
+		return 2
+	}
+	switch args[0] {
+	case "gen":
+		return runSecretGen(args[1:])
+	case "inspect":
+		return runSecretInspect(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown secret command %q\n%s\n", args[0], secretUsage)
+		return 2
+	}
+}
+
+// runSecretGen prints a new SECRET_KEY and, with -out, writes it and the
+// panel side of the credentials to a directory.
+func runSecretGen(args []string) int {
+	fs := flag.NewFlagSet("secret gen", flag.ContinueOnError)
+	out := fs.String("out", "", "Directory to write the CA, panel client certificate and JWT keys to")
+	cn := fs.String("cn", provision.DefaultNodeName, "Common name of the node certificate")
+	hosts := fs.String("host", "", "Comma-separated IPs and DNS names of the node")
+	days := fs.Int("days", int(provision.DefaultValidity/(24*time.Hour)), "Validity of the certificates in days")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *days <= 0 {
+		fmt.Fprintln(os.Stderr, "-days must be positive")
+		return 2
+	}
+
+	opts := provision.Options{
+		NodeName: *cn,
+		Validity: time.Duration(*days) * 24 * time.Hour,
+	}
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			opts.Hosts = append(opts.Hosts, host)
+		}
+	}
+
+	bundle, err := provision.Generate(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate credentials: %v\n", err)
+		return 1
+	}
+
+	if *out != "" {
+		if err := writeBundle(*out, bundle); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write credentials: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Wrote credentials to %s\n", *out)
+	}
+
+	fmt.Println(bundle.SecretKey)
+	return 0
+}
+
+// writeBundle writes the generated files to dir, private keys readable by
+// the owner only.
+func writeBundle(dir string, bundle *provision.Bundle) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{"ca.pem", bundle.CACertPEM, 0o644},
+		{"ca.key", bundle.CAKeyPEM, 0o600},
+		{"client.pem", bundle.ClientCertPEM, 0o644},
+		{"client.key", bundle.ClientKeyPEM, 0o600},
+		{"jwt.key", bundle.JWTPrivatePEM, 0o600},
+		{"jwt.pub", bundle.JWTPublicPEM, 0o644},
+		{"secret_key", []byte(bundle.SecretKey + "\n"), 0o600},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSecretInspect prints the certificates and keys of a SECRET_KEY taken
+// from the argument, stdin ("-"), -file, or SECRET_KEY / SECRET_KEY_FILE
+// as the node would. It returns 1 if the node could not use it.
+func runSecretInspect(args []string) int {
+	fs := flag.NewFlagSet("secret inspect", flag.ContinueOnError)
+	file := fs.String("file", "", "Read the SECRET_KEY from a file")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	secretKey, err := readSecretKey(fs.Arg(0), *file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	payload, err := config.ParseSecretKey(secretKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid SECRET_KEY: %v\n", err)
+		return 1
+	}
+
+	report := provision.Inspect(payload, time.Now())
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(os.Stdout, report)
+	}
+
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+func readSecretKey(arg, file string) (string, error) {
+	switch {
+	case arg == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case arg != "":
+		return arg, nil
+	}
+
+	if file == "" {
+		if v := os.Getenv("SECRET_KEY"); v != "" {
+			return v, nil
+		}
+		file = os.Getenv("SECRET_KEY_FILE")
+	}
+	if file == "" {
+		return "", config.ErrConfigSecretKeyRequired
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret key file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func printReport(w io.Writer, report *provision.Report) {
+	printCerts := func(title string, certs []provision.CertInfo) {
+		for _, cert := range certs {
+			fmt.Fprintf(w, "%s:\n", title)
+			fmt.Fprintf(w, "  Subject:    %s\n", cert.Subject)
+			fmt.Fprintf(w, "  Issuer:     %s\n", cert.Issuer)
+			fmt.Fprintf(w, "  Not before: %s\n", cert.NotBefore.Format(time.RFC3339))
+			status := fmt.Sprintf("%d days left", cert.DaysLeft)
+			if cert.Expired {
+				status = "expired"
+			}
+			fmt.Fprintf(w, "  Not after:  %s (%s)\n", cert.NotAfter.Format(time.RFC3339), status)
+			if names := append(append([]string{}, cert.DNSNames...), cert.IPs...); len(names) > 0 {
+				fmt.Fprintf(w, "  Hosts:      %s\n", strings.Join(names, ", "))
+			}
+		}
+	}
+	printCerts("CA certificate", report.CA)
+	printCerts("Node certificate", report.Node)
+	for _, key := range report.JWTKeys {
+		fmt.Fprintf(w, "JWT public key: %s\n", key)
+	}
+	printCerts("Trusted CA certificate", report.TrustedCA)
+	for _, key := range report.TrustedJWTKeys {
+		fmt.Fprintf(w, "Trusted JWT public key: %s\n", key)
+	}
+
+	if report.OK() {
+		fmt.Fprintln(w, "OK")
+		return
+	}
+	fmt.Fprintln(w, "Problems:")
+	for _, problem := range report.Problems {
+		fmt.Fprintf(w, "  - %s\n", problem)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyToggleDebug does nothing: Windows has no SIGUSR1. Use
+// /internal/set-log-level instead.
+func notifyToggleDebug(chan<- os.Signal) {}
+
+// notifyUpgrade does nothing: graceful upgrades need Unix signals and
+// descriptor passing.
+func notifyUpgrade(chan<- os.Signal) {}
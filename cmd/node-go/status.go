@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/config"
+)
+
+// runStatus implements the status subcommand: it prints the xray state,
+// versions, uptime, users per inbound and recent errors of the running
+// node, read from its internal server, so an operator on the host needs no
+// panel JWT. Only the internal server settings of the configuration are
+// used; no SECRET_KEY is needed.
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	timeout := fs.Duration("timeout", 5*time.Second, "How long to wait for the node")
+	asJSON := fs.Bool("json", false, "Print the status as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *configPath != "" {
+		os.Setenv("CONFIG_PATH", *configPath)
+	}
+	cfg, err := config.LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	status, raw, err := fetchStatus(cfg, *timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to get node status: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		os.Stdout.Write(raw)
+		fmt.Println()
+	} else {
+		printStatus(os.Stdout, status)
+	}
+	return 0
+}
+
+// fetchStatus requests /internal/status of the internal server and returns
+// it decoded and as received.
+func fetchStatus(cfg *config.Config, timeout time.Duration) (*controller.NodeStatusResponse, []byte, error) {
+	client, baseURL := internalClient(cfg, timeout)
+	resp, err := client.Get(baseURL + "/internal/status")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var status controller.NodeStatusResponse
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &status, body, nil
+}
+
+func printStatus(w io.Writer, status *controller.NodeStatusResponse) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	xrayState := "stopped"
+	if status.IsXrayRunning {
+		xrayState = "running"
+		if status.XrayVersion != nil {
+			xrayState += " (" + *status.XrayVersion + ")"
+		}
+	}
+	fmt.Fprintf(tw, "Node version:\t%s\n", status.NodeVersion)
+	fmt.Fprintf(tw, "Uptime:\t%s\n", time.Duration(status.Uptime)*time.Second)
+	fmt.Fprintf(tw, "Xray:\t%s\n", xrayState)
+	tw.Flush()
+
+	if len(status.Inbounds) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(tw, "INBOUND\tUSERS")
+		total := 0
+		for _, inbound := range status.Inbounds {
+			fmt.Fprintf(tw, "%s\t%d\n", inbound.Tag, inbound.Users)
+			total += inbound.Users
+		}
+		fmt.Fprintf(tw, "total\t%d\n", total)
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w)
+	if len(status.RecentErrors) == 0 {
+		fmt.Fprintln(w, "No recent errors")
+		return
+	}
+	fmt.Fprintln(w, "Recent errors:")
+	for _, line := range status.RecentErrors {
+		fmt.Fprintf(w, "  %s\n", formatLogLine(line))
+	}
+}
+
+// formatLogLine renders a JSON log line as "time message: error", or as
+// is when it has no message.
+func formatLogLine(line json.RawMessage) string {
+	var fields struct {
+		Time    string `json:"time"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(line, &fields) != nil || fields.Message == "" {
+		return string(line)
+	}
+
+	text := fields.Message
+	if fields.Error != "" {
+		text += ": " + fields.Error
+	}
+	if fields.Time != "" {
+		text = fields.Time + " " + text
+	}
+	return text
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// runValidate implements the validate subcommand: it checks an xray config
+// file the way /node/xray/start would load it, with placeholders resolved
+// and the api inbound injected, and returns 0 when it is valid and 1
+// otherwise. Placeholders and the api port come from the same
+// configuration the node loads; no SECRET_KEY is needed.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	file := fs.String("f", "", "Xray config file to validate, - for stdin")
+	configPath := fs.String("config", "", "Path to configuration file")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "Usage: remnawave-node-go validate -f config.json [-config PATH] [-json]")
+		return 2
+	}
+
+	if *configPath != "" {
+		os.Setenv("CONFIG_PATH", *configPath)
+	}
+	cfg, err := config.LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+	placeholders, err := xray.ParsePlaceholders(cfg.ConfigVars, cfg.ConfigEnvAllowlist)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid config variables: %v\n", err)
+		return 1
+	}
+
+	data, err := readConfigFile(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", *file, err)
+		return 1
+	}
+
+	var configErrors []xray.ConfigError
+	var xrayConfig map[string]interface{}
+	if err := json.Unmarshal(data, &xrayConfig); err != nil {
+		configErrors = []xray.ConfigError{{Section: "json", Message: err.Error()}}
+	} else {
+		configErrors, err = controller.CheckPanelConfig(xrayConfig, placeholders, cfg.APIPort)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to serialize config: %v\n", err)
+			return 1
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(controller.ValidateResponse{
+			Valid:  len(configErrors) == 0,
+			Errors: configErrors,
+		})
+	} else {
+		for _, e := range configErrors {
+			section := e.Section
+			if e.Tag != "" {
+				section += " (" + e.Tag + ")"
+			}
+			fmt.Printf("%s: %s\n", section, e.Message)
+		}
+		if len(configErrors) == 0 {
+			fmt.Println("valid")
+		}
+	}
+
+	if len(configErrors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readConfigFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
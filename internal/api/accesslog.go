@@ -0,0 +1,107 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Access log modes of config.AccessLog.
+	AccessLogOff    = "off"
+	AccessLogErrors = "errors"
+	AccessLogAll    = "all"
+
+	requestIDHeader = "X-Request-Id"
+	maxRequestIDLen = 128
+)
+
+// statsPathPrefixes are the endpoints polled by the panel and monitoring,
+// left out of the access log when AccessLogSkipStats is set.
+var statsPathPrefixes = []string{"/node/stats/", "/internal/stats", "/metrics"}
+
+// loggingMiddleware assigns every request an ID, taken from X-Request-Id
+// when the client sends a usable one, and logs the request once it is
+// served: all of them, only those that failed (status 400 and above), or
+// none, depending on AccessLog.
+func (s *Server) loggingMiddleware() gin.HandlerFunc {
+	mode := s.config.AccessLog
+	skipStats := s.config.AccessLogSkipStats
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := sanitizeRequestID(c.GetHeader(requestIDHeader))
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Set("request_id", requestID)
+		c.Header(requestIDHeader, requestID)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		path := c.Request.URL.Path
+		switch {
+		case mode == AccessLogOff:
+			return
+		case mode == AccessLogErrors && status < http.StatusBadRequest:
+			return
+		case skipStats && status < http.StatusBadRequest && isStatsPath(path):
+			return
+		}
+
+		log := s.logger.WithField("requestId", requestID).
+			WithField("method", c.Request.Method).
+			WithField("path", path).
+			WithField("status", status).
+			WithField("latencyMs", float64(time.Since(start).Microseconds())/1000).
+			WithField("bytes", max(c.Writer.Size(), 0)).
+			WithField("ip", c.ClientIP())
+		if tls := c.Request.TLS; tls != nil && len(tls.PeerCertificates) > 0 {
+			log = log.WithField("clientCn", tls.PeerCertificates[0].Subject.CommonName)
+		}
+
+		switch {
+		case status >= http.StatusInternalServerError:
+			log.Error("HTTP request")
+		case status >= http.StatusBadRequest:
+			log.Warn("HTTP request")
+		default:
+			log.Info("HTTP request")
+		}
+	}
+}
+
+func isStatsPath(path string) bool {
+	for _, prefix := range statsPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeRequestID returns id if it is short and made of printable ASCII
+// without spaces, so it is safe to log and echo back, and "" otherwise.
+func sanitizeRequestID(id string) string {
+	if len(id) > maxRequestIDLen {
+		return ""
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return ""
+		}
+	}
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type accessLogEntry struct {
+	Level     string  `json:"level"`
+	Message   string  `json:"message"`
+	RequestID string  `json:"requestId"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Bytes     int     `json:"bytes"`
+}
+
+func accessLogRouter(cfg *config.Config, buf *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	server := &Server{
+		config: cfg,
+		logger: logger.New(logger.Config{Level: logger.LevelDebug, Format: logger.FormatJSON, Output: buf}),
+	}
+	router := gin.New()
+	router.Use(server.loggingMiddleware())
+	router.GET("/node/xray/status", func(c *gin.Context) { c.String(http.StatusOK, "running") })
+	router.GET("/node/stats/system", func(c *gin.Context) { c.String(http.StatusOK, "{}") })
+	router.GET("/node/stats/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	router.POST("/node/handler/add-user", func(c *gin.Context) { c.Status(http.StatusBadRequest) })
+	return router
+}
+
+func readAccessLog(t *testing.T, buf *bytes.Buffer) []accessLogEntry {
+	t.Helper()
+
+	var entries []accessLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry accessLogEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
+
+func serve(router *gin.Engine, method, path, requestID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	if requestID != "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoggingMiddleware_All(t *testing.T) {
+	var buf bytes.Buffer
+	router := accessLogRouter(&config.Config{AccessLog: AccessLogAll, AccessLogSkipStats: true}, &buf)
+
+	w := serve(router, "GET", "/node/xray/status", "panel-42")
+	assert.Equal(t, "panel-42", w.Header().Get("X-Request-Id"))
+
+	entries := readAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, accessLogEntry{
+		Level:     "info",
+		Message:   "HTTP request",
+		RequestID: "panel-42",
+		Method:    "GET",
+		Path:      "/node/xray/status",
+		Status:    http.StatusOK,
+		LatencyMs: entries[0].LatencyMs,
+		Bytes:     len("running"),
+	}, entries[0])
+
+	// Successful stats polling is skipped, failures are not.
+	serve(router, "GET", "/node/stats/system", "")
+	assert.Empty(t, readAccessLog(t, &buf))
+	serve(router, "GET", "/node/stats/fail", "")
+	entries = readAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "error", entries[0].Level)
+
+	serve(router, "POST", "/node/handler/add-user", "")
+	entries = readAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "warn", entries[0].Level)
+}
+
+func TestLoggingMiddleware_Modes(t *testing.T) {
+	var buf bytes.Buffer
+	router := accessLogRouter(&config.Config{AccessLog: AccessLogErrors}, &buf)
+	serve(router, "GET", "/node/xray/status", "")
+	serve(router, "POST", "/node/handler/add-user", "")
+	entries := readAccessLog(t, &buf)
+	require.Len(t, entries, 1)
+	assert.Equal(t, http.StatusBadRequest, entries[0].Status)
+
+	router = accessLogRouter(&config.Config{AccessLog: AccessLogOff}, &buf)
+	w := serve(router, "GET", "/node/stats/fail", "")
+	assert.Empty(t, readAccessLog(t, &buf))
+	assert.Len(t, w.Header().Get("X-Request-Id"), 32)
+
+	router = accessLogRouter(&config.Config{AccessLog: AccessLogAll}, &buf)
+	serve(router, "GET", "/node/stats/system", "")
+	assert.Len(t, readAccessLog(t, &buf), 1)
+}
+
+func TestSanitizeRequestID(t *testing.T) {
+	assert.Equal(t, "abc-123_x.y", sanitizeRequestID("abc-123_x.y"))
+	assert.Empty(t, sanitizeRequestID("with space"))
+	assert.Empty(t, sanitizeRequestID("line\nbreak"))
+	assert.Empty(t, sanitizeRequestID(strings.Repeat("a", 129)))
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pires/go-proxyproto"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// ipAllowlist holds the networks allowed to reach the panel-facing
+// listeners. An empty list allows every address.
+type ipAllowlist []*net.IPNet
+
+// parseIPAllowlist parses a comma-separated list of IPs and CIDR ranges.
+func parseIPAllowlist(value string) (ipAllowlist, error) {
+	var list ipAllowlist
+	for _, source := range strings.Split(value, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+
+		if strings.Contains(source, "/") {
+			_, ipNet, err := net.ParseCIDR(source)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q", source)
+			}
+			list = append(list, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(source)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", source)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		list = append(list, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+	}
+	return list, nil
+}
+
+// networks returns the list in the CIDR notation.
+func (l ipAllowlist) networks() []string {
+	networks := make([]string, 0, len(l))
+	for _, ipNet := range l {
+		networks = append(networks, ipNet.String())
+	}
+	return networks
+}
+
+func (l ipAllowlist) allows(addr net.Addr) bool {
+	if len(l) == 0 {
+		return true
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, ipNet := range l {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowlistListener closes connections from addresses outside the
+// allowlist as soon as they are accepted, before the TLS handshake. The
+// allowlist is read per connection, so it can be replaced at runtime.
+type allowlistListener struct {
+	net.Listener
+	allowlist *atomic.Pointer[ipAllowlist]
+	log       *logger.Logger
+}
+
+func (l *allowlistListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.allowlist.Load().allows(conn.RemoteAddr()) {
+			return conn, nil
+		}
+
+		l.log.WithField("ip", conn.RemoteAddr().String()).Debug("Dropped connection from address outside the panel allowlist")
+		conn.Close()
+	}
+}
+
+// proxyPolicy uses the PROXY protocol header of connections from trusted
+// proxies and rejects the connections of anyone else sending one.
+func (l ipAllowlist) proxyPolicy(opts proxyproto.ConnPolicyOptions) (proxyproto.Policy, error) {
+	if len(l) > 0 && l.allows(opts.Upstream) {
+		return proxyproto.USE, nil
+	}
+	return proxyproto.REJECT, nil
+}
+
+// listenPanel opens a TCP listener for panel traffic, filtered by the
+// panel allowlist. With PROXY_PROTOCOL the allowlist applies to the load
+// balancer, as the client address is only known once the header is read.
+func (s *Server) listenPanel(name, addr string) (net.Listener, error) {
+	listener, err := s.listen(name, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	listener = &allowlistListener{Listener: listener, allowlist: &s.panelAllowlist, log: s.logger}
+	if s.config.ProxyProtocol {
+		listener = &proxyproto.Listener{Listener: listener, ConnPolicy: s.trustedProxies.proxyPolicy}
+	}
+	return listener, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestParseIPAllowlist(t *testing.T) {
+	list, err := parseIPAllowlist(" 203.0.113.10, 198.51.100.0/24,2001:db8::1,, ")
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	tcp := func(ip string) net.Addr { return &net.TCPAddr{IP: net.ParseIP(ip), Port: 443} }
+	assert.True(t, list.allows(tcp("203.0.113.10")))
+	assert.True(t, list.allows(tcp("::ffff:203.0.113.10")))
+	assert.False(t, list.allows(tcp("203.0.113.11")))
+	assert.True(t, list.allows(tcp("198.51.100.77")))
+	assert.True(t, list.allows(tcp("2001:db8::1")))
+	assert.False(t, list.allows(tcp("2001:db8::2")))
+
+	empty, err := parseIPAllowlist("")
+	require.NoError(t, err)
+	assert.True(t, empty.allows(tcp("192.0.2.1")))
+
+	_, err = parseIPAllowlist("203.0.113.10,not-an-ip")
+	assert.Error(t, err)
+	_, err = parseIPAllowlist("198.51.100.0/33")
+	assert.Error(t, err)
+}
+
+func TestAllowlistListener(t *testing.T) {
+	accept := func(t *testing.T, allowed string) (net.Listener, net.Conn) {
+		allowlist, err := parseIPAllowlist(allowed)
+		require.NoError(t, err)
+
+		server := &Server{
+			config: &config.Config{},
+			logger: logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}),
+		}
+		server.panelAllowlist.Store(&allowlist)
+		listener, err := server.listenPanel("main", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return listener, conn
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		listener, _ := accept(t, "127.0.0.0/8")
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		listener, conn := accept(t, "203.0.113.10")
+		accepted := make(chan error, 1)
+		go func() {
+			_, err := listener.Accept()
+			accepted <- err
+		}()
+
+		// The connection is closed by the listener, not handed out.
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err := conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF)
+
+		listener.Close()
+		assert.Error(t, <-accepted)
+	})
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	accept := func(t *testing.T, trusted string) net.Conn {
+		proxies, err := parseIPAllowlist(trusted)
+		require.NoError(t, err)
+
+		server := &Server{
+			config:         &config.Config{TrustedProxies: trusted, ProxyProtocol: true},
+			logger:         logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}),
+			trustedProxies: proxies,
+		}
+		server.panelAllowlist.Store(&ipAllowlist{})
+		listener, err := server.listenPanel("main", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { listener.Close() })
+
+		client, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+		_, err = client.Write([]byte("PROXY TCP4 203.0.113.7 127.0.0.1 50000 443\r\nping"))
+		require.NoError(t, err)
+
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return conn
+	}
+
+	t.Run("trusted", func(t *testing.T) {
+		conn := accept(t, "127.0.0.1")
+		assert.Equal(t, "203.0.113.7:50000", conn.RemoteAddr().String())
+		data := make([]byte, 4)
+		_, err := io.ReadFull(conn, data)
+		require.NoError(t, err)
+		assert.Equal(t, "ping", string(data))
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		conn := accept(t, "10.0.0.0/8")
+		_, err := conn.Read(make([]byte, 4))
+		assert.Error(t, err, "PROXY header from an untrusted address")
+	})
+}
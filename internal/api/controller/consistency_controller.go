@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/consistency"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type CheckConsistencyRequest struct {
+	Repair bool `json:"repair"`
+}
+
+type CheckConsistencyResponse struct {
+	Report consistency.Report `json:"report"`
+	Error  *string            `json:"error"`
+}
+
+type ConsistencyController struct {
+	checker *consistency.Checker
+	logger  *logger.Logger
+}
+
+func NewConsistencyController(checker *consistency.Checker, log *logger.Logger) *ConsistencyController {
+	return &ConsistencyController{
+		checker: checker,
+		logger:  log,
+	}
+}
+
+func (c *ConsistencyController) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/check-consistency", c.handleCheckConsistency)
+}
+
+func (c *ConsistencyController) handleCheckConsistency(ctx *gin.Context) {
+	var req CheckConsistencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		req.Repair = false
+	}
+
+	report, err := c.checker.Check(req.Repair)
+	if err != nil {
+		errMsg := "consistency check failed: " + err.Error()
+		status := http.StatusInternalServerError
+		if errors.Is(err, consistency.ErrCoreNotRunning) {
+			status = http.StatusServiceUnavailable
+		}
+		ctx.JSON(status, wrapResponse(CheckConsistencyResponse{Error: &errMsg}))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(CheckConsistencyResponse{Report: report}))
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/eventlog"
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	trafficSnapshotInterval = 10 * time.Second
+	eventsKeepAliveInterval = 30 * time.Second
+)
+
+// EventsController streams node events to the panel as Server-Sent Events,
+// so it does not have to poll the stats endpoints.
+type EventsController struct {
+	bus      *events.Bus
+	eventLog *eventlog.Log
+	stats    *StatsController
+	logger   *logger.Logger
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewEventsController(bus *events.Bus, eventLog *eventlog.Log, stats *StatsController, log *logger.Logger) *EventsController {
+	return &EventsController{
+		bus:      bus,
+		eventLog: eventLog,
+		stats:    stats,
+		logger:   log,
+	}
+}
+
+func (c *EventsController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/events", c.handleEvents)
+	group.GET("/events/history", c.handleGetHistory)
+}
+
+// Start launches the goroutine publishing periodic traffic snapshots.
+// Snapshots are only collected while someone is subscribed.
+func (c *EventsController) Start() {
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stopCh = make(chan struct{})
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(trafficSnapshotInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				c.PublishTrafficSnapshot()
+			}
+		}
+	}()
+}
+
+// Stop terminates the snapshot goroutine.
+func (c *EventsController) Stop() {
+	c.mu.Lock()
+	stopCh := c.stopCh
+	c.stopCh = nil
+	c.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		c.wg.Wait()
+	}
+}
+
+// PublishTrafficSnapshot publishes the current user traffic counters without
+// resetting them, so polling /node/stats/get-users-stats keeps working.
+func (c *EventsController) PublishTrafficSnapshot() {
+	if c.bus.SubscriberCount() == 0 {
+		return
+	}
+
+	c.bus.Publish(events.TypeTrafficSnapshot, c.stats.UsersStats(false))
+}
+
+func (c *EventsController) handleEvents(ctx *gin.Context) {
+	ch, unsubscribe := c.bus.Subscribe()
+	defer unsubscribe()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	c.logger.WithField("ip", ctx.ClientIP()).Info("Events subscriber connected")
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	// The stream outlives the server read and write timeouts.
+	rc := http.NewResponseController(ctx.Writer)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	// Flush headers right away so the client knows the stream is open.
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+
+	c.logger.WithField("ip", ctx.ClientIP()).Info("Events subscriber disconnected")
+}
+
+type EventHistoryResponse struct {
+	Events []events.Event `json:"events"`
+}
+
+// handleGetHistory returns the recorded events after the optional since
+// query parameter (unix seconds), of the optional type, oldest first.
+func (c *EventsController) handleGetHistory(ctx *gin.Context) {
+	since, err := parseUnixQuery(ctx, "since")
+	if err != nil {
+		errMsg := "invalid since: " + err.Error()
+		ctx.JSON(http.StatusBadRequest, wrapResponse(struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg}))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(EventHistoryResponse{
+		Events: c.eventLog.Entries(since, ctx.Query("type")),
+	}))
+}
@@ -3,12 +3,26 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xtls/xray-core/features/inbound"
-
+	"github.com/xtls/xray-core/features/stats"
+	"go.opentelemetry.io/otel/attribute"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/eventlog"
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/expiry"
+	"github.com/remnawave/node-go/internal/iplimit"
+	"github.com/remnawave/node-go/internal/jobs"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/telemetry"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
@@ -31,6 +45,8 @@ type AddUserHashData struct {
 type AddUserRequest struct {
 	Data     []AddUserInboundData `json:"data" binding:"required,dive"`
 	HashData AddUserHashData      `json:"hashData"`
+	IPLimit  int                  `json:"ipLimit" binding:"omitempty,min=0"`
+	ExpireAt *time.Time           `json:"expireAt,omitempty"`
 }
 
 type AddUserResponseData struct {
@@ -38,12 +54,32 @@ type AddUserResponseData struct {
 	Error   *string `json:"error"`
 }
 
+// BulkUserResult is the outcome of a bulk operation for one user, or for
+// one user in one inbound when Inbound is set.
+type BulkUserResult struct {
+	Username string  `json:"username"`
+	Inbound  string  `json:"inbound,omitempty"`
+	Success  bool    `json:"success"`
+	Error    *string `json:"error"`
+}
+
+// BulkUsersResponseData reports a bulk operation. Success is false if any
+// of the results failed, so the panel can retry only those.
+type BulkUsersResponseData struct {
+	Success bool             `json:"success"`
+	Error   *string          `json:"error"`
+	Results []BulkUserResult `json:"results"`
+}
+
 type BulkUserData struct {
-	UserID         string `json:"userId" binding:"required"`
-	HashUUID       string `json:"hashUuid,omitempty"`
-	VlessUUID      string `json:"vlessUuid,omitempty"`
-	TrojanPassword string `json:"trojanPassword,omitempty"`
-	SSPassword     string `json:"ssPassword,omitempty"`
+	UserID         string     `json:"userId" binding:"required"`
+	HashUUID       string     `json:"hashUuid,omitempty"`
+	VlessUUID      string     `json:"vlessUuid,omitempty"`
+	VmessUUID      string     `json:"vmessUuid,omitempty"`
+	TrojanPassword string     `json:"trojanPassword,omitempty"`
+	SSPassword     string     `json:"ssPassword,omitempty"`
+	IPLimit        int        `json:"ipLimit" binding:"omitempty,min=0"`
+	ExpireAt       *time.Time `json:"expireAt,omitempty"`
 }
 
 type BulkInboundData struct {
@@ -82,6 +118,36 @@ type RemoveUsersRequest struct {
 	Users []BulkRemoveUserEntry `json:"users" binding:"required,dive"`
 }
 
+// SyncUsersRequest is the full set of users the node should have.
+type SyncUsersRequest struct {
+	AffectedInboundTags []string        `json:"affectedInboundTags"`
+	Users               []BulkUserEntry `json:"users" binding:"required,dive"`
+}
+
+// SyncUsersResponseData reports a sync. Results cover only the users that
+// were added or removed.
+type SyncUsersResponseData struct {
+	Success   bool             `json:"success"`
+	Error     *string          `json:"error"`
+	Added     int              `json:"added"`
+	Removed   int              `json:"removed"`
+	Unchanged int              `json:"unchanged"`
+	Results   []BulkUserResult `json:"results"`
+}
+
+type GetUserRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type GetUserResponseData struct {
+	Username string             `json:"username"`
+	Inbounds []xray.UserInbound `json:"inbounds"`
+	Uplink   int64              `json:"uplink"`
+	Downlink int64              `json:"downlink"`
+	IPLimit  int                `json:"ipLimit"`
+	ExpireAt *time.Time         `json:"expireAt"`
+}
+
 type GetInboundUsersRequest struct {
 	Tag string `json:"tag" binding:"required"`
 }
@@ -98,16 +164,33 @@ type GetInboundUsersCountResponseData struct {
 	Count int `json:"count"`
 }
 
+// bulkQueueSize is the per-worker backlog of bulk user operations.
+const bulkQueueSize = 64
+
 type HandlerController struct {
 	core          *xray.Core
 	configManager *xray.ConfigManager
+	ipLimiter     *iplimit.Limiter
+	expiry        *expiry.Scheduler
+	events        *events.Bus
+	eventLog      *eventlog.Log
+	jobs          *jobs.Manager
+	bulkWorkers   int
 	logger        *logger.Logger
 }
 
-func NewHandlerController(core *xray.Core, configManager *xray.ConfigManager, log *logger.Logger) *HandlerController {
+// NewHandlerController creates the handler controller. eventLog records
+// the user batches and may be nil.
+func NewHandlerController(core *xray.Core, configManager *xray.ConfigManager, ipLimiter *iplimit.Limiter, expiryScheduler *expiry.Scheduler, eventBus *events.Bus, eventLog *eventlog.Log, jobManager *jobs.Manager, bulkWorkers int, log *logger.Logger) *HandlerController {
 	return &HandlerController{
 		core:          core,
 		configManager: configManager,
+		ipLimiter:     ipLimiter,
+		expiry:        expiryScheduler,
+		events:        eventBus,
+		eventLog:      eventLog,
+		jobs:          jobManager,
+		bulkWorkers:   bulkWorkers,
 		logger:        log,
 	}
 }
@@ -117,6 +200,8 @@ func (c *HandlerController) RegisterRoutes(group *gin.RouterGroup) {
 	group.POST("/add-users", c.handleAddUsers)
 	group.POST("/remove-user", c.handleRemoveUser)
 	group.POST("/remove-users", c.handleRemoveUsers)
+	group.POST("/sync-users", c.handleSyncUsers)
+	group.POST("/get-user", c.handleGetUser)
 	group.POST("/get-inbound-users", c.handleGetInboundUsers)
 	group.POST("/get-inbound-users-count", c.handleGetInboundUsersCount)
 }
@@ -144,32 +229,50 @@ func (c *HandlerController) handleAddUser(ctx *gin.Context) {
 	var req AddUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse add-user request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(AddUserResponseData{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, AddUserResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
+	_, end := telemetry.Track(ctx.Request.Context(), "user.add")
+	resp, status := c.AddUser(req)
+	end(status)
+	respond(ctx, status, apperrors.CodeAddUserError, resp.Error, resp)
+}
+
+// AddUser adds a user to the inbounds listed in the request, replacing any
+// previous entries for the same username.
+func (c *HandlerController) AddUser(req AddUserRequest) (AddUserResponseData, int) {
 	if len(req.Data) == 0 {
 		errMsg := "no inbound data provided"
-		ctx.JSON(http.StatusBadRequest, wrapResponse(AddUserResponseData{
+		return AddUserResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
-		return
+		}, http.StatusBadRequest
+	}
+
+	for _, inboundData := range req.Data {
+		inbound, userData := addUserInbound(inboundData)
+		if err := xray.ValidateUserForInbound(inbound, userData); err != nil {
+			errMsg := err.Error()
+			return AddUserResponseData{
+				Success: false,
+				Error:   &errMsg,
+			}, http.StatusBadRequest
+		}
 	}
 
 	userManager, err := c.getUserManager()
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get user manager")
 		errMsg := "xray core not available: " + err.Error()
-		ctx.JSON(http.StatusServiceUnavailable, wrapResponse(AddUserResponseData{
+		return AddUserResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
-		return
+		}, http.StatusServiceUnavailable
 	}
 
 	username := req.Data[0].Username
@@ -192,44 +295,17 @@ func (c *HandlerController) handleAddUser(ctx *gin.Context) {
 	}
 
 	for _, inboundData := range req.Data {
-		userData := xray.UserData{
-			UserID:    inboundData.Username,
-			VlessUUID: inboundData.UUID,
-		}
-
-		if inboundData.Type == "trojan" {
-			userData.TrojanPassword = inboundData.Password
-		} else if inboundData.Type == "shadowsocks" {
-			userData.SSPassword = inboundData.Password
-		}
-
-		inbound := xray.InboundUserData{
-			Type:       inboundData.Type,
-			Tag:        inboundData.Tag,
-			Flow:       inboundData.Flow,
-			CipherType: xray.ParseCipherType(inboundData.CipherType),
-			IVCheck:    inboundData.IVCheck,
-		}
-
-		user := xray.BuildUserForInbound(inbound, userData)
-		if user == nil {
-			c.logger.WithField("type", inboundData.Type).
-				WithField("tag", inboundData.Tag).
-				Error("Failed to build user - unsupported type")
-			continue
-		}
-
+		user := xray.BuildUserForInbound(addUserInbound(inboundData))
 		if err := userManager.AddUser(bgCtx, inboundData.Tag, user); err != nil {
 			c.logger.WithError(err).
 				WithField("tag", inboundData.Tag).
 				WithField("username", inboundData.Username).
 				Error("Failed to add user to inbound")
 			errMsg := "failed to add user: " + err.Error()
-			ctx.JSON(http.StatusInternalServerError, wrapResponse(AddUserResponseData{
+			return AddUserResponseData{
 				Success: false,
 				Error:   &errMsg,
-			}))
-			return
+			}, http.StatusInternalServerError
 		}
 	}
 
@@ -239,45 +315,81 @@ func (c *HandlerController) handleAddUser(ctx *gin.Context) {
 		}
 	}
 
+	c.ipLimiter.SetLimit(username, req.IPLimit)
+	c.expiry.Set(username, req.HashData.VlessUUID, expireTime(req.ExpireAt))
+
+	inboundTags := make([]string, 0, len(req.Data))
+	for _, inboundData := range req.Data {
+		inboundTags = append(inboundTags, inboundData.Tag)
+	}
+	c.events.Publish(events.TypeUserAdded, events.UserEvent{Username: username, Inbounds: inboundTags})
+	c.eventLog.Record(eventlog.TypeUsersAdded, eventlog.NewUserBatch("add-user", []string{username}, 0))
+
 	c.logger.WithField("username", username).
 		WithField("inbounds", len(req.Data)).
 		Info("User added successfully")
 
-	ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
+	return AddUserResponseData{
 		Success: true,
 		Error:   nil,
-	}))
+	}, http.StatusOK
 }
 
 func (c *HandlerController) handleAddUsers(ctx *gin.Context) {
 	var req AddUsersRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse add-users request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(AddUserResponseData{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, BulkUsersResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
+			Results: []BulkUserResult{},
+		})
 		return
 	}
 
+	_, end := telemetry.Track(ctx.Request.Context(), "users.add", attribute.Int("users", len(req.Users)))
+	if isAsync(ctx) {
+		if status := submitJob(ctx, c.jobs, "add-users", apperrors.CodeAddUserError, func(p *jobs.Progress) (interface{}, int, *string) {
+			resp, status := c.addUsers(req, p)
+			end(status)
+			return resp, status, resp.Error
+		}); status != http.StatusAccepted {
+			end(status)
+		}
+		return
+	}
+
+	resp, status := c.addUsers(req, nil)
+	end(status)
+	respond(ctx, status, apperrors.CodeAddUserError, resp.Error, resp)
+}
+
+// AddUsers adds users in bulk. A failure for one user or inbound does not
+// stop the remaining entries; each is reported in the results.
+func (c *HandlerController) AddUsers(req AddUsersRequest) (BulkUsersResponseData, int) {
+	return c.addUsers(req, nil)
+}
+
+// addUsers implements AddUsers, reporting each processed user to p.
+func (c *HandlerController) addUsers(req AddUsersRequest, p *jobs.Progress) (BulkUsersResponseData, int) {
 	if len(req.Users) == 0 {
-		ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
+		return BulkUsersResponseData{
 			Success: true,
 			Error:   nil,
-		}))
-		return
+			Results: []BulkUserResult{},
+		}, http.StatusOK
 	}
 
 	userManager, err := c.getUserManager()
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get user manager")
 		errMsg := "xray core not available: " + err.Error()
-		ctx.JSON(http.StatusServiceUnavailable, wrapResponse(AddUserResponseData{
+		return BulkUsersResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
-		return
+			Results: []BulkUserResult{},
+		}, http.StatusServiceUnavailable
 	}
 
 	bgCtx := context.Background()
@@ -287,94 +399,192 @@ func (c *HandlerController) handleAddUsers(ctx *gin.Context) {
 		allTags = c.configManager.GetXtlsConfigInbounds()
 	}
 
-	for _, userEntry := range req.Users {
-		username := userEntry.UserData.UserID
-		hashUUID := userEntry.UserData.HashUUID
+	usernames := make([]string, len(req.Users))
+	for i, userEntry := range req.Users {
+		usernames[i] = userEntry.UserData.UserID
+	}
 
-		if err := userManager.RemoveUserFromAllInbounds(bgCtx, allTags, username); err != nil {
-			c.logger.WithError(err).WithField("username", username).
-				Warn("Error removing user from inbounds during bulk add")
-		}
+	c.beginBatch()
+	defer c.endBatch()
 
-		if hashUUID != "" {
-			for _, tag := range allTags {
-				c.configManager.RemoveUserFromInbound(tag, hashUUID)
-			}
+	p.SetTotal(len(req.Users))
+	perUser := make([][]BulkUserResult, len(req.Users))
+	c.forEachUser(usernames, func(i int) {
+		perUser[i] = c.addBulkUser(bgCtx, userManager, allTags, req.Users[i])
+		p.Add(1)
+	})
+
+	results := make([]BulkUserResult, 0, len(req.Users))
+	for _, userResults := range perUser {
+		results = append(results, userResults...)
+	}
+
+	resp := bulkResponse(results)
+	c.eventLog.Record(eventlog.TypeUsersAdded, eventlog.NewUserBatch("add-users", usernames, failedUsers(results)))
+	if resp.Success {
+		c.logger.WithField("count", len(req.Users)).Info("Bulk users added successfully")
+	} else {
+		c.logger.WithField("count", len(req.Users)).Warn("Bulk users added with failures")
+	}
+
+	return resp, http.StatusOK
+}
+
+// addUserInbound converts one inbound of an add-user request to the inbound
+// and user data the account builders take.
+func addUserInbound(inboundData AddUserInboundData) (xray.InboundUserData, xray.UserData) {
+	userData := xray.UserData{
+		UserID:    inboundData.Username,
+		VlessUUID: inboundData.UUID,
+	}
+
+	if inboundData.Type == "vmess" {
+		userData.VmessUUID = inboundData.UUID
+	} else if inboundData.Type == "trojan" {
+		userData.TrojanPassword = inboundData.Password
+	} else if inboundData.Type == "shadowsocks" {
+		userData.SSPassword = inboundData.Password
+	}
+
+	inbound := xray.InboundUserData{
+		Type:       inboundData.Type,
+		Tag:        inboundData.Tag,
+		Flow:       inboundData.Flow,
+		CipherType: xray.ParseCipherType(inboundData.CipherType),
+		IVCheck:    inboundData.IVCheck,
+	}
+	return inbound, userData
+}
+
+// addBulkUser replaces a user of a bulk add and returns one result per inbound.
+func (c *HandlerController) addBulkUser(bgCtx context.Context, userManager *xray.UserManager, allTags []string, userEntry BulkUserEntry) []BulkUserResult {
+	username := userEntry.UserData.UserID
+	hashUUID := userEntry.UserData.HashUUID
+
+	results := make([]BulkUserResult, 0, len(userEntry.InboundData))
+	if err := userManager.RemoveUserFromAllInbounds(bgCtx, allTags, username); err != nil {
+		c.logger.WithError(err).WithField("username", username).
+			Warn("Error removing user from inbounds during bulk add")
+	}
+
+	if hashUUID != "" {
+		for _, tag := range allTags {
+			c.configManager.RemoveUserFromInbound(tag, hashUUID)
 		}
+	}
 
-		for _, inboundData := range userEntry.InboundData {
-			userData := xray.UserData{
-				UserID:         username,
-				HashUUID:       userEntry.UserData.HashUUID,
-				VlessUUID:      userEntry.UserData.VlessUUID,
-				TrojanPassword: userEntry.UserData.TrojanPassword,
-				SSPassword:     userEntry.UserData.SSPassword,
-			}
+	inboundTags := make([]string, 0, len(userEntry.InboundData))
+	for _, inboundData := range userEntry.InboundData {
+		result := BulkUserResult{Username: username, Inbound: inboundData.Tag, Success: true}
 
-			inbound := xray.InboundUserData{
-				Type:       inboundData.Type,
-				Tag:        inboundData.Tag,
-				Flow:       inboundData.Flow,
-				CipherType: xray.ParseCipherType(inboundData.CipherType),
-				IVCheck:    inboundData.IVCheck,
-			}
+		userData := xray.UserData{
+			UserID:         username,
+			HashUUID:       userEntry.UserData.HashUUID,
+			VlessUUID:      userEntry.UserData.VlessUUID,
+			VmessUUID:      userEntry.UserData.VmessUUID,
+			TrojanPassword: userEntry.UserData.TrojanPassword,
+			SSPassword:     userEntry.UserData.SSPassword,
+		}
 
-			user := xray.BuildUserForInbound(inbound, userData)
-			if user == nil {
-				c.logger.WithField("type", inboundData.Type).
-					WithField("tag", inboundData.Tag).
-					Error("Failed to build user - unsupported type")
-				continue
-			}
+		inbound := xray.InboundUserData{
+			Type:       inboundData.Type,
+			Tag:        inboundData.Tag,
+			Flow:       inboundData.Flow,
+			CipherType: xray.ParseCipherType(inboundData.CipherType),
+			IVCheck:    inboundData.IVCheck,
+		}
 
-			if err := userManager.AddUser(bgCtx, inboundData.Tag, user); err != nil {
-				c.logger.WithError(err).
-					WithField("tag", inboundData.Tag).
-					WithField("username", username).
-					Error("Failed to add user to inbound during bulk add")
-				errMsg := "failed to add user: " + err.Error()
-				ctx.JSON(http.StatusInternalServerError, wrapResponse(AddUserResponseData{
-					Success: false,
-					Error:   &errMsg,
-				}))
-				return
-			}
+		if err := xray.ValidateUserForInbound(inbound, userData); err != nil {
+			c.logger.WithError(err).
+				WithField("type", inboundData.Type).
+				WithField("tag", inboundData.Tag).
+				Error("Failed to build user")
+			errMsg := err.Error()
+			result.Success = false
+			result.Error = &errMsg
+			results = append(results, result)
+			continue
+		}
 
-			if userEntry.UserData.HashUUID != "" {
-				c.configManager.AddUserToInbound(inboundData.Tag, userEntry.UserData.HashUUID)
-			}
+		user := xray.BuildUserForInbound(inbound, userData)
+		if err := userManager.AddUser(bgCtx, inboundData.Tag, user); err != nil {
+			c.logger.WithError(err).
+				WithField("tag", inboundData.Tag).
+				WithField("username", username).
+				Error("Failed to add user to inbound during bulk add")
+			errMsg := "failed to add user: " + err.Error()
+			result.Success = false
+			result.Error = &errMsg
+			results = append(results, result)
+			continue
 		}
+
+		if userEntry.UserData.HashUUID != "" {
+			c.configManager.AddUserToInbound(inboundData.Tag, userEntry.UserData.HashUUID)
+		}
+		inboundTags = append(inboundTags, inboundData.Tag)
+		results = append(results, result)
 	}
 
-	c.logger.WithField("count", len(req.Users)).Info("Bulk users added successfully")
+	c.ipLimiter.SetLimit(username, userEntry.UserData.IPLimit)
+	c.expiry.Set(username, userEntry.UserData.HashUUID, expireTime(userEntry.UserData.ExpireAt))
 
-	ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
-		Success: true,
+	if len(inboundTags) > 0 {
+		c.events.Publish(events.TypeUserAdded, events.UserEvent{Username: username, Inbounds: inboundTags})
+	}
+
+	return results
+}
+
+// bulkResponse summarizes the results of a bulk operation.
+func bulkResponse(results []BulkUserResult) BulkUsersResponseData {
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+
+	resp := BulkUsersResponseData{
+		Success: failed == 0,
 		Error:   nil,
-	}))
+		Results: results,
+	}
+	if failed > 0 {
+		errMsg := fmt.Sprintf("%d of %d operations failed", failed, len(results))
+		resp.Error = &errMsg
+	}
+	return resp
 }
 
 func (c *HandlerController) handleRemoveUser(ctx *gin.Context) {
 	var req RemoveUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse remove-user request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(AddUserResponseData{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, AddUserResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
+	_, end := telemetry.Track(ctx.Request.Context(), "user.remove")
+	resp, status := c.RemoveUser(req)
+	end(status)
+	respond(ctx, status, apperrors.CodeRemoveUserError, resp.Error, resp)
+}
+
+// RemoveUser removes a user from all inbounds.
+func (c *HandlerController) RemoveUser(req RemoveUserRequest) (AddUserResponseData, int) {
 	userManager, err := c.getUserManager()
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get user manager")
 		errMsg := "xray core not available: " + err.Error()
-		ctx.JSON(http.StatusServiceUnavailable, wrapResponse(AddUserResponseData{
+		return AddUserResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
-		return
+		}, http.StatusServiceUnavailable
 	}
 
 	bgCtx := context.Background()
@@ -391,82 +601,513 @@ func (c *HandlerController) handleRemoveUser(ctx *gin.Context) {
 		}
 	}
 
+	c.ipLimiter.RemoveUser(req.Username)
+	c.expiry.Remove(req.Username)
+	c.events.Publish(events.TypeUserRemoved, events.UserEvent{Username: req.Username})
+	c.eventLog.Record(eventlog.TypeUsersRemoved, eventlog.NewUserBatch("remove-user", []string{req.Username}, 0))
+
 	c.logger.WithField("username", req.Username).Info("User removed successfully")
 
-	ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
+	return AddUserResponseData{
 		Success: true,
 		Error:   nil,
-	}))
+	}, http.StatusOK
 }
 
 func (c *HandlerController) handleRemoveUsers(ctx *gin.Context) {
 	var req RemoveUsersRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse remove-users request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(AddUserResponseData{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, BulkUsersResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
+			Results: []BulkUserResult{},
+		})
+		return
+	}
+
+	_, end := telemetry.Track(ctx.Request.Context(), "users.remove", attribute.Int("users", len(req.Users)))
+	if isAsync(ctx) {
+		if status := submitJob(ctx, c.jobs, "remove-users", apperrors.CodeRemoveUserError, func(p *jobs.Progress) (interface{}, int, *string) {
+			resp, status := c.removeUsers(req, p)
+			end(status)
+			return resp, status, resp.Error
+		}); status != http.StatusAccepted {
+			end(status)
+		}
 		return
 	}
 
+	resp, status := c.removeUsers(req, nil)
+	end(status)
+	respond(ctx, status, apperrors.CodeRemoveUserError, resp.Error, resp)
+}
+
+// RemoveUsers removes users in bulk, reporting the outcome per user.
+func (c *HandlerController) RemoveUsers(req RemoveUsersRequest) (BulkUsersResponseData, int) {
+	return c.removeUsers(req, nil)
+}
+
+// removeUsers implements RemoveUsers, reporting each processed user to p.
+func (c *HandlerController) removeUsers(req RemoveUsersRequest, p *jobs.Progress) (BulkUsersResponseData, int) {
 	if len(req.Users) == 0 {
-		ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
+		return BulkUsersResponseData{
 			Success: true,
 			Error:   nil,
-		}))
-		return
+			Results: []BulkUserResult{},
+		}, http.StatusOK
 	}
 
 	userManager, err := c.getUserManager()
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to get user manager")
 		errMsg := "xray core not available: " + err.Error()
-		ctx.JSON(http.StatusServiceUnavailable, wrapResponse(AddUserResponseData{
+		return BulkUsersResponseData{
 			Success: false,
 			Error:   &errMsg,
-		}))
-		return
+			Results: []BulkUserResult{},
+		}, http.StatusServiceUnavailable
 	}
 
 	bgCtx := context.Background()
 	allTags := c.configManager.GetXtlsConfigInbounds()
 
+	usernames := make([]string, len(req.Users))
+	for i, userEntry := range req.Users {
+		usernames[i] = userEntry.UserID
+	}
+
+	c.beginBatch()
+	defer c.endBatch()
+
+	p.SetTotal(len(req.Users))
+	results := make([]BulkUserResult, len(req.Users))
+	c.forEachUser(usernames, func(i int) {
+		results[i] = c.removeBulkUser(bgCtx, userManager, allTags, req.Users[i])
+		p.Add(1)
+	})
+
+	resp := bulkResponse(results)
+	c.eventLog.Record(eventlog.TypeUsersRemoved, eventlog.NewUserBatch("remove-users", usernames, failedUsers(results)))
+	if resp.Success {
+		c.logger.WithField("count", len(req.Users)).Info("Bulk users removed successfully")
+	} else {
+		c.logger.WithField("count", len(req.Users)).Warn("Bulk users removed with failures")
+	}
+
+	return resp, http.StatusOK
+}
+
+// failedUsers returns the number of users with a failed result.
+func failedUsers(results []BulkUserResult) int {
+	failed := make(map[string]struct{})
+	for _, result := range results {
+		if !result.Success {
+			failed[result.Username] = struct{}{}
+		}
+	}
+	return len(failed)
+}
+
+// removeBulkUser removes a user of a bulk remove.
+func (c *HandlerController) removeBulkUser(bgCtx context.Context, userManager *xray.UserManager, allTags []string, userEntry BulkRemoveUserEntry) BulkUserResult {
+	result := BulkUserResult{Username: userEntry.UserID, Success: true}
+
+	if err := userManager.RemoveUserFromAllInbounds(bgCtx, allTags, userEntry.UserID); err != nil {
+		c.logger.WithError(err).WithField("username", userEntry.UserID).
+			Warn("Error removing user from all inbounds during bulk remove")
+		errMsg := "failed to remove user: " + err.Error()
+		result.Success = false
+		result.Error = &errMsg
+		return result
+	}
+
+	if userEntry.HashUUID != "" {
+		for _, tag := range allTags {
+			c.configManager.RemoveUserFromInbound(tag, userEntry.HashUUID)
+		}
+	}
+
+	c.ipLimiter.RemoveUser(userEntry.UserID)
+	c.expiry.Remove(userEntry.UserID)
+	c.events.Publish(events.TypeUserRemoved, events.UserEvent{Username: userEntry.UserID})
+
+	return result
+}
+
+// beginBatch defers the saves of the IP limits and expiries to endBatch,
+// once for all the users of a bulk request.
+func (c *HandlerController) beginBatch() {
+	c.ipLimiter.BeginBatch()
+	c.expiry.BeginBatch()
+}
+
+func (c *HandlerController) endBatch() {
+	c.expiry.EndBatch()
+	c.ipLimiter.EndBatch()
+}
+
+// forEachUser calls fn for each index of usernames on up to bulkWorkers
+// goroutines. Entries with the same username always go to the same worker,
+// so repeated entries for a user are still applied in request order, as are
+// the per-inbound operations of each entry.
+func (c *HandlerController) forEachUser(usernames []string, fn func(i int)) {
+	workers := c.bulkWorkers
+	if workers > len(usernames) {
+		workers = len(usernames)
+	}
+	if workers <= 1 {
+		for i := range usernames {
+			fn(i)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	queues := make([]chan int, workers)
+	for w := range queues {
+		queues[w] = make(chan int, bulkQueueSize)
+		wg.Add(1)
+		go func(queue <-chan int) {
+			defer wg.Done()
+			for i := range queue {
+				fn(i)
+			}
+		}(queues[w])
+	}
+
+	for i, username := range usernames {
+		h := fnv.New32a()
+		h.Write([]byte(username))
+		queues[h.Sum32()%uint32(workers)] <- i
+	}
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+}
+
+func (c *HandlerController) handleSyncUsers(ctx *gin.Context) {
+	var req SyncUsersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse sync-users request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, SyncUsersResponseData{
+			Success: false,
+			Error:   &errMsg,
+			Results: []BulkUserResult{},
+		})
+		return
+	}
+
+	_, end := telemetry.Track(ctx.Request.Context(), "users.sync", attribute.Int("users", len(req.Users)))
+	if isAsync(ctx) {
+		if status := submitJob(ctx, c.jobs, "sync-users", apperrors.CodeSyncUsersError, func(p *jobs.Progress) (interface{}, int, *string) {
+			resp, status := c.syncUsers(req, p)
+			end(status)
+			return resp, status, resp.Error
+		}); status != http.StatusAccepted {
+			end(status)
+		}
+		return
+	}
+
+	resp, status := c.syncUsers(req, nil)
+	end(status)
+	respond(ctx, status, apperrors.CodeSyncUsersError, resp.Error, resp)
+}
+
+// SyncUsers makes the runtime users match the desired set in req. Users
+// already present in exactly their desired inbounds with the desired hash
+// are left alone, the others are re-added, and users missing from the set
+// are removed. The inbound hash sets are then rebuilt from the desired set.
+func (c *HandlerController) SyncUsers(req SyncUsersRequest) (SyncUsersResponseData, int) {
+	return c.syncUsers(req, nil)
+}
+
+// syncUsers implements SyncUsers, reporting each added or removed user to p.
+func (c *HandlerController) syncUsers(req SyncUsersRequest, p *jobs.Progress) (SyncUsersResponseData, int) {
+	userManager, err := c.getUserManager()
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to get user manager")
+		errMsg := "xray core not available: " + err.Error()
+		return SyncUsersResponseData{
+			Success: false,
+			Error:   &errMsg,
+			Results: []BulkUserResult{},
+		}, http.StatusServiceUnavailable
+	}
+
+	bgCtx := context.Background()
+
+	tagSet := make(map[string]struct{})
+	scope := req.AffectedInboundTags
+	if len(scope) == 0 {
+		scope = c.configManager.GetXtlsConfigInbounds()
+	}
+	for _, tag := range scope {
+		tagSet[tag] = struct{}{}
+	}
 	for _, userEntry := range req.Users {
-		if err := userManager.RemoveUserFromAllInbounds(bgCtx, allTags, userEntry.UserID); err != nil {
-			c.logger.WithError(err).WithField("username", userEntry.UserID).
-				Warn("Error removing user from all inbounds during bulk remove")
+		for _, inboundData := range userEntry.InboundData {
+			tagSet[inboundData.Tag] = struct{}{}
 		}
+	}
+	allTags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		allTags = append(allTags, tag)
+	}
+	sort.Strings(allTags)
 
-		if userEntry.HashUUID != "" {
-			for _, tag := range allTags {
-				c.configManager.RemoveUserFromInbound(tag, userEntry.HashUUID)
+	runtimeTags := make(map[string]map[string]struct{})
+	for _, tag := range allTags {
+		usernames, err := userManager.GetUsers(bgCtx, tag)
+		if err != nil {
+			c.logger.WithError(err).WithField("tag", tag).Debug("Could not list inbound users during sync")
+			continue
+		}
+		for _, username := range usernames {
+			if runtimeTags[username] == nil {
+				runtimeTags[username] = make(map[string]struct{})
 			}
+			runtimeTags[username][tag] = struct{}{}
 		}
 	}
 
-	c.logger.WithField("count", len(req.Users)).Info("Bulk users removed successfully")
+	c.beginBatch()
+	defer c.endBatch()
 
-	ctx.JSON(http.StatusOK, wrapResponse(AddUserResponseData{
-		Success: true,
-		Error:   nil,
-	}))
+	desired := make(map[string]struct{}, len(req.Users))
+	toAdd := make([]BulkUserEntry, 0)
+	unchanged := 0
+	for _, userEntry := range req.Users {
+		username := userEntry.UserData.UserID
+		desired[username] = struct{}{}
+
+		if !c.userInSync(userEntry, runtimeTags[username]) {
+			toAdd = append(toAdd, userEntry)
+			continue
+		}
+
+		unchanged++
+		c.ipLimiter.SetLimit(username, userEntry.UserData.IPLimit)
+		c.expiry.Set(username, userEntry.UserData.HashUUID, expireTime(userEntry.UserData.ExpireAt))
+	}
+
+	toRemove := make([]BulkRemoveUserEntry, 0)
+	for username := range runtimeTags {
+		if _, ok := desired[username]; !ok {
+			toRemove = append(toRemove, BulkRemoveUserEntry{UserID: username})
+		}
+	}
+	sort.Slice(toRemove, func(i, j int) bool { return toRemove[i].UserID < toRemove[j].UserID })
+
+	p.SetTotal(len(toAdd) + len(toRemove))
+	addUsernames := make([]string, len(toAdd))
+	for i, userEntry := range toAdd {
+		addUsernames[i] = userEntry.UserData.UserID
+	}
+	perUser := make([][]BulkUserResult, len(toAdd))
+	c.forEachUser(addUsernames, func(i int) {
+		perUser[i] = c.addBulkUser(bgCtx, userManager, allTags, toAdd[i])
+		p.Add(1)
+	})
+
+	removeUsernames := make([]string, len(toRemove))
+	for i, userEntry := range toRemove {
+		removeUsernames[i] = userEntry.UserID
+	}
+	removeResults := make([]BulkUserResult, len(toRemove))
+	c.forEachUser(removeUsernames, func(i int) {
+		removeResults[i] = c.removeBulkUser(bgCtx, userManager, allTags, toRemove[i])
+		p.Add(1)
+	})
+
+	results := make([]BulkUserResult, 0, len(toAdd)+len(toRemove))
+	failed := make(map[string]map[string]struct{})
+	for _, userResults := range perUser {
+		for _, result := range userResults {
+			if !result.Success {
+				if failed[result.Inbound] == nil {
+					failed[result.Inbound] = make(map[string]struct{})
+				}
+				failed[result.Inbound][result.Username] = struct{}{}
+			}
+			results = append(results, result)
+		}
+	}
+	results = append(results, removeResults...)
+
+	hashes := make(map[string][]string)
+	for _, userEntry := range req.Users {
+		if userEntry.UserData.HashUUID == "" {
+			continue
+		}
+		for _, inboundData := range userEntry.InboundData {
+			if _, ok := failed[inboundData.Tag][userEntry.UserData.UserID]; ok {
+				continue
+			}
+			hashes[inboundData.Tag] = append(hashes[inboundData.Tag], userEntry.UserData.HashUUID)
+		}
+	}
+	for _, tag := range allTags {
+		c.configManager.SetInboundUsers(tag, hashes[tag])
+	}
+
+	bulk := bulkResponse(results)
+	resp := SyncUsersResponseData{
+		Success:   bulk.Success,
+		Error:     bulk.Error,
+		Added:     len(toAdd),
+		Removed:   len(toRemove),
+		Unchanged: unchanged,
+		Results:   bulk.Results,
+	}
+
+	if len(addUsernames) > 0 {
+		c.eventLog.Record(eventlog.TypeUsersAdded, eventlog.NewUserBatch("sync-users", addUsernames, failedUsers(results[:len(results)-len(removeResults)])))
+	}
+	if len(removeUsernames) > 0 {
+		c.eventLog.Record(eventlog.TypeUsersRemoved, eventlog.NewUserBatch("sync-users", removeUsernames, failedUsers(removeResults)))
+	}
+
+	c.logger.WithField("added", resp.Added).
+		WithField("removed", resp.Removed).
+		WithField("unchanged", resp.Unchanged).
+		Info("Users synced")
+
+	return resp, http.StatusOK
+}
+
+// userInSync reports whether a user is already in exactly the desired
+// inbounds, with the desired hash where one is given.
+func (c *HandlerController) userInSync(userEntry BulkUserEntry, runtimeTags map[string]struct{}) bool {
+	if len(runtimeTags) != len(userEntry.InboundData) {
+		return false
+	}
+
+	hashUUID := userEntry.UserData.HashUUID
+	for _, inboundData := range userEntry.InboundData {
+		if _, ok := runtimeTags[inboundData.Tag]; !ok {
+			return false
+		}
+		if hashUUID != "" && !c.configManager.HasUserInInbound(inboundData.Tag, hashUUID) {
+			return false
+		}
+	}
+	return true
+}
+
+// ExpireUser removes a user whose expiry passed. Unlike RemoveUser it also
+// runs while xray is down, so expired hashes never linger in the config.
+func (c *HandlerController) ExpireUser(username, hashUUID string) {
+	allTags := c.configManager.GetXtlsConfigInbounds()
+
+	if userManager, err := c.getUserManager(); err == nil {
+		if err := userManager.RemoveUserFromAllInbounds(context.Background(), allTags, username); err != nil {
+			c.logger.WithError(err).WithField("username", username).
+				Warn("Error removing expired user from all inbounds")
+		}
+	}
+
+	if hashUUID != "" {
+		for _, tag := range allTags {
+			c.configManager.RemoveUserFromInbound(tag, hashUUID)
+		}
+	}
+
+	c.ipLimiter.RemoveUser(username)
+	c.events.Publish(events.TypeUserRemoved, events.UserEvent{Username: username})
+	c.eventLog.Record(eventlog.TypeUsersRemoved, eventlog.NewUserBatch("expire", []string{username}, 0))
+
+	c.logger.WithField("username", username).Info("Expired user removed")
+}
+
+// expireTime returns the expiry of an add-user payload, zero if none.
+func expireTime(expireAt *time.Time) time.Time {
+	if expireAt == nil {
+		return time.Time{}
+	}
+	return *expireAt
+}
+
+func (c *HandlerController) handleGetUser(ctx *gin.Context) {
+	var req GetUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse get-user request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, errorBody(errMsg))
+		return
+	}
+
+	userManager, err := c.getUserManager()
+	if err != nil {
+		errMsg := "xray core not available: " + err.Error()
+		respondError(ctx, http.StatusServiceUnavailable, apperrors.CodeGetUserError, errMsg)
+		return
+	}
+
+	inbounds := userManager.FindUser(context.Background(), req.Username)
+	if len(inbounds) == 0 {
+		errMsg := "user not found: " + req.Username
+		respondError(ctx, http.StatusNotFound, apperrors.CodeGetUserError, errMsg)
+		return
+	}
+
+	resp := GetUserResponseData{
+		Username: req.Username,
+		Inbounds: inbounds,
+		IPLimit:  c.ipLimiter.Limit(req.Username),
+	}
+	if expireAt, ok := c.expiry.ExpireAt(req.Username); ok {
+		resp.ExpireAt = &expireAt
+	}
+	if instance := c.core.Instance(); instance != nil {
+		if stm, ok := instance.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+			prefix := "user>>>" + req.Username + ">>>traffic>>>"
+			if counter := stm.GetCounter(prefix + "uplink"); counter != nil {
+				resp.Uplink = counter.Value()
+			}
+			if counter := stm.GetCounter(prefix + "downlink"); counter != nil {
+				resp.Downlink = counter.Value()
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
 }
 
 func (c *HandlerController) handleGetInboundUsers(ctx *gin.Context) {
 	var req GetInboundUsersRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse get-inbound-users request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(struct {
-			Error *string `json:"error"`
-		}{Error: &errMsg}))
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, errorBody(errMsg))
+		return
+	}
+
+	userManager, err := c.getUserManager()
+	if err != nil {
+		errMsg := "xray core not available: " + err.Error()
+		respondError(ctx, http.StatusServiceUnavailable, apperrors.CodeFailedToGetInboundUsers, errMsg)
+		return
+	}
+
+	users, err := userManager.GetUsers(context.Background(), req.Tag)
+	if errors.Is(err, xray.ErrInboundNotFound) {
+		respondError(ctx, http.StatusNotFound, apperrors.CodeInboundNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.WithError(err).WithField("tag", req.Tag).Error("Failed to get inbound users")
+		errMsg := "failed to get inbound users: " + err.Error()
+		respondError(ctx, http.StatusInternalServerError, apperrors.CodeFailedToGetInboundUsers, errMsg)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, wrapResponse(GetInboundUsersResponseData{
-		Users: []string{},
+		Users: users,
 	}))
 }
 
@@ -474,14 +1115,31 @@ func (c *HandlerController) handleGetInboundUsersCount(ctx *gin.Context) {
 	var req GetInboundUsersCountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse get-inbound-users-count request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(struct {
-			Error *string `json:"error"`
-		}{Error: &errMsg}))
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, errorBody(errMsg))
+		return
+	}
+
+	userManager, err := c.getUserManager()
+	if err != nil {
+		errMsg := "xray core not available: " + err.Error()
+		respondError(ctx, http.StatusServiceUnavailable, apperrors.CodeFailedToGetInboundUsers, errMsg)
+		return
+	}
+
+	count, err := userManager.GetUsersCount(context.Background(), req.Tag)
+	if errors.Is(err, xray.ErrInboundNotFound) {
+		respondError(ctx, http.StatusNotFound, apperrors.CodeInboundNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		c.logger.WithError(err).WithField("tag", req.Tag).Error("Failed to get inbound users count")
+		errMsg := "failed to get inbound users count: " + err.Error()
+		respondError(ctx, http.StatusInternalServerError, apperrors.CodeFailedToGetInboundUsers, errMsg)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, wrapResponse(GetInboundUsersCountResponseData{
-		Count: 0,
+		Count: int(count),
 	}))
 }
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddUserRejectsUnmanagedProtocols(t *testing.T) {
+	c := &HandlerController{}
+
+	for _, inboundType := range []string{"socks", "http", "hysteria2", "tuic"} {
+		resp, status := c.AddUser(AddUserRequest{
+			Data: []AddUserInboundData{
+				{Tag: "vless-in", Username: "alice", Type: "vless", UUID: "550e8400-e29b-41d4-a716-446655440000"},
+				{Tag: inboundType + "-in", Username: "alice", Type: inboundType, Password: "secret"},
+			},
+		})
+
+		assert.Equal(t, http.StatusBadRequest, status, inboundType)
+		assert.False(t, resp.Success)
+		require.NotNil(t, resp.Error)
+		assert.Equal(t, "unsupported inbound type: "+inboundType, *resp.Error)
+	}
+}
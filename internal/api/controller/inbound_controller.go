@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// apiInboundTag is the tag of the inbound generateAPIConfig injects; the
+// node cannot manage users or stats without it.
+const apiInboundTag = "api"
+
+type AddInboundRequest struct {
+	Tag            string                 `json:"tag" binding:"required"`
+	Listen         string                 `json:"listen,omitempty"`
+	Port           int                    `json:"port" binding:"required,min=1,max=65535"`
+	Protocol       string                 `json:"protocol" binding:"required"`
+	Settings       map[string]interface{} `json:"settings,omitempty"`
+	StreamSettings map[string]interface{} `json:"streamSettings,omitempty"`
+	Sniffing       map[string]interface{} `json:"sniffing,omitempty"`
+}
+
+type RemoveInboundRequest struct {
+	Tag string `json:"tag" binding:"required"`
+}
+
+type InboundResponse struct {
+	Success bool    `json:"success"`
+	Error   *string `json:"error"`
+}
+
+// InboundController adds and removes single inbounds on the running core,
+// e.g. to open a test inbound for a while, keeping the config manager in
+// step so hashes and persisted state reflect them.
+type InboundController struct {
+	core          *xray.Core
+	configManager *xray.ConfigManager
+	logger        *logger.Logger
+}
+
+func NewInboundController(core *xray.Core, configManager *xray.ConfigManager, log *logger.Logger) *InboundController {
+	return &InboundController{
+		core:          core,
+		configManager: configManager,
+		logger:        log,
+	}
+}
+
+func (c *InboundController) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/add-inbound", c.handleAddInbound)
+	group.POST("/remove-inbound", c.handleRemoveInbound)
+}
+
+func (c *InboundController) handleAddInbound(ctx *gin.Context) {
+	var req AddInboundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse add-inbound request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if !c.core.IsRunning() {
+		errMsg := "xray core not running"
+		respond(ctx, http.StatusConflict, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if req.Tag == apiInboundTag || c.configManager.HasInbound(req.Tag) {
+		errMsg := "inbound already exists: " + req.Tag
+		respond(ctx, http.StatusConflict, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	inbound := map[string]interface{}{
+		"tag":      req.Tag,
+		"port":     req.Port,
+		"protocol": req.Protocol,
+	}
+	if req.Listen != "" {
+		inbound["listen"] = req.Listen
+	}
+	if req.Settings != nil {
+		inbound["settings"] = req.Settings
+	}
+	if req.StreamSettings != nil {
+		inbound["streamSettings"] = req.StreamSettings
+	}
+	if req.Sniffing != nil {
+		inbound["sniffing"] = req.Sniffing
+	}
+
+	inboundJSON, err := json.Marshal(inbound)
+	if err != nil {
+		errMsg := "failed to serialize inbound: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if err := c.core.AddInbound(inboundJSON); err != nil {
+		c.logger.WithError(err).WithField("tag", req.Tag).Error("Failed to add inbound")
+		errMsg := "failed to add inbound: " + err.Error()
+		respond(ctx, http.StatusBadRequest, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	c.configManager.AddInbound(inbound)
+
+	ctx.JSON(http.StatusOK, wrapResponse(InboundResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *InboundController) handleRemoveInbound(ctx *gin.Context) {
+	var req RemoveInboundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse remove-inbound request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if req.Tag == apiInboundTag {
+		errMsg := "the api inbound cannot be removed"
+		respond(ctx, http.StatusBadRequest, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if !c.core.IsRunning() || !c.configManager.HasInbound(req.Tag) {
+		errMsg := "inbound not found: " + req.Tag
+		respond(ctx, http.StatusNotFound, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if err := c.core.RemoveInbound(req.Tag); err != nil {
+		c.logger.WithError(err).WithField("tag", req.Tag).Error("Failed to remove inbound")
+		errMsg := "failed to remove inbound: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateInboundError, &errMsg, InboundResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	c.configManager.RemoveInbound(req.Tag)
+
+	ctx.JSON(http.StatusOK, wrapResponse(InboundResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/certmon"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// NodeInfoResponse describes the node and what it supports, so the panel
+// can detect its capabilities before using newer APIs.
+type NodeInfoResponse struct {
+	// NodeID is the configured node ID, or the fingerprint without one.
+	NodeID string `json:"nodeId"`
+	// Fingerprint is the hex SHA-256 of the DER node certificate.
+	Fingerprint string            `json:"fingerprint"`
+	CommonName  string            `json:"commonName"`
+	Labels      map[string]string `json:"labels"`
+	NodeInfo    NodeInfo          `json:"nodeInfo"`
+	CoreVersion string            `json:"coreVersion"`
+	// CoreMode is "embedded" or "external".
+	CoreMode string `json:"coreMode"`
+	// Protocols are the inbound protocols whose users the node manages.
+	Protocols []string `json:"protocols"`
+	// Features flags the optional APIs and behaviors by name, enabled or
+	// not; a name missing from an older node means it is not supported.
+	Features map[string]bool `json:"features"`
+}
+
+// InfoController serves the identity and capabilities of the node.
+type InfoController struct {
+	core     *xray.Core
+	certs    *certmon.Monitor
+	nodeID   string
+	labels   map[string]string
+	features map[string]bool
+}
+
+func NewInfoController(core *xray.Core, certs *certmon.Monitor, nodeID string, labels map[string]string, features map[string]bool) *InfoController {
+	return &InfoController{
+		core:     core,
+		certs:    certs,
+		nodeID:   nodeID,
+		labels:   labels,
+		features: features,
+	}
+}
+
+func (c *InfoController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/info", c.handleInfo)
+}
+
+func (c *InfoController) handleInfo(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(c.Info()))
+}
+
+// Info describes the node. The certificate fields follow a credentials
+// reload.
+func (c *InfoController) Info() NodeInfoResponse {
+	resp := NodeInfoResponse{
+		NodeID:      c.nodeID,
+		Labels:      c.labels,
+		NodeInfo:    nodeInfo(),
+		CoreVersion: c.core.GetVersion(),
+		CoreMode:    "embedded",
+		Protocols:   xray.ManagedProtocols,
+		Features:    c.features,
+	}
+	if c.core.IsExternal() {
+		resp.CoreMode = "external"
+	}
+	if cert, ok := c.certs.Certificate(certmon.NameNode); ok {
+		fingerprint := sha256.Sum256(cert.Raw)
+		resp.Fingerprint = hex.EncodeToString(fingerprint[:])
+		resp.CommonName = cert.Subject.CommonName
+	}
+	if resp.NodeID == "" {
+		resp.NodeID = resp.Fingerprint
+	}
+	return resp
+}
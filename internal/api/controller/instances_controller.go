@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+type CreateInstanceRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type InstanceInfo struct {
+	ID        string  `json:"id"`
+	IsRunning bool    `json:"isRunning"`
+	Version   *string `json:"version"`
+}
+
+type InstancesResponse struct {
+	Instances []InstanceInfo `json:"instances"`
+}
+
+type InstanceResponse struct {
+	Success bool    `json:"success"`
+	Error   *string `json:"error"`
+}
+
+// InstancesController manages the core instances and serves the endpoints
+// of each under /instances/:id: /xray, /handler and /stats, as for the
+// default core. The instance routers are registered as instances come and
+// go.
+type InstancesController struct {
+	instances *xray.CoreManager
+	logger    *logger.Logger
+
+	mu      sync.RWMutex
+	routers map[string]http.Handler
+}
+
+func NewInstancesController(instances *xray.CoreManager, log *logger.Logger) *InstancesController {
+	return &InstancesController{
+		instances: instances,
+		logger:    log,
+		routers:   make(map[string]http.Handler),
+	}
+}
+
+// SetRouter serves the endpoints of instance id through router, where they
+// are rooted at "/".
+func (c *InstancesController) SetRouter(id string, router http.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routers[id] = router
+}
+
+// RemoveRouter stops serving the endpoints of instance id.
+func (c *InstancesController) RemoveRouter(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.routers, id)
+}
+
+func (c *InstancesController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/instances", c.handleList)
+	group.POST("/instances", c.handleCreate)
+	group.DELETE("/instances/:id", c.handleRemove)
+	group.Any("/instances/:id/*path", c.handleInstance)
+}
+
+func (c *InstancesController) handleList(ctx *gin.Context) {
+	instances := c.instances.List()
+	resp := InstancesResponse{Instances: make([]InstanceInfo, 0, len(instances))}
+	for _, instance := range instances {
+		resp.Instances = append(resp.Instances, instanceInfo(instance))
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
+}
+
+func (c *InstancesController) handleCreate(ctx *gin.Context) {
+	var req CreateInstanceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse create instance request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, InstanceResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	instance, err := c.instances.Create(req.ID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, xray.ErrInstanceExists) {
+			status = http.StatusConflict
+		}
+		errMsg := err.Error()
+		respond(ctx, status, apperrors.CodeXrayInstanceError, &errMsg, InstanceResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(instanceInfo(instance)))
+}
+
+func (c *InstancesController) handleRemove(ctx *gin.Context) {
+	if err := c.instances.Remove(ctx.Param("id")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, xray.ErrInstanceNotFound) {
+			status = http.StatusNotFound
+		}
+		errMsg := err.Error()
+		respond(ctx, status, apperrors.CodeXrayInstanceError, &errMsg, InstanceResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(InstanceResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+// handleInstance passes the request on to the router of the instance, with
+// the /instances/:id prefix stripped.
+func (c *InstancesController) handleInstance(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	c.mu.RLock()
+	router, exists := c.routers[id]
+	c.mu.RUnlock()
+	if !exists {
+		errMsg := xray.ErrInstanceNotFound.Error() + ": " + id
+		respond(ctx, http.StatusNotFound, apperrors.CodeXrayInstanceError, &errMsg, InstanceResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	req := ctx.Request.Clone(ctx.Request.Context())
+	req.URL.Path = ctx.Param("path")
+	req.URL.RawPath = ""
+	router.ServeHTTP(ctx.Writer, req)
+}
+
+func instanceInfo(instance *xray.CoreInstance) InstanceInfo {
+	info := InstanceInfo{
+		ID:        instance.ID,
+		IsRunning: instance.Core.IsRunning(),
+	}
+	if info.IsRunning {
+		version := instance.Core.GetVersion()
+		info.Version = &version
+	}
+	return info
+}
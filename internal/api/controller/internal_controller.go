@@ -1,35 +1,248 @@
 package controller
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xtls/xray-core/features/inbound"
 
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/version"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
-// InternalController handles internal API endpoints.
+const (
+	// configWaitTimeout bounds how long a get-config long-poll is held.
+	configWaitTimeout = time.Minute
+	// statusErrorLines is the number of recent error lines in a status.
+	statusErrorLines = 10
+)
+
+// InboundUsers lists the users of one inbound.
+type InboundUsers struct {
+	Tag   string   `json:"tag"`
+	Users []string `json:"users"`
+}
+
+type InboundUsersResponse struct {
+	Inbounds []InboundUsers `json:"inbounds"`
+}
+
+// InboundUserCount is the number of users of one inbound.
+type InboundUserCount struct {
+	Tag   string `json:"tag"`
+	Users int    `json:"users"`
+}
+
+// NodeStatusResponse summarizes the node for an operator on the host.
+type NodeStatusResponse struct {
+	NodeVersion   string  `json:"nodeVersion"`
+	IsXrayRunning bool    `json:"isXrayRunning"`
+	XrayVersion   *string `json:"xrayVersion"`
+	// Uptime is the node uptime in seconds.
+	Uptime   int64              `json:"uptime"`
+	Inbounds []InboundUserCount `json:"inbounds"`
+	// RecentErrors are the last error log lines, oldest first, as written
+	// by the node.
+	RecentErrors []json.RawMessage `json:"recentErrors"`
+}
+
+// InternalController handles internal API endpoints. Its responses are not
+// wrapped, so local tools can consume them directly.
 type InternalController struct {
-	configManager *xray.ConfigManager
-	logger        *logger.Logger
+	core            *xray.Core
+	configManager   *xray.ConfigManager
+	xrayController  *XrayController
+	statsController *StatsController
+	logsController  *LogsController
+	logger          *logger.Logger
 }
 
 // NewInternalController creates a new InternalController instance.
-func NewInternalController(configManager *xray.ConfigManager, log *logger.Logger) *InternalController {
+func NewInternalController(core *xray.Core, configManager *xray.ConfigManager, xrayController *XrayController, statsController *StatsController, logsController *LogsController, log *logger.Logger) *InternalController {
 	return &InternalController{
-		configManager: configManager,
-		logger:        log,
+		core:            core,
+		configManager:   configManager,
+		xrayController:  xrayController,
+		statsController: statsController,
+		logsController:  logsController,
+		logger:          log,
 	}
 }
 
 // RegisterRoutes registers the internal controller routes.
 func (c *InternalController) RegisterRoutes(group *gin.RouterGroup) {
 	group.GET("/get-config", c.handleGetConfig)
+	group.GET("/xray-status", c.handleXrayStatus)
+	group.GET("/healthcheck", c.handleHealthcheck)
+	group.GET("/stats", c.handleStats)
+	group.GET("/inbound-users", c.handleInboundUsers)
+	group.GET("/status", c.handleStatus)
 }
 
 // handleGetConfig returns the raw xray configuration JSON (not wrapped).
+// Credentials and keys are redacted unless ?full=true is given.
+//
+// The response carries an ETag; a request whose If-None-Match matches it
+// gets 304. With ?wait=true such a request is held until the config
+// changes, for at most configWaitTimeout.
 func (c *InternalController) handleGetConfig(ctx *gin.Context) {
-	config := c.configManager.GetXrayConfig()
-	ctx.JSON(http.StatusOK, config)
+	full, _ := strconv.ParseBool(ctx.Query("full"))
+	wait, _ := strconv.ParseBool(ctx.Query("wait"))
+	ifNoneMatch := ctx.GetHeader("If-None-Match")
+
+	timeout := time.NewTimer(configWaitTimeout)
+	defer timeout.Stop()
+
+	for {
+		config, changed := c.configManager.WatchXrayConfig()
+		if !full {
+			config = xray.RedactConfig(config)
+		}
+
+		body, err := json.Marshal(config)
+		if err != nil {
+			c.logger.WithError(err).Error("Failed to serialize xray config")
+			ctx.Status(http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		ctx.Header("ETag", etag)
+
+		if ifNoneMatch != etag {
+			ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+			return
+		}
+		if !wait {
+			ctx.Status(http.StatusNotModified)
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			ctx.Status(http.StatusNotModified)
+			return
+		case <-ctx.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// handleXrayStatus returns whether xray is running and its version.
+func (c *InternalController) handleXrayStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.xrayController.Status())
+}
+
+// handleHealthcheck returns the node healthcheck, with 503 when a check
+// fails, for probes that only look at the status code.
+func (c *InternalController) handleHealthcheck(ctx *gin.Context) {
+	resp := c.xrayController.Healthcheck()
+	status := http.StatusOK
+	if !resp.IsHealthy {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, resp)
+}
+
+// handleStats returns the traffic of all inbounds and outbounds. Counters
+// are never reset here, as the panel owns them.
+func (c *InternalController) handleStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.statsController.CombinedStats(false))
+}
+
+// handleInboundUsers lists the users of every tracked inbound, or only of
+// the one given by the tag query parameter.
+func (c *InternalController) handleInboundUsers(ctx *gin.Context) {
+	userManager, err := c.getUserManager()
+	if err != nil {
+		errMsg := "xray core not available: " + err.Error()
+		ctx.JSON(http.StatusServiceUnavailable, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	tags := c.configManager.GetXtlsConfigInbounds()
+	if tag := ctx.Query("tag"); tag != "" {
+		tags = []string{tag}
+	}
+
+	inbounds, err := c.inboundUsers(userManager, tags)
+	if err != nil {
+		errMsg := "failed to get inbound users: " + err.Error()
+		ctx.JSON(http.StatusInternalServerError, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, InboundUsersResponse{Inbounds: inbounds})
+}
+
+// handleStatus returns the xray state and version, the node uptime, the
+// user count of every tracked inbound and the last error log lines, for
+// the status subcommand.
+func (c *InternalController) handleStatus(ctx *gin.Context) {
+	xrayStatus := c.xrayController.Status()
+	resp := NodeStatusResponse{
+		NodeVersion:   version.Version,
+		IsXrayRunning: xrayStatus.IsRunning,
+		XrayVersion:   xrayStatus.Version,
+		Uptime:        c.statsController.SystemStats().Uptime,
+		Inbounds:      []InboundUserCount{},
+		RecentErrors:  c.logsController.RecentErrors(statusErrorLines),
+	}
+
+	if userManager, err := c.getUserManager(); err == nil {
+		inbounds, err := c.inboundUsers(userManager, c.configManager.GetXtlsConfigInbounds())
+		if err == nil {
+			for _, inbound := range inbounds {
+				resp.Inbounds = append(resp.Inbounds, InboundUserCount{Tag: inbound.Tag, Users: len(inbound.Users)})
+			}
+		}
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// inboundUsers returns the users of the inbounds with the given tags,
+// sorted by tag.
+func (c *InternalController) inboundUsers(userManager *xray.UserManager, tags []string) ([]InboundUsers, error) {
+	sort.Strings(tags)
+
+	inbounds := make([]InboundUsers, 0, len(tags))
+	for _, tag := range tags {
+		users, err := userManager.GetUsers(context.Background(), tag)
+		if err != nil {
+			c.logger.WithError(err).WithField("tag", tag).Error("Failed to get inbound users")
+			return nil, err
+		}
+		inbounds = append(inbounds, InboundUsers{Tag: tag, Users: users})
+	}
+	return inbounds, nil
+}
+
+func (c *InternalController) getUserManager() (*xray.UserManager, error) {
+	instance := c.core.Instance()
+	if instance == nil {
+		return nil, errors.New("xray core not running")
+	}
+
+	ibm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return nil, errors.New("inbound manager not available")
+	}
+
+	return xray.NewUserManager(ibm, c.logger), nil
 }
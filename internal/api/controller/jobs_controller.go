@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// JobAcceptedResponse is returned instead of the operation result when a
+// request is run as a job.
+type JobAcceptedResponse struct {
+	JobID string `json:"jobId"`
+}
+
+type JobsController struct {
+	manager *jobs.Manager
+	logger  *logger.Logger
+}
+
+func NewJobsController(manager *jobs.Manager, log *logger.Logger) *JobsController {
+	return &JobsController{
+		manager: manager,
+		logger:  log,
+	}
+}
+
+func (c *JobsController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/jobs/:id", c.handleGetJob)
+}
+
+func (c *JobsController) handleGetJob(ctx *gin.Context) {
+	job, exists := c.manager.Get(ctx.Param("id"))
+	if !exists {
+		errMsg := "job not found: " + ctx.Param("id")
+		ctx.JSON(http.StatusNotFound, wrapResponse(struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg}))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(job))
+}
+
+// isAsync reports whether the request asked to run as a job with ?async=true.
+func isAsync(ctx *gin.Context) bool {
+	async, _ := strconv.ParseBool(ctx.Query("async"))
+	return async
+}
+
+// submitJob runs an operation returning a response and HTTP status as a job
+// and answers 202 with its ID. The job fails if the status is an error, with
+// the response kept as its result. While too many jobs are in progress it
+// answers 429, and 503 once the node is shutting down, with code. Returns
+// the status answered.
+func submitJob(ctx *gin.Context, manager *jobs.Manager, jobType, code string, run func(p *jobs.Progress) (interface{}, int, *string)) int {
+	job, err := manager.Submit(jobType, func(p *jobs.Progress) (interface{}, error) {
+		resp, status, errMsg := run(p)
+		if status >= http.StatusBadRequest {
+			msg := http.StatusText(status)
+			if errMsg != nil {
+				msg = *errMsg
+			}
+			return resp, errors.New(msg)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		if errors.Is(err, jobs.ErrTooManyJobs) {
+			status = http.StatusTooManyRequests
+		}
+		respondError(ctx, status, code, "failed to submit job: "+err.Error())
+		return status
+	}
+
+	ctx.JSON(http.StatusAccepted, wrapResponse(JobAcceptedResponse{JobID: job.ID}))
+	return http.StatusAccepted
+}
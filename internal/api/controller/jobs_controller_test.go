@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestSubmitJobRejectsPastLimitAndAfterStop(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager := jobs.NewManager(logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+	release := make(chan struct{})
+
+	submit := func() (int, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/node/handler/add-users?async=true", nil)
+		status := submitJob(ctx, manager, "test", apperrors.CodeAddUserError, func(p *jobs.Progress) (interface{}, int, *string) {
+			<-release
+			return nil, http.StatusOK, nil
+		})
+		return status, w
+	}
+
+	for range jobs.MaxActive {
+		status, w := submit()
+		require.Equal(t, http.StatusAccepted, status)
+		require.Equal(t, http.StatusAccepted, w.Code)
+	}
+
+	status, w := submit()
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "too many jobs in progress")
+
+	close(release)
+	require.NoError(t, manager.Stop(context.Background()))
+
+	status, w = submit()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/lastseen"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type UsersLastSeenRequest struct {
+	// Username limits the response to one user; all users when empty.
+	Username string `json:"username"`
+}
+
+type UsersLastSeenResponse struct {
+	Users []lastseen.User `json:"users"`
+}
+
+// LastSeenController reports when users last connected and from which IPs.
+type LastSeenController struct {
+	tracker *lastseen.Tracker
+	logger  *logger.Logger
+}
+
+func NewLastSeenController(tracker *lastseen.Tracker, log *logger.Logger) *LastSeenController {
+	return &LastSeenController{
+		tracker: tracker,
+		logger:  log,
+	}
+}
+
+func (c *LastSeenController) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/get-users-last-seen", c.handleGetUsersLastSeen)
+}
+
+func (c *LastSeenController) handleGetUsersLastSeen(ctx *gin.Context) {
+	var req UsersLastSeenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		req.Username = ""
+	}
+
+	now := time.Now()
+	resp := UsersLastSeenResponse{Users: []lastseen.User{}}
+	if req.Username == "" {
+		resp.Users = c.tracker.Users(now)
+	} else if user, found := c.tracker.User(req.Username, now); found {
+		resp.Users = append(resp.Users, user)
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
+}
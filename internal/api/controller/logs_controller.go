@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	defaultLogLines = 100
+
+	// Values of the source filter; xray lines are those captured from the
+	// core, tagged with source=xray.
+	logSourceNode = "node"
+	logSourceXray = "xray"
+)
+
+type GetLogsResponse struct {
+	// Entries are the log lines as written by the node, oldest first.
+	Entries []json.RawMessage `json:"entries"`
+}
+
+// LogsController serves the recent log lines kept by the logger buffer, so
+// the panel can read node and xray logs without shell access.
+type LogsController struct {
+	buffer *logger.Buffer
+	logger *logger.Logger
+}
+
+func NewLogsController(buffer *logger.Buffer, log *logger.Logger) *LogsController {
+	return &LogsController{
+		buffer: buffer,
+		logger: log,
+	}
+}
+
+func (c *LogsController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/logs", c.handleGetLogs)
+	group.GET("/logs/stream", c.handleStreamLogs)
+}
+
+// logFilter selects log entries from the query: level is the minimum
+// level, since an RFC 3339 time and source either node or xray.
+type logFilter struct {
+	level  logger.Level
+	since  time.Time
+	source string
+}
+
+func parseLogFilter(ctx *gin.Context) (logFilter, error) {
+	var filter logFilter
+
+	if v := ctx.Query("level"); v != "" {
+		level, err := logger.ParseLevel(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.level = level
+	}
+	if v := ctx.Query("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid since %q", v)
+		}
+		filter.since = since
+	}
+	switch v := ctx.Query("source"); v {
+	case "", logSourceNode, logSourceXray:
+		filter.source = v
+	default:
+		return filter, fmt.Errorf("unknown log source %q", v)
+	}
+
+	return filter, nil
+}
+
+func (f logFilter) matches(entry logger.Entry) bool {
+	if f.level != "" && !entry.AtLeast(f.level) {
+		return false
+	}
+	if !f.since.IsZero() && entry.Time.Before(f.since) {
+		return false
+	}
+	if f.source != "" && entrySource(entry) != f.source {
+		return false
+	}
+	return true
+}
+
+func entrySource(entry logger.Entry) string {
+	var fields struct {
+		Source string `json:"source"`
+	}
+	if json.Unmarshal(entry.Line, &fields) == nil && fields.Source == logSourceXray {
+		return logSourceXray
+	}
+	return logSourceNode
+}
+
+// tail returns the last n entries of the buffer matching filter.
+func (c *LogsController) tail(filter logFilter, n int) []logger.Entry {
+	all := c.buffer.Entries()
+
+	entries := make([]logger.Entry, 0, min(n, len(all)))
+	for i := len(all) - 1; i >= 0 && len(entries) < n; i-- {
+		if filter.matches(all[i]) {
+			entries = append(entries, all[i])
+		}
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+// parseLogLines returns the lines query parameter, fallback when absent.
+func parseLogLines(ctx *gin.Context, fallback int) (int, error) {
+	v := ctx.Query("lines")
+	if v == "" {
+		return fallback, nil
+	}
+	lines, err := strconv.Atoi(v)
+	if err != nil || lines < 0 {
+		return 0, fmt.Errorf("invalid lines %q", v)
+	}
+	return lines, nil
+}
+
+// checkLogsRequest answers the request with an error and returns false
+// when the buffer is disabled or the query is invalid.
+func (c *LogsController) checkLogsRequest(ctx *gin.Context, defaultLines int) (logFilter, int, bool) {
+	if c.buffer == nil {
+		errMsg := "log buffer is disabled"
+		ctx.JSON(http.StatusServiceUnavailable, wrapResponse(struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg}))
+		return logFilter{}, 0, false
+	}
+
+	filter, err := parseLogFilter(ctx)
+	if err == nil {
+		var lines int
+		if lines, err = parseLogLines(ctx, defaultLines); err == nil {
+			return filter, lines, true
+		}
+	}
+
+	errMsg := "invalid query: " + err.Error()
+	ctx.JSON(http.StatusBadRequest, wrapResponse(struct {
+		Error *string `json:"error"`
+	}{Error: &errMsg}))
+	return logFilter{}, 0, false
+}
+
+func (c *LogsController) handleGetLogs(ctx *gin.Context) {
+	filter, lines, ok := c.checkLogsRequest(ctx, defaultLogLines)
+	if !ok {
+		return
+	}
+
+	entries := c.tail(filter, lines)
+	resp := GetLogsResponse{Entries: make([]json.RawMessage, len(entries))}
+	for i, entry := range entries {
+		resp.Entries[i] = entry.Line
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
+}
+
+// RecentErrors returns the last n lines logged at error level or above,
+// oldest first; none when the buffer is disabled.
+func (c *LogsController) RecentErrors(n int) []json.RawMessage {
+	lines := []json.RawMessage{}
+	if c.buffer == nil {
+		return lines
+	}
+	for _, entry := range c.tail(logFilter{level: logger.LevelError}, n) {
+		lines = append(lines, entry.Line)
+	}
+	return lines
+}
+
+// handleStreamLogs sends the lines matching the filter as Server-Sent
+// Events of type log as they are written. With lines, the last matching
+// lines already kept are sent first.
+func (c *LogsController) handleStreamLogs(ctx *gin.Context) {
+	filter, lines, ok := c.checkLogsRequest(ctx, 0)
+	if !ok {
+		return
+	}
+
+	ch, unsubscribe := c.buffer.Subscribe()
+	defer unsubscribe()
+
+	backlog := c.tail(filter, lines)
+	var sentUntil time.Time
+	if len(backlog) > 0 {
+		sentUntil = backlog[len(backlog)-1].Time
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+
+	c.logger.WithField("ip", ctx.ClientIP()).Info("Logs subscriber connected")
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	// The stream outlives the server read and write timeouts.
+	rc := http.NewResponseController(ctx.Writer)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	// Flush headers right away so the client knows the stream is open.
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	for _, entry := range backlog {
+		ctx.SSEvent("log", entry.Line)
+	}
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case entry, ok := <-ch:
+			if !ok {
+				return false
+			}
+			// Lines written while the backlog was read are in both.
+			if !entry.Time.After(sentUntil) || !filter.matches(entry) {
+				return true
+			}
+			ctx.SSEvent("log", entry.Line)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+
+	c.logger.WithField("ip", ctx.ClientIP()).Info("Logs subscriber disconnected")
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/outboundmon"
+)
+
+type OutboundHealthResponse struct {
+	// Outbounds is empty when no outbound is probed.
+	Outbounds []outboundmon.Status `json:"outbounds"`
+}
+
+// OutboundHealthController reports the health of the probed outbounds.
+type OutboundHealthController struct {
+	prober *outboundmon.Prober
+	logger *logger.Logger
+}
+
+// NewOutboundHealthController creates the controller; prober is nil when
+// outbound probes are disabled.
+func NewOutboundHealthController(prober *outboundmon.Prober, log *logger.Logger) *OutboundHealthController {
+	return &OutboundHealthController{
+		prober: prober,
+		logger: log,
+	}
+}
+
+func (c *OutboundHealthController) RegisterRoutes(group *gin.RouterGroup) {
+	group.GET("/get-outbound-health", c.handleGetOutboundHealth)
+}
+
+func (c *OutboundHealthController) handleGetOutboundHealth(ctx *gin.Context) {
+	resp := OutboundHealthResponse{Outbounds: []outboundmon.Status{}}
+	if c.prober != nil {
+		resp.Outbounds = c.prober.Statuses()
+	}
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
+}
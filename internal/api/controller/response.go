@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+)
+
+// CodedErrorsKey is the gin context key the server sets on requests whose
+// failures are answered with coded error bodies.
+const CodedErrorsKey = "coded_errors"
+
+// respond writes data in the response envelope with status. A failure,
+// status 400 or above, is written as a coded error body with code and
+// errMsg instead when the request asks for coded errors.
+func respond(ctx *gin.Context, status int, code string, errMsg *string, data interface{}) {
+	if status >= http.StatusBadRequest && ctx.GetBool(CodedErrorsKey) {
+		detail := ""
+		if errMsg != nil {
+			detail = *errMsg
+		}
+		ctx.JSON(status, apperrors.NewResponse(ctx.Request.URL.Path, code, detail))
+		return
+	}
+	ctx.JSON(status, wrapResponse(data))
+}
+
+// respondError writes a failure whose legacy body holds only the error
+// message.
+func respondError(ctx *gin.Context, status int, code string, errMsg string) {
+	respond(ctx, status, code, &errMsg, errorBody(errMsg))
+}
+
+// errorBody is the legacy body of a failure holding only errMsg.
+func errorBody(errMsg string) interface{} {
+	return struct {
+		Error *string `json:"error"`
+	}{Error: &errMsg}
+}
@@ -0,0 +1,289 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/routing"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+type AddRoutingRuleRequest struct {
+	RuleTag     string `json:"ruleTag" binding:"required"`
+	SourceIP    string `json:"sourceIp" binding:"required"`
+	OutboundTag string `json:"outboundTag" binding:"required"`
+	TTLSeconds  int64  `json:"ttlSeconds" binding:"omitempty,min=0"`
+	Creator     string `json:"creator"`
+}
+
+type RemoveRoutingRuleRequest struct {
+	RuleTag string `json:"ruleTag" binding:"required"`
+}
+
+type RoutingRuleResponse struct {
+	Success bool    `json:"success"`
+	Error   *string `json:"error"`
+}
+
+type ListRoutingRulesResponse struct {
+	Rules []xray.RoutingRule `json:"rules"`
+}
+
+type ListNodeRoutingRulesResponse struct {
+	Rules []routing.Rule `json:"rules"`
+}
+
+type SetUserOutboundRequest struct {
+	Username    string `json:"username" binding:"required"`
+	OutboundTag string `json:"outboundTag" binding:"required"`
+}
+
+type RemoveUserOutboundRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+type ListUserOutboundsResponse struct {
+	Users []routing.UserOutbound `json:"users"`
+}
+
+type RoutingController struct {
+	core       *xray.Core
+	registry   *routing.Registry
+	userRoutes *routing.UserRoutes
+	logger     *logger.Logger
+}
+
+func NewRoutingController(core *xray.Core, registry *routing.Registry, userRoutes *routing.UserRoutes, log *logger.Logger) *RoutingController {
+	return &RoutingController{
+		core:       core,
+		registry:   registry,
+		userRoutes: userRoutes,
+		logger:     log,
+	}
+}
+
+func (c *RoutingController) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/add-rule", c.handleAddRule)
+	group.POST("/remove-rule", c.handleRemoveRule)
+	group.GET("/list-rules", c.handleListRules)
+	group.GET("/rules", c.handleListNodeRules)
+	group.DELETE("/rules/:tag", c.handleDeleteRule)
+	group.POST("/set-user-outbound", c.handleSetUserOutbound)
+	group.POST("/remove-user-outbound", c.handleRemoveUserOutbound)
+	group.GET("/user-outbounds", c.handleListUserOutbounds)
+}
+
+func (c *RoutingController) handleAddRule(ctx *gin.Context) {
+	var req AddRoutingRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse add-rule request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	creator := req.Creator
+	if creator == "" {
+		creator = tokenSubject(ctx)
+	}
+
+	rule := routing.Rule{
+		Tag:         req.RuleTag,
+		Source:      req.SourceIP,
+		OutboundTag: req.OutboundTag,
+		Creator:     creator,
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if _, err := c.registry.Add(rule, ttl); err != nil {
+		if errors.Is(err, routing.ErrInvalidSource) {
+			errMsg := "invalid IP address or CIDR range"
+			respond(ctx, http.StatusBadRequest, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+				Success: false,
+				Error:   &errMsg,
+			})
+			return
+		}
+
+		c.logger.WithError(err).WithField("ruleTag", req.RuleTag).Error("Failed to add routing rule")
+		errMsg := "failed to add routing rule: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *RoutingController) handleRemoveRule(ctx *gin.Context) {
+	var req RemoveRoutingRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse remove-rule request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	// Rules not added through the registry are removed from xray directly.
+	if c.registry.Remove(req.RuleTag) {
+		ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+			Success: true,
+			Error:   nil,
+		}))
+		return
+	}
+
+	if err := c.core.RemoveRoutingRule(req.RuleTag); err != nil {
+		c.logger.WithError(err).WithField("ruleTag", req.RuleTag).Error("Failed to remove routing rule")
+		errMsg := "failed to remove routing rule: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *RoutingController) handleListRules(ctx *gin.Context) {
+	rules, err := c.core.ListRoutingRules()
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to list routing rules")
+		status := http.StatusInternalServerError
+		if !c.core.IsRunning() {
+			status = http.StatusServiceUnavailable
+		}
+		respondError(ctx, status, apperrors.CodeUpdateRoutingError, "failed to list routing rules: "+err.Error())
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(ListRoutingRulesResponse{
+		Rules: rules,
+	}))
+}
+
+func (c *RoutingController) handleListNodeRules(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(ListNodeRoutingRulesResponse{
+		Rules: c.registry.List(),
+	}))
+}
+
+func (c *RoutingController) handleDeleteRule(ctx *gin.Context) {
+	tag := ctx.Param("tag")
+	if !c.registry.Remove(tag) {
+		errMsg := "routing rule not found: " + tag
+		respond(ctx, http.StatusNotFound, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *RoutingController) handleSetUserOutbound(ctx *gin.Context) {
+	var req SetUserOutboundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse set-user-outbound request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	// Outbounds can only be checked against a running core; mappings set
+	// while it is down are applied as they are on next start.
+	if c.core.IsRunning() && !c.core.HasOutbound(req.OutboundTag) {
+		errMsg := "outbound not found: " + req.OutboundTag
+		respond(ctx, http.StatusBadRequest, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if err := c.userRoutes.Set(req.Username, req.OutboundTag); err != nil {
+		c.logger.WithError(err).WithField("username", req.Username).Error("Failed to set user outbound")
+		errMsg := "failed to set user outbound: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *RoutingController) handleRemoveUserOutbound(ctx *gin.Context) {
+	var req RemoveUserOutboundRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse remove-user-outbound request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	if !c.userRoutes.Remove(req.Username) {
+		errMsg := "no outbound set for user: " + req.Username
+		respond(ctx, http.StatusNotFound, apperrors.CodeUpdateRoutingError, &errMsg, RoutingRuleResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(RoutingRuleResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
+
+func (c *RoutingController) handleListUserOutbounds(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(ListUserOutboundsResponse{
+		Users: c.userRoutes.List(),
+	}))
+}
+
+// tokenSubject returns the subject of the request's JWT, if any.
+func tokenSubject(ctx *gin.Context) string {
+	claims, _ := ctx.Get("jwt_claims")
+	mapClaims, _ := claims.(jwt.MapClaims)
+	if mapClaims == nil {
+		return ""
+	}
+	subject, _ := mapClaims.GetSubject()
+	return subject
+}
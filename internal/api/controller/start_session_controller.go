@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/startsession"
+)
+
+type StartSessionBatchRequest struct {
+	// Seq numbers the batch from 0. Re-uploading a seq replaces the batch.
+	Seq      *int                        `json:"seq" binding:"required,min=0"`
+	Inbounds []startsession.InboundUsers `json:"inbounds" binding:"required"`
+}
+
+type StartSessionResponse struct {
+	Success bool    `json:"success"`
+	Error   *string `json:"error"`
+}
+
+type StartSessionCommitRequest struct {
+	// Batches, if set, is the number of batches uploaded; the commit fails
+	// without starting if any of them is missing.
+	Batches int `json:"batches" binding:"min=0"`
+}
+
+// StartSessionController serves the split form of the start command for
+// nodes with many users: the base config and hashes are uploaded first,
+// the users in batches after, and the commit starts xray with the
+// assembled config like a start command would.
+type StartSessionController struct {
+	sessions *startsession.Manager
+	xray     *XrayController
+	jobs     *jobs.Manager
+	logger   *logger.Logger
+}
+
+func NewStartSessionController(sessions *startsession.Manager, xrayController *XrayController, jobManager *jobs.Manager, log *logger.Logger) *StartSessionController {
+	return &StartSessionController{
+		sessions: sessions,
+		xray:     xrayController,
+		jobs:     jobManager,
+		logger:   log,
+	}
+}
+
+func (c *StartSessionController) RegisterRoutes(group *gin.RouterGroup) {
+	group.POST("/start-session", c.handleCreate)
+	group.GET("/start-session/:id", c.handleGet)
+	group.POST("/start-session/:id/batch", c.handleBatch)
+	group.POST("/start-session/:id/commit", c.handleCommit)
+	group.DELETE("/start-session/:id", c.handleDelete)
+}
+
+func (c *StartSessionController) handleCreate(ctx *gin.Context) {
+	var req StartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse start-session request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(c.sessions.Create(req.XrayConfig, req.Internals)))
+}
+
+func (c *StartSessionController) handleGet(ctx *gin.Context) {
+	info, err := c.sessions.Get(ctx.Param("id"))
+	if err != nil {
+		errMsg := err.Error()
+		respond(ctx, http.StatusNotFound, apperrors.CodeStartXrayError, &errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(info))
+}
+
+func (c *StartSessionController) handleBatch(ctx *gin.Context) {
+	var req StartSessionBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse start-session batch")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	info, err := c.sessions.AddBatch(ctx.Param("id"), *req.Seq, req.Inbounds)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, startsession.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		errMsg := err.Error()
+		respond(ctx, status, apperrors.CodeStartXrayError, &errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(info))
+}
+
+// handleCommit starts xray with the assembled config. The session is closed
+// once xray started; after a failure it stays open for a retry, with
+// batches resent as needed.
+func (c *StartSessionController) handleCommit(ctx *gin.Context) {
+	var req StartSessionCommitRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			c.logger.WithError(err).Error("Failed to parse start-session commit")
+			errMsg := "invalid request body: " + bindErrorMessage(err)
+			respondInvalid(ctx, err, errMsg, StartSessionResponse{
+				Success: false,
+				Error:   &errMsg,
+			})
+			return
+		}
+	}
+
+	id := ctx.Param("id")
+	xrayConfig, internals, err := c.sessions.Build(id, req.Batches)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, startsession.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		errMsg := err.Error()
+		respond(ctx, status, apperrors.CodeStartXrayError, &errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	start := func() (StartResponse, int) {
+		resp, status := c.xray.Start(StartRequest{XrayConfig: xrayConfig, Internals: internals})
+		if status == http.StatusOK {
+			c.sessions.Delete(id)
+		}
+		return resp, status
+	}
+
+	if isAsync(ctx) {
+		submitJob(ctx, c.jobs, "start", apperrors.CodeStartXrayError, func(p *jobs.Progress) (interface{}, int, *string) {
+			resp, status := start()
+			return resp, status, resp.Error
+		})
+		return
+	}
+
+	resp, status := start()
+	respond(ctx, status, apperrors.CodeStartXrayError, resp.Error, resp)
+}
+
+func (c *StartSessionController) handleDelete(ctx *gin.Context) {
+	if !c.sessions.Delete(ctx.Param("id")) {
+		errMsg := "start session not found: " + ctx.Param("id")
+		respond(ctx, http.StatusNotFound, apperrors.CodeStartXrayError, &errMsg, StartSessionResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(StartSessionResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
@@ -1,15 +1,24 @@
 package controller
 
 import (
+	"context"
 	"net/http"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/features/stats"
 
+	"github.com/remnawave/node-go/internal/checkpoint"
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/limitmon"
 	"github.com/remnawave/node-go/internal/logger"
 	"github.com/remnawave/node-go/internal/xray"
 )
@@ -22,6 +31,11 @@ type UsernameRequest struct {
 	Username string `json:"username" binding:"required"`
 }
 
+type UsernameListRequest struct {
+	Usernames []string `json:"usernames" binding:"required"`
+	Reset     bool     `json:"reset"`
+}
+
 type TagResetRequest struct {
 	Tag   string `json:"tag" binding:"required"`
 	Reset bool   `json:"reset"`
@@ -37,6 +51,14 @@ type SystemStatsResponse struct {
 	Frees        uint64 `json:"frees"`
 	LiveObjects  uint64 `json:"liveObjects"`
 	Uptime       int64  `json:"uptime"`
+	// OpenFiles and MaxOpenFiles are the file descriptors of the node
+	// and its soft limit. ConntrackCount and ConntrackMax are the kernel
+	// connection tracking table usage, when nf_conntrack is loaded. Both
+	// are Linux only.
+	OpenFiles      uint64 `json:"openFiles,omitempty"`
+	MaxOpenFiles   uint64 `json:"maxOpenFiles,omitempty"`
+	ConntrackCount uint64 `json:"conntrackCount,omitempty"`
+	ConntrackMax   uint64 `json:"conntrackMax,omitempty"`
 }
 
 type UserStats struct {
@@ -45,14 +67,45 @@ type UserStats struct {
 	Downlink int64  `json:"downlink"`
 }
 
+// UsersStatsRequest filters and paginates get-users-stats. Without any of
+// the optional fields every user with traffic is returned.
+type UsersStatsRequest struct {
+	Reset          bool     `json:"reset"`
+	UsernamePrefix string   `json:"usernamePrefix"`
+	Usernames      []string `json:"usernames"`
+	// MinTraffic is the minimum uplink plus downlink in bytes.
+	MinTraffic int64 `json:"minTraffic"`
+	// Limit is the maximum number of users returned, 0 for no limit.
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+func (r UsersStatsRequest) filtered() bool {
+	return r.UsernamePrefix != "" || r.Usernames != nil || r.MinTraffic > 0 || r.Limit > 0 || r.Offset > 0
+}
+
 type UsersStatsResponse struct {
 	Users []UserStats `json:"users"`
+	// Total is the number of users matching the filters, before pagination.
+	Total int `json:"total"`
 }
 
 type UserOnlineResponse struct {
 	Online bool `json:"online"`
 }
 
+type InboundOnlineUsers struct {
+	Inbound string   `json:"inbound"`
+	Count   int      `json:"count"`
+	Users   []string `json:"users"`
+}
+
+type OnlineUsersResponse struct {
+	Inbounds []InboundOnlineUsers `json:"inbounds"`
+	// Total is the number of distinct online users.
+	Total int `json:"total"`
+}
+
 type InboundStatsResponse struct {
 	Inbound  string `json:"inbound"`
 	Uplink   int64  `json:"uplink"`
@@ -90,29 +143,40 @@ type CombinedStatsResponse struct {
 	Outbounds []OutboundEntry `json:"outbounds"`
 }
 
+type StatsHistoryResponse struct {
+	Snapshots []history.Snapshot `json:"snapshots"`
+}
+
 type StatsController struct {
-	core      *xray.Core
-	logger    *logger.Logger
-	startTime time.Time
+	core       *xray.Core
+	history    *history.Recorder
+	checkpoint *checkpoint.Checkpoint
+	logger     *logger.Logger
+	startTime  time.Time
 }
 
-func NewStatsController(core *xray.Core, log *logger.Logger) *StatsController {
+func NewStatsController(core *xray.Core, recorder *history.Recorder, trafficCheckpoint *checkpoint.Checkpoint, log *logger.Logger) *StatsController {
 	return &StatsController{
-		core:      core,
-		logger:    log,
-		startTime: time.Now(),
+		core:       core,
+		history:    recorder,
+		checkpoint: trafficCheckpoint,
+		logger:     log,
+		startTime:  time.Now(),
 	}
 }
 
 func (c *StatsController) RegisterRoutes(group *gin.RouterGroup) {
 	group.GET("/get-system-stats", c.handleGetSystemStats)
 	group.POST("/get-users-stats", c.handleGetUsersStats)
+	group.POST("/get-users-stats-by-list", c.handleGetUsersStatsByList)
 	group.POST("/get-user-online-status", c.handleGetUserOnlineStatus)
+	group.GET("/get-online-users", c.handleGetOnlineUsers)
 	group.POST("/get-inbound-stats", c.handleGetInboundStats)
 	group.POST("/get-outbound-stats", c.handleGetOutboundStats)
 	group.POST("/get-all-inbounds-stats", c.handleGetAllInboundsStats)
 	group.POST("/get-all-outbounds-stats", c.handleGetAllOutboundsStats)
 	group.POST("/get-combined-stats", c.handleGetCombinedStats)
+	group.GET("/history", c.handleGetHistory)
 }
 
 func (c *StatsController) getStatsManager() stats.Manager {
@@ -158,79 +222,120 @@ func (c *StatsController) getCounterValue(stm stats.Manager, name string, reset
 	if counter == nil {
 		return 0
 	}
-	value := counter.Value()
+	return readCounter(counter, reset)
+}
+
+// readCounter returns the counter value, resetting it to zero when reset is
+// set. The reset is a single atomic swap, so bytes added concurrently are
+// either returned now or kept for the next read, never dropped.
+func readCounter(counter stats.Counter, reset bool) int64 {
 	if reset {
-		counter.Set(0)
+		return counter.Set(0)
 	}
-	return value
+	return counter.Value()
 }
 
-func (c *StatsController) collectTrafficStats(stm *appstats.Manager, prefix string, reset bool) map[string]map[string]int64 {
-	result := make(map[string]map[string]int64)
-
-	stm.VisitCounters(func(name string, counter stats.Counter) bool {
-		if !strings.HasPrefix(name, prefix) {
-			return true
-		}
-
-		parts := strings.Split(name, ">>>")
-		if len(parts) < 4 {
-			return true
-		}
-
-		tag := parts[1]
-		if parts[2] != "traffic" {
-			return true
-		}
-		direction := parts[3]
+// parseTrafficCounter splits a traffic counter name such as
+// "user>>>alice>>>traffic>>>uplink" into its parts. The parts are
+// substrings of name, so parsing does not allocate.
+func parseTrafficCounter(name string) (kind, tag, direction string, ok bool) {
+	kind, rest, ok := strings.Cut(name, ">>>")
+	if !ok {
+		return "", "", "", false
+	}
+	tag, rest, ok = strings.Cut(rest, ">>>")
+	if !ok {
+		return "", "", "", false
+	}
+	category, rest, ok := strings.Cut(rest, ">>>")
+	if !ok || category != "traffic" {
+		return "", "", "", false
+	}
+	direction, _, _ = strings.Cut(rest, ">>>")
+	return kind, tag, direction, true
+}
 
-		if result[tag] == nil {
-			result[tag] = make(map[string]int64)
-		}
+// addTraffic stores value as the direction of the traffic of key.
+func addTraffic(result map[string]checkpoint.Traffic, key, direction string, value int64) {
+	traffic := result[key]
+	switch direction {
+	case "uplink":
+		traffic.Uplink = value
+	case "downlink":
+		traffic.Downlink = value
+	}
+	result[key] = traffic
+}
 
-		value := counter.Value()
-		if reset {
-			counter.Set(0)
-		}
+// trafficMaps recycles the maps traffic is collected into; with tens of
+// thousands of users, growing a fresh map on every poll dominates the cost
+// of collecting stats.
+var trafficMaps = sync.Pool{
+	New: func() any { return make(map[string]checkpoint.Traffic) },
+}
 
-		result[tag][direction] = value
-		return true
-	})
+func getTrafficMap() map[string]checkpoint.Traffic {
+	return trafficMaps.Get().(map[string]checkpoint.Traffic)
+}
 
-	return result
+// putTrafficMap returns a map obtained from getTrafficMap once nothing
+// refers to it anymore.
+func putTrafficMap(m map[string]checkpoint.Traffic) {
+	if m == nil {
+		return
+	}
+	clear(m)
+	trafficMaps.Put(m)
 }
 
-func (c *StatsController) collectUserStats(stm *appstats.Manager, reset bool) map[string]*UserStats {
-	userTraffic := make(map[string]*UserStats)
+// collectTrafficStats reads the inbound and/or outbound traffic counters in
+// a single pass, keyed by tag. The map of a kind not requested is nil; the
+// others are released with putTrafficMap.
+func (c *StatsController) collectTrafficStats(stm *appstats.Manager, reset, withInbounds, withOutbounds bool) (inbounds, outbounds map[string]checkpoint.Traffic) {
+	if withInbounds {
+		inbounds = getTrafficMap()
+	}
+	if withOutbounds {
+		outbounds = getTrafficMap()
+	}
 
 	stm.VisitCounters(func(name string, counter stats.Counter) bool {
-		if !strings.HasPrefix(name, "user>>>") {
+		// Most counters belong to users; skip them before parsing.
+		var result map[string]checkpoint.Traffic
+		switch {
+		case strings.HasPrefix(name, "inbound>>>"):
+			result = inbounds
+		case strings.HasPrefix(name, "outbound>>>"):
+			result = outbounds
+		}
+		if result == nil {
 			return true
 		}
 
-		parts := strings.Split(name, ">>>")
-		if len(parts) < 4 || parts[2] != "traffic" {
+		_, tag, direction, ok := parseTrafficCounter(name)
+		if !ok {
 			return true
 		}
 
-		username := parts[1]
-		direction := parts[3]
+		addTraffic(result, tag, direction, readCounter(counter, reset))
+		return true
+	})
 
-		value := counter.Value()
-		if reset {
-			counter.Set(0)
-		}
+	return inbounds, outbounds
+}
 
-		if userTraffic[username] == nil {
-			userTraffic[username] = &UserStats{Username: username}
-		}
+// collectUserStats reads the user traffic counters, keyed by username. The
+// map is released with putTrafficMap.
+func (c *StatsController) collectUserStats(stm *appstats.Manager, reset bool) map[string]checkpoint.Traffic {
+	userTraffic := getTrafficMap()
 
-		if direction == "uplink" {
-			userTraffic[username].Uplink = value
-		} else if direction == "downlink" {
-			userTraffic[username].Downlink = value
+	stm.VisitCounters(func(name string, counter stats.Counter) bool {
+		kind, username, direction, ok := parseTrafficCounter(name)
+		if !ok || kind != "user" {
+			return true
 		}
 
+		addTraffic(userTraffic, username, direction, readCounter(counter, reset))
 		return true
 	})
 
@@ -238,12 +343,18 @@ func (c *StatsController) collectUserStats(stm *appstats.Manager, reset bool) ma
 }
 
 func (c *StatsController) handleGetSystemStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(c.SystemStats()))
+}
+
+// SystemStats reports Go runtime memory stats, the node uptime and its
+// usage of its resource limits.
+func (c *StatsController) SystemStats() SystemStatsResponse {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
 	uptime := int64(time.Since(c.startTime).Seconds())
 
-	ctx.JSON(http.StatusOK, wrapResponse(SystemStatsResponse{
+	resp := SystemStatsResponse{
 		NumGoroutine: runtime.NumGoroutine(),
 		NumGC:        memStats.NumGC,
 		Alloc:        memStats.Alloc,
@@ -253,44 +364,222 @@ func (c *StatsController) handleGetSystemStats(ctx *gin.Context) {
 		Frees:        memStats.Frees,
 		LiveObjects:  memStats.Mallocs - memStats.Frees,
 		Uptime:       uptime,
-	}))
+	}
+	for _, usage := range limitmon.Read() {
+		switch usage.Resource {
+		case limitmon.ResourceOpenFiles:
+			resp.OpenFiles, resp.MaxOpenFiles = usage.Used, usage.Limit
+		case limitmon.ResourceConntrack:
+			resp.ConntrackCount, resp.ConntrackMax = usage.Used, usage.Limit
+		}
+	}
+	return resp
 }
 
 func (c *StatsController) handleGetUsersStats(ctx *gin.Context) {
-	var req ResetRequest
+	var req UsersStatsRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		req.Reset = false
+		req = UsersStatsRequest{}
+	}
+	if req.Limit < 0 || req.Offset < 0 || req.MinTraffic < 0 {
+		errMsg := "limit, offset and minTraffic must not be negative"
+		respondError(ctx, http.StatusBadRequest, apperrors.CodeInvalidRequest, errMsg)
+		return
 	}
 
-	stm := c.getConcreteStatsManager()
-	if stm == nil {
-		ctx.JSON(http.StatusOK, wrapResponse(UsersStatsResponse{
-			Users: []UserStats{},
-		}))
+	if !req.filtered() {
+		ctx.JSON(http.StatusOK, wrapResponse(c.UsersStats(req.Reset)))
 		return
 	}
+	ctx.JSON(http.StatusOK, wrapResponse(c.FilteredUsersStats(req)))
+}
 
-	userTraffic := c.collectUserStats(stm, req.Reset)
+// UsersStats returns the traffic of users with non-zero counters,
+// optionally resetting the counters. Traffic checkpointed before an xray or
+// node restart and not collected yet is included.
+func (c *StatsController) UsersStats(reset bool) UsersStatsResponse {
+	traffic := c.checkpoint.Collect(reset, func() map[string]checkpoint.Traffic {
+		stm := c.getConcreteStatsManager()
+		if stm == nil {
+			return nil
+		}
 
-	users := make([]UserStats, 0, len(userTraffic))
-	for _, userStats := range userTraffic {
-		if userStats.Uplink > 0 || userStats.Downlink > 0 {
-			users = append(users, *userStats)
+		return c.collectUserStats(stm, reset)
+	})
+	defer putTrafficMap(traffic)
+
+	users := make([]UserStats, 0, len(traffic))
+	for username, userTraffic := range traffic {
+		if userTraffic.Uplink > 0 || userTraffic.Downlink > 0 {
+			users = append(users, UserStats{
+				Username: username,
+				Uplink:   userTraffic.Uplink,
+				Downlink: userTraffic.Downlink,
+			})
 		}
 	}
 
-	ctx.JSON(http.StatusOK, wrapResponse(UsersStatsResponse{
+	return UsersStatsResponse{
 		Users: users,
-	}))
+		Total: len(users),
+	}
+}
+
+// FilteredUsersStats is UsersStats for the users matching the filters of
+// req, sorted by username and paginated. With reset only the counters of
+// the returned users are reset, so a panel can drain a large node page by
+// page by repeating the request with offset 0.
+func (c *StatsController) FilteredUsersStats(req UsersStatsRequest) UsersStatsResponse {
+	var resp UsersStatsResponse
+
+	c.checkpoint.CollectUsers(req.Reset, func(carried map[string]checkpoint.Traffic) []string {
+		stm := c.getConcreteStatsManager()
+
+		var traffic map[string]checkpoint.Traffic
+		if stm != nil {
+			traffic = c.collectUserStats(stm, false)
+		} else {
+			traffic = getTrafficMap()
+		}
+		defer putTrafficMap(traffic)
+		for username, t := range carried {
+			total := traffic[username]
+			total.Uplink += t.Uplink
+			total.Downlink += t.Downlink
+			traffic[username] = total
+		}
+
+		page, total := selectUsers(traffic, req)
+		resp.Total = total
+		resp.Users = make([]UserStats, 0, len(page))
+		for _, username := range page {
+			t := traffic[username]
+			if req.Reset && stm != nil {
+				// Read the counters again while resetting them, so traffic
+				// counted since the first read is reported rather than lost.
+				t = carried[username]
+				t.Uplink += c.getCounterValue(stm, "user>>>"+username+">>>traffic>>>uplink", true)
+				t.Downlink += c.getCounterValue(stm, "user>>>"+username+">>>traffic>>>downlink", true)
+			}
+			resp.Users = append(resp.Users, UserStats{
+				Username: username,
+				Uplink:   t.Uplink,
+				Downlink: t.Downlink,
+			})
+		}
+		return page
+	})
+
+	return resp
+}
+
+func (c *StatsController) handleGetUsersStatsByList(ctx *gin.Context) {
+	var req UsernameListRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse get-users-stats-by-list request")
+		respondInvalid(ctx, err, "invalid request body: "+bindErrorMessage(err), UsersStatsResponse{
+			Users: []UserStats{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(c.UsersStatsByList(req.Usernames, req.Reset)))
+}
+
+// UsersStatsByList returns the traffic of the given users, looking their
+// counters up by name instead of scanning all counters. Users without
+// counters or checkpointed traffic are left out; with reset only the
+// counters of the listed users are reset.
+func (c *StatsController) UsersStatsByList(usernames []string, reset bool) UsersStatsResponse {
+	resp := UsersStatsResponse{Users: make([]UserStats, 0, len(usernames))}
+
+	c.checkpoint.CollectUsers(reset, func(carried map[string]checkpoint.Traffic) []string {
+		stm := c.getStatsManager()
+
+		reported := make([]string, 0, len(usernames))
+		seen := make(map[string]struct{}, len(usernames))
+		for _, username := range usernames {
+			if _, ok := seen[username]; ok {
+				continue
+			}
+			seen[username] = struct{}{}
+
+			traffic, found := carried[username]
+			if stm != nil {
+				prefix := "user>>>" + username + ">>>traffic>>>"
+				if counter := stm.GetCounter(prefix + "uplink"); counter != nil {
+					traffic.Uplink += readCounter(counter, reset)
+					found = true
+				}
+				if counter := stm.GetCounter(prefix + "downlink"); counter != nil {
+					traffic.Downlink += readCounter(counter, reset)
+					found = true
+				}
+			}
+			if !found {
+				continue
+			}
+
+			reported = append(reported, username)
+			resp.Users = append(resp.Users, UserStats{
+				Username: username,
+				Uplink:   traffic.Uplink,
+				Downlink: traffic.Downlink,
+			})
+		}
+		return reported
+	})
+
+	resp.Total = len(resp.Users)
+	return resp
+}
+
+// selectUsers returns the page of the users with traffic matching the
+// filters of req, sorted by username, and the number of matching users.
+func selectUsers(traffic map[string]checkpoint.Traffic, req UsersStatsRequest) (page []string, total int) {
+	var wanted map[string]struct{}
+	if req.Usernames != nil {
+		wanted = make(map[string]struct{}, len(req.Usernames))
+		for _, username := range req.Usernames {
+			wanted[username] = struct{}{}
+		}
+	}
+
+	matched := make([]string, 0, len(traffic))
+	for username, t := range traffic {
+		if t.Uplink <= 0 && t.Downlink <= 0 {
+			continue
+		}
+		if t.Uplink+t.Downlink < req.MinTraffic || !strings.HasPrefix(username, req.UsernamePrefix) {
+			continue
+		}
+		if wanted != nil {
+			if _, ok := wanted[username]; !ok {
+				continue
+			}
+		}
+		matched = append(matched, username)
+	}
+	sort.Strings(matched)
+
+	total = len(matched)
+	if req.Offset >= total {
+		return nil, total
+	}
+	matched = matched[req.Offset:]
+	if req.Limit > 0 && req.Limit < len(matched) {
+		matched = matched[:req.Limit]
+	}
+	return matched, total
 }
 
 func (c *StatsController) handleGetUserOnlineStatus(ctx *gin.Context) {
 	var req UsernameRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse get-user-online-status request")
-		ctx.JSON(http.StatusBadRequest, wrapResponse(UserOnlineResponse{
+		respondInvalid(ctx, err, "invalid request body: "+bindErrorMessage(err), UserOnlineResponse{
 			Online: false,
-		}))
+		})
 		return
 	}
 
@@ -310,15 +599,62 @@ func (c *StatsController) handleGetUserOnlineStatus(ctx *gin.Context) {
 	}))
 }
 
+func (c *StatsController) handleGetOnlineUsers(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(c.OnlineUsers()))
+}
+
+// OnlineUsers returns, per inbound, the users xray currently sees
+// connections from. Users are only tracked as online when the xray policy
+// enables statsUserOnline.
+func (c *StatsController) OnlineUsers() OnlineUsersResponse {
+	resp := OnlineUsersResponse{Inbounds: []InboundOnlineUsers{}}
+
+	instance := c.core.Instance()
+	if instance == nil {
+		return resp
+	}
+	stm, ok := instance.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return resp
+	}
+	ibm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return resp
+	}
+
+	online := make(map[string]struct{})
+	for _, name := range stm.GetAllOnlineUsers() {
+		if username, ok := strings.CutPrefix(name, "user>>>"); ok {
+			online[strings.TrimSuffix(username, ">>>online")] = struct{}{}
+		}
+	}
+	resp.Total = len(online)
+
+	inboundUsers := xray.NewUserManager(ibm, c.logger).InboundUsers(context.Background())
+	for tag, usernames := range inboundUsers {
+		entry := InboundOnlineUsers{Inbound: tag, Users: []string{}}
+		for _, username := range usernames {
+			if _, ok := online[username]; ok {
+				entry.Users = append(entry.Users, username)
+			}
+		}
+		entry.Count = len(entry.Users)
+		resp.Inbounds = append(resp.Inbounds, entry)
+	}
+	sort.Slice(resp.Inbounds, func(i, j int) bool { return resp.Inbounds[i].Inbound < resp.Inbounds[j].Inbound })
+
+	return resp
+}
+
 func (c *StatsController) handleGetInboundStats(ctx *gin.Context) {
 	var req TagResetRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse get-inbound-stats request")
-		ctx.JSON(http.StatusBadRequest, wrapResponse(InboundStatsResponse{
+		respondInvalid(ctx, err, "invalid request body: "+bindErrorMessage(err), InboundStatsResponse{
 			Inbound:  "",
 			Uplink:   0,
 			Downlink: 0,
-		}))
+		})
 		return
 	}
 
@@ -349,11 +685,11 @@ func (c *StatsController) handleGetOutboundStats(ctx *gin.Context) {
 	var req TagResetRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse get-outbound-stats request")
-		ctx.JSON(http.StatusBadRequest, wrapResponse(OutboundStatsResponse{
+		respondInvalid(ctx, err, "invalid request body: "+bindErrorMessage(err), OutboundStatsResponse{
 			Outbound: "",
 			Uplink:   0,
 			Downlink: 0,
-		}))
+		})
 		return
 	}
 
@@ -394,16 +730,10 @@ func (c *StatsController) handleGetAllInboundsStats(ctx *gin.Context) {
 		return
 	}
 
-	trafficData := c.collectTrafficStats(stm, "inbound>>>", req.Reset)
+	trafficData, _ := c.collectTrafficStats(stm, req.Reset, true, false)
+	defer putTrafficMap(trafficData)
 
-	inbounds := make([]InboundEntry, 0, len(trafficData))
-	for tag, traffic := range trafficData {
-		inbounds = append(inbounds, InboundEntry{
-			Inbound:  tag,
-			Uplink:   traffic["uplink"],
-			Downlink: traffic["downlink"],
-		})
-	}
+	inbounds := inboundEntries(trafficData)
 
 	ctx.JSON(http.StatusOK, wrapResponse(AllInboundsStatsResponse{
 		Inbounds: inbounds,
@@ -424,16 +754,10 @@ func (c *StatsController) handleGetAllOutboundsStats(ctx *gin.Context) {
 		return
 	}
 
-	trafficData := c.collectTrafficStats(stm, "outbound>>>", req.Reset)
+	_, trafficData := c.collectTrafficStats(stm, req.Reset, false, true)
+	defer putTrafficMap(trafficData)
 
-	outbounds := make([]OutboundEntry, 0, len(trafficData))
-	for tag, traffic := range trafficData {
-		outbounds = append(outbounds, OutboundEntry{
-			Outbound: tag,
-			Uplink:   traffic["uplink"],
-			Downlink: traffic["downlink"],
-		})
-	}
+	outbounds := outboundEntries(trafficData)
 
 	ctx.JSON(http.StatusOK, wrapResponse(AllOutboundsStatsResponse{
 		Outbounds: outbounds,
@@ -446,38 +770,90 @@ func (c *StatsController) handleGetCombinedStats(ctx *gin.Context) {
 		req.Reset = false
 	}
 
+	ctx.JSON(http.StatusOK, wrapResponse(c.CombinedStats(req.Reset)))
+}
+
+// CombinedStats returns the traffic of all inbounds and outbounds,
+// optionally resetting the counters.
+func (c *StatsController) CombinedStats(reset bool) CombinedStatsResponse {
 	stm := c.getConcreteStatsManager()
 	if stm == nil {
-		ctx.JSON(http.StatusOK, wrapResponse(CombinedStatsResponse{
+		return CombinedStatsResponse{
 			Inbounds:  []InboundEntry{},
 			Outbounds: []OutboundEntry{},
-		}))
+		}
+	}
+
+	inboundData, outboundData := c.collectTrafficStats(stm, reset, true, true)
+	defer putTrafficMap(inboundData)
+	defer putTrafficMap(outboundData)
+
+	inbounds := inboundEntries(inboundData)
+	outbounds := outboundEntries(outboundData)
+
+	return CombinedStatsResponse{
+		Inbounds:  inbounds,
+		Outbounds: outbounds,
+	}
+}
+
+// handleGetHistory returns the per-minute traffic snapshots between the
+// optional from and to query parameters (unix seconds, inclusive).
+func (c *StatsController) handleGetHistory(ctx *gin.Context) {
+	from, err := parseUnixQuery(ctx, "from")
+	if err != nil {
+		errMsg := "invalid from: " + err.Error()
+		respondError(ctx, http.StatusBadRequest, apperrors.CodeInvalidRequest, errMsg)
 		return
 	}
 
-	inboundData := c.collectTrafficStats(stm, "inbound>>>", req.Reset)
-	outboundData := c.collectTrafficStats(stm, "outbound>>>", req.Reset)
+	to, err := parseUnixQuery(ctx, "to")
+	if err != nil {
+		errMsg := "invalid to: " + err.Error()
+		respondError(ctx, http.StatusBadRequest, apperrors.CodeInvalidRequest, errMsg)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(StatsHistoryResponse{
+		Snapshots: c.history.Range(from, to),
+	}))
+}
+
+// parseUnixQuery parses a unix timestamp query parameter.
+// A missing parameter yields the zero time.
+func parseUnixQuery(ctx *gin.Context, name string) (time.Time, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(seconds, 0), nil
+}
 
-	inbounds := make([]InboundEntry, 0, len(inboundData))
-	for tag, traffic := range inboundData {
-		inbounds = append(inbounds, InboundEntry{
+func inboundEntries(traffic map[string]checkpoint.Traffic) []InboundEntry {
+	entries := make([]InboundEntry, 0, len(traffic))
+	for tag, t := range traffic {
+		entries = append(entries, InboundEntry{
 			Inbound:  tag,
-			Uplink:   traffic["uplink"],
-			Downlink: traffic["downlink"],
+			Uplink:   t.Uplink,
+			Downlink: t.Downlink,
 		})
 	}
+	return entries
+}
 
-	outbounds := make([]OutboundEntry, 0, len(outboundData))
-	for tag, traffic := range outboundData {
-		outbounds = append(outbounds, OutboundEntry{
+func outboundEntries(traffic map[string]checkpoint.Traffic) []OutboundEntry {
+	entries := make([]OutboundEntry, 0, len(traffic))
+	for tag, t := range traffic {
+		entries = append(entries, OutboundEntry{
 			Outbound: tag,
-			Uplink:   traffic["uplink"],
-			Downlink: traffic["downlink"],
+			Uplink:   t.Uplink,
+			Downlink: t.Downlink,
 		})
 	}
-
-	ctx.JSON(http.StatusOK, wrapResponse(CombinedStatsResponse{
-		Inbounds:  inbounds,
-		Outbounds: outbounds,
-	}))
+	return entries
 }
@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appstats "github.com/xtls/xray-core/app/stats"
+	xlog "github.com/xtls/xray-core/common/log"
+	"github.com/xtls/xray-core/features/inbound"
+
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func TestReadCounter(t *testing.T) {
+	counter := new(appstats.Counter)
+	counter.Add(42)
+
+	assert.Equal(t, int64(42), readCounter(counter, false))
+	assert.Equal(t, int64(42), counter.Value())
+
+	assert.Equal(t, int64(42), readCounter(counter, true))
+	assert.Equal(t, int64(0), counter.Value())
+}
+
+func TestReadCounterResetIsExactUnderLoad(t *testing.T) {
+	counter := new(appstats.Counter)
+
+	const writers, adds = 8, 10000
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < adds; j++ {
+				counter.Add(1)
+			}
+		}()
+	}
+
+	var collected int64
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		collected += readCounter(counter, true)
+	}
+
+	assert.Equal(t, int64(writers*adds), collected)
+}
+
+func TestParseTrafficCounter(t *testing.T) {
+	kind, tag, direction, ok := parseTrafficCounter("user>>>alice@example.com>>>traffic>>>downlink")
+	assert.True(t, ok)
+	assert.Equal(t, "user", kind)
+	assert.Equal(t, "alice@example.com", tag)
+	assert.Equal(t, "downlink", direction)
+
+	for _, name := range []string{"user>>>alice>>>online", "inbound>>>vless", "user>>>alice>>>traffic", "plain"} {
+		_, _, _, ok := parseTrafficCounter(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestCollectStats(t *testing.T) {
+	stm, err := appstats.NewManager(context.Background(), &appstats.Config{})
+	require.NoError(t, err)
+	for name, value := range map[string]int64{
+		"user>>>alice>>>traffic>>>uplink":      10,
+		"user>>>alice>>>traffic>>>downlink":    20,
+		"user>>>bob>>>traffic>>>downlink":      5,
+		"user>>>bob>>>online":                  1,
+		"inbound>>>vless>>>traffic>>>uplink":   7,
+		"outbound>>>direct>>>traffic>>>uplink": 3,
+	} {
+		counter, err := stm.RegisterCounter(name)
+		require.NoError(t, err)
+		counter.Add(value)
+	}
+	c := &StatsController{}
+
+	users := c.collectUserStats(stm, false)
+	assert.Equal(t, map[string]checkpoint.Traffic{
+		"alice": {Uplink: 10, Downlink: 20},
+		"bob":   {Downlink: 5},
+	}, users)
+	putTrafficMap(users)
+
+	inbounds, outbounds := c.collectTrafficStats(stm, true, true, false)
+	assert.Equal(t, map[string]checkpoint.Traffic{"vless": {Uplink: 7}}, inbounds)
+	assert.Nil(t, outbounds)
+	putTrafficMap(inbounds)
+
+	// Only the requested kind is reset.
+	inbounds, outbounds = c.collectTrafficStats(stm, false, true, true)
+	assert.Equal(t, map[string]checkpoint.Traffic{"vless": {}}, inbounds)
+	assert.Equal(t, map[string]checkpoint.Traffic{"direct": {Uplink: 3}}, outbounds)
+}
+
+func TestSelectUsers(t *testing.T) {
+	traffic := map[string]checkpoint.Traffic{
+		"alice": {Uplink: 100, Downlink: 100},
+		"anna":  {Uplink: 10},
+		"bob":   {Downlink: 500},
+		"carol": {},
+		"dave":  {Uplink: 1},
+	}
+
+	page, total := selectUsers(traffic, UsersStatsRequest{})
+	assert.Equal(t, []string{"alice", "anna", "bob", "dave"}, page)
+	assert.Equal(t, 4, total)
+
+	page, total = selectUsers(traffic, UsersStatsRequest{UsernamePrefix: "a", MinTraffic: 50})
+	assert.Equal(t, []string{"alice"}, page)
+	assert.Equal(t, 1, total)
+
+	page, _ = selectUsers(traffic, UsersStatsRequest{Usernames: []string{"bob", "carol", "eve"}})
+	assert.Equal(t, []string{"bob"}, page)
+
+	page, total = selectUsers(traffic, UsersStatsRequest{Limit: 2, Offset: 1})
+	assert.Equal(t, []string{"anna", "bob"}, page)
+	assert.Equal(t, 4, total)
+
+	page, total = selectUsers(traffic, UsersStatsRequest{Offset: 10})
+	assert.Empty(t, page)
+	assert.Equal(t, 4, total)
+}
+
+func TestFilteredUsersStatsResetsReturnedUsersOnly(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	require.NoError(t, core.Start([]byte(`{"log":{"loglevel":"none"},"stats":{},"outbounds":[{"protocol":"freedom"}]}`)))
+	defer core.Stop()
+
+	c := NewStatsController(core, nil, checkpoint.New(core, state.NewMemoryStore(), log), log)
+	stm := c.getConcreteStatsManager()
+	require.NotNil(t, stm)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		counter, err := stm.RegisterCounter("user>>>" + username + ">>>traffic>>>uplink")
+		require.NoError(t, err)
+		counter.Add(10)
+	}
+
+	resp := c.FilteredUsersStats(UsersStatsRequest{Reset: true, Limit: 2})
+	assert.Equal(t, []UserStats{{Username: "alice", Uplink: 10}, {Username: "bob", Uplink: 10}}, resp.Users)
+	assert.Equal(t, 3, resp.Total)
+
+	resp = c.FilteredUsersStats(UsersStatsRequest{Reset: true, Limit: 2})
+	assert.Equal(t, []UserStats{{Username: "carol", Uplink: 10}}, resp.Users)
+	assert.Equal(t, 1, resp.Total)
+}
+
+func TestUsersStatsByList(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	require.NoError(t, core.Start([]byte(`{"log":{"loglevel":"none"},"stats":{},"outbounds":[{"protocol":"freedom"}]}`)))
+	defer core.Stop()
+
+	c := NewStatsController(core, nil, checkpoint.New(core, state.NewMemoryStore(), log), log)
+	stm := c.getConcreteStatsManager()
+	require.NotNil(t, stm)
+	for name, value := range map[string]int64{
+		"user>>>alice>>>traffic>>>uplink":   10,
+		"user>>>alice>>>traffic>>>downlink": 20,
+		"user>>>bob>>>traffic>>>uplink":     5,
+	} {
+		counter, err := stm.RegisterCounter(name)
+		require.NoError(t, err)
+		counter.Add(value)
+	}
+
+	resp := c.UsersStatsByList([]string{"alice", "eve", "alice"}, true)
+	assert.Equal(t, []UserStats{{Username: "alice", Uplink: 10, Downlink: 20}}, resp.Users)
+
+	resp = c.UsersStatsByList([]string{"alice", "bob"}, false)
+	assert.Equal(t, []UserStats{{Username: "alice"}, {Username: "bob", Uplink: 5}}, resp.Users)
+}
+
+func TestOnlineUsers(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	require.NoError(t, core.Start([]byte(`{
+		"log": {"loglevel": "none"},
+		"stats": {},
+		"inbounds": [
+			{"tag": "vless-in", "listen": "127.0.0.1", "port": 0, "protocol": "vless", "settings": {"clients": [], "decryption": "none"}},
+			{"tag": "empty-in", "listen": "127.0.0.1", "port": 0, "protocol": "vless", "settings": {"clients": [], "decryption": "none"}}
+		],
+		"outbounds": [{"protocol": "freedom"}]
+	}`)))
+	defer core.Stop()
+
+	c := NewStatsController(core, nil, checkpoint.New(core, state.NewMemoryStore(), log), log)
+	ibm := core.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager)
+	users := xray.NewUserManager(ibm, log)
+	for _, username := range []string{"alice", "bob"} {
+		require.NoError(t, users.AddUser(context.Background(), "vless-in", xray.BuildVlessUser(username, "550e8400-e29b-41d4-a716-446655440000", "", 0)))
+	}
+
+	onlineMap, err := c.getConcreteStatsManager().RegisterOnlineMap("user>>>alice>>>online")
+	require.NoError(t, err)
+	onlineMap.AddIP("198.51.100.7")
+
+	resp := c.OnlineUsers()
+	assert.Equal(t, 1, resp.Total)
+	assert.Equal(t, []InboundOnlineUsers{
+		{Inbound: "empty-in", Count: 0, Users: []string{}},
+		{Inbound: "vless-in", Count: 1, Users: []string{"alice"}},
+	}, resp.Inbounds)
+}
+
+type discardLogHandler struct{}
+
+func (discardLogHandler) Handle(xlog.Message) {}
+
+// newBenchStatsManager registers the counters of a large node: users
+// uplink and downlink counters plus a few inbounds and outbounds.
+func newBenchStatsManager(b *testing.B, users int) *appstats.Manager {
+	b.Helper()
+
+	// Keep xray from logging every registered counter.
+	xlog.RegisterHandler(discardLogHandler{})
+
+	stm, err := appstats.NewManager(context.Background(), &appstats.Config{})
+	require.NoError(b, err)
+
+	register := func(name string, value int64) {
+		counter, err := stm.RegisterCounter(name)
+		require.NoError(b, err)
+		counter.Add(value)
+	}
+	for i := 0; i < users; i++ {
+		username := "user-" + strconv.Itoa(i)
+		register("user>>>"+username+">>>traffic>>>uplink", int64(i+1))
+		register("user>>>"+username+">>>traffic>>>downlink", int64(2*i+1))
+	}
+	for i := 0; i < 16; i++ {
+		tag := "tag-" + strconv.Itoa(i)
+		register("inbound>>>"+tag+">>>traffic>>>uplink", 1)
+		register("inbound>>>"+tag+">>>traffic>>>downlink", 1)
+		register("outbound>>>"+tag+">>>traffic>>>uplink", 1)
+		register("outbound>>>"+tag+">>>traffic>>>downlink", 1)
+	}
+	return stm
+}
+
+func BenchmarkCollectUserStats(b *testing.B) {
+	stm := newBenchStatsManager(b, 25000)
+	c := &StatsController{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		putTrafficMap(c.collectUserStats(stm, false))
+	}
+}
+
+func BenchmarkCollectTrafficStats(b *testing.B) {
+	stm := newBenchStatsManager(b, 25000)
+	c := &StatsController{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inbounds, outbounds := c.collectTrafficStats(stm, false, true, true)
+		putTrafficMap(inbounds)
+		putTrafficMap(outbounds)
+	}
+}
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+)
+
+func init() {
+	// Report fields by their JSON names, as the panel sends them.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// validationErrors returns one entry per invalid field of a binding error,
+// none when err is not about fields, e.g. malformed JSON.
+func validationErrors(err error) []apperrors.ValidationError {
+	var fieldErrors validator.ValidationErrors
+	if errors.As(err, &fieldErrors) {
+		result := make([]apperrors.ValidationError, 0, len(fieldErrors))
+		for _, fe := range fieldErrors {
+			result = append(result, apperrors.ValidationError{
+				Path:    fieldPath(fe.Namespace()),
+				Message: fieldMessage(fe),
+			})
+		}
+		return result
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return []apperrors.ValidationError{{
+			Path:    strings.Split(typeError.Field, "."),
+			Message: "must be " + jsonTypeName(typeError.Type),
+		}}
+	}
+	return nil
+}
+
+// bindErrorMessage describes a binding error, field by field when
+// possible: "data.0.username: is required; ipLimit: must be 0 or more".
+func bindErrorMessage(err error) string {
+	fields := validationErrors(err)
+	if len(fields) == 0 {
+		return err.Error()
+	}
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, strings.Join(field.Path, ".")+": "+field.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// respondInvalid answers a request that failed to bind. With coded errors,
+// field errors are answered with a validation error body listing them;
+// otherwise it is respond with CodeInvalidRequest and errMsg.
+func respondInvalid(ctx *gin.Context, err error, errMsg string, data interface{}) {
+	if ctx.GetBool(CodedErrorsKey) {
+		if fields := validationErrors(err); len(fields) > 0 {
+			ctx.JSON(http.StatusBadRequest, apperrors.NewValidationResponse(fields))
+			return
+		}
+	}
+	respond(ctx, http.StatusBadRequest, apperrors.CodeInvalidRequest, &errMsg, data)
+}
+
+// fieldPath splits a validator namespace, such as
+// "AddUserRequest.data[0].username", into ["data", "0", "username"],
+// leaving out the request type.
+func fieldPath(namespace string) []string {
+	_, rest, found := strings.Cut(namespace, ".")
+	if !found {
+		rest = namespace
+	}
+
+	path := make([]string, 0, 4)
+	for _, part := range strings.Split(rest, ".") {
+		name, index, hasIndex := strings.Cut(part, "[")
+		path = append(path, name)
+		for hasIndex {
+			var key string
+			key, index, _ = strings.Cut(index, "]")
+			path = append(path, key)
+			_, index, hasIndex = strings.Cut(index, "[")
+		}
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if unit := sizeUnit(fe.Kind()); unit != "" {
+			return "must have at least " + fe.Param() + " " + unit
+		}
+		return "must be " + fe.Param() + " or more"
+	case "max":
+		if unit := sizeUnit(fe.Kind()); unit != "" {
+			return "must have at most " + fe.Param() + " " + unit
+		}
+		return "must be " + fe.Param() + " or less"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "ip":
+		return "must be an IP address"
+	case "cidr":
+		return "must be a CIDR range"
+	case "uuid":
+		return "must be a UUID"
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed the %s=%s check", fe.Tag(), fe.Param())
+	}
+	return "failed the " + fe.Tag() + " check"
+}
+
+// sizeUnit is what min and max count for values of kind, empty for
+// numbers.
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	}
+	return ""
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
+)
+
+func bindAddUser(t *testing.T, body string) error {
+	t.Helper()
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	var req AddUserRequest
+	err := ctx.ShouldBindJSON(&req)
+	require.Error(t, err)
+	return err
+}
+
+func TestValidationErrors_FieldErrors(t *testing.T) {
+	err := bindAddUser(t, `{"data": [{"tag": "vless-in"}], "ipLimit": -1}`)
+
+	assert.ElementsMatch(t, []apperrors.ValidationError{
+		{Path: []string{"data", "0", "username"}, Message: "is required"},
+		{Path: []string{"data", "0", "type"}, Message: "is required"},
+		{Path: []string{"ipLimit"}, Message: "must be 0 or more"},
+	}, validationErrors(err))
+	assert.Contains(t, bindErrorMessage(err), "data.0.username: is required")
+}
+
+func TestValidationErrors_TypeError(t *testing.T) {
+	err := bindAddUser(t, `{"data": [], "ipLimit": "many"}`)
+
+	assert.Equal(t, []apperrors.ValidationError{
+		{Path: []string{"ipLimit"}, Message: "must be an integer"},
+	}, validationErrors(err))
+}
+
+func TestValidationErrors_MalformedJSON(t *testing.T) {
+	err := bindAddUser(t, `{"data":`)
+
+	assert.Empty(t, validationErrors(err))
+	assert.Equal(t, err.Error(), bindErrorMessage(err))
+}
+
+func TestRespondInvalid(t *testing.T) {
+	err := bindAddUser(t, `{"data": [{"tag": "vless-in"}]}`)
+
+	for _, coded := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(w)
+		ctx.Request = httptest.NewRequest(http.MethodPost, "/node/handler/add-user", nil)
+		ctx.Set(CodedErrorsKey, coded)
+
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, AddUserResponseData{Error: &errMsg})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		if coded {
+			assert.Equal(t, "Validation failed", body["message"])
+			assert.Len(t, body["errors"], 2)
+		} else {
+			assert.Contains(t, body, "response")
+		}
+	}
+}
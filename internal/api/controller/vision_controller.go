@@ -1,19 +1,24 @@
 package controller
 
 import (
-	"crypto/md5"
-	"encoding/hex"
-	"net"
+	"errors"
 	"net/http"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	apperrors "github.com/remnawave/node-go/internal/errors"
 	"github.com/remnawave/node-go/internal/logger"
-	"github.com/remnawave/node-go/internal/xray"
+	"github.com/remnawave/node-go/internal/vision"
 )
 
 type BlockIPRequest struct {
+	IP         string `json:"ip" binding:"required"`
+	TTLSeconds int64  `json:"ttlSeconds" binding:"omitempty,min=0"`
+}
+
+type UnblockIPRequest struct {
 	IP string `json:"ip" binding:"required"`
 }
 
@@ -22,84 +27,69 @@ type BlockIPResponse struct {
 	Error   *string `json:"error"`
 }
 
+type ListBlockedIPsQuery struct {
+	Prefix string `form:"prefix"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1"`
+	Offset int    `form:"offset" binding:"omitempty,min=0"`
+}
+
+type ListBlockedIPsResponse struct {
+	Total   int            `json:"total"`
+	Count   int            `json:"count"`
+	IPs     []string       `json:"ips"`
+	Entries []vision.Entry `json:"entries"`
+}
+
 type VisionController struct {
-	core       *xray.Core
-	logger     *logger.Logger
-	blockedIPs map[string]string
-	mu         sync.RWMutex
+	blocklist *vision.Blocklist
+	logger    *logger.Logger
 }
 
-func NewVisionController(core *xray.Core, log *logger.Logger) *VisionController {
+func NewVisionController(blocklist *vision.Blocklist, log *logger.Logger) *VisionController {
 	return &VisionController{
-		core:       core,
-		logger:     log,
-		blockedIPs: make(map[string]string),
+		blocklist: blocklist,
+		logger:    log,
 	}
 }
 
 func (c *VisionController) RegisterRoutes(group *gin.RouterGroup) {
 	group.POST("/block-ip", c.handleBlockIP)
 	group.POST("/unblock-ip", c.handleUnblockIP)
-}
-
-func (c *VisionController) getIPHash(ip string) string {
-	hash := md5.Sum([]byte(ip))
-	return hex.EncodeToString(hash[:])
+	group.GET("/blocked-ips", c.handleListBlockedIPs)
 }
 
 func (c *VisionController) handleBlockIP(ctx *gin.Context) {
 	var req BlockIPRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse block-ip request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(BlockIPResponse{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, BlockIPResponse{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
-	if net.ParseIP(req.IP) == nil {
-		errMsg := "invalid IP address format"
-		ctx.JSON(http.StatusBadRequest, wrapResponse(BlockIPResponse{
-			Success: false,
-			Error:   &errMsg,
-		}))
-		return
-	}
-
-	ruleTag := c.getIPHash(req.IP)
-
-	c.mu.Lock()
-	_, alreadyBlocked := c.blockedIPs[ruleTag]
-	if alreadyBlocked {
-		c.mu.Unlock()
-		ctx.JSON(http.StatusOK, wrapResponse(BlockIPResponse{
-			Success: true,
-			Error:   nil,
-		}))
-		return
-	}
-	c.blockedIPs[ruleTag] = req.IP
-	c.mu.Unlock()
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if _, err := c.blocklist.Block(req.IP, ttl); err != nil {
+		if errors.Is(err, vision.ErrInvalidSource) {
+			errMsg := "invalid IP address format"
+			respond(ctx, http.StatusBadRequest, apperrors.CodeVisionBlocklistError, &errMsg, BlockIPResponse{
+				Success: false,
+				Error:   &errMsg,
+			})
+			return
+		}
 
-	if err := c.core.AddRoutingRule(ruleTag, req.IP, "BLOCK"); err != nil {
 		c.logger.WithError(err).WithField("ip", req.IP).Error("Failed to add routing rule")
-
-		c.mu.Lock()
-		delete(c.blockedIPs, ruleTag)
-		c.mu.Unlock()
-
 		errMsg := "failed to block IP: " + err.Error()
-		ctx.JSON(http.StatusInternalServerError, wrapResponse(BlockIPResponse{
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeVisionBlocklistError, &errMsg, BlockIPResponse{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
-	c.logger.WithField("ip", req.IP).WithField("ruleTag", ruleTag).Info("IP blocked")
-
 	ctx.JSON(http.StatusOK, wrapResponse(BlockIPResponse{
 		Success: true,
 		Error:   nil,
@@ -107,62 +97,81 @@ func (c *VisionController) handleBlockIP(ctx *gin.Context) {
 }
 
 func (c *VisionController) handleUnblockIP(ctx *gin.Context) {
-	var req BlockIPRequest
+	var req UnblockIPRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		c.logger.WithError(err).Error("Failed to parse unblock-ip request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(BlockIPResponse{
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, BlockIPResponse{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
-	if net.ParseIP(req.IP) == nil {
+	if _, err := c.blocklist.Unblock(req.IP); err != nil {
 		errMsg := "invalid IP address format"
-		ctx.JSON(http.StatusBadRequest, wrapResponse(BlockIPResponse{
+		respond(ctx, http.StatusBadRequest, apperrors.CodeVisionBlocklistError, &errMsg, BlockIPResponse{
 			Success: false,
 			Error:   &errMsg,
-		}))
+		})
 		return
 	}
 
-	ruleTag := c.getIPHash(req.IP)
+	ctx.JSON(http.StatusOK, wrapResponse(BlockIPResponse{
+		Success: true,
+		Error:   nil,
+	}))
+}
 
-	c.mu.Lock()
-	_, wasBlocked := c.blockedIPs[ruleTag]
-	delete(c.blockedIPs, ruleTag)
-	c.mu.Unlock()
+func (c *VisionController) handleListBlockedIPs(ctx *gin.Context) {
+	var query ListBlockedIPsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		c.logger.WithError(err).Error("Failed to parse blocked-ips query")
+		errMsg := "invalid query: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, BlockIPResponse{
+			Success: false,
+			Error:   &errMsg,
+		})
+		return
+	}
 
-	if wasBlocked {
-		if err := c.core.RemoveRoutingRule(ruleTag); err != nil {
-			c.logger.WithError(err).WithField("ip", req.IP).Warn("Failed to remove routing rule")
+	filtered := make([]vision.Entry, 0)
+	for _, entry := range c.blocklist.List() {
+		if strings.HasPrefix(entry.IP, query.Prefix) {
+			filtered = append(filtered, entry)
 		}
 	}
 
-	c.logger.WithField("ip", req.IP).WithField("ruleTag", ruleTag).Info("IP unblocked")
+	total := len(filtered)
+	start := min(query.Offset, total)
+	end := total
+	if query.Limit > 0 && query.Limit < total-start {
+		end = start + query.Limit
+	}
+	page := filtered[start:end]
 
-	ctx.JSON(http.StatusOK, wrapResponse(BlockIPResponse{
-		Success: true,
-		Error:   nil,
+	ips := make([]string, 0, len(page))
+	for _, entry := range page {
+		ips = append(ips, entry.IP)
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(ListBlockedIPsResponse{
+		Total:   total,
+		Count:   len(page),
+		IPs:     ips,
+		Entries: page,
 	}))
 }
 
 func (c *VisionController) GetBlockedIPs() []string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	ips := make([]string, 0, len(c.blockedIPs))
-	for _, ip := range c.blockedIPs {
-		ips = append(ips, ip)
+	entries := c.blocklist.List()
+	ips := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ips = append(ips, entry.IP)
 	}
 	return ips
 }
 
 func (c *VisionController) IsBlocked(ip string) bool {
-	ruleTag := c.getIPHash(ip)
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	_, blocked := c.blockedIPs[ruleTag]
-	return blocked
+	return c.blocklist.IsBlocked(ip)
 }
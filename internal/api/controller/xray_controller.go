@@ -2,14 +2,28 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xtls/xray-core/features/stats"
 
+	"github.com/remnawave/node-go/internal/certmon"
+	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/jsonpatch"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/maintenance"
+	"github.com/remnawave/node-go/internal/telemetry"
+	"github.com/remnawave/node-go/internal/version"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
@@ -21,10 +35,7 @@ func wrapResponse(data interface{}) successResponse {
 	return successResponse{Response: data}
 }
 
-const (
-	NodeVersion = "1.0.0"
-	APIPort     = 61012
-)
+const healthcheckDialTimeout = time.Second
 
 type StartRequest struct {
 	XrayConfig map[string]interface{} `json:"xrayConfig" binding:"required"`
@@ -32,7 +43,13 @@ type StartRequest struct {
 }
 
 type NodeInfo struct {
-	Version string `json:"version"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+}
+
+func nodeInfo() NodeInfo {
+	return NodeInfo{Version: version.Version, Commit: version.Commit, BuildTime: version.BuildTime}
 }
 
 type SystemInfo struct {
@@ -49,6 +66,10 @@ type StartResponse struct {
 	Error      *string     `json:"error"`
 	SystemInfo *SystemInfo `json:"systemInfo"`
 	NodeInfo   NodeInfo    `json:"nodeInfo"`
+	// RestartDeferred is set when the config needs a core restart that waits
+	// for the maintenance window; the running core keeps the previous
+	// config meanwhile.
+	RestartDeferred bool `json:"restartDeferred,omitempty"`
 }
 
 type StopResponse struct {
@@ -58,27 +79,118 @@ type StopResponse struct {
 type StatusResponse struct {
 	IsRunning bool    `json:"isRunning"`
 	Version   *string `json:"version"`
+	// EmptyConfigHash is the hash of the applied config without users.
+	EmptyConfigHash string `json:"emptyConfigHash"`
+	// Inbounds are the user counts of the inbounds the panel manages.
+	Inbounds []InboundUserCount `json:"inbounds"`
+	// Uptime is the xray uptime in seconds, 0 while stopped.
+	Uptime   int64 `json:"uptime"`
+	Restarts int   `json:"restarts"`
+	// LastStartError is the last failed start or crash of xray, if any.
+	LastStartError   *string    `json:"lastStartError"`
+	LastStartErrorAt *time.Time `json:"lastStartErrorAt"`
+}
+
+type HealthcheckResult struct {
+	Name    string  `json:"name"`
+	Healthy bool    `json:"healthy"`
+	Skipped bool    `json:"skipped"`
+	Error   *string `json:"error"`
 }
 
 type HealthcheckResponse struct {
-	IsHealthy     bool    `json:"isHealthy"`
-	IsXrayRunning bool    `json:"isXrayRunning"`
-	XrayVersion   *string `json:"xrayVersion"`
-	NodeVersion   string  `json:"nodeVersion"`
+	IsHealthy     bool                `json:"isHealthy"`
+	IsXrayRunning bool                `json:"isXrayRunning"`
+	XrayVersion   *string             `json:"xrayVersion"`
+	NodeVersion   string              `json:"nodeVersion"`
+	NodeCommit    string              `json:"nodeCommit"`
+	NodeBuildTime string              `json:"nodeBuildTime"`
+	Checks        []HealthcheckResult `json:"checks"`
+	Certificates  []certmon.Info      `json:"certificates"`
+}
+
+type ValidateRequest struct {
+	XrayConfig map[string]interface{} `json:"xrayConfig" binding:"required"`
+}
+
+// ValidateResponse reports whether a start with the config would load it.
+// Errors lists the problems found otherwise.
+type ValidateResponse struct {
+	Valid  bool               `json:"valid"`
+	Errors []xray.ConfigError `json:"errors"`
+	Error  *string            `json:"error"`
+}
+
+type DiffConfigRequest struct {
+	XrayConfig map[string]interface{} `json:"xrayConfig" binding:"required"`
+}
+
+// GenRealityKeysRequest optionally names a REALITY inbound whose keys to
+// rotate. Without one, a key pair is only generated.
+type GenRealityKeysRequest struct {
+	InboundTag   string `json:"inboundTag"`
+	ShortIDCount int    `json:"shortIdCount" binding:"omitempty,min=0,max=16"`
+}
+
+type GenRealityKeysResponse struct {
+	PrivateKey string   `json:"privateKey"`
+	PublicKey  string   `json:"publicKey"`
+	ShortIDs   []string `json:"shortIds"`
+	Rotated    bool     `json:"rotated"`
+	Error      *string  `json:"error"`
+}
+
+// Actions PatchConfig takes to put a patched config into effect.
+const (
+	patchActionNone    = "none"
+	patchActionReload  = "reload"
+	patchActionRestart = "restart"
+)
+
+// PatchConfigResponse reports how a JSON Patch was put into effect: not at
+// all for a no-op, by reloading the inbounds whose users changed, or by
+// restarting the core. Hashes are those of the resulting state.
+type PatchConfigResponse struct {
+	Applied bool               `json:"applied"`
+	Action  string             `json:"action"`
+	Diff    *xray.ConfigDiff   `json:"diff"`
+	Hashes  *xray.Hashes       `json:"hashes"`
+	Errors  []xray.ConfigError `json:"errors"`
+	Error   *string            `json:"error"`
 }
 
+var errNotRealityInbound = errors.New("inbound does not use REALITY")
+
 type XrayController struct {
 	core          *xray.Core
 	configManager *xray.ConfigManager
+	certs         *certmon.Monitor
+	apiPort       int
+	placeholders  *xray.Placeholders
+	jobs          *jobs.Manager
 	logger        *logger.Logger
 	startMu       sync.Mutex
 	isProcessing  atomic.Bool
+	draining      atomic.Bool
+
+	// maintenance defers the core restarts of config changes; pendingStart
+	// is the start request waiting for the window. Both are guarded by
+	// startMu.
+	maintenance  *maintenance.Scheduler
+	pendingStart *StartRequest
 }
 
-func NewXrayController(core *xray.Core, configManager *xray.ConfigManager, log *logger.Logger) *XrayController {
+// restartAction names the deferred core restarts.
+const restartAction = "xray-restart"
+
+func NewXrayController(core *xray.Core, configManager *xray.ConfigManager, certs *certmon.Monitor, apiPort int, placeholders *xray.Placeholders, jobManager *jobs.Manager, log *logger.Logger) *XrayController {
 	return &XrayController{
 		core:          core,
 		configManager: configManager,
+		certs:         certs,
+		apiPort:       apiPort,
+		placeholders:  placeholders,
+		jobs:          jobManager,
 		logger:        log,
 	}
 }
@@ -88,140 +200,667 @@ func (c *XrayController) RegisterRoutes(group *gin.RouterGroup) {
 	group.GET("/stop", c.handleStop)
 	group.GET("/status", c.handleStatus)
 	group.GET("/healthcheck", c.handleHealthcheck)
+	group.POST("/validate", c.handleValidate)
+	group.POST("/diff-config", c.handleDiffConfig)
+	group.POST("/patch-config", c.handlePatchConfig)
+	group.POST("/gen-reality-keys", c.handleGenRealityKeys)
+}
+
+// DeferRestarts makes start requests needing a restart of the running core
+// wait for the maintenance window of scheduler. Requests the panel forces
+// and first starts are never deferred.
+func (c *XrayController) DeferRestarts(scheduler *maintenance.Scheduler) {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	c.maintenance = scheduler
+}
+
+// Drain rejects start requests from now on and waits for one in progress,
+// for a node shutting down: a core started now would be stopped right away.
+func (c *XrayController) Drain() {
+	c.draining.Store(true)
+	c.startMu.Lock()
+	c.startMu.Unlock()
 }
 
 func (c *XrayController) handleStart(ctx *gin.Context) {
+	var req StartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse start request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, StartResponse{
+			IsStarted: false,
+			Error:     &errMsg,
+			NodeInfo:  nodeInfo(),
+		})
+		return
+	}
+
+	_, end := telemetry.Track(ctx.Request.Context(), "xray.start")
+	if isAsync(ctx) {
+		if status := submitJob(ctx, c.jobs, "start", apperrors.CodeStartXrayError, func(p *jobs.Progress) (interface{}, int, *string) {
+			resp, status := c.Start(req)
+			end(status)
+			return resp, status, resp.Error
+		}); status != http.StatusAccepted {
+			end(status)
+		}
+		return
+	}
+
+	resp, status := c.Start(req)
+	end(status)
+	respond(ctx, status, apperrors.CodeStartXrayError, resp.Error, resp)
+}
+
+// Start applies a start request from the panel and returns the response
+// together with the HTTP status it maps to. It is shared by the REST and
+// gRPC APIs. Placeholders in the config are resolved from the node settings
+// first.
+func (c *XrayController) Start(req StartRequest) (StartResponse, int) {
 	if !c.isProcessing.CompareAndSwap(false, true) {
 		c.logger.Warn("Start request already in progress, rejecting duplicate")
 		errMsg := "another start request is already in progress"
-		ctx.JSON(http.StatusConflict, wrapResponse(StartResponse{
+		return StartResponse{
 			IsStarted: false,
 			Error:     &errMsg,
-			NodeInfo:  NodeInfo{Version: NodeVersion},
-		}))
-		return
+			NodeInfo:  nodeInfo(),
+		}, http.StatusConflict
 	}
 	defer c.isProcessing.Store(false)
 
 	c.startMu.Lock()
 	defer c.startMu.Unlock()
 
-	var req StartRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		c.logger.WithError(err).Error("Failed to parse start request")
-		errMsg := "invalid request body: " + err.Error()
-		ctx.JSON(http.StatusBadRequest, wrapResponse(StartResponse{
+	if c.draining.Load() {
+		errMsg := "node is shutting down"
+		return StartResponse{
 			IsStarted: false,
 			Error:     &errMsg,
-			NodeInfo:  NodeInfo{Version: NodeVersion},
-		}))
-		return
+			NodeInfo:  nodeInfo(),
+		}, http.StatusServiceUnavailable
 	}
 
+	return c.start(req)
+}
+
+// start applies a start request with startMu held. A newer request always
+// supersedes a deferred one.
+func (c *XrayController) start(req StartRequest) (StartResponse, int) {
+	c.cancelDeferredRestart()
+
 	hashes := req.Internals.Hashes
 	forceRestart := req.Internals.ForceRestart
 
 	if c.core.IsRunning() && !forceRestart {
 		needRestart := c.configManager.IsNeedRestartCore(hashes)
 		if !needRestart {
-			version := c.core.GetVersion()
-			sysInfo := getSystemInfo()
-			ctx.JSON(http.StatusOK, wrapResponse(StartResponse{
-				IsStarted:  true,
-				Version:    &version,
-				SystemInfo: &sysInfo,
-				NodeInfo:   NodeInfo{Version: NodeVersion},
-			}))
-			return
+			return c.startedResponse(), http.StatusOK
 		}
-		c.logger.Info("Restart required - proceeding with xray core restart")
 	}
 
-	config := generateAPIConfig(req.XrayConfig)
+	xrayConfig, err := c.placeholders.Resolve(req.XrayConfig)
+	if err != nil {
+		c.logger.WithError(err).Error("Failed to resolve xray config placeholders")
+		errMsg := "failed to resolve placeholders: " + err.Error()
+		return StartResponse{
+			IsStarted: false,
+			Error:     &errMsg,
+			NodeInfo:  nodeInfo(),
+		}, http.StatusBadRequest
+	}
+
+	config := generateAPIConfig(xrayConfig, c.apiPort)
+
+	if c.core.IsRunning() && !forceRestart {
+		if tags, ok := c.configManager.InboundsToReload(hashes); ok {
+			err := c.reloadInbounds(config, hashes, tags)
+			if err == nil {
+				return c.startedResponse(), http.StatusOK
+			}
+			c.logger.WithError(err).Warn("Partial inbound reload failed - falling back to full restart")
+		}
+		if c.maintenance.Closed() {
+			c.pendingStart = &req
+			c.maintenance.Defer(restartAction, c.runDeferredRestart)
+			resp := c.startedResponse()
+			resp.RestartDeferred = true
+			return resp, http.StatusOK
+		}
+		c.logger.Info("Restart required - proceeding with xray core restart")
+	}
 
 	if err := c.configManager.ExtractUsersFromConfig(hashes, config); err != nil {
 		c.logger.WithError(err).Error("Failed to extract users from config")
 		errMsg := "failed to extract users: " + err.Error()
-		ctx.JSON(http.StatusInternalServerError, wrapResponse(StartResponse{
+		return StartResponse{
 			IsStarted: false,
 			Error:     &errMsg,
-			NodeInfo:  NodeInfo{Version: NodeVersion},
-		}))
-		return
+			NodeInfo:  nodeInfo(),
+		}, http.StatusInternalServerError
 	}
 
 	configJSON, err := json.Marshal(config)
 	if err != nil {
 		c.logger.WithError(err).Error("Failed to marshal xray config")
 		errMsg := "failed to serialize config: " + err.Error()
-		ctx.JSON(http.StatusInternalServerError, wrapResponse(StartResponse{
+		return StartResponse{
 			IsStarted: false,
 			Error:     &errMsg,
-			NodeInfo:  NodeInfo{Version: NodeVersion},
-		}))
-		return
+			NodeInfo:  nodeInfo(),
+		}, http.StatusInternalServerError
 	}
 
 	if err := c.core.Start(configJSON); err != nil {
 		c.logger.WithError(err).Error("Failed to start xray core")
 		errMsg := "failed to start xray: " + err.Error()
-		ctx.JSON(http.StatusInternalServerError, wrapResponse(StartResponse{
+		return StartResponse{
 			IsStarted: false,
 			Error:     &errMsg,
-			NodeInfo:  NodeInfo{Version: NodeVersion},
-		}))
-		return
+			NodeInfo:  nodeInfo(),
+		}, http.StatusInternalServerError
 	}
 
-	version := c.core.GetVersion()
-	sysInfo := getSystemInfo()
+	c.logger.WithField("version", c.core.GetVersion()).Info("Xray core started successfully")
+
+	return c.startedResponse(), http.StatusOK
+}
+
+// runDeferredRestart applies the start request deferred to the maintenance
+// window.
+func (c *XrayController) runDeferredRestart() {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	req := c.pendingStart
+	if req == nil || c.draining.Load() {
+		return
+	}
+	// Failures are logged by start.
+	c.start(*req)
+}
 
-	c.logger.WithField("version", version).Info("Xray core started successfully")
+// cancelDeferredRestart drops the start request waiting for the window, if
+// any. startMu must be held.
+func (c *XrayController) cancelDeferredRestart() {
+	if c.pendingStart == nil {
+		return
+	}
+	c.pendingStart = nil
+	c.maintenance.Cancel(restartAction)
+}
 
-	ctx.JSON(http.StatusOK, wrapResponse(StartResponse{
+func (c *XrayController) startedResponse() StartResponse {
+	xrayVersion := c.core.GetVersion()
+	sysInfo := getSystemInfo()
+	return StartResponse{
 		IsStarted:  true,
-		Version:    &version,
+		Version:    &xrayVersion,
 		SystemInfo: &sysInfo,
-		NodeInfo:   NodeInfo{Version: NodeVersion},
-	}))
+		NodeInfo:   nodeInfo(),
+	}
+}
+
+// reloadInbounds applies a config whose changes are limited to the users of
+// some inbounds by re-creating only those inbound handlers.
+func (c *XrayController) reloadInbounds(config map[string]interface{}, hashes xray.Hashes, tags []string) error {
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	if err := c.core.ReloadInbounds(configJSON, tags); err != nil {
+		return err
+	}
+
+	if err := c.configManager.ExtractUsersFromConfig(hashes, config); err != nil {
+		return err
+	}
+
+	c.logger.WithField("inbounds", tags).Info("Inbounds reloaded without core restart")
+
+	return nil
+}
+
+// Resume starts xray with the config restored from the previous run, if any,
+// so a restarted node keeps serving and the panel's next start command only
+// restarts the core when something actually changed.
+func (c *XrayController) Resume() error {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	config := c.configManager.GetXrayConfig()
+	if len(config) == 0 || c.core.IsRunning() {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		c.configManager.Cleanup()
+		return err
+	}
+
+	if err := c.core.Start(configJSON); err != nil {
+		c.configManager.Cleanup()
+		return err
+	}
+
+	c.logger.WithField("version", c.core.GetVersion()).Info("Xray core resumed from persisted config")
+
+	return nil
+}
+
+// Restart restarts the running core with the applied config, keeping the
+// users added at runtime, e.g. to release the memory it grew over time.
+func (c *XrayController) Restart() error {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	config := c.configManager.GetXrayConfig()
+	if len(config) == 0 || !c.core.IsRunning() || c.draining.Load() {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return c.core.RestartKeepingUsers(configJSON)
+}
+
+// ReloadRouting rebuilds the routing rules of the running core from the
+// applied config, e.g. after the geodata files they match against changed.
+func (c *XrayController) ReloadRouting() error {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	config := c.configManager.GetXrayConfig()
+	if len(config) == 0 || !c.core.IsRunning() {
+		return nil
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return c.core.ReloadRouting(configJSON)
 }
 
 func (c *XrayController) handleStop(ctx *gin.Context) {
+	_, end := telemetry.Track(ctx.Request.Context(), "xray.stop")
+	resp, status := c.Stop()
+	end(status)
+	respond(ctx, status, apperrors.CodeStopXrayError, nil, resp)
+}
+
+// Stop stops xray and forgets the applied config.
+func (c *XrayController) Stop() (StopResponse, int) {
 	c.startMu.Lock()
 	defer c.startMu.Unlock()
 
+	c.cancelDeferredRestart()
 	if err := c.core.Stop(); err != nil {
 		c.logger.WithError(err).Error("Failed to stop xray core")
-		ctx.JSON(http.StatusInternalServerError, wrapResponse(StopResponse{
+		return StopResponse{
 			IsStopped: false,
-		}))
-		return
+		}, http.StatusInternalServerError
 	}
 
 	c.configManager.Cleanup()
 
 	c.logger.Info("Xray core stopped and config manager cleaned up")
 
-	ctx.JSON(http.StatusOK, wrapResponse(StopResponse{
+	return StopResponse{
 		IsStopped: true,
+	}, http.StatusOK
+}
+
+// handleValidate checks a panel config the way a start would load it,
+// including its resolved placeholders and what generateAPIConfig injects,
+// without touching the running core.
+func (c *XrayController) handleValidate(ctx *gin.Context) {
+	var req ValidateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse validate request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, ValidateResponse{
+			Valid:  false,
+			Errors: []xray.ConfigError{},
+			Error:  &errMsg,
+		})
+		return
+	}
+
+	configErrors, err := CheckPanelConfig(req.XrayConfig, c.placeholders, c.apiPort)
+	if err != nil {
+		errMsg := "failed to serialize config: " + err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateXrayConfigError, &errMsg, ValidateResponse{
+			Valid:  false,
+			Errors: []xray.ConfigError{},
+			Error:  &errMsg,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, wrapResponse(ValidateResponse{
+		Valid:  len(configErrors) == 0,
+		Errors: configErrors,
+		Error:  nil,
 	}))
 }
 
+// CheckPanelConfig resolves the placeholders of a panel config, injects
+// what generateAPIConfig adds for apiPort and checks the result as a start
+// would load it. It returns every problem found, none for a valid config;
+// the error is only for a config that cannot be serialized.
+func CheckPanelConfig(xrayConfig map[string]interface{}, placeholders *xray.Placeholders, apiPort int) ([]xray.ConfigError, error) {
+	resolved, err := placeholders.Resolve(xrayConfig)
+	if err != nil {
+		return []xray.ConfigError{{Section: "placeholders", Message: err.Error()}}, nil
+	}
+
+	configJSON, err := json.Marshal(generateAPIConfig(resolved, apiPort))
+	if err != nil {
+		return nil, err
+	}
+
+	configErrors := xray.CheckConfig(configJSON)
+	if configErrors == nil {
+		configErrors = []xray.ConfigError{}
+	}
+	return configErrors, nil
+}
+
+// handleDiffConfig compares a panel config, as a start would apply it, with
+// the applied one.
+func (c *XrayController) handleDiffConfig(ctx *gin.Context) {
+	var req DiffConfigRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.logger.WithError(err).Error("Failed to parse diff-config request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, errorBody(errMsg))
+		return
+	}
+
+	xrayConfig, err := c.placeholders.Resolve(req.XrayConfig)
+	if err != nil {
+		errMsg := "failed to resolve placeholders: " + err.Error()
+		respondError(ctx, http.StatusBadRequest, apperrors.CodeUpdateXrayConfigError, errMsg)
+		return
+	}
+
+	incoming := generateAPIConfig(xrayConfig, c.apiPort)
+	ctx.JSON(http.StatusOK, wrapResponse(xray.DiffConfigs(c.configManager.GetXrayConfig(), incoming)))
+}
+
+// handlePatchConfig applies an RFC 6902 JSON Patch, sent as the request
+// body, to the applied config.
+func (c *XrayController) handlePatchConfig(ctx *gin.Context) {
+	var ops []jsonpatch.Operation
+	if err := ctx.ShouldBindJSON(&ops); err != nil {
+		c.logger.WithError(err).Error("Failed to parse patch-config request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, PatchConfigResponse{
+			Errors: []xray.ConfigError{},
+			Error:  &errMsg,
+		})
+		return
+	}
+
+	resp, status := c.PatchConfig(ops)
+	respond(ctx, status, apperrors.CodeUpdateXrayConfigError, resp.Error, resp)
+}
+
+// PatchConfig applies ops to the applied config and puts the result into
+// effect the cheapest way the diff allows: nothing for a no-op, reloading
+// the changed inbounds when only users changed, a core restart otherwise.
+// The patched config is validated first and rejected as a whole if invalid.
+func (c *XrayController) PatchConfig(ops []jsonpatch.Operation) (PatchConfigResponse, int) {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	resp := PatchConfigResponse{Errors: []xray.ConfigError{}}
+	fail := func(status int, msg string) (PatchConfigResponse, int) {
+		resp.Error = &msg
+		return resp, status
+	}
+
+	if !c.core.IsRunning() {
+		return fail(http.StatusConflict, "xray core not running")
+	}
+
+	// The applied config is resolved already, so only the values the patch
+	// brings in can hold placeholders.
+	for i := range ops {
+		value, err := c.placeholders.ResolveJSON(ops[i].Value)
+		if err != nil {
+			return fail(http.StatusBadRequest, "failed to resolve placeholders: "+err.Error())
+		}
+		ops[i].Value = value
+	}
+
+	applied := c.configManager.GetXrayConfig()
+	patchedRaw, err := jsonpatch.Apply(applied, ops)
+	if err != nil {
+		return fail(http.StatusBadRequest, "failed to apply patch: "+err.Error())
+	}
+	patched, ok := patchedRaw.(map[string]interface{})
+	if !ok {
+		return fail(http.StatusBadRequest, "patched config is not an object")
+	}
+
+	config := generateAPIConfig(patched, c.apiPort)
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fail(http.StatusInternalServerError, "failed to serialize config: "+err.Error())
+	}
+	if configErrors := xray.CheckConfig(configJSON); len(configErrors) > 0 {
+		resp.Errors = configErrors
+		return fail(http.StatusBadRequest, "patched config is invalid")
+	}
+
+	diff := xray.DiffConfigs(applied, config)
+	resp.Diff = &diff
+
+	switch {
+	case diff.Identical:
+		resp.Action = patchActionNone
+	case !diff.RestartRequired:
+		tags := make([]string, 0, len(diff.InboundsChanged))
+		for _, inboundDiff := range diff.InboundsChanged {
+			tags = append(tags, inboundDiff.Tag)
+		}
+		resp.Action = patchActionReload
+		if err := c.core.ReloadInbounds(configJSON, tags); err != nil {
+			c.logger.WithError(err).Warn("Inbound reload for patch failed - falling back to full restart")
+			resp.Action = patchActionRestart
+		}
+	default:
+		resp.Action = patchActionRestart
+	}
+
+	if resp.Action == patchActionRestart {
+		if err := c.core.Start(configJSON); err != nil {
+			c.logger.WithError(err).Error("Failed to restart xray with patched config")
+			if previous, marshalErr := json.Marshal(applied); marshalErr == nil {
+				if rollbackErr := c.core.Start(previous); rollbackErr != nil {
+					c.logger.WithError(rollbackErr).Error("Failed to restore previous xray config")
+				}
+			}
+			resp.Action = ""
+			return fail(http.StatusInternalServerError, "failed to start xray: "+err.Error())
+		}
+	}
+
+	if resp.Action != patchActionNone {
+		if err := c.configManager.ApplyPatchedConfig(config, diff.InboundsAdded, !diff.RestartRequired); err != nil {
+			return fail(http.StatusInternalServerError, "failed to record patched config: "+err.Error())
+		}
+		c.logger.WithField("action", resp.Action).WithField("operations", len(ops)).Info("Xray config patched")
+	}
+
+	hashes := c.configManager.CurrentHashes()
+	resp.Hashes = &hashes
+	resp.Applied = true
+	return resp, http.StatusOK
+}
+
+func (c *XrayController) handleGenRealityKeys(ctx *gin.Context) {
+	var req GenRealityKeysRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.logger.WithError(err).Error("Failed to parse gen-reality-keys request")
+		errMsg := "invalid request body: " + bindErrorMessage(err)
+		respondInvalid(ctx, err, errMsg, GenRealityKeysResponse{
+			Error: &errMsg,
+		})
+		return
+	}
+
+	privateKey, publicKey, err := xray.GenerateRealityKeys()
+	if err != nil {
+		errMsg := err.Error()
+		respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateXrayConfigError, &errMsg, GenRealityKeysResponse{
+			Error: &errMsg,
+		})
+		return
+	}
+
+	shortIDs := make([]string, 0, req.ShortIDCount)
+	for range req.ShortIDCount {
+		shortID, err := xray.GenerateShortID()
+		if err != nil {
+			errMsg := err.Error()
+			respond(ctx, http.StatusInternalServerError, apperrors.CodeUpdateXrayConfigError, &errMsg, GenRealityKeysResponse{
+				Error: &errMsg,
+			})
+			return
+		}
+		shortIDs = append(shortIDs, shortID)
+	}
+
+	resp := GenRealityKeysResponse{
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		ShortIDs:   shortIDs,
+	}
+	if req.InboundTag == "" {
+		ctx.JSON(http.StatusOK, wrapResponse(resp))
+		return
+	}
+
+	status, err := c.rotateRealityKeys(req.InboundTag, privateKey, &resp.ShortIDs)
+	if err != nil {
+		c.logger.WithError(err).WithField("inbound", req.InboundTag).Error("Failed to rotate REALITY keys")
+		errMsg := "failed to rotate REALITY keys: " + err.Error()
+		resp.Error = &errMsg
+		respond(ctx, status, apperrors.CodeUpdateXrayConfigError, resp.Error, resp)
+		return
+	}
+
+	resp.Rotated = true
+	c.logger.WithField("inbound", req.InboundTag).Info("REALITY keys rotated")
+	ctx.JSON(http.StatusOK, wrapResponse(resp))
+}
+
+// rotateRealityKeys sets privateKey on the REALITY inbound tagged tag and,
+// if *shortIDs is not empty, its shortIds, then restarts the inbound keeping
+// its users. *shortIDs is set to the shortIds in effect. The panel's hashes
+// do not cover the keys, so the rotation lasts until it next restarts the
+// core.
+func (c *XrayController) rotateRealityKeys(tag, privateKey string, shortIDs *[]string) (int, error) {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+
+	if !c.core.IsRunning() {
+		return http.StatusConflict, errors.New("xray core not running")
+	}
+
+	status := http.StatusInternalServerError
+	err := c.configManager.UpdateInbound(tag, func(inbound map[string]interface{}) error {
+		streamSettings, _ := inbound["streamSettings"].(map[string]interface{})
+		realitySettings, _ := streamSettings["realitySettings"].(map[string]interface{})
+		if realitySettings == nil {
+			status = http.StatusBadRequest
+			return errNotRealityInbound
+		}
+
+		realitySettings["privateKey"] = privateKey
+		if len(*shortIDs) > 0 {
+			ids := make([]interface{}, 0, len(*shortIDs))
+			for _, id := range *shortIDs {
+				ids = append(ids, id)
+			}
+			realitySettings["shortIds"] = ids
+			return nil
+		}
+
+		current, _ := realitySettings["shortIds"].([]interface{})
+		for _, id := range current {
+			if s, ok := id.(string); ok {
+				*shortIDs = append(*shortIDs, s)
+			}
+		}
+		return nil
+	}, func(config map[string]interface{}) error {
+		configJSON, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+		return c.core.RestartInbound(configJSON, tag)
+	})
+	if errors.Is(err, xray.ErrInboundNotFound) {
+		status = http.StatusNotFound
+	}
+	return status, err
+}
+
 func (c *XrayController) handleStatus(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(c.Status()))
+}
+
+// Status reports whether xray is running, its version, the applied config
+// and the history of its starts.
+func (c *XrayController) Status() StatusResponse {
 	isRunning := c.core.IsRunning()
-	var version *string
+	var xrayVersion *string
 	if isRunning {
 		v := c.core.GetVersion()
-		version = &v
+		xrayVersion = &v
 	}
 
-	ctx.JSON(http.StatusOK, wrapResponse(StatusResponse{
-		IsRunning: isRunning,
-		Version:   version,
-	}))
+	hashes := c.configManager.CurrentHashes()
+	resp := StatusResponse{
+		IsRunning:       isRunning,
+		Version:         xrayVersion,
+		EmptyConfigHash: hashes.EmptyConfig,
+		Inbounds:        make([]InboundUserCount, 0, len(hashes.Inbounds)),
+	}
+	for _, inbound := range hashes.Inbounds {
+		resp.Inbounds = append(resp.Inbounds, InboundUserCount{Tag: inbound.Tag, Users: inbound.UsersCount})
+	}
+
+	info := c.core.StartInfo()
+	if !info.StartedAt.IsZero() {
+		resp.Uptime = int64(time.Since(info.StartedAt).Seconds())
+	}
+	resp.Restarts = info.Restarts
+	if info.LastError != nil {
+		errMsg := info.LastError.Error()
+		resp.LastStartError = &errMsg
+		resp.LastStartErrorAt = &info.LastErrorAt
+	}
+
+	return resp
 }
 
 func (c *XrayController) handleHealthcheck(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, wrapResponse(c.Healthcheck()))
+}
+
+// Healthcheck probes the node and the running xray.
+func (c *XrayController) Healthcheck() HealthcheckResponse {
 	isRunning := c.core.IsRunning()
 	var xrayVersion *string
 	if isRunning {
@@ -229,12 +868,127 @@ func (c *XrayController) handleHealthcheck(ctx *gin.Context) {
 		xrayVersion = &v
 	}
 
-	ctx.JSON(http.StatusOK, wrapResponse(HealthcheckResponse{
-		IsHealthy:     true,
+	checks := []HealthcheckResult{
+		c.runHealthcheck("xrayApi", !isRunning, c.checkAPIInbound),
+		c.runHealthcheck("statsManager", !isRunning, c.checkStatsManager),
+		c.runHealthcheck("tlsCertificate", false, c.checkCertificate),
+	}
+
+	isHealthy := true
+	for _, check := range checks {
+		if !check.Healthy && !check.Skipped {
+			isHealthy = false
+		}
+	}
+
+	return HealthcheckResponse{
+		IsHealthy:     isHealthy,
 		IsXrayRunning: isRunning,
 		XrayVersion:   xrayVersion,
-		NodeVersion:   NodeVersion,
-	}))
+		NodeVersion:   version.Version,
+		NodeCommit:    version.Commit,
+		NodeBuildTime: version.BuildTime,
+		Checks:        checks,
+		Certificates:  c.certificates(),
+	}
+}
+
+// runHealthcheck runs a single probe. Probes that depend on xray are skipped
+// while the core is stopped, which is a valid state for an idle node.
+func (c *XrayController) runHealthcheck(name string, skip bool, probe func() error) HealthcheckResult {
+	if skip {
+		return HealthcheckResult{Name: name, Skipped: true}
+	}
+
+	if err := probe(); err != nil {
+		c.logger.WithError(err).WithField("check", name).Warn("Healthcheck failed")
+		errMsg := err.Error()
+		return HealthcheckResult{Name: name, Healthy: false, Error: &errMsg}
+	}
+
+	return HealthcheckResult{Name: name, Healthy: true}
+}
+
+// checkAPIInbound verifies the xray api inbound accepts connections.
+func (c *XrayController) checkAPIInbound() error {
+	address := apiInboundAddress(c.configManager.GetXrayConfig(), c.apiPort)
+
+	conn, err := net.DialTimeout("tcp", address, healthcheckDialTimeout)
+	if err != nil {
+		return fmt.Errorf("api inbound %s not reachable: %w", address, err)
+	}
+	return conn.Close()
+}
+
+// checkStatsManager verifies the running instance exposes its stats manager.
+func (c *XrayController) checkStatsManager() error {
+	instance := c.core.Instance()
+	if instance == nil {
+		return errors.New("xray core not running")
+	}
+
+	if _, ok := instance.GetFeature(stats.ManagerType()).(stats.Manager); !ok {
+		return errors.New("stats manager not available")
+	}
+	return nil
+}
+
+// checkCertificate verifies the node certificate is currently valid.
+func (c *XrayController) checkCertificate() error {
+	if c.certs == nil {
+		return errors.New("node certificate not loaded")
+	}
+	cert, ok := c.certs.Certificate(certmon.NameNode)
+	if !ok {
+		return errors.New("node certificate not loaded")
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("node certificate not valid before %s", cert.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("node certificate expired at %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// certificates lists the expiry of the node certificate and CA.
+func (c *XrayController) certificates() []certmon.Info {
+	if c.certs == nil {
+		return []certmon.Info{}
+	}
+	return c.certs.Certificates()
+}
+
+// apiInboundAddress returns the address of the "api" inbound in config,
+// falling back to the one injected by generateAPIConfig on apiPort.
+func apiInboundAddress(config map[string]interface{}, apiPort int) string {
+	host := "127.0.0.1"
+	port := strconv.Itoa(apiPort)
+
+	inbounds, _ := config["inbounds"].([]interface{})
+	for _, inbound := range inbounds {
+		ib, ok := inbound.(map[string]interface{})
+		if !ok || ib["tag"] != "api" {
+			continue
+		}
+
+		if listen, ok := ib["listen"].(string); ok && listen != "" && listen != "0.0.0.0" && listen != "::" {
+			host = listen
+		}
+		switch p := ib["port"].(type) {
+		case float64:
+			port = strconv.Itoa(int(p))
+		case int:
+			port = strconv.Itoa(p)
+		case string:
+			port = p
+		}
+		break
+	}
+
+	return net.JoinHostPort(host, port)
 }
 
 func getSystemInfo() SystemInfo {
@@ -247,7 +1001,9 @@ func getSystemInfo() SystemInfo {
 	}
 }
 
-func generateAPIConfig(config map[string]interface{}) map[string]interface{} {
+// generateAPIConfig injects the api inbound on apiPort, its routing rule and
+// the stats settings the node relies on into a panel config.
+func generateAPIConfig(config map[string]interface{}, apiPort int) map[string]interface{} {
 	result := make(map[string]interface{})
 	for k, v := range config {
 		result[k] = v
@@ -255,7 +1011,7 @@ func generateAPIConfig(config map[string]interface{}) map[string]interface{} {
 
 	apiInbound := map[string]interface{}{
 		"tag":      "api",
-		"port":     APIPort,
+		"port":     apiPort,
 		"listen":   "127.0.0.1",
 		"protocol": "dokodemo-door",
 		"settings": map[string]interface{}{
@@ -384,7 +1140,8 @@ func generateAPIConfig(config map[string]interface{}) map[string]interface{} {
 	// Enable user stats (required for per-user traffic tracking)
 	existingLevel0["statsUserUplink"] = true
 	existingLevel0["statsUserDownlink"] = true
-	existingLevel0["statsUserOnline"] = false
+	// Online IP tracking is required for per-user IP limits
+	existingLevel0["statsUserOnline"] = true
 
 	existingLevels["0"] = existingLevel0
 	existingPolicy["levels"] = existingLevels
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func TestCheckPanelConfig(t *testing.T) {
+	placeholders, err := xray.ParsePlaceholders("PORT=443", "")
+	require.NoError(t, err)
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "in",
+				"port":     "${PORT}",
+				"protocol": "vless",
+				"settings": map[string]interface{}{"clients": []interface{}{}, "decryption": "none"},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+
+	configErrors, err := CheckPanelConfig(config, placeholders, 61012)
+	require.NoError(t, err)
+	assert.Empty(t, configErrors)
+
+	unset, err := xray.ParsePlaceholders("", "")
+	require.NoError(t, err)
+	configErrors, err = CheckPanelConfig(config, unset, 61012)
+	require.NoError(t, err)
+	require.Len(t, configErrors, 1)
+	assert.Equal(t, "placeholders", configErrors[0].Section)
+
+	config["outbounds"] = []interface{}{
+		map[string]interface{}{"tag": "broken", "protocol": "nope"},
+	}
+	configErrors, err = CheckPanelConfig(config, placeholders, 61012)
+	require.NoError(t, err)
+	require.Len(t, configErrors, 1)
+	assert.Equal(t, "outbounds[0]", configErrors[0].Section)
+	assert.Equal(t, "broken", configErrors[0].Tag)
+}
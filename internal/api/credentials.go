@@ -0,0 +1,148 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/revocation"
+)
+
+// nodeCredentials are the TLS materials taken from SECRET_KEY, with the
+// panel CAs trusted besides its own.
+type nodeCredentials struct {
+	cert   *tls.Certificate
+	caPool *x509.CertPool
+	cas    []*x509.Certificate
+}
+
+func parseNodeCredentials(cfg *config.Config) (*nodeCredentials, error) {
+	cert, err := cfg.Payload.KeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	creds := &nodeCredentials{cert: &cert, caPool: x509.NewCertPool()}
+	for _, caPEM := range cfg.PanelCAs() {
+		found := false
+		for rest := []byte(caPEM); ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			ca, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+			}
+			creds.caPool.AddCert(ca)
+			creds.cas = append(creds.cas, ca)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("failed to parse CA certificate")
+		}
+	}
+	return creds, nil
+}
+
+// credentialStore holds the current node credentials. TLS configs built
+// from it pick up a reload on the next handshake, so listeners are kept.
+type credentialStore struct {
+	current atomic.Pointer[nodeCredentials]
+	// revocation rejects revoked client certificates; nil when no CRL is
+	// configured.
+	revocation *revocation.Checker
+}
+
+// tlsConfig returns a config requiring a client certificate signed by a
+// current panel CA and presenting the current node certificate. It matches
+// tls.RequireAndVerifyClientCert, with the CA looked up per handshake, and
+// also rejects client certificates listed in the CRL.
+func (c *credentialStore) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.current.Load().cert, nil
+		},
+		ClientAuth:            tls.RequireAnyClientCert,
+		VerifyPeerCertificate: c.verifyClientCertificate,
+		MinVersion:            tls.VersionTLS12,
+	}
+}
+
+func (c *credentialStore) verifyClientCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no client certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse client certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         c.current.Load().caPool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range chains[0] {
+		if c.revocation.Revoked(cert) {
+			return fmt.Errorf("client certificate %q (serial %s) has been revoked", cert.Subject.String(), cert.SerialNumber.String())
+		}
+	}
+	return nil
+}
+
+// ReloadCredentials loads the configuration again, from CONFIG_PATH and the
+// environment, and swaps in the node certificate, CA and JWT keys of its
+// SECRET_KEY and the panel CAs and JWT keys trusted besides them. Established connections are kept; new handshakes and tokens
+// use the new credentials. Nothing changes if anything fails to parse or
+// validate. The CRL is loaded again too, against the new CAs.
+func (s *Server) ReloadCredentials() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	creds, err := parseNodeCredentials(cfg)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenValidator.SetStaticKeys(cfg.PanelJWTKeys()...); err != nil {
+		return fmt.Errorf("invalid JWT public key: %w", err)
+	}
+	if err := s.certs.SetCertificates(cfg.Payload.NodeCertPEM, cfg.Payload.CACertPEM); err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+	s.credentials.current.Store(creds)
+	s.certs.Check()
+	if s.credentials.revocation != nil {
+		s.credentials.revocation.RefreshAndLog()
+	}
+
+	s.logger.Info("Node credentials reloaded")
+	return nil
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+type testPKI struct {
+	payload    *config.NodePayload
+	caPool     *x509.CertPool
+	clientCert tls.Certificate
+	jwtKey     *ecdsa.PrivateKey
+	caCert     *x509.Certificate
+	caKey      *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+			DNSNames:     []string{"localhost"},
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	nodeCertPEM, nodeKeyPEM := issue(2, x509.ExtKeyUsageServerAuth)
+	clientCertPEM, clientKeyPEM := issue(3, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	require.NoError(t, err)
+
+	jwtKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwtPubDER, err := x509.MarshalPKIXPublicKey(&jwtKey.PublicKey)
+	require.NoError(t, err)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(caCert)
+
+	return &testPKI{
+		payload: &config.NodePayload{
+			CACertPEM:    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+			JWTPublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: jwtPubDER})),
+			NodeCertPEM:  string(nodeCertPEM),
+			NodeKeyPEM:   string(nodeKeyPEM),
+		},
+		caPool:     caPool,
+		clientCert: clientCert,
+		jwtKey:     jwtKey,
+		caCert:     caCert,
+		caKey:      caKey,
+	}
+}
+
+func (p *testPKI) secretKey(t *testing.T) string {
+	t.Helper()
+
+	data, err := json.Marshal(p.payload)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func (p *testPKI) token(t *testing.T) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(p.jwtKey)
+	require.NoError(t, err)
+	return token
+}
+
+// revoke writes a PEM CRL signed by the CA listing the client certificate.
+func (p *testPKI) revoke(t *testing.T, path string) {
+	t.Helper()
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []x509.RevocationListEntry{{
+			SerialNumber:   p.clientCert.Leaf.SerialNumber,
+			RevocationTime: time.Now(),
+		}},
+	}, p.caCert, p.caKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0o600))
+}
+
+// serveTLS accepts connections on the main server TLS config and closes
+// them after the handshake. It returns the listener address.
+func serveTLS(t *testing.T, server *Server) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server.mainServer.TLSConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// handshake connects with the client certificate of client, trusting the
+// CA of server, and reports whether both sides accepted each other.
+func handshake(addr string, client, server *testPKI) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{client.clientCert},
+		RootCAs:      server.caPool,
+		ServerName:   "localhost",
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 a rejected client certificate shows up on the first read;
+	// the test listener closes accepted connections right away.
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+func TestReloadCredentials(t *testing.T) {
+	oldPKI := newTestPKI(t)
+	newPKI := newTestPKI(t)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		StateDir:         t.TempDir(),
+		Payload:          oldPKI.payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	addr := serveTLS(t, server)
+
+	require.NoError(t, handshake(addr, oldPKI, oldPKI))
+	assert.Error(t, handshake(addr, newPKI, oldPKI))
+	_, err = server.tokenValidator.Validate(oldPKI.token(t))
+	require.NoError(t, err)
+
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("SECRET_KEY", newPKI.secretKey(t))
+	require.NoError(t, server.ReloadCredentials())
+
+	// The same listener now presents the new certificate and trusts the new CA.
+	require.NoError(t, handshake(addr, newPKI, newPKI))
+	assert.Error(t, handshake(addr, oldPKI, newPKI))
+	assert.Error(t, handshake(addr, newPKI, oldPKI))
+	_, err = server.tokenValidator.Validate(newPKI.token(t))
+	assert.NoError(t, err)
+	_, err = server.tokenValidator.Validate(oldPKI.token(t))
+	assert.Error(t, err)
+
+	// A broken SECRET_KEY leaves the current credentials in place.
+	t.Setenv("SECRET_KEY", base64.StdEncoding.EncodeToString([]byte(`{"caCertPem":"x","jwtPublicKey":"x","nodeCertPem":"x","nodeKeyPem":"x"}`)))
+	assert.Error(t, server.ReloadCredentials())
+	assert.NoError(t, handshake(addr, newPKI, newPKI))
+}
+
+func TestRevokedClientCertificate(t *testing.T) {
+	pki := newTestPKI(t)
+	crlPath := filepath.Join(t.TempDir(), "panel.crl")
+
+	cfg := &config.Config{
+		NodePort:           2222,
+		InternalRestPort:   61001,
+		StateDir:           t.TempDir(),
+		CRLFile:            crlPath,
+		CRLRefreshInterval: 3600,
+		Payload:            pki.payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+	addr := serveTLS(t, server)
+
+	// Without a loadable CRL nothing is revoked.
+	server.credentials.revocation.RefreshAndLog()
+	require.NoError(t, handshake(addr, pki, pki))
+
+	pki.revoke(t, crlPath)
+	require.NoError(t, server.credentials.revocation.Refresh())
+	assert.Error(t, handshake(addr, pki, pki))
+}
+
+func TestTrustedPanels(t *testing.T) {
+	primary := newTestPKI(t)
+	standby := newTestPKI(t)
+	migrated := newTestPKI(t)
+	stranger := newTestPKI(t)
+
+	payload := *primary.payload
+	payload.TrustedCACertPEMs = []string{standby.payload.CACertPEM}
+	payload.TrustedJWTPublicKeys = []string{standby.payload.JWTPublicKey}
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		StateDir:         t.TempDir(),
+		PanelCACerts:     migrated.payload.CACertPEM,
+		JWTPublicKeys:    migrated.payload.JWTPublicKey,
+		Payload:          &payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+	addr := serveTLS(t, server)
+
+	// Every trusted panel reaches the node, which presents its own certificate.
+	for _, panel := range []*testPKI{primary, standby, migrated} {
+		assert.NoError(t, handshake(addr, panel, primary))
+		_, err = server.tokenValidator.Validate(panel.token(t))
+		assert.NoError(t, err)
+	}
+	assert.Error(t, handshake(addr, stranger, primary))
+	_, err = server.tokenValidator.Validate(stranger.token(t))
+	assert.Error(t, err)
+}
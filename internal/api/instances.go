@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/expiry"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/iplimit"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// instanceComponents are the background components of an additional core
+// instance. Their state is kept in memory: additional instances do not
+// survive a node restart and are set up again by the panel.
+type instanceComponents struct {
+	blocklist  *vision.Blocklist
+	ipLimiter  *iplimit.Limiter
+	expiry     *expiry.Scheduler
+	history    *history.Recorder
+	checkpoint *checkpoint.Checkpoint
+}
+
+func (c *instanceComponents) start() {
+	c.blocklist.Start()
+	c.ipLimiter.Start()
+	c.expiry.Start()
+	c.history.Start()
+	c.checkpoint.Start()
+}
+
+func (c *instanceComponents) stop() {
+	c.checkpoint.Stop()
+	c.history.Stop()
+	c.expiry.Stop()
+	c.ipLimiter.Stop()
+	c.blocklist.Stop()
+}
+
+// setupInstances serves the default core under /node/instances/default and
+// sets up every instance created later.
+func (s *Server) setupInstances() {
+	s.instancesController.SetRouter(xray.DefaultInstanceID,
+		instanceRouter(s.xrayController, s.handlerController, s.statsController))
+
+	s.coreManager.OnCreate(func(instance *xray.CoreInstance) {
+		c := s.newInstanceComponents(instance)
+		c.start()
+
+		s.instancesMu.Lock()
+		s.instances[instance.ID] = c
+		s.instancesMu.Unlock()
+	})
+	s.coreManager.OnRemove(func(instance *xray.CoreInstance) {
+		s.instancesController.RemoveRouter(instance.ID)
+
+		s.instancesMu.Lock()
+		c := s.instances[instance.ID]
+		delete(s.instances, instance.ID)
+		s.instancesMu.Unlock()
+
+		if c != nil {
+			c.stop()
+		}
+	})
+}
+
+// newInstanceComponents creates the components of instance and registers
+// its router.
+func (s *Server) newInstanceComponents(instance *xray.CoreInstance) *instanceComponents {
+	log := s.logger.WithField("instance", instance.ID)
+	store := state.NewMemoryStore()
+	if err := instance.ConfigManager.AttachStore(store); err != nil {
+		log.WithError(err).Warn("Failed to attach state store")
+	}
+
+	c := &instanceComponents{
+		blocklist:  vision.NewBlocklist(instance.Core, store, log),
+		expiry:     expiry.NewScheduler(store, log),
+		history:    history.NewRecorder(instance.Core, s.config.StatsHistorySize, log),
+		checkpoint: checkpoint.New(instance.Core, store, log),
+	}
+	c.ipLimiter = iplimit.NewLimiter(instance.Core, c.blocklist, store, log)
+
+	// The api inbound of an additional instance takes any free port, the
+	// configured one belongs to the default core.
+	xrayController := controller.NewXrayController(instance.Core, instance.ConfigManager, s.certs, 0, s.placeholders, s.jobs, log)
+	handlerController := controller.NewHandlerController(instance.Core, instance.ConfigManager, c.ipLimiter, c.expiry, events.NewBus(), nil, s.jobs, s.config.BulkWorkers, log)
+	c.expiry.OnExpire(handlerController.ExpireUser)
+	statsController := controller.NewStatsController(instance.Core, c.history, c.checkpoint, log)
+
+	s.instancesController.SetRouter(instance.ID, instanceRouter(xrayController, handlerController, statsController))
+
+	return c
+}
+
+// instanceRouter serves the endpoints of one core instance, rooted at "/".
+// Authentication is done by the main router in front of it.
+func instanceRouter(xrayController *controller.XrayController, handlerController *controller.HandlerController, statsController *controller.StatsController) http.Handler {
+	router := gin.New()
+	xrayController.RegisterRoutes(router.Group("/xray"))
+	handlerController.RegisterRoutes(router.Group("/handler"))
+	statsController.RegisterRoutes(router.Group("/stats"))
+	return router
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/remnawave/node-go/internal/maintenance"
+)
+
+// maintenanceStatus reports the maintenance window and the actions waiting
+// for it.
+type maintenanceStatus struct {
+	// Window is the cron expression of the window, empty when none is
+	// configured and actions run right away.
+	Window   string `json:"window"`
+	Duration int64  `json:"duration"`
+	Open     bool   `json:"open"`
+	// NextWindow is the next opening of the window, unset while it is open.
+	NextWindow *time.Time           `json:"nextWindow"`
+	Pending    []maintenance.Action `json:"pending"`
+}
+
+// Defer runs fn in the maintenance window, right away when none is
+// configured or it is open. Deferring again under the same name replaces
+// the pending fn.
+func (s *Server) Defer(name string, fn func()) {
+	s.maintenance.Defer(name, fn)
+}
+
+func (s *Server) handleMaintenance(c *gin.Context) {
+	status := maintenanceStatus{Pending: s.maintenance.Pending()}
+	if window := s.maintenance.Window(); window != nil {
+		now := time.Now()
+		status.Window = window.Expr
+		status.Duration = int64(window.Duration / time.Second)
+		status.Open = window.Contains(now)
+		if next := window.Next(now); !status.Open && !next.IsZero() {
+			status.NextWindow = &next
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	jwksRequestTimeout = 10 * time.Second
+	jwksMaxBodySize    = 1 << 20
+)
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKSRefresher periodically fetches the panel signing keys from a JWKS URL
+// into a TokenValidator, so the panel can rotate its key without touching
+// the nodes. The panel should publish a new key before signing with it.
+//
+// When a fetch fails the previously fetched keys stay in use.
+type JWKSRefresher struct {
+	url       string
+	interval  time.Duration
+	client    *http.Client
+	validator *TokenValidator
+	log       *logger.Logger
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewJWKSRefresher creates a refresher loading url into validator every
+// interval.
+func NewJWKSRefresher(url string, interval time.Duration, validator *TokenValidator, log *logger.Logger) *JWKSRefresher {
+	return &JWKSRefresher{
+		url:       url,
+		interval:  interval,
+		client:    &http.Client{Timeout: jwksRequestTimeout},
+		validator: validator,
+		log:       log,
+	}
+}
+
+// Start fetches the keys once and launches the refresh goroutine.
+func (r *JWKSRefresher) Start() {
+	r.mu.Lock()
+	if r.stopCh != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.stopCh = make(chan struct{})
+	stopCh := r.stopCh
+	r.mu.Unlock()
+
+	r.refreshAndLog()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				r.refreshAndLog()
+			}
+		}
+	}()
+}
+
+// Stop terminates the refresh goroutine.
+func (r *JWKSRefresher) Stop() {
+	r.mu.Lock()
+	stopCh := r.stopCh
+	r.stopCh = nil
+	r.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		r.wg.Wait()
+	}
+}
+
+// Refresh fetches the key set and hands its RSA, EC and Ed25519 signing
+// keys to the validator.
+func (r *JWKSRefresher) Refresh() error {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBodySize)).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode key set: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return fmt.Errorf("invalid key %q: %w", key.Kid, err)
+		}
+		if publicKey != nil {
+			keys[key.Kid] = publicKey
+		}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("key set has no supported signing keys")
+	}
+
+	r.validator.SetRemoteKeys(keys)
+	return nil
+}
+
+func (r *JWKSRefresher) refreshAndLog() {
+	if err := r.Refresh(); err != nil {
+		r.log.WithError(err).WithField("url", r.url).Warn("Failed to refresh JWKS, keeping current keys")
+	}
+}
+
+// publicKey decodes the key, or returns nil for a key type that cannot
+// sign tokens accepted by the validator.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA":
+		return k.rsaPublicKey()
+	case k.Kty == "EC":
+		return k.ecPublicKey()
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func (k jwk) ecPublicKey() (crypto.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, nil
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x coordinate: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y coordinate: %w", err)
+	}
+
+	// Round-trip through the uncompressed encoding so off-curve points are
+	// rejected.
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x) > size || len(y) > size {
+		return nil, fmt.Errorf("invalid point")
+	}
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	copy(point[1+size-len(x):1+size], x)
+	copy(point[1+2*size-len(y):], y)
+
+	publicKey, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid point: %w", err)
+	}
+	return publicKey, nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid modulus or exponent")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
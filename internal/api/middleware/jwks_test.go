@@ -0,0 +1,244 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func signWithKid(t *testing.T, privateKey *rsa.PrivateKey, kid string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+func encodeJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestTokenValidator_MultiplePEMKeys(t *testing.T) {
+	firstKey, firstPEM := generateTestKeyPair(t)
+	secondKey, secondPEM := generateTestKeyPair(t)
+	otherKey, _ := generateTestKeyPair(t)
+
+	validator, err := NewTokenValidator(firstPEM, "", secondPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	for name, key := range map[string]*rsa.PrivateKey{"first": firstKey, "second": secondKey} {
+		if _, err := validator.Validate(signWithKid(t, key, "")); err != nil {
+			t.Errorf("Expected token signed with %s key to be valid: %v", name, err)
+		}
+	}
+	if _, err := validator.Validate(signWithKid(t, otherKey, "")); err == nil {
+		t.Error("Expected token signed with unknown key to be rejected")
+	}
+}
+
+func TestTokenValidator_PEMBundle(t *testing.T) {
+	firstKey, firstPEM := generateTestKeyPair(t)
+	secondKey, secondPEM := generateTestKeyPair(t)
+
+	validator, err := NewTokenValidator(firstPEM + secondPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	if _, err := validator.Validate(signWithKid(t, firstKey, "")); err != nil {
+		t.Errorf("Expected first key to be accepted: %v", err)
+	}
+	if _, err := validator.Validate(signWithKid(t, secondKey, "")); err != nil {
+		t.Errorf("Expected second key to be accepted: %v", err)
+	}
+}
+
+func TestTokenValidator_NoKeys(t *testing.T) {
+	if _, err := NewTokenValidator("", ""); err == nil {
+		t.Error("Expected error without keys")
+	}
+}
+
+func TestJWKSRefresher_RotatesKeys(t *testing.T) {
+	_, staticPEM := generateTestKeyPair(t)
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var (
+		keys    atomic.Value
+		failing atomic.Bool
+	)
+	keys.Store([]map[string]string{encodeJWK("old", &oldKey.PublicKey)})
+
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys.Load()})
+	}))
+	defer panel.Close()
+
+	validator, err := NewTokenValidator(staticPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	refresher := NewJWKSRefresher(panel.URL, time.Hour, validator, log)
+
+	if _, err := validator.Validate(signWithKid(t, oldKey, "old")); err == nil {
+		t.Fatal("Expected token to be rejected before the first refresh")
+	}
+
+	if err := refresher.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := validator.Validate(signWithKid(t, oldKey, "old")); err != nil {
+		t.Errorf("Expected old key to be accepted: %v", err)
+	}
+	if _, err := validator.Validate(signWithKid(t, newKey, "old")); err == nil {
+		t.Error("Expected token with mismatching kid to be rejected")
+	}
+
+	keys.Store([]map[string]string{
+		encodeJWK("old", &oldKey.PublicKey),
+		encodeJWK("new", &newKey.PublicKey),
+	})
+	if err := refresher.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := validator.Validate(signWithKid(t, newKey, "new")); err != nil {
+		t.Errorf("Expected new key to be accepted: %v", err)
+	}
+
+	failing.Store(true)
+	if err := refresher.Refresh(); err == nil {
+		t.Error("Expected refresh to fail")
+	}
+	if _, err := validator.Validate(signWithKid(t, newKey, "new")); err != nil {
+		t.Errorf("Expected keys to be kept after a failed refresh: %v", err)
+	}
+	failing.Store(false)
+
+	keys.Store([]map[string]string{encodeJWK("new", &newKey.PublicKey)})
+	if err := refresher.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if _, err := validator.Validate(signWithKid(t, oldKey, "old")); err == nil {
+		t.Error("Expected retired key to be rejected")
+	}
+}
+
+func TestJWKSRefresher_ECAndOKPKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{
+				"kty": "EC",
+				"kid": "ec",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+				"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+			},
+			{
+				"kty": "OKP",
+				"kid": "ed",
+				"crv": "Ed25519",
+				"x":   base64.RawURLEncoding.EncodeToString(edPub),
+			},
+		}})
+	}))
+	defer panel.Close()
+
+	_, staticPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(staticPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	if err := NewJWKSRefresher(panel.URL, time.Hour, validator, nil).Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+	esToken := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	esToken.Header["kid"] = "ec"
+	signed, err := esToken.SignedString(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := validator.Validate(signed); err != nil {
+		t.Errorf("Expected ES256 token to be valid: %v", err)
+	}
+
+	edToken := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	edToken.Header["kid"] = "ed"
+	signed, err = edToken.SignedString(edKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	if _, err := validator.Validate(signed); err != nil {
+		t.Errorf("Expected EdDSA token to be valid: %v", err)
+	}
+}
+
+func TestJWKSRefresher_RejectsSetWithoutSigningKeys(t *testing.T) {
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"keys":[{"kty":"EC","kid":"ec"}]}`))
+	}))
+	defer panel.Close()
+
+	_, staticPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(staticPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	refresher := NewJWKSRefresher(panel.URL, time.Hour, validator, nil)
+	if err := refresher.Refresh(); err == nil {
+		t.Error("Expected refresh to fail for a set without supported signing keys")
+	}
+}
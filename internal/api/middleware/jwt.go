@@ -1,12 +1,17 @@
 package middleware
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -14,12 +19,13 @@ import (
 	"github.com/remnawave/node-go/internal/logger"
 )
 
-// JWTMiddleware creates a middleware that validates JWT tokens using RS256.
+// JWTMiddleware creates a middleware that validates JWT tokens using RS256,
+// ES256/384/512 or EdDSA, depending on the type of the public key.
 // On auth failure, the socket is destroyed (no HTTP response sent).
 // This matches the original NestJS behavior: response.socket?.destroy()
 func JWTMiddleware(publicKeyPEM string, log *logger.Logger) gin.HandlerFunc {
-	// Parse the RSA public key once at initialization
-	publicKey, err := parseRSAPublicKey(publicKeyPEM)
+	// Parse the public key once at initialization
+	validator, err := NewTokenValidator(publicKeyPEM)
 	if err != nil {
 		// If key parsing fails at startup, return middleware that always fails
 		return func(c *gin.Context) {
@@ -30,6 +36,12 @@ func JWTMiddleware(publicKeyPEM string, log *logger.Logger) gin.HandlerFunc {
 		}
 	}
 
+	return ValidatorMiddleware(validator, log)
+}
+
+// ValidatorMiddleware is JWTMiddleware with an existing validator, so the
+// REST API and the gRPC server share one key set.
+func ValidatorMiddleware(validator *TokenValidator, log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -39,53 +51,261 @@ func JWTMiddleware(publicKeyPEM string, log *logger.Logger) gin.HandlerFunc {
 			return
 		}
 
-		// Expect "Bearer <token>" format
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			logAuthFailure(log, c, "invalid Authorization header format")
+		claims, err := validator.ValidateHeader(authHeader)
+		if err != nil {
+			logAuthFailure(log, c, err.Error())
 			destroySocket(c)
 			return
 		}
 
-		tokenString := parts[1]
+		// Token is valid - store claims in context for later use
+		c.Set("jwt_claims", claims)
 
-		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// Verify signing method is RS256
-			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return publicKey, nil
-		}, jwt.WithValidMethods([]string{"RS256"}))
+		c.Next()
+	}
+}
+
+// validMethods are the accepted token algorithms. Each key only verifies
+// the algorithms of its type, see keyMatches.
+var validMethods = []string{"RS256", "ES256", "ES384", "ES512", "EdDSA"}
+
+// TokenValidator validates tokens signed by the panel with an RSA, ECDSA
+// or Ed25519 key. It is shared by the REST middleware and the gRPC
+// interceptor.
+//
+// It accepts the static keys it was created with and the remote keys set
+// by a JWKSRefresher. A token whose kid names a remote key is checked
+// against that key only; any other token is checked against every key.
+type TokenValidator struct {
+	options ValidationOptions
+	replay  *replayCache
+
+	mu         sync.RWMutex
+	staticKeys []crypto.PublicKey
+	remoteKeys map[string]crypto.PublicKey
+}
+
+// ValidationOptions are the claim checks applied on top of the signature
+// and the exp/nbf/iat times.
+type ValidationOptions struct {
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew tolerated on exp, nbf and iat.
+	Leeway time.Duration
+	// ReplayProtection requires a jti and exp claim and accepts each jti
+	// only once until the token expires.
+	ReplayProtection bool
+}
+
+// NewTokenValidator parses the panel public keys once. Each argument may
+// hold several PEM blocks; empty arguments are ignored.
+func NewTokenValidator(publicKeyPEMs ...string) (*TokenValidator, error) {
+	keys, err := parseStaticKeys(publicKeyPEMs)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenValidator{staticKeys: keys}, nil
+}
+
+// SetStaticKeys replaces the static keys, as NewTokenValidator parses them.
+// On error the current keys are kept.
+func (v *TokenValidator) SetStaticKeys(publicKeyPEMs ...string) error {
+	keys, err := parseStaticKeys(publicKeyPEMs)
+	if err != nil {
+		return err
+	}
 
+	v.mu.Lock()
+	v.staticKeys = keys
+	v.mu.Unlock()
+	return nil
+}
+
+func parseStaticKeys(publicKeyPEMs []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, publicKeyPEM := range publicKeyPEMs {
+		parsed, err := parsePublicKeys(publicKeyPEM)
 		if err != nil {
-			logAuthFailure(log, c, fmt.Sprintf("token validation failed: %v", err))
-			destroySocket(c)
-			return
+			return nil, err
 		}
+		keys = append(keys, parsed...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("failed to parse PEM block")
+	}
+	return keys, nil
+}
 
-		if !token.Valid {
-			logAuthFailure(log, c, "invalid token")
-			destroySocket(c)
-			return
+// SetOptions sets the claim checks. It must be called before the validator
+// is used.
+func (v *TokenValidator) SetOptions(options ValidationOptions) {
+	v.options = options
+	v.replay = nil
+	if options.ReplayProtection {
+		v.replay = newReplayCache()
+	}
+}
+
+// SetRemoteKeys replaces the keys fetched from the panel, indexed by kid.
+func (v *TokenValidator) SetRemoteKeys(keys map[string]crypto.PublicKey) {
+	v.mu.Lock()
+	v.remoteKeys = keys
+	v.mu.Unlock()
+}
+
+// keysFor returns the keys a token with the given kid and signing method
+// may be signed with.
+func (v *TokenValidator) keysFor(kid string, method jwt.SigningMethod) []jwt.VerificationKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	candidates := make([]crypto.PublicKey, 0, len(v.staticKeys)+len(v.remoteKeys))
+	if key, ok := v.remoteKeys[kid]; ok && kid != "" {
+		candidates = append(candidates, key)
+	} else {
+		candidates = append(candidates, v.staticKeys...)
+		for _, key := range v.remoteKeys {
+			candidates = append(candidates, key)
 		}
+	}
 
-		// Token is valid - store claims in context for later use
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			c.Set("jwt_claims", claims)
+	var keys []jwt.VerificationKey
+	for _, key := range candidates {
+		if keyMatches(method, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyMatches reports whether key verifies tokens of the signing method, so
+// a key is never used with an algorithm of another type or curve.
+func keyMatches(method jwt.SigningMethod, key crypto.PublicKey) bool {
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		return ok && ecKey.Curve.Params().BitSize == m.CurveBits
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	default:
+		return false
+	}
+}
+
+// ValidateHeader validates a "Bearer <token>" Authorization header value.
+func (v *TokenValidator) ValidateHeader(authHeader string) (jwt.MapClaims, error) {
+	// Expect "Bearer <token>" format
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return nil, fmt.Errorf("invalid Authorization header format")
+	}
+
+	return v.Validate(parts[1])
+}
+
+// Validate parses the token and verifies its signature and claims.
+func (v *TokenValidator) Validate(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		keys := v.keysFor(kid, token.Method)
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no key for signing method: %v", token.Header["alg"])
 		}
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}, v.parserOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("token validation failed: %v", err)
+	}
 
-		c.Next()
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	if v.replay != nil {
+		if err := v.checkReplay(claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+func (v *TokenValidator) parserOptions() []jwt.ParserOption {
+	options := []jwt.ParserOption{jwt.WithValidMethods(validMethods)}
+	if v.options.Issuer != "" {
+		options = append(options, jwt.WithIssuer(v.options.Issuer))
+	}
+	if v.options.Audience != "" {
+		options = append(options, jwt.WithAudience(v.options.Audience))
+	}
+	if v.options.Leeway > 0 {
+		options = append(options, jwt.WithLeeway(v.options.Leeway))
+	}
+	if v.options.ReplayProtection {
+		options = append(options, jwt.WithExpirationRequired())
 	}
+	return options
 }
 
-// parseRSAPublicKey parses a PEM-encoded RSA public key.
-func parseRSAPublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+// checkReplay accepts the jti of a valid token once. It is remembered
+// until exp plus the leeway, after which the token is rejected anyway.
+func (v *TokenValidator) checkReplay(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return fmt.Errorf("token has no jti claim")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return fmt.Errorf("token has no exp claim")
+	}
+
+	if !v.replay.use(jti, exp.Add(v.options.Leeway), time.Now()) {
+		return fmt.Errorf("token already used")
+	}
+	return nil
+}
+
+// parsePublicKeys parses every PEM-encoded public key in the input.
+func parsePublicKeys(publicKeyPEM string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	rest := []byte(publicKeyPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		key, err := parsePublicKeyBlock(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 && strings.TrimSpace(publicKeyPEM) != "" {
+		return nil, fmt.Errorf("failed to parse PEM block")
+	}
+	return keys, nil
+}
+
+// parsePublicKey parses a PEM-encoded RSA, ECDSA or Ed25519 public key.
+func parsePublicKey(publicKeyPEM string) (crypto.PublicKey, error) {
 	block, _ := pem.Decode([]byte(publicKeyPEM))
 	if block == nil {
 		return nil, fmt.Errorf("failed to parse PEM block")
 	}
+	return parsePublicKeyBlock(block)
+}
 
+// parsePublicKeyBlock parses the public key in a PEM block. The key type,
+// and so the token algorithms it verifies, comes from the key itself.
+func parsePublicKeyBlock(block *pem.Block) (crypto.PublicKey, error) {
 	// Try parsing as PKIX (standard format)
 	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
@@ -97,12 +317,18 @@ func parseRSAPublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
 		return rsaPub, nil
 	}
 
-	rsaPub, ok := pub.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("not an RSA public key")
+	switch key := pub.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256, 384, 521:
+			return key, nil
+		}
+		return nil, fmt.Errorf("unsupported ECDSA curve: %s", key.Curve.Params().Name)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
 	}
-
-	return rsaPub, nil
 }
 
 // logAuthFailure logs authentication failure with request details.
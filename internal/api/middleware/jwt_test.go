@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -382,7 +385,7 @@ func TestJWTMiddleware_NilLogger(t *testing.T) {
 	}
 }
 
-func TestParseRSAPublicKey_PKIX(t *testing.T) {
+func TestParsePublicKey_PKIX(t *testing.T) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		t.Fatalf("Failed to generate key: %v", err)
@@ -394,16 +397,16 @@ func TestParseRSAPublicKey_PKIX(t *testing.T) {
 		Bytes: publicKeyBytes,
 	})
 
-	key, err := parseRSAPublicKey(string(publicKeyPEM))
+	key, err := parsePublicKey(string(publicKeyPEM))
 	if err != nil {
 		t.Errorf("Failed to parse PKIX public key: %v", err)
 	}
-	if key == nil {
-		t.Error("Expected non-nil key")
+	if _, ok := key.(*rsa.PublicKey); !ok {
+		t.Errorf("Expected RSA key, got %T", key)
 	}
 }
 
-func TestParseRSAPublicKey_PKCS1(t *testing.T) {
+func TestParsePublicKey_PKCS1(t *testing.T) {
 	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	if err != nil {
 		t.Fatalf("Failed to generate key: %v", err)
@@ -415,30 +418,227 @@ func TestParseRSAPublicKey_PKCS1(t *testing.T) {
 		Bytes: publicKeyBytes,
 	})
 
-	key, err := parseRSAPublicKey(string(publicKeyPEM))
+	key, err := parsePublicKey(string(publicKeyPEM))
 	if err != nil {
 		t.Errorf("Failed to parse PKCS1 public key: %v", err)
 	}
-	if key == nil {
-		t.Error("Expected non-nil key")
+	if _, ok := key.(*rsa.PublicKey); !ok {
+		t.Errorf("Expected RSA key, got %T", key)
 	}
 }
 
-func TestParseRSAPublicKey_InvalidPEM(t *testing.T) {
-	_, err := parseRSAPublicKey("not a pem")
+func TestParsePublicKey_InvalidPEM(t *testing.T) {
+	_, err := parsePublicKey("not a pem")
 	if err == nil {
 		t.Error("Expected error for invalid PEM")
 	}
 }
 
-func TestParseRSAPublicKey_InvalidKey(t *testing.T) {
+func TestParsePublicKey_InvalidKey(t *testing.T) {
 	invalidPEM := pem.EncodeToMemory(&pem.Block{
 		Type:  "PUBLIC KEY",
 		Bytes: []byte("invalid key data"),
 	})
 
-	_, err := parseRSAPublicKey(string(invalidPEM))
+	_, err := parsePublicKey(string(invalidPEM))
 	if err == nil {
 		t.Error("Expected error for invalid key data")
 	}
 }
+
+func marshalPublicKeyPEM(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestTokenValidator_ECDSAAndEd25519(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+
+	validator, err := NewTokenValidator(marshalPublicKeyPEM(t, &ecKey.PublicKey), marshalPublicKeyPEM(t, edPub))
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	esToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to sign ES256 token: %v", err)
+	}
+	if _, err := validator.Validate(esToken); err != nil {
+		t.Errorf("Expected ES256 token to be valid: %v", err)
+	}
+
+	edToken, err := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(edKey)
+	if err != nil {
+		t.Fatalf("Failed to sign EdDSA token: %v", err)
+	}
+	if _, err := validator.Validate(edToken); err != nil {
+		t.Errorf("Expected EdDSA token to be valid: %v", err)
+	}
+
+	// A P-256 key must not verify tokens claiming another curve.
+	es384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	es384Token, err := jwt.NewWithClaims(jwt.SigningMethodES384, claims).SignedString(es384Key)
+	if err != nil {
+		t.Fatalf("Failed to sign ES384 token: %v", err)
+	}
+	if _, err := validator.Validate(es384Token); err == nil {
+		t.Error("Expected ES384 token to be rejected without a P-384 key")
+	}
+
+	rsaKey, _ := generateTestKeyPair(t)
+	if _, err := validator.Validate(generateTestToken(t, rsaKey, claims)); err == nil {
+		t.Error("Expected RS256 token to be rejected without an RSA key")
+	}
+}
+
+func TestTokenValidator_RSAKeyRejectsECDSAToken(t *testing.T) {
+	_, publicKeyPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	if _, err := validator.Validate(token); err == nil {
+		t.Error("Expected ES256 token to be rejected by an RSA-only validator")
+	}
+}
+
+func TestParsePublicKey_UnsupportedCurve(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	if _, err := parsePublicKey(marshalPublicKeyPEM(t, &key.PublicKey)); err == nil {
+		t.Error("Expected error for P-224 key")
+	}
+}
+
+func TestTokenValidator_IssuerAndAudience(t *testing.T) {
+	privateKey, publicKeyPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	validator.SetOptions(ValidationOptions{Issuer: "panel", Audience: "node-1"})
+
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		valid  bool
+	}{
+		{"matching", jwt.MapClaims{"iss": "panel", "aud": "node-1", "exp": exp}, true},
+		{"audience list", jwt.MapClaims{"iss": "panel", "aud": []string{"node-1", "node-2"}, "exp": exp}, true},
+		{"other node", jwt.MapClaims{"iss": "panel", "aud": "node-2", "exp": exp}, false},
+		{"other issuer", jwt.MapClaims{"iss": "other", "aud": "node-1", "exp": exp}, false},
+		{"missing claims", jwt.MapClaims{"exp": exp}, false},
+	}
+
+	for _, tt := range tests {
+		_, err := validator.Validate(generateTestToken(t, privateKey, tt.claims))
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid token, got %v", tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s: expected token to be rejected", tt.name)
+		}
+	}
+}
+
+func TestTokenValidator_Leeway(t *testing.T) {
+	privateKey, publicKeyPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	token := generateTestToken(t, privateKey, jwt.MapClaims{
+		"exp": time.Now().Add(-10 * time.Second).Unix(),
+	})
+	if _, err := validator.Validate(token); err == nil {
+		t.Fatal("Expected expired token to be rejected without leeway")
+	}
+
+	validator.SetOptions(ValidationOptions{Leeway: time.Minute})
+	if _, err := validator.Validate(token); err != nil {
+		t.Errorf("Expected token within leeway to be valid: %v", err)
+	}
+}
+
+func TestTokenValidator_ReplayProtection(t *testing.T) {
+	privateKey, publicKeyPEM := generateTestKeyPair(t)
+	validator, err := NewTokenValidator(publicKeyPEM)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+	validator.SetOptions(ValidationOptions{ReplayProtection: true})
+
+	exp := time.Now().Add(time.Hour).Unix()
+	token := generateTestToken(t, privateKey, jwt.MapClaims{"jti": "request-1", "exp": exp})
+	if _, err := validator.Validate(token); err != nil {
+		t.Fatalf("Expected first use to be valid: %v", err)
+	}
+	if _, err := validator.Validate(token); err == nil {
+		t.Error("Expected replayed token to be rejected")
+	}
+
+	other := generateTestToken(t, privateKey, jwt.MapClaims{"jti": "request-2", "exp": exp})
+	if _, err := validator.Validate(other); err != nil {
+		t.Errorf("Expected token with another jti to be valid: %v", err)
+	}
+
+	if _, err := validator.Validate(generateTestToken(t, privateKey, jwt.MapClaims{"exp": exp})); err == nil {
+		t.Error("Expected token without jti to be rejected")
+	}
+	if _, err := validator.Validate(generateTestToken(t, privateKey, jwt.MapClaims{"jti": "request-3"})); err == nil {
+		t.Error("Expected token without exp to be rejected")
+	}
+}
+
+func TestReplayCache_ForgetsExpiredEntries(t *testing.T) {
+	cache := newReplayCache()
+	now := time.Now()
+
+	if !cache.use("a", now.Add(time.Second), now) {
+		t.Fatal("Expected first use to succeed")
+	}
+	if cache.use("a", now.Add(time.Second), now) {
+		t.Error("Expected second use to fail")
+	}
+
+	later := now.Add(2 * replayPruneInterval)
+	if !cache.use("b", later.Add(time.Second), later) {
+		t.Error("Expected new jti to succeed")
+	}
+	if _, ok := cache.seen["a"]; ok {
+		t.Error("Expected expired entry to be pruned")
+	}
+}
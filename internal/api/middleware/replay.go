@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// replayPruneInterval bounds how often expired jti entries are dropped.
+const replayPruneInterval = time.Minute
+
+// replayCache remembers the jti of accepted tokens until they expire, so
+// each token is accepted once.
+type replayCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{seen: make(map[string]time.Time)}
+}
+
+// use records jti as used until expiresAt and reports false if it was
+// already used.
+func (c *replayCache) use(jti string, expiresAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPrune) >= replayPruneInterval {
+		for id, expiry := range c.seen {
+			if now.After(expiry) {
+				delete(c.seen, id)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	if expiry, ok := c.seen[jti]; ok && !now.After(expiry) {
+		return false
+	}
+	c.seen[jti] = expiresAt
+	return true
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ScopePolicy maps JWT scopes to the routes they grant, so a token can be
+// limited to, for example, the stats endpoints.
+//
+// Tokens without a scope claim, such as the ones the panel signs, keep
+// full access. A scoped token may only call a route granted by one of its
+// scopes. Routes are REST paths or gRPC full method names; a route ending
+// in "/" or "*" matches as a prefix, any other route exactly.
+type ScopePolicy struct {
+	routes map[string][]string
+}
+
+// ParseScopePolicy parses a policy in the form
+// "scope=route,route;scope=route". An empty spec disables scope checks.
+func ParseScopePolicy(spec string) (*ScopePolicy, error) {
+	policy := &ScopePolicy{routes: make(map[string][]string)}
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		scope, routes, ok := strings.Cut(entry, "=")
+		scope = strings.TrimSpace(scope)
+		if !ok || scope == "" {
+			return nil, fmt.Errorf("invalid scope entry %q", entry)
+		}
+
+		for _, route := range strings.Split(routes, ",") {
+			route = strings.TrimSpace(route)
+			if !strings.HasPrefix(route, "/") {
+				return nil, fmt.Errorf("invalid route %q for scope %q", route, scope)
+			}
+			policy.routes[scope] = append(policy.routes[scope], route)
+		}
+	}
+
+	return policy, nil
+}
+
+// Enabled reports whether the policy restricts anything.
+func (p *ScopePolicy) Enabled() bool {
+	return p != nil && len(p.routes) > 0
+}
+
+// Allows reports whether a token with the given claims may call route.
+func (p *ScopePolicy) Allows(claims jwt.MapClaims, route string) bool {
+	if !p.Enabled() {
+		return true
+	}
+
+	scopes, scoped := tokenScopes(claims)
+	if !scoped {
+		return true
+	}
+
+	for _, scope := range scopes {
+		for _, pattern := range p.routes[scope] {
+			if routeMatches(pattern, route) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tokenScopes reads the scope claim, either a space-separated string as
+// in OAuth or a list. The second value is false when the claim is absent.
+func tokenScopes(claims jwt.MapClaims) ([]string, bool) {
+	switch v := claims["scope"].(type) {
+	case nil:
+		return nil, false
+	case string:
+		return strings.Fields(v), true
+	case []interface{}:
+		scopes := make([]string, 0, len(v))
+		for _, item := range v {
+			if scope, ok := item.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+		return scopes, true
+	default:
+		// An unreadable claim still marks the token as scoped.
+		return nil, true
+	}
+}
+
+func routeMatches(pattern, route string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(route, prefix)
+	}
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(route, pattern)
+	}
+	return route == pattern
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParseScopePolicy(t *testing.T) {
+	policy, err := ParseScopePolicy(" monitor = /node/stats/*, /node/xray/status ; ops=/node/xray/ ;")
+	if err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+	if !policy.Enabled() {
+		t.Fatal("Expected policy to be enabled")
+	}
+
+	monitor := jwt.MapClaims{"scope": "monitor"}
+	tests := []struct {
+		claims jwt.MapClaims
+		route  string
+		allow  bool
+	}{
+		{monitor, "/node/stats/get-system-stats", true},
+		{monitor, "/node/xray/status", true},
+		{monitor, "/node/xray/status-extra", false},
+		{monitor, "/node/xray/start", false},
+		{monitor, "/node/handler/add-user", false},
+		{jwt.MapClaims{"scope": "monitor ops"}, "/node/xray/start", true},
+		{jwt.MapClaims{"scope": []interface{}{"ops"}}, "/node/xray/stop", true},
+		{jwt.MapClaims{"scope": "unknown"}, "/node/stats/get-system-stats", false},
+		{jwt.MapClaims{"scope": ""}, "/node/stats/get-system-stats", false},
+		{jwt.MapClaims{"scope": 42}, "/node/stats/get-system-stats", false},
+		{jwt.MapClaims{"sub": "panel"}, "/node/handler/add-user", true},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Allows(tt.claims, tt.route); got != tt.allow {
+			t.Errorf("Allows(%v, %q) = %v, want %v", tt.claims["scope"], tt.route, got, tt.allow)
+		}
+	}
+}
+
+func TestParseScopePolicy_Empty(t *testing.T) {
+	policy, err := ParseScopePolicy("")
+	if err != nil {
+		t.Fatalf("Failed to parse empty policy: %v", err)
+	}
+	if policy.Enabled() {
+		t.Error("Expected empty policy to be disabled")
+	}
+	if !policy.Allows(jwt.MapClaims{"scope": "monitor"}, "/node/xray/start") {
+		t.Error("Expected disabled policy to allow everything")
+	}
+
+	var nilPolicy *ScopePolicy
+	if !nilPolicy.Allows(jwt.MapClaims{"scope": "monitor"}, "/node/xray/start") {
+		t.Error("Expected nil policy to allow everything")
+	}
+}
+
+func TestParseScopePolicy_Invalid(t *testing.T) {
+	for _, spec := range []string{"monitor", "=/node/stats/", "monitor=node/stats", "monitor="} {
+		if _, err := ParseScopePolicy(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/remnawave/node-go/internal/autoblock"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/webhook"
+)
+
+// credentialSettings are reloaded by ReloadCredentials, or, for the state
+// encryption key, the Redis and broker URLs, the Vault credentials and the
+// cluster secret, forgotten once the server is built, so ReloadConfig leaves
+// them out of its report.
+var credentialSettings = map[string]bool{
+	"secretKey":          true,
+	"secretKeyFile":      true,
+	"jwtPublicKeys":      true,
+	"panelCaCerts":       true,
+	"stateEncryptionKey": true,
+	"stateRedisUrl":      true,
+	"vaultToken":         true,
+	"vaultSecretId":      true,
+	"clusterSecret":      true,
+	"brokerUrl":          true,
+}
+
+// ConfigReload reports the settings, by their JSON names, a configuration
+// reload found changed.
+type ConfigReload struct {
+	// Applied took effect right away.
+	Applied []string `json:"applied"`
+	// RestartRequired take effect once the node is restarted.
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// ReloadConfig loads the configuration again, from CONFIG_PATH and the
+// environment, and applies the settings that can change at runtime: the
+// log level, the panel allowlist, the Telegram rate limit, the webhook
+// URL, secret and events, and the auto block policy. Nothing changes if
+// anything fails to parse or validate. The other changed settings are reported and
+// logged as requiring a restart.
+func (s *Server) ReloadConfig() (*ConfigReload, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	reload, err := s.applyConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	log := s.logger
+	if len(reload.Applied) > 0 {
+		log = log.WithField("applied", strings.Join(reload.Applied, ","))
+	}
+	log.Info("Configuration reloaded")
+	if len(reload.RestartRequired) > 0 {
+		s.logger.WithField("settings", strings.Join(reload.RestartRequired, ",")).
+			Warn("Changed settings take effect after a restart")
+	}
+	return reload, nil
+}
+
+// applyConfig applies the runtime settings of cfg that differ from the
+// settings in effect.
+func (s *Server) applyConfig(cfg *config.Config) (*ConfigReload, error) {
+	allowlist, err := parseIPAllowlist(cfg.PanelAllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid panel allowed IPs: %w", err)
+	}
+	whitelist, err := autoblock.ParseWhitelist(cfg.AutoBlockWhitelist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto block whitelist: %w", err)
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	running := s.running
+	next := *running
+
+	if cfg.LogLevel != running.LogLevel {
+		s.logger.SetLevel(configuredLogLevel(cfg.LogLevel))
+		next.LogLevel = cfg.LogLevel
+	}
+	if cfg.PanelAllowedIPs != running.PanelAllowedIPs {
+		s.panelAllowlist.Store(&allowlist)
+		next.PanelAllowedIPs = cfg.PanelAllowedIPs
+	}
+	// Enabling or disabling alerts and webhooks requires a restart.
+	if s.telegram != nil && cfg.TelegramRateLimit != running.TelegramRateLimit {
+		s.telegram.SetRateLimit(time.Duration(cfg.TelegramRateLimit) * time.Second)
+		next.TelegramRateLimit = cfg.TelegramRateLimit
+	}
+	if s.webhooks != nil && cfg.WebhookURL != "" {
+		s.webhooks.SetTarget(cfg.WebhookURL, cfg.WebhookSecret, webhook.ParseEvents(cfg.WebhookEvents))
+		next.WebhookURL = cfg.WebhookURL
+		next.WebhookSecret = cfg.WebhookSecret
+		next.WebhookEvents = cfg.WebhookEvents
+	}
+	if s.autoBlocker != nil {
+		s.autoBlocker.SetPolicy(autoblock.Policy{
+			MaxFailures: cfg.AutoBlockMaxFailures,
+			Window:      time.Duration(cfg.AutoBlockWindow) * time.Second,
+			BanDuration: time.Duration(cfg.AutoBlockBanDuration) * time.Second,
+			Whitelist:   whitelist,
+			IPv6Prefix:  cfg.AutoBlockIPv6Prefix,
+		})
+		next.AutoBlockMaxFailures = cfg.AutoBlockMaxFailures
+		next.AutoBlockWindow = cfg.AutoBlockWindow
+		next.AutoBlockBanDuration = cfg.AutoBlockBanDuration
+		next.AutoBlockWhitelist = cfg.AutoBlockWhitelist
+		next.AutoBlockIPv6Prefix = cfg.AutoBlockIPv6Prefix
+	}
+	if s.cluster != nil {
+		s.cluster.SetWhitelist(whitelist)
+		next.AutoBlockWhitelist = cfg.AutoBlockWhitelist
+	}
+
+	s.running = &next
+	return &ConfigReload{
+		Applied:         changedSettings(running, &next),
+		RestartRequired: changedSettings(&next, cfg),
+	}, nil
+}
+
+// LogLevel returns the level LOG_LEVEL sets, as of the last configuration
+// reload, regardless of later changes through SIGUSR1 or the internal API.
+func (s *Server) LogLevel() logger.Level {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	return configuredLogLevel(s.running.LogLevel)
+}
+
+// configuredLogLevel returns the level named by LOG_LEVEL, info when it is
+// not a known level.
+func configuredLogLevel(name string) logger.Level {
+	level, err := logger.ParseLevel(name)
+	if err != nil {
+		return logger.LevelInfo
+	}
+	return level
+}
+
+// changedSettings returns the JSON names of the settings, credentials
+// aside, that differ between old and new.
+func changedSettings(old, new *config.Config) []string {
+	changed := make([]string, 0)
+	oldValue, newValue := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < oldValue.NumField(); i++ {
+		name, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || credentialSettings[name] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
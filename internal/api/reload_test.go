@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func TestApplyConfig(t *testing.T) {
+	pki := newTestPKI(t)
+	cfg := &config.Config{
+		NodePort:             2222,
+		InternalRestPort:     61001,
+		LogLevel:             "info",
+		StateDir:             t.TempDir(),
+		WebhookURL:           "http://127.0.0.1:1/hook",
+		TelegramBotToken:     "123:token",
+		TelegramChatID:       "-10042",
+		TelegramRateLimit:    600,
+		AutoBlock:            true,
+		AutoBlockMaxFailures: 10,
+		AutoBlockWindow:      60,
+		AutoBlockBanDuration: 3600,
+		Payload:              pki.payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	next := *cfg
+	next.LogLevel = "debug"
+	next.PanelAllowedIPs = "203.0.113.10"
+	next.TelegramRateLimit = 60
+	next.WebhookSecret = "secret"
+	next.AutoBlockWhitelist = "10.0.0.0/8"
+	next.NodePort = 3333
+	next.SecretKey = "rotated"
+
+	reload, err := server.applyConfig(&next)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logLevel", "panelAllowedIps", "webhookSecret", "telegramRateLimit", "autoBlockWhitelist"}, reload.Applied)
+	assert.Equal(t, []string{"nodePort"}, reload.RestartRequired)
+
+	assert.Equal(t, logger.LevelDebug, log.Level())
+	assert.Equal(t, logger.LevelDebug, server.LogLevel())
+	assert.False(t, server.panelAllowlist.Load().allows(&net.TCPAddr{IP: net.ParseIP("192.0.2.1")}))
+	assert.Equal(t, 2222, server.config.NodePort)
+
+	// A restart setting stays reported until the node restarts.
+	reload, err = server.applyConfig(&next)
+	require.NoError(t, err)
+	assert.Empty(t, reload.Applied)
+	assert.Equal(t, []string{"nodePort"}, reload.RestartRequired)
+
+	// Nothing is applied when a setting is invalid.
+	invalid := next
+	invalid.LogLevel = "warn"
+	invalid.PanelAllowedIPs = "not-an-ip"
+	_, err = server.applyConfig(&invalid)
+	require.Error(t, err)
+	assert.Equal(t, logger.LevelDebug, log.Level())
+}
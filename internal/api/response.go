@@ -2,29 +2,19 @@ package api
 
 import (
 	"time"
+
+	apperrors "github.com/remnawave/node-go/internal/errors"
 )
 
 type SuccessResponse struct {
 	Response interface{} `json:"response"`
 }
 
-type ErrorResponse struct {
-	Timestamp string `json:"timestamp"`
-	Path      string `json:"path"`
-	Message   string `json:"message"`
-	ErrorCode string `json:"errorCode"`
-}
+type ErrorResponse = apperrors.Response
 
-type ValidationError struct {
-	Path    []string `json:"path"`
-	Message string   `json:"message"`
-}
+type ValidationError = apperrors.ValidationError
 
-type ValidationErrorResponse struct {
-	StatusCode int               `json:"statusCode"`
-	Message    string            `json:"message"`
-	Errors     []ValidationError `json:"errors"`
-}
+type ValidationErrorResponse = apperrors.ValidationResponse
 
 func NewSuccessResponse(data interface{}) SuccessResponse {
 	return SuccessResponse{Response: data}
@@ -40,9 +30,5 @@ func NewErrorResponse(path, message, errorCode string) ErrorResponse {
 }
 
 func NewValidationErrorResponse(errors []ValidationError) ValidationErrorResponse {
-	return ValidationErrorResponse{
-		StatusCode: 400,
-		Message:    "Validation failed",
-		Errors:     errors,
-	}
+	return apperrors.NewValidationResponse(errors)
 }
@@ -1,120 +1,669 @@
 package api
 
 import (
-	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/klauspost/compress/zstd"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
 
 	"github.com/remnawave/node-go/internal/api/controller"
 	"github.com/remnawave/node-go/internal/api/middleware"
+	"github.com/remnawave/node-go/internal/autoblock"
+	"github.com/remnawave/node-go/internal/broker"
+	"github.com/remnawave/node-go/internal/certmon"
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/cluster"
 	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/consistency"
+	"github.com/remnawave/node-go/internal/diskmon"
 	apperrors "github.com/remnawave/node-go/internal/errors"
+	"github.com/remnawave/node-go/internal/eventlog"
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/expiry"
+	"github.com/remnawave/node-go/internal/firewall"
+	"github.com/remnawave/node-go/internal/geodata"
+	"github.com/remnawave/node-go/internal/grpcapi"
+	"github.com/remnawave/node-go/internal/handover"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/iplimit"
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/lastseen"
+	"github.com/remnawave/node-go/internal/limitmon"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/maintenance"
+	"github.com/remnawave/node-go/internal/memmon"
+	"github.com/remnawave/node-go/internal/metrics"
+	"github.com/remnawave/node-go/internal/outboundmon"
+	"github.com/remnawave/node-go/internal/push"
+	"github.com/remnawave/node-go/internal/revocation"
+	"github.com/remnawave/node-go/internal/routing"
+	"github.com/remnawave/node-go/internal/secretsource"
+	"github.com/remnawave/node-go/internal/startsession"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/telegram"
+	"github.com/remnawave/node-go/internal/telemetry"
+	"github.com/remnawave/node-go/internal/trafficexport"
+	"github.com/remnawave/node-go/internal/version"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/webhook"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
+// telemetryFlushTimeout bounds the export of the last spans and metrics
+// on shutdown.
+const telemetryFlushTimeout = 5 * time.Second
+
 type Server struct {
-	config             *config.Config
-	logger             *logger.Logger
-	core               *xray.Core
-	configManager      *xray.ConfigManager
-	xrayController     *controller.XrayController
-	handlerController  *controller.HandlerController
-	statsController    *controller.StatsController
-	visionController   *controller.VisionController
-	internalController *controller.InternalController
-	mainServer         *http.Server
-	internalServer     *http.Server
-	mainRouter         *gin.Engine
-	internalRouter     *gin.Engine
+	config                 *config.Config
+	reloadMu               sync.Mutex
+	running                *config.Config
+	logger                 *logger.Logger
+	core                   *xray.Core
+	configManager          *xray.ConfigManager
+	store                  *state.FreezableStore
+	replicaStore           *state.FreezableStore
+	blocklist              *vision.Blocklist
+	ipLimiter              *iplimit.Limiter
+	expiry                 *expiry.Scheduler
+	events                 *events.Bus
+	eventLog               *eventlog.Log
+	history                *history.Recorder
+	checkpoint             *checkpoint.Checkpoint
+	lastSeen               *lastseen.Tracker
+	routingRules           *routing.Registry
+	userRoutes             *routing.UserRoutes
+	consistency            *consistency.Checker
+	jobs                   *jobs.Manager
+	certs                  *certmon.Monitor
+	metrics                *metrics.Registry
+	pusher                 *push.Pusher
+	heartbeater            *push.Heartbeater
+	trafficExporter        *trafficexport.Exporter
+	webhooks               *webhook.Sink
+	broker                 *broker.Publisher
+	telegram               *telegram.Notifier
+	disks                  *diskmon.Monitor
+	limits                 *limitmon.Monitor
+	memory                 *memmon.Monitor
+	outbounds              *outboundmon.Prober
+	telemetry              *telemetry.Provider
+	autoBlocker            *autoblock.Blocker
+	cluster                *cluster.Cluster
+	firewall               firewall.Firewall
+	geodata                *geodata.Manager
+	maintenance            *maintenance.Scheduler
+	coreManager            *xray.CoreManager
+	instancesMu            sync.Mutex
+	instances              map[string]*instanceComponents
+	placeholders           *xray.Placeholders
+	credentials            credentialStore
+	tokenValidator         *middleware.TokenValidator
+	scopePolicy            *middleware.ScopePolicy
+	jwksRefresher          *middleware.JWKSRefresher
+	secretRefresher        *secretsource.Refresher
+	xrayController         *controller.XrayController
+	startSessionController *controller.StartSessionController
+	handlerController      *controller.HandlerController
+	inboundController      *controller.InboundController
+	statsController        *controller.StatsController
+	visionController       *controller.VisionController
+	routingController      *controller.RoutingController
+	internalController     *controller.InternalController
+	eventsController       *controller.EventsController
+	consistencyController  *controller.ConsistencyController
+	lastSeenController     *controller.LastSeenController
+	outboundsController    *controller.OutboundHealthController
+	infoController         *controller.InfoController
+	jobsController         *controller.JobsController
+	logsController         *controller.LogsController
+	instancesController    *controller.InstancesController
+	mainServer             *http.Server
+	internalServer         *http.Server
+	clusterServer          *http.Server
+	internalSocketMode     os.FileMode
+	panelAllowlist         atomic.Pointer[ipAllowlist]
+	trustedProxies         ipAllowlist
+	grpcServer             *grpc.Server
+	streamsCtx             context.Context
+	endStreams             context.CancelFunc
+	handover               *handover.Handover
+	handedOver             atomic.Bool
+	mainRouter             *gin.Engine
+	internalRouter         *gin.Engine
 }
 
 func NewServer(cfg *config.Config, log *logger.Logger, core *xray.Core, configMgr *xray.ConfigManager) (*Server, error) {
 	gin.SetMode(gin.ReleaseMode)
 
+	running := *cfg
 	s := &Server{
 		config:        cfg,
+		running:       &running,
 		logger:        log,
 		core:          core,
 		configManager: configMgr,
 	}
 
-	s.xrayController = controller.NewXrayController(core, configMgr, log)
-	s.handlerController = controller.NewHandlerController(core, configMgr, log)
-	s.statsController = controller.NewStatsController(core, log)
-	s.visionController = controller.NewVisionController(core, log)
-	s.internalController = controller.NewInternalController(configMgr, log)
+	var stateKey []byte
+	var err error
+	if cfg.StateEncryptionKey != "" {
+		stateKey, err = state.ParseKey(cfg.StateEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state encryption key: %w", err)
+		}
+	}
+	// replicaStore keeps the state of this process alone, such as its
+	// traffic counters, apart from the state shared with other replicas.
+	var store, replicaStore state.Store
+	if cfg.StateRedisURL != "" {
+		var redisStore *state.RedisStore
+		redisStore, err = state.NewRedisStore(cfg.StateRedisURL, cfg.StateRedisPrefix, stateKey)
+		if err == nil {
+			replica := cfg.NodeID
+			if replica == "" {
+				replica, _ = os.Hostname()
+			}
+			store, replicaStore = redisStore, redisStore.ForReplica(replica)
+		}
+	} else {
+		store, err = state.New(cfg.StateDir, stateKey)
+		replicaStore = store
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
+	}
+	s.store = state.NewFreezableStore(store)
+	s.replicaStore = state.NewFreezableStore(replicaStore)
+	if err := configMgr.AttachStore(s.replicaStore); err != nil {
+		log.WithError(err).Warn("Failed to restore config state, starting fresh")
+	}
+	s.blocklist = vision.NewBlocklist(core, s.store, log)
+	s.ipLimiter = iplimit.NewLimiter(core, s.blocklist, s.store, log)
+	s.expiry = expiry.NewScheduler(s.store, log)
+	s.events = events.NewBus()
+	var eventStore state.Store
+	if cfg.EventHistoryPersist {
+		eventStore = s.replicaStore
+	}
+	s.eventLog = eventlog.New(cfg.EventHistorySize, s.events, eventStore, log)
+	core.OnAuthFailure(s.eventLog.RecordAuthFailure)
+	s.history = history.NewRecorder(core, cfg.StatsHistorySize, log)
+	s.checkpoint = checkpoint.New(core, s.replicaStore, log)
+	s.lastSeen = lastseen.New(core, s.store, log)
+	s.routingRules = routing.NewRegistry(core, s.store, log)
+	s.userRoutes = routing.NewUserRoutes(core, s.store, log)
+	s.consistency = consistency.NewChecker(core, configMgr, time.Duration(cfg.ConsistencyCheckInterval)*time.Second, cfg.ConsistencyAutoRepair, log)
+	s.jobs = jobs.NewManager(log)
+	s.certs = certmon.NewMonitor(log)
+	if err := s.certs.SetCertificates(cfg.Payload.NodeCertPEM, cfg.Payload.CACertPEM); err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	s.publishCoreEvents()
+
+	s.placeholders, err = xray.ParsePlaceholders(cfg.ConfigVars, cfg.ConfigEnvAllowlist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config vars: %w", err)
+	}
+	s.xrayController = controller.NewXrayController(core, configMgr, s.certs, cfg.APIPort, s.placeholders, s.jobs, log)
+	var window *maintenance.Window
+	if cfg.MaintenanceWindow != "" {
+		window, err = maintenance.ParseWindow(cfg.MaintenanceWindow, time.Duration(cfg.MaintenanceWindowDuration)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window: %w", err)
+		}
+	}
+	s.maintenance = maintenance.NewScheduler(window, log)
+	if window != nil {
+		s.xrayController.DeferRestarts(s.maintenance)
+	}
+	s.startSessionController = controller.NewStartSessionController(startsession.NewManager(log), s.xrayController, s.jobs, log)
+	s.handlerController = controller.NewHandlerController(core, configMgr, s.ipLimiter, s.expiry, s.events, s.eventLog, s.jobs, cfg.BulkWorkers, log)
+	s.inboundController = controller.NewInboundController(core, configMgr, log)
+	s.expiry.OnExpire(s.handlerController.ExpireUser)
+	s.statsController = controller.NewStatsController(core, s.history, s.checkpoint, log)
+	s.eventsController = controller.NewEventsController(s.events, s.eventLog, s.statsController, log)
+	s.consistencyController = controller.NewConsistencyController(s.consistency, log)
+	s.lastSeenController = controller.NewLastSeenController(s.lastSeen, log)
+	s.jobsController = controller.NewJobsController(s.jobs, log)
+	s.logsController = controller.NewLogsController(log.Buffer(), log)
+	s.visionController = controller.NewVisionController(s.blocklist, log)
+	s.routingController = controller.NewRoutingController(core, s.routingRules, s.userRoutes, log)
+	s.internalController = controller.NewInternalController(core, configMgr, s.xrayController, s.statsController, s.logsController, log)
+	s.coreManager = xray.NewCoreManager(core, configMgr, log)
+	s.instances = make(map[string]*instanceComponents)
+	s.instancesController = controller.NewInstancesController(s.coreManager, log)
+	labels, err := config.ParseLabels(cfg.NodeLabels)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node labels: %w", err)
+	}
+	s.infoController = controller.NewInfoController(core, s.certs, cfg.NodeID, labels, nodeFeatures(cfg))
+	s.setupInstances()
+	if cfg.StatsPushURL != "" {
+		interval := time.Duration(cfg.StatsPushInterval) * time.Second
+		s.pusher, err = push.NewPusher(cfg.StatsPushURL, interval, cfg, s.statsController, s.replicaStore, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stats pusher: %w", err)
+		}
+	}
+	if cfg.HeartbeatURL != "" {
+		interval := time.Duration(cfg.HeartbeatInterval) * time.Second
+		s.heartbeater, err = push.NewHeartbeater(cfg.HeartbeatURL, interval, cfg, s.xrayController, s.statsController, configMgr, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create heartbeater: %w", err)
+		}
+	}
+	if cfg.TrafficExportURL != "" {
+		s.trafficExporter, err = trafficexport.New(trafficexport.Options{
+			URL:      cfg.TrafficExportURL,
+			Format:   cfg.TrafficExportFormat,
+			Interval: time.Duration(cfg.TrafficExportInterval) * time.Second,
+			Table:    cfg.TrafficExportTable,
+			Token:    cfg.TrafficExportToken,
+		}, s.history, log)
+		if err != nil {
+			return nil, fmt.Errorf("invalid traffic export: %w", err)
+		}
+	}
+	if cfg.WebhookURL != "" {
+		s.webhooks = webhook.NewSink(cfg.WebhookURL, cfg.WebhookSecret, webhook.ParseEvents(cfg.WebhookEvents), s.events, log)
+	}
+	if cfg.BrokerURL != "" {
+		s.broker, err = broker.NewPublisher(cfg.BrokerURL, cfg.BrokerPrefix, cfg.NodeID, webhook.ParseEvents(cfg.BrokerEvents), s.events, log)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event broker: %w", err)
+		}
+	}
+	if (cfg.TelegramBotToken == "") != (cfg.TelegramChatID == "") {
+		return nil, fmt.Errorf("telegram alerts need both a bot token and a chat ID")
+	}
+	if cfg.TelegramBotToken != "" {
+		rateLimit := time.Duration(cfg.TelegramRateLimit) * time.Second
+		s.telegram = telegram.NewNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, rateLimit, s.events, log)
+	}
+	if cfg.OTLPEndpoint != "" {
+		s.telemetry, err = telemetry.Setup(context.Background(), cfg.OTLPEndpoint, version.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+		}
+	}
+	diskPaths := []string{cfg.StateDir}
+	if cfg.GeodataDir != "" {
+		diskPaths = append(diskPaths, cfg.GeodataDir)
+	}
+	s.disks = diskmon.NewMonitor(diskPaths, float64(cfg.DiskMinFreePercent), log)
+	s.disks.OnLow(func(usage diskmon.Usage) {
+		s.events.Publish(events.TypeDiskLow, events.DiskEvent{
+			Path:        usage.Path,
+			FreeBytes:   usage.Free,
+			TotalBytes:  usage.Total,
+			FreePercent: int(usage.FreePercent()),
+		})
+	})
+	s.limits = limitmon.NewMonitor(float64(cfg.LimitWarnPercent), log)
+	s.limits.OnHigh(func(usage limitmon.Usage) {
+		s.events.Publish(events.TypeLimitHigh, events.LimitEvent{
+			Resource:    usage.Resource,
+			Used:        usage.Used,
+			Limit:       usage.Limit,
+			UsedPercent: int(usage.UsedPercent()),
+		})
+	})
+	if cfg.MemoryRestartThreshold > 0 {
+		threshold := uint64(cfg.MemoryRestartThreshold) << 20
+		s.memory = memmon.NewMonitor(threshold, func() (uint64, error) {
+			pid := core.ProcessID()
+			if pid == 0 {
+				return 0, fmt.Errorf("xray is not running")
+			}
+			return memmon.ProcessRSS(pid)
+		}, log)
+		s.memory.OnExceeded(func(rss uint64) {
+			s.events.Publish(events.TypeMemoryHigh, events.MemoryEvent{
+				RSSBytes:        rss,
+				ThresholdBytes:  threshold,
+				RestartDeferred: s.maintenance.Closed(),
+			})
+			s.maintenance.Defer("xray-memory-restart", s.restartForMemory)
+		})
+	}
+	if tags := outboundmon.ParseTags(cfg.OutboundProbeTags); len(tags) > 0 {
+		target, err := outboundmon.ParseTarget(cfg.OutboundProbeTarget)
+		if err != nil {
+			return nil, fmt.Errorf("invalid outbound probe target: %w", err)
+		}
+		if core.IsExternal() {
+			log.Warn("Outbound probes need the embedded xray core, they will fail with an external one")
+		}
+		s.outbounds = outboundmon.New(tags, target,
+			time.Duration(cfg.OutboundProbeInterval)*time.Second,
+			time.Duration(cfg.OutboundProbeTimeout)*time.Second,
+			core.DialOutbound, log)
+	}
+	s.outboundsController = controller.NewOutboundHealthController(s.outbounds, log)
+	s.tokenValidator, err = middleware.NewTokenValidator(cfg.PanelJWTKeys()...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT public key: %w", err)
+	}
+	s.tokenValidator.SetOptions(middleware.ValidationOptions{
+		Issuer:           cfg.JWTIssuer,
+		Audience:         cfg.JWTAudience,
+		Leeway:           time.Duration(cfg.JWTLeeway) * time.Second,
+		ReplayProtection: cfg.JWTReplayProtection,
+	})
+	s.scopePolicy, err = middleware.ParseScopePolicy(cfg.JWTScopes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT scopes: %w", err)
+	}
+	if cfg.JWKSURL != "" {
+		interval := time.Duration(cfg.JWKSRefreshInterval) * time.Second
+		s.jwksRefresher = middleware.NewJWKSRefresher(cfg.JWKSURL, interval, s.tokenValidator, log)
+	}
+	if cfg.SecretKeyRefreshInterval > 0 {
+		source, err := cfg.SecretKeySource()
+		if err != nil {
+			return nil, err
+		}
+		if source != nil {
+			interval := time.Duration(cfg.SecretKeyRefreshInterval) * time.Second
+			s.secretRefresher = secretsource.NewRefresher(source, interval, cfg.SecretKey, s.ReloadCredentials, log)
+		}
+	}
+	if cfg.CRLFile != "" || cfg.CRLURL != "" {
+		interval := time.Duration(cfg.CRLRefreshInterval) * time.Second
+		s.credentials.revocation = revocation.NewChecker(cfg.CRLFile, cfg.CRLURL, interval, s.panelCAs, log)
+	}
+	switch cfg.AccessLog {
+	case "", AccessLogOff, AccessLogErrors, AccessLogAll:
+	default:
+		return nil, fmt.Errorf("invalid access log mode %q", cfg.AccessLog)
+	}
+	allowlist, err := parseIPAllowlist(cfg.PanelAllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid panel allowed IPs: %w", err)
+	}
+	s.panelAllowlist.Store(&allowlist)
+	s.trustedProxies, err = parseIPAllowlist(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	whitelist, err := autoblock.ParseWhitelist(cfg.AutoBlockWhitelist)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auto block whitelist: %w", err)
+	}
+	if cfg.ClusterSecret != "" {
+		s.cluster = cluster.New(cluster.ParsePeers(cfg.ClusterPeers), cfg.ClusterSecret, cfg.NodeID, s.blocklist, log)
+		s.cluster.SetWhitelist(whitelist)
+	}
+	if cfg.AutoBlock {
+		s.autoBlocker = autoblock.New(core, s.blocklist, autoblock.Policy{
+			MaxFailures: cfg.AutoBlockMaxFailures,
+			Window:      time.Duration(cfg.AutoBlockWindow) * time.Second,
+			BanDuration: time.Duration(cfg.AutoBlockBanDuration) * time.Second,
+			Whitelist:   whitelist,
+			IPv6Prefix:  cfg.AutoBlockIPv6Prefix,
+		}, log)
+		s.autoBlocker.OnBlock(func(ip string, banDuration time.Duration) {
+			s.events.Publish(events.TypeIPAutoBlocked, events.IPBlockEvent{IP: ip, BanSeconds: int64(banDuration / time.Second)})
+			if s.cluster != nil {
+				s.cluster.Share(ip, banDuration)
+			}
+		})
+	}
+	if cfg.FirewallBackend != "" {
+		s.firewall, err = firewall.New(cfg.FirewallBackend)
+		if err != nil {
+			return nil, fmt.Errorf("invalid firewall backend: %w", err)
+		}
+	}
+	if cfg.GeodataEmbedded {
+		geodata.UseEmbedded()
+	}
+	assetPath := cfg.AssetPath
+	if assetPath != "" && !cfg.GeodataAutoUpdate {
+		if info, err := os.Stat(assetPath); err != nil {
+			return nil, fmt.Errorf("invalid asset path: %w", err)
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("invalid asset path: %s is not a directory", assetPath)
+		}
+	}
+	if cfg.GeodataAutoUpdate {
+		dir := cfg.GeodataDir
+		if dir == "" {
+			dir = assetPath
+		}
+		if dir == "" {
+			dir = os.Getenv("XRAY_LOCATION_ASSET")
+		}
+		if dir == "" {
+			dir = xray.DefaultAssetDir
+		}
+		// xray has to load the geodata files from where they are kept.
+		if assetPath == "" {
+			assetPath = dir
+		} else if assetPath != dir {
+			log.WithField("assetPath", assetPath).WithField("geodataDir", dir).
+				Warn("Geodata is downloaded outside the asset path, xray will not use it")
+		}
+
+		interval := time.Duration(cfg.GeodataUpdateInterval) * time.Second
+		s.geodata = geodata.NewManager(dir, []geodata.Asset{
+			{Name: "geoip.dat", URL: cfg.GeoIPURL},
+			{Name: "geosite.dat", URL: cfg.GeoSiteURL},
+		}, interval, log)
+		s.geodata.OnChange(func() {
+			if err := s.xrayController.ReloadRouting(); err != nil {
+				log.WithError(err).Error("Failed to reload routing after geodata update")
+			}
+		})
+		if window != nil {
+			s.geodata.DeferUpdates(s.maintenance)
+		}
+	}
+	core.SetAssetPath(assetPath)
+	if cfg.InternalSocketPath != "" {
+		mode, err := strconv.ParseUint(cfg.InternalSocketMode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid internal socket mode %q: %w", cfg.InternalSocketMode, err)
+		}
+		s.internalSocketMode = os.FileMode(mode)
+	}
+	s.metrics = s.setupMetrics()
 	s.mainRouter = s.setupMainRouter()
 	s.internalRouter = s.setupInternalRouter()
 
-	tlsConfig, err := s.buildTLSConfig()
-	if err != nil {
+	if _, err := s.buildTLSConfig(); err != nil {
 		return nil, fmt.Errorf("failed to build TLS config: %w", err)
 	}
+	s.newServers()
+
+	if cfg.GracefulUpgrade {
+		s.handover, err = handover.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up graceful upgrade: %w", err)
+		}
+	}
+
+	// Everything needing the private keys holds them parsed by now.
+	s.config.ForgetSecrets()
+	s.running.ForgetSecrets()
+
+	return s, nil
+}
+
+// newServers creates the servers of the panel and internal APIs, unstarted.
+func (s *Server) newServers() {
+	tlsConfig := s.credentials.tlsConfig()
+	s.streamsCtx, s.endStreams = context.WithCancel(context.Background())
 
 	s.mainServer = &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.NodePort),
+		Addr:         fmt.Sprintf(":%d", s.config.NodePort),
 		Handler:      s.mainRouter,
 		TLSConfig:    tlsConfig,
 		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
 	}
+	s.applyTimeouts(s.mainServer)
 
 	s.internalServer = &http.Server{
-		Addr:    fmt.Sprintf("127.0.0.1:%d", cfg.InternalRestPort),
+		Addr:    fmt.Sprintf("127.0.0.1:%d", s.config.InternalRestPort),
 		Handler: s.internalRouter,
 	}
+	s.applyTimeouts(s.internalServer)
 
-	return s, nil
+	if s.config.GRPCPort > 0 {
+		service := grpcapi.NewService(s.xrayController, s.handlerController, s.statsController, s.logger)
+		s.grpcServer = grpcapi.NewServer(service, tlsConfig.Clone(), s.tokenValidator, s.scopePolicy, s.logger)
+	}
+
+	if s.cluster != nil && s.config.ClusterPort > 0 {
+		s.clusterServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", s.config.ClusterPort),
+			Handler: s.cluster,
+		}
+		s.applyTimeouts(s.clusterServer)
+	}
 }
 
-func (s *Server) buildTLSConfig() (*tls.Config, error) {
-	cert, err := tls.X509KeyPair(
-		[]byte(s.config.Payload.NodeCertPEM),
-		[]byte(s.config.Payload.NodeKeyPEM),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+// publishCoreEvents forwards xray lifecycle changes and certificate expiry
+// warnings to the event bus.
+func (s *Server) publishCoreEvents() {
+	s.core.OnStart(func() {
+		s.events.Publish(events.TypeXrayStarted, events.XrayEvent{Version: s.core.GetVersion()})
+	})
+	s.core.OnStop(func() {
+		s.events.Publish(events.TypeXrayStopped, events.XrayEvent{})
+	})
+	s.core.OnStartFailed(func(err error) {
+		errMsg := err.Error()
+		s.events.Publish(events.TypeXrayCrashed, events.XrayEvent{Error: &errMsg})
+	})
+	s.certs.OnExpiring(func(info certmon.Info) {
+		s.events.Publish(events.TypeCertExpiring, events.CertificateEvent{
+			Name:     info.Name,
+			Subject:  info.Subject,
+			NotAfter: info.NotAfter,
+			DaysLeft: info.DaysLeft,
+			Expired:  !time.Now().Before(info.NotAfter),
+		})
+	})
+}
+
+// restartForMemory restarts xray over its memory threshold, then returns
+// the heap the embedded core freed to the OS.
+func (s *Server) restartForMemory() {
+	if err := s.xrayController.Restart(); err != nil {
+		s.logger.WithError(err).Error("Failed to restart xray over its memory threshold")
+		return
 	}
+	if !s.core.IsExternal() {
+		debug.FreeOSMemory()
+	}
+}
 
-	caCertPool := x509.NewCertPool()
-	if !caCertPool.AppendCertsFromPEM([]byte(s.config.Payload.CACertPEM)) {
-		return nil, fmt.Errorf("failed to parse CA certificate")
+// nodeFeatures flags the optional APIs and behaviors of the node for
+// /node/info. Names are only ever added, so the panel can rely on them.
+func nodeFeatures(cfg *config.Config) map[string]bool {
+	return map[string]bool{
+		"vision":            true,
+		"routing":           true,
+		"events":            true,
+		"jobs":              true,
+		"instances":         true,
+		"patchConfig":       true,
+		"zstd":              true,
+		"logs":              cfg.LogBufferSize > 0,
+		"pushStats":         cfg.StatsPushURL != "",
+		"heartbeat":         cfg.HeartbeatURL != "",
+		"trafficExport":     cfg.TrafficExportURL != "",
+		"grpc":              cfg.GRPCPort > 0,
+		"codedErrors":       cfg.ErrorResponseVersion == config.ErrorResponseCoded,
+		"maintenanceWindow": cfg.MaintenanceWindow != "",
+		"cluster":           cfg.ClusterSecret != "",
+		"eventBroker":       cfg.BrokerURL != "",
+		"outboundHealth":    cfg.OutboundProbeTags != "",
 	}
+}
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequireAndVerifyClientCert,
-		ClientCAs:    caCertPool,
-		MinVersion:   tls.VersionTLS12,
-	}, nil
+// panelCAs returns the current panel CAs, one of which must sign the CRL.
+func (s *Server) panelCAs() []*x509.Certificate {
+	if creds := s.credentials.current.Load(); creds != nil {
+		return creds.cas
+	}
+	return nil
+}
+
+func (s *Server) buildTLSConfig() (*tls.Config, error) {
+	creds, err := parseNodeCredentials(s.config)
+	if err != nil {
+		return nil, err
+	}
+	s.credentials.current.Store(creds)
+
+	return s.credentials.tlsConfig(), nil
 }
 
 func (s *Server) setupMainRouter() *gin.Engine {
 	router := gin.New()
+	// The networks were validated by NewServer.
+	_ = router.SetTrustedProxies(s.trustedProxies.networks())
 	router.Use(gin.Recovery())
+	if s.telemetry != nil {
+		router.Use(middleware.TracingMiddleware())
+	}
 	router.Use(s.loggingMiddleware())
+	router.Use(s.bodyLimitMiddleware())
 	router.Use(s.zstdMiddleware())
-	router.Use(middleware.JWTMiddleware(s.config.Payload.JWTPublicKey, s.logger))
+	router.Use(middleware.ValidatorMiddleware(s.tokenValidator, s.logger))
+	if s.scopePolicy.Enabled() {
+		router.Use(s.scopeMiddleware())
+	}
+	if s.config.ErrorResponseVersion == config.ErrorResponseCoded {
+		router.Use(func(c *gin.Context) {
+			c.Set(controller.CodedErrorsKey, true)
+		})
+	}
 
 	router.NoRoute(s.notFoundHandler())
 
 	nodeGroup := router.Group("/node")
 	{
+		s.infoController.RegisterRoutes(nodeGroup)
+
 		xrayGroup := nodeGroup.Group("/xray")
 		s.xrayController.RegisterRoutes(xrayGroup)
+		s.startSessionController.RegisterRoutes(xrayGroup)
 
 		handlerGroup := nodeGroup.Group("/handler")
 		s.handlerController.RegisterRoutes(handlerGroup)
+		s.consistencyController.RegisterRoutes(handlerGroup)
+		s.inboundController.RegisterRoutes(handlerGroup)
 
 		statsGroup := nodeGroup.Group("/stats")
 		s.statsController.RegisterRoutes(statsGroup)
+		s.lastSeenController.RegisterRoutes(statsGroup)
+		s.outboundsController.RegisterRoutes(statsGroup)
+
+		routingGroup := nodeGroup.Group("/routing")
+		s.routingController.RegisterRoutes(routingGroup)
+
+		s.jobsController.RegisterRoutes(nodeGroup)
+		s.instancesController.RegisterRoutes(nodeGroup)
+
+		streamsGroup := nodeGroup.Group("", s.streamsMiddleware())
+		s.eventsController.RegisterRoutes(streamsGroup)
+		s.logsController.RegisterRoutes(streamsGroup)
 	}
 
 	return router
@@ -122,9 +671,16 @@ func (s *Server) setupMainRouter() *gin.Engine {
 
 func (s *Server) setupInternalRouter() *gin.Engine {
 	router := gin.New()
+	// Local clients are not behind a proxy.
+	_ = router.SetTrustedProxies(nil)
 	router.Use(gin.Recovery())
 	router.Use(s.loggingMiddleware())
-	router.Use(PortGuardMiddleware(s.config.InternalRestPort))
+	router.Use(s.bodyLimitMiddleware())
+	// Only local processes can reach a unix socket, so the port guard is
+	// needed for the TCP listener alone.
+	if s.config.InternalSocketPath == "" {
+		router.Use(PortGuardMiddleware(s.config.InternalRestPort))
+	}
 
 	router.NoRoute(func(c *gin.Context) {
 		c.String(404, "Cannot %s %s", c.Request.Method, c.Request.URL.Path)
@@ -133,8 +689,14 @@ func (s *Server) setupInternalRouter() *gin.Engine {
 	internalGroup := router.Group("/internal")
 	{
 		s.internalController.RegisterRoutes(internalGroup)
+		internalGroup.POST("/reload-credentials", s.handleReloadCredentials)
+		internalGroup.POST("/reload-config", s.handleReloadConfig)
+		internalGroup.POST("/set-log-level", s.handleSetLogLevel)
+		internalGroup.GET("/maintenance", s.handleMaintenance)
 	}
 
+	router.GET("/metrics", s.handleMetrics)
+
 	visionGroup := router.Group("/vision")
 	{
 		s.visionController.RegisterRoutes(visionGroup)
@@ -143,6 +705,177 @@ func (s *Server) setupInternalRouter() *gin.Engine {
 	return router
 }
 
+type logSettings struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// handleSetLogLevel changes the log level and/or format of the whole node
+// until the next restart.
+func (s *Server) handleSetLogLevel(c *gin.Context) {
+	var req logSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errMsg := "invalid request: " + err.Error()
+		c.JSON(http.StatusBadRequest, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	var errMsg string
+	level, levelErr := logger.ParseLevel(req.Level)
+	format, formatErr := logger.ParseFormat(req.Format)
+	switch {
+	case req.Level == "" && req.Format == "":
+		errMsg = "level or format is required"
+	case req.Level != "" && levelErr != nil:
+		errMsg = levelErr.Error()
+	case req.Format != "" && formatErr != nil:
+		errMsg = formatErr.Error()
+	}
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	if req.Level != "" {
+		s.logger.SetLevel(level)
+	}
+	if req.Format != "" {
+		s.logger.SetFormat(format)
+	}
+
+	current := logSettings{Level: string(s.logger.Level()), Format: string(s.logger.Format())}
+	s.logger.WithField("level", current.Level).WithField("format", current.Format).Warn("Log settings changed")
+	c.JSON(http.StatusOK, current)
+}
+
+// handleReloadCredentials reloads the node certificate, CA and JWT keys.
+func (s *Server) handleReloadCredentials(c *gin.Context) {
+	if err := s.ReloadCredentials(); err != nil {
+		s.logger.WithError(err).Error("Failed to reload node credentials")
+		errMsg := "failed to reload credentials: " + err.Error()
+		c.JSON(http.StatusInternalServerError, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	c.JSON(http.StatusOK, struct {
+		Reloaded bool `json:"reloaded"`
+	}{Reloaded: true})
+}
+
+// handleReloadConfig reloads the configuration and reports the changed
+// settings.
+func (s *Server) handleReloadConfig(c *gin.Context) {
+	reload, err := s.ReloadConfig()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to reload configuration")
+		errMsg := "failed to reload configuration: " + err.Error()
+		c.JSON(http.StatusInternalServerError, struct {
+			Error *string `json:"error"`
+		}{Error: &errMsg})
+		return
+	}
+
+	c.JSON(http.StatusOK, reload)
+}
+
+// setupMetrics registers the metrics served on the internal /metrics.
+func (s *Server) setupMetrics() *metrics.Registry {
+	registry := metrics.NewRegistry()
+
+	registry.Gauge("remnawave_node_certificate_expiry_timestamp_seconds",
+		"Expiry time of the node certificate and CA in unix seconds.",
+		func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, cert := range s.certs.Certificates() {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"certificate": cert.Name, "subject": cert.Subject},
+					Value:  float64(cert.NotAfter.Unix()),
+				})
+			}
+			return samples
+		})
+	registry.Gauge("remnawave_node_xray_running", "Whether the xray core is running.",
+		func() []metrics.Sample {
+			value := 0.0
+			if s.core.IsRunning() {
+				value = 1
+			}
+			return []metrics.Sample{{Value: value}}
+		})
+	registry.Gauge("remnawave_node_resource_used", "Open file descriptors of the node and conntrack table entries.",
+		func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, usage := range limitmon.Read() {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"resource": usage.Resource},
+					Value:  float64(usage.Used),
+				})
+			}
+			return samples
+		})
+	registry.Gauge("remnawave_node_resource_limit", "Limit of the open file descriptors of the node and of the conntrack table.",
+		func() []metrics.Sample {
+			var samples []metrics.Sample
+			for _, usage := range limitmon.Read() {
+				samples = append(samples, metrics.Sample{
+					Labels: map[string]string{"resource": usage.Resource},
+					Value:  float64(usage.Limit),
+				})
+			}
+			return samples
+		})
+	registry.Gauge("remnawave_node_outbound_up", "Whether the last probe through the outbound succeeded.",
+		s.outboundSamples(func(status outboundmon.Status) float64 {
+			if status.Alive {
+				return 1
+			}
+			return 0
+		}))
+	registry.Gauge("remnawave_node_outbound_latency_seconds", "Duration of the last successful probe through the outbound.",
+		s.outboundSamples(func(status outboundmon.Status) float64 {
+			return float64(status.LatencyMs) / 1000
+		}))
+	registry.Counter("remnawave_node_outbound_probe_failures_total", "Failed probes through the outbound.",
+		s.outboundSamples(func(status outboundmon.Status) float64 {
+			return float64(status.Failures)
+		}))
+
+	return registry
+}
+
+// outboundSamples collects a value of each probed outbound, labeled with
+// its tag.
+func (s *Server) outboundSamples(value func(outboundmon.Status) float64) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		if s.outbounds == nil {
+			return nil
+		}
+		var samples []metrics.Sample
+		for _, status := range s.outbounds.Statuses() {
+			samples = append(samples, metrics.Sample{
+				Labels: map[string]string{"outbound": status.Outbound},
+				Value:  value(status),
+			})
+		}
+		return samples
+	}
+}
+
+// handleMetrics serves the metrics in the Prometheus text format.
+func (s *Server) handleMetrics(c *gin.Context) {
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if _, err := s.metrics.WriteTo(c.Writer); err != nil {
+		s.logger.WithError(err).Warn("Failed to write metrics")
+	}
+}
+
 func (s *Server) MainRouter() *gin.Engine {
 	return s.mainRouter
 }
@@ -151,31 +884,54 @@ func (s *Server) InternalRouter() *gin.Engine {
 	return s.internalRouter
 }
 
-func (s *Server) loggingMiddleware() gin.HandlerFunc {
+// scopeMiddleware answers 403 when the token scopes do not grant the
+// matched route.
+func (s *Server) scopeMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+
+		claims, _ := c.Get("jwt_claims")
+		mapClaims, _ := claims.(jwt.MapClaims)
+		if !s.scopePolicy.Allows(mapClaims, route) {
+			s.logger.WithField("url", c.Request.URL.String()).
+				WithField("ip", c.ClientIP()).
+				Warn("Token scope does not grant this route")
+			ErrorHandler(apperrors.CodeForbiddenRoleError, c)
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
 
-func (s *Server) zstdMiddleware() gin.HandlerFunc {
-	decoder, _ := zstd.NewReader(nil)
+// applyTimeouts sets the configured timeouts on srv. Zero disables one.
+func (s *Server) applyTimeouts(srv *http.Server) {
+	srv.ReadHeaderTimeout = time.Duration(s.config.HTTPReadHeaderTimeout) * time.Second
+	srv.ReadTimeout = time.Duration(s.config.HTTPReadTimeout) * time.Second
+	srv.WriteTimeout = time.Duration(s.config.HTTPWriteTimeout) * time.Second
+	srv.IdleTimeout = time.Duration(s.config.HTTPIdleTimeout) * time.Second
+}
+
+// bodyLimitMiddleware answers 413 to requests whose body is larger than
+// MaxBodySize, and caps reads of bodies without a Content-Length.
+func (s *Server) bodyLimitMiddleware() gin.HandlerFunc {
+	limit := int64(s.config.MaxBodySize)
 
 	return func(c *gin.Context) {
-		if c.GetHeader("Content-Encoding") == "zstd" {
-			body, err := io.ReadAll(c.Request.Body)
-			if err != nil {
-				c.AbortWithStatus(400)
-				return
-			}
-			decompressed, err := decoder.DecodeAll(body, nil)
-			if err != nil {
-				c.AbortWithStatus(400)
-				return
-			}
-			c.Request.Body = io.NopCloser(bytes.NewReader(decompressed))
-			c.Request.Header.Del("Content-Encoding")
-			c.Request.ContentLength = int64(len(decompressed))
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
 		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
 		c.Next()
 	}
 }
@@ -187,22 +943,148 @@ func (s *Server) notFoundHandler() gin.HandlerFunc {
 }
 
 func (s *Server) Start() error {
-	errCh := make(chan error, 2)
+	if s.jwksRefresher != nil {
+		s.jwksRefresher.Start()
+	}
+	if s.secretRefresher != nil {
+		s.secretRefresher.Start()
+	}
+	// Subscribed before the first certificate and disk checks.
+	s.eventLog.Start()
+	if s.webhooks != nil {
+		s.webhooks.Start()
+	}
+	if s.broker != nil {
+		s.broker.Start()
+	}
+	if s.telegram != nil {
+		s.telegram.Start()
+	}
+	s.certs.Start()
+	s.disks.Start()
+	s.limits.Start()
+	if s.memory != nil {
+		s.memory.Start()
+	}
+	if s.outbounds != nil {
+		s.outbounds.Start()
+	}
+	if s.credentials.revocation != nil {
+		s.credentials.revocation.Start()
+	}
+	s.blocklist.Start()
+	s.routingRules.Start()
+	if s.firewall != nil {
+		if err := s.firewall.Setup(); err != nil {
+			s.logger.WithError(err).Error("Failed to set up firewall, blocking IPs through xray routing only")
+		} else {
+			s.blocklist.SetFirewall(s.firewall)
+		}
+	}
+	s.ipLimiter.Start()
+	if s.autoBlocker != nil {
+		s.autoBlocker.Start()
+	}
+	if s.cluster != nil {
+		s.cluster.Start()
+	}
+	s.expiry.Start()
+	s.eventsController.Start()
+	s.history.Start()
+	s.checkpoint.Start()
+	s.lastSeen.Start()
+	s.consistency.Start()
+	if s.pusher != nil {
+		s.pusher.Start()
+	}
+	if s.heartbeater != nil {
+		s.heartbeater.Start()
+	}
+	if s.trafficExporter != nil {
+		s.trafficExporter.Start()
+	}
+	if s.geodata != nil {
+		s.geodata.Start()
+	}
+	s.maintenance.Start()
+
+	if err := s.xrayController.Resume(); err != nil {
+		s.logger.WithError(err).Error("Failed to resume xray core from persisted config")
+	}
+
+	if err := s.serve(); err != nil {
+		return err
+	}
+
+	if s.config.PIDFile != "" {
+		if err := handover.WritePIDFile(s.config.PIDFile); err != nil {
+			s.logger.WithError(err).Warn("Failed to write PID file")
+		}
+	}
+	if s.handover != nil {
+		if err := s.handover.Ready(); err != nil {
+			s.logger.WithError(err).Warn("Failed to report readiness to the previous process")
+		}
+	}
+	return nil
+}
+
+// serve starts the servers of the panel, internal and cluster APIs.
+func (s *Server) serve() error {
+	errCh := make(chan error, 4)
+
+	mainListener, err := s.listenPanel("main", s.mainServer.Addr)
+	if err != nil {
+		return fmt.Errorf("main server error: %w", err)
+	}
 
 	go func() {
 		s.logger.Info(fmt.Sprintf("Starting main HTTPS server on :%d", s.config.NodePort))
-		if err := s.mainServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		if err := s.mainServer.ServeTLS(mainListener, "", ""); err != nil && err != http.ErrServerClosed {
 			errCh <- fmt.Errorf("main server error: %w", err)
 		}
 	}()
 
+	internalListener, err := s.listenInternal()
+	if err != nil {
+		return fmt.Errorf("internal server error: %w", err)
+	}
+
 	go func() {
-		s.logger.Info(fmt.Sprintf("Starting internal HTTP server on 127.0.0.1:%d", s.config.InternalRestPort))
-		if err := s.internalServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info(fmt.Sprintf("Starting internal HTTP server on %s", internalListener.Addr()))
+		if err := s.internalServer.Serve(internalListener); err != nil && err != http.ErrServerClosed {
 			errCh <- fmt.Errorf("internal server error: %w", err)
 		}
 	}()
 
+	if s.grpcServer != nil {
+		listener, err := s.listenPanel("grpc", fmt.Sprintf(":%d", s.config.GRPCPort))
+		if err != nil {
+			return fmt.Errorf("gRPC server error: %w", err)
+		}
+
+		go func() {
+			s.logger.Info(fmt.Sprintf("Starting gRPC server on :%d", s.config.GRPCPort))
+			if err := s.grpcServer.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+				errCh <- fmt.Errorf("gRPC server error: %w", err)
+			}
+		}()
+	}
+
+	if s.clusterServer != nil {
+		listener, err := s.listen("cluster", "tcp", s.clusterServer.Addr)
+		if err != nil {
+			return fmt.Errorf("cluster server error: %w", err)
+		}
+
+		go func() {
+			s.logger.Info(fmt.Sprintf("Starting cluster server on :%d", s.config.ClusterPort))
+			if err := s.clusterServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				errCh <- fmt.Errorf("cluster server error: %w", err)
+			}
+		}()
+	}
+
 	select {
 	case err := <-errCh:
 		return err
@@ -211,16 +1093,203 @@ func (s *Server) Start() error {
 	}
 }
 
+// listenInternal opens the listener of the internal server: the configured
+// unix socket, replacing a stale one left by a previous run, or the
+// localhost TCP port.
+func (s *Server) listenInternal() (net.Listener, error) {
+	path := s.config.InternalSocketPath
+	if path == "" {
+		return s.listen("internal", "tcp", s.internalServer.Addr)
+	}
+	if s.handover != nil && s.handover.Inherits("internal") {
+		return s.listen("internal", "unix", path)
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := s.listen("internal", "unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, s.internalSocketMode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
 func (s *Server) Stop() error {
-	if err := s.mainServer.Close(); err != nil {
-		return err
+	s.xrayController.Drain()
+	s.shutdownServers()
+	s.drainJobs()
+
+	s.coreManager.StopAll()
+	s.maintenance.Stop()
+	if s.geodata != nil {
+		s.geodata.Stop()
 	}
-	if err := s.internalServer.Close(); err != nil {
-		return err
+	if s.pusher != nil {
+		s.pusher.Stop()
+		// After a graceful upgrade the new process pushes what is pending.
+		if !s.handedOver.Load() {
+			if err := s.pusher.Push(); err != nil {
+				s.logger.WithError(err).Warn("Failed to push stats before exit, kept for the next start")
+			}
+		}
+	}
+	if s.trafficExporter != nil {
+		s.trafficExporter.Stop()
+		// The history is not handed over in a graceful upgrade, so what is
+		// left is exported either way.
+		if err := s.trafficExporter.Export(); err != nil {
+			s.logger.WithError(err).Warn("Failed to export traffic before exit")
+		}
+	}
+	if s.heartbeater != nil {
+		s.heartbeater.Stop()
+		// After a graceful upgrade the node lives on in the new process.
+		if !s.handedOver.Load() {
+			if err := s.heartbeater.Send(true); err != nil {
+				s.logger.WithError(err).Warn("Failed to tell the panel the node is stopping")
+			}
+		}
+	}
+	if s.jwksRefresher != nil {
+		s.jwksRefresher.Stop()
+	}
+	if s.secretRefresher != nil {
+		s.secretRefresher.Stop()
+	}
+	s.consistency.Stop()
+	s.lastSeen.Stop()
+	s.checkpoint.Stop()
+	s.history.Stop()
+	s.eventsController.Stop()
+	s.expiry.Stop()
+	if s.cluster != nil {
+		s.cluster.Stop()
+	}
+	if s.autoBlocker != nil {
+		s.autoBlocker.Stop()
+	}
+	s.ipLimiter.Stop()
+	s.routingRules.Stop()
+	s.blocklist.Stop()
+	// After a graceful upgrade the rules belong to the new process.
+	if s.firewall != nil && !s.handedOver.Load() {
+		s.blocklist.SetFirewall(nil)
+		if err := s.firewall.Close(); err != nil {
+			s.logger.WithError(err).Warn("Failed to remove firewall rules")
+		}
+	}
+	s.certs.Stop()
+	if s.credentials.revocation != nil {
+		s.credentials.revocation.Stop()
+	}
+	s.disks.Stop()
+	s.limits.Stop()
+	if s.outbounds != nil {
+		s.outbounds.Stop()
+	}
+	if s.memory != nil {
+		s.memory.Stop()
+	}
+	if s.telegram != nil {
+		s.telegram.Stop()
+	}
+	if s.webhooks != nil {
+		s.webhooks.Stop()
+	}
+	if s.broker != nil {
+		s.broker.Stop()
+	}
+	s.eventLog.Stop()
+	if closer, ok := s.store.Store.(io.Closer); ok {
+		closer.Close()
+	}
+	if s.telemetry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryFlushTimeout)
+		if err := s.telemetry.Shutdown(ctx); err != nil {
+			s.logger.WithError(err).Warn("Failed to flush telemetry")
+		}
+		cancel()
+	}
+
+	if s.config.PIDFile != "" && !s.handedOver.Load() {
+		os.Remove(s.config.PIDFile)
 	}
 	return nil
 }
 
+// drainJobs rejects new jobs and waits up to ShutdownTimeout for the
+// running ones, before the core they work on is stopped.
+func (s *Server) drainJobs() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := s.jobs.Stop(ctx); err != nil {
+		s.logger.WithError(err).Warn("Jobs still running at shutdown")
+	}
+}
+
+// shutdownServers closes the listeners of the API servers and waits up to
+// ShutdownTimeout for the requests in flight, then closes what is left.
+// Event and log streams are ended right away.
+func (s *Server) shutdownServers() {
+	s.endStreams()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if s.grpcServer != nil {
+		done := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			s.grpcServer.Stop()
+		}
+	}
+	if err := s.mainServer.Shutdown(ctx); err != nil {
+		s.logger.WithError(err).Warn("Main server did not drain in time")
+		s.mainServer.Close()
+	}
+	if err := s.internalServer.Shutdown(ctx); err != nil {
+		s.logger.WithError(err).Warn("Internal server did not drain in time")
+		s.internalServer.Close()
+	}
+	if s.clusterServer != nil {
+		if err := s.clusterServer.Shutdown(ctx); err != nil {
+			s.logger.WithError(err).Warn("Cluster server did not drain in time")
+			s.clusterServer.Close()
+		}
+	}
+}
+
+// streamsMiddleware ends the request when the servers shut down, for
+// streams that would otherwise hold the drain until its timeout.
+func (s *Server) streamsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.streamsCtx, cancel)
+		defer stop()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
 func destroySocket(c *gin.Context) {
 	defer func() {
 		recover()
@@ -1,23 +1,37 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
+	"fmt"
+	"io"
 	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/remnawave/node-go/internal/api/controller"
 	"github.com/remnawave/node-go/internal/config"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/routing"
+	"github.com/remnawave/node-go/internal/state"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
@@ -212,3 +226,395 @@ func TestPortGuardMiddleware_AllowsInternal(t *testing.T) {
 
 	assert.Equal(t, 200, w.Code)
 }
+
+func TestInternalServer_UnixSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	socketPath := filepath.Join(t.TempDir(), "internal.sock")
+	cfg := &config.Config{
+		NodePort:           2222,
+		InternalRestPort:   61001,
+		InternalSocketPath: socketPath,
+		InternalSocketMode: "0660",
+		StateDir:           t.TempDir(),
+		Payload:            payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	// A stale socket from a previous run is replaced.
+	stale, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := server.listenInternal()
+	require.NoError(t, err)
+	go server.internalServer.Serve(listener)
+	defer server.internalServer.Close()
+
+	info, err := os.Stat(socketPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	resp, err := client.Get("http://unix/internal/get-config")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, "{}", string(body))
+}
+
+func TestInternalServer_InvalidSocketMode(t *testing.T) {
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:           2222,
+		InternalRestPort:   61001,
+		InternalSocketPath: filepath.Join(t.TempDir(), "internal.sock"),
+		InternalSocketMode: "rw",
+		StateDir:           t.TempDir(),
+		Payload:            payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	_, err = NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	assert.Error(t, err)
+}
+
+func TestNewServer_InvalidMaintenanceWindow(t *testing.T) {
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:                  2222,
+		InternalRestPort:          61001,
+		StateDir:                  t.TempDir(),
+		MaintenanceWindow:         "0 25 * * *",
+		MaintenanceWindowDuration: 3600,
+		Payload:                   payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	_, err = NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	assert.ErrorContains(t, err, "invalid maintenance window")
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:            2222,
+		InternalRestPort:    61001,
+		InternalSocketPath:  filepath.Join(t.TempDir(), "internal.sock"),
+		InternalSocketMode:  "0600",
+		MaxBodySize:         2048,
+		MaxDecompressedSize: 2048,
+		HTTPReadTimeout:     30,
+		Payload:             payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, server.mainServer.ReadTimeout)
+	assert.Equal(t, time.Duration(0), server.internalServer.WriteTimeout)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/vision/block-ip", strings.NewReader(`{"ip":"203.0.113.10","padding":"`+strings.Repeat("x", 2048)+`"}`))
+	server.InternalRouter().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// A small zstd body that decompresses past the limit is rejected too.
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	compressed := encoder.EncodeAll(bytes.Repeat([]byte{' '}, 4096), nil)
+	require.Less(t, len(compressed), 2048)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/node/handler/add-user", bytes.NewReader(compressed))
+	req.Header.Set("Content-Encoding", "zstd")
+	server.MainRouter().ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/node/handler/add-user", bytes.NewReader(encoder.EncodeAll([]byte(`{}`), nil)))
+	req.Header.Set("Content-Encoding", "zstd")
+	server.MainRouter().ServeHTTP(w, req)
+	assert.NotEqual(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestInstances(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		Payload:          payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+	defer server.coreManager.StopAll()
+
+	// The main router requires a panel token; serve the routes bare.
+	router := gin.New()
+	server.instancesController.RegisterRoutes(router.Group("/node"))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/node/instances", `{"id":"group-a"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusConflict, do("POST", "/node/instances", `{"id":"group-a"}`).Code)
+
+	start := `{"xrayConfig":{"log":{"loglevel":"none"},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]},"internals":{"forceRestart":false,"hashes":{"emptyConfig":"","inbounds":[]}}}`
+	w = do("POST", "/node/instances/group-a/xray/start", start)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"isStarted":true`)
+
+	w = do("GET", "/node/instances/group-a/xray/status", "")
+	assert.Contains(t, w.Body.String(), `"isRunning":true`)
+	w = do("GET", "/node/instances/default/xray/status", "")
+	assert.Contains(t, w.Body.String(), `"isRunning":false`)
+
+	w = do("GET", "/node/instances", "")
+	assert.Contains(t, w.Body.String(), `{"id":"default","isRunning":false,"version":null}`)
+	assert.Contains(t, w.Body.String(), `"id":"group-a","isRunning":true`)
+
+	assert.Equal(t, http.StatusOK, do("DELETE", "/node/instances/group-a", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/node/instances/group-a/xray/status", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("DELETE", "/node/instances/default", "").Code)
+}
+
+func TestNodeInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		NodeLabels:       "region=eu, tier=premium",
+		StatsPushURL:     "https://panel.example.com/stats",
+		Payload:          payload,
+	}
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	router := gin.New()
+	server.infoController.RegisterRoutes(router.Group("/node"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/node/info", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Response controller.NodeInfoResponse `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	info := resp.Response
+	assert.Len(t, info.Fingerprint, 64)
+	assert.Equal(t, info.Fingerprint, info.NodeID)
+	assert.Equal(t, "localhost", info.CommonName)
+	assert.Equal(t, map[string]string{"region": "eu", "tier": "premium"}, info.Labels)
+	assert.NotEmpty(t, info.CoreVersion)
+	assert.Equal(t, "embedded", info.CoreMode)
+	assert.Contains(t, info.Protocols, "vless")
+	assert.True(t, info.Features["vision"])
+	assert.True(t, info.Features["routing"])
+	assert.True(t, info.Features["pushStats"])
+	assert.False(t, info.Features["heartbeat"])
+
+	// The server forgets the node key of its configuration.
+	cfg.Payload, err = generateTestCerts()
+	require.NoError(t, err)
+	cfg.NodeID = "node-eu-1"
+	server, err = NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+	assert.Equal(t, "node-eu-1", server.infoController.Info().NodeID)
+
+	cfg.NodeLabels = "region"
+	_, err = NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	assert.EqualError(t, err, `invalid node labels: invalid label "region", expected key=value`)
+}
+
+func TestUpgrade_Disabled(t *testing.T) {
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		StateDir:         t.TempDir(),
+		Payload:          payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	_, err = server.Upgrade()
+	assert.ErrorIs(t, err, ErrUpgradeDisabled)
+}
+
+func TestStop_DrainsRequestsAndRejectsStart(t *testing.T) {
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: listener.Addr().(*net.TCPAddr).Port,
+		StateDir:         t.TempDir(),
+		ShutdownTimeout:  5,
+		Payload:          payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	inFlight := make(chan struct{})
+	server.internalRouter.GET("/slow", func(c *gin.Context) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	go server.internalServer.Serve(listener)
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{body: string(body), err: err}
+	}()
+
+	<-inFlight
+	require.NoError(t, server.Stop())
+
+	r := <-results
+	require.NoError(t, r.err)
+	assert.Equal(t, "done", r.body)
+
+	_, status := server.xrayController.Start(controller.StartRequest{})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+// recordingStore counts the writes reaching a store.
+type recordingStore struct {
+	state.Store
+	mu     sync.Mutex
+	writes map[string]int
+}
+
+func (s *recordingStore) Save(key string, v interface{}) error {
+	s.mu.Lock()
+	s.writes[key]++
+	s.mu.Unlock()
+	return s.Store.Save(key, v)
+}
+
+func (s *recordingStore) Delete(key string) error {
+	s.mu.Lock()
+	s.writes[key]++
+	s.mu.Unlock()
+	return s.Store.Delete(key)
+}
+
+func (s *recordingStore) reset() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writes := s.writes
+	s.writes = make(map[string]int)
+	return writes
+}
+
+func TestServer_FrozenStoresDropComponentWrites(t *testing.T) {
+	payload, err := generateTestCerts()
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		NodePort:         2222,
+		InternalRestPort: 61001,
+		StateDir:         t.TempDir(),
+		Payload:          payload,
+	}
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	server, err := NewServer(cfg, log, xray.NewCore(log), xray.NewConfigManager(log))
+	require.NoError(t, err)
+
+	recorder := &recordingStore{Store: state.NewMemoryStore(), writes: make(map[string]int)}
+	server.store.Store = recorder
+	server.replicaStore.Store = recorder
+
+	write := func(i int) {
+		user := fmt.Sprintf("user-%d", i)
+		_, err := server.blocklist.Block(fmt.Sprintf("192.0.2.%d", i), time.Hour)
+		require.NoError(t, err)
+		server.ipLimiter.SetLimit(user, i)
+		server.expiry.Set(user, "", time.Now().Add(time.Hour))
+		server.lastSeen.Record(user, "198.51.100.1", time.Now())
+		_, err = server.routingRules.Add(routing.Rule{Tag: fmt.Sprintf("rule-%d", i), Source: "203.0.113.1", OutboundTag: "direct"}, 0)
+		require.NoError(t, err)
+		require.NoError(t, server.userRoutes.Set(user, "direct"))
+		server.checkpoint.Snapshot()
+	}
+
+	write(1)
+	server.lastSeen.Stop()
+	writes := recorder.reset()
+	for _, key := range []string{"blocked-ips", "ip-limits", "user-expiry", "last-seen", "routing-rules", "user-outbounds"} {
+		assert.NotZero(t, writes[key], "unfrozen %s writes reach the store", key)
+	}
+
+	server.store.Freeze()
+	server.replicaStore.Freeze()
+	write(2)
+	server.lastSeen.Stop()
+	server.checkpoint.Stop()
+	server.blocklist.Stop()
+	server.ipLimiter.Stop()
+	server.expiry.Stop()
+	server.routingRules.Stop()
+	assert.Empty(t, recorder.reset())
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// upgradeTimeout bounds the wait for a new process to take over.
+const upgradeTimeout = time.Minute
+
+// UpgradeDrainTimeout is how long the xray connections of a process that
+// handed over get to finish before it exits.
+const UpgradeDrainTimeout = 30 * time.Second
+
+var (
+	ErrUpgradeDisabled = errors.New("graceful upgrade is disabled")
+	ErrUpgradeExternal = errors.New("graceful upgrade requires the embedded xray core")
+)
+
+// listen opens the listener called name, taking it over from the previous
+// process after a graceful upgrade.
+func (s *Server) listen(name, network, address string) (net.Listener, error) {
+	if s.handover == nil {
+		return net.Listen(network, address)
+	}
+	return s.handover.Listen(name, network, address)
+}
+
+// Upgrade hands the node over to a new process of its binary, typically
+// after the binary was replaced. The API servers stop taking requests,
+// the state is persisted for the new process to restore, and the new
+// process takes over the listening sockets; panel connections wait in the
+// socket backlog meanwhile. The inbounds of xray listen with SO_REUSEPORT,
+// so the new core binds them while this one still serves.
+//
+// On success the xray core of this process closes its inbound listeners,
+// so the kernel hands new connections to the new core only, and keeps
+// serving the connections it accepted. Stop must follow, at the latest
+// after UpgradeDrainTimeout, then the process exits: connections still open
+// by then are closed with it. If the new process fails to start, this one
+// serves again.
+func (s *Server) Upgrade() (int, error) {
+	if s.handover == nil {
+		return 0, ErrUpgradeDisabled
+	}
+	if s.core.IsExternal() {
+		return 0, ErrUpgradeExternal
+	}
+
+	pid, err := s.handover.Upgrade(upgradeTimeout, func() {
+		s.shutdownServers()
+		// Traffic counted from here on is lost with this process.
+		s.checkpoint.Snapshot()
+		s.store.Freeze()
+		s.replicaStore.Freeze()
+	})
+	if err != nil {
+		s.store.Unfreeze()
+		s.replicaStore.Unfreeze()
+		s.newServers()
+		if serveErr := s.serve(); serveErr != nil {
+			return 0, fmt.Errorf("%w; failed to serve again: %v", err, serveErr)
+		}
+		return 0, err
+	}
+
+	s.handedOver.Store(true)
+	if err := s.core.CloseInbounds(); err != nil {
+		s.logger.WithError(err).Warn("Failed to close xray inbounds after the upgrade")
+	}
+	return pid, nil
+}
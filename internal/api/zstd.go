@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdMiddleware decompresses zstd request bodies while the handler reads
+// them, with a decoder taken from a pool, so a small body cannot expand
+// into memory before the handler sees it. The decompressed body is capped
+// at MaxDecompressedSize: a frame declaring a larger size is answered with
+// 413 right away, and reading past the cap fails with *http.MaxBytesError.
+func (s *Server) zstdMiddleware() gin.HandlerFunc {
+	limit := int64(s.config.MaxDecompressedSize)
+
+	options := []zstd.DOption{zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true)}
+	if limit > 0 {
+		// Bounds the window, the memory held while streaming.
+		options = append(options, zstd.WithDecoderMaxMemory(uint64(limit)))
+	}
+	decoders := &sync.Pool{
+		New: func() any {
+			decoder, err := zstd.NewReader(nil, options...)
+			if err != nil {
+				return nil
+			}
+			return decoder
+		},
+	}
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Content-Encoding") != "zstd" {
+			c.Next()
+			return
+		}
+
+		compressed := bufio.NewReader(c.Request.Body)
+		if limit > 0 && declaredSizeExceeds(compressed, limit) {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		decoder, _ := decoders.Get().(*zstd.Decoder)
+		if decoder == nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if err := decoder.Reset(compressed); err != nil {
+			decoders.Put(decoder)
+			c.AbortWithStatus(400)
+			return
+		}
+
+		body := &zstdBody{decoder: decoder, body: c.Request.Body, limit: limit, remaining: limit}
+		defer func() {
+			decoder.Reset(nil)
+			decoders.Put(decoder)
+		}()
+
+		c.Request.Body = body
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// declaredSizeExceeds reports whether the first frame header declares a
+// content size above limit. Frames without a declared size are checked
+// while streaming instead.
+func declaredSizeExceeds(r *bufio.Reader, limit int64) bool {
+	prefix, _ := r.Peek(zstd.HeaderMaxSize)
+
+	var header zstd.Header
+	if err := header.Decode(prefix); err != nil {
+		return false
+	}
+	return header.HasFCS && header.FrameContentSize > uint64(limit)
+}
+
+// zstdBody streams the decompressed request body, failing once more than
+// limit bytes come out of the decoder. A limit of zero disables the cap.
+type zstdBody struct {
+	decoder   *zstd.Decoder
+	body      io.Closer
+	limit     int64
+	remaining int64
+}
+
+func (b *zstdBody) Read(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.decoder.Read(p)
+	}
+
+	// Read one byte past the cap to tell a body of exactly limit bytes from
+	// a longer one.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.decoder.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, &http.MaxBytesError{Limit: b.limit}
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *zstdBody) Close() error {
+	return b.body.Close()
+}
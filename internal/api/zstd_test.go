@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+// streamCompress compresses data as a stream, without a declared content
+// size in the frame header.
+func streamCompress(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = encoder.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, encoder.Close())
+	return buf.Bytes()
+}
+
+func TestZstdMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := &Server{config: &config.Config{MaxDecompressedSize: 4096}}
+	router := gin.New()
+	router.Use(server.zstdMiddleware())
+	router.POST("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Data(http.StatusOK, "application/octet-stream", body)
+	})
+
+	post := func(body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/echo", bytes.NewReader(body))
+		req.Header.Set("Content-Encoding", "zstd")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Decoders are reused across requests.
+	for i := 0; i < 3; i++ {
+		exact := bytes.Repeat([]byte{'a' + byte(i)}, 4096)
+		w := post(streamCompress(t, exact))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, exact, w.Body.Bytes())
+	}
+
+	// Without a declared size the cap is hit while streaming.
+	w := post(streamCompress(t, bytes.Repeat([]byte{'a'}, 4097)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// A declared size above the cap is rejected before decoding.
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	w = post(encoder.EncodeAll(bytes.Repeat([]byte{'a'}, 4097), nil))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = post([]byte("not zstd"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/echo", bytes.NewReader([]byte("plain"))))
+	assert.Equal(t, "plain", w.Body.String())
+}
@@ -0,0 +1,272 @@
+package autoblock
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	janitorInterval = time.Minute
+	// pendingQueue bounds the IPs and IPv6 networks waiting to be blocked;
+	// further ones are blocked on their next failure.
+	pendingQueue = 256
+)
+
+// Policy sets when an IP is blocked: after MaxFailures authentication
+// failures within Window, for BanDuration. IPs in Whitelist and loopback
+// addresses are never blocked.
+//
+// IPv6 clients usually hold a whole /64 and move within it, so the
+// failures of IPv6 addresses are counted and blocked per IPv6Prefix
+// network. Zero or 128 counts every address on its own.
+type Policy struct {
+	MaxFailures int
+	Window      time.Duration
+	BanDuration time.Duration
+	Whitelist   []*net.IPNet
+	IPv6Prefix  int
+}
+
+// source returns what the failures of ip are counted and blocked for: its
+// IPv6Prefix network for an IPv6 address, unless the network holds a
+// whitelisted address, and the address otherwise.
+func (p Policy) source(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() || addr.Is4In6() || p.IPv6Prefix <= 0 || p.IPv6Prefix >= 128 {
+		return ip
+	}
+	prefix, err := addr.WithZone("").Prefix(p.IPv6Prefix)
+	if err != nil {
+		return ip
+	}
+	for _, ipNet := range p.Whitelist {
+		whitelisted, ok := netip.AddrFromSlice(ipNet.IP)
+		ones, _ := ipNet.Mask.Size()
+		if ok && prefix.Overlaps(netip.PrefixFrom(whitelisted, ones)) {
+			return ip
+		}
+	}
+	return prefix.String()
+}
+
+// ParseWhitelist parses a comma-separated list of IPs and CIDR ranges.
+func ParseWhitelist(value string) ([]*net.IPNet, error) {
+	var whitelist []*net.IPNet
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			whitelist = append(whitelist, ipNet)
+			continue
+		}
+		ip := net.ParseIP(item)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP or CIDR %q", item)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		whitelist = append(whitelist, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return whitelist, nil
+}
+
+// Blocker blocks the source IPs of repeated authentication failures and
+// REALITY probes reported by xray, fail2ban style, through the vision
+// blocklist. Blocks expire after the ban duration.
+type Blocker struct {
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	policy    Policy
+	blocklist *vision.Blocklist
+	log       *logger.Logger
+	onBlock   func(ip string, banDuration time.Duration)
+
+	pending chan string
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New registers a core hook counting the authentication failures.
+func New(core *xray.Core, blocklist *vision.Blocklist, policy Policy, log *logger.Logger) *Blocker {
+	b := &Blocker{
+		failures:  make(map[string][]time.Time),
+		policy:    policy,
+		blocklist: blocklist,
+		log:       log,
+		pending:   make(chan string, pendingQueue),
+	}
+
+	core.OnAuthFailure(b.recordFailure)
+
+	return b
+}
+
+// OnBlock sets a function called with each IP the blocker blocked. It must
+// be set before Start.
+func (b *Blocker) OnBlock(fn func(ip string, banDuration time.Duration)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.onBlock = fn
+}
+
+// SetPolicy replaces the policy. Failures already counted are kept and
+// judged by the new policy.
+func (b *Blocker) SetPolicy(policy Policy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.policy = policy
+}
+
+func (b *Blocker) recordFailure(failure xray.AuthFailure) {
+	if b.Failure(failure.IP, failure.Time) {
+		b.log.WithField("ip", failure.IP).WithField("reason", failure.Reason).
+			Warn("Too many authentication failures, blocking IP")
+	}
+}
+
+// Failure counts an authentication failure of ip and reports whether it
+// reached the threshold, in which case ip, or its IPv6 network, is queued
+// for blocking.
+func (b *Blocker) Failure(ip string, at time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.whitelistedLocked(ip) {
+		return false
+	}
+
+	source := b.policy.source(ip)
+	recent := append(pruneBefore(b.failures[source], at.Add(-b.policy.Window)), at)
+	b.failures[source] = recent
+	if len(recent) < b.policy.MaxFailures {
+		return false
+	}
+
+	// Blocking updates the xray routing rules, which must not happen on the
+	// xray connection path the failure is reported from. With the queue
+	// full, the next failure tries again.
+	select {
+	case b.pending <- source:
+		delete(b.failures, source)
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *Blocker) whitelistedLocked(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return true
+	}
+	if parsed.IsLoopback() {
+		return true
+	}
+	for _, ipNet := range b.policy.Whitelist {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneBefore drops the times before cutoff from the sorted times.
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// Start launches the goroutine that blocks the queued IPs and forgets
+// stale failures.
+func (b *Blocker) Start() {
+	b.mu.Lock()
+	if b.stopCh != nil {
+		b.mu.Unlock()
+		return
+	}
+	b.stopCh = make(chan struct{})
+	stopCh := b.stopCh
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case ip := <-b.pending:
+				b.block(ip)
+			case now := <-ticker.C:
+				b.removeStale(now)
+			}
+		}
+	}()
+}
+
+// Stop terminates the goroutine.
+func (b *Blocker) Stop() {
+	b.mu.Lock()
+	stopCh := b.stopCh
+	b.stopCh = nil
+	b.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		b.wg.Wait()
+	}
+}
+
+func (b *Blocker) block(ip string) {
+	if b.blocklist.IsBlocked(ip) {
+		return
+	}
+	b.mu.Lock()
+	banDuration, onBlock := b.policy.BanDuration, b.onBlock
+	b.mu.Unlock()
+
+	if _, err := b.blocklist.Block(ip, banDuration); err != nil {
+		b.log.WithError(err).WithField("ip", ip).Error("Failed to block IP")
+		return
+	}
+	if onBlock != nil {
+		onBlock(ip, banDuration)
+	}
+}
+
+// removeStale forgets the IPs without failures within the window.
+func (b *Blocker) removeStale(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cutoff := now.Add(-b.policy.Window)
+
+	for ip, times := range b.failures {
+		if recent := pruneBefore(times, cutoff); len(recent) > 0 {
+			b.failures[ip] = recent
+		} else {
+			delete(b.failures, ip)
+		}
+	}
+}
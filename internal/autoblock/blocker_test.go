@@ -0,0 +1,129 @@
+package autoblock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestBlocker(t *testing.T, whitelist string) (*Blocker, *vision.Blocklist) {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	blocklist := vision.NewBlocklist(core, state.NewMemoryStore(), log)
+
+	parsed, err := ParseWhitelist(whitelist)
+	require.NoError(t, err)
+	policy := Policy{MaxFailures: 3, Window: time.Minute, BanDuration: time.Hour, Whitelist: parsed}
+	return New(core, blocklist, policy, log), blocklist
+}
+
+func TestParseWhitelist(t *testing.T) {
+	whitelist, err := ParseWhitelist(" 10.0.0.0/8, 203.0.113.5 ,2001:db8::1,")
+	require.NoError(t, err)
+	require.Len(t, whitelist, 3)
+	assert.Equal(t, "10.0.0.0/8", whitelist[0].String())
+	assert.Equal(t, "203.0.113.5/32", whitelist[1].String())
+	assert.Equal(t, "2001:db8::1/128", whitelist[2].String())
+
+	_, err = ParseWhitelist("10.0.0.1,not-an-ip")
+	assert.Error(t, err)
+}
+
+func TestBlocker_ThresholdWithinWindow(t *testing.T) {
+	b, _ := newTestBlocker(t, "")
+	start := time.Now()
+
+	assert.False(t, b.Failure("198.51.100.1", start))
+	assert.False(t, b.Failure("198.51.100.1", start.Add(10*time.Second)))
+	// The first failure left the window.
+	assert.False(t, b.Failure("198.51.100.1", start.Add(65*time.Second)))
+	assert.True(t, b.Failure("198.51.100.1", start.Add(70*time.Second)))
+
+	// Counting starts over once an IP is queued.
+	assert.False(t, b.Failure("198.51.100.1", start.Add(71*time.Second)))
+
+	b.removeStale(start.Add(time.Hour))
+	b.mu.Lock()
+	assert.Empty(t, b.failures)
+	b.mu.Unlock()
+}
+
+func TestBlocker_Whitelist(t *testing.T) {
+	b, _ := newTestBlocker(t, "10.0.0.0/8")
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		assert.False(t, b.Failure("10.1.2.3", now))
+		assert.False(t, b.Failure("127.0.0.1", now))
+		assert.False(t, b.Failure("::1", now))
+	}
+}
+
+func TestBlocker_SetPolicy(t *testing.T) {
+	b, _ := newTestBlocker(t, "")
+	now := time.Now()
+
+	assert.False(t, b.Failure("10.1.2.3", now))
+
+	whitelist, err := ParseWhitelist("10.0.0.0/8")
+	require.NoError(t, err)
+	b.SetPolicy(Policy{MaxFailures: 2, Window: time.Minute, BanDuration: time.Hour, Whitelist: whitelist})
+	assert.False(t, b.Failure("10.1.2.3", now))
+
+	assert.False(t, b.Failure("198.51.100.1", now))
+	assert.True(t, b.Failure("198.51.100.1", now))
+}
+
+func TestBlocker_IPv6Prefix(t *testing.T) {
+	b, _ := newTestBlocker(t, "2001:db8:ffff::1")
+	whitelist := b.policy.Whitelist
+	b.SetPolicy(Policy{MaxFailures: 3, Window: time.Minute, BanDuration: time.Hour, Whitelist: whitelist, IPv6Prefix: 64})
+	now := time.Now()
+
+	// Addresses of one /64 count together.
+	assert.False(t, b.Failure("2001:db8:1:2::1", now))
+	assert.False(t, b.Failure("2001:db8:1:2:aaaa::1", now))
+	assert.False(t, b.Failure("2001:db8:1:3::1", now))
+	assert.True(t, b.Failure("2001:db8:1:2:ffff:ffff:ffff:ffff", now))
+	assert.Equal(t, "2001:db8:1:2::/64", <-b.pending)
+
+	// IPv4 and IPv4-mapped addresses are counted on their own.
+	assert.Equal(t, "192.0.2.1", b.policy.source("192.0.2.1"))
+	assert.Equal(t, "::ffff:192.0.2.1", b.policy.source("::ffff:192.0.2.1"))
+
+	// A network holding a whitelisted address is not blocked as a whole.
+	assert.Equal(t, "2001:db8:ffff::2", b.policy.source("2001:db8:ffff::2"))
+
+	b.SetPolicy(Policy{MaxFailures: 3, Window: time.Minute, BanDuration: time.Hour, IPv6Prefix: 128})
+	assert.Equal(t, "2001:db8:1:2::1", b.policy.source("2001:db8:1:2::1"))
+}
+
+func TestBlocker_BlocksThroughBlocklist(t *testing.T) {
+	b, blocklist := newTestBlocker(t, "")
+	blocked := make(chan string, 1)
+	b.OnBlock(func(ip string, banDuration time.Duration) {
+		assert.Equal(t, time.Hour, banDuration)
+		blocked <- ip
+	})
+	b.Start()
+	defer b.Stop()
+
+	for i := 0; i < 3; i++ {
+		b.recordFailure(xray.AuthFailure{IP: "198.51.100.2", Reason: "invalid user", Time: time.Now()})
+	}
+
+	require.Eventually(t, func() bool { return blocklist.IsBlocked("198.51.100.2") }, time.Second, 10*time.Millisecond)
+	entries := blocklist.List()
+	require.Len(t, entries, 1)
+	require.NotNil(t, entries[0].ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *entries[0].ExpiresAt, time.Minute)
+	assert.Equal(t, "198.51.100.2", <-blocked)
+}
@@ -0,0 +1,297 @@
+// Package broker publishes node events to a NATS or MQTT message broker,
+// so a panel can react to them as they happen instead of polling the node.
+//
+// Only the publishing side of both protocols is implemented: NATS
+// messages are sent with PUB, MQTT messages with QoS 0. An event becomes a
+// JSON message on <prefix>.<event type> for NATS, e.g.
+// remnawave.node.ip.autoblocked, and on the same topic with "/" for "."
+// for MQTT, e.g. remnawave/node/ip/autoblocked.
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// DefaultPrefix starts the subjects and topics of the events.
+	DefaultPrefix = "remnawave.node"
+
+	dialTimeout  = 10 * time.Second
+	writeTimeout = 10 * time.Second
+	// queueSize is the number of events waiting for the broker before new
+	// ones are dropped.
+	queueSize = 1024
+	// maxAttempts bounds the publications of one event; between them the
+	// wait doubles from initialBackoff up to maxBackoff.
+	maxAttempts    = 4
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	// drainTimeout bounds the publication of queued events on Stop.
+	drainTimeout = 5 * time.Second
+)
+
+// errTooLarge is returned for a message the broker does not take. It is
+// dropped right away, since it would never be taken.
+var errTooLarge = errors.New("message exceeds the maximum payload of the broker")
+
+// Message is the body published for an event.
+type Message struct {
+	events.Event
+	// Node is the NODE_ID of the node, or its hostname, to tell nodes
+	// apart on a shared broker.
+	Node string `json:"node"`
+}
+
+// conn is a connection to a broker.
+type conn interface {
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+// Publisher publishes the selected events of a bus to a broker, one at a
+// time and in order. It connects on the first event and again after a
+// failure; an event is dropped after maxAttempts or when the queue is full.
+type Publisher struct {
+	url     *url.URL
+	prefix  string
+	types   map[string]struct{}
+	bus     *events.Bus
+	node    string
+	log     *logger.Logger
+	backoff time.Duration
+	dial    func(u *url.URL, clientID string) (conn, error)
+
+	conn conn
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// ParseURL checks a broker URL: nats:// or tls:// for NATS, mqtt:// or
+// mqtts:// for MQTT, with the credentials in its user info.
+func ParseURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %w", err)
+	}
+	switch u.Scheme {
+	case "nats", "tls", "mqtt", "mqtts":
+	default:
+		return nil, fmt.Errorf("invalid broker URL scheme %q, expected nats, tls, mqtt or mqtts", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("broker URL has no host")
+	}
+	return u, nil
+}
+
+// NewPublisher creates a publisher for node, the hostname when empty, to the
+// broker of rawURL for the given event types, all of them when empty, on
+// subjects starting with prefix.
+func NewPublisher(rawURL, prefix, node string, types []string, bus *events.Bus, log *logger.Logger) (*Publisher, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+
+	p := &Publisher{
+		url:     u,
+		prefix:  strings.TrimSuffix(prefix, "."),
+		bus:     bus,
+		node:    node,
+		log:     log,
+		backoff: initialBackoff,
+		dial:    dial,
+	}
+	if len(types) > 0 {
+		p.types = make(map[string]struct{}, len(types))
+		for _, t := range types {
+			p.types[t] = struct{}{}
+		}
+	}
+	return p, nil
+}
+
+// dial connects to the broker of u with the protocol of its scheme.
+func dial(u *url.URL, clientID string) (conn, error) {
+	switch u.Scheme {
+	case "mqtt", "mqtts":
+		return dialMQTT(u, clientID)
+	default:
+		return dialNATS(u, clientID)
+	}
+}
+
+// Topic returns the subject, or for MQTT the topic, of an event type.
+func (p *Publisher) Topic(eventType string) string {
+	topic := p.prefix + "." + eventType
+	if p.isMQTT() {
+		topic = strings.ReplaceAll(topic, ".", "/")
+	}
+	return topic
+}
+
+func (p *Publisher) isMQTT() bool {
+	return p.url.Scheme == "mqtt" || p.url.Scheme == "mqtts"
+}
+
+// Start subscribes to the bus and launches the publishing goroutine.
+func (p *Publisher) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopCh != nil {
+		return
+	}
+
+	p.stopCh = make(chan struct{})
+	p.done = make(chan struct{})
+	stopCh, done := p.stopCh, p.done
+	ch, unsubscribe := p.bus.Subscribe()
+	queue := make(chan events.Event, queueSize)
+
+	enqueue := func(event events.Event) {
+		if p.types != nil {
+			if _, selected := p.types[event.Type]; !selected {
+				return
+			}
+		}
+		select {
+		case queue <- event:
+		default:
+			p.log.WithField("event", event.Type).Warn("Broker queue full, dropping event")
+		}
+	}
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		defer close(queue)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-stopCh:
+				// Events published right before Stop, such as xray.stopped
+				// on shutdown, are still published.
+				for {
+					select {
+					case event := <-ch:
+						enqueue(event)
+					default:
+						return
+					}
+				}
+			case event := <-ch:
+				enqueue(event)
+			}
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		for event := range queue {
+			select {
+			case <-done:
+			default:
+				p.publish(stopCh, event)
+			}
+		}
+		p.disconnect()
+	}()
+}
+
+// Stop ends the subscription and publishes the queued events with a last
+// attempt each, for up to drainTimeout, then disconnects.
+func (p *Publisher) Stop() {
+	p.mu.Lock()
+	stopCh, done := p.stopCh, p.done
+	p.stopCh, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	timer := time.AfterFunc(drainTimeout, func() { close(done) })
+	p.wg.Wait()
+	if !timer.Stop() {
+		return
+	}
+	close(done)
+}
+
+// publish sends event until it succeeds or maxAttempts is reached. Once
+// stopCh is closed, a failed attempt is not retried.
+func (p *Publisher) publish(stopCh <-chan struct{}, event events.Event) {
+	payload, err := json.Marshal(Message{Event: event, Node: p.node})
+	if err != nil {
+		p.log.WithError(err).WithField("event", event.Type).Error("Failed to serialize broker event")
+		return
+	}
+	topic := p.Topic(event.Type)
+
+	wait := p.backoff
+	for attempt := 1; ; attempt++ {
+		err := p.send(topic, payload)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts || errors.Is(err, errTooLarge) {
+			p.log.WithError(err).WithField("event", event.Type).WithField("broker", p.url.Redacted()).
+				Error("Failed to publish event to broker, giving up")
+			return
+		}
+		p.log.WithError(err).WithField("event", event.Type).WithField("attempt", attempt).
+			Warn("Failed to publish event to broker, will retry")
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+// send publishes on the current connection, connecting first if there is
+// none. A connection that failed is dropped.
+func (p *Publisher) send(topic string, payload []byte) error {
+	if p.conn == nil {
+		c, err := p.dial(p.url, "remnawave-node-"+p.node)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		p.conn = c
+	}
+	if err := p.conn.Publish(topic, payload); err != nil {
+		if !errors.Is(err, errTooLarge) {
+			p.disconnect()
+		}
+		return err
+	}
+	return nil
+}
+
+func (p *Publisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
@@ -0,0 +1,235 @@
+package broker
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types, in the high nibble of the first byte.
+const (
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttPingreq    = 0xc0
+	mqttDisconnect = 0xe0
+
+	// mqttKeepAlive is announced in CONNECT; PINGREQs are sent at half
+	// of it so the broker never considers the client gone.
+	mqttKeepAlive = 60 * time.Second
+	// mqttMaxRemaining is the largest remaining length the protocol can
+	// encode.
+	mqttMaxRemaining = 268435455
+)
+
+// mqttConnackErrors are the CONNACK return codes refusing a connection.
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// mqttConn is a publishing connection to an MQTT broker. A goroutine
+// discards what the broker sends, PINGRESPs only, and another keeps the
+// connection alive.
+type mqttConn struct {
+	conn   net.Conn
+	stopCh chan struct{}
+	once   sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+// dialMQTT connects to the broker of u, over TLS for mqtts://, with the
+// user and password of its user info.
+func dialMQTT(u *url.URL, clientID string) (conn, error) {
+	address := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if u.Scheme == "mqtts" {
+			port = "8883"
+		}
+		address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var netConn net.Conn
+	var err error
+	if u.Scheme == "mqtts" {
+		netConn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		netConn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	netConn.SetDeadline(time.Now().Add(dialTimeout))
+
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = appendMQTTString(payload, clientID)
+	if u.User != nil {
+		flags |= 0x80
+		payload = appendMQTTString(payload, u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			flags |= 0x40
+			payload = appendMQTTString(payload, password)
+		}
+	}
+	variable := appendMQTTString(nil, "MQTT")
+	keepAlive := uint16(mqttKeepAlive / time.Second)
+	variable = append(variable, 4, flags, byte(keepAlive>>8), byte(keepAlive))
+
+	if _, err := netConn.Write(mqttPacket(mqttConnect, append(variable, payload...))); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(netConn)
+	packetType, body, err := readMQTTPacket(reader)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if packetType != mqttConnack || len(body) != 2 {
+		netConn.Close()
+		return nil, fmt.Errorf("unexpected packet 0x%02x instead of CONNACK", packetType)
+	}
+	if code := body[1]; code != 0 {
+		netConn.Close()
+		if reason, ok := mqttConnackErrors[code]; ok {
+			return nil, fmt.Errorf("connection refused: %s", reason)
+		}
+		return nil, fmt.Errorf("connection refused with code %d", code)
+	}
+	netConn.SetDeadline(time.Time{})
+
+	c := &mqttConn{conn: netConn, stopCh: make(chan struct{})}
+	go c.read(reader)
+	go c.keepAlive()
+	return c, nil
+}
+
+func (c *mqttConn) read(reader *bufio.Reader) {
+	for {
+		if _, _, err := readMQTTPacket(reader); err != nil {
+			c.fail(err)
+			return
+		}
+	}
+}
+
+func (c *mqttConn) keepAlive() {
+	ticker := time.NewTicker(mqttKeepAlive / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			if c.write(mqttPacket(mqttPingreq, nil)) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *mqttConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *mqttConn) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+func (c *mqttConn) Publish(topic string, payload []byte) error {
+	body := appendMQTTString(nil, topic)
+	if len(body)+len(payload) > mqttMaxRemaining {
+		return errTooLarge
+	}
+	return c.write(mqttPacket(mqttPublish, append(body, payload...)))
+}
+
+func (c *mqttConn) Close() error {
+	c.once.Do(func() {
+		close(c.stopCh)
+		c.write(mqttPacket(mqttDisconnect, nil))
+	})
+	return c.conn.Close()
+}
+
+// mqttPacket frames body as a packet of packetType.
+func mqttPacket(packetType byte, body []byte) []byte {
+	packet := []byte{packetType}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// readMQTTPacket reads a packet and returns its type and body.
+func readMQTTPacket(reader *bufio.Reader) (byte, []byte, error) {
+	header, err := reader.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		digit, err := reader.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
+
+// appendMQTTString appends s with its 2-byte length prefix.
+func appendMQTTString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
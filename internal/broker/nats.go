@@ -0,0 +1,180 @@
+package broker
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsInfo holds the fields of the INFO a NATS server greets with that
+// the client acts on.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	MaxPayload  int  `json:"max_payload"`
+}
+
+// natsConnect is the CONNECT of the client.
+type natsConnect struct {
+	Verbose   bool   `json:"verbose"`
+	Pedantic  bool   `json:"pedantic"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang"`
+	Version   string `json:"version"`
+	Protocol  int    `json:"protocol"`
+	User      string `json:"user,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+	AuthToken string `json:"auth_token,omitempty"`
+}
+
+// natsConn is a publishing connection to a NATS server. A goroutine
+// answers the PINGs of the server and records the first error it sends.
+type natsConn struct {
+	conn       net.Conn
+	maxPayload int
+
+	mu  sync.Mutex
+	err error
+}
+
+// dialNATS connects to the server of u: nats:// upgrades to TLS when the
+// server requires it, tls:// always does. The user info of u holds a user
+// and password, or a token alone.
+func dialNATS(u *url.URL, clientID string) (conn, error) {
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	netConn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	netConn.SetDeadline(time.Now().Add(dialTimeout))
+
+	reader := bufio.NewReader(netConn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to read server info: %w", err)
+	}
+	infoJSON, found := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	if !found {
+		netConn.Close()
+		return nil, fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("invalid server info: %w", err)
+	}
+
+	if u.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(netConn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		}
+		netConn = tlsConn
+		reader = bufio.NewReader(netConn)
+	}
+
+	connect := natsConnect{Name: clientID, Lang: "go", Version: "1.0.0", Protocol: 1}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			connect.User, connect.Pass = u.User.Username(), password
+		} else {
+			connect.AuthToken = u.User.Username()
+		}
+	}
+	data, err := json.Marshal(connect)
+	if err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if _, err := fmt.Fprintf(netConn, "CONNECT %s\r\nPING\r\n", data); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	// The server answers the PING once it accepted the CONNECT, or sends
+	// an error, e.g. for bad credentials.
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "PONG" {
+			break
+		}
+		if msg, found := strings.CutPrefix(line, "-ERR "); found {
+			netConn.Close()
+			return nil, fmt.Errorf("server error: %s", strings.Trim(msg, "'"))
+		}
+	}
+	netConn.SetDeadline(time.Time{})
+
+	c := &natsConn{conn: netConn, maxPayload: info.MaxPayload}
+	go c.read(reader)
+	return c, nil
+}
+
+// read answers PINGs until the connection ends.
+func (c *natsConn) read(reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			c.write([]byte("PONG\r\n"))
+		case strings.HasPrefix(line, "-ERR "):
+			c.fail(fmt.Errorf("server error: %s", strings.Trim(strings.TrimPrefix(line, "-ERR "), "'")))
+		}
+	}
+}
+
+func (c *natsConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+func (c *natsConn) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(data); err != nil {
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+func (c *natsConn) Publish(subject string, payload []byte) error {
+	if c.maxPayload > 0 && len(payload) > c.maxPayload {
+		return errTooLarge
+	}
+	data := make([]byte, 0, len(subject)+len(payload)+32)
+	data = fmt.Appendf(data, "PUB %s %d\r\n", subject, len(payload))
+	data = append(data, payload...)
+	data = append(data, "\r\n"...)
+	return c.write(data)
+}
+
+func (c *natsConn) Close() error {
+	return c.conn.Close()
+}
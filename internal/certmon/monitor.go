@@ -0,0 +1,242 @@
+package certmon
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// Certificate names used in Info and the logs.
+	NameNode = "node"
+	NameCA   = "ca"
+
+	checkInterval = time.Hour
+)
+
+// warnDays are the days before expiry at which a warning is logged, each
+// once per certificate. From errorDays on it is logged as an error.
+var warnDays = []int{30, 14, 7, 3, 1}
+
+const errorDays = 3
+
+// Info describes a monitored certificate.
+type Info struct {
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"`
+}
+
+type entry struct {
+	cert *x509.Certificate
+	// warned is the smallest threshold of warnDays already logged, 0 if none.
+	warned int
+}
+
+// Monitor watches the node certificate and the CA taken from SECRET_KEY and
+// logs escalating warnings as they approach expiry, so operators can renew
+// them before mTLS with the panel breaks.
+type Monitor struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	log     *logger.Logger
+	now     func() time.Time
+
+	onExpiring func(Info)
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewMonitor(log *logger.Logger) *Monitor {
+	return &Monitor{
+		entries: make(map[string]*entry),
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+// SetCertificates parses the node certificate and CA, replacing the
+// monitored ones. On error nothing changes.
+func (m *Monitor) SetCertificates(nodeCertPEM, caCertPEM string) error {
+	node, err := parseCertificate(nodeCertPEM)
+	if err != nil {
+		return fmt.Errorf("node certificate: %w", err)
+	}
+	ca, err := parseCertificate(caCertPEM)
+	if err != nil {
+		return fmt.Errorf("CA certificate: %w", err)
+	}
+
+	m.mu.Lock()
+	m.entries = map[string]*entry{
+		NameNode: {cert: node},
+		NameCA:   {cert: ca},
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+func parseCertificate(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return nil, errors.New("not valid PEM")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// Certificate returns the monitored certificate with the given name.
+func (m *Monitor) Certificate(name string) (*x509.Certificate, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[name]
+	if !ok {
+		return nil, false
+	}
+	return e.cert, true
+}
+
+// Certificates returns the monitored certificates, node first.
+func (m *Monitor) Certificates() []Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	infos := make([]Info, 0, len(m.entries))
+	for _, name := range []string{NameNode, NameCA} {
+		if e, ok := m.entries[name]; ok {
+			infos = append(infos, Info{
+				Name:     name,
+				Subject:  e.cert.Subject.String(),
+				NotAfter: e.cert.NotAfter.UTC(),
+				DaysLeft: daysLeft(e.cert, now),
+			})
+		}
+	}
+	return infos
+}
+
+// OnExpiring sets a function called with each certificate Check logs: once
+// per expiry threshold crossed, and on every check once expired. It must
+// be set before Start.
+func (m *Monitor) OnExpiring(fn func(Info)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onExpiring = fn
+}
+
+func daysLeft(cert *x509.Certificate, now time.Time) int {
+	return int(cert.NotAfter.Sub(now).Hours() / 24)
+}
+
+// Start checks the certificates now and then every hour.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.mu.Unlock()
+
+	m.Check()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the check goroutine.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		m.wg.Wait()
+	}
+}
+
+// Check logs the certificates that crossed an expiry threshold since the
+// last check. Expired certificates are logged on every check.
+func (m *Monitor) Check() {
+	m.mu.Lock()
+
+	var expiring []Info
+	now := m.now()
+	for _, name := range []string{NameNode, NameCA} {
+		e, ok := m.entries[name]
+		if !ok {
+			continue
+		}
+
+		info := Info{
+			Name:     name,
+			Subject:  e.cert.Subject.String(),
+			NotAfter: e.cert.NotAfter.UTC(),
+			DaysLeft: daysLeft(e.cert, now),
+		}
+		log := m.log.WithField("certificate", name).
+			WithField("subject", info.Subject).
+			WithField("notAfter", info.NotAfter.Format(time.RFC3339))
+
+		if now.After(e.cert.NotAfter) {
+			log.Error("Certificate has expired, mTLS with the panel will fail")
+			expiring = append(expiring, info)
+			continue
+		}
+
+		days := info.DaysLeft
+		threshold := 0
+		for _, d := range warnDays {
+			if days < d {
+				threshold = d
+			}
+		}
+		if threshold == 0 || (e.warned != 0 && e.warned <= threshold) {
+			continue
+		}
+		e.warned = threshold
+		expiring = append(expiring, info)
+
+		log = log.WithField("daysLeft", days)
+		if threshold <= errorDays {
+			log.Error("Certificate expires soon, renew it before mTLS with the panel breaks")
+		} else {
+			log.Warn("Certificate expires soon, renew it before mTLS with the panel breaks")
+		}
+	}
+
+	onExpiring := m.onExpiring
+	m.mu.Unlock()
+
+	if onExpiring != nil {
+		for _, info := range expiring {
+			onExpiring(info)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package certmon
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func certPEM(t *testing.T, name string, notAfter time.Time) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+type logEntry struct {
+	Level       string `json:"level"`
+	Certificate string `json:"certificate"`
+	DaysLeft    int    `json:"daysLeft"`
+}
+
+func readLogs(t *testing.T, buf *bytes.Buffer) []logEntry {
+	t.Helper()
+
+	var entries []logEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry logEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestMonitor_Certificates(t *testing.T) {
+	now := time.Now()
+	m := NewMonitor(logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+	m.now = func() time.Time { return now }
+
+	assert.Empty(t, m.Certificates())
+	_, ok := m.Certificate(NameNode)
+	assert.False(t, ok)
+
+	nodeExpiry := now.Add(90*24*time.Hour + time.Hour)
+	require.NoError(t, m.SetCertificates(certPEM(t, "node", nodeExpiry), certPEM(t, "ca", now.Add(400*24*time.Hour))))
+
+	infos := m.Certificates()
+	require.Len(t, infos, 2)
+	assert.Equal(t, NameNode, infos[0].Name)
+	assert.Equal(t, "CN=node", infos[0].Subject)
+	assert.True(t, nodeExpiry.Truncate(time.Second).Equal(infos[0].NotAfter))
+	assert.Equal(t, 90, infos[0].DaysLeft)
+	assert.Equal(t, NameCA, infos[1].Name)
+
+	cert, ok := m.Certificate(NameNode)
+	require.True(t, ok)
+	assert.Equal(t, "node", cert.Subject.CommonName)
+
+	assert.Error(t, m.SetCertificates("not a pem", certPEM(t, "ca", nodeExpiry)))
+	assert.Equal(t, "CN=node", m.Certificates()[0].Subject)
+}
+
+func TestMonitor_CheckEscalates(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Now()
+	m := NewMonitor(logger.New(logger.Config{Level: logger.LevelDebug, Format: logger.FormatJSON, Output: &buf}))
+	m.now = func() time.Time { return now }
+	var expiring []Info
+	m.OnExpiring(func(info Info) { expiring = append(expiring, info) })
+
+	notAfter := now.Add(40 * 24 * time.Hour)
+	require.NoError(t, m.SetCertificates(certPEM(t, "node", notAfter), certPEM(t, "ca", now.Add(400*24*time.Hour))))
+
+	m.Check()
+	assert.Empty(t, readLogs(t, &buf))
+
+	// Crossing 30 days warns once.
+	now = notAfter.Add(-20*24*time.Hour - time.Hour)
+	m.Check()
+	m.Check()
+	logs := readLogs(t, &buf)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "warn", logs[0].Level)
+	assert.Equal(t, NameNode, logs[0].Certificate)
+	assert.Equal(t, 20, logs[0].DaysLeft)
+	require.Len(t, expiring, 1)
+	assert.Equal(t, NameNode, expiring[0].Name)
+	assert.Equal(t, 20, expiring[0].DaysLeft)
+
+	// Jumping from 20 to 2 days skips the intermediate thresholds.
+	now = notAfter.Add(-2*24*time.Hour - time.Hour)
+	m.Check()
+	m.Check()
+	logs = readLogs(t, &buf)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "error", logs[0].Level)
+
+	// Expired certificates are reported on every check.
+	now = notAfter.Add(time.Hour)
+	m.Check()
+	m.Check()
+	logs = readLogs(t, &buf)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "error", logs[0].Level)
+	assert.Len(t, expiring, 4, "each logged check is reported")
+
+	// Replaced certificates start over.
+	require.NoError(t, m.SetCertificates(certPEM(t, "node", now.Add(10*24*time.Hour)), certPEM(t, "ca", now.Add(400*24*time.Hour))))
+	m.Check()
+	logs = readLogs(t, &buf)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "warn", logs[0].Level)
+}
@@ -0,0 +1,289 @@
+package checkpoint
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	stateKey         = "traffic-checkpoint"
+	snapshotInterval = 30 * time.Second
+)
+
+// Traffic is the number of bytes a user transferred in each direction.
+type Traffic struct {
+	Uplink   int64 `json:"uplink"`
+	Downlink int64 `json:"downlink"`
+}
+
+type persistedCheckpoint struct {
+	Carried map[string]Traffic `json:"carried"`
+	Live    map[string]Traffic `json:"live"`
+}
+
+// Checkpoint keeps user traffic that the panel has not collected yet
+// across xray and node restarts.
+//
+// The user counters of the running instance are snapshotted to disk
+// periodically ("live"). When the instance goes away, the last snapshot is
+// moved to "carried" traffic, which is added to the next user stats
+// response and cleared once the panel collects with reset.
+// Traffic recorded after the last snapshot of an instance is still lost.
+type Checkpoint struct {
+	mu      sync.Mutex
+	carried map[string]Traffic
+	live    map[string]Traffic
+	core    *xray.Core
+	store   state.Store
+	log     *logger.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New restores the persisted checkpoint and registers core hooks carrying
+// over the traffic of an instance once it is gone.
+func New(core *xray.Core, store state.Store, log *logger.Logger) *Checkpoint {
+	c := &Checkpoint{
+		carried: make(map[string]Traffic),
+		live:    make(map[string]Traffic),
+		core:    core,
+		store:   store,
+		log:     log,
+	}
+
+	c.restore()
+	core.OnStart(c.carryOver)
+	core.OnStop(c.carryOver)
+	core.OnStartFailed(func(error) { c.carryOver() })
+
+	return c
+}
+
+// Start launches the goroutine that periodically snapshots the counters.
+func (c *Checkpoint) Start() {
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stopCh = make(chan struct{})
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(snapshotInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				c.Snapshot()
+			}
+		}
+	}()
+}
+
+// Stop terminates the snapshot goroutine and takes a final snapshot.
+func (c *Checkpoint) Stop() {
+	c.mu.Lock()
+	stopCh := c.stopCh
+	c.stopCh = nil
+	c.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		c.wg.Wait()
+	}
+
+	c.Snapshot()
+}
+
+// Snapshot records the current user counters of the running instance.
+// It does nothing while xray is not running.
+func (c *Checkpoint) Snapshot() {
+	instance := c.core.Instance()
+	if instance == nil {
+		return
+	}
+	stm, ok := instance.GetFeature(stats.ManagerType()).(*appstats.Manager)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The instance was replaced meanwhile and its traffic already carried over.
+	if c.core.Instance() != instance {
+		return
+	}
+
+	live := make(map[string]Traffic)
+	stm.VisitCounters(func(name string, counter stats.Counter) bool {
+		parts := strings.Split(name, ">>>")
+		if len(parts) < 4 || parts[0] != "user" || parts[2] != "traffic" {
+			return true
+		}
+
+		traffic := live[parts[1]]
+		switch parts[3] {
+		case "uplink":
+			traffic.Uplink = counter.Value()
+		case "downlink":
+			traffic.Downlink = counter.Value()
+		}
+		live[parts[1]] = traffic
+		return true
+	})
+	c.live = live
+
+	c.saveLocked()
+}
+
+// Collect runs collect, which reads (and with reset, clears) the live user
+// counters, and adds the carried traffic to its result. With reset the
+// carried traffic is dropped as it has now been reported.
+// Both happen under the checkpoint lock so a concurrent snapshot cannot
+// record counter values that were just reset.
+func (c *Checkpoint) Collect(reset bool, collect func() map[string]Traffic) map[string]Traffic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := collect()
+	if result == nil {
+		result = make(map[string]Traffic)
+	}
+
+	for username, carried := range c.carried {
+		traffic := result[username]
+		traffic.Uplink += carried.Uplink
+		traffic.Downlink += carried.Downlink
+		result[username] = traffic
+	}
+
+	if reset {
+		changed := len(c.carried) > 0 || len(c.live) > 0
+		c.carried = make(map[string]Traffic)
+		c.live = make(map[string]Traffic)
+		if changed {
+			c.saveLocked()
+		}
+	}
+
+	return result
+}
+
+// CollectUsers is Collect for part of the users: collect runs under the
+// checkpoint lock with the carried traffic, which it must not modify, and
+// returns the users it reported. With reset only the carried traffic of
+// those users is dropped.
+func (c *Checkpoint) CollectUsers(reset bool, collect func(carried map[string]Traffic) []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reported := collect(c.carried)
+	if !reset {
+		return
+	}
+
+	changed := false
+	for _, username := range reported {
+		if _, ok := c.carried[username]; ok {
+			delete(c.carried, username)
+			changed = true
+		}
+		if _, ok := c.live[username]; ok {
+			delete(c.live, username)
+			changed = true
+		}
+	}
+	if changed {
+		c.saveLocked()
+	}
+}
+
+// Carried returns the traffic carried over from previous instances.
+func (c *Checkpoint) Carried() map[string]Traffic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	carried := make(map[string]Traffic, len(c.carried))
+	for username, traffic := range c.carried {
+		carried[username] = traffic
+	}
+	return carried
+}
+
+// carryOver moves the last snapshot of a previous instance to the carried
+// traffic. Runs whenever the core instance is replaced or stopped, as its
+// counters are gone with it.
+func (c *Checkpoint) carryOver() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.live) == 0 {
+		return
+	}
+	c.carryOverLocked()
+	c.saveLocked()
+}
+
+func (c *Checkpoint) carryOverLocked() {
+	for username, live := range c.live {
+		traffic := c.carried[username]
+		traffic.Uplink += live.Uplink
+		traffic.Downlink += live.Downlink
+		c.carried[username] = traffic
+	}
+	c.live = make(map[string]Traffic)
+}
+
+// restore loads the checkpoint of a previous run. The instance that produced
+// the live snapshot is gone, so it is carried over right away.
+func (c *Checkpoint) restore() {
+	var stored persistedCheckpoint
+	found, err := c.store.Load(stateKey, &stored)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to restore traffic checkpoint")
+		return
+	}
+	if !found {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for username, traffic := range stored.Carried {
+		c.carried[username] = traffic
+	}
+	for username, traffic := range stored.Live {
+		c.live[username] = traffic
+	}
+	c.carryOverLocked()
+
+	if len(c.carried) > 0 {
+		c.log.WithField("users", len(c.carried)).Info("Restored unreported traffic from checkpoint")
+	}
+}
+
+// saveLocked persists the checkpoint. The caller must hold c.mu.
+func (c *Checkpoint) saveLocked() {
+	if err := c.store.Save(stateKey, persistedCheckpoint{Carried: c.carried, Live: c.live}); err != nil {
+		c.log.WithError(err).Error("Failed to persist traffic checkpoint")
+	}
+}
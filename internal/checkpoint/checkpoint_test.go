@@ -0,0 +1,133 @@
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const statsConfig = `{"log":{"loglevel":"none"},"stats":{},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]}`
+
+func newTestCheckpoint(t *testing.T, core *xray.Core, store state.Store) *Checkpoint {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return New(core, store, log)
+}
+
+func newTestCore() *xray.Core {
+	return xray.NewCore(logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+}
+
+func addUserTraffic(t *testing.T, core *xray.Core, username string, uplink, downlink int64) {
+	t.Helper()
+	stm, ok := core.Instance().GetFeature(stats.ManagerType()).(*appstats.Manager)
+	require.True(t, ok)
+
+	for direction, value := range map[string]int64{"uplink": uplink, "downlink": downlink} {
+		name := "user>>>" + username + ">>>traffic>>>" + direction
+		counter := stm.GetCounter(name)
+		if counter == nil {
+			var err error
+			counter, err = stm.RegisterCounter(name)
+			require.NoError(t, err)
+		}
+		counter.Add(value)
+	}
+}
+
+func TestCheckpoint_CollectMergesCarried(t *testing.T) {
+	c := newTestCheckpoint(t, newTestCore(), state.NewMemoryStore())
+	c.carried = map[string]Traffic{"alice": {Uplink: 10, Downlink: 20}}
+
+	result := c.Collect(false, func() map[string]Traffic {
+		return map[string]Traffic{
+			"alice": {Uplink: 1, Downlink: 2},
+			"bob":   {Uplink: 5},
+		}
+	})
+	assert.Equal(t, Traffic{Uplink: 11, Downlink: 22}, result["alice"])
+	assert.Equal(t, Traffic{Uplink: 5}, result["bob"])
+	assert.Len(t, c.Carried(), 1, "collect without reset keeps carried traffic")
+
+	result = c.Collect(true, func() map[string]Traffic { return nil })
+	assert.Equal(t, Traffic{Uplink: 10, Downlink: 20}, result["alice"])
+	assert.Empty(t, c.Carried())
+}
+
+func TestCheckpoint_CollectUsersDropsReportedCarried(t *testing.T) {
+	c := newTestCheckpoint(t, newTestCore(), state.NewMemoryStore())
+	c.carried = map[string]Traffic{
+		"alice": {Uplink: 10},
+		"bob":   {Downlink: 20},
+	}
+
+	c.CollectUsers(false, func(carried map[string]Traffic) []string {
+		assert.Len(t, carried, 2)
+		return []string{"alice"}
+	})
+	assert.Len(t, c.Carried(), 2, "collect without reset keeps carried traffic")
+
+	c.CollectUsers(true, func(carried map[string]Traffic) []string {
+		return []string{"alice"}
+	})
+	assert.Equal(t, map[string]Traffic{"bob": {Downlink: 20}}, c.Carried())
+}
+
+func TestCheckpoint_CarriesOverAcrossCoreRestart(t *testing.T) {
+	core := newTestCore()
+	c := newTestCheckpoint(t, core, state.NewMemoryStore())
+
+	require.NoError(t, core.Start([]byte(statsConfig)))
+	defer core.Stop()
+
+	addUserTraffic(t, core, "alice", 100, 200)
+	c.Snapshot()
+	assert.Empty(t, c.Carried())
+
+	require.NoError(t, core.Restart([]byte(statsConfig)))
+	assert.Equal(t, map[string]Traffic{"alice": {Uplink: 100, Downlink: 200}}, c.Carried())
+
+	addUserTraffic(t, core, "alice", 1, 1)
+	c.Snapshot()
+	require.NoError(t, core.Stop())
+	assert.Equal(t, map[string]Traffic{"alice": {Uplink: 101, Downlink: 201}}, c.Carried())
+}
+
+func TestCheckpoint_RestoresAfterNodeRestart(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	core := newTestCore()
+	c := newTestCheckpoint(t, core, store)
+
+	require.NoError(t, core.Start([]byte(statsConfig)))
+	addUserTraffic(t, core, "alice", 100, 200)
+	c.Snapshot()
+
+	// The process dies without stopping the core: only the snapshot survives.
+	restored := newTestCheckpoint(t, newTestCore(), store)
+	assert.Equal(t, map[string]Traffic{"alice": {Uplink: 100, Downlink: 200}}, restored.Carried())
+
+	restored.Collect(true, func() map[string]Traffic { return nil })
+
+	again := newTestCheckpoint(t, newTestCore(), store)
+	assert.Empty(t, again.Carried(), "reported traffic is not restored again")
+
+	require.NoError(t, core.Stop())
+}
+
+func TestCheckpoint_StartStop(t *testing.T) {
+	c := newTestCheckpoint(t, newTestCore(), state.NewMemoryStore())
+
+	c.Start()
+	c.Start()
+	c.Stop()
+	c.Stop()
+}
@@ -0,0 +1,419 @@
+// Package cluster shares the IPs blocked automatically on one node with the
+// other nodes of a cluster, so an attacker blocked on one node is blocked on
+// all of them within seconds.
+//
+// Nodes POST the blocks to the cluster endpoint of their peers, signed with
+// a secret shared by the cluster. Blocks received from a peer are applied
+// but not passed on, so every node lists all the others as peers. A node
+// skips blocks covering its own whitelist or a range broader than a single
+// client could hold.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// Path is the path of the cluster endpoint.
+	Path = "/cluster/blocks"
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256, keyed with
+	// the cluster secret, of TimestampHeader, a dot and the request body.
+	SignatureHeader = "X-Cluster-Signature"
+	// TimestampHeader carries the unix time the request was signed at.
+	TimestampHeader = "X-Cluster-Timestamp"
+
+	// maxClockSkew bounds the age of accepted requests, so a captured
+	// request cannot be replayed later.
+	maxClockSkew = 5 * time.Minute
+	maxBodySize  = 1 << 20
+
+	requestTimeout = 5 * time.Second
+	// queueSize is the number of blocks waiting to be shared before new
+	// ones are dropped.
+	queueSize = 1024
+	// batchDelay gathers the blocks of a burst into one request per peer.
+	batchDelay = 500 * time.Millisecond
+	// maxAttempts bounds the deliveries of a batch to a peer, retryDelay
+	// apart.
+	maxAttempts = 3
+	retryDelay  = 2 * time.Second
+
+	// minIPv4Bits and minIPv6Bits bound the size of the ranges accepted
+	// from peers: an IPv6 client can hold a /32 allocation, an IPv4 one no
+	// more than a /24.
+	minIPv4Bits = 24
+	minIPv6Bits = 32
+)
+
+// Block is an IP or CIDR range blocked on a node.
+type Block struct {
+	IP string `json:"ip"`
+	// BanSeconds is how long the block lasts, 0 for a permanent one.
+	BanSeconds int64 `json:"banSeconds"`
+}
+
+// Message is the body of a request to the cluster endpoint.
+type Message struct {
+	// Node identifies the node that blocked the IPs.
+	Node   string  `json:"node"`
+	Blocks []Block `json:"blocks"`
+}
+
+// Blocklist applies the blocks received from peers.
+type Blocklist interface {
+	Block(source string, ttl time.Duration) (string, error)
+	IsBlocked(source string) bool
+}
+
+// Cluster sends the blocks of this node to its peers and serves the
+// endpoint receiving theirs.
+type Cluster struct {
+	peers     []string
+	secret    []byte
+	node      string
+	blocklist Blocklist
+	client    *http.Client
+	log       *logger.Logger
+	now       func() time.Time
+	retry     time.Duration
+
+	queue chan Block
+
+	mu        sync.Mutex
+	whitelist []*net.IPNet
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a cluster member named node, the hostname when empty, that
+// shares its blocks with the peers, base URLs such as http://10.0.0.2:61003.
+func New(peers []string, secret, node string, blocklist Blocklist, log *logger.Logger) *Cluster {
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	return &Cluster{
+		peers:     peers,
+		secret:    []byte(secret),
+		node:      node,
+		blocklist: blocklist,
+		client:    &http.Client{Timeout: requestTimeout},
+		log:       log,
+		now:       time.Now,
+		retry:     retryDelay,
+		queue:     make(chan Block, queueSize),
+	}
+}
+
+// SetWhitelist sets the IPs and ranges that blocks received from peers must
+// not cover, typically AUTO_BLOCK_WHITELIST. Loopback addresses are always
+// protected.
+func (c *Cluster) SetWhitelist(whitelist []*net.IPNet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.whitelist = whitelist
+}
+
+// ParsePeers splits a comma-separated list of peer URLs.
+func ParsePeers(value string) []string {
+	var peers []string
+	for _, peer := range strings.Split(value, ",") {
+		if peer = strings.TrimRight(strings.TrimSpace(peer), "/"); peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// Share queues a block of this node for the peers. It never blocks: with
+// the queue full the block stays local.
+func (c *Cluster) Share(ip string, banDuration time.Duration) {
+	if len(c.peers) == 0 {
+		return
+	}
+	select {
+	case c.queue <- Block{IP: ip, BanSeconds: int64(banDuration / time.Second)}:
+	default:
+		c.log.WithField("ip", ip).Warn("Cluster queue full, block not shared")
+	}
+}
+
+// Start launches the goroutine sending the shared blocks.
+func (c *Cluster) Start() {
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stopCh = make(chan struct{})
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case block := <-c.queue:
+				c.send(stopCh, c.collect(stopCh, block))
+			}
+		}
+	}()
+}
+
+// Stop terminates the send goroutine. Blocks not sent yet are dropped.
+func (c *Cluster) Stop() {
+	c.mu.Lock()
+	stopCh := c.stopCh
+	c.stopCh = nil
+	c.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		c.wg.Wait()
+	}
+}
+
+// collect returns first and the blocks queued within batchDelay after it.
+func (c *Cluster) collect(stopCh <-chan struct{}, first Block) []Block {
+	blocks := []Block{first}
+	timer := time.NewTimer(batchDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return blocks
+		case <-timer.C:
+			return blocks
+		case block := <-c.queue:
+			blocks = append(blocks, block)
+		}
+	}
+}
+
+// send delivers blocks to every peer in parallel.
+func (c *Cluster) send(stopCh <-chan struct{}, blocks []Block) {
+	body, err := json.Marshal(Message{Node: c.node, Blocks: blocks})
+	if err != nil {
+		c.log.WithError(err).Error("Failed to serialize cluster blocks")
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, peer := range c.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.deliver(stopCh, peer, body)
+		}()
+	}
+	wg.Wait()
+}
+
+func (c *Cluster) deliver(stopCh <-chan struct{}, peer string, body []byte) {
+	for attempt := 1; ; attempt++ {
+		err := c.post(peer, body)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts {
+			c.log.WithError(err).WithField("peer", peer).Error("Failed to share blocks with cluster peer, giving up")
+			return
+		}
+		c.log.WithError(err).WithField("peer", peer).WithField("attempt", attempt).
+			Warn("Failed to share blocks with cluster peer, will retry")
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(c.retry):
+		}
+	}
+}
+
+func (c *Cluster) post(peer string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer+Path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(c.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(c.secret, timestamp, body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("peer responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ServeHTTP receives the blocks of a peer. Requests without a valid
+// signature made within maxClockSkew are answered 401.
+func (c *Cluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != Path {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !c.verify(r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body) {
+		c.log.WithField("ip", r.RemoteAddr).Warn("Rejected cluster request with an invalid signature")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.Apply(msg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Cluster) verify(timestamp, signature string, body []byte) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := c.now().Sub(time.Unix(unix, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return false
+	}
+	expected := "sha256=" + Sign(c.secret, timestamp, body)
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// Apply blocks the IPs of msg that are not blocked yet and returns how
+// many it blocked. Messages of this node itself are ignored, and so are
+// blocks that are too broad or cover a whitelisted address.
+func (c *Cluster) Apply(msg Message) int {
+	if msg.Node == c.node {
+		return 0
+	}
+
+	c.mu.Lock()
+	whitelist := c.whitelist
+	c.mu.Unlock()
+
+	applied := 0
+	for _, block := range msg.Blocks {
+		if block.BanSeconds < 0 || c.blocklist.IsBlocked(block.IP) {
+			continue
+		}
+		if err := checkBlock(block.IP, whitelist); err != nil {
+			c.log.WithError(err).WithField("ip", block.IP).WithField("node", msg.Node).
+				Warn("Rejected block shared by cluster peer")
+			continue
+		}
+		if _, err := c.blocklist.Block(block.IP, time.Duration(block.BanSeconds)*time.Second); err != nil {
+			c.log.WithError(err).WithField("ip", block.IP).WithField("node", msg.Node).
+				Warn("Failed to apply block shared by cluster peer")
+			continue
+		}
+		applied++
+	}
+	if applied > 0 {
+		c.log.WithField("node", msg.Node).WithField("count", applied).Info("Applied IP blocks shared by cluster peer")
+	}
+	return applied
+}
+
+var loopback = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+}
+
+// checkBlock returns why ip, an IP or CIDR range shared by a peer, must not
+// be blocked on this node, or nil if it may.
+func checkBlock(ip string, whitelist []*net.IPNet) error {
+	prefix, err := netip.ParsePrefix(ip)
+	if err != nil {
+		addr, addrErr := netip.ParseAddr(ip)
+		if addrErr != nil {
+			return fmt.Errorf("invalid IP or CIDR %q", ip)
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if prefix.Addr().Is4In6() {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0))
+	}
+	prefix = prefix.Masked()
+
+	minBits := minIPv6Bits
+	if prefix.Addr().Is4() {
+		minBits = minIPv4Bits
+	}
+	if prefix.Bits() < minBits {
+		return fmt.Errorf("range %s is broader than /%d", prefix, minBits)
+	}
+
+	for _, protected := range loopback {
+		if prefix.Overlaps(protected) {
+			return errors.New("range covers loopback addresses")
+		}
+	}
+	for _, ipNet := range whitelist {
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		ones, _ := ipNet.Mask.Size()
+		if addr.Is4In6() {
+			addr, ones = addr.Unmap(), max(ones-96, 0)
+		}
+		if prefix.Overlaps(netip.PrefixFrom(addr, ones)) {
+			return fmt.Errorf("range overlaps whitelisted %s", ipNet)
+		}
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp, a dot and body keyed with
+// secret, as sent in SignatureHeader.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
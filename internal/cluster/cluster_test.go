@@ -0,0 +1,181 @@
+package cluster
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type fakeBlocklist struct {
+	mu      sync.Mutex
+	blocked map[string]time.Duration
+}
+
+func newFakeBlocklist() *fakeBlocklist {
+	return &fakeBlocklist{blocked: make(map[string]time.Duration)}
+}
+
+func (b *fakeBlocklist) Block(source string, ttl time.Duration) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blocked[source] = ttl
+	return source, nil
+}
+
+func (b *fakeBlocklist) IsBlocked(source string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.blocked[source]
+	return ok
+}
+
+func (b *fakeBlocklist) ttl(source string) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ttl, ok := b.blocked[source]
+	return ttl, ok
+}
+
+func newTestCluster(t *testing.T, node string, peers []string, blocklist Blocklist) *Cluster {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := New(peers, "cluster-secret", node, blocklist, log)
+	c.retry = 10 * time.Millisecond
+	return c
+}
+
+func TestCluster_SharesBlocksWithPeers(t *testing.T) {
+	remote := newFakeBlocklist()
+	server := httptest.NewServer(newTestCluster(t, "node-b", nil, remote))
+	defer server.Close()
+
+	local := newTestCluster(t, "node-a", ParsePeers(" "+server.URL+"/ ,"), newFakeBlocklist())
+	local.Start()
+	defer local.Stop()
+
+	local.Share("198.51.100.7", time.Hour)
+	local.Share("2001:db8:1:2::/64", 0)
+
+	require.Eventually(t, func() bool { return remote.IsBlocked("2001:db8:1:2::/64") }, 5*time.Second, 10*time.Millisecond)
+	ttl, ok := remote.ttl("198.51.100.7")
+	require.True(t, ok)
+	assert.Equal(t, time.Hour, ttl)
+	ttl, _ = remote.ttl("2001:db8:1:2::/64")
+	assert.Zero(t, ttl)
+}
+
+func TestCluster_RetriesUnreachablePeer(t *testing.T) {
+	remote := newFakeBlocklist()
+	peer := newTestCluster(t, "node-b", nil, remote)
+	var attempts int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		first := attempts == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		peer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	local := newTestCluster(t, "node-a", []string{server.URL}, newFakeBlocklist())
+	local.Start()
+	defer local.Stop()
+
+	local.Share("198.51.100.8", time.Minute)
+	require.Eventually(t, func() bool { return remote.IsBlocked("198.51.100.8") }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestCluster_RejectsInvalidRequests(t *testing.T) {
+	blocklist := newFakeBlocklist()
+	c := newTestCluster(t, "node-b", nil, blocklist)
+	now := time.Now()
+	body := []byte(`{"node":"node-a","blocks":[{"ip":"203.0.113.9","banSeconds":60}]}`)
+
+	request := func(secret string, at time.Time) int {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body))
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, "sha256="+Sign([]byte(secret), timestamp, body))
+		w := httptest.NewRecorder()
+		c.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request("wrong-secret", now))
+	assert.Equal(t, http.StatusUnauthorized, request("cluster-secret", now.Add(-10*time.Minute)), "replayed")
+	assert.False(t, blocklist.IsBlocked("203.0.113.9"))
+
+	assert.Equal(t, http.StatusNoContent, request("cluster-secret", now))
+	assert.True(t, blocklist.IsBlocked("203.0.113.9"))
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestCluster_Apply(t *testing.T) {
+	blocklist := newFakeBlocklist()
+	c := newTestCluster(t, "node-a", nil, blocklist)
+	blocklist.Block("192.0.2.1", time.Hour)
+
+	assert.Zero(t, c.Apply(Message{Node: "node-a", Blocks: []Block{{IP: "192.0.2.2"}}}), "own blocks")
+	assert.Equal(t, 1, c.Apply(Message{Node: "node-b", Blocks: []Block{
+		{IP: "192.0.2.1", BanSeconds: 60},
+		{IP: "192.0.2.3", BanSeconds: 60},
+		{IP: "192.0.2.4", BanSeconds: -1},
+	}}))
+	ttl, _ := blocklist.ttl("192.0.2.1")
+	assert.Equal(t, time.Hour, ttl, "already blocked")
+	assert.False(t, blocklist.IsBlocked("192.0.2.4"))
+}
+
+func TestCluster_ApplyRejectsProtectedAndBroadBlocks(t *testing.T) {
+	blocklist := newFakeBlocklist()
+	c := newTestCluster(t, "node-a", nil, blocklist)
+	_, office, err := net.ParseCIDR("198.51.100.0/24")
+	require.NoError(t, err)
+	c.SetWhitelist([]*net.IPNet{office, {IP: net.ParseIP("2001:db8::5"), Mask: net.CIDRMask(128, 128)}})
+
+	rejected := []string{
+		"0.0.0.0/0",
+		"::/0",
+		"203.0.0.0/16",
+		"2001:db8::/16",
+		"127.0.0.1",
+		"::1",
+		"198.51.100.7",
+		"198.51.0.0/16",
+		"2001:db8::/64",
+		"::ffff:198.51.100.7",
+		"not-an-ip",
+	}
+	blocks := make([]Block, 0, len(rejected))
+	for _, ip := range rejected {
+		blocks = append(blocks, Block{IP: ip, BanSeconds: 60})
+	}
+	assert.Zero(t, c.Apply(Message{Node: "node-b", Blocks: blocks}))
+	for _, ip := range rejected {
+		assert.False(t, blocklist.IsBlocked(ip), ip)
+	}
+
+	assert.Equal(t, 3, c.Apply(Message{Node: "node-b", Blocks: []Block{
+		{IP: "192.0.2.1", BanSeconds: 60},
+		{IP: "203.0.113.0/24", BanSeconds: 60},
+		{IP: "2001:db8:1::/64", BanSeconds: 60},
+	}}))
+}
@@ -1,49 +1,441 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 )
 
 const (
 	DefaultNodePort         = 2222
 	DefaultInternalRestPort = 61001
+	DefaultAPIPort          = 61012
 	DefaultLogLevel         = "info"
+	DefaultAccessLog        = "all"
+	DefaultLogBufferSize    = 1000
+	DefaultStateDir         = "/etc/remnawave-node/state"
+	DefaultStateRedisPrefix = "remnawave-node:"
+	DefaultStatsHistorySize = 1440
+	DefaultEventHistorySize = 1000
+
+	DefaultInternalSocketMode = "0600"
+
+	DefaultStatsPushInterval = 60
+
+	DefaultHeartbeatInterval = 30
+
+	DefaultTrafficExportFormat   = "clickhouse"
+	DefaultTrafficExportInterval = 300
+
+	DefaultTelegramRateLimit  = 600
+	DefaultDiskMinFreePercent = 5
+	DefaultLimitWarnPercent   = 80
+
+	DefaultOutboundProbeTarget   = "https://www.google.com/generate_204"
+	DefaultOutboundProbeInterval = 60
+	DefaultOutboundProbeTimeout  = 10
+
+	DefaultBulkWorkers = 4
+
+	// ErrorResponseLegacy keeps the error bodies of earlier versions: the
+	// usual response envelope with an error message. ErrorResponseCoded
+	// answers errors with a body carrying an error code instead.
+	ErrorResponseLegacy = 1
+	ErrorResponseCoded  = 2
+
+	DefaultSecretKeyVaultField      = "secretKey"
+	DefaultSecretKeyRefreshInterval = 3600
+
+	DefaultJWKSRefreshInterval = 300
+
+	DefaultCRLRefreshInterval = 3600
+
+	DefaultGeodataUpdateInterval = 86400
+	DefaultGeoIPURL              = "https://github.com/Loyalsoldier/v2ray-rules-dat/releases/latest/download/geoip.dat"
+	DefaultGeoSiteURL            = "https://github.com/Loyalsoldier/v2ray-rules-dat/releases/latest/download/geosite.dat"
+
+	DefaultXrayMode   = "embedded"
+	DefaultXrayBinary = "xray"
+
+	DefaultAutoBlockMaxFailures = 10
+	DefaultAutoBlockWindow      = 60
+	DefaultAutoBlockBanDuration = 3600
+	DefaultAutoBlockIPv6Prefix  = 64
+
+	DefaultMaintenanceWindowDuration = 3600
+
+	DefaultMaxBodySize           = 64 << 20
+	DefaultMaxDecompressedSize   = 64 << 20
+	DefaultHTTPReadHeaderTimeout = 10
+	DefaultHTTPReadTimeout       = 60
+	DefaultHTTPWriteTimeout      = 120
+	DefaultHTTPIdleTimeout       = 120
+	DefaultShutdownTimeout       = 10
 )
 
 var (
-	ErrConfigSecretKeyRequired = errors.New("SECRET_KEY environment variable is required")
+	ErrConfigSecretKeyRequired = errors.New("SECRET_KEY or SECRET_KEY_FILE environment variable is required")
 )
 
 type Config struct {
-	SecretKey        string `json:"secretKey"`
+	SecretKey string `json:"secretKey"`
+	// SecretKeyFile is read for the secret key instead, e.g. a mounted
+	// Docker secret, so it stays out of the environment.
+	SecretKeyFile    string `json:"secretKeyFile"`
 	NodePort         int    `json:"nodePort"`
 	InternalRestPort int    `json:"internalRestPort"`
-	LogLevel         string `json:"logLevel"`
+	// APIPort is the localhost port of the xray api inbound injected into
+	// the panel config. Nodes sharing a host need distinct ports.
+	APIPort  int    `json:"apiPort"`
+	LogLevel string `json:"logLevel"`
+	// AccessLog selects which HTTP requests are logged: "all", "errors"
+	// (status 400 and above) or "off". AccessLogSkipStats leaves successful
+	// stats and metrics polling out.
+	AccessLog          string `json:"accessLog"`
+	AccessLogSkipStats bool   `json:"accessLogSkipStats"`
+	// LogBufferSize is the number of recent log lines kept in memory for
+	// /node/logs. Zero disables the endpoint.
+	LogBufferSize int `json:"logBufferSize"`
+
+	// NodeID and NodeLabels, comma-separated key=value pairs, identify the
+	// node to the panel in /node/info. Without a NodeID the node is
+	// identified by the fingerprint of its certificate.
+	NodeID     string `json:"nodeId"`
+	NodeLabels string `json:"nodeLabels"`
+
+	StateDir         string `json:"stateDir"`
+	GRPCPort         int    `json:"grpcPort"`
+	StatsHistorySize int    `json:"statsHistorySize"`
+	// EventHistorySize is the number of entries behind
+	// /node/events/history, saved in StateDir with EventHistoryPersist.
+	EventHistorySize    int  `json:"eventHistorySize"`
+	EventHistoryPersist bool `json:"eventHistoryPersist"`
+
+	// StateEncryptionKey, 32 bytes in base64, encrypts the documents in
+	// StateDir with AES-256-GCM. StateEncryptionKeyFile is read for it
+	// instead, like SecretKeyFile. State written without a key is still
+	// read and encrypted on its next save.
+	StateEncryptionKey     string `json:"stateEncryptionKey"`
+	StateEncryptionKeyFile string `json:"stateEncryptionKeyFile"`
+
+	// StateRedisURL keeps the state in Redis instead of StateDir, under
+	// keys starting with StateRedisPrefix, so that the replicas of a node
+	// sharing the prefix share their blocks, limits and last-seen data.
+	// The state of each replica goes under the prefix and its NodeID.
+	StateRedisURL    string `json:"stateRedisUrl"`
+	StateRedisPrefix string `json:"stateRedisPrefix"`
+
+	// SecretKeyVaultPath reads the secret key from the field
+	// SecretKeyVaultField of a HashiCorp Vault secret at VaultAddr, e.g.
+	// secret/data/remnawave/node-1 on a KV v2 mount. Vault is logged in to
+	// with VaultToken, VaultTokenFile read on every fetch (e.g. a Vault
+	// Agent sink) or the AppRole VaultRoleID and VaultSecretID, which
+	// VaultSecretIDFile is read for instead. SecretKeyGCPSecret reads the
+	// key from Google Secret Manager instead, e.g.
+	// projects/my-project/secrets/node-1, as the instance service account.
+	// The key is fetched again every SecretKeyRefreshInterval seconds, zero
+	// for never, and the credentials reloaded when it changed.
+	SecretKeyVaultPath       string `json:"secretKeyVaultPath"`
+	SecretKeyVaultField      string `json:"secretKeyVaultField"`
+	VaultAddr                string `json:"vaultAddr"`
+	VaultNamespace           string `json:"vaultNamespace"`
+	VaultToken               string `json:"vaultToken"`
+	VaultTokenFile           string `json:"vaultTokenFile"`
+	VaultRoleID              string `json:"vaultRoleId"`
+	VaultSecretID            string `json:"vaultSecretId"`
+	VaultSecretIDFile        string `json:"vaultSecretIdFile"`
+	SecretKeyGCPSecret       string `json:"secretKeyGcpSecret"`
+	SecretKeyRefreshInterval int    `json:"secretKeyRefreshInterval"`
+
+	// InternalSocketPath serves the internal API on a unix socket, created
+	// with the octal permissions InternalSocketMode, instead of on
+	// 127.0.0.1:InternalRestPort.
+	InternalSocketPath string `json:"internalSocketPath"`
+	InternalSocketMode string `json:"internalSocketMode"`
+
+	// PanelAllowedIPs is a comma-separated list of IPs and CIDR ranges;
+	// when set, the main and gRPC listeners drop connections from any other
+	// address before the TLS handshake.
+	PanelAllowedIPs string `json:"panelAllowedIps"`
+
+	// TrustedProxies is a comma-separated list of IPs and CIDR ranges of
+	// the proxies in front of the node. The client IP of panel requests is
+	// taken from X-Forwarded-For only on requests from them, and with
+	// ProxyProtocol from the PROXY protocol header they send on the main
+	// and gRPC listeners.
+	TrustedProxies string `json:"trustedProxies"`
+	ProxyProtocol  bool   `json:"proxyProtocol"`
+
+	// MaxBodySize is the largest request body accepted, in bytes, and
+	// MaxDecompressedSize the largest a zstd body may decompress to. The
+	// HTTP timeouts are in seconds and apply to the main and internal
+	// servers; the events stream is exempt from the read and write
+	// timeouts. Zero disables a limit.
+	MaxBodySize           int `json:"maxBodySize"`
+	MaxDecompressedSize   int `json:"maxDecompressedSize"`
+	HTTPReadHeaderTimeout int `json:"httpReadHeaderTimeout"`
+	HTTPReadTimeout       int `json:"httpReadTimeout"`
+	HTTPWriteTimeout      int `json:"httpWriteTimeout"`
+	HTTPIdleTimeout       int `json:"httpIdleTimeout"`
+
+	// ShutdownTimeout is how many seconds requests in flight get to finish
+	// when the node stops, before their connections are closed.
+	ShutdownTimeout int `json:"shutdownTimeout"`
+
+	// StatsPushURL enables push mode: the node POSTs its stats to this
+	// panel URL every StatsPushInterval seconds.
+	StatsPushURL      string `json:"statsPushUrl"`
+	StatsPushInterval int    `json:"statsPushInterval"`
+
+	// HeartbeatURL enables the heartbeat: the node POSTs its identity,
+	// version, xray status and key metrics to this panel URL every
+	// HeartbeatInterval seconds, so the panel notices dead nodes and can
+	// register new ones.
+	HeartbeatURL      string `json:"heartbeatUrl"`
+	HeartbeatInterval int    `json:"heartbeatInterval"`
+
+	// WebhookURL enables webhook notifications: node events listed in
+	// WebhookEvents (comma-separated, a default set when empty) are POSTed
+	// to this URL, signed with HMAC-SHA256 of WebhookSecret.
+	WebhookURL    string `json:"webhookUrl"`
+	WebhookSecret string `json:"webhookSecret"`
+	WebhookEvents string `json:"webhookEvents"`
+
+	// BrokerURL enables the publication of node events to a NATS
+	// (nats://, tls://) or MQTT (mqtt://, mqtts://) broker, on subjects
+	// starting with BrokerPrefix, remnawave.node when empty. BrokerEvents
+	// is a comma-separated list of the event types to publish, all of them
+	// when empty.
+	BrokerURL    string `json:"brokerUrl"`
+	BrokerPrefix string `json:"brokerPrefix"`
+	BrokerEvents string `json:"brokerEvents"`
+
+	// TrafficExportURL enables the export of per-user traffic, taken from
+	// the per-minute history, every TrafficExportInterval seconds:
+	// TrafficExportFormat "clickhouse" inserts into TrafficExportTable
+	// through the ClickHouse HTTP interface, "influx" writes line protocol
+	// to this URL with TrafficExportToken.
+	TrafficExportURL      string `json:"trafficExportUrl"`
+	TrafficExportFormat   string `json:"trafficExportFormat"`
+	TrafficExportTable    string `json:"trafficExportTable"`
+	TrafficExportToken    string `json:"trafficExportToken"`
+	TrafficExportInterval int    `json:"trafficExportInterval"`
+
+	// TelegramBotToken and TelegramChatID enable Telegram alerts for
+	// critical events: xray failing to start, certificates nearing expiry
+	// and low disk space. Each kind is sent at most once every
+	// TelegramRateLimit seconds.
+	TelegramBotToken  string `json:"telegramBotToken"`
+	TelegramChatID    string `json:"telegramChatId"`
+	TelegramRateLimit int    `json:"telegramRateLimit"`
+
+	// OTLPEndpoint enables OpenTelemetry: traces of panel requests and
+	// node operations, and their metrics, are exported over OTLP/HTTP to
+	// this collector URL, e.g. http://otel-collector:4318.
+	OTLPEndpoint string `json:"otlpEndpoint"`
+
+	// DiskMinFreePercent is the free space, in percent of the filesystem,
+	// below which the state and geodata directories are reported low.
+	DiskMinFreePercent int `json:"diskMinFreePercent"`
+
+	// LimitWarnPercent is the use, in percent of the limit, of the open
+	// file descriptors or the conntrack table above which they are
+	// reported high.
+	LimitWarnPercent int `json:"limitWarnPercent"`
+
+	// MemoryRestartThreshold is the RSS of the xray process, in MiB, above
+	// which the core is restarted with its users (0 disables the check).
+	// The restart waits for the maintenance window when one is set.
+	MemoryRestartThreshold int `json:"memoryRestartThreshold"`
+
+	// OutboundProbeTags enables the health checks of the outbounds listed
+	// (comma-separated): every OutboundProbeInterval seconds, the node
+	// connects to OutboundProbeTarget through each of them, a
+	// tcp://host:port, tls://host:port or http(s) URL, giving up after
+	// OutboundProbeTimeout seconds.
+	OutboundProbeTags     string `json:"outboundProbeTags"`
+	OutboundProbeTarget   string `json:"outboundProbeTarget"`
+	OutboundProbeInterval int    `json:"outboundProbeInterval"`
+	OutboundProbeTimeout  int    `json:"outboundProbeTimeout"`
+
+	// GoMemoryLimit is the soft memory limit of the node process, a size
+	// such as 900MiB or a percentage of the container memory limit such as
+	// 90%; GOMEMLIMIT takes precedence. GoMaxProcsAuto sets GOMAXPROCS from
+	// the container CPU quota unless GOMAXPROCS is set.
+	GoMemoryLimit  string `json:"goMemoryLimit"`
+	GoMaxProcsAuto bool   `json:"goMaxProcsAuto"`
+
+	// BulkWorkers is the number of users processed in parallel by bulk
+	// add/remove requests.
+	BulkWorkers int `json:"bulkWorkers"`
+
+	// ErrorResponseVersion selects the body of failed panel API requests,
+	// ErrorResponseLegacy or ErrorResponseCoded.
+	ErrorResponseVersion int `json:"errorResponseVersion"`
+
+	// ConsistencyCheckInterval in seconds enables the periodic comparison of
+	// the config state with the xray users; zero disables it.
+	ConsistencyCheckInterval int  `json:"consistencyCheckInterval"`
+	ConsistencyAutoRepair    bool `json:"consistencyAutoRepair"`
+
+	// JWTPublicKeys holds PEM keys accepted for panel JWTs besides the one
+	// in SECRET_KEY. JWKSURL adds the keys published by the panel, fetched
+	// every JWKSRefreshInterval seconds and selected by kid. PanelCACerts
+	// likewise holds PEM CAs accepted for panel client certificates, e.g.
+	// of a standby panel or of the panel the node is migrated to.
+	JWTPublicKeys       string `json:"jwtPublicKeys"`
+	PanelCACerts        string `json:"panelCaCerts"`
+	JWKSURL             string `json:"jwksUrl"`
+	JWKSRefreshInterval int    `json:"jwksRefreshInterval"`
+
+	// JWTIssuer and JWTAudience, when set, must match the iss and aud claims
+	// of panel JWTs. JWTLeeway is the tolerated clock skew in seconds.
+	// JWTReplayProtection accepts each token jti only once.
+	JWTIssuer           string `json:"jwtIssuer"`
+	JWTAudience         string `json:"jwtAudience"`
+	JWTLeeway           int    `json:"jwtLeeway"`
+	JWTReplayProtection bool   `json:"jwtReplayProtection"`
+
+	// JWTScopes limits tokens carrying a scope claim to the routes granted
+	// by their scopes, as "scope=route,route;scope=route". Tokens without
+	// a scope claim keep full access.
+	JWTScopes string `json:"jwtScopes"`
+
+	// CRLFile and CRLURL point to revocation lists, signed by the CA in
+	// SECRET_KEY, of panel client certificates to reject. They are loaded
+	// again every CRLRefreshInterval seconds.
+	CRLFile            string `json:"crlFile"`
+	CRLURL             string `json:"crlUrl"`
+	CRLRefreshInterval int    `json:"crlRefreshInterval"`
+
+	// AutoBlock blocks source IPs after AutoBlockMaxFailures xray
+	// authentication failures or REALITY probes within AutoBlockWindow
+	// seconds, for AutoBlockBanDuration seconds. AutoBlockWhitelist is a
+	// comma-separated list of IPs and CIDR ranges never blocked. IPv6
+	// addresses are counted and blocked by their AutoBlockIPv6Prefix
+	// network, 128 for the address alone.
+	AutoBlock            bool   `json:"autoBlock"`
+	AutoBlockMaxFailures int    `json:"autoBlockMaxFailures"`
+	AutoBlockWindow      int    `json:"autoBlockWindow"`
+	AutoBlockBanDuration int    `json:"autoBlockBanDuration"`
+	AutoBlockWhitelist   string `json:"autoBlockWhitelist"`
+	AutoBlockIPv6Prefix  int    `json:"autoBlockIpv6Prefix"`
+
+	// ClusterSecret enables the cluster mode: the IPs auto blocked on this
+	// node are sent to ClusterPeers, a comma-separated list of the cluster
+	// endpoint URLs of the other nodes, and theirs are received on
+	// ClusterPort (0 only sends). Requests are signed with the secret.
+	ClusterSecret string `json:"clusterSecret"`
+	ClusterPeers  string `json:"clusterPeers"`
+	ClusterPort   int    `json:"clusterPort"`
+
+	// FirewallBackend, "nftables" or "ipset", also drops the traffic of
+	// blocked IPs in the kernel, so xray does not handle it. The sets and
+	// rules are removed when the node stops.
+	FirewallBackend string `json:"firewallBackend"`
+
+	// ConfigVars are the node variables ${NAME} placeholders in the panel
+	// xray config resolve to, as "NAME=value,NAME=value".
+	// ConfigEnvAllowlist is a comma-separated list of the environment
+	// variables ${ENV:NAME} placeholders may read; a trailing "*" allows a
+	// prefix.
+	ConfigVars         string `json:"configVars"`
+	ConfigEnvAllowlist string `json:"configEnvAllowlist"`
+
+	// AssetPath is the directory xray loads geoip.dat, geosite.dat and other
+	// "ext:" files from. Empty uses XRAY_LOCATION_ASSET, or the first of
+	// the usual xray directories holding geoip.dat.
+	AssetPath string `json:"assetPath"`
+
+	// GeodataAutoUpdate downloads geoip.dat and geosite.dat from GeoIPURL
+	// and GeoSiteURL into GeodataDir, by default AssetPath, when missing, and checks for new
+	// versions every GeodataUpdateInterval seconds (0 disables the checks).
+	// Each file is verified against the SHA-256 published next to it at
+	// URL + ".sha256sum".
+	GeodataAutoUpdate     bool   `json:"geodataAutoUpdate"`
+	GeodataDir            string `json:"geodataDir"`
+	GeoIPURL              string `json:"geoipUrl"`
+	GeoSiteURL            string `json:"geositeUrl"`
+	GeodataUpdateInterval int    `json:"geodataUpdateInterval"`
+	// GeodataEmbedded serves the geodata compiled into the binary for
+	// geodata files missing on disk: only the private lists, unless built
+	// with the geodata_embed tag.
+	GeodataEmbedded bool `json:"geodataEmbedded"`
+
+	// XrayMode, "embedded" or "external", chooses between the xray-core
+	// compiled into the node and a separate xray process of XrayBinary
+	// driven through its gRPC API. XrayVersion, if set, is the version the
+	// binary must have.
+	XrayMode    string `json:"xrayMode"`
+	XrayBinary  string `json:"xrayBinary"`
+	XrayVersion string `json:"xrayVersion"`
+
+	// GracefulUpgrade makes SIGUSR2 start a new process of the node binary
+	// that takes over the listening sockets before this one exits. PIDFile,
+	// if set, is kept pointing at the process currently serving, for
+	// supervisors following it.
+	GracefulUpgrade bool   `json:"gracefulUpgrade"`
+	PIDFile         string `json:"pidFile"`
+
+	// MaintenanceWindow is a cron expression, in the local time zone, at
+	// which a maintenance window of MaintenanceWindowDuration seconds
+	// opens. When set, core restarts for config changes, scheduled geodata
+	// updates and SIGUSR2 upgrades wait for the window.
+	MaintenanceWindow         string `json:"maintenanceWindow"`
+	MaintenanceWindowDuration int    `json:"maintenanceWindowDuration"`
 
 	Payload *NodePayload `json:"-"`
 }
 
 func Load() (*Config, error) {
-	cfg := &Config{
-		NodePort:         DefaultNodePort,
-		InternalRestPort: DefaultInternalRestPort,
-		LogLevel:         DefaultLogLevel,
+	cfg, err := LoadSettings()
+	if err != nil {
+		return nil, err
 	}
 
-	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
-		if err := loadFromFile(cfg, configPath); err != nil {
+	if cfg.SecretKeyFile != "" {
+		data, err := os.ReadFile(cfg.SecretKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret key file: %w", err)
+		}
+		cfg.SecretKey = strings.TrimSpace(string(data))
+	} else {
+		if cfg.VaultSecretIDFile != "" {
+			data, err := os.ReadFile(cfg.VaultSecretIDFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read Vault secret ID file: %w", err)
+			}
+			cfg.VaultSecretID = strings.TrimSpace(string(data))
+		}
+		source, err := cfg.SecretKeySource()
+		if err != nil {
 			return nil, err
 		}
+		if source != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), secretKeyFetchTimeout)
+			cfg.SecretKey, err = source.Fetch(ctx)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch secret key: %w", err)
+			}
+		}
 	}
 
-	loadFromEnv(cfg)
-
 	if cfg.SecretKey == "" {
 		return nil, ErrConfigSecretKeyRequired
 	}
 
+	if cfg.StateEncryptionKeyFile != "" {
+		data, err := os.ReadFile(cfg.StateEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read state encryption key file: %w", err)
+		}
+		cfg.StateEncryptionKey = strings.TrimSpace(string(data))
+	}
+
 	payload, err := ParseSecretKey(cfg.SecretKey)
 	if err != nil {
 		return nil, err
@@ -53,6 +445,77 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// LoadSettings loads the configuration like Load but leaves SECRET_KEY
+// alone, for commands that work without the node credentials.
+func LoadSettings() (*Config, error) {
+	cfg := &Config{
+		NodePort:           DefaultNodePort,
+		InternalRestPort:   DefaultInternalRestPort,
+		APIPort:            DefaultAPIPort,
+		LogLevel:           DefaultLogLevel,
+		AccessLog:          DefaultAccessLog,
+		AccessLogSkipStats: true,
+		LogBufferSize:      DefaultLogBufferSize,
+		StateDir:           DefaultStateDir,
+		StateRedisPrefix:   DefaultStateRedisPrefix,
+		StatsHistorySize:   DefaultStatsHistorySize,
+		EventHistorySize:   DefaultEventHistorySize,
+
+		SecretKeyVaultField:      DefaultSecretKeyVaultField,
+		SecretKeyRefreshInterval: DefaultSecretKeyRefreshInterval,
+
+		InternalSocketMode:    DefaultInternalSocketMode,
+		MaxBodySize:           DefaultMaxBodySize,
+		MaxDecompressedSize:   DefaultMaxDecompressedSize,
+		HTTPReadHeaderTimeout: DefaultHTTPReadHeaderTimeout,
+		HTTPReadTimeout:       DefaultHTTPReadTimeout,
+		HTTPWriteTimeout:      DefaultHTTPWriteTimeout,
+		HTTPIdleTimeout:       DefaultHTTPIdleTimeout,
+		ShutdownTimeout:       DefaultShutdownTimeout,
+		StatsPushInterval:     DefaultStatsPushInterval,
+		HeartbeatInterval:     DefaultHeartbeatInterval,
+		TrafficExportFormat:   DefaultTrafficExportFormat,
+		TrafficExportInterval: DefaultTrafficExportInterval,
+		TelegramRateLimit:     DefaultTelegramRateLimit,
+		DiskMinFreePercent:    DefaultDiskMinFreePercent,
+		LimitWarnPercent:      DefaultLimitWarnPercent,
+		OutboundProbeTarget:   DefaultOutboundProbeTarget,
+		OutboundProbeInterval: DefaultOutboundProbeInterval,
+		OutboundProbeTimeout:  DefaultOutboundProbeTimeout,
+		BulkWorkers:           DefaultBulkWorkers,
+		ErrorResponseVersion:  ErrorResponseLegacy,
+		GoMaxProcsAuto:        true,
+
+		JWKSRefreshInterval: DefaultJWKSRefreshInterval,
+		CRLRefreshInterval:  DefaultCRLRefreshInterval,
+
+		GeoIPURL:              DefaultGeoIPURL,
+		GeoSiteURL:            DefaultGeoSiteURL,
+		GeodataUpdateInterval: DefaultGeodataUpdateInterval,
+		GeodataEmbedded:       true,
+
+		XrayMode:   DefaultXrayMode,
+		XrayBinary: DefaultXrayBinary,
+
+		AutoBlockMaxFailures: DefaultAutoBlockMaxFailures,
+		AutoBlockWindow:      DefaultAutoBlockWindow,
+		AutoBlockBanDuration: DefaultAutoBlockBanDuration,
+		AutoBlockIPv6Prefix:  DefaultAutoBlockIPv6Prefix,
+
+		MaintenanceWindowDuration: DefaultMaintenanceWindowDuration,
+	}
+
+	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
+		if err := loadFromFile(cfg, configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	loadFromEnv(cfg)
+
+	return cfg, nil
+}
+
 func loadFromFile(cfg *Config, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -65,6 +528,9 @@ func loadFromEnv(cfg *Config) {
 	if v := os.Getenv("SECRET_KEY"); v != "" {
 		cfg.SecretKey = v
 	}
+	if v := os.Getenv("SECRET_KEY_FILE"); v != "" {
+		cfg.SecretKeyFile = v
+	}
 	if v := os.Getenv("NODE_PORT"); v != "" {
 		if port := parseIntOr(v, 0); port > 0 {
 			cfg.NodePort = port
@@ -75,9 +541,401 @@ func loadFromEnv(cfg *Config) {
 			cfg.InternalRestPort = port
 		}
 	}
+	if v := os.Getenv("INTERNAL_SOCKET_PATH"); v != "" {
+		cfg.InternalSocketPath = v
+	}
+	if v := os.Getenv("INTERNAL_SOCKET_MODE"); v != "" {
+		cfg.InternalSocketMode = v
+	}
+	if v := os.Getenv("API_PORT"); v != "" {
+		if port := parseIntOr(v, 0); port > 0 {
+			cfg.APIPort = port
+		}
+	}
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		cfg.LogLevel = v
 	}
+	if v := os.Getenv("ACCESS_LOG"); v != "" {
+		cfg.AccessLog = v
+	}
+	if v := os.Getenv("ACCESS_LOG_SKIP_STATS"); v != "" {
+		cfg.AccessLogSkipStats = v == "true" || v == "1"
+	}
+	if v := os.Getenv("LOG_BUFFER_SIZE"); v != "" {
+		if size := parseIntOr(v, -1); size >= 0 {
+			cfg.LogBufferSize = size
+		}
+	}
+	if v := os.Getenv("NODE_ID"); v != "" {
+		cfg.NodeID = v
+	}
+	if v := os.Getenv("NODE_LABELS"); v != "" {
+		cfg.NodeLabels = v
+	}
+	if v := os.Getenv("STATE_DIR"); v != "" {
+		cfg.StateDir = v
+	}
+	if v := os.Getenv("STATE_ENCRYPTION_KEY"); v != "" {
+		cfg.StateEncryptionKey = v
+	}
+	if v := os.Getenv("STATE_ENCRYPTION_KEY_FILE"); v != "" {
+		cfg.StateEncryptionKeyFile = v
+	}
+	if v := os.Getenv("STATE_REDIS_URL"); v != "" {
+		cfg.StateRedisURL = v
+	}
+	if v := os.Getenv("STATE_REDIS_PREFIX"); v != "" {
+		cfg.StateRedisPrefix = v
+	}
+	if v := os.Getenv("SECRET_KEY_VAULT_PATH"); v != "" {
+		cfg.SecretKeyVaultPath = v
+	}
+	if v := os.Getenv("SECRET_KEY_VAULT_FIELD"); v != "" {
+		cfg.SecretKeyVaultField = v
+	}
+	if v := os.Getenv("VAULT_ADDR"); v != "" {
+		cfg.VaultAddr = v
+	}
+	if v := os.Getenv("VAULT_NAMESPACE"); v != "" {
+		cfg.VaultNamespace = v
+	}
+	if v := os.Getenv("VAULT_TOKEN"); v != "" {
+		cfg.VaultToken = v
+	}
+	if v := os.Getenv("VAULT_TOKEN_FILE"); v != "" {
+		cfg.VaultTokenFile = v
+	}
+	if v := os.Getenv("VAULT_ROLE_ID"); v != "" {
+		cfg.VaultRoleID = v
+	}
+	if v := os.Getenv("VAULT_SECRET_ID"); v != "" {
+		cfg.VaultSecretID = v
+	}
+	if v := os.Getenv("VAULT_SECRET_ID_FILE"); v != "" {
+		cfg.VaultSecretIDFile = v
+	}
+	if v := os.Getenv("SECRET_KEY_GCP_SECRET"); v != "" {
+		cfg.SecretKeyGCPSecret = v
+	}
+	if v := os.Getenv("SECRET_KEY_REFRESH_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, -1); interval >= 0 {
+			cfg.SecretKeyRefreshInterval = interval
+		}
+	}
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		if port := parseIntOr(v, 0); port > 0 {
+			cfg.GRPCPort = port
+		}
+	}
+	if v := os.Getenv("STATS_HISTORY_SIZE"); v != "" {
+		if size := parseIntOr(v, 0); size > 0 {
+			cfg.StatsHistorySize = size
+		}
+	}
+	if v := os.Getenv("EVENT_HISTORY_SIZE"); v != "" {
+		if size := parseIntOr(v, 0); size > 0 {
+			cfg.EventHistorySize = size
+		}
+	}
+	if v := os.Getenv("EVENT_HISTORY_PERSIST"); v != "" {
+		cfg.EventHistoryPersist = v == "true" || v == "1"
+	}
+	if v := os.Getenv("MAX_BODY_SIZE"); v != "" {
+		if size := parseIntOr(v, -1); size >= 0 {
+			cfg.MaxBodySize = size
+		}
+	}
+	if v := os.Getenv("MAX_DECOMPRESSED_SIZE"); v != "" {
+		if size := parseIntOr(v, -1); size >= 0 {
+			cfg.MaxDecompressedSize = size
+		}
+	}
+	if v := os.Getenv("HTTP_READ_HEADER_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, -1); timeout >= 0 {
+			cfg.HTTPReadHeaderTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_READ_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, -1); timeout >= 0 {
+			cfg.HTTPReadTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_WRITE_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, -1); timeout >= 0 {
+			cfg.HTTPWriteTimeout = timeout
+		}
+	}
+	if v := os.Getenv("HTTP_IDLE_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, -1); timeout >= 0 {
+			cfg.HTTPIdleTimeout = timeout
+		}
+	}
+	if v := os.Getenv("SHUTDOWN_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, -1); timeout >= 0 {
+			cfg.ShutdownTimeout = timeout
+		}
+	}
+	if v := os.Getenv("PANEL_ALLOWED_IPS"); v != "" {
+		cfg.PanelAllowedIPs = v
+	}
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		cfg.TrustedProxies = v
+	}
+	if v := os.Getenv("PROXY_PROTOCOL"); v != "" {
+		cfg.ProxyProtocol = v == "true" || v == "1"
+	}
+	if v := os.Getenv("STATS_PUSH_URL"); v != "" {
+		cfg.StatsPushURL = v
+	}
+	if v := os.Getenv("STATS_PUSH_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.StatsPushInterval = interval
+		}
+	}
+	if v := os.Getenv("HEARTBEAT_URL"); v != "" {
+		cfg.HeartbeatURL = v
+	}
+	if v := os.Getenv("HEARTBEAT_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.HeartbeatInterval = interval
+		}
+	}
+	if v := os.Getenv("WEBHOOK_URL"); v != "" {
+		cfg.WebhookURL = v
+	}
+	if v := os.Getenv("WEBHOOK_SECRET"); v != "" {
+		cfg.WebhookSecret = v
+	}
+	if v := os.Getenv("WEBHOOK_EVENTS"); v != "" {
+		cfg.WebhookEvents = v
+	}
+	if v := os.Getenv("BROKER_URL"); v != "" {
+		cfg.BrokerURL = v
+	}
+	if v := os.Getenv("BROKER_PREFIX"); v != "" {
+		cfg.BrokerPrefix = v
+	}
+	if v := os.Getenv("BROKER_EVENTS"); v != "" {
+		cfg.BrokerEvents = v
+	}
+	if v := os.Getenv("TRAFFIC_EXPORT_URL"); v != "" {
+		cfg.TrafficExportURL = v
+	}
+	if v := os.Getenv("TRAFFIC_EXPORT_FORMAT"); v != "" {
+		cfg.TrafficExportFormat = v
+	}
+	if v := os.Getenv("TRAFFIC_EXPORT_TABLE"); v != "" {
+		cfg.TrafficExportTable = v
+	}
+	if v := os.Getenv("TRAFFIC_EXPORT_TOKEN"); v != "" {
+		cfg.TrafficExportToken = v
+	}
+	if v := os.Getenv("TRAFFIC_EXPORT_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.TrafficExportInterval = interval
+		}
+	}
+	if v := os.Getenv("TELEGRAM_BOT_TOKEN"); v != "" {
+		cfg.TelegramBotToken = v
+	}
+	if v := os.Getenv("TELEGRAM_CHAT_ID"); v != "" {
+		cfg.TelegramChatID = v
+	}
+	if v := os.Getenv("TELEGRAM_RATE_LIMIT"); v != "" {
+		if limit := parseIntOr(v, 0); limit > 0 {
+			cfg.TelegramRateLimit = limit
+		}
+	}
+	if v := os.Getenv("OTLP_ENDPOINT"); v != "" {
+		cfg.OTLPEndpoint = v
+	}
+	if v := os.Getenv("DISK_MIN_FREE_PERCENT"); v != "" {
+		if percent := parseIntOr(v, 0); percent > 0 {
+			cfg.DiskMinFreePercent = percent
+		}
+	}
+	if v := os.Getenv("LIMIT_WARN_PERCENT"); v != "" {
+		if percent := parseIntOr(v, 0); percent > 0 && percent <= 100 {
+			cfg.LimitWarnPercent = percent
+		}
+	}
+	if v := os.Getenv("MEMORY_RESTART_THRESHOLD"); v != "" {
+		if threshold := parseIntOr(v, -1); threshold >= 0 {
+			cfg.MemoryRestartThreshold = threshold
+		}
+	}
+	if v := os.Getenv("OUTBOUND_PROBE_TAGS"); v != "" {
+		cfg.OutboundProbeTags = v
+	}
+	if v := os.Getenv("OUTBOUND_PROBE_TARGET"); v != "" {
+		cfg.OutboundProbeTarget = v
+	}
+	if v := os.Getenv("OUTBOUND_PROBE_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.OutboundProbeInterval = interval
+		}
+	}
+	if v := os.Getenv("OUTBOUND_PROBE_TIMEOUT"); v != "" {
+		if timeout := parseIntOr(v, 0); timeout > 0 {
+			cfg.OutboundProbeTimeout = timeout
+		}
+	}
+	if v := os.Getenv("GO_MEMORY_LIMIT"); v != "" {
+		cfg.GoMemoryLimit = v
+	}
+	if v := os.Getenv("GOMAXPROCS_AUTO"); v != "" {
+		cfg.GoMaxProcsAuto = v == "true" || v == "1"
+	}
+	if v := os.Getenv("BULK_WORKERS"); v != "" {
+		if workers := parseIntOr(v, 0); workers > 0 {
+			cfg.BulkWorkers = workers
+		}
+	}
+	if v := os.Getenv("ERROR_RESPONSE_VERSION"); v != "" {
+		if version := parseIntOr(v, 0); version == ErrorResponseLegacy || version == ErrorResponseCoded {
+			cfg.ErrorResponseVersion = version
+		}
+	}
+	if v := os.Getenv("CONSISTENCY_CHECK_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, -1); interval >= 0 {
+			cfg.ConsistencyCheckInterval = interval
+		}
+	}
+	if v := os.Getenv("CONSISTENCY_AUTO_REPAIR"); v != "" {
+		cfg.ConsistencyAutoRepair = v == "true" || v == "1"
+	}
+	if v := os.Getenv("JWT_PUBLIC_KEYS"); v != "" {
+		cfg.JWTPublicKeys = v
+	}
+	if v := os.Getenv("PANEL_CA_CERTS"); v != "" {
+		cfg.PanelCACerts = v
+	}
+	if v := os.Getenv("JWKS_URL"); v != "" {
+		cfg.JWKSURL = v
+	}
+	if v := os.Getenv("JWKS_REFRESH_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.JWKSRefreshInterval = interval
+		}
+	}
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		cfg.JWTIssuer = v
+	}
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		cfg.JWTAudience = v
+	}
+	if v := os.Getenv("JWT_LEEWAY"); v != "" {
+		if leeway := parseIntOr(v, -1); leeway >= 0 {
+			cfg.JWTLeeway = leeway
+		}
+	}
+	if v := os.Getenv("JWT_REPLAY_PROTECTION"); v != "" {
+		cfg.JWTReplayProtection = v == "true" || v == "1"
+	}
+	if v := os.Getenv("JWT_SCOPES"); v != "" {
+		cfg.JWTScopes = v
+	}
+	if v := os.Getenv("CRL_FILE"); v != "" {
+		cfg.CRLFile = v
+	}
+	if v := os.Getenv("CRL_URL"); v != "" {
+		cfg.CRLURL = v
+	}
+	if v := os.Getenv("CRL_REFRESH_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, 0); interval > 0 {
+			cfg.CRLRefreshInterval = interval
+		}
+	}
+	if v := os.Getenv("AUTO_BLOCK"); v != "" {
+		cfg.AutoBlock = v == "true" || v == "1"
+	}
+	if v := os.Getenv("AUTO_BLOCK_MAX_FAILURES"); v != "" {
+		if n := parseIntOr(v, 0); n > 0 {
+			cfg.AutoBlockMaxFailures = n
+		}
+	}
+	if v := os.Getenv("AUTO_BLOCK_WINDOW"); v != "" {
+		if window := parseIntOr(v, 0); window > 0 {
+			cfg.AutoBlockWindow = window
+		}
+	}
+	if v := os.Getenv("AUTO_BLOCK_BAN_DURATION"); v != "" {
+		if duration := parseIntOr(v, 0); duration > 0 {
+			cfg.AutoBlockBanDuration = duration
+		}
+	}
+	if v := os.Getenv("AUTO_BLOCK_WHITELIST"); v != "" {
+		cfg.AutoBlockWhitelist = v
+	}
+	if v := os.Getenv("AUTO_BLOCK_IPV6_PREFIX"); v != "" {
+		if prefix := parseIntOr(v, 0); prefix > 0 && prefix <= 128 {
+			cfg.AutoBlockIPv6Prefix = prefix
+		}
+	}
+	if v := os.Getenv("CLUSTER_SECRET"); v != "" {
+		cfg.ClusterSecret = v
+	}
+	if v := os.Getenv("CLUSTER_PEERS"); v != "" {
+		cfg.ClusterPeers = v
+	}
+	if v := os.Getenv("CLUSTER_PORT"); v != "" {
+		cfg.ClusterPort = parseIntOr(v, 0)
+	}
+	if v := os.Getenv("FIREWALL_BACKEND"); v != "" {
+		cfg.FirewallBackend = v
+	}
+	if v := os.Getenv("CONFIG_VARS"); v != "" {
+		cfg.ConfigVars = v
+	}
+	if v := os.Getenv("CONFIG_ENV_ALLOWLIST"); v != "" {
+		cfg.ConfigEnvAllowlist = v
+	}
+	if v := os.Getenv("ASSET_PATH"); v != "" {
+		cfg.AssetPath = v
+	}
+	if v := os.Getenv("GEODATA_AUTO_UPDATE"); v != "" {
+		cfg.GeodataAutoUpdate = v == "true" || v == "1"
+	}
+	if v := os.Getenv("GEODATA_DIR"); v != "" {
+		cfg.GeodataDir = v
+	}
+	if v := os.Getenv("GEOIP_URL"); v != "" {
+		cfg.GeoIPURL = v
+	}
+	if v := os.Getenv("GEOSITE_URL"); v != "" {
+		cfg.GeoSiteURL = v
+	}
+	if v := os.Getenv("GEODATA_UPDATE_INTERVAL"); v != "" {
+		if interval := parseIntOr(v, -1); interval >= 0 {
+			cfg.GeodataUpdateInterval = interval
+		}
+	}
+	if v := os.Getenv("GEODATA_EMBEDDED"); v != "" {
+		cfg.GeodataEmbedded = v == "true" || v == "1"
+	}
+	if v := os.Getenv("XRAY_MODE"); v != "" {
+		cfg.XrayMode = v
+	}
+	if v := os.Getenv("XRAY_BINARY"); v != "" {
+		cfg.XrayBinary = v
+	}
+	if v := os.Getenv("XRAY_VERSION"); v != "" {
+		cfg.XrayVersion = v
+	}
+	if v := os.Getenv("GRACEFUL_UPGRADE"); v != "" {
+		cfg.GracefulUpgrade = v == "true" || v == "1"
+	}
+	if v := os.Getenv("PID_FILE"); v != "" {
+		cfg.PIDFile = v
+	}
+	if v := os.Getenv("MAINTENANCE_WINDOW"); v != "" {
+		cfg.MaintenanceWindow = v
+	}
+	if v := os.Getenv("MAINTENANCE_WINDOW_DURATION"); v != "" {
+		if duration := parseIntOr(v, 0); duration > 0 {
+			cfg.MaintenanceWindowDuration = duration
+		}
+	}
 }
 
 func parseIntOr(s string, fallback int) int {
@@ -87,3 +945,24 @@ func parseIntOr(s string, fallback int) int {
 	}
 	return n
 }
+
+// ParseLabels parses comma-separated key=value pairs, such as NodeLabels.
+func ParseLabels(spec string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		if _, dup := labels[key]; dup {
+			return nil, fmt.Errorf("duplicate label %q", key)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"encoding/base64"
 	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -27,6 +29,7 @@ func TestLoad_FromEnvOnly(t *testing.T) {
 	os.Unsetenv("CONFIG_PATH")
 	os.Unsetenv("NODE_PORT")
 	os.Unsetenv("INTERNAL_REST_PORT")
+	os.Unsetenv("API_PORT")
 	os.Unsetenv("LOG_LEVEL")
 	defer os.Unsetenv("SECRET_KEY")
 
@@ -35,7 +38,97 @@ func TestLoad_FromEnvOnly(t *testing.T) {
 
 	assert.Equal(t, DefaultNodePort, cfg.NodePort)
 	assert.Equal(t, DefaultInternalRestPort, cfg.InternalRestPort)
+	assert.Equal(t, DefaultAPIPort, cfg.APIPort)
+	assert.Empty(t, cfg.InternalSocketPath)
+	assert.Equal(t, DefaultInternalSocketMode, cfg.InternalSocketMode)
 	assert.Equal(t, DefaultLogLevel, cfg.LogLevel)
+	assert.Equal(t, DefaultAccessLog, cfg.AccessLog)
+	assert.True(t, cfg.AccessLogSkipStats)
+	assert.Equal(t, DefaultLogBufferSize, cfg.LogBufferSize)
+	assert.Empty(t, cfg.NodeID)
+	assert.Empty(t, cfg.NodeLabels)
+	assert.Equal(t, DefaultStateDir, cfg.StateDir)
+	assert.Empty(t, cfg.StateEncryptionKey)
+	assert.Empty(t, cfg.SecretKeyVaultPath)
+	assert.Equal(t, DefaultSecretKeyVaultField, cfg.SecretKeyVaultField)
+	assert.Equal(t, DefaultSecretKeyRefreshInterval, cfg.SecretKeyRefreshInterval)
+	assert.Equal(t, 0, cfg.GRPCPort)
+	assert.Equal(t, DefaultStatsHistorySize, cfg.StatsHistorySize)
+	assert.Equal(t, DefaultEventHistorySize, cfg.EventHistorySize)
+	assert.False(t, cfg.EventHistoryPersist)
+	assert.Equal(t, DefaultMaxBodySize, cfg.MaxBodySize)
+	assert.Equal(t, DefaultMaxDecompressedSize, cfg.MaxDecompressedSize)
+	assert.Equal(t, DefaultHTTPReadHeaderTimeout, cfg.HTTPReadHeaderTimeout)
+	assert.Equal(t, DefaultHTTPReadTimeout, cfg.HTTPReadTimeout)
+	assert.Equal(t, DefaultHTTPWriteTimeout, cfg.HTTPWriteTimeout)
+	assert.Equal(t, DefaultHTTPIdleTimeout, cfg.HTTPIdleTimeout)
+	assert.Equal(t, DefaultShutdownTimeout, cfg.ShutdownTimeout)
+	assert.Empty(t, cfg.PanelAllowedIPs)
+	assert.Empty(t, cfg.TrustedProxies)
+	assert.False(t, cfg.ProxyProtocol)
+	assert.Empty(t, cfg.StatsPushURL)
+	assert.Equal(t, DefaultStatsPushInterval, cfg.StatsPushInterval)
+	assert.Empty(t, cfg.HeartbeatURL)
+	assert.Equal(t, DefaultHeartbeatInterval, cfg.HeartbeatInterval)
+	assert.Empty(t, cfg.WebhookURL)
+	assert.Empty(t, cfg.BrokerURL)
+	assert.Empty(t, cfg.WebhookEvents)
+	assert.Empty(t, cfg.TrafficExportURL)
+	assert.Equal(t, DefaultTrafficExportFormat, cfg.TrafficExportFormat)
+	assert.Equal(t, DefaultTrafficExportInterval, cfg.TrafficExportInterval)
+	assert.Empty(t, cfg.TelegramBotToken)
+	assert.Equal(t, DefaultTelegramRateLimit, cfg.TelegramRateLimit)
+	assert.Empty(t, cfg.OTLPEndpoint)
+	assert.Equal(t, DefaultDiskMinFreePercent, cfg.DiskMinFreePercent)
+	assert.Equal(t, DefaultLimitWarnPercent, cfg.LimitWarnPercent)
+	assert.Empty(t, cfg.OutboundProbeTags)
+	assert.Equal(t, DefaultOutboundProbeTarget, cfg.OutboundProbeTarget)
+	assert.Equal(t, DefaultOutboundProbeInterval, cfg.OutboundProbeInterval)
+	assert.Equal(t, DefaultOutboundProbeTimeout, cfg.OutboundProbeTimeout)
+	assert.Zero(t, cfg.MemoryRestartThreshold)
+	assert.Empty(t, cfg.GoMemoryLimit)
+	assert.True(t, cfg.GoMaxProcsAuto)
+	assert.Equal(t, DefaultBulkWorkers, cfg.BulkWorkers)
+	assert.Equal(t, ErrorResponseLegacy, cfg.ErrorResponseVersion)
+	assert.Equal(t, 0, cfg.ConsistencyCheckInterval)
+	assert.False(t, cfg.ConsistencyAutoRepair)
+	assert.Empty(t, cfg.JWTPublicKeys)
+	assert.Empty(t, cfg.PanelCACerts)
+	assert.Empty(t, cfg.JWKSURL)
+	assert.Equal(t, DefaultJWKSRefreshInterval, cfg.JWKSRefreshInterval)
+	assert.Empty(t, cfg.JWTIssuer)
+	assert.Empty(t, cfg.JWTAudience)
+	assert.Equal(t, 0, cfg.JWTLeeway)
+	assert.False(t, cfg.JWTReplayProtection)
+	assert.Empty(t, cfg.JWTScopes)
+	assert.Empty(t, cfg.CRLFile)
+	assert.Empty(t, cfg.CRLURL)
+	assert.Equal(t, DefaultCRLRefreshInterval, cfg.CRLRefreshInterval)
+	assert.False(t, cfg.AutoBlock)
+	assert.Equal(t, DefaultAutoBlockMaxFailures, cfg.AutoBlockMaxFailures)
+	assert.Equal(t, DefaultAutoBlockWindow, cfg.AutoBlockWindow)
+	assert.Equal(t, DefaultAutoBlockBanDuration, cfg.AutoBlockBanDuration)
+	assert.Equal(t, DefaultAutoBlockIPv6Prefix, cfg.AutoBlockIPv6Prefix)
+	assert.Empty(t, cfg.ClusterSecret)
+	assert.Empty(t, cfg.StateRedisURL)
+	assert.Equal(t, DefaultStateRedisPrefix, cfg.StateRedisPrefix)
+	assert.Zero(t, cfg.ClusterPort)
+	assert.Empty(t, cfg.AutoBlockWhitelist)
+	assert.Empty(t, cfg.FirewallBackend)
+	assert.Empty(t, cfg.AssetPath)
+	assert.False(t, cfg.GeodataAutoUpdate)
+	assert.Empty(t, cfg.GeodataDir)
+	assert.Equal(t, DefaultGeoIPURL, cfg.GeoIPURL)
+	assert.Equal(t, DefaultGeoSiteURL, cfg.GeoSiteURL)
+	assert.Equal(t, DefaultGeodataUpdateInterval, cfg.GeodataUpdateInterval)
+	assert.True(t, cfg.GeodataEmbedded)
+	assert.Equal(t, DefaultXrayMode, cfg.XrayMode)
+	assert.Equal(t, DefaultXrayBinary, cfg.XrayBinary)
+	assert.Empty(t, cfg.XrayVersion)
+	assert.False(t, cfg.GracefulUpgrade)
+	assert.Empty(t, cfg.PIDFile)
+	assert.Empty(t, cfg.MaintenanceWindow)
+	assert.Equal(t, DefaultMaintenanceWindowDuration, cfg.MaintenanceWindowDuration)
 	assert.NotNil(t, cfg.Payload)
 }
 
@@ -43,13 +136,213 @@ func TestLoad_EnvOverridesDefaults(t *testing.T) {
 	os.Setenv("SECRET_KEY", makeTestSecretKey())
 	os.Setenv("NODE_PORT", "3333")
 	os.Setenv("INTERNAL_REST_PORT", "62000")
+	os.Setenv("API_PORT", "62012")
+	os.Setenv("INTERNAL_SOCKET_PATH", "/run/remnawave-node/internal.sock")
+	os.Setenv("INTERNAL_SOCKET_MODE", "0660")
 	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("ACCESS_LOG", "errors")
+	os.Setenv("ACCESS_LOG_SKIP_STATS", "false")
+	os.Setenv("LOG_BUFFER_SIZE", "0")
+	os.Setenv("NODE_ID", "node-eu-1")
+	os.Setenv("NODE_LABELS", "region=eu")
+	os.Setenv("STATE_DIR", "/tmp/node-state")
+	os.Setenv("STATE_ENCRYPTION_KEY", "c3RhdGUta2V5")
+	os.Setenv("SECRET_KEY_VAULT_FIELD", "nodeSecretKey")
+	os.Setenv("VAULT_NAMESPACE", "admin/nodes")
+	os.Setenv("VAULT_ROLE_ID", "role-id")
+	os.Setenv("SECRET_KEY_REFRESH_INTERVAL", "0")
+	os.Setenv("GRPC_PORT", "2223")
+	os.Setenv("STATS_HISTORY_SIZE", "60")
+	os.Setenv("EVENT_HISTORY_SIZE", "200")
+	os.Setenv("EVENT_HISTORY_PERSIST", "true")
+	os.Setenv("MAX_BODY_SIZE", "1048576")
+	os.Setenv("MAX_DECOMPRESSED_SIZE", "8388608")
+	os.Setenv("HTTP_READ_HEADER_TIMEOUT", "5")
+	os.Setenv("HTTP_READ_TIMEOUT", "30")
+	os.Setenv("HTTP_WRITE_TIMEOUT", "0")
+	os.Setenv("HTTP_IDLE_TIMEOUT", "90")
+	os.Setenv("SHUTDOWN_TIMEOUT", "30")
+	os.Setenv("PANEL_ALLOWED_IPS", "203.0.113.10,2001:db8::/32")
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+	os.Setenv("PROXY_PROTOCOL", "true")
+	os.Setenv("STATS_PUSH_URL", "https://panel.example.com/api/nodes/stats")
+	os.Setenv("STATS_PUSH_INTERVAL", "30")
+	os.Setenv("HEARTBEAT_URL", "https://panel.example.com/api/nodes/heartbeat")
+	os.Setenv("HEARTBEAT_INTERVAL", "15")
+	os.Setenv("WEBHOOK_URL", "https://hooks.example.com/node")
+	os.Setenv("WEBHOOK_SECRET", "hook-secret")
+	os.Setenv("WEBHOOK_EVENTS", "xray.crashed,certificate.expiring")
+	os.Setenv("BROKER_URL", "nats://token@nats.example.com:4222")
+	os.Setenv("BROKER_PREFIX", "panel.nodes")
+	os.Setenv("BROKER_EVENTS", "user.added,user.removed")
+	os.Setenv("TRAFFIC_EXPORT_URL", "http://influxdb:8086/api/v2/write?org=ops&bucket=traffic")
+	os.Setenv("TRAFFIC_EXPORT_FORMAT", "influx")
+	os.Setenv("TRAFFIC_EXPORT_TABLE", "node_traffic")
+	os.Setenv("TRAFFIC_EXPORT_TOKEN", "influx-token")
+	os.Setenv("TRAFFIC_EXPORT_INTERVAL", "60")
+	os.Setenv("TELEGRAM_BOT_TOKEN", "123456:bot-token")
+	os.Setenv("TELEGRAM_CHAT_ID", "-1001234567890")
+	os.Setenv("TELEGRAM_RATE_LIMIT", "120")
+	os.Setenv("OTLP_ENDPOINT", "http://otel-collector:4318")
+	os.Setenv("DISK_MIN_FREE_PERCENT", "10")
+	os.Setenv("LIMIT_WARN_PERCENT", "90")
+	os.Setenv("OUTBOUND_PROBE_TAGS", "direct,warp")
+	os.Setenv("OUTBOUND_PROBE_TARGET", "tls://1.1.1.1:443")
+	os.Setenv("OUTBOUND_PROBE_INTERVAL", "30")
+	os.Setenv("OUTBOUND_PROBE_TIMEOUT", "5")
+	os.Setenv("MEMORY_RESTART_THRESHOLD", "1024")
+	os.Setenv("GO_MEMORY_LIMIT", "90%")
+	os.Setenv("GOMAXPROCS_AUTO", "false")
+	os.Setenv("BULK_WORKERS", "16")
+	os.Setenv("ERROR_RESPONSE_VERSION", "2")
+	os.Setenv("CONSISTENCY_CHECK_INTERVAL", "300")
+	os.Setenv("CONSISTENCY_AUTO_REPAIR", "true")
+	os.Setenv("JWT_PUBLIC_KEYS", "-----BEGIN PUBLIC KEY-----")
+	os.Setenv("PANEL_CA_CERTS", "-----BEGIN CERTIFICATE-----")
+	os.Setenv("JWKS_URL", "https://panel.example.com/.well-known/jwks.json")
+	os.Setenv("JWKS_REFRESH_INTERVAL", "120")
+	os.Setenv("JWT_ISSUER", "remnawave-panel")
+	os.Setenv("JWT_AUDIENCE", "node-1")
+	os.Setenv("JWT_LEEWAY", "30")
+	os.Setenv("JWT_REPLAY_PROTECTION", "true")
+	os.Setenv("JWT_SCOPES", "monitor=/node/stats/")
+	os.Setenv("CRL_FILE", "/etc/remnawave-node/panel.crl")
+	os.Setenv("CRL_URL", "https://panel.example.com/crl.pem")
+	os.Setenv("CRL_REFRESH_INTERVAL", "600")
+	os.Setenv("AUTO_BLOCK", "true")
+	os.Setenv("AUTO_BLOCK_MAX_FAILURES", "5")
+	os.Setenv("AUTO_BLOCK_WINDOW", "30")
+	os.Setenv("AUTO_BLOCK_BAN_DURATION", "86400")
+	os.Setenv("AUTO_BLOCK_IPV6_PREFIX", "56")
+	os.Setenv("CLUSTER_SECRET", "cluster-secret-value")
+	os.Setenv("STATE_REDIS_URL", "redis://:secret@10.0.0.5:6379/1")
+	os.Setenv("STATE_REDIS_PREFIX", "edge-1:")
+	os.Setenv("CLUSTER_PEERS", "http://10.0.0.2:2224/cluster/blocks")
+	os.Setenv("CLUSTER_PORT", "2224")
+	os.Setenv("AUTO_BLOCK_WHITELIST", "10.0.0.0/8,203.0.113.5")
+	os.Setenv("FIREWALL_BACKEND", "nftables")
+	os.Setenv("CONFIG_VARS", "NODE_PUBLIC_IP=203.0.113.7")
+	os.Setenv("CONFIG_ENV_ALLOWLIST", "WARP_*")
+	os.Setenv("ASSET_PATH", "/var/lib/remnawave-node/assets")
+	os.Setenv("GEODATA_AUTO_UPDATE", "true")
+	os.Setenv("GEODATA_DIR", "/var/lib/remnawave-node/geodata")
+	os.Setenv("GEOIP_URL", "https://mirror.example.com/geoip.dat")
+	os.Setenv("GEOSITE_URL", "https://mirror.example.com/geosite.dat")
+	os.Setenv("GEODATA_UPDATE_INTERVAL", "0")
+	os.Setenv("GEODATA_EMBEDDED", "false")
+	os.Setenv("XRAY_MODE", "external")
+	os.Setenv("XRAY_BINARY", "/opt/xray/xray")
+	os.Setenv("XRAY_VERSION", "25.1")
+	os.Setenv("GRACEFUL_UPGRADE", "true")
+	os.Setenv("PID_FILE", "/run/remnanode.pid")
+	os.Setenv("MAINTENANCE_WINDOW", "0 3 * * *")
+	os.Setenv("MAINTENANCE_WINDOW_DURATION", "7200")
 	os.Unsetenv("CONFIG_PATH")
 	defer func() {
 		os.Unsetenv("SECRET_KEY")
 		os.Unsetenv("NODE_PORT")
 		os.Unsetenv("INTERNAL_REST_PORT")
+		os.Unsetenv("API_PORT")
+		os.Unsetenv("INTERNAL_SOCKET_PATH")
+		os.Unsetenv("INTERNAL_SOCKET_MODE")
 		os.Unsetenv("LOG_LEVEL")
+		os.Unsetenv("ACCESS_LOG")
+		os.Unsetenv("ACCESS_LOG_SKIP_STATS")
+		os.Unsetenv("LOG_BUFFER_SIZE")
+		os.Unsetenv("NODE_ID")
+		os.Unsetenv("NODE_LABELS")
+		os.Unsetenv("STATE_DIR")
+		os.Unsetenv("STATE_ENCRYPTION_KEY")
+		os.Unsetenv("SECRET_KEY_VAULT_FIELD")
+		os.Unsetenv("VAULT_NAMESPACE")
+		os.Unsetenv("VAULT_ROLE_ID")
+		os.Unsetenv("SECRET_KEY_REFRESH_INTERVAL")
+		os.Unsetenv("GRPC_PORT")
+		os.Unsetenv("STATS_HISTORY_SIZE")
+		os.Unsetenv("EVENT_HISTORY_SIZE")
+		os.Unsetenv("EVENT_HISTORY_PERSIST")
+		os.Unsetenv("MAX_BODY_SIZE")
+		os.Unsetenv("MAX_DECOMPRESSED_SIZE")
+		os.Unsetenv("HTTP_READ_HEADER_TIMEOUT")
+		os.Unsetenv("HTTP_READ_TIMEOUT")
+		os.Unsetenv("HTTP_WRITE_TIMEOUT")
+		os.Unsetenv("HTTP_IDLE_TIMEOUT")
+		os.Unsetenv("SHUTDOWN_TIMEOUT")
+		os.Unsetenv("PANEL_ALLOWED_IPS")
+		os.Unsetenv("TRUSTED_PROXIES")
+		os.Unsetenv("PROXY_PROTOCOL")
+		os.Unsetenv("STATS_PUSH_URL")
+		os.Unsetenv("STATS_PUSH_INTERVAL")
+		os.Unsetenv("HEARTBEAT_URL")
+		os.Unsetenv("HEARTBEAT_INTERVAL")
+		os.Unsetenv("WEBHOOK_URL")
+		os.Unsetenv("WEBHOOK_SECRET")
+		os.Unsetenv("WEBHOOK_EVENTS")
+		os.Unsetenv("BROKER_URL")
+		os.Unsetenv("BROKER_PREFIX")
+		os.Unsetenv("BROKER_EVENTS")
+		os.Unsetenv("TRAFFIC_EXPORT_URL")
+		os.Unsetenv("TRAFFIC_EXPORT_FORMAT")
+		os.Unsetenv("TRAFFIC_EXPORT_TABLE")
+		os.Unsetenv("TRAFFIC_EXPORT_TOKEN")
+		os.Unsetenv("TRAFFIC_EXPORT_INTERVAL")
+		os.Unsetenv("TELEGRAM_BOT_TOKEN")
+		os.Unsetenv("TELEGRAM_CHAT_ID")
+		os.Unsetenv("TELEGRAM_RATE_LIMIT")
+		os.Unsetenv("OTLP_ENDPOINT")
+		os.Unsetenv("DISK_MIN_FREE_PERCENT")
+		os.Unsetenv("LIMIT_WARN_PERCENT")
+		os.Unsetenv("OUTBOUND_PROBE_TAGS")
+		os.Unsetenv("OUTBOUND_PROBE_TARGET")
+		os.Unsetenv("OUTBOUND_PROBE_INTERVAL")
+		os.Unsetenv("OUTBOUND_PROBE_TIMEOUT")
+		os.Unsetenv("MEMORY_RESTART_THRESHOLD")
+		os.Unsetenv("GO_MEMORY_LIMIT")
+		os.Unsetenv("GOMAXPROCS_AUTO")
+		os.Unsetenv("BULK_WORKERS")
+		os.Unsetenv("ERROR_RESPONSE_VERSION")
+		os.Unsetenv("CONSISTENCY_CHECK_INTERVAL")
+		os.Unsetenv("CONSISTENCY_AUTO_REPAIR")
+		os.Unsetenv("JWT_PUBLIC_KEYS")
+		os.Unsetenv("PANEL_CA_CERTS")
+		os.Unsetenv("JWKS_URL")
+		os.Unsetenv("JWKS_REFRESH_INTERVAL")
+		os.Unsetenv("JWT_ISSUER")
+		os.Unsetenv("JWT_AUDIENCE")
+		os.Unsetenv("JWT_LEEWAY")
+		os.Unsetenv("JWT_REPLAY_PROTECTION")
+		os.Unsetenv("JWT_SCOPES")
+		os.Unsetenv("CRL_FILE")
+		os.Unsetenv("CRL_URL")
+		os.Unsetenv("CRL_REFRESH_INTERVAL")
+		os.Unsetenv("AUTO_BLOCK")
+		os.Unsetenv("AUTO_BLOCK_MAX_FAILURES")
+		os.Unsetenv("AUTO_BLOCK_WINDOW")
+		os.Unsetenv("AUTO_BLOCK_BAN_DURATION")
+		os.Unsetenv("AUTO_BLOCK_IPV6_PREFIX")
+		os.Unsetenv("CLUSTER_SECRET")
+		os.Unsetenv("STATE_REDIS_URL")
+		os.Unsetenv("STATE_REDIS_PREFIX")
+		os.Unsetenv("CLUSTER_PEERS")
+		os.Unsetenv("CLUSTER_PORT")
+		os.Unsetenv("AUTO_BLOCK_WHITELIST")
+		os.Unsetenv("FIREWALL_BACKEND")
+		os.Unsetenv("CONFIG_VARS")
+		os.Unsetenv("CONFIG_ENV_ALLOWLIST")
+		os.Unsetenv("ASSET_PATH")
+		os.Unsetenv("GEODATA_AUTO_UPDATE")
+		os.Unsetenv("GEODATA_DIR")
+		os.Unsetenv("GEOIP_URL")
+		os.Unsetenv("GEOSITE_URL")
+		os.Unsetenv("GEODATA_UPDATE_INTERVAL")
+		os.Unsetenv("GEODATA_EMBEDDED")
+		os.Unsetenv("XRAY_MODE")
+		os.Unsetenv("XRAY_BINARY")
+		os.Unsetenv("XRAY_VERSION")
+		os.Unsetenv("GRACEFUL_UPGRADE")
+		os.Unsetenv("PID_FILE")
+		os.Unsetenv("MAINTENANCE_WINDOW")
+		os.Unsetenv("MAINTENANCE_WINDOW_DURATION")
 	}()
 
 	cfg, err := Load()
@@ -57,7 +350,107 @@ func TestLoad_EnvOverridesDefaults(t *testing.T) {
 
 	assert.Equal(t, 3333, cfg.NodePort)
 	assert.Equal(t, 62000, cfg.InternalRestPort)
+	assert.Equal(t, 62012, cfg.APIPort)
+	assert.Equal(t, "/run/remnawave-node/internal.sock", cfg.InternalSocketPath)
+	assert.Equal(t, "0660", cfg.InternalSocketMode)
 	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "errors", cfg.AccessLog)
+	assert.False(t, cfg.AccessLogSkipStats)
+	assert.Equal(t, 0, cfg.LogBufferSize)
+	assert.Equal(t, "node-eu-1", cfg.NodeID)
+	assert.Equal(t, "region=eu", cfg.NodeLabels)
+	assert.Equal(t, "/tmp/node-state", cfg.StateDir)
+	assert.Equal(t, "c3RhdGUta2V5", cfg.StateEncryptionKey)
+	assert.Equal(t, "nodeSecretKey", cfg.SecretKeyVaultField)
+	assert.Equal(t, "admin/nodes", cfg.VaultNamespace)
+	assert.Equal(t, "role-id", cfg.VaultRoleID)
+	assert.Equal(t, 0, cfg.SecretKeyRefreshInterval)
+	assert.Equal(t, 2223, cfg.GRPCPort)
+	assert.Equal(t, 60, cfg.StatsHistorySize)
+	assert.Equal(t, 200, cfg.EventHistorySize)
+	assert.True(t, cfg.EventHistoryPersist)
+	assert.Equal(t, 1048576, cfg.MaxBodySize)
+	assert.Equal(t, 8388608, cfg.MaxDecompressedSize)
+	assert.Equal(t, 5, cfg.HTTPReadHeaderTimeout)
+	assert.Equal(t, 30, cfg.HTTPReadTimeout)
+	assert.Equal(t, 0, cfg.HTTPWriteTimeout)
+	assert.Equal(t, 90, cfg.HTTPIdleTimeout)
+	assert.Equal(t, 30, cfg.ShutdownTimeout)
+	assert.Equal(t, "203.0.113.10,2001:db8::/32", cfg.PanelAllowedIPs)
+	assert.Equal(t, "10.0.0.0/8", cfg.TrustedProxies)
+	assert.True(t, cfg.ProxyProtocol)
+	assert.Equal(t, "https://panel.example.com/api/nodes/stats", cfg.StatsPushURL)
+	assert.Equal(t, 30, cfg.StatsPushInterval)
+	assert.Equal(t, "https://panel.example.com/api/nodes/heartbeat", cfg.HeartbeatURL)
+	assert.Equal(t, 15, cfg.HeartbeatInterval)
+	assert.Equal(t, "https://hooks.example.com/node", cfg.WebhookURL)
+	assert.Equal(t, "nats://token@nats.example.com:4222", cfg.BrokerURL)
+	assert.Equal(t, "panel.nodes", cfg.BrokerPrefix)
+	assert.Equal(t, "user.added,user.removed", cfg.BrokerEvents)
+	assert.Equal(t, "hook-secret", cfg.WebhookSecret)
+	assert.Equal(t, "xray.crashed,certificate.expiring", cfg.WebhookEvents)
+	assert.Equal(t, "http://influxdb:8086/api/v2/write?org=ops&bucket=traffic", cfg.TrafficExportURL)
+	assert.Equal(t, "influx", cfg.TrafficExportFormat)
+	assert.Equal(t, "node_traffic", cfg.TrafficExportTable)
+	assert.Equal(t, "influx-token", cfg.TrafficExportToken)
+	assert.Equal(t, 60, cfg.TrafficExportInterval)
+	assert.Equal(t, "123456:bot-token", cfg.TelegramBotToken)
+	assert.Equal(t, "-1001234567890", cfg.TelegramChatID)
+	assert.Equal(t, 120, cfg.TelegramRateLimit)
+	assert.Equal(t, "http://otel-collector:4318", cfg.OTLPEndpoint)
+	assert.Equal(t, 10, cfg.DiskMinFreePercent)
+	assert.Equal(t, 90, cfg.LimitWarnPercent)
+	assert.Equal(t, "direct,warp", cfg.OutboundProbeTags)
+	assert.Equal(t, "tls://1.1.1.1:443", cfg.OutboundProbeTarget)
+	assert.Equal(t, 30, cfg.OutboundProbeInterval)
+	assert.Equal(t, 5, cfg.OutboundProbeTimeout)
+	assert.Equal(t, 1024, cfg.MemoryRestartThreshold)
+	assert.Equal(t, "90%", cfg.GoMemoryLimit)
+	assert.False(t, cfg.GoMaxProcsAuto)
+	assert.Equal(t, 16, cfg.BulkWorkers)
+	assert.Equal(t, ErrorResponseCoded, cfg.ErrorResponseVersion)
+	assert.Equal(t, 300, cfg.ConsistencyCheckInterval)
+	assert.True(t, cfg.ConsistencyAutoRepair)
+	assert.Equal(t, "-----BEGIN PUBLIC KEY-----", cfg.JWTPublicKeys)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", cfg.PanelCACerts)
+	assert.Equal(t, "https://panel.example.com/.well-known/jwks.json", cfg.JWKSURL)
+	assert.Equal(t, 120, cfg.JWKSRefreshInterval)
+	assert.Equal(t, "remnawave-panel", cfg.JWTIssuer)
+	assert.Equal(t, "node-1", cfg.JWTAudience)
+	assert.Equal(t, 30, cfg.JWTLeeway)
+	assert.True(t, cfg.JWTReplayProtection)
+	assert.Equal(t, "monitor=/node/stats/", cfg.JWTScopes)
+	assert.Equal(t, "/etc/remnawave-node/panel.crl", cfg.CRLFile)
+	assert.Equal(t, "https://panel.example.com/crl.pem", cfg.CRLURL)
+	assert.Equal(t, 600, cfg.CRLRefreshInterval)
+	assert.True(t, cfg.AutoBlock)
+	assert.Equal(t, 5, cfg.AutoBlockMaxFailures)
+	assert.Equal(t, 30, cfg.AutoBlockWindow)
+	assert.Equal(t, 86400, cfg.AutoBlockBanDuration)
+	assert.Equal(t, 56, cfg.AutoBlockIPv6Prefix)
+	assert.Equal(t, "cluster-secret-value", cfg.ClusterSecret)
+	assert.Equal(t, "redis://:secret@10.0.0.5:6379/1", cfg.StateRedisURL)
+	assert.Equal(t, "edge-1:", cfg.StateRedisPrefix)
+	assert.Equal(t, "http://10.0.0.2:2224/cluster/blocks", cfg.ClusterPeers)
+	assert.Equal(t, 2224, cfg.ClusterPort)
+	assert.Equal(t, "10.0.0.0/8,203.0.113.5", cfg.AutoBlockWhitelist)
+	assert.Equal(t, "nftables", cfg.FirewallBackend)
+	assert.Equal(t, "NODE_PUBLIC_IP=203.0.113.7", cfg.ConfigVars)
+	assert.Equal(t, "WARP_*", cfg.ConfigEnvAllowlist)
+	assert.Equal(t, "/var/lib/remnawave-node/assets", cfg.AssetPath)
+	assert.True(t, cfg.GeodataAutoUpdate)
+	assert.Equal(t, "/var/lib/remnawave-node/geodata", cfg.GeodataDir)
+	assert.Equal(t, "https://mirror.example.com/geoip.dat", cfg.GeoIPURL)
+	assert.Equal(t, "https://mirror.example.com/geosite.dat", cfg.GeoSiteURL)
+	assert.Equal(t, 0, cfg.GeodataUpdateInterval)
+	assert.False(t, cfg.GeodataEmbedded)
+	assert.Equal(t, "external", cfg.XrayMode)
+	assert.Equal(t, "/opt/xray/xray", cfg.XrayBinary)
+	assert.Equal(t, "25.1", cfg.XrayVersion)
+	assert.True(t, cfg.GracefulUpgrade)
+	assert.Equal(t, "/run/remnanode.pid", cfg.PIDFile)
+	assert.Equal(t, "0 3 * * *", cfg.MaintenanceWindow)
+	assert.Equal(t, 7200, cfg.MaintenanceWindowDuration)
 }
 
 func TestLoad_MissingSecretKey(t *testing.T) {
@@ -68,6 +461,17 @@ func TestLoad_MissingSecretKey(t *testing.T) {
 	assert.ErrorIs(t, err, ErrConfigSecretKeyRequired)
 }
 
+func TestLoadSettings_WithoutSecretKey(t *testing.T) {
+	os.Unsetenv("SECRET_KEY")
+	os.Unsetenv("CONFIG_PATH")
+	t.Setenv("API_PORT", "62000")
+
+	cfg, err := LoadSettings()
+	require.NoError(t, err)
+	assert.Equal(t, 62000, cfg.APIPort)
+	assert.Nil(t, cfg.Payload)
+}
+
 func TestLoad_InvalidSecretKey(t *testing.T) {
 	os.Setenv("SECRET_KEY", "invalid-base64!!!")
 	os.Unsetenv("CONFIG_PATH")
@@ -77,6 +481,119 @@ func TestLoad_InvalidSecretKey(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLoad_SecretKeyFile(t *testing.T) {
+	secretPath := filepath.Join(t.TempDir(), "secret_key")
+	require.NoError(t, os.WriteFile(secretPath, []byte(makeTestSecretKey()+"\n"), 0600))
+
+	os.Unsetenv("SECRET_KEY")
+	os.Unsetenv("CONFIG_PATH")
+	os.Setenv("SECRET_KEY_FILE", secretPath)
+	defer os.Unsetenv("SECRET_KEY_FILE")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, makeTestSecretKey(), cfg.SecretKey)
+	assert.NotNil(t, cfg.Payload)
+}
+
+func TestLoad_SecretKeyFileFromConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretPath := filepath.Join(tmpDir, "secret_key")
+	require.NoError(t, os.WriteFile(secretPath, []byte(makeTestSecretKey()), 0600))
+
+	configPath := filepath.Join(tmpDir, "config.json")
+	data, _ := json.Marshal(map[string]interface{}{"secretKeyFile": secretPath})
+	require.NoError(t, os.WriteFile(configPath, data, 0644))
+
+	os.Unsetenv("SECRET_KEY")
+	os.Setenv("CONFIG_PATH", configPath)
+	defer os.Unsetenv("CONFIG_PATH")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	assert.Equal(t, secretPath, cfg.SecretKeyFile)
+	assert.NotNil(t, cfg.Payload)
+}
+
+func TestLoad_StateEncryptionKeyFile(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "state_key")
+	require.NoError(t, os.WriteFile(keyPath, []byte("c3RhdGUta2V5\n"), 0600))
+
+	os.Setenv("SECRET_KEY", makeTestSecretKey())
+	os.Unsetenv("CONFIG_PATH")
+	os.Setenv("STATE_ENCRYPTION_KEY_FILE", keyPath)
+	defer os.Unsetenv("SECRET_KEY")
+	defer os.Unsetenv("STATE_ENCRYPTION_KEY_FILE")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "c3RhdGUta2V5", cfg.StateEncryptionKey)
+
+	os.Setenv("STATE_ENCRYPTION_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_SecretKeyFromVault(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/node-1" || r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]string{"secretKey": makeTestSecretKey()},
+				"metadata": map[string]int{"version": 3},
+			},
+		})
+	}))
+	defer vault.Close()
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("s.token\n"), 0600))
+
+	os.Unsetenv("SECRET_KEY")
+	os.Unsetenv("CONFIG_PATH")
+	os.Setenv("SECRET_KEY_VAULT_PATH", "secret/data/node-1")
+	os.Setenv("VAULT_ADDR", vault.URL)
+	os.Setenv("VAULT_TOKEN_FILE", tokenPath)
+	defer func() {
+		os.Unsetenv("SECRET_KEY_VAULT_PATH")
+		os.Unsetenv("VAULT_ADDR")
+		os.Unsetenv("VAULT_TOKEN_FILE")
+		os.Unsetenv("SECRET_KEY_GCP_SECRET")
+	}()
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, makeTestSecretKey(), cfg.SecretKey)
+	assert.NotNil(t, cfg.Payload)
+
+	// Only the configuration is needed by LoadSettings.
+	cfg, err = LoadSettings()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.SecretKey)
+
+	os.Setenv("SECRET_KEY_VAULT_PATH", "secret/data/node-2")
+	_, err = Load()
+	assert.ErrorContains(t, err, "failed to fetch secret key: vault: GET secret/data/node-2: status 403")
+
+	os.Setenv("SECRET_KEY_GCP_SECRET", "projects/p/secrets/node-1")
+	_, err = Load()
+	assert.EqualError(t, err, "SECRET_KEY_VAULT_PATH and SECRET_KEY_GCP_SECRET cannot both be set")
+}
+
+func TestLoad_SecretKeyFileMissing(t *testing.T) {
+	os.Unsetenv("SECRET_KEY")
+	os.Unsetenv("CONFIG_PATH")
+	os.Setenv("SECRET_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	defer os.Unsetenv("SECRET_KEY_FILE")
+
+	_, err := Load()
+	assert.Error(t, err)
+}
+
 func TestLoad_FromConfigFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.json")
@@ -148,3 +665,20 @@ func TestLoad_InvalidPortIgnored(t *testing.T) {
 
 	assert.Equal(t, DefaultNodePort, cfg.NodePort)
 }
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels(" region = eu ,tier=premium,, note=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "eu", "tier": "premium", "note": ""}, labels)
+
+	labels, err = ParseLabels("")
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	_, err = ParseLabels("region")
+	assert.EqualError(t, err, `invalid label "region", expected key=value`)
+	_, err = ParseLabels("=eu")
+	assert.Error(t, err)
+	_, err = ParseLabels("region=eu,region=us")
+	assert.EqualError(t, err, `duplicate label "region"`)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyPair parses the node certificate chain and private key, like
+// tls.X509KeyPair, and wipes the decoded key material it allocated once
+// the key is parsed. The parsed key itself stays in the certificate.
+func (p *NodePayload) KeyPair() (tls.Certificate, error) {
+	var cert tls.Certificate
+	rest := []byte(p.NodeCertPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return tls.Certificate{}, errors.New("no certificate found in nodeCertPem")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid node certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	keyPEM := []byte(p.NodeKeyPEM)
+	defer clear(keyPEM)
+	var block *pem.Block
+	for rest := keyPEM; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return tls.Certificate{}, errors.New("no private key found in nodeKeyPem")
+		}
+		defer clear(block.Bytes)
+		// Blocks such as EC PARAMETERS may come first.
+		if block.Type == "PRIVATE KEY" || strings.HasSuffix(block.Type, " PRIVATE KEY") {
+			break
+		}
+	}
+
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(leaf.PublicKey) {
+		return tls.Certificate{}, errors.New("private key does not match public key")
+	}
+	cert.PrivateKey = key
+	return cert, nil
+}
+
+// parsePrivateKey parses a PKCS #8, SEC 1 or PKCS #1 private key, the
+// forms tls.X509KeyPair accepts.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("failed to parse private key")
+}
+
+// ForgetSecrets drops the SECRET_KEY, the node private key, the state
+// encryption key, the Redis and broker URLs with their credentials, the
+// Vault credentials and the cluster secret from the configuration once
+// everything built from them exists, so the process does not keep them for its whole life. Go
+// strings cannot be wiped; dropping the references lets the garbage
+// collector reclaim them. The payload is replaced, not modified, since
+// others may share it.
+func (c *Config) ForgetSecrets() {
+	c.SecretKey = ""
+	c.StateEncryptionKey = ""
+	c.StateRedisURL = ""
+	c.VaultToken = ""
+	c.VaultSecretID = ""
+	c.ClusterSecret = ""
+	c.BrokerURL = ""
+	if c.Payload != nil {
+		payload := *c.Payload
+		payload.NodeKeyPEM = ""
+		c.Payload = &payload
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodePayload_KeyPair(t *testing.T) {
+	payload := newTestPayload(t, newTestCA(t, time.Now().Add(-time.Hour)))
+
+	cert, err := payload.KeyPair()
+	require.NoError(t, err)
+	require.Len(t, cert.Certificate, 1)
+	assert.Equal(t, "node", cert.Leaf.Subject.CommonName)
+	assert.NotNil(t, cert.PrivateKey)
+
+	// As written by openssl ecparam -genkey.
+	withParams := *payload
+	withParams.NodeKeyPEM = "-----BEGIN EC PARAMETERS-----\nBggqhkjOPQMBBw==\n-----END EC PARAMETERS-----\n" + payload.NodeKeyPEM
+	_, err = withParams.KeyPair()
+	assert.NoError(t, err)
+
+	mismatched := *payload
+	mismatched.NodeKeyPEM = newTestPayload(t, newTestCA(t, time.Now())).NodeKeyPEM
+	_, err = mismatched.KeyPair()
+	assert.EqualError(t, err, "private key does not match public key")
+
+	noKey := *payload
+	noKey.NodeKeyPEM = "node-key"
+	_, err = noKey.KeyPair()
+	assert.Error(t, err)
+}
+
+func TestConfig_ForgetSecrets(t *testing.T) {
+	payload := newTestPayload(t, newTestCA(t, time.Now().Add(-time.Hour)))
+	cfg := &Config{SecretKey: "secret", StateEncryptionKey: "state-key", StateRedisURL: "redis://:secret@localhost", BrokerURL: "nats://token@localhost", Payload: payload}
+
+	cfg.ForgetSecrets()
+	assert.Empty(t, cfg.SecretKey)
+	assert.Empty(t, cfg.StateEncryptionKey)
+	assert.Empty(t, cfg.StateRedisURL)
+	assert.Empty(t, cfg.BrokerURL)
+	assert.Empty(t, cfg.Payload.NodeKeyPEM)
+	assert.Equal(t, payload.NodeCertPEM, cfg.Payload.NodeCertPEM)
+	assert.NotEmpty(t, payload.NodeKeyPEM, "the shared payload is left alone")
+}
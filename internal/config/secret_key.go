@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/remnawave/node-go/internal/secretsource"
 )
 
 var (
@@ -19,6 +22,11 @@ type NodePayload struct {
 	JWTPublicKey string `json:"jwtPublicKey"`
 	NodeCertPEM  string `json:"nodeCertPem"`
 	NodeKeyPEM   string `json:"nodeKeyPem"`
+	// TrustedCACertPEMs and TrustedJWTPublicKeys are further panel CAs and
+	// JWT keys the node accepts, so a primary and a standby panel, or the
+	// panels a node is migrated between, can manage it at the same time.
+	TrustedCACertPEMs    []string `json:"trustedCaCertPems,omitempty"`
+	TrustedJWTPublicKeys []string `json:"trustedJwtPublicKeys,omitempty"`
 }
 
 func ParseSecretKey(base64Str string) (*NodePayload, error) {
@@ -43,6 +51,18 @@ func ParseSecretKey(base64Str string) (*NodePayload, error) {
 	return &payload, nil
 }
 
+// EncodeSecretKey returns the SECRET_KEY value carrying payload.
+func EncodeSecretKey(payload *NodePayload) (string, error) {
+	if err := validateNodePayload(payload); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
 func validateNodePayload(p *NodePayload) error {
 	if p.CACertPEM == "" {
 		return fmt.Errorf("%w: caCertPem", ErrSecretKeyMissingField)
@@ -58,3 +78,55 @@ func validateNodePayload(p *NodePayload) error {
 	}
 	return nil
 }
+
+// PanelCAs returns the PEM CAs accepted for panel client certificates: the
+// CA of the SECRET_KEY, the trusted CAs of its payload and PanelCACerts.
+// An entry may hold several certificates.
+func (c *Config) PanelCAs() []string {
+	cas := append([]string{c.Payload.CACertPEM}, c.Payload.TrustedCACertPEMs...)
+	if c.PanelCACerts != "" {
+		cas = append(cas, c.PanelCACerts)
+	}
+	return cas
+}
+
+// PanelJWTKeys returns the PEM keys accepted for panel JWTs: the key of the
+// SECRET_KEY, the trusted keys of its payload and JWTPublicKeys.
+func (c *Config) PanelJWTKeys() []string {
+	keys := append([]string{c.Payload.JWTPublicKey}, c.Payload.TrustedJWTPublicKeys...)
+	if c.JWTPublicKeys != "" {
+		keys = append(keys, c.JWTPublicKeys)
+	}
+	return keys
+}
+
+// secretKeyFetchTimeout bounds fetching the secret key from a secret
+// manager, including the login.
+const secretKeyFetchTimeout = 30 * time.Second
+
+// SecretKeySource returns the secret manager the secret key is fetched
+// from, or nil when it is set directly.
+func (c *Config) SecretKeySource() (secretsource.Source, error) {
+	switch {
+	case c.SecretKeyVaultPath != "" && c.SecretKeyGCPSecret != "":
+		return nil, errors.New("SECRET_KEY_VAULT_PATH and SECRET_KEY_GCP_SECRET cannot both be set")
+	case c.SecretKeyVaultPath != "":
+		if c.VaultAddr == "" {
+			return nil, errors.New("VAULT_ADDR is required with SECRET_KEY_VAULT_PATH")
+		}
+		return secretsource.NewVault(secretsource.VaultOptions{
+			Addr:      c.VaultAddr,
+			Namespace: c.VaultNamespace,
+			Token:     c.VaultToken,
+			TokenFile: c.VaultTokenFile,
+			RoleID:    c.VaultRoleID,
+			SecretID:  c.VaultSecretID,
+			Path:      c.SecretKeyVaultPath,
+			Field:     c.SecretKeyVaultField,
+		}), nil
+	case c.SecretKeyGCPSecret != "":
+		return secretsource.NewGCPSecretManager(c.SecretKeyGCPSecret), nil
+	default:
+		return nil, nil
+	}
+}
@@ -104,3 +104,17 @@ func TestParseSecretKey_MissingField_NodeKeyPem(t *testing.T) {
 	assert.True(t, errors.Is(err, ErrSecretKeyMissingField))
 	assert.Contains(t, err.Error(), "nodeKeyPem")
 }
+
+func TestEncodeSecretKey_RoundTrip(t *testing.T) {
+	payload, err := ParseSecretKey(makeValidSecretKey())
+	require.NoError(t, err)
+
+	encoded, err := EncodeSecretKey(payload)
+	require.NoError(t, err)
+	decoded, err := ParseSecretKey(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+
+	_, err = EncodeSecretKey(&NodePayload{CACertPEM: "ca"})
+	assert.True(t, errors.Is(err, ErrSecretKeyMissingField))
+}
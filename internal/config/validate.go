@@ -0,0 +1,256 @@
+package config
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/remnawave/node-go/internal/outboundmon"
+	"github.com/remnawave/node-go/internal/state"
+)
+
+// minClusterSecretLength is the shortest CLUSTER_SECRET accepted.
+const minClusterSecretLength = 16
+
+// ValidationError lists every problem Validate found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration before anything is started: the ports
+// are in range and distinct, the state encryption key and the node labels
+// are well-formed, and the SECRET_KEY materials parse, the node key matches
+// the node certificate and the CA is valid and signed it, and the panel CAs
+// and JWT keys trusted besides them are valid too. A configuration loaded
+// with LoadSettings is checked without the SECRET_KEY. The returned error
+// is a *ValidationError.
+func (c *Config) Validate() error {
+	return c.validateAt(time.Now())
+}
+
+func (c *Config) validateAt(now time.Time) error {
+	var problems []string
+	problems = append(problems, c.portProblems()...)
+	if c.Payload != nil {
+		problems = append(problems, payloadProblems(c.Payload, now)...)
+	}
+	problems = append(problems, c.trustProblems(now)...)
+	switch {
+	case c.ClusterSecret == "" && (c.ClusterPeers != "" || c.ClusterPort != 0):
+		problems = append(problems, "CLUSTER_PEERS and CLUSTER_PORT require CLUSTER_SECRET")
+	case c.ClusterSecret != "" && len(c.ClusterSecret) < minClusterSecretLength:
+		problems = append(problems, fmt.Sprintf("CLUSTER_SECRET is shorter than %d characters; generate one with: openssl rand -hex 32", minClusterSecretLength))
+	}
+	if c.ProxyProtocol && c.TrustedProxies == "" {
+		problems = append(problems, "PROXY_PROTOCOL requires TRUSTED_PROXIES, the load balancers allowed to send the header")
+	}
+	if _, err := ParseLabels(c.NodeLabels); err != nil {
+		problems = append(problems, "NODE_LABELS: "+err.Error())
+	}
+	if c.StateEncryptionKey != "" {
+		if _, err := state.ParseKey(c.StateEncryptionKey); err != nil {
+			problems = append(problems, "STATE_ENCRYPTION_KEY: "+err.Error()+"; generate one with: openssl rand -base64 32")
+		}
+	}
+	if c.OutboundProbeTags != "" {
+		if _, err := outboundmon.ParseTarget(c.OutboundProbeTarget); err != nil {
+			problems = append(problems, "OUTBOUND_PROBE_TARGET: "+err.Error())
+		}
+	}
+	if c.StateRedisURL != "" {
+		if _, err := state.ParseRedisURL(c.StateRedisURL); err != nil {
+			problems = append(problems, "STATE_REDIS_URL: "+err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+type portSetting struct {
+	name string
+	port int
+}
+
+func (c *Config) portProblems() []string {
+	ports := []portSetting{
+		{"NODE_PORT", c.NodePort},
+		{"API_PORT", c.APIPort},
+	}
+	// The internal API listens on a unix socket instead when one is set.
+	if c.InternalSocketPath == "" {
+		ports = append(ports, portSetting{"INTERNAL_REST_PORT", c.InternalRestPort})
+	}
+	if c.GRPCPort != 0 {
+		ports = append(ports, portSetting{"GRPC_PORT", c.GRPCPort})
+	}
+	if c.ClusterPort != 0 {
+		ports = append(ports, portSetting{"CLUSTER_PORT", c.ClusterPort})
+	}
+
+	var problems []string
+	used := make(map[int]string, len(ports))
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s %d is not a port between 1 and 65535", p.name, p.port))
+			continue
+		}
+		if other, ok := used[p.port]; ok {
+			problems = append(problems, fmt.Sprintf("%s and %s are both %d; every listener needs its own port", other, p.name, p.port))
+			continue
+		}
+		used[p.port] = p.name
+	}
+	return problems
+}
+
+// payloadProblems checks the SECRET_KEY materials the TLS listeners and the
+// JWT validation are built from, so a broken key fails at startup rather
+// than at the first panel handshake.
+func payloadProblems(p *NodePayload, now time.Time) []string {
+	const reissue = "; copy the SECRET_KEY of this node from the panel again"
+
+	var problems []string
+	ca, err := parseCertificatePEM(p.CACertPEM)
+	if err != nil {
+		problems = append(problems, "SECRET_KEY caCertPem: "+err.Error()+reissue)
+	} else if !ca.IsCA {
+		problems = append(problems, "SECRET_KEY caCertPem is not a CA certificate"+reissue)
+	} else if problem := validityProblem(ca, now); problem != "" {
+		problems = append(problems, "SECRET_KEY caCertPem "+problem)
+	}
+
+	cert, err := parseCertificatePEM(p.NodeCertPEM)
+	if err != nil {
+		problems = append(problems, "SECRET_KEY nodeCertPem: "+err.Error()+reissue)
+	} else if problem := validityProblem(cert, now); problem != "" {
+		problems = append(problems, "SECRET_KEY nodeCertPem "+problem)
+	}
+
+	if block, _ := pem.Decode([]byte(p.NodeKeyPEM)); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+		problems = append(problems, "SECRET_KEY nodeKeyPem is not a PEM private key"+reissue)
+	} else if cert != nil {
+		if _, err := p.KeyPair(); err != nil {
+			problems = append(problems, "SECRET_KEY nodeKeyPem does not match nodeCertPem: "+err.Error()+reissue)
+		}
+	}
+
+	if ca != nil && ca.IsCA && cert != nil {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:       roots,
+			CurrentTime: now,
+			KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		var invalid x509.CertificateInvalidError
+		// Expired certificates are reported above.
+		if err != nil && !(errors.As(err, &invalid) && invalid.Reason == x509.Expired) {
+			problems = append(problems, "SECRET_KEY nodeCertPem is not signed by caCertPem: "+err.Error()+reissue)
+		}
+	}
+
+	if err := checkPublicKeyPEM(p.JWTPublicKey); err != nil {
+		problems = append(problems, "SECRET_KEY jwtPublicKey: "+err.Error()+reissue)
+	}
+	return problems
+}
+
+// trustProblems checks the panel CAs and JWT keys trusted besides the ones
+// of the SECRET_KEY.
+func (c *Config) trustProblems(now time.Time) []string {
+	var problems []string
+	if c.Payload != nil {
+		for i, caPEM := range c.Payload.TrustedCACertPEMs {
+			problems = append(problems, caProblems(fmt.Sprintf("SECRET_KEY trustedCaCertPems[%d]", i), caPEM, now)...)
+		}
+		for i, keyPEM := range c.Payload.TrustedJWTPublicKeys {
+			if err := checkPublicKeyPEM(keyPEM); err != nil {
+				problems = append(problems, fmt.Sprintf("SECRET_KEY trustedJwtPublicKeys[%d]: %v", i, err))
+			}
+		}
+	}
+	if c.PanelCACerts != "" {
+		problems = append(problems, caProblems("PANEL_CA_CERTS", c.PanelCACerts, now)...)
+	}
+	return problems
+}
+
+// caProblems checks every certificate in the PEM bundle of the setting
+// name is a CA certificate valid at now.
+func caProblems(name, bundle string, now time.Time) []string {
+	var problems []string
+	found := false
+	for rest := []byte(bundle); ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		found = true
+
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			problems = append(problems, name+": invalid certificate: "+err.Error())
+		} else if !ca.IsCA {
+			problems = append(problems, fmt.Sprintf("%s: %q is not a CA certificate", name, ca.Subject.CommonName))
+		} else if problem := validityProblem(ca, now); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %q %s", name, ca.Subject.CommonName, problem))
+		}
+	}
+	if !found {
+		problems = append(problems, name+": not a PEM certificate")
+	}
+	return problems
+}
+
+func parseCertificatePEM(certPEM string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("not a PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// validityProblem describes why cert is not valid at now, empty when it is.
+func validityProblem(cert *x509.Certificate, now time.Time) string {
+	switch {
+	case now.Before(cert.NotBefore):
+		return fmt.Sprintf("is not valid before %s; check the system clock", cert.NotBefore.UTC().Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		return fmt.Sprintf("expired at %s; renew it in the panel and update SECRET_KEY", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+// checkPublicKeyPEM checks that publicKeyPEM holds a PKIX or PKCS #1 public
+// key, the formats JWT validation accepts.
+func checkPublicKeyPEM(publicKeyPEM string) error {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return fmt.Errorf("not a PEM public key")
+	}
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return nil
+	}
+	if _, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return nil
+	}
+	return fmt.Errorf("invalid public key")
+}
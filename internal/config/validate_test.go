@@ -0,0 +1,281 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testIssuer struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  string
+}
+
+// newTestCA returns a CA valid from notBefore for a day.
+func newTestCA(t *testing.T, notBefore time.Time) *testIssuer {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testIssuer{cert: cert, key: key, pem: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))}
+}
+
+// issue returns a node certificate and key signed by the CA, with its
+// validity.
+func (ca *testIssuer) issue(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    ca.cert.NotBefore,
+		NotAfter:     ca.cert.NotAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func newTestPayload(t *testing.T, ca *testIssuer) *NodePayload {
+	t.Helper()
+
+	nodeCert, nodeKey := ca.issue(t)
+	pubDER, err := x509.MarshalPKIXPublicKey(&ca.key.PublicKey)
+	require.NoError(t, err)
+	return &NodePayload{
+		CACertPEM:    ca.pem,
+		JWTPublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+		NodeCertPEM:  nodeCert,
+		NodeKeyPEM:   nodeKey,
+	}
+}
+
+func newValidConfig(t *testing.T) *Config {
+	t.Helper()
+
+	return &Config{
+		NodePort:         DefaultNodePort,
+		InternalRestPort: DefaultInternalRestPort,
+		APIPort:          DefaultAPIPort,
+		Payload:          newTestPayload(t, newTestCA(t, time.Now().Add(-time.Hour))),
+	}
+}
+
+func validationProblems(t *testing.T, err error) []string {
+	t.Helper()
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "expected a ValidationError, got %v", err)
+	return validationErr.Problems
+}
+
+func TestValidate_Valid(t *testing.T) {
+	cfg := newValidConfig(t)
+	assert.NoError(t, cfg.Validate())
+
+	// Without SECRET_KEY, as loaded by LoadSettings.
+	cfg.Payload = nil
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Ports(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.NodePort = 70000
+	cfg.APIPort = DefaultInternalRestPort
+	cfg.GRPCPort = DefaultInternalRestPort
+
+	problems := validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{
+		"NODE_PORT 70000 is not a port between 1 and 65535",
+		"API_PORT and INTERNAL_REST_PORT are both 61001; every listener needs its own port",
+		"API_PORT and GRPC_PORT are both 61001; every listener needs its own port",
+	}, problems)
+
+	// The internal API does not use its port on a unix socket.
+	cfg.NodePort = DefaultNodePort
+	cfg.GRPCPort = 0
+	cfg.InternalSocketPath = "/run/remnawave-node/internal.sock"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_SecretKeyMaterials(t *testing.T) {
+	cfg := newValidConfig(t)
+	other := newTestPayload(t, newTestCA(t, time.Now().Add(-time.Hour)))
+
+	t.Run("malformed", func(t *testing.T) {
+		cfg := *cfg
+		cfg.Payload = &NodePayload{
+			CACertPEM:    "ca-cert",
+			JWTPublicKey: "jwt-key",
+			NodeCertPEM:  "node-cert",
+			NodeKeyPEM:   "node-key",
+		}
+		problems := validationProblems(t, cfg.Validate())
+		require.Len(t, problems, 4)
+		assert.Contains(t, problems[0], "SECRET_KEY caCertPem: not a PEM certificate")
+		assert.Contains(t, problems[1], "SECRET_KEY nodeCertPem: not a PEM certificate")
+		assert.Contains(t, problems[2], "SECRET_KEY nodeKeyPem is not a PEM private key")
+		assert.Contains(t, problems[3], "SECRET_KEY jwtPublicKey: not a PEM public key")
+		assert.Contains(t, problems[0], "copy the SECRET_KEY of this node from the panel again")
+	})
+
+	t.Run("key mismatch", func(t *testing.T) {
+		payload := *cfg.Payload
+		payload.NodeKeyPEM = other.NodeKeyPEM
+		cfg := *cfg
+		cfg.Payload = &payload
+
+		problems := validationProblems(t, cfg.Validate())
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "SECRET_KEY nodeKeyPem does not match nodeCertPem")
+	})
+
+	t.Run("foreign CA", func(t *testing.T) {
+		payload := *cfg.Payload
+		payload.CACertPEM = other.CACertPEM
+		cfg := *cfg
+		cfg.Payload = &payload
+
+		problems := validationProblems(t, cfg.Validate())
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "SECRET_KEY nodeCertPem is not signed by caCertPem")
+	})
+
+	t.Run("CA not a CA", func(t *testing.T) {
+		payload := *cfg.Payload
+		payload.CACertPEM = payload.NodeCertPEM
+		cfg := *cfg
+		cfg.Payload = &payload
+
+		problems := validationProblems(t, cfg.Validate())
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0], "SECRET_KEY caCertPem is not a CA certificate")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		problems := validationProblems(t, cfg.validateAt(time.Now().Add(48*time.Hour)))
+		require.Len(t, problems, 2)
+		assert.Contains(t, problems[0], "SECRET_KEY caCertPem expired at")
+		assert.Contains(t, problems[1], "SECRET_KEY nodeCertPem expired at")
+	})
+
+	t.Run("not yet valid", func(t *testing.T) {
+		problems := validationProblems(t, cfg.validateAt(time.Now().Add(-48*time.Hour)))
+		require.Len(t, problems, 2)
+		assert.Contains(t, problems[0], "check the system clock")
+	})
+}
+
+func TestValidate_StateEncryptionKey(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.StateEncryptionKey = "QUJDREVGR0hJSktMTU5PUFFSU1RVVldYWVowMTIzNDU="
+	assert.NoError(t, cfg.Validate())
+
+	cfg.StateEncryptionKey = "c2hvcnQ="
+	problems := validationProblems(t, cfg.Validate())
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "STATE_ENCRYPTION_KEY: state encryption key is 5 bytes, expected 32")
+}
+
+func TestValidate_ProxyProtocol(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.ProxyProtocol = true
+	problems := validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{"PROXY_PROTOCOL requires TRUSTED_PROXIES, the load balancers allowed to send the header"}, problems)
+
+	cfg.TrustedProxies = "10.0.0.0/8"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_OutboundProbeTarget(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.OutboundProbeTarget = "udp://1.1.1.1:53"
+	assert.NoError(t, cfg.Validate(), "the target is only checked when probes are enabled")
+
+	cfg.OutboundProbeTags = "direct"
+	problems := validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{`OUTBOUND_PROBE_TARGET: invalid probe target scheme "udp", expected tcp, tls, http or https`}, problems)
+}
+
+func TestValidate_StateRedisURL(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.StateRedisURL = "redis://localhost:6379/0"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.StateRedisURL = "localhost:6379"
+	problems := validationProblems(t, cfg.Validate())
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "STATE_REDIS_URL: invalid redis URL")
+}
+
+func TestValidate_Cluster(t *testing.T) {
+	cfg := newValidConfig(t)
+	cfg.ClusterPort = 2224
+	problems := validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{"CLUSTER_PEERS and CLUSTER_PORT require CLUSTER_SECRET"}, problems)
+
+	cfg.ClusterSecret = "short"
+	problems = validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{"CLUSTER_SECRET is shorter than 16 characters; generate one with: openssl rand -hex 32"}, problems)
+
+	cfg.ClusterSecret = "0123456789abcdef0123456789abcdef"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.ClusterPort = cfg.NodePort
+	problems = validationProblems(t, cfg.Validate())
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "CLUSTER_PORT")
+}
+
+func TestValidate_TrustedPanels(t *testing.T) {
+	cfg := newValidConfig(t)
+	standby := newTestPayload(t, newTestCA(t, time.Now().Add(-time.Hour)))
+	cfg.Payload.TrustedCACertPEMs = []string{standby.CACertPEM}
+	cfg.Payload.TrustedJWTPublicKeys = []string{standby.JWTPublicKey}
+	cfg.PanelCACerts = newTestCA(t, time.Now().Add(-time.Hour)).pem + newTestCA(t, time.Now().Add(-time.Hour)).pem
+	assert.NoError(t, cfg.Validate())
+	assert.Len(t, cfg.PanelCAs(), 3)
+	assert.Len(t, cfg.PanelJWTKeys(), 2)
+
+	cfg.Payload.TrustedCACertPEMs = []string{"ca-cert", standby.NodeCertPEM}
+	cfg.Payload.TrustedJWTPublicKeys = []string{"jwt-key"}
+	cfg.PanelCACerts = newTestCA(t, time.Now().Add(-48*time.Hour)).pem
+	problems := validationProblems(t, cfg.Validate())
+	assert.Equal(t, []string{
+		"SECRET_KEY trustedCaCertPems[0]: not a PEM certificate",
+		`SECRET_KEY trustedCaCertPems[1]: "node" is not a CA certificate`,
+		"SECRET_KEY trustedJwtPublicKeys[0]: not a PEM public key",
+	}, problems[:3])
+	require.Len(t, problems, 4)
+	assert.Contains(t, problems[3], `PANEL_CA_CERTS: "Test CA" expired at`)
+}
@@ -0,0 +1,219 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/inbound"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// ErrCoreNotRunning is returned by Check while xray is not running.
+var ErrCoreNotRunning = errors.New("xray core not running")
+
+// InboundReport is the drift found in one inbound.
+type InboundReport struct {
+	Tag string `json:"tag"`
+	// Orphaned are users present in xray but not in the hash set, by email.
+	Orphaned []string `json:"orphaned"`
+	// Missing are hash set entries with no matching user in xray, by ID.
+	Missing []string `json:"missing"`
+}
+
+// Report is the result of a consistency check.
+type Report struct {
+	Consistent bool            `json:"consistent"`
+	Repaired   bool            `json:"repaired"`
+	Checked    []string        `json:"checked"`
+	Inbounds   []InboundReport `json:"inbounds"`
+}
+
+// Checker compares the users of each running inbound with the hash sets the
+// ConfigManager keeps for the panel. Only vless and vmess inbounds can be
+// compared, as the hash sets hold account IDs.
+//
+// Repairing rewrites the hash sets to match xray. The panel then sees the
+// real hashes and restarts the core with the full config on its next sync,
+// which fixes the users themselves.
+type Checker struct {
+	core          *xray.Core
+	configManager *xray.ConfigManager
+	interval      time.Duration
+	autoRepair    bool
+	log           *logger.Logger
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewChecker creates a checker. With a positive interval Start runs a check
+// periodically, repairing the drift if autoRepair is set.
+func NewChecker(core *xray.Core, configManager *xray.ConfigManager, interval time.Duration, autoRepair bool, log *logger.Logger) *Checker {
+	return &Checker{
+		core:          core,
+		configManager: configManager,
+		interval:      interval,
+		autoRepair:    autoRepair,
+		log:           log,
+	}
+}
+
+// Start launches the periodic check, if an interval is configured.
+func (c *Checker) Start() {
+	if c.interval <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stopCh = make(chan struct{})
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if _, err := c.Check(c.autoRepair); err != nil && !errors.Is(err, ErrCoreNotRunning) {
+					c.log.WithError(err).Warn("Consistency check failed")
+				}
+			}
+		}
+	}()
+}
+
+// Stop terminates the periodic check.
+func (c *Checker) Stop() {
+	c.mu.Lock()
+	stopCh := c.stopCh
+	c.stopCh = nil
+	c.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		c.wg.Wait()
+	}
+}
+
+// Check compares every tracked vless and vmess inbound with xray and, with
+// repair, aligns the hash sets of the inconsistent ones.
+func (c *Checker) Check(repair bool) (Report, error) {
+	userManager, err := c.getUserManager()
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Consistent: true,
+		Checked:    []string{},
+		Inbounds:   []InboundReport{},
+	}
+
+	ctx := context.Background()
+	for _, hash := range c.configManager.CurrentHashes().Inbounds {
+		tag := hash.Tag
+		switch c.configManager.InboundProtocol(tag) {
+		case "vless", "vmess":
+		default:
+			continue
+		}
+
+		runtimeIDs, err := userManager.GetUserIDs(ctx, tag)
+		if err != nil {
+			c.log.WithError(err).WithField("tag", tag).Debug("Could not list inbound users for consistency check")
+			continue
+		}
+		hashIDs, _ := c.configManager.GetInboundUsers(tag)
+		report.Checked = append(report.Checked, tag)
+
+		inboundReport := compare(tag, runtimeIDs, hashIDs)
+		if len(inboundReport.Orphaned) == 0 && len(inboundReport.Missing) == 0 {
+			continue
+		}
+
+		report.Consistent = false
+		report.Inbounds = append(report.Inbounds, inboundReport)
+		c.log.WithField("tag", tag).
+			WithField("orphaned", len(inboundReport.Orphaned)).
+			WithField("missing", len(inboundReport.Missing)).
+			Warn("Inbound users drifted from config state")
+
+		if repair {
+			ids := make([]string, 0, len(runtimeIDs))
+			for _, id := range runtimeIDs {
+				ids = append(ids, id)
+			}
+			c.configManager.SetInboundUsers(tag, ids)
+			report.Repaired = true
+		}
+	}
+
+	if report.Repaired {
+		c.log.WithField("inbounds", len(report.Inbounds)).Info("Config state repaired to match xray")
+	}
+
+	return report, nil
+}
+
+// compare finds the users of one inbound that only xray or only the hash
+// set knows about.
+func compare(tag string, runtimeIDs map[string]string, hashIDs []string) InboundReport {
+	report := InboundReport{
+		Tag:      tag,
+		Orphaned: []string{},
+		Missing:  []string{},
+	}
+
+	hashed := make(map[string]struct{}, len(hashIDs))
+	for _, id := range hashIDs {
+		hashed[id] = struct{}{}
+	}
+
+	present := make(map[string]struct{}, len(runtimeIDs))
+	for email, id := range runtimeIDs {
+		present[id] = struct{}{}
+		if _, ok := hashed[id]; !ok {
+			report.Orphaned = append(report.Orphaned, email)
+		}
+	}
+	for _, id := range hashIDs {
+		if _, ok := present[id]; !ok {
+			report.Missing = append(report.Missing, id)
+		}
+	}
+
+	sort.Strings(report.Orphaned)
+	sort.Strings(report.Missing)
+	return report
+}
+
+func (c *Checker) getUserManager() (*xray.UserManager, error) {
+	instance := c.core.Instance()
+	if instance == nil {
+		return nil, ErrCoreNotRunning
+	}
+
+	ibm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return nil, errors.New("inbound manager not available")
+	}
+
+	return xray.NewUserManager(ibm, c.log), nil
+}
@@ -0,0 +1,39 @@
+package consistency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func TestCompare(t *testing.T) {
+	report := compare("vless-in", map[string]string{
+		"alice": "uuid-alice",
+		"bob":   "uuid-bob",
+	}, []string{"uuid-alice", "uuid-carol"})
+
+	assert.Equal(t, "vless-in", report.Tag)
+	assert.Equal(t, []string{"bob"}, report.Orphaned)
+	assert.Equal(t, []string{"uuid-carol"}, report.Missing)
+
+	report = compare("vless-in", map[string]string{"alice": "uuid-alice"}, []string{"uuid-alice"})
+	assert.Empty(t, report.Orphaned)
+	assert.Empty(t, report.Missing)
+}
+
+func TestChecker_CoreNotRunning(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewChecker(xray.NewCore(log), xray.NewConfigManager(log), 0, false, log)
+
+	_, err := c.Check(false)
+	require.ErrorIs(t, err, ErrCoreNotRunning)
+
+	// Without an interval the periodic check is disabled.
+	c.Start()
+	assert.Nil(t, c.stopCh)
+	c.Stop()
+}
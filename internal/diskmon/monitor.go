@@ -0,0 +1,145 @@
+// Package diskmon warns when the filesystems the node writes to run low on
+// space, before persisting state or logs starts failing.
+package diskmon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const checkInterval = time.Minute
+
+// Usage is the space of the filesystem holding Path.
+type Usage struct {
+	Path string `json:"path"`
+	// Free is the space available to the node, in bytes.
+	Free  uint64 `json:"free"`
+	Total uint64 `json:"total"`
+}
+
+// FreePercent returns the free space as a percentage of the total.
+func (u Usage) FreePercent() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return float64(u.Free) / float64(u.Total) * 100
+}
+
+// Monitor checks the free space of a set of paths every minute. A path
+// going below the threshold is reported once, until it recovers.
+type Monitor struct {
+	paths          []string
+	minFreePercent float64
+	log            *logger.Logger
+	usage          func(path string) (Usage, error)
+
+	mu    sync.Mutex
+	low   map[string]bool
+	onLow func(Usage)
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor for paths, which are low when less than
+// minFreePercent of their filesystem is free. Paths that do not exist are
+// skipped.
+func NewMonitor(paths []string, minFreePercent float64, log *logger.Logger) *Monitor {
+	return &Monitor{
+		paths:          paths,
+		minFreePercent: minFreePercent,
+		log:            log,
+		usage:          diskUsage,
+		low:            make(map[string]bool),
+	}
+}
+
+// OnLow sets a function called when a path goes below the threshold. It
+// must be set before Start.
+func (m *Monitor) OnLow(fn func(Usage)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onLow = fn
+}
+
+// Start checks the paths now and then every minute.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.mu.Unlock()
+
+	m.Check()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the check goroutine.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		m.wg.Wait()
+	}
+}
+
+// Check logs the paths that went below or back above the threshold since
+// the last check.
+func (m *Monitor) Check() {
+	m.mu.Lock()
+
+	var low []Usage
+	for _, path := range m.paths {
+		usage, err := m.usage(path)
+		if err != nil {
+			continue
+		}
+
+		entry := m.log.WithField("path", path).
+			WithField("free", usage.Free).
+			WithField("freePercent", int(usage.FreePercent()))
+		isLow := usage.FreePercent() < m.minFreePercent
+		switch {
+		case isLow && !m.low[path]:
+			entry.Error("Disk space is running low")
+			low = append(low, usage)
+		case !isLow && m.low[path]:
+			entry.Info("Disk space recovered")
+		}
+		m.low[path] = isLow
+	}
+	onLow := m.onLow
+	m.mu.Unlock()
+
+	if onLow != nil {
+		for _, usage := range low {
+			onLow(usage)
+		}
+	}
+}
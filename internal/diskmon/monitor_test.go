@@ -0,0 +1,51 @@
+package diskmon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestMonitor_ReportsOncePerCrossing(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	m := NewMonitor([]string{"/data", "/missing"}, 5, log)
+
+	free := uint64(50)
+	m.usage = func(path string) (Usage, error) {
+		if path == "/missing" {
+			return Usage{}, errors.New("no such file or directory")
+		}
+		return Usage{Path: path, Free: free, Total: 1000}, nil
+	}
+	var low []Usage
+	m.OnLow(func(u Usage) { low = append(low, u) })
+
+	m.Check()
+	assert.Empty(t, low, "5% free is not below the threshold")
+
+	free = 40
+	m.Check()
+	m.Check()
+	require.Len(t, low, 1)
+	assert.Equal(t, "/data", low[0].Path)
+	assert.InDelta(t, 4.0, low[0].FreePercent(), 0.001)
+
+	free = 200
+	m.Check()
+	free = 10
+	m.Check()
+	assert.Len(t, low, 2, "reported again after recovering")
+}
+
+func TestDiskUsage(t *testing.T) {
+	usage, err := diskUsage(t.TempDir())
+	if err != nil {
+		t.Skip(err)
+	}
+	assert.NotZero(t, usage.Total)
+	assert.LessOrEqual(t, usage.Free, usage.Total)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package diskmon
+
+import "errors"
+
+// diskUsage is not implemented: the monitor checks nothing outside Unix.
+func diskUsage(path string) (Usage, error) {
+	return Usage{}, errors.New("disk usage is not supported on this platform")
+}
@@ -1,5 +1,7 @@
 package errors
 
+import "time"
+
 type ErrorDef struct {
 	Code     string
 	Message  string
@@ -22,6 +24,19 @@ var ERRORS = map[string]ErrorDef{
 	"A015": {Code: "A015", Message: "Failed to get inbounds stats", HTTPCode: 500},
 	"A016": {Code: "A016", Message: "Failed to get outbounds stats", HTTPCode: 500},
 	"A017": {Code: "A017", Message: "Failed to get combined stats", HTTPCode: 500},
+	"A018": {Code: "A018", Message: "Invalid request", HTTPCode: 400},
+	"A019": {Code: "A019", Message: "Failed to start Xray", HTTPCode: 500},
+	"A020": {Code: "A020", Message: "Failed to stop Xray", HTTPCode: 500},
+	"A021": {Code: "A021", Message: "Failed to update Xray config", HTTPCode: 500},
+	"A022": {Code: "A022", Message: "Failed to add user", HTTPCode: 500},
+	"A023": {Code: "A023", Message: "Failed to remove user", HTTPCode: 500},
+	"A024": {Code: "A024", Message: "Failed to sync users", HTTPCode: 500},
+	"A025": {Code: "A025", Message: "Failed to get user", HTTPCode: 500},
+	"A026": {Code: "A026", Message: "Failed to update vision blocklist", HTTPCode: 500},
+	"A027": {Code: "A027", Message: "Failed to update inbound", HTTPCode: 500},
+	"A028": {Code: "A028", Message: "Failed to update routing rules", HTTPCode: 500},
+	"A029": {Code: "A029", Message: "Failed to manage Xray instance", HTTPCode: 500},
+	"A030": {Code: "A030", Message: "Inbound not found", HTTPCode: 404},
 }
 
 const (
@@ -40,9 +55,73 @@ const (
 	CodeFailedToGetInboundsStats  = "A015"
 	CodeFailedToGetOutboundsStats = "A016"
 	CodeFailedToGetCombinedStats  = "A017"
+	CodeInvalidRequest            = "A018"
+	CodeStartXrayError            = "A019"
+	CodeStopXrayError             = "A020"
+	CodeUpdateXrayConfigError     = "A021"
+	CodeAddUserError              = "A022"
+	CodeRemoveUserError           = "A023"
+	CodeSyncUsersError            = "A024"
+	CodeGetUserError              = "A025"
+	CodeVisionBlocklistError      = "A026"
+	CodeUpdateInboundError        = "A027"
+	CodeUpdateRoutingError        = "A028"
+	CodeXrayInstanceError         = "A029"
+	CodeInboundNotFound           = "A030"
 )
 
 func GetError(code string) (ErrorDef, bool) {
 	e, ok := ERRORS[code]
 	return e, ok
 }
+
+// Response is the body of a coded error.
+type Response struct {
+	Timestamp string `json:"timestamp"`
+	Path      string `json:"path"`
+	Message   string `json:"message"`
+	ErrorCode string `json:"errorCode"`
+}
+
+// NewResponse returns the response for code at path. The message is
+// detail when given, the message of the code otherwise; unknown codes are
+// answered as A001.
+func NewResponse(path, code, detail string) Response {
+	def, ok := GetError(code)
+	if !ok {
+		def = ERRORS[CodeInternalServerError]
+	}
+	message := def.Message
+	if detail != "" {
+		message = detail
+	}
+	return Response{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Path:      path,
+		Message:   message,
+		ErrorCode: def.Code,
+	}
+}
+
+// ValidationError is an invalid field of a request, Path being the keys
+// leading to it, e.g. ["data", "0", "username"].
+type ValidationError struct {
+	Path    []string `json:"path"`
+	Message string   `json:"message"`
+}
+
+// ValidationResponse is the body of a request whose fields failed
+// validation.
+type ValidationResponse struct {
+	StatusCode int               `json:"statusCode"`
+	Message    string            `json:"message"`
+	Errors     []ValidationError `json:"errors"`
+}
+
+func NewValidationResponse(errors []ValidationError) ValidationResponse {
+	return ValidationResponse{
+		StatusCode: 400,
+		Message:    "Validation failed",
+		Errors:     errors,
+	}
+}
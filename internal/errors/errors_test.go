@@ -10,7 +10,9 @@ func TestErrors_AllCodesPresent(t *testing.T) {
 	expectedCodes := []string{
 		"A001", "A002", "A003", "A004", "A005", "A006",
 		"A009", "A010", "A011", "A012", "A013", "A014",
-		"A015", "A016", "A017",
+		"A015", "A016", "A017", "A018", "A019", "A020",
+		"A021", "A022", "A023", "A024", "A025", "A026",
+		"A027", "A028", "A029", "A030",
 	}
 
 	for _, code := range expectedCodes {
@@ -33,6 +35,8 @@ func TestErrors_HTTPCodes(t *testing.T) {
 		{"A003", 401},
 		{"A004", 403},
 		{"A010", 500},
+		{"A018", 400},
+		{"A030", 404},
 	}
 
 	for _, tt := range tests {
@@ -54,3 +58,17 @@ func TestErrorConstants(t *testing.T) {
 	assert.Equal(t, "A003", CodeUnauthorized)
 	assert.Equal(t, "A010", CodeFailedToGetSystemStats)
 }
+
+func TestNewResponse(t *testing.T) {
+	resp := NewResponse("/node/handler/add-user", CodeAddUserError, "")
+	assert.Equal(t, "/node/handler/add-user", resp.Path)
+	assert.Equal(t, "A022", resp.ErrorCode)
+	assert.Equal(t, "Failed to add user", resp.Message)
+	assert.NotEmpty(t, resp.Timestamp)
+
+	resp = NewResponse("/node/xray/start", CodeStartXrayError, "node is shutting down")
+	assert.Equal(t, "node is shutting down", resp.Message)
+
+	resp = NewResponse("/", "INVALID", "")
+	assert.Equal(t, CodeInternalServerError, resp.ErrorCode)
+}
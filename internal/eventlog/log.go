@@ -0,0 +1,290 @@
+// Package eventlog keeps a bounded history of notable node events, such as
+// xray starts and crashes, user batches and blocked IPs, for post-mortem
+// debugging after the live event stream is gone.
+package eventlog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// DefaultSize is the number of entries kept when no size is configured.
+const DefaultSize = 1000
+
+const (
+	stateKey = "event-history"
+	// flushInterval is how often auth failures are summarized into an
+	// entry and a persisted history is saved.
+	flushInterval = time.Minute
+	// MaxBatchUsernames bounds the usernames kept per user batch entry.
+	MaxBatchUsernames = 100
+	// maxFailureIPs bounds the addresses counted per auth failures entry.
+	maxFailureIPs = 50
+)
+
+// Entry types recorded in the history only, not published on the bus.
+const (
+	TypeUsersAdded   = "users.added"
+	TypeUsersRemoved = "users.removed"
+	TypeAuthFailures = "auth.failures"
+)
+
+// recordedTypes are the bus events kept in the history.
+var recordedTypes = map[string]bool{
+	events.TypeXrayStarted:   true,
+	events.TypeXrayStopped:   true,
+	events.TypeXrayCrashed:   true,
+	events.TypeCertExpiring:  true,
+	events.TypeIPAutoBlocked: true,
+	events.TypeDiskLow:       true,
+	events.TypeMemoryHigh:    true,
+	events.TypeLimitHigh:     true,
+}
+
+// UserBatch is the data of user batch entries: one add or remove operation
+// of the panel, or an expired user.
+type UserBatch struct {
+	Operation string `json:"operation"`
+	Count     int    `json:"count"`
+	Failed    int    `json:"failed,omitempty"`
+	// Usernames lists the first MaxBatchUsernames users of the batch.
+	Usernames []string `json:"usernames"`
+}
+
+// NewUserBatch returns the batch entry data for usernames.
+func NewUserBatch(operation string, usernames []string, failed int) UserBatch {
+	listed := usernames
+	if len(listed) > MaxBatchUsernames {
+		listed = listed[:MaxBatchUsernames]
+	}
+
+	return UserBatch{
+		Operation: operation,
+		Count:     len(usernames),
+		Failed:    failed,
+		Usernames: append([]string(nil), listed...),
+	}
+}
+
+// AuthFailures is the data of auth failures entries, which summarize the
+// connections xray turned away during one flush interval.
+type AuthFailures struct {
+	Count int `json:"count"`
+	// IPs counts the failures per source address, for the first addresses
+	// seen in the interval.
+	IPs map[string]int `json:"ips"`
+}
+
+// Log records events in a fixed-size ring buffer, optionally saved to a
+// state store so the history survives node restarts.
+type Log struct {
+	mu      sync.Mutex
+	entries []events.Event
+	next    int
+	full    bool
+	dirty   bool
+
+	failures     map[string]int
+	failureCount int
+
+	bus   *events.Bus
+	store state.Store
+	log   *logger.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New creates a log keeping up to size entries of the events of bus. With
+// a store, the history is restored from it and saved back periodically.
+// A size of zero or less uses DefaultSize.
+func New(size int, bus *events.Bus, store state.Store, log *logger.Logger) *Log {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	l := &Log{
+		entries:  make([]events.Event, size),
+		failures: make(map[string]int),
+		bus:      bus,
+		store:    store,
+		log:      log,
+	}
+	l.restore()
+	return l
+}
+
+// Start subscribes to the bus and launches the goroutine recording its
+// events and flushing auth failures.
+func (l *Log) Start() {
+	l.mu.Lock()
+	if l.stopCh != nil {
+		l.mu.Unlock()
+		return
+	}
+	l.stopCh = make(chan struct{})
+	stopCh := l.stopCh
+	l.mu.Unlock()
+
+	ch, unsubscribe := l.bus.Subscribe()
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		defer unsubscribe()
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event := <-ch:
+				if recordedTypes[event.Type] {
+					l.add(event)
+				}
+			case <-ticker.C:
+				l.Flush()
+			}
+		}
+	}()
+}
+
+// Stop terminates the goroutine and flushes the pending auth failures and
+// the history.
+func (l *Log) Stop() {
+	l.mu.Lock()
+	stopCh := l.stopCh
+	l.stopCh = nil
+	l.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		l.wg.Wait()
+	}
+
+	l.Flush()
+}
+
+// Record adds an entry to the history. It is a no-op on a nil log, so
+// components of additional core instances can go without a history.
+func (l *Log) Record(eventType string, data interface{}) {
+	if l == nil {
+		return
+	}
+	l.add(events.Event{Type: eventType, Time: time.Now().UTC(), Data: data})
+}
+
+// RecordAuthFailure counts a connection xray turned away. Failures are
+// summarized in one entry per flush interval, so probes cannot flood the
+// history; it is meant as an xray.Core.OnAuthFailure hook.
+func (l *Log) RecordAuthFailure(failure xray.AuthFailure) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.failureCount++
+	if _, ok := l.failures[failure.IP]; ok || len(l.failures) < maxFailureIPs {
+		l.failures[failure.IP]++
+	}
+}
+
+// Flush records the auth failures counted since the last flush and saves
+// the history when it is persisted.
+func (l *Log) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failureCount > 0 {
+		l.addLocked(events.Event{
+			Type: TypeAuthFailures,
+			Time: time.Now().UTC(),
+			Data: AuthFailures{Count: l.failureCount, IPs: l.failures},
+		})
+		l.failureCount = 0
+		l.failures = make(map[string]int)
+	}
+
+	if l.dirty && l.store != nil {
+		if err := l.store.Save(stateKey, l.orderedLocked()); err != nil {
+			l.log.WithError(err).Warn("Failed to save event history")
+			return
+		}
+		l.dirty = false
+	}
+}
+
+// Entries returns the entries recorded after since, of the given type when
+// eventType is not empty, oldest first.
+func (l *Log) Entries(since time.Time, eventType string) []events.Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]events.Event, 0)
+	for _, entry := range l.orderedLocked() {
+		if !entry.Time.After(since) {
+			continue
+		}
+		if eventType != "" && entry.Type != eventType {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (l *Log) add(event events.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addLocked(event)
+}
+
+func (l *Log) addLocked(event events.Event) {
+	l.entries[l.next] = event
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.dirty = true
+}
+
+// orderedLocked returns the entries oldest first.
+func (l *Log) orderedLocked() []events.Event {
+	if !l.full {
+		return append([]events.Event(nil), l.entries[:l.next]...)
+	}
+	ordered := make([]events.Event, 0, len(l.entries))
+	ordered = append(ordered, l.entries[l.next:]...)
+	return append(ordered, l.entries[:l.next]...)
+}
+
+// restore loads the history saved by a previous run, keeping the newest
+// entries when it holds more than fit.
+func (l *Log) restore() {
+	if l.store == nil {
+		return
+	}
+
+	var saved []events.Event
+	found, err := l.store.Load(stateKey, &saved)
+	if err != nil {
+		l.log.WithError(err).Warn("Failed to restore event history")
+		return
+	}
+	if !found {
+		return
+	}
+
+	if len(saved) > len(l.entries) {
+		saved = saved[len(saved)-len(l.entries):]
+	}
+	for _, entry := range saved {
+		l.addLocked(entry)
+	}
+	l.dirty = false
+}
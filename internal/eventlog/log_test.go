@@ -0,0 +1,93 @@
+package eventlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestLog(size int, bus *events.Bus, store state.Store) *Log {
+	return New(size, bus, store, logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+}
+
+func types(entries []events.Event) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry.Type)
+	}
+	return result
+}
+
+func TestLog_KeepsNewestEntries(t *testing.T) {
+	l := newTestLog(3, events.NewBus(), nil)
+
+	for _, eventType := range []string{"a", "b", "c", "d"} {
+		l.Record(eventType, nil)
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, types(l.Entries(time.Time{}, "")))
+	assert.Equal(t, []string{"c"}, types(l.Entries(time.Time{}, "c")))
+	assert.Empty(t, l.Entries(time.Now().Add(time.Minute), ""))
+}
+
+func TestLog_RecordsSelectedBusEvents(t *testing.T) {
+	bus := events.NewBus()
+	l := newTestLog(10, bus, nil)
+	l.Start()
+	defer l.Stop()
+
+	bus.Publish(events.TypeTrafficSnapshot, nil)
+	bus.Publish(events.TypeUserAdded, events.UserEvent{Username: "alice"})
+	bus.Publish(events.TypeXrayStarted, events.XrayEvent{Version: "1.0"})
+
+	require.Eventually(t, func() bool { return len(l.Entries(time.Time{}, "")) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{events.TypeXrayStarted}, types(l.Entries(time.Time{}, "")))
+}
+
+func TestLog_SummarizesAuthFailures(t *testing.T) {
+	l := newTestLog(10, events.NewBus(), nil)
+
+	l.Flush()
+	assert.Empty(t, l.Entries(time.Time{}, ""), "no entry without failures")
+
+	for i := 0; i < 3; i++ {
+		l.RecordAuthFailure(xray.AuthFailure{IP: "198.51.100.1"})
+	}
+	l.RecordAuthFailure(xray.AuthFailure{IP: "198.51.100.2"})
+	l.Flush()
+
+	entries := l.Entries(time.Time{}, TypeAuthFailures)
+	require.Len(t, entries, 1)
+	assert.Equal(t, AuthFailures{Count: 4, IPs: map[string]int{"198.51.100.1": 3, "198.51.100.2": 1}}, entries[0].Data)
+}
+
+func TestLog_PersistsHistory(t *testing.T) {
+	store := state.NewMemoryStore()
+	l := newTestLog(10, events.NewBus(), store)
+	l.Record(TypeUsersAdded, NewUserBatch("add-users", []string{"alice", "bob"}, 1))
+	l.Record(TypeUsersRemoved, nil)
+	l.Flush()
+
+	restored := newTestLog(1, events.NewBus(), store)
+	entries := restored.Entries(time.Time{}, "")
+	assert.Equal(t, []string{TypeUsersRemoved}, types(entries), "only the newest entries that fit are restored")
+}
+
+func TestNewUserBatch(t *testing.T) {
+	usernames := make([]string, MaxBatchUsernames+5)
+	batch := NewUserBatch("sync-users", usernames, 2)
+	assert.Equal(t, MaxBatchUsernames+5, batch.Count)
+	assert.Len(t, batch.Usernames, MaxBatchUsernames)
+	assert.Equal(t, 2, batch.Failed)
+}
+
+func TestLog_RecordOnNilLog(t *testing.T) {
+	var l *Log
+	assert.NotPanics(t, func() { l.Record(TypeUsersAdded, nil) })
+}
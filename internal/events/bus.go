@@ -0,0 +1,155 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to subscribers.
+const (
+	TypeXrayStarted     = "xray.started"
+	TypeXrayStopped     = "xray.stopped"
+	TypeXrayCrashed     = "xray.crashed"
+	TypeUserAdded       = "user.added"
+	TypeUserRemoved     = "user.removed"
+	TypeTrafficSnapshot = "stats.traffic"
+	TypeCertExpiring    = "certificate.expiring"
+	TypeIPAutoBlocked   = "ip.autoblocked"
+	TypeDiskLow         = "disk.low"
+	TypeMemoryHigh      = "memory.high"
+	TypeLimitHigh       = "limit.high"
+)
+
+// subscriberBuffer is the number of events queued per subscriber before
+// new events are dropped for it.
+const subscriberBuffer = 64
+
+// Event is a single node event.
+type Event struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// XrayEvent is the payload of xray lifecycle events.
+type XrayEvent struct {
+	Version string  `json:"version,omitempty"`
+	Error   *string `json:"error,omitempty"`
+}
+
+// UserEvent is the payload of user add/remove events.
+type UserEvent struct {
+	Username string   `json:"username"`
+	Inbounds []string `json:"inbounds,omitempty"`
+}
+
+// CertificateEvent is the payload of certificate expiry events, sent when
+// the node certificate or CA crosses an expiry threshold and, once expired,
+// on every hourly check.
+type CertificateEvent struct {
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"notAfter"`
+	DaysLeft int       `json:"daysLeft"`
+	Expired  bool      `json:"expired"`
+}
+
+// IPBlockEvent is the payload of IP auto-block events.
+type IPBlockEvent struct {
+	// IP is the blocked address, or the network of an IPv6 address blocked
+	// per AUTO_BLOCK_IPV6_PREFIX, e.g. 2001:db8:1:2::/64.
+	IP string `json:"ip"`
+	// BanSeconds is how long the IP stays blocked.
+	BanSeconds int64 `json:"banSeconds"`
+}
+
+// DiskEvent is the payload of low disk space events, sent when a
+// filesystem the node writes to goes below the free space threshold.
+type DiskEvent struct {
+	Path        string `json:"path"`
+	FreeBytes   uint64 `json:"freeBytes"`
+	TotalBytes  uint64 `json:"totalBytes"`
+	FreePercent int    `json:"freePercent"`
+}
+
+// MemoryEvent is the payload of high memory events, sent when the RSS of
+// the xray process exceeds the restart threshold, before the node restarts
+// the core.
+type MemoryEvent struct {
+	RSSBytes       uint64 `json:"rssBytes"`
+	ThresholdBytes uint64 `json:"thresholdBytes"`
+	// RestartDeferred is set when the restart waits for the maintenance
+	// window.
+	RestartDeferred bool `json:"restartDeferred"`
+}
+
+// LimitEvent is the payload of resource limit events, sent when the open
+// file descriptors of the node or the conntrack table go above the warning
+// threshold of their limit.
+type LimitEvent struct {
+	// Resource is openFiles or conntrack.
+	Resource    string `json:"resource"`
+	Used        uint64 `json:"used"`
+	Limit       uint64 `json:"limit"`
+	UsedPercent int    `json:"usedPercent"`
+}
+
+// Bus fans out node events to subscribers. Publishing never blocks:
+// a subscriber that falls behind misses events rather than stalling the node.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish sends an event to all current subscribers.
+func (b *Bus) Publish(eventType string, data interface{}) {
+	event := Event{
+		Type: eventType,
+		Time: time.Now().UTC(),
+		Data: data,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a subscriber. The returned function unsubscribes
+// and closes the channel; it is safe to call more than once.
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// SubscriberCount returns the number of active subscribers.
+func (b *Bus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscribers)
+}
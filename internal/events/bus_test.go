@@ -0,0 +1,55 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	// Publishing without subscribers is a no-op.
+	bus.Publish(TypeXrayStarted, nil)
+
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	assert.Equal(t, 1, bus.SubscriberCount())
+
+	bus.Publish(TypeUserAdded, map[string]string{"username": "alice"})
+
+	event := <-ch
+	assert.Equal(t, TypeUserAdded, event.Type)
+	assert.False(t, event.Time.IsZero())
+	assert.Equal(t, map[string]string{"username": "alice"}, event.Data)
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+
+	ch, unsubscribe := bus.Subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	assert.Equal(t, 0, bus.SubscriberCount())
+	_, ok := <-ch
+	assert.False(t, ok, "channel should be closed")
+
+	bus.Publish(TypeXrayStopped, nil)
+}
+
+func TestBus_SlowSubscriberDropsEvents(t *testing.T) {
+	bus := NewBus()
+
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		bus.Publish(TypeTrafficSnapshot, i)
+	}
+
+	require.Len(t, ch, subscriberBuffer)
+	event := <-ch
+	assert.Equal(t, 0, event.Data)
+}
@@ -0,0 +1,275 @@
+package expiry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+)
+
+const (
+	stateKey = "user-expiry"
+
+	// maxWait bounds the sleep until the next expiry, so wall clock jumps
+	// delay enforcement by at most this long.
+	maxWait = time.Minute
+)
+
+// Entry is the expiry of a single user.
+type Entry struct {
+	HashUUID string    `json:"hashUuid,omitempty"`
+	ExpireAt time.Time `json:"expireAt"`
+}
+
+// Scheduler removes users once their expiry time passes, independently of
+// the panel. Expiries are persisted, so users that expire while the node is
+// down are removed right after it comes back.
+type Scheduler struct {
+	mu       sync.Mutex
+	entries  map[string]Entry
+	onExpire func(username, hashUUID string)
+	store    state.Store
+	log      *logger.Logger
+
+	// batches counts the open batches; changes made meanwhile are saved
+	// when the last one ends, as dirty records.
+	batches int
+	dirty   bool
+
+	wakeCh chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewScheduler restores persisted expiries.
+func NewScheduler(store state.Store, log *logger.Logger) *Scheduler {
+	s := &Scheduler{
+		entries: make(map[string]Entry),
+		store:   store,
+		log:     log,
+		wakeCh:  make(chan struct{}, 1),
+	}
+
+	s.restore()
+
+	return s
+}
+
+// OnExpire sets the function removing an expired user. It must be set
+// before Start.
+func (s *Scheduler) OnExpire(fn func(username, hashUUID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onExpire = fn
+}
+
+// Set schedules the removal of a user at expireAt. A zero expireAt clears
+// the expiry. hashUUID is the config hash dropped along with the user.
+func (s *Scheduler) Set(username, hashUUID string, expireAt time.Time) {
+	if expireAt.IsZero() {
+		s.Remove(username)
+		return
+	}
+
+	entry := Entry{HashUUID: hashUUID, ExpireAt: expireAt}
+
+	s.mu.Lock()
+	current, exists := s.entries[username]
+	s.entries[username] = entry
+	if exists && current.HashUUID == entry.HashUUID && current.ExpireAt.Equal(entry.ExpireAt) {
+		s.mu.Unlock()
+		return
+	}
+	s.changedLocked()
+	s.mu.Unlock()
+
+	s.wake()
+}
+
+// Remove clears the expiry of a user.
+func (s *Scheduler) Remove(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[username]; !exists {
+		return
+	}
+	delete(s.entries, username)
+	s.changedLocked()
+}
+
+// BeginBatch defers saving the expiries to EndBatch, so that the users of
+// a bulk request are saved once rather than one by one. While batches are
+// open, the changes of other callers are saved when the last one ends.
+func (s *Scheduler) BeginBatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches++
+}
+
+// EndBatch ends a batch opened by BeginBatch, saving the expiries if it
+// was the last one and they changed.
+func (s *Scheduler) EndBatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches--
+	if s.batches == 0 && s.dirty {
+		s.saveLocked()
+	}
+}
+
+// ExpireAt returns the expiry time of a user, if any.
+func (s *Scheduler) ExpireAt(username string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[username]
+	return entry.ExpireAt, exists
+}
+
+// Start launches the goroutine that removes users as they expire.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.stopCh != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stopCh = make(chan struct{})
+	stopCh := s.stopCh
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-s.wakeCh:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+			case now := <-timer.C:
+				s.Check(now)
+			}
+			timer.Reset(s.nextWait(time.Now()))
+		}
+	}()
+}
+
+// Stop terminates the expiry goroutine.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh := s.stopCh
+	s.stopCh = nil
+	s.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		s.wg.Wait()
+	}
+}
+
+// Check removes all users expired at now and returns their usernames.
+func (s *Scheduler) Check(now time.Time) []string {
+	s.mu.Lock()
+	onExpire := s.onExpire
+	expired := make(map[string]Entry)
+	for username, entry := range s.entries {
+		if !entry.ExpireAt.After(now) {
+			expired[username] = entry
+			delete(s.entries, username)
+		}
+	}
+	if len(expired) > 0 {
+		s.changedLocked()
+	}
+	s.mu.Unlock()
+
+	usernames := make([]string, 0, len(expired))
+	for username, entry := range expired {
+		s.log.WithField("username", username).WithField("expireAt", entry.ExpireAt).
+			Info("User expired, removing")
+		if onExpire != nil {
+			onExpire(username, entry.HashUUID)
+		}
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
+
+// nextWait returns how long to sleep until the earliest expiry.
+func (s *Scheduler) nextWait(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := maxWait
+	for _, entry := range s.entries {
+		if until := entry.ExpireAt.Sub(now); until < wait {
+			wait = until
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// wake makes the expiry goroutine recompute its sleep after a change.
+func (s *Scheduler) wake() {
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// restore loads the expiries of a previous run.
+func (s *Scheduler) restore() {
+	var stored map[string]Entry
+	found, err := s.store.Load(stateKey, &stored)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to restore user expiries")
+		return
+	}
+	if !found {
+		return
+	}
+
+	for username, entry := range stored {
+		s.entries[username] = entry
+	}
+
+	if len(s.entries) > 0 {
+		s.log.WithField("users", len(s.entries)).Info("Restored user expiries")
+	}
+}
+
+// changedLocked saves the expiries, or marks them for the end of the
+// batches. The caller must hold s.mu.
+func (s *Scheduler) changedLocked() {
+	if s.batches > 0 {
+		s.dirty = true
+		return
+	}
+	s.saveLocked()
+}
+
+// saveLocked persists the expiries. The caller must hold s.mu.
+func (s *Scheduler) saveLocked() {
+	s.dirty = false
+	if err := s.store.Save(stateKey, s.entries); err != nil {
+		s.log.WithError(err).Error("Failed to persist user expiries")
+	}
+}
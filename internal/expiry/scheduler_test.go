@@ -0,0 +1,155 @@
+package expiry
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+)
+
+func newTestScheduler(t *testing.T, store state.Store) *Scheduler {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return NewScheduler(store, log)
+}
+
+func TestScheduler_CheckExpiresDueUsers(t *testing.T) {
+	s := newTestScheduler(t, state.NewMemoryStore())
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expired := make(map[string]string)
+	s.OnExpire(func(username, hashUUID string) {
+		expired[username] = hashUUID
+	})
+
+	s.Set("alice", "hash-alice", now.Add(-time.Second))
+	s.Set("bob", "hash-bob", now)
+	s.Set("carol", "", now.Add(time.Hour))
+
+	assert.ElementsMatch(t, []string{"alice", "bob"}, s.Check(now))
+	assert.Equal(t, map[string]string{"alice": "hash-alice", "bob": "hash-bob"}, expired)
+
+	_, exists := s.ExpireAt("alice")
+	assert.False(t, exists)
+	expireAt, exists := s.ExpireAt("carol")
+	assert.True(t, exists)
+	assert.Equal(t, now.Add(time.Hour), expireAt)
+
+	assert.Empty(t, s.Check(now))
+}
+
+func TestScheduler_SetZeroClearsExpiry(t *testing.T) {
+	s := newTestScheduler(t, state.NewMemoryStore())
+
+	s.Set("alice", "", time.Now().Add(time.Hour))
+	s.Set("alice", "", time.Time{})
+	_, exists := s.ExpireAt("alice")
+	assert.False(t, exists)
+
+	s.Set("bob", "", time.Now().Add(time.Hour))
+	s.Remove("bob")
+	_, exists = s.ExpireAt("bob")
+	assert.False(t, exists)
+}
+
+// countingStore counts the saves.
+type countingStore struct {
+	state.Store
+	saves atomic.Int32
+}
+
+func (s *countingStore) Save(key string, v interface{}) error {
+	s.saves.Add(1)
+	return s.Store.Save(key, v)
+}
+
+func TestScheduler_Batch(t *testing.T) {
+	store := &countingStore{Store: state.NewMemoryStore()}
+	s := newTestScheduler(t, store)
+	expireAt := time.Now().Add(time.Hour)
+
+	s.BeginBatch()
+	s.BeginBatch()
+	for i := range 100 {
+		s.Set(fmt.Sprintf("user-%d", i), "", expireAt)
+	}
+	s.Remove("user-0")
+	s.EndBatch()
+	assert.Zero(t, store.saves.Load(), "saves wait for the end of the last batch")
+	s.EndBatch()
+	assert.Equal(t, int32(1), store.saves.Load())
+
+	restored := newTestScheduler(t, store.Store)
+	_, exists := restored.ExpireAt("user-0")
+	assert.False(t, exists)
+	_, exists = restored.ExpireAt("user-99")
+	assert.True(t, exists)
+}
+
+func TestScheduler_Persistence(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	expireAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, store)
+	s.Set("alice", "hash-alice", expireAt)
+
+	restored := newTestScheduler(t, store)
+	got, exists := restored.ExpireAt("alice")
+	require.True(t, exists)
+	assert.True(t, expireAt.Equal(got))
+
+	var expiredHash string
+	restored.OnExpire(func(username, hashUUID string) { expiredHash = hashUUID })
+	restored.Check(expireAt)
+	assert.Equal(t, "hash-alice", expiredHash)
+
+	again := newTestScheduler(t, store)
+	_, exists = again.ExpireAt("alice")
+	assert.False(t, exists, "expired users are not restored")
+}
+
+func TestScheduler_StartExpiresOnTime(t *testing.T) {
+	s := newTestScheduler(t, state.NewMemoryStore())
+
+	var mu sync.Mutex
+	var expired []string
+	done := make(chan struct{})
+	s.OnExpire(func(username, hashUUID string) {
+		mu.Lock()
+		expired = append(expired, username)
+		mu.Unlock()
+		close(done)
+	})
+
+	s.Start()
+	defer s.Stop()
+
+	s.Set("alice", "", time.Now().Add(50*time.Millisecond))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("user was not expired")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"alice"}, expired)
+}
+
+func TestScheduler_StartStop(t *testing.T) {
+	s := newTestScheduler(t, state.NewMemoryStore())
+
+	s.Start()
+	s.Start()
+	s.Stop()
+	s.Stop()
+}
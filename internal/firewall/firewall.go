@@ -0,0 +1,77 @@
+package firewall
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Backends of config.FirewallBackend.
+const (
+	BackendNftables = "nftables"
+	BackendIPSet    = "ipset"
+)
+
+// Firewall drops the traffic of blocked sources in the kernel, before it
+// reaches xray.
+type Firewall interface {
+	// Setup creates the sets and the rules dropping their traffic,
+	// replacing those left by a previous run.
+	Setup() error
+	// Add blocks source, an IP or CIDR range, until expiresAt, or
+	// permanently when it is nil. Adding a blocked source updates its
+	// expiry.
+	Add(source string, expiresAt *time.Time) error
+	// Remove unblocks source. Removing a source that is not blocked is not
+	// an error.
+	Remove(source string) error
+	// Close removes the sets and rules.
+	Close() error
+}
+
+// New returns the firewall of the named backend. Nothing is changed on the
+// host before Setup.
+func New(backend string) (Firewall, error) {
+	switch backend {
+	case BackendNftables:
+		return &nftables{run: runCommand}, nil
+	case BackendIPSet:
+		return &ipset{run: runCommand}, nil
+	default:
+		return nil, fmt.Errorf("unknown firewall backend %q", backend)
+	}
+}
+
+// runFunc runs a command with stdin as its input.
+type runFunc func(stdin string, name string, args ...string) error
+
+func runCommand(stdin string, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+func isIPv6(source string) bool {
+	return strings.Contains(source, ":")
+}
+
+// timeoutSeconds returns the seconds left until expiresAt, rounded up so
+// the kernel does not drop the entry before the blocklist does, or zero
+// for a permanent block.
+func timeoutSeconds(expiresAt *time.Time) int {
+	if expiresAt == nil {
+		return 0
+	}
+	return max(int(math.Ceil(time.Until(*expiresAt).Seconds())), 1)
+}
@@ -0,0 +1,128 @@
+package firewall
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the commands run, failing those for which fail returns
+// true.
+type recorder struct {
+	commands []string
+	stdins   []string
+	fail     func(command string) bool
+}
+
+func (r *recorder) run(stdin string, name string, args ...string) error {
+	command := strings.Join(append([]string{name}, args...), " ")
+	r.commands = append(r.commands, command)
+	r.stdins = append(r.stdins, stdin)
+	if r.fail != nil && r.fail(command) {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	fw, err := New(BackendNftables)
+	require.NoError(t, err)
+	assert.IsType(t, &nftables{}, fw)
+
+	fw, err = New(BackendIPSet)
+	require.NoError(t, err)
+	assert.IsType(t, &ipset{}, fw)
+
+	_, err = New("pf")
+	assert.Error(t, err)
+}
+
+func TestTimeoutSeconds(t *testing.T) {
+	assert.Equal(t, 0, timeoutSeconds(nil))
+
+	expiresAt := time.Now().Add(90*time.Second + 100*time.Millisecond)
+	assert.Equal(t, 91, timeoutSeconds(&expiresAt))
+
+	past := time.Now().Add(-time.Minute)
+	assert.Equal(t, 1, timeoutSeconds(&past))
+}
+
+func TestNftables(t *testing.T) {
+	rec := &recorder{}
+	fw := &nftables{run: rec.run}
+
+	require.NoError(t, fw.Setup())
+	assert.Equal(t, "nft -f -", rec.commands[0])
+	assert.Contains(t, rec.stdins[0], "delete table inet remnawave_node")
+	assert.Contains(t, rec.stdins[0], "ip saddr @blocked4 drop")
+	assert.Contains(t, rec.stdins[0], "ip6 saddr @blocked6 drop")
+
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, fw.Add("203.0.113.0/24", &expiresAt))
+	assert.Equal(t, "add element inet remnawave_node blocked4 { 203.0.113.0/24 }\n"+
+		"delete element inet remnawave_node blocked4 { 203.0.113.0/24 }\n"+
+		"add element inet remnawave_node blocked4 { 203.0.113.0/24 timeout 3600s }\n", rec.stdins[1])
+
+	require.NoError(t, fw.Add("2001:db8::1", nil))
+	assert.Contains(t, rec.stdins[2], "add element inet remnawave_node blocked6 { 2001:db8::1 }\n")
+	assert.NotContains(t, rec.stdins[2], "timeout")
+
+	require.NoError(t, fw.Remove("2001:db8::1"))
+	assert.Equal(t, "add element inet remnawave_node blocked6 { 2001:db8::1 }\n"+
+		"delete element inet remnawave_node blocked6 { 2001:db8::1 }\n", rec.stdins[3])
+
+	require.NoError(t, fw.Close())
+	assert.Contains(t, rec.stdins[4], "delete table inet remnawave_node")
+}
+
+func TestIPSet(t *testing.T) {
+	// No rules or sets are left from a previous run.
+	rec := &recorder{fail: func(command string) bool {
+		return strings.Contains(command, " -C ") || strings.Contains(command, "ipset list")
+	}}
+	fw := &ipset{run: rec.run}
+
+	require.NoError(t, fw.Setup())
+	assert.Contains(t, rec.commands, "ipset create remnawave_node_blocked4 hash:net family inet timeout 0 -exist")
+	assert.Contains(t, rec.commands, "ipset create remnawave_node_blocked6 hash:net family inet6 timeout 0 -exist")
+	assert.Contains(t, rec.commands, "iptables -I INPUT -m set --match-set remnawave_node_blocked4 src -j DROP")
+	assert.Contains(t, rec.commands, "ip6tables -I INPUT -m set --match-set remnawave_node_blocked6 src -j DROP")
+
+	rec.commands = nil
+	expiresAt := time.Now().Add(time.Minute)
+	require.NoError(t, fw.Add("198.51.100.7", &expiresAt))
+	require.NoError(t, fw.Add("2001:db8::/32", nil))
+	require.NoError(t, fw.Remove("198.51.100.7"))
+	assert.Equal(t, []string{
+		"ipset add remnawave_node_blocked4 198.51.100.7 timeout 60 -exist",
+		"ipset add remnawave_node_blocked6 2001:db8::/32 timeout 0 -exist",
+		"ipset del remnawave_node_blocked4 198.51.100.7 -exist",
+	}, rec.commands)
+}
+
+func TestIPSetClose(t *testing.T) {
+	// The IPv4 rule is present once; the IPv6 rule and set are gone.
+	checks := 0
+	rec := &recorder{}
+	rec.fail = func(command string) bool {
+		switch {
+		case strings.HasPrefix(command, "iptables -C"):
+			checks++
+			return checks > 1
+		case strings.HasPrefix(command, "ip6tables -C"), command == "ipset list -n remnawave_node_blocked6":
+			return true
+		}
+		return false
+	}
+	fw := &ipset{run: rec.run}
+
+	require.NoError(t, fw.Close())
+	assert.Contains(t, rec.commands, "iptables -D INPUT -m set --match-set remnawave_node_blocked4 src -j DROP")
+	assert.Contains(t, rec.commands, "ipset destroy remnawave_node_blocked4")
+	assert.NotContains(t, rec.commands, "ip6tables -D INPUT -m set --match-set remnawave_node_blocked6 src -j DROP")
+	assert.NotContains(t, rec.commands, "ipset destroy remnawave_node_blocked6")
+}
@@ -0,0 +1,88 @@
+package firewall
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	ipsetBlocked4 = "remnawave_node_blocked4"
+	ipsetBlocked6 = "remnawave_node_blocked6"
+)
+
+// ipset drops blocked sources with an ipset per address family, supporting
+// per-entry timeouts, matched by a DROP rule at the top of the iptables and
+// ip6tables INPUT chains.
+type ipset struct {
+	run runFunc
+}
+
+type ipsetFamily struct {
+	set      string
+	family   string
+	iptables string
+}
+
+var ipsetFamilies = []ipsetFamily{
+	{set: ipsetBlocked4, family: "inet", iptables: "iptables"},
+	{set: ipsetBlocked6, family: "inet6", iptables: "ip6tables"},
+}
+
+func dropRule(set string) []string {
+	return []string{"INPUT", "-m", "set", "--match-set", set, "src", "-j", "DROP"}
+}
+
+func (f *ipset) Setup() error {
+	// Removing leftovers of a previous run also empties the sets.
+	f.Close()
+
+	for _, family := range ipsetFamilies {
+		if err := f.run("", "ipset", "create", family.set, "hash:net", "family", family.family, "timeout", "0", "-exist"); err != nil {
+			return err
+		}
+		if err := f.run("", "ipset", "flush", family.set); err != nil {
+			return err
+		}
+		if err := f.run("", family.iptables, append([]string{"-I"}, dropRule(family.set)...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ipsetName(source string) string {
+	if isIPv6(source) {
+		return ipsetBlocked6
+	}
+	return ipsetBlocked4
+}
+
+func (f *ipset) Add(source string, expiresAt *time.Time) error {
+	timeout := strconv.Itoa(timeoutSeconds(expiresAt))
+	return f.run("", "ipset", "add", ipsetName(source), source, "timeout", timeout, "-exist")
+}
+
+func (f *ipset) Remove(source string) error {
+	return f.run("", "ipset", "del", ipsetName(source), source, "-exist")
+}
+
+func (f *ipset) Close() error {
+	var errs []error
+	for _, family := range ipsetFamilies {
+		// Delete every copy of the rule; the set cannot be destroyed while
+		// a rule references it.
+		for f.run("", family.iptables, append([]string{"-C"}, dropRule(family.set)...)...) == nil {
+			if err := f.run("", family.iptables, append([]string{"-D"}, dropRule(family.set)...)...); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+		if f.run("", "ipset", "list", "-n", family.set) == nil {
+			if err := f.run("", "ipset", "destroy", family.set); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
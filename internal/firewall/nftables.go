@@ -0,0 +1,74 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const nftTable = "inet remnawave_node"
+
+// nftables drops blocked sources with a table of its own, holding one set
+// per address family with per-element timeouts, and an input chain
+// dropping their packets ahead of the default filter priority.
+type nftables struct {
+	run runFunc
+}
+
+func (f *nftables) nft(script string) error {
+	return f.run(script, "nft", "-f", "-")
+}
+
+func (f *nftables) Setup() error {
+	// Declaring the table first lets the delete succeed on a clean host.
+	return f.nft(`table ` + nftTable + `
+delete table ` + nftTable + `
+table ` + nftTable + ` {
+	set blocked4 {
+		type ipv4_addr
+		flags interval, timeout
+	}
+	set blocked6 {
+		type ipv6_addr
+		flags interval, timeout
+	}
+	chain input {
+		type filter hook input priority -10; policy accept;
+		ip saddr @blocked4 drop
+		ip6 saddr @blocked6 drop
+	}
+}
+`)
+}
+
+func nftSet(source string) string {
+	if isIPv6(source) {
+		return "blocked6"
+	}
+	return "blocked4"
+}
+
+func (f *nftables) Add(source string, expiresAt *time.Time) error {
+	element := source
+	if timeout := timeoutSeconds(expiresAt); timeout > 0 {
+		element = fmt.Sprintf("%s timeout %ds", source, timeout)
+	}
+
+	// Elements keep the timeout they were added with, so replace them; the
+	// first add makes the delete succeed for a new element.
+	set := nftTable + " " + nftSet(source)
+	var script strings.Builder
+	fmt.Fprintf(&script, "add element %s { %s }\n", set, source)
+	fmt.Fprintf(&script, "delete element %s { %s }\n", set, source)
+	fmt.Fprintf(&script, "add element %s { %s }\n", set, element)
+	return f.nft(script.String())
+}
+
+func (f *nftables) Remove(source string) error {
+	set := nftTable + " " + nftSet(source)
+	return f.nft(fmt.Sprintf("add element %s { %s }\ndelete element %s { %s }\n", set, source, set, source))
+}
+
+func (f *nftables) Close() error {
+	return f.nft("table " + nftTable + "\ndelete table " + nftTable + "\n")
+}
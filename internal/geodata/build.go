@@ -0,0 +1,135 @@
+package geodata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/xtls/xray-core/app/router"
+	"google.golang.org/protobuf/proto"
+)
+
+// FilterGeoIP returns the lists of a geoip.dat file named by codes, e.g. to
+// build a smaller file to embed. Codes are case-insensitive.
+func FilterGeoIP(data []byte, codes []string) (*router.GeoIPList, error) {
+	var list router.GeoIPList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid geoip data: %w", err)
+	}
+
+	byCode := make(map[string]*router.GeoIP, len(list.Entry))
+	for _, entry := range list.Entry {
+		byCode[strings.ToUpper(entry.CountryCode)] = entry
+	}
+
+	filtered := &router.GeoIPList{}
+	for _, code := range codes {
+		entry, ok := byCode[strings.ToUpper(code)]
+		if !ok {
+			return nil, fmt.Errorf("geoip list %q not found", code)
+		}
+		filtered.Entry = append(filtered.Entry, entry)
+	}
+	return filtered, nil
+}
+
+// FilterGeoSite returns the lists of a geosite.dat file named by codes.
+func FilterGeoSite(data []byte, codes []string) (*router.GeoSiteList, error) {
+	var list router.GeoSiteList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid geosite data: %w", err)
+	}
+
+	byCode := make(map[string]*router.GeoSite, len(list.Entry))
+	for _, entry := range list.Entry {
+		byCode[strings.ToUpper(entry.CountryCode)] = entry
+	}
+
+	filtered := &router.GeoSiteList{}
+	for _, code := range codes {
+		entry, ok := byCode[strings.ToUpper(code)]
+		if !ok {
+			return nil, fmt.Errorf("geosite list %q not found", code)
+		}
+		filtered.Entry = append(filtered.Entry, entry)
+	}
+	return filtered, nil
+}
+
+// ParseCIDRs builds the geoip list code from CIDRs or single addresses.
+func ParseCIDRs(code string, lines []string) (*router.GeoIP, error) {
+	geoip := &router.GeoIP{CountryCode: strings.ToUpper(code)}
+	for _, line := range lines {
+		prefix, err := netip.ParsePrefix(line)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(line)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", line)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		bits := prefix.Bits()
+		addr := prefix.Addr()
+		if addr.Is4In6() && bits >= 96 {
+			addr, bits = addr.Unmap(), bits-96
+		}
+		geoip.Cidr = append(geoip.Cidr, &router.CIDR{
+			Ip:     addr.AsSlice(),
+			Prefix: uint32(bits),
+		})
+	}
+	return geoip, nil
+}
+
+// ParseDomains builds the geosite list code from rules in the form of the
+// domain lists of xray: "domain:" for a domain and its subdomains, "full:"
+// for an exact domain, "keyword:" and "regexp:". A rule without a prefix is
+// a domain.
+func ParseDomains(code string, lines []string) (*router.GeoSite, error) {
+	geosite := &router.GeoSite{CountryCode: strings.ToUpper(code)}
+	for _, line := range lines {
+		kind, value, ok := strings.Cut(line, ":")
+		if !ok {
+			kind, value = "domain", line
+		}
+
+		var domainType router.Domain_Type
+		switch kind {
+		case "domain":
+			domainType = router.Domain_Domain
+		case "full":
+			domainType = router.Domain_Full
+		case "keyword":
+			domainType = router.Domain_Plain
+		case "regexp":
+			domainType = router.Domain_Regex
+		default:
+			return nil, fmt.Errorf("invalid domain rule %q", line)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("invalid domain rule %q", line)
+		}
+
+		geosite.Domain = append(geosite.Domain, &router.Domain{
+			Type:  domainType,
+			Value: value,
+		})
+	}
+	return geosite, nil
+}
+
+// ReadLines returns the lines of a list file, skipping blank lines and
+// "#" comments.
+func ReadLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,124 @@
+package geodata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/common/platform/filesystem"
+	"google.golang.org/protobuf/proto"
+)
+
+// embeddedAssets holds the geodata files compiled in with the geodata_embed
+// build tag, by file name. Without it the builtin minimal set is used.
+var embeddedAssets map[string][]byte
+
+var (
+	builtinOnce   sync.Once
+	builtinAssets map[string][]byte
+
+	useEmbeddedOnce sync.Once
+)
+
+// privateCIDRs are the special-purpose ranges of geoip:private.
+var privateCIDRs = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"255.255.255.255/32",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// privateDomains are the special-use domains of geosite:private.
+var privateDomains = []string{
+	"domain:localhost",
+	"domain:local",
+	"domain:localdomain",
+	"domain:lan",
+	"domain:internal",
+	"domain:home.arpa",
+	"domain:test",
+	"domain:invalid",
+}
+
+// Embedded returns the geodata file name compiled into the binary: the set
+// chosen at build time with the geodata_embed tag, or else a minimal
+// geoip.dat and geosite.dat holding only the "private" lists.
+func Embedded(name string) ([]byte, bool) {
+	if embeddedAssets != nil {
+		data, ok := embeddedAssets[name]
+		return data, ok
+	}
+
+	builtinOnce.Do(func() {
+		builtinAssets = buildBuiltin()
+	})
+	data, ok := builtinAssets[name]
+	return data, ok
+}
+
+// UseEmbedded makes xray fall back to the embedded geodata when a geodata
+// file is missing from the asset directory, so routing rules using e.g.
+// geoip:private work without any asset files mounted. Files on disk,
+// including the ones the Manager downloads, always take precedence. It
+// affects every xray instance of the process.
+func UseEmbedded() {
+	useEmbeddedOnce.Do(func() {
+		open := filesystem.NewFileReader
+		filesystem.NewFileReader = func(path string) (io.ReadCloser, error) {
+			reader, err := open(path)
+			if err != nil && errors.Is(err, os.ErrNotExist) {
+				if data, ok := Embedded(filepath.Base(path)); ok {
+					return io.NopCloser(bytes.NewReader(data)), nil
+				}
+			}
+			return reader, err
+		}
+	})
+}
+
+func buildBuiltin() map[string][]byte {
+	assets := make(map[string][]byte)
+
+	geoip, err := ParseCIDRs("private", privateCIDRs)
+	if err != nil {
+		panic(err)
+	}
+	data, err := proto.Marshal(&router.GeoIPList{Entry: []*router.GeoIP{geoip}})
+	if err != nil {
+		panic(err)
+	}
+	assets["geoip.dat"] = data
+
+	geosite, err := ParseDomains("private", privateDomains)
+	if err != nil {
+		panic(err)
+	}
+	data, err = proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{geosite}})
+	if err != nil {
+		panic(err)
+	}
+	assets["geosite.dat"] = data
+
+	return assets
+}
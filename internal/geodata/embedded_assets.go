@@ -0,0 +1,22 @@
+//go:build geodata_embed
+
+package geodata
+
+import (
+	_ "embed"
+)
+
+// The files are generated by "make geodata-embed".
+
+//go:embed embedded/geoip.dat
+var embeddedGeoIP []byte
+
+//go:embed embedded/geosite.dat
+var embeddedGeoSite []byte
+
+func init() {
+	embeddedAssets = map[string][]byte{
+		"geoip.dat":   embeddedGeoIP,
+		"geosite.dat": embeddedGeoSite,
+	}
+}
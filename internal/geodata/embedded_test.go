@@ -0,0 +1,108 @@
+package geodata
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xtls/xray-core/app/router"
+	"github.com/xtls/xray-core/infra/conf"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEmbedded_BuiltinPrivateLists(t *testing.T) {
+	data, ok := Embedded("geoip.dat")
+	require.True(t, ok)
+	geoip, err := FilterGeoIP(data, []string{"private"})
+	require.NoError(t, err)
+	require.Len(t, geoip.Entry, 1)
+	assert.Equal(t, "PRIVATE", geoip.Entry[0].CountryCode)
+	assert.Contains(t, geoip.Entry[0].Cidr, &router.CIDR{Ip: net.IPv4(10, 0, 0, 0).To4(), Prefix: 8})
+
+	data, ok = Embedded("geosite.dat")
+	require.True(t, ok)
+	geosite, err := FilterGeoSite(data, []string{"PRIVATE"})
+	require.NoError(t, err)
+	require.Len(t, geosite.Entry, 1)
+	assert.NotEmpty(t, geosite.Entry[0].Domain)
+
+	_, ok = Embedded("other.dat")
+	assert.False(t, ok)
+}
+
+func TestUseEmbedded_RoutingWithoutAssetFiles(t *testing.T) {
+	t.Setenv("XRAY_LOCATION_ASSET", t.TempDir())
+	UseEmbedded()
+
+	var routing conf.RouterConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"rules": [
+		{"ip": ["geoip:private"], "outboundTag": "direct"},
+		{"domain": ["geosite:private"], "outboundTag": "direct"}
+	]}`), &routing))
+
+	config, err := routing.Build()
+	require.NoError(t, err)
+	assert.Len(t, config.Rule, 2)
+}
+
+func TestFilter_MissingCode(t *testing.T) {
+	data, _ := Embedded("geoip.dat")
+	_, err := FilterGeoIP(data, []string{"private", "cn"})
+	assert.ErrorContains(t, err, `"cn" not found`)
+
+	_, err = FilterGeoIP([]byte("not protobuf"), []string{"private"})
+	assert.Error(t, err)
+}
+
+func TestParseCIDRs(t *testing.T) {
+	geoip, err := ParseCIDRs("office", []string{"198.51.100.0/24", "192.0.2.7", "2001:db8::/32", "::ffff:203.0.113.0/120"})
+	require.NoError(t, err)
+	assert.Equal(t, "OFFICE", geoip.CountryCode)
+	assert.Equal(t, []*router.CIDR{
+		{Ip: net.IPv4(198, 51, 100, 0).To4(), Prefix: 24},
+		{Ip: net.IPv4(192, 0, 2, 7).To4(), Prefix: 32},
+		{Ip: net.ParseIP("2001:db8::"), Prefix: 32},
+		{Ip: net.IPv4(203, 0, 113, 0).To4(), Prefix: 24},
+	}, geoip.Cidr)
+
+	_, err = ParseCIDRs("office", []string{"not-a-cidr"})
+	assert.Error(t, err)
+}
+
+func TestParseDomains(t *testing.T) {
+	geosite, err := ParseDomains("office", []string{"example.com", "full:panel.example.com", "keyword:tracker", `regexp:^cdn\d+\.`})
+	require.NoError(t, err)
+	assert.Equal(t, "OFFICE", geosite.CountryCode)
+
+	var types []router.Domain_Type
+	for _, domain := range geosite.Domain {
+		types = append(types, domain.Type)
+	}
+	assert.Equal(t, []router.Domain_Type{router.Domain_Domain, router.Domain_Full, router.Domain_Plain, router.Domain_Regex}, types)
+
+	_, err = ParseDomains("office", []string{"ext:other.dat:list"})
+	assert.Error(t, err)
+	_, err = ParseDomains("office", []string{"full:"})
+	assert.Error(t, err)
+}
+
+func TestReadLines(t *testing.T) {
+	lines, err := ReadLines(strings.NewReader("# office ranges\n10.1.0.0/16\n\n  10.2.0.0/16  # branch\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.1.0.0/16", "10.2.0.0/16"}, lines)
+}
+
+func TestFilter_RoundTrip(t *testing.T) {
+	geosite, err := ParseDomains("office", []string{"example.com"})
+	require.NoError(t, err)
+	data, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{geosite}})
+	require.NoError(t, err)
+
+	filtered, err := FilterGeoSite(data, []string{"office"})
+	require.NoError(t, err)
+	require.Len(t, filtered.Entry, 1)
+	assert.Equal(t, "example.com", filtered.Entry[0].Domain[0].Value)
+}
@@ -0,0 +1,315 @@
+// Package geodata keeps the geoip.dat and geosite.dat files used by the xray
+// routing rules present and up to date.
+package geodata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	requestTimeout = 5 * time.Minute
+	maxAssetSize   = 128 << 20
+	// checksumSuffix is appended to the URL of an asset to get its SHA-256
+	// checksum, as published alongside the common geodata releases.
+	checksumSuffix = ".sha256sum"
+)
+
+// ErrChecksumMismatch is returned when a downloaded asset does not match its
+// published checksum.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// updateAction names the scheduled updates handed to a Deferrer.
+const updateAction = "geodata-update"
+
+// Deferrer postpones an action, e.g. to a maintenance window; it is
+// implemented by maintenance.Scheduler.
+type Deferrer interface {
+	Defer(name string, fn func())
+}
+
+// Asset is a geodata file stored as Name in the asset directory and
+// downloaded from URL.
+type Asset struct {
+	Name string
+	URL  string
+}
+
+// Manager downloads the assets missing from the asset directory and checks
+// for new versions every interval. An asset is only replaced by a download
+// that matches its published checksum, and the replacement is atomic, so xray
+// never reads a partial file. The change hooks run after any asset changed,
+// e.g. to reload the routing rules built from the files.
+type Manager struct {
+	dir      string
+	assets   []Asset
+	interval time.Duration
+	client   *http.Client
+	log      *logger.Logger
+
+	updateMu sync.Mutex
+
+	mu            sync.Mutex
+	onChangeHooks []func()
+	deferrer      Deferrer
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager keeping assets in dir. A zero interval
+// disables the scheduled updates; missing assets are still downloaded on
+// Start.
+func NewManager(dir string, assets []Asset, interval time.Duration, log *logger.Logger) *Manager {
+	return &Manager{
+		dir:      dir,
+		assets:   assets,
+		interval: interval,
+		client:   &http.Client{Timeout: requestTimeout},
+		log:      log,
+	}
+}
+
+// OnChange registers fn to run after an update changed any asset.
+func (m *Manager) OnChange(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChangeHooks = append(m.onChangeHooks, fn)
+}
+
+// DeferUpdates hands the scheduled updates to d instead of running them
+// right away. The update on Start is never deferred, since xray may need
+// the missing assets.
+func (m *Manager) DeferUpdates(d Deferrer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deferrer = d
+}
+
+// Start updates the assets once, so that missing ones are in place before
+// xray starts, and launches the update goroutine.
+func (m *Manager) Start() {
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.mu.Unlock()
+
+	m.UpdateAndLog()
+
+	if m.interval <= 0 {
+		return
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.scheduledUpdate()
+			}
+		}
+	}()
+}
+
+func (m *Manager) scheduledUpdate() {
+	m.mu.Lock()
+	deferrer := m.deferrer
+	m.mu.Unlock()
+
+	if deferrer == nil {
+		m.UpdateAndLog()
+		return
+	}
+	deferrer.Defer(updateAction, m.UpdateAndLog)
+}
+
+// Stop terminates the update goroutine.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		m.wg.Wait()
+	}
+}
+
+// Update brings every asset in line with its published checksum and
+// returns the names of the assets that changed. The change hooks run if
+// any did. An asset that fails to update keeps its current file; the
+// others are still updated and the first error is returned.
+func (m *Manager) Update() ([]string, error) {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create asset directory: %w", err)
+	}
+
+	var changed []string
+	var firstErr error
+	for _, asset := range m.assets {
+		updated, err := m.updateAsset(asset)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", asset.Name, err)
+			}
+			continue
+		}
+		if updated {
+			changed = append(changed, asset.Name)
+		}
+	}
+
+	if len(changed) > 0 {
+		m.log.WithField("assets", changed).Info("Geodata updated")
+
+		m.mu.Lock()
+		hooks := append([]func(){}, m.onChangeHooks...)
+		m.mu.Unlock()
+		for _, hook := range hooks {
+			hook()
+		}
+	}
+
+	return changed, firstErr
+}
+
+// UpdateAndLog updates the assets, logging a failure instead of returning
+// it.
+func (m *Manager) UpdateAndLog() {
+	if _, err := m.Update(); err != nil {
+		m.log.WithError(err).Warn("Failed to update geodata, keeping current files")
+	}
+}
+
+func (m *Manager) updateAsset(asset Asset) (bool, error) {
+	path := filepath.Join(m.dir, asset.Name)
+
+	expected, err := m.fetchChecksum(asset.URL + checksumSuffix)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+
+	current, err := fileChecksum(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	if current == expected {
+		return false, nil
+	}
+
+	tmp, err := os.CreateTemp(m.dir, "."+asset.Name+".*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	got, err := m.download(asset.URL, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to download: %w", err)
+	}
+	if got != expected {
+		return false, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, got)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return false, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// fetchChecksum returns the hex digest at url, the first field of a
+// sha256sum line.
+func (m *Manager) fetchChecksum(url string) (string, error) {
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New("empty checksum")
+	}
+	sum := strings.ToLower(fields[0])
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid checksum %q", fields[0])
+	}
+	return sum, nil
+}
+
+// download writes the body at url to w and returns its checksum.
+func (m *Manager) download(url string, w io.Writer) (string, error) {
+	resp, err := m.client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return "", err
+	}
+	if n > maxAssetSize {
+		return "", fmt.Errorf("asset larger than %d bytes", maxAssetSize)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
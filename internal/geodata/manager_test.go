@@ -0,0 +1,186 @@
+package geodata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func testLogger() *logger.Logger {
+	return logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+}
+
+// assetServer serves files by path, each with its ".sha256sum".
+type assetServer struct {
+	mu        sync.Mutex
+	files     map[string][]byte
+	checksums map[string]string
+	downloads int
+}
+
+func newAssetServer(t *testing.T) (*assetServer, *httptest.Server) {
+	s := &assetServer{files: make(map[string][]byte), checksums: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if sum, ok := s.checksums[r.URL.Path]; ok {
+			w.Write([]byte(sum + "  " + filepath.Base(strings.TrimSuffix(r.URL.Path, checksumSuffix)) + "\n"))
+			return
+		}
+		if data, ok := s.files[r.URL.Path]; ok {
+			s.downloads++
+			w.Write(data)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func (s *assetServer) set(path string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sum := sha256.Sum256(data)
+	s.files[path] = data
+	s.checksums[path+checksumSuffix] = hex.EncodeToString(sum[:])
+}
+
+func (s *assetServer) downloadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.downloads
+}
+
+func TestManager_DownloadsMissingAssets(t *testing.T) {
+	files, server := newAssetServer(t)
+	files.set("/geoip.dat", []byte("geoip v1"))
+	files.set("/geosite.dat", []byte("geosite v1"))
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	m := NewManager(dir, []Asset{
+		{Name: "geoip.dat", URL: server.URL + "/geoip.dat"},
+		{Name: "geosite.dat", URL: server.URL + "/geosite.dat"},
+	}, 0, testLogger())
+
+	changes := 0
+	m.OnChange(func() { changes++ })
+
+	changed, err := m.Update()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"geoip.dat", "geosite.dat"}, changed)
+	assert.Equal(t, 1, changes)
+
+	data, err := os.ReadFile(filepath.Join(dir, "geoip.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, "geoip v1", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary files left behind")
+}
+
+func TestManager_SkipsUnchangedAssets(t *testing.T) {
+	files, server := newAssetServer(t)
+	files.set("/geoip.dat", []byte("geoip v1"))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "geoip.dat"), []byte("geoip v1"), 0644))
+
+	m := NewManager(dir, []Asset{{Name: "geoip.dat", URL: server.URL + "/geoip.dat"}}, 0, testLogger())
+	changes := 0
+	m.OnChange(func() { changes++ })
+
+	changed, err := m.Update()
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Equal(t, 0, changes)
+	assert.Equal(t, 0, files.downloadCount())
+
+	files.set("/geoip.dat", []byte("geoip v2"))
+	changed, err = m.Update()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"geoip.dat"}, changed)
+	assert.Equal(t, 1, changes)
+
+	data, err := os.ReadFile(filepath.Join(dir, "geoip.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, "geoip v2", string(data))
+}
+
+func TestManager_RejectsChecksumMismatch(t *testing.T) {
+	files, server := newAssetServer(t)
+	files.set("/geoip.dat", []byte("geoip v1"))
+	files.set("/geosite.dat", []byte("geosite v1"))
+	files.mu.Lock()
+	files.files["/geoip.dat"] = []byte("tampered")
+	files.mu.Unlock()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "geoip.dat"), []byte("geoip v0"), 0644))
+
+	m := NewManager(dir, []Asset{
+		{Name: "geoip.dat", URL: server.URL + "/geoip.dat"},
+		{Name: "geosite.dat", URL: server.URL + "/geosite.dat"},
+	}, 0, testLogger())
+
+	changed, err := m.Update()
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, []string{"geosite.dat"}, changed, "other assets are still updated")
+
+	data, err := os.ReadFile(filepath.Join(dir, "geoip.dat"))
+	require.NoError(t, err)
+	assert.Equal(t, "geoip v0", string(data), "current file kept")
+}
+
+func TestManager_MissingChecksum(t *testing.T) {
+	_, server := newAssetServer(t)
+
+	dir := t.TempDir()
+	m := NewManager(dir, []Asset{{Name: "geoip.dat", URL: server.URL + "/geoip.dat"}}, 0, testLogger())
+
+	_, err := m.Update()
+	require.Error(t, err)
+
+	_, err = os.Stat(filepath.Join(dir, "geoip.dat"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+type recordingDeferrer struct {
+	names []string
+	fns   []func()
+}
+
+func (d *recordingDeferrer) Defer(name string, fn func()) {
+	d.names = append(d.names, name)
+	d.fns = append(d.fns, fn)
+}
+
+func TestManager_DefersScheduledUpdates(t *testing.T) {
+	files, server := newAssetServer(t)
+	files.set("/geoip.dat", []byte("geoip v1"))
+
+	m := NewManager(t.TempDir(), []Asset{{Name: "geoip.dat", URL: server.URL + "/geoip.dat"}}, 0, testLogger())
+	deferrer := &recordingDeferrer{}
+	m.DeferUpdates(deferrer)
+
+	m.scheduledUpdate()
+	assert.Equal(t, []string{updateAction}, deferrer.names)
+	assert.Equal(t, 0, files.downloadCount(), "the update waits for the deferrer")
+
+	deferrer.fns[0]()
+	assert.Equal(t, 1, files.downloadCount())
+}
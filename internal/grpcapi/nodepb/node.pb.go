@@ -0,0 +1,1626 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: internal/grpcapi/nodepb/node.proto
+
+package nodepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type InboundHash struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Hash          string                 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	UsersCount    int32                  `protobuf:"varint,3,opt,name=users_count,json=usersCount,proto3" json:"users_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InboundHash) Reset() {
+	*x = InboundHash{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InboundHash) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InboundHash) ProtoMessage() {}
+
+func (x *InboundHash) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InboundHash.ProtoReflect.Descriptor instead.
+func (*InboundHash) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *InboundHash) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *InboundHash) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *InboundHash) GetUsersCount() int32 {
+	if x != nil {
+		return x.UsersCount
+	}
+	return 0
+}
+
+type StartXrayRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Xray config JSON, as sent in xrayConfig by the REST API.
+	XrayConfig      []byte         `protobuf:"bytes,1,opt,name=xray_config,json=xrayConfig,proto3" json:"xray_config,omitempty"`
+	ForceRestart    bool           `protobuf:"varint,2,opt,name=force_restart,json=forceRestart,proto3" json:"force_restart,omitempty"`
+	EmptyConfigHash string         `protobuf:"bytes,3,opt,name=empty_config_hash,json=emptyConfigHash,proto3" json:"empty_config_hash,omitempty"`
+	Inbounds        []*InboundHash `protobuf:"bytes,4,rep,name=inbounds,proto3" json:"inbounds,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StartXrayRequest) Reset() {
+	*x = StartXrayRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartXrayRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartXrayRequest) ProtoMessage() {}
+
+func (x *StartXrayRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartXrayRequest.ProtoReflect.Descriptor instead.
+func (*StartXrayRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StartXrayRequest) GetXrayConfig() []byte {
+	if x != nil {
+		return x.XrayConfig
+	}
+	return nil
+}
+
+func (x *StartXrayRequest) GetForceRestart() bool {
+	if x != nil {
+		return x.ForceRestart
+	}
+	return false
+}
+
+func (x *StartXrayRequest) GetEmptyConfigHash() string {
+	if x != nil {
+		return x.EmptyConfigHash
+	}
+	return ""
+}
+
+func (x *StartXrayRequest) GetInbounds() []*InboundHash {
+	if x != nil {
+		return x.Inbounds
+	}
+	return nil
+}
+
+type StartXrayResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsStarted     bool                   `protobuf:"varint,1,opt,name=is_started,json=isStarted,proto3" json:"is_started,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	NodeVersion   string                 `protobuf:"bytes,3,opt,name=node_version,json=nodeVersion,proto3" json:"node_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartXrayResponse) Reset() {
+	*x = StartXrayResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartXrayResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartXrayResponse) ProtoMessage() {}
+
+func (x *StartXrayResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartXrayResponse.ProtoReflect.Descriptor instead.
+func (*StartXrayResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StartXrayResponse) GetIsStarted() bool {
+	if x != nil {
+		return x.IsStarted
+	}
+	return false
+}
+
+func (x *StartXrayResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *StartXrayResponse) GetNodeVersion() string {
+	if x != nil {
+		return x.NodeVersion
+	}
+	return ""
+}
+
+type StopXrayRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopXrayRequest) Reset() {
+	*x = StopXrayRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopXrayRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopXrayRequest) ProtoMessage() {}
+
+func (x *StopXrayRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopXrayRequest.ProtoReflect.Descriptor instead.
+func (*StopXrayRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{3}
+}
+
+type StopXrayResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsStopped     bool                   `protobuf:"varint,1,opt,name=is_stopped,json=isStopped,proto3" json:"is_stopped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopXrayResponse) Reset() {
+	*x = StopXrayResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopXrayResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopXrayResponse) ProtoMessage() {}
+
+func (x *StopXrayResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopXrayResponse.ProtoReflect.Descriptor instead.
+func (*StopXrayResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StopXrayResponse) GetIsStopped() bool {
+	if x != nil {
+		return x.IsStopped
+	}
+	return false
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{5}
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	IsRunning     bool                   `protobuf:"varint,1,opt,name=is_running,json=isRunning,proto3" json:"is_running,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetStatusResponse) GetIsRunning() bool {
+	if x != nil {
+		return x.IsRunning
+	}
+	return false
+}
+
+func (x *GetStatusResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type AddUserInbound struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Username      string                 `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Uuid          string                 `protobuf:"bytes,4,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	Flow          string                 `protobuf:"bytes,5,opt,name=flow,proto3" json:"flow,omitempty"`
+	Password      string                 `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`
+	CipherType    string                 `protobuf:"bytes,7,opt,name=cipher_type,json=cipherType,proto3" json:"cipher_type,omitempty"`
+	IvCheck       bool                   `protobuf:"varint,8,opt,name=iv_check,json=ivCheck,proto3" json:"iv_check,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddUserInbound) Reset() {
+	*x = AddUserInbound{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddUserInbound) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddUserInbound) ProtoMessage() {}
+
+func (x *AddUserInbound) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddUserInbound.ProtoReflect.Descriptor instead.
+func (*AddUserInbound) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AddUserInbound) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetFlow() string {
+	if x != nil {
+		return x.Flow
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetCipherType() string {
+	if x != nil {
+		return x.CipherType
+	}
+	return ""
+}
+
+func (x *AddUserInbound) GetIvCheck() bool {
+	if x != nil {
+		return x.IvCheck
+	}
+	return false
+}
+
+type AddUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []*AddUserInbound      `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty"`
+	VlessUuid     string                 `protobuf:"bytes,2,opt,name=vless_uuid,json=vlessUuid,proto3" json:"vless_uuid,omitempty"`
+	PrevVlessUuid string                 `protobuf:"bytes,3,opt,name=prev_vless_uuid,json=prevVlessUuid,proto3" json:"prev_vless_uuid,omitempty"`
+	IpLimit       int32                  `protobuf:"varint,4,opt,name=ip_limit,json=ipLimit,proto3" json:"ip_limit,omitempty"`
+	// Unix seconds after which the node removes the user; 0 means never.
+	ExpireAt      int64 `protobuf:"varint,5,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddUserRequest) Reset() {
+	*x = AddUserRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddUserRequest) ProtoMessage() {}
+
+func (x *AddUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddUserRequest.ProtoReflect.Descriptor instead.
+func (*AddUserRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AddUserRequest) GetData() []*AddUserInbound {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *AddUserRequest) GetVlessUuid() string {
+	if x != nil {
+		return x.VlessUuid
+	}
+	return ""
+}
+
+func (x *AddUserRequest) GetPrevVlessUuid() string {
+	if x != nil {
+		return x.PrevVlessUuid
+	}
+	return ""
+}
+
+func (x *AddUserRequest) GetIpLimit() int32 {
+	if x != nil {
+		return x.IpLimit
+	}
+	return 0
+}
+
+func (x *AddUserRequest) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+type BulkUser struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	HashUuid       string                 `protobuf:"bytes,2,opt,name=hash_uuid,json=hashUuid,proto3" json:"hash_uuid,omitempty"`
+	VlessUuid      string                 `protobuf:"bytes,3,opt,name=vless_uuid,json=vlessUuid,proto3" json:"vless_uuid,omitempty"`
+	VmessUuid      string                 `protobuf:"bytes,4,opt,name=vmess_uuid,json=vmessUuid,proto3" json:"vmess_uuid,omitempty"`
+	TrojanPassword string                 `protobuf:"bytes,5,opt,name=trojan_password,json=trojanPassword,proto3" json:"trojan_password,omitempty"`
+	SsPassword     string                 `protobuf:"bytes,6,opt,name=ss_password,json=ssPassword,proto3" json:"ss_password,omitempty"`
+	// Ignored: SOCKS and HTTP inbounds keep their accounts in config only.
+	ProxyPassword string `protobuf:"bytes,7,opt,name=proxy_password,json=proxyPassword,proto3" json:"proxy_password,omitempty"`
+	IpLimit       int32  `protobuf:"varint,8,opt,name=ip_limit,json=ipLimit,proto3" json:"ip_limit,omitempty"`
+	// Unix seconds after which the node removes the user; 0 means never.
+	ExpireAt      int64 `protobuf:"varint,9,opt,name=expire_at,json=expireAt,proto3" json:"expire_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkUser) Reset() {
+	*x = BulkUser{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkUser) ProtoMessage() {}
+
+func (x *BulkUser) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkUser.ProtoReflect.Descriptor instead.
+func (*BulkUser) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BulkUser) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *BulkUser) GetHashUuid() string {
+	if x != nil {
+		return x.HashUuid
+	}
+	return ""
+}
+
+func (x *BulkUser) GetVlessUuid() string {
+	if x != nil {
+		return x.VlessUuid
+	}
+	return ""
+}
+
+func (x *BulkUser) GetVmessUuid() string {
+	if x != nil {
+		return x.VmessUuid
+	}
+	return ""
+}
+
+func (x *BulkUser) GetTrojanPassword() string {
+	if x != nil {
+		return x.TrojanPassword
+	}
+	return ""
+}
+
+func (x *BulkUser) GetSsPassword() string {
+	if x != nil {
+		return x.SsPassword
+	}
+	return ""
+}
+
+func (x *BulkUser) GetProxyPassword() string {
+	if x != nil {
+		return x.ProxyPassword
+	}
+	return ""
+}
+
+func (x *BulkUser) GetIpLimit() int32 {
+	if x != nil {
+		return x.IpLimit
+	}
+	return 0
+}
+
+func (x *BulkUser) GetExpireAt() int64 {
+	if x != nil {
+		return x.ExpireAt
+	}
+	return 0
+}
+
+type BulkInbound struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Flow          string                 `protobuf:"bytes,3,opt,name=flow,proto3" json:"flow,omitempty"`
+	CipherType    string                 `protobuf:"bytes,4,opt,name=cipher_type,json=cipherType,proto3" json:"cipher_type,omitempty"`
+	IvCheck       bool                   `protobuf:"varint,5,opt,name=iv_check,json=ivCheck,proto3" json:"iv_check,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkInbound) Reset() {
+	*x = BulkInbound{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkInbound) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkInbound) ProtoMessage() {}
+
+func (x *BulkInbound) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkInbound.ProtoReflect.Descriptor instead.
+func (*BulkInbound) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BulkInbound) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *BulkInbound) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *BulkInbound) GetFlow() string {
+	if x != nil {
+		return x.Flow
+	}
+	return ""
+}
+
+func (x *BulkInbound) GetCipherType() string {
+	if x != nil {
+		return x.CipherType
+	}
+	return ""
+}
+
+func (x *BulkInbound) GetIvCheck() bool {
+	if x != nil {
+		return x.IvCheck
+	}
+	return false
+}
+
+type BulkUserEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserData      *BulkUser              `protobuf:"bytes,1,opt,name=user_data,json=userData,proto3" json:"user_data,omitempty"`
+	InboundData   []*BulkInbound         `protobuf:"bytes,2,rep,name=inbound_data,json=inboundData,proto3" json:"inbound_data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkUserEntry) Reset() {
+	*x = BulkUserEntry{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkUserEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkUserEntry) ProtoMessage() {}
+
+func (x *BulkUserEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkUserEntry.ProtoReflect.Descriptor instead.
+func (*BulkUserEntry) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BulkUserEntry) GetUserData() *BulkUser {
+	if x != nil {
+		return x.UserData
+	}
+	return nil
+}
+
+func (x *BulkUserEntry) GetInboundData() []*BulkInbound {
+	if x != nil {
+		return x.InboundData
+	}
+	return nil
+}
+
+type AddUsersRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	AffectedInboundTags []string               `protobuf:"bytes,1,rep,name=affected_inbound_tags,json=affectedInboundTags,proto3" json:"affected_inbound_tags,omitempty"`
+	Users               []*BulkUserEntry       `protobuf:"bytes,2,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *AddUsersRequest) Reset() {
+	*x = AddUsersRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddUsersRequest) ProtoMessage() {}
+
+func (x *AddUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddUsersRequest.ProtoReflect.Descriptor instead.
+func (*AddUsersRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AddUsersRequest) GetAffectedInboundTags() []string {
+	if x != nil {
+		return x.AffectedInboundTags
+	}
+	return nil
+}
+
+func (x *AddUsersRequest) GetUsers() []*BulkUserEntry {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type RemoveUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	VlessUuid     string                 `protobuf:"bytes,2,opt,name=vless_uuid,json=vlessUuid,proto3" json:"vless_uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveUserRequest) Reset() {
+	*x = RemoveUserRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveUserRequest) ProtoMessage() {}
+
+func (x *RemoveUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveUserRequest.ProtoReflect.Descriptor instead.
+func (*RemoveUserRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RemoveUserRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *RemoveUserRequest) GetVlessUuid() string {
+	if x != nil {
+		return x.VlessUuid
+	}
+	return ""
+}
+
+type BulkRemoveUser struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	HashUuid      string                 `protobuf:"bytes,2,opt,name=hash_uuid,json=hashUuid,proto3" json:"hash_uuid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BulkRemoveUser) Reset() {
+	*x = BulkRemoveUser{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BulkRemoveUser) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkRemoveUser) ProtoMessage() {}
+
+func (x *BulkRemoveUser) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkRemoveUser.ProtoReflect.Descriptor instead.
+func (*BulkRemoveUser) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BulkRemoveUser) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *BulkRemoveUser) GetHashUuid() string {
+	if x != nil {
+		return x.HashUuid
+	}
+	return ""
+}
+
+type RemoveUsersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*BulkRemoveUser      `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveUsersRequest) Reset() {
+	*x = RemoveUsersRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveUsersRequest) ProtoMessage() {}
+
+func (x *RemoveUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveUsersRequest.ProtoReflect.Descriptor instead.
+func (*RemoveUsersRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RemoveUsersRequest) GetUsers() []*BulkRemoveUser {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type UserOperationResult struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Username string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	// Set for add-users, where each inbound of a user is reported separately.
+	Inbound       string `protobuf:"bytes,2,opt,name=inbound,proto3" json:"inbound,omitempty"`
+	Success       bool   `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserOperationResult) Reset() {
+	*x = UserOperationResult{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserOperationResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserOperationResult) ProtoMessage() {}
+
+func (x *UserOperationResult) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserOperationResult.ProtoReflect.Descriptor instead.
+func (*UserOperationResult) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UserOperationResult) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *UserOperationResult) GetInbound() string {
+	if x != nil {
+		return x.Inbound
+	}
+	return ""
+}
+
+func (x *UserOperationResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UserOperationResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type UserOperationResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// Per-user results of bulk operations.
+	Results       []*UserOperationResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserOperationResponse) Reset() {
+	*x = UserOperationResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserOperationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserOperationResponse) ProtoMessage() {}
+
+func (x *UserOperationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserOperationResponse.ProtoReflect.Descriptor instead.
+func (*UserOperationResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UserOperationResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UserOperationResponse) GetResults() []*UserOperationResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type GetUsersStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reset_        bool                   `protobuf:"varint,1,opt,name=reset,proto3" json:"reset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsersStatsRequest) Reset() {
+	*x = GetUsersStatsRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsersStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsersStatsRequest) ProtoMessage() {}
+
+func (x *GetUsersStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsersStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetUsersStatsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetUsersStatsRequest) GetReset_() bool {
+	if x != nil {
+		return x.Reset_
+	}
+	return false
+}
+
+type UserStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Uplink        int64                  `protobuf:"varint,2,opt,name=uplink,proto3" json:"uplink,omitempty"`
+	Downlink      int64                  `protobuf:"varint,3,opt,name=downlink,proto3" json:"downlink,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserStats) Reset() {
+	*x = UserStats{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserStats) ProtoMessage() {}
+
+func (x *UserStats) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserStats.ProtoReflect.Descriptor instead.
+func (*UserStats) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *UserStats) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *UserStats) GetUplink() int64 {
+	if x != nil {
+		return x.Uplink
+	}
+	return 0
+}
+
+func (x *UserStats) GetDownlink() int64 {
+	if x != nil {
+		return x.Downlink
+	}
+	return 0
+}
+
+type GetUsersStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*UserStats           `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsersStatsResponse) Reset() {
+	*x = GetUsersStatsResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsersStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsersStatsResponse) ProtoMessage() {}
+
+func (x *GetUsersStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsersStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetUsersStatsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetUsersStatsResponse) GetUsers() []*UserStats {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+type GetSystemStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSystemStatsRequest) Reset() {
+	*x = GetSystemStatsRequest{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSystemStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSystemStatsRequest) ProtoMessage() {}
+
+func (x *GetSystemStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSystemStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetSystemStatsRequest) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{21}
+}
+
+type GetSystemStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NumGoroutine  int32                  `protobuf:"varint,1,opt,name=num_goroutine,json=numGoroutine,proto3" json:"num_goroutine,omitempty"`
+	NumGc         uint32                 `protobuf:"varint,2,opt,name=num_gc,json=numGc,proto3" json:"num_gc,omitempty"`
+	Alloc         uint64                 `protobuf:"varint,3,opt,name=alloc,proto3" json:"alloc,omitempty"`
+	TotalAlloc    uint64                 `protobuf:"varint,4,opt,name=total_alloc,json=totalAlloc,proto3" json:"total_alloc,omitempty"`
+	Sys           uint64                 `protobuf:"varint,5,opt,name=sys,proto3" json:"sys,omitempty"`
+	Mallocs       uint64                 `protobuf:"varint,6,opt,name=mallocs,proto3" json:"mallocs,omitempty"`
+	Frees         uint64                 `protobuf:"varint,7,opt,name=frees,proto3" json:"frees,omitempty"`
+	LiveObjects   uint64                 `protobuf:"varint,8,opt,name=live_objects,json=liveObjects,proto3" json:"live_objects,omitempty"`
+	Uptime        int64                  `protobuf:"varint,9,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSystemStatsResponse) Reset() {
+	*x = GetSystemStatsResponse{}
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSystemStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSystemStatsResponse) ProtoMessage() {}
+
+func (x *GetSystemStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_grpcapi_nodepb_node_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSystemStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetSystemStatsResponse) Descriptor() ([]byte, []int) {
+	return file_internal_grpcapi_nodepb_node_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetSystemStatsResponse) GetNumGoroutine() int32 {
+	if x != nil {
+		return x.NumGoroutine
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetNumGc() uint32 {
+	if x != nil {
+		return x.NumGc
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetAlloc() uint64 {
+	if x != nil {
+		return x.Alloc
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetTotalAlloc() uint64 {
+	if x != nil {
+		return x.TotalAlloc
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetSys() uint64 {
+	if x != nil {
+		return x.Sys
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetMallocs() uint64 {
+	if x != nil {
+		return x.Mallocs
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetFrees() uint64 {
+	if x != nil {
+		return x.Frees
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetLiveObjects() uint64 {
+	if x != nil {
+		return x.LiveObjects
+	}
+	return 0
+}
+
+func (x *GetSystemStatsResponse) GetUptime() int64 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+var File_internal_grpcapi_nodepb_node_proto protoreflect.FileDescriptor
+
+const file_internal_grpcapi_nodepb_node_proto_rawDesc = "" +
+	"\n" +
+	"\"internal/grpcapi/nodepb/node.proto\x12\x11remnawave.node.v1\"T\n" +
+	"\vInboundHash\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x12\n" +
+	"\x04hash\x18\x02 \x01(\tR\x04hash\x12\x1f\n" +
+	"\vusers_count\x18\x03 \x01(\x05R\n" +
+	"usersCount\"\xc0\x01\n" +
+	"\x10StartXrayRequest\x12\x1f\n" +
+	"\vxray_config\x18\x01 \x01(\fR\n" +
+	"xrayConfig\x12#\n" +
+	"\rforce_restart\x18\x02 \x01(\bR\fforceRestart\x12*\n" +
+	"\x11empty_config_hash\x18\x03 \x01(\tR\x0femptyConfigHash\x12:\n" +
+	"\binbounds\x18\x04 \x03(\v2\x1e.remnawave.node.v1.InboundHashR\binbounds\"o\n" +
+	"\x11StartXrayResponse\x12\x1d\n" +
+	"\n" +
+	"is_started\x18\x01 \x01(\bR\tisStarted\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12!\n" +
+	"\fnode_version\x18\x03 \x01(\tR\vnodeVersion\"\x11\n" +
+	"\x0fStopXrayRequest\"1\n" +
+	"\x10StopXrayResponse\x12\x1d\n" +
+	"\n" +
+	"is_stopped\x18\x01 \x01(\bR\tisStopped\"\x12\n" +
+	"\x10GetStatusRequest\"L\n" +
+	"\x11GetStatusResponse\x12\x1d\n" +
+	"\n" +
+	"is_running\x18\x01 \x01(\bR\tisRunning\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\xd2\x01\n" +
+	"\x0eAddUserInbound\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x1a\n" +
+	"\busername\x18\x02 \x01(\tR\busername\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04uuid\x18\x04 \x01(\tR\x04uuid\x12\x12\n" +
+	"\x04flow\x18\x05 \x01(\tR\x04flow\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\x12\x1f\n" +
+	"\vcipher_type\x18\a \x01(\tR\n" +
+	"cipherType\x12\x19\n" +
+	"\biv_check\x18\b \x01(\bR\aivCheck\"\xc6\x01\n" +
+	"\x0eAddUserRequest\x125\n" +
+	"\x04data\x18\x01 \x03(\v2!.remnawave.node.v1.AddUserInboundR\x04data\x12\x1d\n" +
+	"\n" +
+	"vless_uuid\x18\x02 \x01(\tR\tvlessUuid\x12&\n" +
+	"\x0fprev_vless_uuid\x18\x03 \x01(\tR\rprevVlessUuid\x12\x19\n" +
+	"\bip_limit\x18\x04 \x01(\x05R\aipLimit\x12\x1b\n" +
+	"\texpire_at\x18\x05 \x01(\x03R\bexpireAt\"\xa7\x02\n" +
+	"\bBulkUser\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
+	"\thash_uuid\x18\x02 \x01(\tR\bhashUuid\x12\x1d\n" +
+	"\n" +
+	"vless_uuid\x18\x03 \x01(\tR\tvlessUuid\x12\x1d\n" +
+	"\n" +
+	"vmess_uuid\x18\x04 \x01(\tR\tvmessUuid\x12'\n" +
+	"\x0ftrojan_password\x18\x05 \x01(\tR\x0etrojanPassword\x12\x1f\n" +
+	"\vss_password\x18\x06 \x01(\tR\n" +
+	"ssPassword\x12%\n" +
+	"\x0eproxy_password\x18\a \x01(\tR\rproxyPassword\x12\x19\n" +
+	"\bip_limit\x18\b \x01(\x05R\aipLimit\x12\x1b\n" +
+	"\texpire_at\x18\t \x01(\x03R\bexpireAt\"\x83\x01\n" +
+	"\vBulkInbound\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04flow\x18\x03 \x01(\tR\x04flow\x12\x1f\n" +
+	"\vcipher_type\x18\x04 \x01(\tR\n" +
+	"cipherType\x12\x19\n" +
+	"\biv_check\x18\x05 \x01(\bR\aivCheck\"\x8c\x01\n" +
+	"\rBulkUserEntry\x128\n" +
+	"\tuser_data\x18\x01 \x01(\v2\x1b.remnawave.node.v1.BulkUserR\buserData\x12A\n" +
+	"\finbound_data\x18\x02 \x03(\v2\x1e.remnawave.node.v1.BulkInboundR\vinboundData\"}\n" +
+	"\x0fAddUsersRequest\x122\n" +
+	"\x15affected_inbound_tags\x18\x01 \x03(\tR\x13affectedInboundTags\x126\n" +
+	"\x05users\x18\x02 \x03(\v2 .remnawave.node.v1.BulkUserEntryR\x05users\"N\n" +
+	"\x11RemoveUserRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x1d\n" +
+	"\n" +
+	"vless_uuid\x18\x02 \x01(\tR\tvlessUuid\"F\n" +
+	"\x0eBulkRemoveUser\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
+	"\thash_uuid\x18\x02 \x01(\tR\bhashUuid\"M\n" +
+	"\x12RemoveUsersRequest\x127\n" +
+	"\x05users\x18\x01 \x03(\v2!.remnawave.node.v1.BulkRemoveUserR\x05users\"{\n" +
+	"\x13UserOperationResult\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x18\n" +
+	"\ainbound\x18\x02 \x01(\tR\ainbound\x12\x18\n" +
+	"\asuccess\x18\x03 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"s\n" +
+	"\x15UserOperationResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12@\n" +
+	"\aresults\x18\x02 \x03(\v2&.remnawave.node.v1.UserOperationResultR\aresults\",\n" +
+	"\x14GetUsersStatsRequest\x12\x14\n" +
+	"\x05reset\x18\x01 \x01(\bR\x05reset\"[\n" +
+	"\tUserStats\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x16\n" +
+	"\x06uplink\x18\x02 \x01(\x03R\x06uplink\x12\x1a\n" +
+	"\bdownlink\x18\x03 \x01(\x03R\bdownlink\"K\n" +
+	"\x15GetUsersStatsResponse\x122\n" +
+	"\x05users\x18\x01 \x03(\v2\x1c.remnawave.node.v1.UserStatsR\x05users\"\x17\n" +
+	"\x15GetSystemStatsRequest\"\x88\x02\n" +
+	"\x16GetSystemStatsResponse\x12#\n" +
+	"\rnum_goroutine\x18\x01 \x01(\x05R\fnumGoroutine\x12\x15\n" +
+	"\x06num_gc\x18\x02 \x01(\rR\x05numGc\x12\x14\n" +
+	"\x05alloc\x18\x03 \x01(\x04R\x05alloc\x12\x1f\n" +
+	"\vtotal_alloc\x18\x04 \x01(\x04R\n" +
+	"totalAlloc\x12\x10\n" +
+	"\x03sys\x18\x05 \x01(\x04R\x03sys\x12\x18\n" +
+	"\amallocs\x18\x06 \x01(\x04R\amallocs\x12\x14\n" +
+	"\x05frees\x18\a \x01(\x04R\x05frees\x12!\n" +
+	"\flive_objects\x18\b \x01(\x04R\vliveObjects\x12\x16\n" +
+	"\x06uptime\x18\t \x01(\x03R\x06uptime2\xcd\x06\n" +
+	"\vNodeService\x12V\n" +
+	"\tStartXray\x12#.remnawave.node.v1.StartXrayRequest\x1a$.remnawave.node.v1.StartXrayResponse\x12S\n" +
+	"\bStopXray\x12\".remnawave.node.v1.StopXrayRequest\x1a#.remnawave.node.v1.StopXrayResponse\x12V\n" +
+	"\tGetStatus\x12#.remnawave.node.v1.GetStatusRequest\x1a$.remnawave.node.v1.GetStatusResponse\x12V\n" +
+	"\aAddUser\x12!.remnawave.node.v1.AddUserRequest\x1a(.remnawave.node.v1.UserOperationResponse\x12X\n" +
+	"\bAddUsers\x12\".remnawave.node.v1.AddUsersRequest\x1a(.remnawave.node.v1.UserOperationResponse\x12\\\n" +
+	"\n" +
+	"RemoveUser\x12$.remnawave.node.v1.RemoveUserRequest\x1a(.remnawave.node.v1.UserOperationResponse\x12^\n" +
+	"\vRemoveUsers\x12%.remnawave.node.v1.RemoveUsersRequest\x1a(.remnawave.node.v1.UserOperationResponse\x12b\n" +
+	"\rGetUsersStats\x12'.remnawave.node.v1.GetUsersStatsRequest\x1a(.remnawave.node.v1.GetUsersStatsResponse\x12e\n" +
+	"\x0eGetSystemStats\x12(.remnawave.node.v1.GetSystemStatsRequest\x1a).remnawave.node.v1.GetSystemStatsResponseB6Z4github.com/remnawave/node-go/internal/grpcapi/nodepbb\x06proto3"
+
+var (
+	file_internal_grpcapi_nodepb_node_proto_rawDescOnce sync.Once
+	file_internal_grpcapi_nodepb_node_proto_rawDescData []byte
+)
+
+func file_internal_grpcapi_nodepb_node_proto_rawDescGZIP() []byte {
+	file_internal_grpcapi_nodepb_node_proto_rawDescOnce.Do(func() {
+		file_internal_grpcapi_nodepb_node_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_internal_grpcapi_nodepb_node_proto_rawDesc), len(file_internal_grpcapi_nodepb_node_proto_rawDesc)))
+	})
+	return file_internal_grpcapi_nodepb_node_proto_rawDescData
+}
+
+var file_internal_grpcapi_nodepb_node_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_internal_grpcapi_nodepb_node_proto_goTypes = []any{
+	(*InboundHash)(nil),            // 0: remnawave.node.v1.InboundHash
+	(*StartXrayRequest)(nil),       // 1: remnawave.node.v1.StartXrayRequest
+	(*StartXrayResponse)(nil),      // 2: remnawave.node.v1.StartXrayResponse
+	(*StopXrayRequest)(nil),        // 3: remnawave.node.v1.StopXrayRequest
+	(*StopXrayResponse)(nil),       // 4: remnawave.node.v1.StopXrayResponse
+	(*GetStatusRequest)(nil),       // 5: remnawave.node.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),      // 6: remnawave.node.v1.GetStatusResponse
+	(*AddUserInbound)(nil),         // 7: remnawave.node.v1.AddUserInbound
+	(*AddUserRequest)(nil),         // 8: remnawave.node.v1.AddUserRequest
+	(*BulkUser)(nil),               // 9: remnawave.node.v1.BulkUser
+	(*BulkInbound)(nil),            // 10: remnawave.node.v1.BulkInbound
+	(*BulkUserEntry)(nil),          // 11: remnawave.node.v1.BulkUserEntry
+	(*AddUsersRequest)(nil),        // 12: remnawave.node.v1.AddUsersRequest
+	(*RemoveUserRequest)(nil),      // 13: remnawave.node.v1.RemoveUserRequest
+	(*BulkRemoveUser)(nil),         // 14: remnawave.node.v1.BulkRemoveUser
+	(*RemoveUsersRequest)(nil),     // 15: remnawave.node.v1.RemoveUsersRequest
+	(*UserOperationResult)(nil),    // 16: remnawave.node.v1.UserOperationResult
+	(*UserOperationResponse)(nil),  // 17: remnawave.node.v1.UserOperationResponse
+	(*GetUsersStatsRequest)(nil),   // 18: remnawave.node.v1.GetUsersStatsRequest
+	(*UserStats)(nil),              // 19: remnawave.node.v1.UserStats
+	(*GetUsersStatsResponse)(nil),  // 20: remnawave.node.v1.GetUsersStatsResponse
+	(*GetSystemStatsRequest)(nil),  // 21: remnawave.node.v1.GetSystemStatsRequest
+	(*GetSystemStatsResponse)(nil), // 22: remnawave.node.v1.GetSystemStatsResponse
+}
+var file_internal_grpcapi_nodepb_node_proto_depIdxs = []int32{
+	0,  // 0: remnawave.node.v1.StartXrayRequest.inbounds:type_name -> remnawave.node.v1.InboundHash
+	7,  // 1: remnawave.node.v1.AddUserRequest.data:type_name -> remnawave.node.v1.AddUserInbound
+	9,  // 2: remnawave.node.v1.BulkUserEntry.user_data:type_name -> remnawave.node.v1.BulkUser
+	10, // 3: remnawave.node.v1.BulkUserEntry.inbound_data:type_name -> remnawave.node.v1.BulkInbound
+	11, // 4: remnawave.node.v1.AddUsersRequest.users:type_name -> remnawave.node.v1.BulkUserEntry
+	14, // 5: remnawave.node.v1.RemoveUsersRequest.users:type_name -> remnawave.node.v1.BulkRemoveUser
+	16, // 6: remnawave.node.v1.UserOperationResponse.results:type_name -> remnawave.node.v1.UserOperationResult
+	19, // 7: remnawave.node.v1.GetUsersStatsResponse.users:type_name -> remnawave.node.v1.UserStats
+	1,  // 8: remnawave.node.v1.NodeService.StartXray:input_type -> remnawave.node.v1.StartXrayRequest
+	3,  // 9: remnawave.node.v1.NodeService.StopXray:input_type -> remnawave.node.v1.StopXrayRequest
+	5,  // 10: remnawave.node.v1.NodeService.GetStatus:input_type -> remnawave.node.v1.GetStatusRequest
+	8,  // 11: remnawave.node.v1.NodeService.AddUser:input_type -> remnawave.node.v1.AddUserRequest
+	12, // 12: remnawave.node.v1.NodeService.AddUsers:input_type -> remnawave.node.v1.AddUsersRequest
+	13, // 13: remnawave.node.v1.NodeService.RemoveUser:input_type -> remnawave.node.v1.RemoveUserRequest
+	15, // 14: remnawave.node.v1.NodeService.RemoveUsers:input_type -> remnawave.node.v1.RemoveUsersRequest
+	18, // 15: remnawave.node.v1.NodeService.GetUsersStats:input_type -> remnawave.node.v1.GetUsersStatsRequest
+	21, // 16: remnawave.node.v1.NodeService.GetSystemStats:input_type -> remnawave.node.v1.GetSystemStatsRequest
+	2,  // 17: remnawave.node.v1.NodeService.StartXray:output_type -> remnawave.node.v1.StartXrayResponse
+	4,  // 18: remnawave.node.v1.NodeService.StopXray:output_type -> remnawave.node.v1.StopXrayResponse
+	6,  // 19: remnawave.node.v1.NodeService.GetStatus:output_type -> remnawave.node.v1.GetStatusResponse
+	17, // 20: remnawave.node.v1.NodeService.AddUser:output_type -> remnawave.node.v1.UserOperationResponse
+	17, // 21: remnawave.node.v1.NodeService.AddUsers:output_type -> remnawave.node.v1.UserOperationResponse
+	17, // 22: remnawave.node.v1.NodeService.RemoveUser:output_type -> remnawave.node.v1.UserOperationResponse
+	17, // 23: remnawave.node.v1.NodeService.RemoveUsers:output_type -> remnawave.node.v1.UserOperationResponse
+	20, // 24: remnawave.node.v1.NodeService.GetUsersStats:output_type -> remnawave.node.v1.GetUsersStatsResponse
+	22, // 25: remnawave.node.v1.NodeService.GetSystemStats:output_type -> remnawave.node.v1.GetSystemStatsResponse
+	17, // [17:26] is the sub-list for method output_type
+	8,  // [8:17] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_internal_grpcapi_nodepb_node_proto_init() }
+func file_internal_grpcapi_nodepb_node_proto_init() {
+	if File_internal_grpcapi_nodepb_node_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_grpcapi_nodepb_node_proto_rawDesc), len(file_internal_grpcapi_nodepb_node_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_internal_grpcapi_nodepb_node_proto_goTypes,
+		DependencyIndexes: file_internal_grpcapi_nodepb_node_proto_depIdxs,
+		MessageInfos:      file_internal_grpcapi_nodepb_node_proto_msgTypes,
+	}.Build()
+	File_internal_grpcapi_nodepb_node_proto = out.File
+	file_internal_grpcapi_nodepb_node_proto_goTypes = nil
+	file_internal_grpcapi_nodepb_node_proto_depIdxs = nil
+}
@@ -0,0 +1,150 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/remnawave/node-go/internal/api/middleware"
+	"github.com/remnawave/node-go/internal/grpcapi/nodepb"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/telemetry"
+)
+
+// NewServer creates a gRPC server serving the node service over mTLS.
+// Every call must carry the panel JWT in the "authorization" metadata,
+// in the same "Bearer <token>" form as the REST API. Scoped tokens are
+// checked against the policy with the full method name as route.
+func NewServer(service *Service, tlsConfig *tls.Config, validator *middleware.TokenValidator, policy *middleware.ScopePolicy, log *logger.Logger) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		grpc.ChainUnaryInterceptor(TracingInterceptor(), AuthInterceptor(validator, policy, log)),
+	)
+	nodepb.RegisterNodeServiceServer(server, service)
+
+	return server
+}
+
+// AuthInterceptor rejects calls without a valid panel JWT, or whose token
+// scopes do not grant the method.
+func AuthInterceptor(validator *middleware.TokenValidator, policy *middleware.ScopePolicy, log *logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			logAuthFailure(log, info.FullMethod, "missing authorization metadata")
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+
+		claims, err := validator.ValidateHeader(values[0])
+		if err != nil {
+			logAuthFailure(log, info.FullMethod, err.Error())
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		if !policy.Allows(claims, info.FullMethod) {
+			return nil, status.Error(codes.PermissionDenied, "token scope does not grant this method")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// TracingInterceptor starts a server span for each call, continuing the
+// trace of the panel when the metadata carries a traceparent, and records
+// the call duration.
+func TracingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		ctx, span := telemetry.Tracer().Start(ctx, info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("rpc.system", "grpc"),
+				attribute.String("rpc.method", info.FullMethod),
+			))
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+		if err != nil {
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		telemetry.RecordRequest(ctx, "grpc", info.FullMethod, code.String(), time.Since(start))
+		return resp, err
+	}
+}
+
+// metadataCarrier reads the trace context from incoming gRPC metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func logAuthFailure(log *logger.Logger, method, reason string) {
+	if log != nil {
+		log.WithField("method", method).
+			WithField("reason", reason).
+			Error("Incorrect SECRET_KEY or JWT! gRPC call rejected.")
+	}
+}
+
+// statusFromHTTP converts the HTTP status returned by a controller into a
+// gRPC status error. A 2xx status yields nil.
+func statusFromHTTP(httpStatus int, errMsg *string) error {
+	if httpStatus >= 200 && httpStatus < 300 {
+		return nil
+	}
+
+	msg := http.StatusText(httpStatus)
+	if errMsg != nil {
+		msg = *errMsg
+	}
+
+	var code codes.Code
+	switch httpStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.Aborted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+
+	return status.Error(code, msg)
+}
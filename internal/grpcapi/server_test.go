@@ -0,0 +1,206 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/api/middleware"
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/expiry"
+	"github.com/remnawave/node-go/internal/grpcapi/nodepb"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/iplimit"
+	"github.com/remnawave/node-go/internal/jobs"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/version"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// setupTestClient serves the node service over an in-memory listener
+// without TLS and returns a client plus a valid bearer token.
+func setupTestClient(t *testing.T) (nodepb.NodeServiceClient, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(key)
+	require.NoError(t, err)
+
+	validator, err := middleware.NewTokenValidator(string(pubPEM))
+	require.NoError(t, err)
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	configMgr := xray.NewConfigManager(log)
+	store := state.NewMemoryStore()
+	limiter := iplimit.NewLimiter(core, vision.NewBlocklist(core, store, log), store, log)
+
+	service := NewService(
+		controller.NewXrayController(core, configMgr, nil, config.DefaultAPIPort, nil, jobs.NewManager(log), log),
+		controller.NewHandlerController(core, configMgr, limiter, expiry.NewScheduler(store, log), events.NewBus(), nil, jobs.NewManager(log), 1, log),
+		controller.NewStatsController(core, history.NewRecorder(core, 0, log), checkpoint.New(core, store, log), log),
+		log,
+	)
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(AuthInterceptor(validator, nil, log)))
+	nodepb.RegisterNodeServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Stop()
+		core.Stop()
+	})
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return nodepb.NewNodeServiceClient(conn), token
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	client, token := setupTestClient(t)
+
+	_, err := client.GetStatus(context.Background(), &nodepb.GetStatusRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetStatus(withToken("not-a-jwt"), &nodepb.GetStatusRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	resp, err := client.GetStatus(withToken(token), &nodepb.GetStatusRequest{})
+	require.NoError(t, err)
+	assert.False(t, resp.IsRunning)
+}
+
+func TestAuthInterceptor_Scopes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	validator, err := middleware.NewTokenValidator(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})))
+	require.NoError(t, err)
+	policy, err := middleware.ParseScopePolicy("monitor=/node.NodeService/GetStatus,/node.NodeService/GetUsersStats")
+	require.NoError(t, err)
+
+	interceptor := AuthInterceptor(validator, policy, nil)
+	call := func(scope interface{}, method string) error {
+		claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+		if scope != nil {
+			claims["scope"] = scope
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		require.NoError(t, err)
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	assert.NoError(t, call("monitor", "/node.NodeService/GetStatus"))
+	assert.Equal(t, codes.PermissionDenied, status.Code(call("monitor", "/node.NodeService/StartXray")))
+	assert.NoError(t, call(nil, "/node.NodeService/StartXray"))
+}
+
+func TestService_UserOperationsWithoutCore(t *testing.T) {
+	client, token := setupTestClient(t)
+	ctx := withToken(token)
+
+	_, err := client.AddUser(ctx, &nodepb.AddUserRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.AddUser(ctx, &nodepb.AddUserRequest{
+		Data: []*nodepb.AddUserInbound{{Tag: "vless-in", Username: "alice", Type: "vless"}},
+	})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = client.RemoveUser(ctx, &nodepb.RemoveUserRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := client.RemoveUsers(ctx, &nodepb.RemoveUsersRequest{})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+}
+
+func TestService_StartStopXray(t *testing.T) {
+	client, token := setupTestClient(t)
+	ctx := withToken(token)
+
+	_, err := client.StartXray(ctx, &nodepb.StartXrayRequest{XrayConfig: []byte("[]")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// An explicit api inbound on a random port keeps the test off the default API port.
+	config := `{"log":{"loglevel":"none"},` +
+		`"inbounds":[{"tag":"api","listen":"127.0.0.1","port":0,"protocol":"dokodemo-door","settings":{"address":"127.0.0.1"}}],` +
+		`"outbounds":[{"tag":"direct","protocol":"freedom"}]}`
+	started, err := client.StartXray(ctx, &nodepb.StartXrayRequest{XrayConfig: []byte(config)})
+	require.NoError(t, err)
+	assert.True(t, started.IsStarted)
+	assert.NotEmpty(t, started.Version)
+	assert.Equal(t, version.Version, started.NodeVersion)
+
+	statusResp, err := client.GetStatus(ctx, &nodepb.GetStatusRequest{})
+	require.NoError(t, err)
+	assert.True(t, statusResp.IsRunning)
+
+	stats, err := client.GetUsersStats(ctx, &nodepb.GetUsersStatsRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, stats.Users)
+
+	sys, err := client.GetSystemStats(ctx, &nodepb.GetSystemStatsRequest{})
+	require.NoError(t, err)
+	assert.Greater(t, sys.NumGoroutine, int32(0))
+
+	stopped, err := client.StopXray(ctx, &nodepb.StopXrayRequest{})
+	require.NoError(t, err)
+	assert.True(t, stopped.IsStopped)
+}
+
+func TestStatusFromHTTP(t *testing.T) {
+	errMsg := "boom"
+
+	assert.NoError(t, statusFromHTTP(http.StatusOK, nil))
+	assert.Equal(t, codes.InvalidArgument, status.Code(statusFromHTTP(http.StatusBadRequest, &errMsg)))
+	assert.Equal(t, codes.Aborted, status.Code(statusFromHTTP(http.StatusConflict, &errMsg)))
+	assert.Equal(t, codes.Unavailable, status.Code(statusFromHTTP(http.StatusServiceUnavailable, &errMsg)))
+	assert.Equal(t, codes.Internal, status.Code(statusFromHTTP(http.StatusInternalServerError, nil)))
+	assert.Equal(t, "boom", status.Convert(statusFromHTTP(http.StatusInternalServerError, &errMsg)).Message())
+}
@@ -0,0 +1,291 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/grpcapi/nodepb"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/telemetry"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// Service implements nodepb.NodeServiceServer on top of the REST
+// controllers, so both APIs share the same behaviour and state.
+type Service struct {
+	nodepb.UnimplementedNodeServiceServer
+
+	xray    *controller.XrayController
+	handler *controller.HandlerController
+	stats   *controller.StatsController
+	logger  *logger.Logger
+}
+
+func NewService(xrayController *controller.XrayController, handlerController *controller.HandlerController, statsController *controller.StatsController, log *logger.Logger) *Service {
+	return &Service{
+		xray:    xrayController,
+		handler: handlerController,
+		stats:   statsController,
+		logger:  log,
+	}
+}
+
+func (s *Service) StartXray(ctx context.Context, in *nodepb.StartXrayRequest) (*nodepb.StartXrayResponse, error) {
+	var xrayConfig map[string]interface{}
+	if err := json.Unmarshal(in.GetXrayConfig(), &xrayConfig); err != nil || xrayConfig == nil {
+		errMsg := "invalid xray_config: must be a JSON object"
+		return nil, statusFromHTTP(http.StatusBadRequest, &errMsg)
+	}
+
+	req := controller.StartRequest{
+		XrayConfig: xrayConfig,
+		Internals: xray.Internals{
+			ForceRestart: in.GetForceRestart(),
+			Hashes: xray.Hashes{
+				EmptyConfig: in.GetEmptyConfigHash(),
+				Inbounds:    make([]xray.InboundHash, 0, len(in.GetInbounds())),
+			},
+		},
+	}
+	for _, ib := range in.GetInbounds() {
+		req.Internals.Hashes.Inbounds = append(req.Internals.Hashes.Inbounds, xray.InboundHash{
+			Tag:        ib.GetTag(),
+			Hash:       ib.GetHash(),
+			UsersCount: int(ib.GetUsersCount()),
+		})
+	}
+
+	_, end := telemetry.Track(ctx, "xray.start")
+	resp, httpStatus := s.xray.Start(req)
+	end(httpStatus)
+	if err := statusFromHTTP(httpStatus, resp.Error); err != nil {
+		return nil, err
+	}
+
+	out := &nodepb.StartXrayResponse{
+		IsStarted:   resp.IsStarted,
+		NodeVersion: resp.NodeInfo.Version,
+	}
+	if resp.Version != nil {
+		out.Version = *resp.Version
+	}
+	return out, nil
+}
+
+func (s *Service) StopXray(ctx context.Context, in *nodepb.StopXrayRequest) (*nodepb.StopXrayResponse, error) {
+	_, end := telemetry.Track(ctx, "xray.stop")
+	resp, httpStatus := s.xray.Stop()
+	end(httpStatus)
+	if err := statusFromHTTP(httpStatus, nil); err != nil {
+		return nil, err
+	}
+
+	return &nodepb.StopXrayResponse{IsStopped: resp.IsStopped}, nil
+}
+
+func (s *Service) GetStatus(ctx context.Context, in *nodepb.GetStatusRequest) (*nodepb.GetStatusResponse, error) {
+	resp := s.xray.Status()
+
+	out := &nodepb.GetStatusResponse{IsRunning: resp.IsRunning}
+	if resp.Version != nil {
+		out.Version = *resp.Version
+	}
+	return out, nil
+}
+
+func (s *Service) AddUser(ctx context.Context, in *nodepb.AddUserRequest) (*nodepb.UserOperationResponse, error) {
+	req := controller.AddUserRequest{
+		Data: make([]controller.AddUserInboundData, 0, len(in.GetData())),
+		HashData: controller.AddUserHashData{
+			VlessUUID:     in.GetVlessUuid(),
+			PrevVlessUUID: in.GetPrevVlessUuid(),
+		},
+		IPLimit:  int(in.GetIpLimit()),
+		ExpireAt: unixTime(in.GetExpireAt()),
+	}
+	for _, d := range in.GetData() {
+		req.Data = append(req.Data, controller.AddUserInboundData{
+			Tag:        d.GetTag(),
+			Username:   d.GetUsername(),
+			Type:       d.GetType(),
+			UUID:       d.GetUuid(),
+			Flow:       d.GetFlow(),
+			Password:   d.GetPassword(),
+			CipherType: d.GetCipherType(),
+			IVCheck:    d.GetIvCheck(),
+		})
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+
+	_, end := telemetry.Track(ctx, "user.add")
+	resp, httpStatus := s.handler.AddUser(req)
+	end(httpStatus)
+	return userOperation(resp, httpStatus)
+}
+
+func (s *Service) AddUsers(ctx context.Context, in *nodepb.AddUsersRequest) (*nodepb.UserOperationResponse, error) {
+	req := controller.AddUsersRequest{
+		AffectedInboundTags: in.GetAffectedInboundTags(),
+		Users:               make([]controller.BulkUserEntry, 0, len(in.GetUsers())),
+	}
+	for _, u := range in.GetUsers() {
+		ud := u.GetUserData()
+		entry := controller.BulkUserEntry{
+			UserData: controller.BulkUserData{
+				UserID:         ud.GetUserId(),
+				HashUUID:       ud.GetHashUuid(),
+				VlessUUID:      ud.GetVlessUuid(),
+				VmessUUID:      ud.GetVmessUuid(),
+				TrojanPassword: ud.GetTrojanPassword(),
+				SSPassword:     ud.GetSsPassword(),
+				IPLimit:        int(ud.GetIpLimit()),
+				ExpireAt:       unixTime(ud.GetExpireAt()),
+			},
+			InboundData: make([]controller.BulkInboundData, 0, len(u.GetInboundData())),
+		}
+		for _, ib := range u.GetInboundData() {
+			entry.InboundData = append(entry.InboundData, controller.BulkInboundData{
+				Tag:        ib.GetTag(),
+				Type:       ib.GetType(),
+				Flow:       ib.GetFlow(),
+				CipherType: ib.GetCipherType(),
+				IVCheck:    ib.GetIvCheck(),
+			})
+		}
+		req.Users = append(req.Users, entry)
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+
+	_, end := telemetry.Track(ctx, "users.add", attribute.Int("users", len(req.Users)))
+	resp, httpStatus := s.handler.AddUsers(req)
+	end(httpStatus)
+	return bulkUserOperation(resp, httpStatus)
+}
+
+func (s *Service) RemoveUser(ctx context.Context, in *nodepb.RemoveUserRequest) (*nodepb.UserOperationResponse, error) {
+	req := controller.RemoveUserRequest{
+		Username: in.GetUsername(),
+		HashData: controller.RemoveUserHashData{VlessUUID: in.GetVlessUuid()},
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+
+	_, end := telemetry.Track(ctx, "user.remove")
+	resp, httpStatus := s.handler.RemoveUser(req)
+	end(httpStatus)
+	return userOperation(resp, httpStatus)
+}
+
+func (s *Service) RemoveUsers(ctx context.Context, in *nodepb.RemoveUsersRequest) (*nodepb.UserOperationResponse, error) {
+	req := controller.RemoveUsersRequest{
+		Users: make([]controller.BulkRemoveUserEntry, 0, len(in.GetUsers())),
+	}
+	for _, u := range in.GetUsers() {
+		req.Users = append(req.Users, controller.BulkRemoveUserEntry{
+			UserID:   u.GetUserId(),
+			HashUUID: u.GetHashUuid(),
+		})
+	}
+	if err := validate(&req); err != nil {
+		return nil, err
+	}
+
+	_, end := telemetry.Track(ctx, "users.remove", attribute.Int("users", len(req.Users)))
+	resp, httpStatus := s.handler.RemoveUsers(req)
+	end(httpStatus)
+	return bulkUserOperation(resp, httpStatus)
+}
+
+func (s *Service) GetUsersStats(ctx context.Context, in *nodepb.GetUsersStatsRequest) (*nodepb.GetUsersStatsResponse, error) {
+	resp := s.stats.UsersStats(in.GetReset_())
+
+	out := &nodepb.GetUsersStatsResponse{
+		Users: make([]*nodepb.UserStats, 0, len(resp.Users)),
+	}
+	for _, u := range resp.Users {
+		out.Users = append(out.Users, &nodepb.UserStats{
+			Username: u.Username,
+			Uplink:   u.Uplink,
+			Downlink: u.Downlink,
+		})
+	}
+	return out, nil
+}
+
+func (s *Service) GetSystemStats(ctx context.Context, in *nodepb.GetSystemStatsRequest) (*nodepb.GetSystemStatsResponse, error) {
+	resp := s.stats.SystemStats()
+
+	return &nodepb.GetSystemStatsResponse{
+		NumGoroutine: int32(resp.NumGoroutine),
+		NumGc:        resp.NumGC,
+		Alloc:        resp.Alloc,
+		TotalAlloc:   resp.TotalAlloc,
+		Sys:          resp.Sys,
+		Mallocs:      resp.Mallocs,
+		Frees:        resp.Frees,
+		LiveObjects:  resp.LiveObjects,
+		Uptime:       resp.Uptime,
+	}, nil
+}
+
+// validate applies the same binding rules the REST API enforces.
+func validate(req any) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid request: "+err.Error())
+	}
+	return nil
+}
+
+func userOperation(resp controller.AddUserResponseData, httpStatus int) (*nodepb.UserOperationResponse, error) {
+	if err := statusFromHTTP(httpStatus, resp.Error); err != nil {
+		return nil, err
+	}
+	return &nodepb.UserOperationResponse{Success: resp.Success}, nil
+}
+
+// bulkUserOperation reports partial failures in the results rather than as
+// an error, so the caller can retry only the failed entries.
+func bulkUserOperation(resp controller.BulkUsersResponseData, httpStatus int) (*nodepb.UserOperationResponse, error) {
+	if err := statusFromHTTP(httpStatus, resp.Error); err != nil {
+		return nil, err
+	}
+
+	out := &nodepb.UserOperationResponse{
+		Success: resp.Success,
+		Results: make([]*nodepb.UserOperationResult, 0, len(resp.Results)),
+	}
+	for _, result := range resp.Results {
+		r := &nodepb.UserOperationResult{
+			Username: result.Username,
+			Inbound:  result.Inbound,
+			Success:  result.Success,
+		}
+		if result.Error != nil {
+			r.Error = *result.Error
+		}
+		out.Results = append(out.Results, r)
+	}
+	return out, nil
+}
+
+// unixTime converts optional unix seconds, where 0 means unset.
+func unixTime(seconds int64) *time.Time {
+	if seconds <= 0 {
+		return nil
+	}
+	t := time.Unix(seconds, 0)
+	return &t
+}
@@ -0,0 +1,308 @@
+// Package handover passes the listening sockets of the node on to a new
+// process of the node binary, so the binary can be upgraded without the
+// panel ever finding its ports closed.
+package handover
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// envListeners maps listener names to inherited descriptors, e.g.
+	// "main=3,grpc=4".
+	envListeners = "REMNANODE_HANDOVER_LISTENERS"
+	// envReady is the descriptor the new process reports readiness on.
+	envReady = "REMNANODE_HANDOVER_READY"
+
+	// firstExtraFD is the descriptor of the first of exec.Cmd.ExtraFiles.
+	firstExtraFD = 3
+)
+
+var (
+	ErrUpgrading = errors.New("an upgrade is already in progress")
+	ErrNotReady  = errors.New("new process did not become ready")
+)
+
+// Handover keeps the listeners of the process by name. Listeners inherited
+// from the process that started this one are reused, the others opened.
+type Handover struct {
+	mu        sync.Mutex
+	inherited map[string]*os.File
+	ready     *os.File
+	names     []string
+	listeners map[string]net.Listener
+	upgrading bool
+}
+
+// New returns the handover of this process, taking over the listeners
+// passed on by a previous process, if any.
+func New() (*Handover, error) {
+	h := &Handover{
+		inherited: make(map[string]*os.File),
+		listeners: make(map[string]net.Listener),
+	}
+
+	if v := os.Getenv(envListeners); v != "" {
+		for _, entry := range strings.Split(v, ",") {
+			name, fdStr, ok := strings.Cut(entry, "=")
+			fd, err := strconv.Atoi(fdStr)
+			if !ok || err != nil || fd < firstExtraFD {
+				return nil, fmt.Errorf("invalid %s entry %q", envListeners, entry)
+			}
+			h.inherited[name] = os.NewFile(uintptr(fd), name)
+		}
+	}
+	if v := os.Getenv(envReady); v != "" {
+		fd, err := strconv.Atoi(v)
+		if err != nil || fd < firstExtraFD {
+			return nil, fmt.Errorf("invalid %s %q", envReady, v)
+		}
+		h.ready = os.NewFile(uintptr(fd), "ready")
+	}
+
+	// Processes started by this one get their own.
+	os.Unsetenv(envListeners)
+	os.Unsetenv(envReady)
+
+	return h, nil
+}
+
+// Inherits reports whether a listener called name was passed on to this
+// process and not yet taken by Listen.
+func (h *Handover) Inherits(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.inherited[name]
+	return ok
+}
+
+// Listen returns the listener called name: the one inherited under that
+// name, or a new one on network and address. The listener is passed on by
+// the next upgrade.
+func (h *Handover) Listen(name, network, address string) (net.Listener, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.listeners[name]; exists {
+		return nil, fmt.Errorf("listener %s already open", name)
+	}
+
+	var listener net.Listener
+	if f, ok := h.inherited[name]; ok {
+		delete(h.inherited, name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to use inherited listener %s: %w", name, err)
+		}
+		if l.Addr().Network() != network {
+			l.Close()
+			return nil, fmt.Errorf("inherited listener %s is %s, not %s", name, l.Addr().Network(), network)
+		}
+		listener = l
+	} else {
+		l, err := net.Listen(network, address)
+		if err != nil {
+			return nil, err
+		}
+		listener = l
+	}
+
+	h.names = append(h.names, name)
+	h.listeners[name] = listener
+	return listener, nil
+}
+
+// Ready tells the process that started this one that it has taken over,
+// and closes the inherited listeners left unused. It does nothing in a
+// process not started by an upgrade.
+func (h *Handover) Ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for name, f := range h.inherited {
+		f.Close()
+		delete(h.inherited, name)
+	}
+
+	if h.ready == nil {
+		return nil
+	}
+	_, err := h.ready.Write([]byte{1})
+	h.ready.Close()
+	h.ready = nil
+	return err
+}
+
+// Upgrade starts the executable of this process again, with the same
+// arguments and environment, passing on the listeners, and waits up to
+// timeout for it to call Ready.
+//
+// release is called once the listeners are duplicated for the new process
+// and before it starts, to stop serving on them: the sockets stay open and
+// connections wait in their backlog until the new process accepts them. On
+// success the caller should shut down, leaving alone whatever it shares
+// with the new process. On failure the new process is killed and the
+// duplicates are kept for Listen, under the same names, to serve again.
+func (h *Handover) Upgrade(timeout time.Duration, release func()) (int, error) {
+	h.mu.Lock()
+	if h.upgrading {
+		h.mu.Unlock()
+		return 0, ErrUpgrading
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		h.mu.Unlock()
+		return 0, fmt.Errorf("failed to find the executable: %w", err)
+	}
+
+	names := h.names
+	files := make([]*os.File, 0, len(names))
+	mapping := make([]string, 0, len(names))
+	for _, name := range names {
+		f, err := listenerFile(h.listeners[name])
+		if err != nil {
+			h.mu.Unlock()
+			closeFiles(files)
+			return 0, fmt.Errorf("failed to pass on listener %s: %w", name, err)
+		}
+		mapping = append(mapping, fmt.Sprintf("%s=%d", name, firstExtraFD+len(files)))
+		files = append(files, f)
+	}
+	for _, listener := range h.listeners {
+		// The socket file stays, for the new process or for this one to
+		// serve again.
+		if l, ok := listener.(*net.UnixListener); ok {
+			l.SetUnlinkOnClose(false)
+		}
+	}
+	h.names = nil
+	h.listeners = make(map[string]net.Listener)
+	h.upgrading = true
+	h.mu.Unlock()
+
+	release()
+
+	pid, err := start(exe, files, mapping, timeout)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.upgrading = false
+	if err != nil {
+		for i, name := range names {
+			h.inherited[name] = files[i]
+		}
+		return 0, err
+	}
+	closeFiles(files)
+	return pid, nil
+}
+
+// start runs exe with files as its inherited listeners and waits for it to
+// be ready.
+func start(exe string, files []*os.File, mapping []string, timeout time.Duration) (int, error) {
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ready pipe: %w", err)
+	}
+	defer readyR.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(),
+		envListeners+"="+strings.Join(mapping, ","),
+		fmt.Sprintf("%s=%d", envReady, firstExtraFD+len(files)),
+	)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(append([]*os.File{}, files...), readyW)
+
+	err = cmd.Start()
+	// Only the new process holds the write end now, so its exit ends the
+	// read below.
+	readyW.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", filepath.Base(exe), err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := io.ReadFull(readyR, buf)
+		result <- err
+	}()
+
+	select {
+	case err = <-result:
+	case <-time.After(timeout):
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		waitErr := cmd.Wait()
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("exited: %v", waitErr)
+		}
+		return 0, fmt.Errorf("%w: %v", ErrNotReady, err)
+	}
+
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+	return pid, nil
+}
+
+// listenerFile returns a duplicate of the descriptor of listener.
+func listenerFile(listener net.Listener) (*os.File, error) {
+	l, ok := listener.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("%T has no file descriptor", listener)
+	}
+	return l.File()
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// WritePIDFile atomically replaces the file at path with the PID of this
+// process.
+func WritePIDFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := fmt.Fprintf(tmp, "%d\n", os.Getpid()); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write PID file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+//go:build !windows
+
+package handover
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain runs the test binary as the new process of an upgrade when it
+// is started by one: it takes over the "main" listener and answers one
+// connection with "new". With HANDOVER_TEST_FAIL=1 it exits instead.
+func TestMain(m *testing.M) {
+	if os.Getenv(envListeners) == "" {
+		os.Exit(m.Run())
+	}
+
+	if os.Getenv("HANDOVER_TEST_FAIL") == "1" {
+		os.Exit(1)
+	}
+	h, err := New()
+	if err != nil {
+		os.Exit(2)
+	}
+	l, err := h.Listen("main", "tcp", "")
+	if err != nil {
+		os.Exit(3)
+	}
+	if err := h.Ready(); err != nil {
+		os.Exit(4)
+	}
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := l.Accept()
+	if err != nil {
+		os.Exit(5)
+	}
+	conn.Write([]byte("new\n"))
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestNew_TakesOverListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	require.NoError(t, err)
+	// New takes ownership of the descriptor.
+	fd, err := syscall.Dup(int(f.Fd()))
+	require.NoError(t, err)
+	f.Close()
+
+	t.Setenv(envListeners, "main="+strconv.Itoa(fd))
+	h, err := New()
+	require.NoError(t, err)
+	assert.Empty(t, os.Getenv(envListeners))
+	assert.True(t, h.Inherits("main"))
+	assert.False(t, h.Inherits("grpc"))
+
+	inherited, err := h.Listen("main", "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer inherited.Close()
+	assert.Equal(t, l.Addr().String(), inherited.Addr().String())
+	assert.False(t, h.Inherits("main"))
+
+	_, err = h.Listen("main", "tcp", "127.0.0.1:0")
+	assert.Error(t, err)
+	require.NoError(t, h.Ready())
+}
+
+func TestNew_InvalidEnv(t *testing.T) {
+	t.Setenv(envListeners, "main=x")
+	_, err := New()
+	assert.Error(t, err)
+}
+
+func TestUpgrade(t *testing.T) {
+	h, err := New()
+	require.NoError(t, err)
+	l, err := h.Listen("main", "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+
+	released := false
+	pid, err := h.Upgrade(30*time.Second, func() {
+		released = true
+		l.Close()
+	})
+	require.NoError(t, err)
+	assert.True(t, released)
+	assert.NotZero(t, pid)
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", line)
+}
+
+func TestUpgrade_FailureKeepsListeners(t *testing.T) {
+	t.Setenv("HANDOVER_TEST_FAIL", "1")
+
+	h, err := New()
+	require.NoError(t, err)
+	l, err := h.Listen("main", "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+
+	_, err = h.Upgrade(30*time.Second, func() { l.Close() })
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	require.True(t, h.Inherits("main"))
+	again, err := h.Listen("main", "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer again.Close()
+	assert.Equal(t, addr, again.Addr().String())
+}
@@ -0,0 +1,220 @@
+package history
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// DefaultSize keeps one day of per-minute snapshots.
+const DefaultSize = 1440
+
+const sampleInterval = time.Minute
+
+// Traffic is the number of bytes transferred in each direction.
+type Traffic struct {
+	Uplink   int64 `json:"uplink"`
+	Downlink int64 `json:"downlink"`
+}
+
+// Snapshot holds the traffic recorded during one sampling interval,
+// ending at Time. Entries without traffic are omitted.
+type Snapshot struct {
+	Time      time.Time          `json:"time"`
+	Inbounds  map[string]Traffic `json:"inbounds"`
+	Outbounds map[string]Traffic `json:"outbounds"`
+	Users     map[string]Traffic `json:"users"`
+}
+
+// Recorder keeps a fixed-size ring buffer of traffic snapshots so the panel
+// can recover data it missed while it was down.
+//
+// Snapshots hold deltas between samples. When a counter goes down (the panel
+// reset it or xray restarted), its current value is taken as the delta.
+type Recorder struct {
+	mu        sync.RWMutex
+	snapshots []Snapshot
+	next      int
+	full      bool
+	last      map[string]int64
+	core      *xray.Core
+	log       *logger.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRecorder creates a recorder keeping up to size snapshots.
+// A size of zero or less uses DefaultSize.
+func NewRecorder(core *xray.Core, size int, log *logger.Logger) *Recorder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+
+	return &Recorder{
+		snapshots: make([]Snapshot, size),
+		last:      make(map[string]int64),
+		core:      core,
+		log:       log,
+	}
+}
+
+// Start launches the goroutine taking a snapshot every minute.
+func (r *Recorder) Start() {
+	r.mu.Lock()
+	if r.stopCh != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.stopCh = make(chan struct{})
+	stopCh := r.stopCh
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(sampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				r.Record(now)
+			}
+		}
+	}()
+}
+
+// Stop terminates the sampling goroutine.
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	stopCh := r.stopCh
+	r.stopCh = nil
+	r.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		r.wg.Wait()
+	}
+}
+
+// Record takes a snapshot of the traffic since the previous one.
+// Nothing is recorded while xray is not running.
+func (r *Recorder) Record(now time.Time) {
+	stm := r.getStatsManager()
+	if stm == nil {
+		return
+	}
+
+	current := make(map[string]int64)
+	stm.VisitCounters(func(name string, counter stats.Counter) bool {
+		current[name] = counter.Value()
+		return true
+	})
+
+	r.add(now, current)
+}
+
+// add appends the snapshot built from the given counter values.
+func (r *Recorder) add(now time.Time, current map[string]int64) {
+	snapshot := Snapshot{
+		Time:      now.UTC(),
+		Inbounds:  make(map[string]Traffic),
+		Outbounds: make(map[string]Traffic),
+		Users:     make(map[string]Traffic),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, value := range current {
+		delta := value - r.last[name]
+		if delta < 0 {
+			delta = value
+		}
+		if delta == 0 {
+			continue
+		}
+
+		parts := strings.Split(name, ">>>")
+		if len(parts) < 4 || parts[2] != "traffic" {
+			continue
+		}
+
+		var target map[string]Traffic
+		switch parts[0] {
+		case "inbound":
+			target = snapshot.Inbounds
+		case "outbound":
+			target = snapshot.Outbounds
+		case "user":
+			target = snapshot.Users
+		default:
+			continue
+		}
+
+		traffic := target[parts[1]]
+		switch parts[3] {
+		case "uplink":
+			traffic.Uplink += delta
+		case "downlink":
+			traffic.Downlink += delta
+		}
+		target[parts[1]] = traffic
+	}
+	r.last = current
+
+	r.snapshots[r.next] = snapshot
+	r.next = (r.next + 1) % len(r.snapshots)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Range returns the snapshots taken within [from, to], oldest first.
+// A zero bound is open.
+func (r *Recorder) Range(from, to time.Time) []Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	start, count := 0, r.next
+	if r.full {
+		start, count = r.next, len(r.snapshots)
+	}
+
+	result := make([]Snapshot, 0, count)
+	for i := 0; i < count; i++ {
+		snapshot := r.snapshots[(start+i)%len(r.snapshots)]
+		if !from.IsZero() && snapshot.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && snapshot.Time.After(to) {
+			continue
+		}
+		result = append(result, snapshot)
+	}
+
+	return result
+}
+
+func (r *Recorder) getStatsManager() *appstats.Manager {
+	instance := r.core.Instance()
+	if instance == nil {
+		return nil
+	}
+
+	stm, ok := instance.GetFeature(stats.ManagerType()).(*appstats.Manager)
+	if !ok {
+		return nil
+	}
+	return stm
+}
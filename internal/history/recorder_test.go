@@ -0,0 +1,89 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestRecorder(size int) *Recorder {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return NewRecorder(xray.NewCore(log), size, log)
+}
+
+func TestRecorder_RecordsDeltas(t *testing.T) {
+	r := newTestRecorder(10)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r.add(start, map[string]int64{
+		"user>>>alice>>>traffic>>>uplink":       100,
+		"user>>>alice>>>traffic>>>downlink":     200,
+		"inbound>>>vless-in>>>traffic>>>uplink": 100,
+	})
+	r.add(start.Add(time.Minute), map[string]int64{
+		"user>>>alice>>>traffic>>>uplink":       150,
+		"user>>>alice>>>traffic>>>downlink":     200,
+		"inbound>>>vless-in>>>traffic>>>uplink": 150,
+	})
+	// Counters were reset between samples.
+	r.add(start.Add(2*time.Minute), map[string]int64{
+		"user>>>alice>>>traffic>>>uplink":        30,
+		"user>>>alice>>>traffic>>>downlink":      0,
+		"outbound>>>direct>>>traffic>>>downlink": 5,
+	})
+
+	snapshots := r.Range(time.Time{}, time.Time{})
+	require.Len(t, snapshots, 3)
+
+	assert.Equal(t, Traffic{Uplink: 100, Downlink: 200}, snapshots[0].Users["alice"])
+	assert.Equal(t, Traffic{Uplink: 100}, snapshots[0].Inbounds["vless-in"])
+
+	assert.Equal(t, Traffic{Uplink: 50}, snapshots[1].Users["alice"])
+	assert.Equal(t, Traffic{Uplink: 50}, snapshots[1].Inbounds["vless-in"])
+
+	assert.Equal(t, Traffic{Uplink: 30}, snapshots[2].Users["alice"])
+	assert.Empty(t, snapshots[2].Inbounds)
+	assert.Equal(t, Traffic{Downlink: 5}, snapshots[2].Outbounds["direct"])
+}
+
+func TestRecorder_RingBufferAndRange(t *testing.T) {
+	r := newTestRecorder(3)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		r.add(start.Add(time.Duration(i)*time.Minute), map[string]int64{})
+	}
+
+	snapshots := r.Range(time.Time{}, time.Time{})
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, start.Add(2*time.Minute), snapshots[0].Time)
+	assert.Equal(t, start.Add(4*time.Minute), snapshots[2].Time)
+
+	snapshots = r.Range(start.Add(3*time.Minute), start.Add(3*time.Minute))
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, start.Add(3*time.Minute), snapshots[0].Time)
+
+	assert.Empty(t, r.Range(start.Add(time.Hour), time.Time{}))
+}
+
+func TestRecorder_RecordWithoutCore(t *testing.T) {
+	r := newTestRecorder(0)
+	assert.Len(t, r.snapshots, DefaultSize)
+
+	r.Record(time.Now())
+	assert.Empty(t, r.Range(time.Time{}, time.Time{}))
+}
+
+func TestRecorder_StartStop(t *testing.T) {
+	r := newTestRecorder(1)
+
+	r.Start()
+	r.Start()
+	r.Stop()
+	r.Stop()
+}
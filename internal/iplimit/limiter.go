@@ -0,0 +1,307 @@
+package iplimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	// BanDuration is how long an IP exceeding a user's limit stays blocked.
+	BanDuration = 5 * time.Minute
+
+	stateKey      = "ip-limits"
+	checkInterval = 10 * time.Second
+)
+
+// Limiter enforces per-user limits on concurrent source IPs.
+// It reads the online IP maps xray keeps per user (policy statsUserOnline)
+// and blocks IPs beyond the limit through the vision blocklist.
+// The earliest seen IPs are kept, so a new device is the one rejected.
+type Limiter struct {
+	mu        sync.RWMutex
+	limits    map[string]int
+	firstSeen map[string]map[string]time.Time
+	core      *xray.Core
+	blocklist *vision.Blocklist
+	store     state.Store
+	log       *logger.Logger
+
+	// batches counts the open batches; changes made meanwhile are saved
+	// when the last one ends, as dirty records.
+	batches int
+	dirty   bool
+	// saveMu orders the saves, so that a snapshot never overwrites a newer
+	// one.
+	saveMu sync.Mutex
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewLimiter restores persisted limits.
+func NewLimiter(core *xray.Core, blocklist *vision.Blocklist, store state.Store, log *logger.Logger) *Limiter {
+	l := &Limiter{
+		limits:    make(map[string]int),
+		firstSeen: make(map[string]map[string]time.Time),
+		core:      core,
+		blocklist: blocklist,
+		store:     store,
+		log:       log,
+	}
+
+	l.restore()
+
+	return l
+}
+
+// SetLimit sets the maximum number of concurrent IPs for a user.
+// A limit of zero or less removes the limit.
+func (l *Limiter) SetLimit(username string, limit int) {
+	l.mu.Lock()
+	current, exists := l.limits[username]
+	if limit <= 0 {
+		delete(l.limits, username)
+		delete(l.firstSeen, username)
+	} else {
+		l.limits[username] = limit
+	}
+	changed := (limit <= 0 && exists) || (limit > 0 && current != limit)
+	deferred := changed && l.batches > 0
+	if deferred {
+		l.dirty = true
+	}
+	l.mu.Unlock()
+
+	if changed && !deferred {
+		l.save()
+	}
+}
+
+// BeginBatch defers saving the limits to EndBatch, so that the users of a
+// bulk request are saved once rather than one by one. While batches are
+// open, the changes of other callers are saved when the last one ends.
+func (l *Limiter) BeginBatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.batches++
+}
+
+// EndBatch ends a batch opened by BeginBatch, saving the limits if it was
+// the last one and they changed.
+func (l *Limiter) EndBatch() {
+	l.mu.Lock()
+	l.batches--
+	flush := l.batches == 0 && l.dirty
+	l.mu.Unlock()
+
+	if flush {
+		l.save()
+	}
+}
+
+// RemoveUser drops the limit and tracking state of a user.
+func (l *Limiter) RemoveUser(username string) {
+	l.SetLimit(username, 0)
+}
+
+// Limit returns the IP limit of a user, or zero if unlimited.
+func (l *Limiter) Limit(username string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.limits[username]
+}
+
+// Start launches the goroutine that periodically enforces the limits.
+func (l *Limiter) Start() {
+	l.mu.Lock()
+	if l.stopCh != nil {
+		l.mu.Unlock()
+		return
+	}
+	l.stopCh = make(chan struct{})
+	stopCh := l.stopCh
+	l.mu.Unlock()
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				l.Check(now)
+			}
+		}
+	}()
+}
+
+// Stop terminates the enforcement goroutine.
+func (l *Limiter) Stop() {
+	l.mu.Lock()
+	stopCh := l.stopCh
+	l.stopCh = nil
+	l.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		l.wg.Wait()
+	}
+}
+
+// Check enforces all limits once and returns the IPs that were blocked.
+func (l *Limiter) Check(now time.Time) []string {
+	stm := l.getStatsManager()
+	if stm == nil {
+		return nil
+	}
+
+	l.mu.RLock()
+	limits := make(map[string]int, len(l.limits))
+	for username, limit := range l.limits {
+		limits[username] = limit
+	}
+	l.mu.RUnlock()
+
+	var blocked []string
+	for username, limit := range limits {
+		var online []string
+		if om := stm.GetOnlineMap("user>>>" + username + ">>>online"); om != nil {
+			online = om.List()
+		}
+
+		for _, ip := range l.excessIPs(username, limit, online, now) {
+			if l.blocklist.IsBlocked(ip) {
+				continue
+			}
+			if _, err := l.blocklist.Block(ip, BanDuration); err != nil {
+				l.log.WithError(err).WithField("username", username).WithField("ip", ip).
+					Error("Failed to block IP over user limit")
+				continue
+			}
+			l.log.WithField("username", username).WithField("ip", ip).WithField("limit", limit).
+				Warn("User exceeded IP limit, IP blocked")
+			blocked = append(blocked, ip)
+		}
+	}
+
+	return blocked
+}
+
+// excessIPs records when each online IP of a user was first seen and
+// returns the IPs beyond the limit, newest first-seen last.
+func (l *Limiter) excessIPs(username string, limit int, online []string, now time.Time) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, limited := l.limits[username]; !limited {
+		return nil
+	}
+
+	seen := l.firstSeen[username]
+	if seen == nil {
+		seen = make(map[string]time.Time)
+		l.firstSeen[username] = seen
+	}
+
+	current := make(map[string]struct{}, len(online))
+	for _, ip := range online {
+		current[ip] = struct{}{}
+		if _, ok := seen[ip]; !ok {
+			seen[ip] = now
+		}
+	}
+	for ip := range seen {
+		if _, ok := current[ip]; !ok {
+			delete(seen, ip)
+		}
+	}
+
+	if len(seen) <= limit {
+		return nil
+	}
+
+	ips := make([]string, 0, len(seen))
+	for ip := range seen {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		ti, tj := seen[ips[i]], seen[ips[j]]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return ips[i] < ips[j]
+	})
+
+	excess := ips[limit:]
+	for _, ip := range excess {
+		delete(seen, ip)
+	}
+	return excess
+}
+
+func (l *Limiter) getStatsManager() stats.Manager {
+	instance := l.core.Instance()
+	if instance == nil {
+		return nil
+	}
+
+	stm, ok := instance.GetFeature(stats.ManagerType()).(stats.Manager)
+	if !ok {
+		return nil
+	}
+	return stm
+}
+
+// restore loads the limits persisted by a previous run.
+func (l *Limiter) restore() {
+	var stored map[string]int
+	found, err := l.store.Load(stateKey, &stored)
+	if err != nil {
+		l.log.WithError(err).Error("Failed to restore IP limits")
+		return
+	}
+	if !found {
+		return
+	}
+
+	l.mu.Lock()
+	for username, limit := range stored {
+		if limit > 0 {
+			l.limits[username] = limit
+		}
+	}
+	l.mu.Unlock()
+}
+
+// save persists the current limits.
+func (l *Limiter) save() {
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	l.mu.Lock()
+	snapshot := make(map[string]int, len(l.limits))
+	for username, limit := range l.limits {
+		snapshot[username] = limit
+	}
+	l.dirty = false
+	l.mu.Unlock()
+
+	if err := l.store.Save(stateKey, snapshot); err != nil {
+		l.log.WithError(err).Error("Failed to persist IP limits")
+	}
+}
@@ -0,0 +1,126 @@
+package iplimit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/vision"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestLimiter(t *testing.T, store state.Store) *Limiter {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	return NewLimiter(core, vision.NewBlocklist(core, state.NewMemoryStore(), log), store, log)
+}
+
+func TestLimiter_SetLimit(t *testing.T) {
+	l := newTestLimiter(t, state.NewMemoryStore())
+
+	l.SetLimit("alice", 2)
+	assert.Equal(t, 2, l.Limit("alice"))
+
+	l.SetLimit("alice", 0)
+	assert.Equal(t, 0, l.Limit("alice"))
+
+	l.SetLimit("bob", 1)
+	l.RemoveUser("bob")
+	assert.Equal(t, 0, l.Limit("bob"))
+}
+
+func TestLimiter_ExcessIPsKeepsEarliest(t *testing.T) {
+	l := newTestLimiter(t, state.NewMemoryStore())
+	l.SetLimit("alice", 2)
+
+	start := time.Now()
+
+	excess := l.excessIPs("alice", 2, []string{"10.0.0.1"}, start)
+	assert.Empty(t, excess)
+
+	excess = l.excessIPs("alice", 2, []string{"10.0.0.1", "10.0.0.2"}, start.Add(time.Second))
+	assert.Empty(t, excess)
+
+	excess = l.excessIPs("alice", 2, []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}, start.Add(2*time.Second))
+	assert.Equal(t, []string{"10.0.0.3"}, excess)
+
+	// An IP that went offline frees its slot.
+	excess = l.excessIPs("alice", 2, []string{"10.0.0.2", "10.0.0.3"}, start.Add(3*time.Second))
+	assert.Empty(t, excess)
+}
+
+func TestLimiter_ExcessIPsIgnoresUnlimitedUsers(t *testing.T) {
+	l := newTestLimiter(t, state.NewMemoryStore())
+
+	excess := l.excessIPs("alice", 1, []string{"10.0.0.1", "10.0.0.2"}, time.Now())
+	assert.Empty(t, excess)
+}
+
+func TestLimiter_CheckWithoutCore(t *testing.T) {
+	l := newTestLimiter(t, state.NewMemoryStore())
+	l.SetLimit("alice", 1)
+
+	assert.Empty(t, l.Check(time.Now()))
+}
+
+func TestLimiter_PersistsLimits(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	l := newTestLimiter(t, store)
+	l.SetLimit("alice", 3)
+	l.SetLimit("bob", 1)
+	l.RemoveUser("bob")
+
+	restored := newTestLimiter(t, store)
+	assert.Equal(t, 3, restored.Limit("alice"))
+	assert.Equal(t, 0, restored.Limit("bob"))
+}
+
+// countingStore counts the saves.
+type countingStore struct {
+	state.Store
+	saves atomic.Int32
+}
+
+func (s *countingStore) Save(key string, v interface{}) error {
+	s.saves.Add(1)
+	return s.Store.Save(key, v)
+}
+
+func TestLimiter_Batch(t *testing.T) {
+	store := &countingStore{Store: state.NewMemoryStore()}
+	l := newTestLimiter(t, store)
+
+	l.BeginBatch()
+	for i := range 100 {
+		l.SetLimit(fmt.Sprintf("user-%d", i), 2)
+	}
+	assert.Zero(t, store.saves.Load(), "saves wait for the end of the batch")
+	l.EndBatch()
+	assert.Equal(t, int32(1), store.saves.Load())
+
+	l.BeginBatch()
+	l.SetLimit("user-0", 2)
+	l.EndBatch()
+	assert.Equal(t, int32(1), store.saves.Load(), "a batch without changes does not save")
+
+	restored := newTestLimiter(t, store.Store)
+	assert.Equal(t, 2, restored.Limit("user-99"))
+}
+
+func TestLimiter_StartStop(t *testing.T) {
+	l := newTestLimiter(t, state.NewMemoryStore())
+
+	l.Start()
+	l.Start()
+	l.Stop()
+	l.Stop()
+}
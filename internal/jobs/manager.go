@@ -0,0 +1,236 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// retention is how long finished jobs stay queryable.
+	retention = time.Hour
+	// maxFinished bounds the finished jobs kept, oldest are dropped first.
+	maxFinished = 1000
+	// MaxActive bounds the jobs pending or running at once.
+	MaxActive = 16
+)
+
+var (
+	// ErrTooManyJobs is returned by Submit while MaxActive jobs are active.
+	ErrTooManyJobs = errors.New("too many jobs in progress")
+	// ErrStopped is returned by Submit once the manager is stopped.
+	ErrStopped = errors.New("job manager stopped")
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// Job is a snapshot of a long-running operation.
+type Job struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Status     Status      `json:"status"`
+	Done       int         `json:"done"`
+	Total      int         `json:"total"`
+	Result     interface{} `json:"result"`
+	Error      *string     `json:"error"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt"`
+}
+
+// Func is the work of a job. It reports progress through p and returns the
+// result stored in the job. A non-nil error marks the job failed; the result
+// is kept either way.
+type Func func(p *Progress) (interface{}, error)
+
+// Progress lets a job report how much of its work is done. A nil Progress
+// discards updates, so code shared with synchronous callers can use it
+// unconditionally.
+type Progress struct {
+	manager *Manager
+	id      string
+}
+
+// SetTotal sets the number of work items of the job.
+func (p *Progress) SetTotal(total int) {
+	if p == nil {
+		return
+	}
+	p.manager.update(p.id, func(job *Job) { job.Total = total })
+}
+
+// Add marks n more work items as done.
+func (p *Progress) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.manager.update(p.id, func(job *Job) { job.Done += n })
+}
+
+// Manager runs jobs in the background and keeps their state in memory for
+// polling. Jobs do not survive a restart.
+type Manager struct {
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	active  int
+	stopped bool
+	log     *logger.Logger
+
+	wg sync.WaitGroup
+}
+
+// NewManager creates an empty job manager.
+func NewManager(log *logger.Logger) *Manager {
+	return &Manager{
+		jobs: make(map[string]*Job),
+		log:  log,
+	}
+}
+
+// Submit starts fn in the background and returns the pending job. It fails
+// with ErrTooManyJobs while MaxActive jobs are active and with ErrStopped
+// once the manager is stopped.
+func (m *Manager) Submit(jobType string, fn Func) (Job, error) {
+	job := &Job{
+		ID:        newID(),
+		Type:      jobType,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return Job{}, ErrStopped
+	}
+	if m.active >= MaxActive {
+		m.mu.Unlock()
+		return Job{}, ErrTooManyJobs
+	}
+	m.pruneLocked(job.CreatedAt)
+	m.jobs[job.ID] = job
+	m.active++
+	m.wg.Add(1)
+	snapshot := *job
+	m.mu.Unlock()
+
+	go m.run(job.ID, fn)
+
+	return snapshot, nil
+}
+
+// Stop rejects new jobs and waits for the active ones to finish, or until
+// ctx is done, in which case it returns the error of ctx.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns the current state of a job.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func (m *Manager) run(id string, fn Func) {
+	defer m.wg.Done()
+
+	m.update(id, func(job *Job) { job.Status = StatusRunning })
+
+	result, err := fn(&Progress{manager: m, id: id})
+
+	now := time.Now()
+	m.mu.Lock()
+	m.active--
+	m.mu.Unlock()
+	m.update(id, func(job *Job) {
+		job.Result = result
+		job.FinishedAt = &now
+		if err != nil {
+			errMsg := err.Error()
+			job.Error = &errMsg
+			job.Status = StatusFailed
+			return
+		}
+		job.Status = StatusCompleted
+	})
+
+	entry := m.log.WithField("jobId", id)
+	if err != nil {
+		entry.WithError(err).Warn("Job failed")
+	} else {
+		entry.Debug("Job completed")
+	}
+}
+
+func (m *Manager) update(id string, fn func(job *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, exists := m.jobs[id]; exists {
+		fn(job)
+	}
+}
+
+// pruneLocked drops expired finished jobs and, past maxFinished, the oldest
+// remaining ones. The caller must hold m.mu.
+func (m *Manager) pruneLocked(now time.Time) {
+	finished := make([]*Job, 0)
+	for id, job := range m.jobs {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if now.Sub(*job.FinishedAt) > retention {
+			delete(m.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+
+	if len(finished) < maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-maxFinished+1] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func newTestManager() *Manager {
+	return NewManager(logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+}
+
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+
+	var job Job
+	require.Eventually(t, func() bool {
+		var exists bool
+		job, exists = m.Get(id)
+		return exists && job.FinishedAt != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestManager_ReportsProgressAndResult(t *testing.T) {
+	m := newTestManager()
+	release := make(chan struct{})
+
+	submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) {
+		p.SetTotal(3)
+		p.Add(2)
+		<-release
+		p.Add(1)
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, submitted.ID)
+	assert.Equal(t, "test", submitted.Type)
+
+	require.Eventually(t, func() bool {
+		job, _ := m.Get(submitted.ID)
+		return job.Status == StatusRunning && job.Done == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	job := waitFinished(t, m, submitted.ID)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, 3, job.Done)
+	assert.Equal(t, 3, job.Total)
+	assert.Equal(t, "ok", job.Result)
+	assert.Nil(t, job.Error)
+}
+
+func TestManager_FailedJob(t *testing.T) {
+	m := newTestManager()
+
+	submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) {
+		return "partial", errors.New("boom")
+	})
+	require.NoError(t, err)
+
+	job := waitFinished(t, m, submitted.ID)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.Equal(t, "partial", job.Result)
+	require.NotNil(t, job.Error)
+	assert.Equal(t, "boom", *job.Error)
+}
+
+func TestManager_UnknownJob(t *testing.T) {
+	_, exists := newTestManager().Get("missing")
+	assert.False(t, exists)
+}
+
+func TestManager_PrunesExpiredJobs(t *testing.T) {
+	m := newTestManager()
+
+	submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) { return nil, nil })
+	require.NoError(t, err)
+	waitFinished(t, m, submitted.ID)
+
+	m.mu.Lock()
+	m.pruneLocked(time.Now().Add(retention + time.Minute))
+	m.mu.Unlock()
+
+	_, exists := m.Get(submitted.ID)
+	assert.False(t, exists)
+}
+
+func TestManager_BoundsActiveJobs(t *testing.T) {
+	m := newTestManager()
+	release := make(chan struct{})
+
+	ids := make([]string, 0, MaxActive)
+	for range MaxActive {
+		submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) {
+			<-release
+			return nil, nil
+		})
+		require.NoError(t, err)
+		ids = append(ids, submitted.ID)
+	}
+
+	_, err := m.Submit("test", func(p *Progress) (interface{}, error) { return nil, nil })
+	assert.ErrorIs(t, err, ErrTooManyJobs)
+
+	close(release)
+	for _, id := range ids {
+		waitFinished(t, m, id)
+	}
+
+	submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) { return nil, nil })
+	require.NoError(t, err)
+	waitFinished(t, m, submitted.ID)
+}
+
+func TestManager_StopWaitsForJobs(t *testing.T) {
+	m := newTestManager()
+	release := make(chan struct{})
+
+	submitted, err := m.Submit("test", func(p *Progress) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Stop(ctx), context.DeadlineExceeded)
+
+	_, err = m.Submit("test", func(p *Progress) (interface{}, error) { return nil, nil })
+	assert.ErrorIs(t, err, ErrStopped)
+
+	close(release)
+	require.NoError(t, m.Stop(context.Background()))
+	job, _ := m.Get(submitted.ID)
+	assert.Equal(t, StatusCompleted, job.Status)
+}
+
+func TestProgress_NilIsNoop(t *testing.T) {
+	var p *Progress
+	p.SetTotal(1)
+	p.Add(1)
+}
@@ -0,0 +1,127 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &v))
+	return v
+}
+
+func ops(t *testing.T, s string) []Operation {
+	t.Helper()
+	var o []Operation
+	require.NoError(t, json.Unmarshal([]byte(s), &o))
+	return o
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "add member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":{"c":2}}]`,
+			want:  `{"a":1,"b":{"c":2}}`,
+		},
+		{
+			name:  "add array element",
+			doc:   `{"a":[1,3]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2},{"op":"add","path":"/a/-","value":4}]`,
+			want:  `{"a":[1,2,3,4]}`,
+		},
+		{
+			name:  "remove",
+			doc:   `{"a":[1,2,3],"b":true}`,
+			patch: `[{"op":"remove","path":"/a/0"},{"op":"remove","path":"/b"}]`,
+			want:  `{"a":[2,3]}`,
+		},
+		{
+			name:  "replace nested",
+			doc:   `{"inbounds":[{"tag":"x","port":443}]}`,
+			patch: `[{"op":"replace","path":"/inbounds/0/port","value":8443}]`,
+			want:  `{"inbounds":[{"tag":"x","port":8443}]}`,
+		},
+		{
+			name:  "move and copy",
+			doc:   `{"a":{"b":1},"c":[]}`,
+			patch: `[{"op":"copy","from":"/a/b","path":"/c/-"},{"op":"move","from":"/a","path":"/d"}]`,
+			want:  `{"c":[1],"d":{"b":1}}`,
+		},
+		{
+			name:  "test passes",
+			doc:   `{"a":[1,{"b":"x"}]}`,
+			patch: `[{"op":"test","path":"/a/1","value":{"b":"x"}}]`,
+			want:  `{"a":[1,{"b":"x"}]}`,
+		},
+		{
+			name:  "escaped pointer",
+			doc:   `{"a/b":{"m~n":1}}`,
+			patch: `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`,
+			want:  `{"a/b":{"m~n":2}}`,
+		},
+		{
+			name:  "replace root",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply(decode(t, tt.doc), ops(t, tt.patch))
+			require.NoError(t, err)
+			assert.Equal(t, decode(t, tt.want), got)
+		})
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{name: "unknown op", patch: `[{"op":"merge","path":"/a","value":1}]`},
+		{name: "missing value", patch: `[{"op":"add","path":"/a"}]`},
+		{name: "replace missing member", patch: `[{"op":"replace","path":"/x","value":1}]`},
+		{name: "remove missing member", patch: `[{"op":"remove","path":"/x"}]`},
+		{name: "index out of range", patch: `[{"op":"add","path":"/a/5","value":1}]`},
+		{name: "leading zero index", patch: `[{"op":"remove","path":"/a/01"}]`},
+		{name: "end index outside add", patch: `[{"op":"replace","path":"/a/-","value":1}]`},
+		{name: "missing parent", patch: `[{"op":"add","path":"/x/y","value":1}]`},
+		{name: "invalid pointer", patch: `[{"op":"add","path":"a","value":1}]`},
+		{name: "move into itself", patch: `[{"op":"move","from":"/a","path":"/a/0"}]`},
+		{name: "test fails", patch: `[{"op":"test","path":"/a/0","value":2}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply(decode(t, `{"a":[1]}`), ops(t, tt.patch))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApply_LeavesDocumentUntouched(t *testing.T) {
+	doc := decode(t, `{"a":[1,2],"b":{"c":1}}`)
+
+	_, err := Apply(doc, ops(t, `[{"op":"remove","path":"/a/0"},{"op":"replace","path":"/b/c","value":2}]`))
+	require.NoError(t, err)
+	assert.Equal(t, decode(t, `{"a":[1,2],"b":{"c":1}}`), doc)
+
+	_, err = Apply(doc, ops(t, `[{"op":"remove","path":"/a/0"},{"op":"test","path":"/b/c","value":2}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+	assert.Equal(t, decode(t, `{"a":[1,2],"b":{"c":1}}`), doc)
+}
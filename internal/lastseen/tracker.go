@@ -0,0 +1,271 @@
+package lastseen
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	// UserRetention is how long a user not seen again is kept.
+	UserRetention = 30 * 24 * time.Hour
+	// IPRetention is how long a source IP not seen again is kept.
+	IPRetention = 24 * time.Hour
+	// MaxIPsPerUser bounds the IPs kept per user; the least recent go first.
+	MaxIPsPerUser = 16
+
+	stateKey     = "last-seen"
+	saveInterval = time.Minute
+)
+
+// User is when a user last connected and from which IPs.
+type User struct {
+	Username string    `json:"username"`
+	LastSeen time.Time `json:"lastSeen"`
+	LastIP   string    `json:"lastIp"`
+	// IPs are the source IPs seen within IPRetention, most recent first.
+	IPs []IP `json:"ips"`
+}
+
+type IP struct {
+	IP       string    `json:"ip"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type record struct {
+	LastSeen time.Time            `json:"lastSeen"`
+	LastIP   string               `json:"lastIp"`
+	IPs      map[string]time.Time `json:"ips"`
+}
+
+// Tracker records when each user last connected and from where, from the
+// connections xray accepts. Unlike the online counter it still answers once
+// the connections are closed. Records are persisted periodically, so they
+// survive node restarts.
+type Tracker struct {
+	mu    sync.Mutex
+	users map[string]*record
+	dirty bool
+	store state.Store
+	log   *logger.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New restores the persisted records and starts recording the connections
+// accepted by core.
+func New(core *xray.Core, store state.Store, log *logger.Logger) *Tracker {
+	t := &Tracker{
+		users: make(map[string]*record),
+		store: store,
+		log:   log,
+	}
+
+	t.restore()
+	core.OnAccess(func(access xray.Access) {
+		t.Record(access.Email, access.IP, access.Time)
+	})
+
+	return t
+}
+
+// Record notes that username connected from ip at the given time.
+func (t *Tracker) Record(username, ip string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, exists := t.users[username]
+	if !exists {
+		rec = &record{IPs: make(map[string]time.Time)}
+		t.users[username] = rec
+	}
+	if at.After(rec.LastSeen) {
+		rec.LastSeen = at
+		if ip != "" {
+			rec.LastIP = ip
+		}
+	}
+	if ip != "" && at.After(rec.IPs[ip]) {
+		rec.IPs[ip] = at
+		if len(rec.IPs) > MaxIPsPerUser {
+			dropOldestIP(rec)
+		}
+	}
+	t.dirty = true
+}
+
+func dropOldestIP(rec *record) {
+	var oldest string
+	for ip, seen := range rec.IPs {
+		if oldest == "" || seen.Before(rec.IPs[oldest]) {
+			oldest = ip
+		}
+	}
+	delete(rec.IPs, oldest)
+}
+
+// User returns the record of username.
+func (t *Tracker) User(username string, now time.Time) (User, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, exists := t.users[username]
+	if !exists || now.Sub(rec.LastSeen) > UserRetention {
+		return User{}, false
+	}
+	return rec.user(username, now), true
+}
+
+// Users returns the records of all users seen within UserRetention, sorted
+// by username.
+func (t *Tracker) Users(now time.Time) []User {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	users := make([]User, 0, len(t.users))
+	for username, rec := range t.users {
+		if now.Sub(rec.LastSeen) <= UserRetention {
+			users = append(users, rec.user(username, now))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+func (r *record) user(username string, now time.Time) User {
+	user := User{
+		Username: username,
+		LastSeen: r.LastSeen,
+		LastIP:   r.LastIP,
+		IPs:      make([]IP, 0, len(r.IPs)),
+	}
+	for ip, seen := range r.IPs {
+		if now.Sub(seen) <= IPRetention {
+			user.IPs = append(user.IPs, IP{IP: ip, LastSeen: seen})
+		}
+	}
+	sort.Slice(user.IPs, func(i, j int) bool {
+		if !user.IPs[i].LastSeen.Equal(user.IPs[j].LastSeen) {
+			return user.IPs[i].LastSeen.After(user.IPs[j].LastSeen)
+		}
+		return user.IPs[i].IP < user.IPs[j].IP
+	})
+	return user
+}
+
+// Start launches the goroutine that prunes and persists the records.
+func (t *Tracker) Start() {
+	t.mu.Lock()
+	if t.stopCh != nil {
+		t.mu.Unlock()
+		return
+	}
+	t.stopCh = make(chan struct{})
+	stopCh := t.stopCh
+	t.mu.Unlock()
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(saveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				t.prune(now)
+				t.save()
+			}
+		}
+	}()
+}
+
+// Stop terminates the goroutine and persists the records.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	stopCh := t.stopCh
+	t.stopCh = nil
+	t.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		t.wg.Wait()
+	}
+	t.save()
+}
+
+// prune drops the users and IPs past their retention.
+func (t *Tracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for username, rec := range t.users {
+		if now.Sub(rec.LastSeen) > UserRetention {
+			delete(t.users, username)
+			t.dirty = true
+			continue
+		}
+		for ip, seen := range rec.IPs {
+			if now.Sub(seen) > IPRetention {
+				delete(rec.IPs, ip)
+				t.dirty = true
+			}
+		}
+	}
+}
+
+// restore loads the records persisted by a previous run.
+func (t *Tracker) restore() {
+	var stored map[string]*record
+	found, err := t.store.Load(stateKey, &stored)
+	if err != nil {
+		t.log.WithError(err).Error("Failed to restore last seen records")
+		return
+	}
+	if !found {
+		return
+	}
+
+	t.mu.Lock()
+	for username, rec := range stored {
+		if rec == nil {
+			continue
+		}
+		if rec.IPs == nil {
+			rec.IPs = make(map[string]time.Time)
+		}
+		t.users[username] = rec
+	}
+	t.mu.Unlock()
+}
+
+// save persists the records if they changed since the last save.
+func (t *Tracker) save() {
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return
+	}
+	snapshot := make(map[string]record, len(t.users))
+	for username, rec := range t.users {
+		ips := make(map[string]time.Time, len(rec.IPs))
+		for ip, seen := range rec.IPs {
+			ips[ip] = seen
+		}
+		snapshot[username] = record{LastSeen: rec.LastSeen, LastIP: rec.LastIP, IPs: ips}
+	}
+	t.dirty = false
+	t.mu.Unlock()
+
+	if err := t.store.Save(stateKey, snapshot); err != nil {
+		t.log.WithError(err).Error("Failed to persist last seen records")
+	}
+}
@@ -0,0 +1,94 @@
+package lastseen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestTracker(t *testing.T, store state.Store) *Tracker {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return New(xray.NewCore(log), store, log)
+}
+
+func TestTracker_Record(t *testing.T) {
+	tr := newTestTracker(t, state.NewMemoryStore())
+	start := time.Now()
+
+	tr.Record("alice", "10.0.0.1", start)
+	tr.Record("alice", "10.0.0.2", start.Add(time.Minute))
+	tr.Record("alice", "10.0.0.1", start.Add(2*time.Minute))
+	// Late deliveries do not move the last seen time back.
+	tr.Record("alice", "10.0.0.3", start.Add(30*time.Second))
+	tr.Record("bob", "", start)
+
+	alice, found := tr.User("alice", start.Add(3*time.Minute))
+	require.True(t, found)
+	assert.Equal(t, start.Add(2*time.Minute), alice.LastSeen)
+	assert.Equal(t, "10.0.0.1", alice.LastIP)
+	assert.Equal(t, []IP{
+		{IP: "10.0.0.1", LastSeen: start.Add(2 * time.Minute)},
+		{IP: "10.0.0.2", LastSeen: start.Add(time.Minute)},
+		{IP: "10.0.0.3", LastSeen: start.Add(30 * time.Second)},
+	}, alice.IPs)
+
+	users := tr.Users(start)
+	require.Len(t, users, 2)
+	assert.Equal(t, "alice", users[0].Username)
+	assert.Equal(t, "bob", users[1].Username)
+	assert.Empty(t, users[1].LastIP)
+	assert.Empty(t, users[1].IPs)
+
+	_, found = tr.User("carol", start)
+	assert.False(t, found)
+}
+
+func TestTracker_Retention(t *testing.T) {
+	tr := newTestTracker(t, state.NewMemoryStore())
+	start := time.Now()
+
+	for i := 0; i < MaxIPsPerUser+2; i++ {
+		tr.Record("alice", fmt.Sprintf("10.0.0.%d", i), start.Add(time.Duration(i)*time.Second))
+	}
+	alice, _ := tr.User("alice", start)
+	require.Len(t, alice.IPs, MaxIPsPerUser)
+	assert.Equal(t, "10.0.0.2", alice.IPs[MaxIPsPerUser-1].IP)
+
+	tr.Record("bob", "10.0.1.1", start.Add(-UserRetention-time.Minute))
+
+	later := start.Add(IPRetention + time.Hour)
+	alice, found := tr.User("alice", later)
+	require.True(t, found)
+	assert.Empty(t, alice.IPs)
+	assert.Len(t, tr.Users(start), 1)
+
+	tr.prune(later)
+	tr.mu.Lock()
+	assert.Len(t, tr.users, 1)
+	assert.Empty(t, tr.users["alice"].IPs)
+	tr.mu.Unlock()
+}
+
+func TestTracker_PersistsAcrossRestarts(t *testing.T) {
+	store := state.NewMemoryStore()
+	start := time.Now().UTC().Truncate(time.Second)
+
+	tr := newTestTracker(t, store)
+	tr.Start()
+	tr.Record("alice", "10.0.0.1", start)
+	tr.Stop()
+
+	restored, found := newTestTracker(t, store).User("alice", start)
+	require.True(t, found)
+	assert.True(t, start.Equal(restored.LastSeen))
+	assert.Equal(t, "10.0.0.1", restored.LastIP)
+	require.Len(t, restored.IPs, 1)
+}
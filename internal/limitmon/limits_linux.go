@@ -0,0 +1,62 @@
+//go:build linux
+
+package limitmon
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	conntrackCountFile = "/proc/sys/net/netfilter/nf_conntrack_count"
+	conntrackMaxFile   = "/proc/sys/net/netfilter/nf_conntrack_max"
+)
+
+// Read returns the open file descriptors of the process against its soft
+// limit and, when the nf_conntrack module is loaded, the connection
+// tracking table usage. Resources that cannot be read are left out.
+func Read() []Usage {
+	var usages []Usage
+	if usage, err := openFiles(); err == nil {
+		usages = append(usages, usage)
+	}
+	if usage, err := conntrack(); err == nil {
+		usages = append(usages, usage)
+	}
+	return usages
+}
+
+func openFiles() (Usage, error) {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return Usage{}, err
+	}
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return Usage{}, err
+	}
+	// The directory being listed holds one of the descriptors.
+	return Usage{Resource: ResourceOpenFiles, Used: uint64(len(entries)) - 1, Limit: limit.Cur}, nil
+}
+
+func conntrack() (Usage, error) {
+	count, err := readUint(conntrackCountFile)
+	if err != nil {
+		return Usage{}, err
+	}
+	max, err := readUint(conntrackMaxFile)
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Resource: ResourceConntrack, Used: count, Limit: max}, nil
+}
+
+func readUint(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
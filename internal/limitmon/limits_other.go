@@ -0,0 +1,8 @@
+//go:build !linux
+
+package limitmon
+
+// Read is not implemented: the monitor checks nothing outside Linux.
+func Read() []Usage {
+	return nil
+}
@@ -0,0 +1,143 @@
+// Package limitmon warns when the node nears the process file descriptor
+// limit or the kernel connection tracking table fills up, the usual silent
+// causes of a node that stops accepting connections.
+package limitmon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const checkInterval = time.Minute
+
+// Resources reported by Read.
+const (
+	ResourceOpenFiles = "openFiles"
+	ResourceConntrack = "conntrack"
+)
+
+// Usage is the use of a limited resource.
+type Usage struct {
+	Resource string `json:"resource"`
+	Used     uint64 `json:"used"`
+	Limit    uint64 `json:"limit"`
+}
+
+// UsedPercent returns the use as a percentage of the limit.
+func (u Usage) UsedPercent() float64 {
+	if u.Limit == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Limit) * 100
+}
+
+// Monitor checks the resources every minute. A resource going above the
+// threshold is reported once, until it recovers.
+type Monitor struct {
+	warnPercent float64
+	log         *logger.Logger
+	read        func() []Usage
+
+	mu     sync.Mutex
+	high   map[string]bool
+	onHigh func(Usage)
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor for which a resource is high when more than
+// warnPercent of its limit is used.
+func NewMonitor(warnPercent float64, log *logger.Logger) *Monitor {
+	return &Monitor{
+		warnPercent: warnPercent,
+		log:         log,
+		read:        Read,
+		high:        make(map[string]bool),
+	}
+}
+
+// OnHigh sets a function called when a resource goes above the threshold.
+// It must be set before Start.
+func (m *Monitor) OnHigh(fn func(Usage)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onHigh = fn
+}
+
+// Start checks the resources now and then every minute.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.mu.Unlock()
+
+	m.Check()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the check goroutine.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		m.wg.Wait()
+	}
+}
+
+// Check logs the resources that went above or back below the threshold
+// since the last check.
+func (m *Monitor) Check() {
+	m.mu.Lock()
+
+	var high []Usage
+	for _, usage := range m.read() {
+		entry := m.log.WithField("resource", usage.Resource).
+			WithField("used", usage.Used).
+			WithField("limit", usage.Limit)
+		isHigh := usage.UsedPercent() > m.warnPercent
+		switch {
+		case isHigh && !m.high[usage.Resource]:
+			entry.Error("Resource limit is nearly exhausted, new connections may be refused")
+			high = append(high, usage)
+		case !isHigh && m.high[usage.Resource]:
+			entry.Info("Resource usage recovered")
+		}
+		m.high[usage.Resource] = isHigh
+	}
+	onHigh := m.onHigh
+	m.mu.Unlock()
+
+	if onHigh != nil {
+		for _, usage := range high {
+			onHigh(usage)
+		}
+	}
+}
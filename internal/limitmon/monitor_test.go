@@ -0,0 +1,54 @@
+package limitmon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestMonitor_ReportsOncePerCrossing(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	m := NewMonitor(80, log)
+
+	files, tracked := uint64(800), uint64(10)
+	m.read = func() []Usage {
+		return []Usage{
+			{Resource: ResourceOpenFiles, Used: files, Limit: 1000},
+			{Resource: ResourceConntrack, Used: tracked, Limit: 100},
+		}
+	}
+	var high []Usage
+	m.OnHigh(func(u Usage) { high = append(high, u) })
+
+	m.Check()
+	assert.Empty(t, high, "80% used is not above the threshold")
+
+	files = 950
+	m.Check()
+	m.Check()
+	require.Len(t, high, 1)
+	assert.Equal(t, ResourceOpenFiles, high[0].Resource)
+	assert.InDelta(t, 95.0, high[0].UsedPercent(), 0.001)
+
+	tracked = 99
+	files = 100
+	m.Check()
+	files = 990
+	m.Check()
+	require.Len(t, high, 3, "reported again after recovering")
+	assert.Equal(t, ResourceConntrack, high[1].Resource)
+}
+
+func TestRead(t *testing.T) {
+	usages := Read()
+	if len(usages) == 0 {
+		t.Skip("resource usage is not available")
+	}
+	files := usages[0]
+	assert.Equal(t, ResourceOpenFiles, files.Resource)
+	assert.NotZero(t, files.Used)
+	assert.LessOrEqual(t, files.Used, files.Limit)
+}
@@ -0,0 +1,44 @@
+//go:build linux
+
+package limits
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cgroupMemoryFiles hold the memory limit of the cgroup of the node, for
+// cgroup v2 and v1.
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// unlimitedV1 is the smallest cgroup v1 value meaning no limit: the
+// kernel reports a page-aligned maximum int64.
+const unlimitedV1 = 1 << 62
+
+// ContainerMemoryLimit returns the memory limit of the cgroup the node
+// runs in, in bytes.
+func ContainerMemoryLimit() (int64, error) {
+	for _, path := range cgroupMemoryFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0, ErrNoContainerLimit
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if limit <= 0 || limit >= unlimitedV1 {
+			return 0, ErrNoContainerLimit
+		}
+		return limit, nil
+	}
+	return 0, ErrNoContainerLimit
+}
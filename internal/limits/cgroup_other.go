@@ -0,0 +1,8 @@
+//go:build !linux
+
+package limits
+
+// ContainerMemoryLimit always fails: cgroups only exist on Linux.
+func ContainerMemoryLimit() (int64, error) {
+	return 0, ErrNoContainerLimit
+}
@@ -0,0 +1,85 @@
+// Package limits adapts the Go runtime to the resource limits of the
+// container the node runs in: a soft memory limit below the cgroup one, so
+// the garbage collector works harder before the kernel OOM-kills the node,
+// and GOMAXPROCS matching the CPU quota rather than the host CPU count.
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"go.uber.org/automaxprocs/maxprocs"
+)
+
+// ErrNoContainerLimit is returned when a percentage memory limit is
+// configured but the node runs without a cgroup memory limit.
+var ErrNoContainerLimit = errors.New("no container memory limit")
+
+var memoryUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10},
+	{"MiB", 1 << 20},
+	{"GiB", 1 << 30},
+	{"TiB", 1 << 40},
+	{"B", 1},
+}
+
+// ParseMemoryLimit returns the memory limit in bytes described by spec:
+// a size with a GOMEMLIMIT unit suffix (B, KiB, MiB, GiB, TiB), such as
+// 900MiB, or a percentage of containerLimit, such as 90%.
+func ParseMemoryLimit(spec string, containerLimit func() (int64, error)) (int64, error) {
+	spec = strings.TrimSpace(spec)
+
+	if percentPart, ok := strings.CutSuffix(spec, "%"); ok {
+		percent, err := strconv.ParseFloat(percentPart, 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return 0, fmt.Errorf("invalid percentage %q", spec)
+		}
+		limit, err := containerLimit()
+		if err != nil {
+			return 0, err
+		}
+		return int64(float64(limit) * percent / 100), nil
+	}
+
+	for _, unit := range memoryUnits {
+		if number, ok := strings.CutSuffix(spec, unit.suffix); ok {
+			n, err := strconv.ParseInt(number, 10, 64)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid size %q", spec)
+			}
+			return n * unit.size, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid size %q: missing unit (B, KiB, MiB, GiB, TiB) or %%", spec)
+}
+
+// SetMemoryLimit sets the soft memory limit of the runtime from spec, see
+// ParseMemoryLimit, and returns it. A GOMEMLIMIT environment variable takes
+// precedence: nothing changes and 0 is returned then.
+func SetMemoryLimit(spec string) (int64, error) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		return 0, nil
+	}
+
+	limit, err := ParseMemoryLimit(spec, ContainerMemoryLimit)
+	if err != nil {
+		return 0, err
+	}
+	debug.SetMemoryLimit(limit)
+	return limit, nil
+}
+
+// SetMaxProcs sets GOMAXPROCS from the CPU quota of the container, unless
+// the GOMAXPROCS environment variable is set, and logs the outcome through
+// logf.
+func SetMaxProcs(logf func(format string, args ...interface{})) error {
+	_, err := maxprocs.Set(maxprocs.Logger(logf))
+	return err
+}
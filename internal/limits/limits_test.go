@@ -0,0 +1,44 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	container := func() (int64, error) { return 1 << 30, nil }
+
+	tests := []struct {
+		spec string
+		want int64
+	}{
+		{"900MiB", 900 << 20},
+		{"2GiB", 2 << 30},
+		{"512KiB", 512 << 10},
+		{"1000000B", 1000000},
+		{"90%", (1 << 30) * 9 / 10},
+		{" 50% ", 1 << 29},
+	}
+	for _, tt := range tests {
+		limit, err := ParseMemoryLimit(tt.spec, container)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, tt.want, limit, tt.spec)
+	}
+
+	for _, spec := range []string{"", "900", "900MB", "-1GiB", "0MiB", "0%", "150%", "x%"} {
+		_, err := ParseMemoryLimit(spec, container)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParseMemoryLimit_WithoutContainerLimit(t *testing.T) {
+	_, err := ParseMemoryLimit("90%", func() (int64, error) { return 0, ErrNoContainerLimit })
+	assert.True(t, errors.Is(err, ErrNoContainerLimit))
+
+	limit, err := ParseMemoryLimit("1GiB", func() (int64, error) { return 0, ErrNoContainerLimit })
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), limit)
+}
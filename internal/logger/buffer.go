@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// bufferSubscriberQueue is the number of entries queued per subscriber
+// before new entries are dropped for it.
+const bufferSubscriberQueue = 256
+
+// Entry is a log line kept by a Buffer.
+type Entry struct {
+	Time  time.Time
+	Level Level
+	// Line is the JSON encoded event, whatever the output format.
+	Line json.RawMessage
+}
+
+// AtLeast reports whether the entry level is min or more severe.
+func (e Entry) AtLeast(min Level) bool {
+	return zerologLevel(e.Level) >= zerologLevel(min)
+}
+
+// Buffer keeps the most recent log lines written through the loggers it
+// is attached to, and hands new ones to subscribers. Lines dropped by the
+// current level are not kept.
+type Buffer struct {
+	mu          sync.RWMutex
+	entries     []Entry
+	next        int
+	full        bool
+	subscribers map[chan Entry]struct{}
+}
+
+// NewBuffer creates a buffer holding the last size lines.
+func NewBuffer(size int) *Buffer {
+	return &Buffer{
+		entries:     make([]Entry, size),
+		subscribers: make(map[chan Entry]struct{}),
+	}
+}
+
+// Size returns the number of lines the buffer holds when full.
+func (b *Buffer) Size() int {
+	return len(b.entries)
+}
+
+func (b *Buffer) add(level zerolog.Level, p []byte) {
+	entry := Entry{
+		Time:  time.Now().UTC(),
+		Level: levelOf(level),
+		Line:  json.RawMessage(bytes.TrimSpace(bytes.Clone(p))),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.entries) > 0 {
+		b.entries[b.next] = entry
+		b.next = (b.next + 1) % len(b.entries)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// Entries returns the kept lines, oldest first.
+func (b *Buffer) Entries() []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.full {
+		return append([]Entry(nil), b.entries[:b.next]...)
+	}
+	entries := make([]Entry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.next:]...)
+	return append(entries, b.entries[:b.next]...)
+}
+
+// Subscribe registers a subscriber receiving every line added from now
+// on. A subscriber that falls behind misses lines. The returned function
+// unsubscribes and closes the channel; it is safe to call more than once.
+func (b *Buffer) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, bufferSubscriberQueue)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func levelOf(level zerolog.Level) Level {
+	switch {
+	case level <= zerolog.DebugLevel:
+		return LevelDebug
+	case level == zerolog.InfoLevel:
+		return LevelInfo
+	case level == zerolog.WarnLevel:
+		return LevelWarn
+	case level <= zerolog.PanicLevel:
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func entryMessages(t *testing.T, entries []Entry) []string {
+	t.Helper()
+
+	messages := make([]string, len(entries))
+	for i, entry := range entries {
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(entry.Line, &fields))
+		messages[i], _ = fields["message"].(string)
+	}
+	return messages
+}
+
+func TestBuffer_KeepsLastLines(t *testing.T) {
+	buffer := NewBuffer(3)
+	log := New(Config{Output: &bytes.Buffer{}, Format: FormatJSON, Buffer: buffer})
+	assert.Same(t, buffer, log.Buffer())
+
+	log.Info("one")
+	log.Info("two")
+	assert.Equal(t, []string{"one", "two"}, entryMessages(t, buffer.Entries()))
+
+	for i := 3; i <= 5; i++ {
+		log.Info(fmt.Sprintf("line %d", i))
+	}
+	assert.Equal(t, []string{"line 3", "line 4", "line 5"}, entryMessages(t, buffer.Entries()))
+}
+
+func TestBuffer_FollowsLevelAndKeepsJSON(t *testing.T) {
+	buffer := NewBuffer(10)
+	log := New(Config{Level: LevelWarn, Output: &bytes.Buffer{}, Format: FormatPretty, Buffer: buffer})
+
+	log.Info("dropped")
+	log.WithField("source", "xray").Error("kept")
+
+	entries := buffer.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, LevelError, entries[0].Level)
+	assert.True(t, entries[0].AtLeast(LevelWarn))
+	assert.WithinDuration(t, time.Now(), entries[0].Time, time.Minute)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(entries[0].Line, &fields))
+	assert.Equal(t, "xray", fields["source"])
+}
+
+func TestBuffer_Subscribe(t *testing.T) {
+	buffer := NewBuffer(0)
+	log := New(Config{Output: &bytes.Buffer{}, Format: FormatJSON, Buffer: buffer})
+
+	ch, unsubscribe := buffer.Subscribe()
+	log.Warn("streamed")
+
+	select {
+	case entry := <-ch:
+		assert.Equal(t, LevelWarn, entry.Level)
+		assert.Equal(t, []string{"streamed"}, entryMessages(t, []Entry{entry}))
+	case <-time.After(time.Second):
+		t.Fatal("no entry received")
+	}
+	assert.Empty(t, buffer.Entries())
+
+	unsubscribe()
+	unsubscribe()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	log.Info("after unsubscribe")
+}
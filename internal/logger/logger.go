@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -28,40 +31,144 @@ type Config struct {
 	Level  Level
 	Format Format
 	Output io.Writer
+	// Buffer, when set, keeps the recent lines for Logger.Buffer.
+	Buffer *Buffer
 }
 
+// Logger writes structured logs. Loggers derived with WithField and
+// WithError share the level and format of the logger they come from, so
+// SetLevel and SetFormat apply to all of them at runtime.
 type Logger struct {
-	zl zerolog.Logger
+	zl  zerolog.Logger
+	out *output
 }
 
-func New(cfg Config) *Logger {
-	if cfg.Output == nil {
-		cfg.Output = os.Stdout
+// output filters events by the current level and encodes them in the
+// current format.
+type output struct {
+	level atomic.Int32
+
+	mu     sync.RWMutex
+	dest   io.Writer
+	format Format
+	w      io.Writer
+
+	buffer *Buffer
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.RLock()
+	w := o.w
+	o.mu.RUnlock()
+	return w.Write(p)
+}
+
+func (o *output) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.Level(o.level.Load()) {
+		return len(p), nil
+	}
+	if o.buffer != nil {
+		o.buffer.add(level, p)
 	}
+	return o.Write(p)
+}
 
-	var output io.Writer = cfg.Output
+func (o *output) setFormat(format Format) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 
-	if cfg.Format == FormatPretty {
-		output = zerolog.ConsoleWriter{
-			Out:        cfg.Output,
+	o.format = format
+	if format == FormatPretty {
+		o.w = zerolog.ConsoleWriter{
+			Out:        o.dest,
 			TimeFormat: "2006-01-02 15:04:05.000",
 		}
+		return
+	}
+	o.w = o.dest
+}
+
+func New(cfg Config) *Logger {
+	if cfg.Output == nil {
+		cfg.Output = os.Stdout
 	}
 
-	zl := zerolog.New(output).With().Timestamp().Logger()
+	out := &output{dest: cfg.Output, buffer: cfg.Buffer}
+	out.setFormat(cfg.Format)
+	out.level.Store(int32(zerologLevel(cfg.Level)))
+
+	// Events are filtered by out, whose level can change; the logger level
+	// only drops trace events early.
+	zl := zerolog.New(out).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	return &Logger{zl: zl, out: out}
+}
 
-	switch cfg.Level {
+func zerologLevel(level Level) zerolog.Level {
+	switch level {
 	case LevelDebug:
-		zl = zl.Level(zerolog.DebugLevel)
+		return zerolog.DebugLevel
 	case LevelWarn:
-		zl = zl.Level(zerolog.WarnLevel)
+		return zerolog.WarnLevel
 	case LevelError:
-		zl = zl.Level(zerolog.ErrorLevel)
+		return zerolog.ErrorLevel
 	default:
-		zl = zl.Level(zerolog.InfoLevel)
+		return zerolog.InfoLevel
 	}
+}
+
+// ParseLevel returns the level named s.
+func ParseLevel(s string) (Level, error) {
+	switch level := Level(s); level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return level, nil
+	default:
+		return "", fmt.Errorf("unknown log level %q", s)
+	}
+}
 
-	return &Logger{zl: zl}
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatJSON, FormatPretty:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q", s)
+	}
+}
+
+// SetLevel changes the level of this logger and every logger sharing its
+// output.
+func (l *Logger) SetLevel(level Level) {
+	l.out.level.Store(int32(zerologLevel(level)))
+}
+
+// Level returns the current level.
+func (l *Logger) Level() Level {
+	return levelOf(zerolog.Level(l.out.level.Load()))
+}
+
+// Buffer returns the buffer keeping the recent lines, or nil when the
+// logger was created without one.
+func (l *Logger) Buffer() *Buffer {
+	return l.out.buffer
+}
+
+// SetFormat changes the format of this logger and every logger sharing
+// its output.
+func (l *Logger) SetFormat(format Format) {
+	l.out.setFormat(format)
+}
+
+// Format returns the current format.
+func (l *Logger) Format() Format {
+	l.out.mu.RLock()
+	defer l.out.mu.RUnlock()
+
+	if l.out.format == FormatPretty {
+		return FormatPretty
+	}
+	return FormatJSON
 }
 
 func (l *Logger) Debug(msg string) {
@@ -81,11 +188,11 @@ func (l *Logger) Error(msg string) {
 }
 
 func (l *Logger) WithField(key string, value interface{}) *Logger {
-	return &Logger{zl: l.zl.With().Interface(key, value).Logger()}
+	return &Logger{zl: l.zl.With().Interface(key, value).Logger(), out: l.out}
 }
 
 func (l *Logger) WithError(err error) *Logger {
-	return &Logger{zl: l.zl.With().Err(err).Logger()}
+	return &Logger{zl: l.zl.With().Err(err).Logger(), out: l.out}
 }
 
 func (l *Logger) Zerolog() *zerolog.Logger {
@@ -194,3 +194,63 @@ func TestLogger_Zerolog_ReturnsUnderlyingLogger(t *testing.T) {
 	zl := log.Zerolog()
 	assert.NotNil(t, zl)
 }
+
+func TestLogger_SetLevelAppliesToDerivedLoggers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:  LevelInfo,
+		Output: buf,
+		Format: FormatJSON,
+	})
+	child := log.WithField("component", "test")
+
+	child.Debug("hidden")
+	log.SetLevel(LevelDebug)
+	assert.Equal(t, LevelDebug, child.Level())
+	child.Debug("shown")
+
+	child.SetLevel(LevelError)
+	log.Warn("hidden warning")
+
+	output := buf.String()
+	assert.NotContains(t, output, "hidden")
+	assert.Contains(t, output, "shown")
+	assert.Equal(t, LevelError, log.Level())
+}
+
+func TestLogger_SetFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := New(Config{
+		Level:  LevelInfo,
+		Output: buf,
+		Format: FormatJSON,
+	})
+	child := log.WithField("key", "value")
+
+	log.SetFormat(FormatPretty)
+	assert.Equal(t, FormatPretty, child.Format())
+	child.Info("pretty message")
+	assert.False(t, json.Valid(bytes.TrimSpace(buf.Bytes())))
+	assert.Contains(t, buf.String(), "pretty message")
+
+	buf.Reset()
+	log.SetFormat(FormatJSON)
+	child.Info("json message")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "value", entry["key"])
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarn, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+
+	format, err := ParseFormat("pretty")
+	require.NoError(t, err)
+	assert.Equal(t, FormatPretty, format)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
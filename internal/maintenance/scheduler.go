@@ -0,0 +1,203 @@
+package maintenance
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// checkInterval is how often the scheduler checks whether the window
+// opened; schedules have a one minute resolution.
+const checkInterval = 15 * time.Second
+
+// Action is a deferred action waiting for the window.
+type Action struct {
+	Name string `json:"name"`
+	// Since is when the action was first deferred.
+	Since time.Time `json:"since"`
+}
+
+type pendingAction struct {
+	fn    func()
+	since time.Time
+}
+
+// Scheduler runs deferred actions once the maintenance window is open.
+// Actions are keyed by name: deferring an action already pending replaces
+// its function, so only the latest one runs.
+type Scheduler struct {
+	window *Window
+	log    *logger.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pendingAction
+
+	wakeCh chan struct{}
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler for window. Without a window, actions
+// run as soon as they are deferred.
+func NewScheduler(window *Window, log *logger.Logger) *Scheduler {
+	return &Scheduler{
+		window:  window,
+		log:     log,
+		now:     time.Now,
+		pending: make(map[string]*pendingAction),
+		wakeCh:  make(chan struct{}, 1),
+	}
+}
+
+// Window returns the maintenance window, nil when none is configured.
+func (s *Scheduler) Window() *Window {
+	if s == nil {
+		return nil
+	}
+	return s.window
+}
+
+// Closed reports whether a window is configured and currently closed, that
+// is whether a deferred action would wait. It is false on a nil scheduler.
+func (s *Scheduler) Closed() bool {
+	if s == nil || s.window == nil {
+		return false
+	}
+	return !s.window.Contains(s.now())
+}
+
+// Defer runs fn right away when no window is configured. Otherwise fn is
+// queued for the scheduler goroutine, which runs it once the window is
+// open, possibly right away; it never runs on the caller's goroutine then.
+// On a nil scheduler fn runs right away.
+func (s *Scheduler) Defer(name string, fn func()) {
+	if s == nil || s.window == nil {
+		fn()
+		return
+	}
+
+	s.mu.Lock()
+	if action, ok := s.pending[name]; ok {
+		action.fn = fn
+	} else {
+		s.pending[name] = &pendingAction{fn: fn, since: s.now()}
+	}
+	s.mu.Unlock()
+
+	if s.Closed() {
+		s.log.WithField("action", name).Info("Action deferred to the maintenance window")
+	}
+
+	select {
+	case s.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel drops the pending action called name, if any. It is a no-op on a
+// nil scheduler.
+func (s *Scheduler) Cancel(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, name)
+}
+
+// Pending returns the actions waiting for the window, oldest first.
+func (s *Scheduler) Pending() []Action {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actions := make([]Action, 0, len(s.pending))
+	for name, action := range s.pending {
+		actions = append(actions, Action{Name: name, Since: action.since})
+	}
+	sortActions(actions)
+	return actions
+}
+
+// Start launches the goroutine running the pending actions when the
+// window opens. It does nothing without a window.
+func (s *Scheduler) Start() {
+	if s.window == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.stopCh != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.stopCh = make(chan struct{})
+	stopCh := s.stopCh
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-s.wakeCh:
+				s.runDue()
+			case <-ticker.C:
+				s.runDue()
+			}
+		}
+	}()
+}
+
+// Stop terminates the goroutine. Actions still pending are dropped.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stopCh := s.stopCh
+	s.stopCh = nil
+	s.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		s.wg.Wait()
+	}
+}
+
+// runDue runs the pending actions, oldest first, if the window is open.
+func (s *Scheduler) runDue() {
+	if s.Closed() {
+		return
+	}
+
+	s.mu.Lock()
+	actions := make([]Action, 0, len(s.pending))
+	fns := make(map[string]func(), len(s.pending))
+	for name, action := range s.pending {
+		actions = append(actions, Action{Name: name, Since: action.since})
+		fns[name] = action.fn
+	}
+	s.pending = make(map[string]*pendingAction)
+	s.mu.Unlock()
+
+	sortActions(actions)
+	for _, action := range actions {
+		s.log.WithField("action", action.Name).Info("Running deferred action in the maintenance window")
+		fns[action.Name]()
+	}
+}
+
+func sortActions(actions []Action) {
+	sort.Slice(actions, func(i, j int) bool {
+		if !actions[i].Since.Equal(actions[j].Since) {
+			return actions[i].Since.Before(actions[j].Since)
+		}
+		return actions[i].Name < actions[j].Name
+	})
+}
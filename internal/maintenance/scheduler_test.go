@@ -0,0 +1,71 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func newTestScheduler(t *testing.T, window *Window, now time.Time) *Scheduler {
+	t.Helper()
+	s := NewScheduler(window, logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestScheduler_WithoutWindowRunsRightAway(t *testing.T) {
+	s := newTestScheduler(t, nil, date(1, 12, 0))
+	ran := false
+	s.Defer("restart", func() { ran = true })
+	assert.True(t, ran)
+	assert.False(t, s.Closed())
+
+	var nilScheduler *Scheduler
+	ran = false
+	nilScheduler.Defer("restart", func() { ran = true })
+	assert.True(t, ran)
+}
+
+func TestScheduler_WaitsForWindow(t *testing.T) {
+	window, err := ParseWindow("0 3 * * *", time.Hour)
+	require.NoError(t, err)
+	s := newTestScheduler(t, window, date(1, 12, 0))
+
+	var runs []string
+	s.Defer("restart", func() { runs = append(runs, "first") })
+	s.Defer("geodata", func() { runs = append(runs, "geodata") })
+	s.Defer("restart", func() { runs = append(runs, "restart") })
+	s.Defer("upgrade", func() { runs = append(runs, "upgrade") })
+	s.Cancel("upgrade")
+	assert.True(t, s.Closed())
+
+	s.runDue()
+	assert.Empty(t, runs, "closed window")
+	require.Len(t, s.Pending(), 2)
+
+	s.now = func() time.Time { return date(2, 3, 10) }
+	s.runDue()
+	assert.ElementsMatch(t, []string{"restart", "geodata"}, runs)
+	assert.Empty(t, s.Pending())
+}
+
+func TestScheduler_RunsOnGoroutineWhenOpen(t *testing.T) {
+	window, err := ParseWindow("0 3 * * *", time.Hour)
+	require.NoError(t, err)
+	s := newTestScheduler(t, window, date(1, 3, 0))
+	s.Start()
+	defer s.Stop()
+
+	done := make(chan struct{})
+	s.Defer("restart", func() { close(done) })
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deferred action did not run in an open window")
+	}
+}
@@ -0,0 +1,199 @@
+// Package maintenance defers disruptive node actions, such as core restarts,
+// geodata updates and binary upgrades, to a recurring maintenance window
+// outside traffic peaks.
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxDuration bounds the length of a window.
+	MaxDuration = 24 * time.Hour
+	// searchLimit bounds the search for the next window start.
+	searchLimit = 366 * 24 * time.Hour
+)
+
+// aliases are the shorthand schedules accepted besides five-field
+// expressions.
+var aliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Schedule is a cron expression: minute, hour, day of month, month and day
+// of week, each a *, a value, a range, a list of those, or any of them with
+// a /step. As in cron, when both days are restricted a time matching either
+// one matches.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseSchedule parses a five-field cron expression or one of @hourly,
+// @daily, @weekly and @monthly.
+func ParseSchedule(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if alias, ok := aliases[expr]; ok {
+		expr = alias
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%q has %d fields, expected %d", expr, len(parts), len(fields))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		var err error
+		if bits[i], err = parseField(part, fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", fields[i].name, err)
+		}
+	}
+
+	// Sunday is both 0 and 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+// parseField returns the values a field selects as a bit set.
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseValue(lowPart, f); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(highPart, f); err != nil {
+					return 0, err
+				}
+				if high < low {
+					return 0, fmt.Errorf("invalid range %q", rangePart)
+				}
+			} else if hasStep {
+				high = f.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%d is out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Matches reports whether the schedule fires in the minute of t, in the
+// location of t.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 ||
+		s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny || s.dowAny:
+		return domMatch && dowMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// Window is a recurring maintenance window, opening when its schedule fires
+// and lasting Duration.
+type Window struct {
+	Expr     string
+	Schedule *Schedule
+	Duration time.Duration
+}
+
+// ParseWindow parses the schedule of a window lasting duration.
+func ParseWindow(expr string, duration time.Duration) (*Window, error) {
+	schedule, err := ParseSchedule(expr)
+	if err != nil {
+		return nil, err
+	}
+	if duration < time.Minute || duration > MaxDuration {
+		return nil, fmt.Errorf("duration %s is not between 1m and %s", duration, MaxDuration)
+	}
+	return &Window{Expr: strings.TrimSpace(expr), Schedule: schedule, Duration: duration}, nil
+}
+
+// Contains reports whether the window is open at t.
+func (w *Window) Contains(t time.Time) bool {
+	for start := t.Truncate(time.Minute); t.Sub(start) < w.Duration; start = start.Add(-time.Minute) {
+		if w.Schedule.Matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the start of the first window opening after t, or the zero
+// time when none does within a year.
+func (w *Window) Next(t time.Time) time.Time {
+	first := t.Truncate(time.Minute).Add(time.Minute)
+	for start := first; start.Sub(first) < searchLimit; start = start.Add(time.Minute) {
+		if w.Schedule.Matches(start) {
+			return start
+		}
+	}
+	return time.Time{}
+}
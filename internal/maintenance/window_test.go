@@ -0,0 +1,68 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(day, hour, minute int) time.Time {
+	// June 2026 starts on a Monday.
+	return time.Date(2026, time.June, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		matches []time.Time
+		misses  []time.Time
+	}{
+		{"30 3 * * *", []time.Time{date(1, 3, 30), date(20, 3, 30)}, []time.Time{date(1, 3, 31), date(1, 4, 30)}},
+		{"*/15 2-4 * * 1-5", []time.Time{date(1, 2, 0), date(5, 4, 45)}, []time.Time{date(1, 2, 10), date(6, 3, 0), date(1, 5, 0)}},
+		{"0 4 * * 7", []time.Time{date(7, 4, 0)}, []time.Time{date(6, 4, 0)}},
+		{"0 4 1,15 * 0", []time.Time{date(1, 4, 0), date(14, 4, 0), date(15, 4, 0)}, []time.Time{date(2, 4, 0)}},
+		{"@daily", []time.Time{date(3, 0, 0)}, []time.Time{date(3, 1, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			require.NoError(t, err)
+			for _, m := range tt.matches {
+				assert.True(t, schedule.Matches(m), m.String())
+			}
+			for _, m := range tt.misses {
+				assert.False(t, schedule.Matches(m), m.String())
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "* * 0 * *"} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	window, err := ParseWindow("30 23 * * *", 2*time.Hour)
+	require.NoError(t, err)
+
+	assert.False(t, window.Contains(date(1, 23, 29)))
+	assert.True(t, window.Contains(date(1, 23, 30)))
+	assert.True(t, window.Contains(date(2, 1, 29).Add(59*time.Second)), "the window spans midnight")
+	assert.False(t, window.Contains(date(2, 1, 30)))
+
+	assert.Equal(t, date(1, 23, 30), window.Next(date(1, 12, 0)))
+	assert.Equal(t, date(2, 23, 30), window.Next(date(1, 23, 30)))
+
+	never, err := ParseWindow("0 0 31 2 *", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, never.Next(date(1, 0, 0)).IsZero())
+
+	_, err = ParseWindow("@daily", 25*time.Hour)
+	assert.Error(t, err)
+}
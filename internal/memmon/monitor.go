@@ -0,0 +1,122 @@
+// Package memmon watches the resident memory of the xray process and
+// reports when it exceeds a threshold, so the node can restart the core
+// before slow memory growth gets it killed.
+package memmon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	checkInterval = time.Minute
+	// cooldown is the minimum time between two reports, so a core still
+	// above the threshold after a restart is not restarted in a loop.
+	cooldown = time.Hour
+)
+
+// Monitor checks the RSS of a process every minute and calls the exceeded
+// hook when it is above the threshold, at most once per cooldown.
+type Monitor struct {
+	threshold uint64
+	rss       func() (uint64, error)
+	log       *logger.Logger
+	now       func() time.Time
+
+	mu         sync.Mutex
+	reported   time.Time
+	onExceeded func(rss uint64)
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a monitor reading the RSS in bytes from rss, which is
+// above the limit when it exceeds threshold bytes. An rss error, e.g. while
+// xray is stopped, skips the check.
+func NewMonitor(threshold uint64, rss func() (uint64, error), log *logger.Logger) *Monitor {
+	return &Monitor{
+		threshold: threshold,
+		rss:       rss,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// OnExceeded sets a function called with the RSS when it exceeds the
+// threshold. It must be set before Start.
+func (m *Monitor) OnExceeded(fn func(rss uint64)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onExceeded = fn
+}
+
+// Start launches the goroutine checking the RSS every minute.
+func (m *Monitor) Start() {
+	m.mu.Lock()
+	if m.stopCh != nil {
+		m.mu.Unlock()
+		return
+	}
+	m.stopCh = make(chan struct{})
+	stopCh := m.stopCh
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				m.Check()
+			}
+		}
+	}()
+}
+
+// Stop terminates the check goroutine.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	stopCh := m.stopCh
+	m.stopCh = nil
+	m.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		m.wg.Wait()
+	}
+}
+
+// Check reads the RSS and reports it if it exceeds the threshold and the
+// last report is older than the cooldown.
+func (m *Monitor) Check() {
+	rss, err := m.rss()
+	if err != nil || rss <= m.threshold {
+		return
+	}
+
+	m.mu.Lock()
+	now := m.now()
+	if !m.reported.IsZero() && now.Sub(m.reported) < cooldown {
+		m.mu.Unlock()
+		return
+	}
+	m.reported = now
+	onExceeded := m.onExceeded
+	m.mu.Unlock()
+
+	m.log.WithField("rssMiB", rss>>20).WithField("thresholdMiB", m.threshold>>20).
+		Warn("Xray memory usage exceeds the threshold")
+	if onExceeded != nil {
+		onExceeded(rss)
+	}
+}
@@ -0,0 +1,55 @@
+package memmon
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestMonitor_ReportsOncePerCooldown(t *testing.T) {
+	rss := uint64(100 << 20)
+	var rssErr error
+	m := NewMonitor(200<<20, func() (uint64, error) { return rss, rssErr }, logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	var reports []uint64
+	m.OnExceeded(func(rss uint64) { reports = append(reports, rss) })
+
+	m.Check()
+	assert.Empty(t, reports, "below the threshold")
+
+	rss = 300 << 20
+	m.Check()
+	m.Check()
+	assert.Equal(t, []uint64{300 << 20}, reports, "reported once per cooldown")
+
+	now = now.Add(cooldown)
+	rssErr = errors.New("xray is not running")
+	m.Check()
+	assert.Len(t, reports, 1, "read errors skip the check")
+
+	rssErr = nil
+	m.Check()
+	assert.Len(t, reports, 2)
+}
+
+func TestProcessRSS(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process memory usage is only read on Linux")
+	}
+
+	rss, err := ProcessRSS(os.Getpid())
+	require.NoError(t, err)
+	assert.Greater(t, rss, uint64(1<<20))
+
+	_, err = ProcessRSS(-1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package memmon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProcessRSS returns the resident set size of the process pid in bytes,
+// read from /proc.
+func ProcessRSS(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format %q", strings.TrimSpace(string(data)))
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resident page count: %w", err)
+	}
+	return pages * uint64(os.Getpagesize()), nil
+}
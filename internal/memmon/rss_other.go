@@ -0,0 +1,10 @@
+//go:build !linux
+
+package memmon
+
+import "errors"
+
+// ProcessRSS is not implemented: the monitor checks nothing outside Linux.
+func ProcessRSS(pid int) (uint64, error) {
+	return 0, errors.New("process memory usage is not supported on this platform")
+}
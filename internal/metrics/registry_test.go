@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+	registry.Gauge("node_up", "Whether the node is up.", func() []Sample {
+		return []Sample{{Value: 1}}
+	})
+	registry.Counter("node_requests_total", "Requests by \"path\".\nSecond line.", func() []Sample {
+		return []Sample{
+			{Labels: map[string]string{"path": "/a", "code": "200"}, Value: 3},
+			{Labels: map[string]string{"path": "x\"y\\z"}, Value: 1.5},
+		}
+	})
+
+	var buf bytes.Buffer
+	n, err := registry.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Equal(t, `# HELP node_up Whether the node is up.
+# TYPE node_up gauge
+node_up 1
+# HELP node_requests_total Requests by "path".\nSecond line.
+# TYPE node_requests_total counter
+node_requests_total{code="200",path="/a"} 3
+node_requests_total{path="x\"y\\z"} 1.5
+`, buf.String())
+}
+
+func TestRegistry_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := NewRegistry().WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
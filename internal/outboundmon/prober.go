@@ -0,0 +1,287 @@
+// Package outboundmon checks that the outbounds of xray reach the
+// internet, by connecting to a target through each of them periodically,
+// so the panel can steer users away from broken exits.
+package outboundmon
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// Target is what a probe connects to: tcp://host:port succeeds once the
+// server sends its first bytes, as SSH or SMTP servers do, tls://host:port
+// once the TLS handshake completes, and an http(s) URL once the response
+// headers arrive, whatever their status.
+type Target struct {
+	Kind    string
+	Address string
+	URL     string
+}
+
+// ParseTarget parses a probe target.
+func ParseTarget(value string) (Target, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Target{}, fmt.Errorf("invalid probe target: %w", err)
+	}
+	switch u.Scheme {
+	case "tcp", "tls":
+		if u.Hostname() == "" || u.Port() == "" {
+			return Target{}, fmt.Errorf("probe target %q needs a host and a port", value)
+		}
+		return Target{Kind: u.Scheme, Address: u.Host}, nil
+	case "http", "https":
+		if u.Hostname() == "" {
+			return Target{}, fmt.Errorf("probe target %q has no host", value)
+		}
+		return Target{Kind: "http", URL: value}, nil
+	default:
+		return Target{}, fmt.Errorf("invalid probe target scheme %q, expected tcp, tls, http or https", u.Scheme)
+	}
+}
+
+// DialFunc opens a connection to address through the outbound tagged tag,
+// as xray.Core.DialOutbound does.
+type DialFunc func(ctx context.Context, tag, network, address string) (net.Conn, error)
+
+// Status is the health of an outbound.
+type Status struct {
+	Outbound string `json:"outbound"`
+	// Alive is whether the last probe succeeded.
+	Alive bool `json:"alive"`
+	// LatencyMs is the duration of the last successful probe.
+	LatencyMs int64     `json:"latencyMs"`
+	LastProbe time.Time `json:"lastProbe"`
+	LastError string    `json:"lastError,omitempty"`
+	// ConsecutiveFailures counts the probes failed since the last success.
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	Probes              uint64 `json:"probes"`
+	Failures            uint64 `json:"failures"`
+}
+
+// Prober probes a set of outbounds every interval, in parallel, and keeps
+// the status of each.
+type Prober struct {
+	tags     []string
+	target   Target
+	interval time.Duration
+	timeout  time.Duration
+	dial     DialFunc
+	log      *logger.Logger
+
+	mu       sync.Mutex
+	statuses map[string]*Status
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New creates a prober of the outbounds tagged tags.
+func New(tags []string, target Target, interval, timeout time.Duration, dial DialFunc, log *logger.Logger) *Prober {
+	return &Prober{
+		tags:     tags,
+		target:   target,
+		interval: interval,
+		timeout:  timeout,
+		dial:     dial,
+		log:      log,
+		statuses: make(map[string]*Status),
+	}
+}
+
+// ParseTags splits a comma-separated list of outbound tags.
+func ParseTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Start launches the probing goroutine. The first probes run right away.
+func (p *Prober) Start() {
+	p.mu.Lock()
+	if p.stopCh != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stopCh = make(chan struct{})
+	stopCh := p.stopCh
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			p.Probe()
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop terminates the probing goroutine, after the probes in progress.
+func (p *Prober) Stop() {
+	p.mu.Lock()
+	stopCh := p.stopCh
+	p.stopCh = nil
+	p.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		p.wg.Wait()
+	}
+}
+
+// Probe probes every outbound once and records the results.
+func (p *Prober) Probe() {
+	var wg sync.WaitGroup
+	for _, tag := range p.tags {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := p.probe(tag)
+			p.record(tag, start, time.Since(start), err)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *Prober) record(tag string, at time.Time, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status, exists := p.statuses[tag]
+	if !exists {
+		status = &Status{Outbound: tag}
+		p.statuses[tag] = status
+	}
+	status.LastProbe = at
+	status.Probes++
+	if err == nil {
+		if status.ConsecutiveFailures > 0 {
+			p.log.WithField("outbound", tag).Info("Outbound is reachable again")
+		}
+		status.Alive = true
+		status.LatencyMs = latency.Milliseconds()
+		status.LastError = ""
+		status.ConsecutiveFailures = 0
+		return
+	}
+
+	if status.ConsecutiveFailures == 0 {
+		p.log.WithError(err).WithField("outbound", tag).Warn("Outbound probe failed")
+	}
+	status.Alive = false
+	status.LastError = err.Error()
+	status.ConsecutiveFailures++
+	status.Failures++
+}
+
+// Statuses returns the status of each probed outbound, sorted by tag.
+// Outbounds not probed yet are left out.
+func (p *Prober) Statuses() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]Status, 0, len(p.statuses))
+	for _, status := range p.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Outbound < statuses[j].Outbound })
+	return statuses
+}
+
+// probe connects to the target through the outbound tagged tag within the
+// timeout.
+func (p *Prober) probe(tag string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	if p.target.Kind == "http" {
+		return p.fetch(ctx, tag)
+	}
+
+	conn, err := p.dial(ctx, tag, "tcp", p.target.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Reads through an outbound ignore deadlines; closing the connection
+	// ends them.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if p.target.Kind == "tls" {
+		host, _, _ := net.SplitHostPort(p.target.Address)
+		err = tls.Client(conn, &tls.Config{ServerName: host}).HandshakeContext(ctx)
+	} else {
+		_, err = conn.Read(make([]byte, 1))
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("no answer from %s within %s", p.target.Address, p.timeout)
+	}
+	return err
+}
+
+// fetch requests the target URL through the outbound tagged tag.
+func (p *Prober) fetch(ctx context.Context, tag string) error {
+	transport := &http.Transport{
+		// The connection lives within the probe context rather than the
+		// dial one, which the transport may end once dialed.
+		DialContext: func(_ context.Context, network, address string) (net.Conn, error) {
+			conn, err := p.dial(ctx, tag, network, address)
+			if err != nil {
+				return nil, err
+			}
+			// Reads through an outbound ignore deadlines; closing the
+			// connection at the end of the probe ends them.
+			context.AfterFunc(ctx, func() { conn.Close() })
+			return conn, nil
+		},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.target.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("no answer from %s within %s", p.target.URL, p.timeout)
+		}
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,149 @@
+package outboundmon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// directDial connects without xray; the "broken" outbound fails.
+func directDial(ctx context.Context, tag, network, address string) (net.Conn, error) {
+	if tag == "broken" {
+		return nil, errors.New("outbound not found: broken")
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, address)
+}
+
+func newTestProber(t *testing.T, tags []string, target string, timeout time.Duration) *Prober {
+	t.Helper()
+	parsed, err := ParseTarget(target)
+	require.NoError(t, err)
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return New(tags, parsed, time.Minute, timeout, directDial, log)
+}
+
+// newTestTCPServer accepts connections and, when banner is set, greets
+// them with it.
+func newTestTCPServer(t *testing.T, banner string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if banner != "" {
+					io.WriteString(conn, banner)
+				}
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("https://www.google.com/generate_204")
+	require.NoError(t, err)
+	assert.Equal(t, Target{Kind: "http", URL: "https://www.google.com/generate_204"}, target)
+
+	target, err = ParseTarget("tls://1.1.1.1:443")
+	require.NoError(t, err)
+	assert.Equal(t, Target{Kind: "tls", Address: "1.1.1.1:443"}, target)
+
+	for _, value := range []string{"tcp://example.com", "udp://1.1.1.1:53", "https://", "example.com:443"} {
+		_, err := ParseTarget(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"direct", "warp"}, ParseTags(" direct, ,warp "))
+	assert.Nil(t, ParseTags(""))
+}
+
+func TestProber_HTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	p := newTestProber(t, []string{"direct", "broken"}, server.URL+"/generate_204", 5*time.Second)
+	p.Probe()
+	p.Probe()
+
+	statuses := p.Statuses()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "broken", statuses[0].Outbound)
+	assert.False(t, statuses[0].Alive)
+	assert.Contains(t, statuses[0].LastError, "outbound not found: broken")
+	assert.Equal(t, 2, statuses[0].ConsecutiveFailures)
+	assert.Equal(t, uint64(2), statuses[0].Failures)
+
+	assert.Equal(t, "direct", statuses[1].Outbound)
+	assert.True(t, statuses[1].Alive)
+	assert.Empty(t, statuses[1].LastError)
+	assert.Equal(t, uint64(2), statuses[1].Probes)
+	assert.Zero(t, statuses[1].Failures)
+	assert.False(t, statuses[1].LastProbe.IsZero())
+}
+
+func TestProber_TCP(t *testing.T) {
+	p := newTestProber(t, []string{"direct"}, "tcp://"+newTestTCPServer(t, "SSH-2.0-test\r\n"), 5*time.Second)
+	p.Probe()
+	assert.True(t, p.Statuses()[0].Alive)
+
+	// A server that never speaks first times out, and is reachable again
+	// once it does.
+	p = newTestProber(t, []string{"direct"}, "tcp://"+newTestTCPServer(t, ""), 100*time.Millisecond)
+	p.Probe()
+	status := p.Statuses()[0]
+	assert.False(t, status.Alive)
+	assert.Contains(t, status.LastError, "within 100ms")
+
+	p.target.Address = newTestTCPServer(t, "220 smtp\r\n")
+	p.Probe()
+	status = p.Statuses()[0]
+	assert.True(t, status.Alive)
+	assert.Zero(t, status.ConsecutiveFailures)
+	assert.Equal(t, uint64(1), status.Failures)
+}
+
+func TestProber_TLS(t *testing.T) {
+	// The test server certificate is not trusted, so the handshake fails.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p := newTestProber(t, []string{"direct"}, "tls://"+strings.TrimPrefix(server.URL, "https://"), 5*time.Second)
+	p.Probe()
+	status := p.Statuses()[0]
+	assert.False(t, status.Alive)
+	assert.Contains(t, status.LastError, "certificate")
+}
+
+func TestProber_StartStop(t *testing.T) {
+	p := newTestProber(t, []string{"direct"}, "tcp://"+newTestTCPServer(t, "hello"), 5*time.Second)
+	p.Start()
+	p.Start()
+	require.Eventually(t, func() bool { return len(p.Statuses()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	p.Stop()
+	p.Stop()
+}
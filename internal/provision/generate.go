@@ -0,0 +1,213 @@
+// Package provision creates and inspects the credentials of a node: the CA
+// shared with the panel, the node certificate, the panel client
+// certificate and the JWT key pair, as carried by SECRET_KEY.
+package provision
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+const (
+	DefaultNodeName   = "remnawave-node"
+	DefaultPanelName  = "remnawave-panel"
+	DefaultValidity   = 10 * 365 * 24 * time.Hour
+	jwtRSAKeyBits     = 2048
+	serialNumberBytes = 16
+)
+
+// Options configures Generate.
+type Options struct {
+	// NodeName is the common name of the node certificate.
+	NodeName string
+	// PanelName is the common name of the panel client certificate.
+	PanelName string
+	// Hosts are the IPs and DNS names the panel reaches the node at, put
+	// in the node certificate.
+	Hosts []string
+	// Validity is how long the certificates are valid.
+	Validity time.Duration
+}
+
+// Bundle holds generated credentials, all PEM encoded. The node gets
+// SecretKey; the panel keeps the CA, its client certificate and the JWT
+// private key.
+type Bundle struct {
+	SecretKey     string
+	Payload       *config.NodePayload
+	CACertPEM     []byte
+	CAKeyPEM      []byte
+	ClientCertPEM []byte
+	ClientKeyPEM  []byte
+	JWTPrivatePEM []byte
+	JWTPublicPEM  []byte
+}
+
+// Generate creates a CA, a node and a panel client certificate signed by
+// it, and an RSA key pair for RS256 panel JWTs, for setups without a panel
+// issuing them.
+func Generate(opts Options) (*Bundle, error) {
+	if opts.NodeName == "" {
+		opts.NodeName = DefaultNodeName
+	}
+	if opts.PanelName == "" {
+		opts.PanelName = DefaultPanelName
+	}
+	if opts.Validity <= 0 {
+		opts.Validity = DefaultValidity
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.Add(opts.Validity)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: opts.NodeName + " CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caCertPEM, caCert, err := createCertificate(caTemplate, nil, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	nodeTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: opts.NodeName},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range opts.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			nodeTemplate.IPAddresses = append(nodeTemplate.IPAddresses, ip)
+		} else if host != "" {
+			nodeTemplate.DNSNames = append(nodeTemplate.DNSNames, host)
+		}
+	}
+	nodeCertPEM, nodeKeyPEM, err := createLeaf(nodeTemplate, caCert, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node certificate: %w", err)
+	}
+
+	clientTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: opts.PanelName},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientCertPEM, clientKeyPEM, err := createLeaf(clientTemplate, caCert, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create panel client certificate: %w", err)
+	}
+
+	jwtKey, err := rsa.GenerateKey(rand.Reader, jwtRSAKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT key: %w", err)
+	}
+	jwtPrivatePEM, err := encodePrivateKey(jwtKey)
+	if err != nil {
+		return nil, err
+	}
+	jwtPublicDER, err := x509.MarshalPKIXPublicKey(&jwtKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	jwtPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: jwtPublicDER})
+
+	caKeyPEM, err := encodePrivateKey(caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &config.NodePayload{
+		CACertPEM:    string(caCertPEM),
+		JWTPublicKey: string(jwtPublicPEM),
+		NodeCertPEM:  string(nodeCertPEM),
+		NodeKeyPEM:   string(nodeKeyPEM),
+	}
+	secretKey, err := config.EncodeSecretKey(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bundle{
+		SecretKey:     secretKey,
+		Payload:       payload,
+		CACertPEM:     caCertPEM,
+		CAKeyPEM:      caKeyPEM,
+		ClientCertPEM: clientCertPEM,
+		ClientKeyPEM:  clientKeyPEM,
+		JWTPrivatePEM: jwtPrivatePEM,
+		JWTPublicPEM:  jwtPublicPEM,
+	}, nil
+}
+
+// createLeaf creates a P-256 key and a certificate for it signed by the CA.
+func createLeaf(template, caCert *x509.Certificate, caKey crypto.Signer) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM, _, err := createCertificate(template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// createCertificate signs template with signer, as issued by parent, or
+// self-signed when parent is nil.
+func createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) ([]byte, *x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialNumberBytes*8))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.SerialNumber = serial
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, nil
+}
+
+func encodePrivateKey(key crypto.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, errors.New("failed to encode private key: " + err.Error())
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
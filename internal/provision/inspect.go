@@ -0,0 +1,207 @@
+package provision
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+// CertInfo describes a certificate of a SECRET_KEY.
+type CertInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	DaysLeft  int       `json:"daysLeft"`
+	Expired   bool      `json:"expired"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+	IPs       []string  `json:"ips,omitempty"`
+}
+
+// Report is what Inspect finds in a SECRET_KEY. TrustedCA and
+// TrustedJWTKeys are the further panel CAs and JWT keys of the payload.
+// Problems lists what would keep the node from accepting panel
+// connections.
+type Report struct {
+	CA             []CertInfo `json:"ca"`
+	Node           []CertInfo `json:"node"`
+	JWTKeys        []string   `json:"jwtKeys"`
+	TrustedCA      []CertInfo `json:"trustedCa,omitempty"`
+	TrustedJWTKeys []string   `json:"trustedJwtKeys,omitempty"`
+	Problems       []string   `json:"problems"`
+}
+
+// Inspect decodes the certificates and keys of payload and checks that
+// they fit together: the node key matches its certificate, which chains
+// to the CA, and nothing has expired.
+func Inspect(payload *config.NodePayload, now time.Time) *Report {
+	r := &Report{Problems: []string{}}
+
+	caCerts, err := parseCertificates(payload.CACertPEM)
+	if err != nil {
+		r.problem("CA certificate: %v", err)
+	}
+	for _, cert := range caCerts {
+		r.CA = append(r.CA, r.describe("CA certificate", cert, now))
+	}
+
+	nodeCerts, err := parseCertificates(payload.NodeCertPEM)
+	if err != nil {
+		r.problem("node certificate: %v", err)
+	}
+	for _, cert := range nodeCerts {
+		r.Node = append(r.Node, r.describe("node certificate", cert, now))
+	}
+
+	if _, err := tls.X509KeyPair([]byte(payload.NodeCertPEM), []byte(payload.NodeKeyPEM)); err != nil {
+		r.problem("node key: %v", err)
+	}
+
+	if len(caCerts) > 0 && len(nodeCerts) > 0 {
+		roots := x509.NewCertPool()
+		for _, cert := range caCerts {
+			roots.AddCert(cert)
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range nodeCerts[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := nodeCerts[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			r.problem("node certificate is not issued by the CA: %v", err)
+		}
+	}
+
+	r.JWTKeys = r.describeKeys("JWT public key", payload.JWTPublicKey)
+
+	for i, caPEM := range payload.TrustedCACertPEMs {
+		what := fmt.Sprintf("trusted CA certificate %d", i+1)
+		certs, err := parseCertificates(caPEM)
+		if err != nil {
+			r.problem("%s: %v", what, err)
+		}
+		for _, cert := range certs {
+			r.TrustedCA = append(r.TrustedCA, r.describe(what, cert, now))
+		}
+	}
+	for i, keyPEM := range payload.TrustedJWTPublicKeys {
+		r.TrustedJWTKeys = append(r.TrustedJWTKeys, r.describeKeys(fmt.Sprintf("trusted JWT public key %d", i+1), keyPEM)...)
+	}
+
+	return r
+}
+
+// describeKeys describes the PEM public keys of keyPEM.
+func (r *Report) describeKeys(what, keyPEM string) []string {
+	var descs []string
+	blocks := 0
+	rest := []byte(keyPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks++
+		desc, err := describeKey(block)
+		if err != nil {
+			r.problem("%s: %v", what, err)
+			continue
+		}
+		descs = append(descs, desc)
+	}
+	if blocks == 0 {
+		r.problem("%s: no PEM block found", what)
+	}
+	return descs
+}
+
+// OK reports whether Inspect found no problems.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r *Report) problem(format string, args ...any) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) describe(what string, cert *x509.Certificate, now time.Time) CertInfo {
+	info := CertInfo{
+		Subject:   cert.Subject.CommonName,
+		Issuer:    cert.Issuer.CommonName,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+		DaysLeft:  int(cert.NotAfter.Sub(now).Hours() / 24),
+		Expired:   now.After(cert.NotAfter),
+		DNSNames:  cert.DNSNames,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPs = append(info.IPs, ip.String())
+	}
+	if info.Expired {
+		r.problem("%s %q expired on %s", what, info.Subject, cert.NotAfter.Format(time.DateOnly))
+	} else if now.Before(cert.NotBefore) {
+		r.problem("%s %q is not valid before %s", what, info.Subject, cert.NotBefore.Format(time.DateOnly))
+	}
+	return info
+}
+
+// parseCertificates parses every PEM-encoded certificate in the input.
+func parseCertificates(certPEM string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return certs, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
+
+// describeKey names the type and size of the public key in block.
+func describeKey(block *pem.Block) (string, error) {
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		rsaPub, rsaErr := x509.ParsePKCS1PublicKey(block.Bytes)
+		if rsaErr != nil {
+			return "", fmt.Errorf("failed to parse public key: %w", err)
+		}
+		pub = rsaPub
+	}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen()), nil
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name, nil
+	case ed25519.PublicKey:
+		return "Ed25519", nil
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
@@ -0,0 +1,109 @@
+package provision
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+func TestGenerate(t *testing.T) {
+	bundle, err := Generate(Options{NodeName: "node-1", Hosts: []string{"10.0.0.1", "node.example.com"}})
+	require.NoError(t, err)
+
+	payload, err := config.ParseSecretKey(bundle.SecretKey)
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Payload, payload)
+
+	_, err = tls.X509KeyPair([]byte(payload.NodeCertPEM), []byte(payload.NodeKeyPEM))
+	require.NoError(t, err)
+	client, err := tls.X509KeyPair(bundle.ClientCertPEM, bundle.ClientKeyPEM)
+	require.NoError(t, err)
+	_, err = tls.X509KeyPair(bundle.CACertPEM, bundle.CAKeyPEM)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(bundle.CACertPEM))
+
+	block, _ := pem.Decode([]byte(payload.NodeCertPEM))
+	nodeCert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", nodeCert.Subject.CommonName)
+	_, err = nodeCert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "node.example.com"})
+	require.NoError(t, err)
+	_, err = nodeCert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "10.0.0.1"})
+	require.NoError(t, err)
+
+	clientCert, err := x509.ParseCertificate(client.Certificate[0])
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPanelName, clientCert.Subject.CommonName)
+	_, err = clientCert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	require.NoError(t, err)
+}
+
+func TestInspect(t *testing.T) {
+	bundle, err := Generate(Options{NodeName: "node-1", Hosts: []string{"10.0.0.1"}, Validity: 30 * 24 * time.Hour})
+	require.NoError(t, err)
+
+	report := Inspect(bundle.Payload, time.Now())
+	assert.True(t, report.OK(), report.Problems)
+	require.Len(t, report.CA, 1)
+	assert.Equal(t, "node-1 CA", report.CA[0].Subject)
+	require.Len(t, report.Node, 1)
+	assert.Equal(t, "node-1", report.Node[0].Subject)
+	assert.Equal(t, "node-1 CA", report.Node[0].Issuer)
+	assert.Equal(t, []string{"10.0.0.1"}, report.Node[0].IPs)
+	assert.InDelta(t, 30, report.Node[0].DaysLeft, 1)
+	assert.Equal(t, []string{"RSA 2048"}, report.JWTKeys)
+
+	expired := Inspect(bundle.Payload, time.Now().Add(60*24*time.Hour))
+	assert.False(t, expired.OK())
+	assert.True(t, expired.Node[0].Expired)
+}
+
+func TestInspect_TrustedPanels(t *testing.T) {
+	primary, err := Generate(Options{NodeName: "node-1"})
+	require.NoError(t, err)
+	standby, err := Generate(Options{NodeName: "node-1-standby"})
+	require.NoError(t, err)
+
+	payload := *primary.Payload
+	payload.TrustedCACertPEMs = []string{standby.Payload.CACertPEM}
+	payload.TrustedJWTPublicKeys = []string{standby.Payload.JWTPublicKey}
+
+	report := Inspect(&payload, time.Now())
+	assert.True(t, report.OK(), report.Problems)
+	require.Len(t, report.TrustedCA, 1)
+	assert.Equal(t, "node-1-standby CA", report.TrustedCA[0].Subject)
+	assert.Equal(t, []string{"RSA 2048"}, report.TrustedJWTKeys)
+
+	payload.TrustedJWTPublicKeys = []string{"not a key"}
+	report = Inspect(&payload, time.Now())
+	assert.Equal(t, []string{"trusted JWT public key 1: no PEM block found"}, report.Problems)
+}
+
+func TestInspect_Mismatch(t *testing.T) {
+	a, err := Generate(Options{})
+	require.NoError(t, err)
+	b, err := Generate(Options{})
+	require.NoError(t, err)
+
+	payload := *a.Payload
+	payload.NodeKeyPEM = b.Payload.NodeKeyPEM
+	payload.CACertPEM = b.Payload.CACertPEM
+	payload.JWTPublicKey = "not a key"
+
+	report := Inspect(&payload, time.Now())
+	assert.False(t, report.OK())
+	assert.Len(t, report.Problems, 3)
+	assert.Empty(t, report.JWTKeys)
+}
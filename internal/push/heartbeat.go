@@ -0,0 +1,213 @@
+package push
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/version"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+// NodeIdentity identifies the node to the panel. The certificate
+// fingerprint is stable across restarts and addresses, so the panel can
+// register a node it does not know yet by it.
+type NodeIdentity struct {
+	CommonName string `json:"commonName"`
+	// Fingerprint is the hex SHA-256 of the DER node certificate.
+	Fingerprint string `json:"fingerprint"`
+	Hostname    string `json:"hostname"`
+	Version     string `json:"version"`
+	NodePort    int    `json:"nodePort"`
+	GRPCPort    int    `json:"grpcPort,omitempty"`
+}
+
+// HeartbeatMetrics are the key figures of the node at the time of a
+// heartbeat.
+type HeartbeatMetrics struct {
+	// Uptime is the node uptime in seconds.
+	Uptime     int64  `json:"uptime"`
+	Inbounds   int    `json:"inbounds"`
+	Users      int    `json:"users"`
+	Goroutines int    `json:"goroutines"`
+	MemAlloc   uint64 `json:"memAlloc"`
+	MemSys     uint64 `json:"memSys"`
+}
+
+// Heartbeat is the body POSTed to the panel.
+type Heartbeat struct {
+	Timestamp int64        `json:"timestamp"`
+	Node      NodeIdentity `json:"node"`
+	// Interval is the number of seconds until the next heartbeat; the
+	// panel can consider the node dead after missing a few.
+	Interval    int              `json:"interval"`
+	Stopping    bool             `json:"stopping"`
+	XrayRunning bool             `json:"xrayRunning"`
+	XrayVersion *string          `json:"xrayVersion"`
+	Metrics     HeartbeatMetrics `json:"metrics"`
+}
+
+// Heartbeater periodically tells the panel the node is alive, so it
+// detects dead nodes quickly and can register new ones. A heartbeat is
+// sent right away on Start, and a last one flagged stopping on shutdown.
+type Heartbeater struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	signer   crypto.Signer
+	identity NodeIdentity
+	xray     *controller.XrayController
+	stats    *controller.StatsController
+	configs  *xray.ConfigManager
+	log      *logger.Logger
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewHeartbeater creates a heartbeater posting to url every interval,
+// authenticated like the stats pusher: the node certificate is presented
+// as TLS client certificate and its key signs each heartbeat.
+func NewHeartbeater(url string, interval time.Duration, cfg *config.Config, xrayController *controller.XrayController, stats *controller.StatsController, configs *xray.ConfigManager, log *logger.Logger) (*Heartbeater, error) {
+	client, signer, err := newPanelClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := nodeIdentity(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Heartbeater{
+		url:      url,
+		interval: interval,
+		client:   client,
+		signer:   signer,
+		identity: identity,
+		xray:     xrayController,
+		stats:    stats,
+		configs:  configs,
+		log:      log,
+	}, nil
+}
+
+func nodeIdentity(cfg *config.Config) (NodeIdentity, error) {
+	cert, err := cfg.Payload.KeyPair()
+	if err != nil {
+		return NodeIdentity{}, fmt.Errorf("failed to load node certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return NodeIdentity{}, fmt.Errorf("failed to parse node certificate: %w", err)
+	}
+	fingerprint := sha256.Sum256(leaf.Raw)
+	hostname, _ := os.Hostname()
+
+	return NodeIdentity{
+		CommonName:  leaf.Subject.CommonName,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		Hostname:    hostname,
+		Version:     version.Version,
+		NodePort:    cfg.NodePort,
+		GRPCPort:    cfg.GRPCPort,
+	}, nil
+}
+
+// Start sends a first heartbeat and launches the goroutine sending one
+// every interval.
+func (h *Heartbeater) Start() {
+	h.mu.Lock()
+	if h.stopCh != nil {
+		h.mu.Unlock()
+		return
+	}
+	h.stopCh = make(chan struct{})
+	stopCh := h.stopCh
+	h.mu.Unlock()
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+
+		for {
+			if err := h.Send(false); err != nil {
+				h.log.WithError(err).WithField("url", h.url).Warn("Failed to send heartbeat to panel")
+			}
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop terminates the heartbeat goroutine.
+func (h *Heartbeater) Stop() {
+	h.mu.Lock()
+	stopCh := h.stopCh
+	h.stopCh = nil
+	h.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		h.wg.Wait()
+	}
+}
+
+// Send posts a heartbeat to the panel; stopping tells it the node is
+// shutting down.
+func (h *Heartbeater) Send(stopping bool) error {
+	body, err := json.Marshal(h.heartbeat(time.Now(), stopping))
+	if err != nil {
+		return err
+	}
+	return post(h.client, h.signer, h.url, body)
+}
+
+func (h *Heartbeater) heartbeat(now time.Time, stopping bool) Heartbeat {
+	status := h.xray.Status()
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	inbounds := h.configs.GetXtlsConfigInbounds()
+	users := 0
+	for _, tag := range inbounds {
+		ids, _ := h.configs.GetInboundUsers(tag)
+		users += len(ids)
+	}
+
+	return Heartbeat{
+		Timestamp:   now.Unix(),
+		Node:        h.identity,
+		Interval:    int(h.interval / time.Second),
+		Stopping:    stopping,
+		XrayRunning: status.IsRunning,
+		XrayVersion: status.Version,
+		Metrics: HeartbeatMetrics{
+			Uptime:     h.stats.SystemStats().Uptime,
+			Inbounds:   len(inbounds),
+			Users:      users,
+			Goroutines: runtime.NumGoroutine(),
+			MemAlloc:   memStats.Alloc,
+			MemSys:     memStats.Sys,
+		},
+	}
+}
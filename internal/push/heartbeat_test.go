@@ -0,0 +1,129 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/version"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestHeartbeater(t *testing.T, url string) (*Heartbeater, *config.Config, *ecdsa.PublicKey) {
+	t.Helper()
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	require.NoError(t, core.Start([]byte(statsConfig)))
+	t.Cleanup(func() { core.Stop() })
+
+	configMgr := xray.NewConfigManager(log)
+	require.NoError(t, configMgr.ExtractUsersFromConfig(xray.Hashes{Inbounds: []xray.InboundHash{{Tag: "vless-in"}}}, map[string]interface{}{
+		"inbounds": []interface{}{map[string]interface{}{
+			"tag": "vless-in",
+			"settings": map[string]interface{}{"clients": []interface{}{
+				map[string]interface{}{"id": "alice"},
+				map[string]interface{}{"id": "bob"},
+			}},
+		}},
+	}))
+	xrayController := controller.NewXrayController(core, configMgr, nil, 0, nil, nil, log)
+	statsController := controller.NewStatsController(core, history.NewRecorder(core, 0, log), checkpoint.New(core, state.NewMemoryStore(), log), log)
+
+	payload, publicKey := generateNodePayload(t)
+	cfg := &config.Config{NodePort: 2222, Payload: payload}
+	h, err := NewHeartbeater(url, time.Minute, cfg, xrayController, statsController, configMgr, log)
+	require.NoError(t, err)
+
+	return h, cfg, publicKey
+}
+
+func TestHeartbeater_SendsSignedHeartbeat(t *testing.T) {
+	var received Heartbeat
+	var signature, body []byte
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature, _ = base64.StdEncoding.DecodeString(r.Header.Get(SignatureHeader))
+		json.Unmarshal(body, &received)
+	}))
+	defer panel.Close()
+
+	h, cfg, publicKey := newTestHeartbeater(t, panel.URL)
+
+	require.NoError(t, h.Send(false))
+
+	digest := sha256.Sum256(body)
+	assert.True(t, ecdsa.VerifyASN1(publicKey, digest[:], signature), "heartbeat must be signed with the node key")
+
+	block, _ := pem.Decode([]byte(cfg.Payload.NodeCertPEM))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	fingerprint := sha256.Sum256(cert.Raw)
+
+	assert.Equal(t, "node", received.Node.CommonName)
+	assert.Equal(t, hex.EncodeToString(fingerprint[:]), received.Node.Fingerprint)
+	assert.Equal(t, version.Version, received.Node.Version)
+	assert.Equal(t, 2222, received.Node.NodePort)
+	assert.Equal(t, 60, received.Interval)
+	assert.False(t, received.Stopping)
+	assert.True(t, received.XrayRunning)
+	assert.NotNil(t, received.XrayVersion)
+	assert.Equal(t, 1, received.Metrics.Inbounds)
+	assert.Equal(t, 2, received.Metrics.Users)
+	assert.NotZero(t, received.Metrics.Goroutines)
+
+	require.NoError(t, h.Send(true))
+	assert.True(t, received.Stopping)
+}
+
+func TestHeartbeater_StartSendsRightAway(t *testing.T) {
+	var mu sync.Mutex
+	count := 0
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}))
+	defer panel.Close()
+
+	h, _, _ := newTestHeartbeater(t, panel.URL)
+
+	h.Start()
+	h.Start()
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return count == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	h.Stop()
+	h.Stop()
+}
+
+func TestHeartbeater_PanelError(t *testing.T) {
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer panel.Close()
+
+	h, _, _ := newTestHeartbeater(t, panel.URL)
+	assert.Error(t, h.Send(false))
+}
@@ -0,0 +1,344 @@
+package push
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/version"
+)
+
+const (
+	// SignatureHeader carries the base64 signature of the request body made
+	// with the node private key: RSA PKCS#1 v1.5 or ECDSA over SHA-256,
+	// or plain Ed25519.
+	SignatureHeader = "X-Node-Signature"
+
+	stateKey       = "stats-push-pending"
+	requestTimeout = 10 * time.Second
+)
+
+// Report is the body POSTed to the panel. Traffic is the amount collected
+// since the last successful push.
+type Report struct {
+	Timestamp   int64                      `json:"timestamp"`
+	NodeVersion string                     `json:"nodeVersion"`
+	Users       []controller.UserStats     `json:"users"`
+	Inbounds    []controller.InboundEntry  `json:"inbounds"`
+	Outbounds   []controller.OutboundEntry `json:"outbounds"`
+}
+
+type traffic struct {
+	Uplink   int64 `json:"uplink"`
+	Downlink int64 `json:"downlink"`
+}
+
+type pendingTraffic struct {
+	Users     map[string]traffic `json:"users"`
+	Inbounds  map[string]traffic `json:"inbounds"`
+	Outbounds map[string]traffic `json:"outbounds"`
+}
+
+// Pusher periodically reports traffic stats to the panel, for deployments
+// where the panel cannot reliably poll the node.
+//
+// Counters are collected with reset on every tick. Traffic that could not be
+// delivered stays pending (and persisted) and is sent with the next report,
+// so nothing is lost while the panel is unreachable.
+type Pusher struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	signer   crypto.Signer
+	stats    *controller.StatsController
+	store    state.Store
+	log      *logger.Logger
+
+	mu      sync.Mutex
+	pending pendingTraffic
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewPusher creates a pusher posting to url every interval. The node
+// certificate is presented as TLS client certificate and its key signs
+// each report.
+func NewPusher(url string, interval time.Duration, cfg *config.Config, stats *controller.StatsController, store state.Store, log *logger.Logger) (*Pusher, error) {
+	client, signer, err := newPanelClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pusher{
+		url:      url,
+		interval: interval,
+		client:   client,
+		signer:   signer,
+		stats:    stats,
+		store:    store,
+		log:      log,
+		pending:  newPendingTraffic(),
+	}
+
+	p.restore()
+
+	return p, nil
+}
+
+// newPanelClient returns a client presenting the node certificate to the
+// panel, trusting the panel CAs besides the system roots, and the node key
+// to sign request bodies with.
+func newPanelClient(cfg *config.Config) (*http.Client, crypto.Signer, error) {
+	cert, err := cfg.Payload.KeyPair()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load node certificate: %w", err)
+	}
+
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("node key cannot sign")
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	for _, caPEM := range cfg.PanelCAs() {
+		rootCAs.AppendCertsFromPEM([]byte(caPEM))
+	}
+
+	client := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      rootCAs,
+				MinVersion:   tls.VersionTLS12,
+			},
+		},
+	}
+	return client, signer, nil
+}
+
+func newPendingTraffic() pendingTraffic {
+	return pendingTraffic{
+		Users:     make(map[string]traffic),
+		Inbounds:  make(map[string]traffic),
+		Outbounds: make(map[string]traffic),
+	}
+}
+
+// Start launches the goroutine pushing reports every interval.
+func (p *Pusher) Start() {
+	p.mu.Lock()
+	if p.stopCh != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.stopCh = make(chan struct{})
+	stopCh := p.stopCh
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := p.Push(); err != nil {
+					p.log.WithError(err).WithField("url", p.url).Warn("Failed to push stats to panel, will retry")
+				}
+			}
+		}
+	}()
+}
+
+// Stop terminates the push goroutine.
+func (p *Pusher) Stop() {
+	p.mu.Lock()
+	stopCh := p.stopCh
+	p.stopCh = nil
+	p.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		p.wg.Wait()
+	}
+}
+
+// Push collects the counters and sends everything pending to the panel.
+func (p *Pusher) Push() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.collectLocked()
+
+	body, err := json.Marshal(p.reportLocked(time.Now()))
+	if err != nil {
+		return err
+	}
+
+	if err := post(p.client, p.signer, p.url, body); err != nil {
+		return err
+	}
+
+	p.pending = newPendingTraffic()
+	p.saveLocked()
+
+	return nil
+}
+
+// collectLocked moves the current counters into the pending traffic.
+// The caller must hold p.mu.
+func (p *Pusher) collectLocked() {
+	for _, user := range p.stats.UsersStats(true).Users {
+		addTraffic(p.pending.Users, user.Username, user.Uplink, user.Downlink)
+	}
+
+	combined := p.stats.CombinedStats(true)
+	for _, inbound := range combined.Inbounds {
+		addTraffic(p.pending.Inbounds, inbound.Inbound, inbound.Uplink, inbound.Downlink)
+	}
+	for _, outbound := range combined.Outbounds {
+		addTraffic(p.pending.Outbounds, outbound.Outbound, outbound.Uplink, outbound.Downlink)
+	}
+
+	p.saveLocked()
+}
+
+func addTraffic(target map[string]traffic, name string, uplink, downlink int64) {
+	if uplink == 0 && downlink == 0 {
+		return
+	}
+	t := target[name]
+	t.Uplink += uplink
+	t.Downlink += downlink
+	target[name] = t
+}
+
+// reportLocked builds the report of the pending traffic, sorted by name.
+// The caller must hold p.mu.
+func (p *Pusher) reportLocked(now time.Time) Report {
+	report := Report{
+		Timestamp:   now.Unix(),
+		NodeVersion: version.Version,
+		Users:       make([]controller.UserStats, 0, len(p.pending.Users)),
+		Inbounds:    make([]controller.InboundEntry, 0, len(p.pending.Inbounds)),
+		Outbounds:   make([]controller.OutboundEntry, 0, len(p.pending.Outbounds)),
+	}
+
+	for _, name := range sortedKeys(p.pending.Users) {
+		t := p.pending.Users[name]
+		report.Users = append(report.Users, controller.UserStats{Username: name, Uplink: t.Uplink, Downlink: t.Downlink})
+	}
+	for _, name := range sortedKeys(p.pending.Inbounds) {
+		t := p.pending.Inbounds[name]
+		report.Inbounds = append(report.Inbounds, controller.InboundEntry{Inbound: name, Uplink: t.Uplink, Downlink: t.Downlink})
+	}
+	for _, name := range sortedKeys(p.pending.Outbounds) {
+		t := p.pending.Outbounds[name]
+		report.Outbounds = append(report.Outbounds, controller.OutboundEntry{Outbound: name, Uplink: t.Uplink, Downlink: t.Downlink})
+	}
+
+	return report
+}
+
+func sortedKeys(m map[string]traffic) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// post sends body to url, signed with the node key, and fails unless the
+// panel answers with a 2xx status.
+func post(client *http.Client, signer crypto.Signer, url string, body []byte) error {
+	signature, err := sign(signer, body)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, base64.StdEncoding.EncodeToString(signature))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("panel responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign signs body with the node key.
+func sign(signer crypto.Signer, body []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		return signer.Sign(rand.Reader, body, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(body)
+	return signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// restore loads traffic left pending by a previous run.
+func (p *Pusher) restore() {
+	var stored pendingTraffic
+	found, err := p.store.Load(stateKey, &stored)
+	if err != nil {
+		p.log.WithError(err).Error("Failed to restore pending push stats")
+		return
+	}
+	if !found {
+		return
+	}
+
+	for name, t := range stored.Users {
+		p.pending.Users[name] = t
+	}
+	for name, t := range stored.Inbounds {
+		p.pending.Inbounds[name] = t
+	}
+	for name, t := range stored.Outbounds {
+		p.pending.Outbounds[name] = t
+	}
+}
+
+// saveLocked persists the pending traffic. The caller must hold p.mu.
+func (p *Pusher) saveLocked() {
+	if err := p.store.Save(stateKey, p.pending); err != nil {
+		p.log.WithError(err).Error("Failed to persist pending push stats")
+	}
+}
@@ -0,0 +1,172 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/api/controller"
+	"github.com/remnawave/node-go/internal/checkpoint"
+	"github.com/remnawave/node-go/internal/config"
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/version"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const statsConfig = `{"log":{"loglevel":"none"},"stats":{},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]}`
+
+func generateNodePayload(t *testing.T) (*config.NodePayload, *ecdsa.PublicKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	return &config.NodePayload{
+		CACertPEM:   string(certPEM),
+		NodeCertPEM: string(certPEM),
+		NodeKeyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}, &key.PublicKey
+}
+
+func newTestPusher(t *testing.T, url string, store state.Store) (*Pusher, *xray.Core, *ecdsa.PublicKey) {
+	t.Helper()
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	require.NoError(t, core.Start([]byte(statsConfig)))
+	t.Cleanup(func() { core.Stop() })
+
+	statsController := controller.NewStatsController(core, history.NewRecorder(core, 0, log), checkpoint.New(core, state.NewMemoryStore(), log), log)
+
+	payload, publicKey := generateNodePayload(t)
+	p, err := NewPusher(url, time.Minute, &config.Config{Payload: payload}, statsController, store, log)
+	require.NoError(t, err)
+
+	return p, core, publicKey
+}
+
+func addCounter(t *testing.T, core *xray.Core, name string, value int64) {
+	t.Helper()
+	stm, ok := core.Instance().GetFeature(stats.ManagerType()).(*appstats.Manager)
+	require.True(t, ok)
+
+	counter := stm.GetCounter(name)
+	if counter == nil {
+		var err error
+		counter, err = stm.RegisterCounter(name)
+		require.NoError(t, err)
+	}
+	counter.Add(value)
+}
+
+func TestPusher_PushesSignedReport(t *testing.T) {
+	var received Report
+	var signature []byte
+	var body []byte
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature, _ = base64.StdEncoding.DecodeString(r.Header.Get(SignatureHeader))
+		json.Unmarshal(body, &received)
+	}))
+	defer panel.Close()
+
+	p, core, publicKey := newTestPusher(t, panel.URL, state.NewMemoryStore())
+
+	addCounter(t, core, "user>>>alice>>>traffic>>>uplink", 100)
+	addCounter(t, core, "user>>>alice>>>traffic>>>downlink", 200)
+	addCounter(t, core, "inbound>>>vless-in>>>traffic>>>uplink", 100)
+	addCounter(t, core, "outbound>>>direct>>>traffic>>>downlink", 200)
+
+	require.NoError(t, p.Push())
+
+	digest := sha256.Sum256(body)
+	assert.True(t, ecdsa.VerifyASN1(publicKey, digest[:], signature), "report must be signed with the node key")
+
+	assert.Equal(t, version.Version, received.NodeVersion)
+	assert.Equal(t, []controller.UserStats{{Username: "alice", Uplink: 100, Downlink: 200}}, received.Users)
+	assert.Equal(t, []controller.InboundEntry{{Inbound: "vless-in", Uplink: 100}}, received.Inbounds)
+	assert.Equal(t, []controller.OutboundEntry{{Outbound: "direct", Downlink: 200}}, received.Outbounds)
+
+	require.NoError(t, p.Push())
+	assert.Empty(t, received.Users, "pushed traffic is not reported again")
+}
+
+func TestPusher_KeepsTrafficWhilePanelUnreachable(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	var received Report
+	panel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer panel.Close()
+
+	store, err := state.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	p, core, _ := newTestPusher(t, panel.URL, store)
+
+	addCounter(t, core, "user>>>alice>>>traffic>>>uplink", 100)
+	assert.Error(t, p.Push())
+
+	addCounter(t, core, "user>>>alice>>>traffic>>>uplink", 50)
+	assert.Error(t, p.Push())
+
+	// The node restarts: pending traffic is restored from the store.
+	restarted, _, _ := newTestPusher(t, panel.URL, store)
+
+	failing.Store(false)
+	require.NoError(t, restarted.Push())
+	assert.Equal(t, []controller.UserStats{{Username: "alice", Uplink: 150}}, received.Users)
+}
+
+func TestPusher_InvalidCertificate(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	_, err := NewPusher("http://127.0.0.1", time.Minute, &config.Config{Payload: &config.NodePayload{}}, nil, state.NewMemoryStore(), log)
+	assert.Error(t, err)
+}
+
+func TestPusher_StartStop(t *testing.T) {
+	p, _, _ := newTestPusher(t, "http://127.0.0.1:1", state.NewMemoryStore())
+
+	p.Start()
+	p.Start()
+	p.Stop()
+	p.Stop()
+}
@@ -0,0 +1,220 @@
+package revocation
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	maxCRLSize     = 10 << 20
+)
+
+// Checker loads certificate revocation lists from a file and/or a URL and
+// reports whether a client certificate was revoked, so a compromised panel
+// certificate can be rejected without rotating the CA on every node.
+//
+// Lists must be signed by one of the CAs returned by the cas callback. When
+// loading fails the previously loaded lists stay in use.
+type Checker struct {
+	file     string
+	url      string
+	interval time.Duration
+	client   *http.Client
+	cas      func() []*x509.Certificate
+	log      *logger.Logger
+
+	mu sync.RWMutex
+	// revoked maps the raw issuer of each list to its revoked serials.
+	revoked map[string]map[string]struct{}
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewChecker creates a checker loading the lists at file and url, either
+// of which may be empty, every interval.
+func NewChecker(file, url string, interval time.Duration, cas func() []*x509.Certificate, log *logger.Logger) *Checker {
+	return &Checker{
+		file:     file,
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: requestTimeout},
+		cas:      cas,
+		log:      log,
+	}
+}
+
+// Revoked reports whether cert appears in a loaded list of its issuer. A
+// nil checker revokes nothing.
+func (c *Checker) Revoked(cert *x509.Certificate) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	serials, ok := c.revoked[string(cert.RawIssuer)]
+	if !ok {
+		return false
+	}
+	_, ok = serials[cert.SerialNumber.String()]
+	return ok
+}
+
+// Start loads the lists once and launches the refresh goroutine.
+func (c *Checker) Start() {
+	c.mu.Lock()
+	if c.stopCh != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.stopCh = make(chan struct{})
+	stopCh := c.stopCh
+	c.mu.Unlock()
+
+	c.RefreshAndLog()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				c.RefreshAndLog()
+			}
+		}
+	}()
+}
+
+// Stop terminates the refresh goroutine.
+func (c *Checker) Stop() {
+	c.mu.Lock()
+	stopCh := c.stopCh
+	c.stopCh = nil
+	c.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		c.wg.Wait()
+	}
+}
+
+// Refresh loads every configured list, checks it is signed by a current
+// CA and replaces the revoked serials. On error nothing changes.
+func (c *Checker) Refresh() error {
+	cas := c.cas()
+	if len(cas) == 0 {
+		return errors.New("no CA certificate")
+	}
+
+	var lists []*x509.RevocationList
+	if c.file != "" {
+		data, err := os.ReadFile(c.file)
+		if err != nil {
+			return fmt.Errorf("failed to read CRL file: %w", err)
+		}
+		list, err := parseCRL(data)
+		if err != nil {
+			return fmt.Errorf("invalid CRL file: %w", err)
+		}
+		lists = append(lists, list)
+	}
+	if c.url != "" {
+		data, err := c.fetch()
+		if err != nil {
+			return fmt.Errorf("failed to fetch CRL: %w", err)
+		}
+		list, err := parseCRL(data)
+		if err != nil {
+			return fmt.Errorf("invalid CRL from URL: %w", err)
+		}
+		lists = append(lists, list)
+	}
+
+	revoked := make(map[string]map[string]struct{})
+	now := time.Now()
+	for _, list := range lists {
+		if err := checkSignature(list, cas); err != nil {
+			return fmt.Errorf("CRL is not signed by a CA: %w", err)
+		}
+		if !list.NextUpdate.IsZero() && now.After(list.NextUpdate) {
+			c.log.WithField("nextUpdate", list.NextUpdate.UTC().Format(time.RFC3339)).
+				Warn("CRL is past its next update, using it anyway")
+		}
+
+		serials, ok := revoked[string(list.RawIssuer)]
+		if !ok {
+			serials = make(map[string]struct{})
+			revoked[string(list.RawIssuer)] = serials
+		}
+		for _, entry := range list.RevokedCertificateEntries {
+			serials[entry.SerialNumber.String()] = struct{}{}
+		}
+	}
+
+	c.mu.Lock()
+	c.revoked = revoked
+	c.mu.Unlock()
+	return nil
+}
+
+// checkSignature checks list is signed by one of cas, returning the error
+// of the last one otherwise.
+func checkSignature(list *x509.RevocationList, cas []*x509.Certificate) error {
+	var err error
+	for _, ca := range cas {
+		if err = list.CheckSignatureFrom(ca); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// RefreshAndLog refreshes the lists, logging a failure instead of
+// returning it.
+func (c *Checker) RefreshAndLog() {
+	if err := c.Refresh(); err != nil {
+		c.log.WithError(err).Warn("Failed to load CRL, keeping current revocations")
+	}
+}
+
+func (c *Checker) fetch() ([]byte, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+}
+
+// parseCRL accepts a PEM "X509 CRL" block or DER.
+func parseCRL(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+		}
+		data = block.Bytes
+	}
+	return x509.ParseRevocationList(data)
+}
@@ -0,0 +1,146 @@
+package revocation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "panel"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) crl(t *testing.T, serials ...int64) []byte {
+	t.Helper()
+
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range serials {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now(),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	require.NoError(t, err)
+	return der
+}
+
+func testLogger() *logger.Logger {
+	return logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+}
+
+func TestChecker_File(t *testing.T) {
+	ca := newTestCA(t)
+	revoked := ca.issue(t, 10)
+	valid := ca.issue(t, 11)
+
+	path := filepath.Join(t.TempDir(), "panel.crl")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: ca.crl(t, 10)}), 0o600))
+
+	checker := NewChecker(path, "", time.Hour, func() []*x509.Certificate { return []*x509.Certificate{ca.cert} }, testLogger())
+	assert.False(t, checker.Revoked(revoked))
+
+	require.NoError(t, checker.Refresh())
+	assert.True(t, checker.Revoked(revoked))
+	assert.False(t, checker.Revoked(valid))
+
+	// A list signed by another CA is rejected and the current one kept.
+	other := newTestCA(t)
+	require.NoError(t, os.WriteFile(path, other.crl(t, 11), 0o600))
+	assert.Error(t, checker.Refresh())
+	assert.True(t, checker.Revoked(revoked))
+
+	// Unless the other CA is trusted too.
+	standby := NewChecker(path, "", time.Hour, func() []*x509.Certificate { return []*x509.Certificate{ca.cert, other.cert} }, testLogger())
+	require.NoError(t, standby.Refresh())
+	assert.True(t, standby.Revoked(other.issue(t, 11)))
+	assert.False(t, standby.Revoked(revoked))
+
+	require.NoError(t, os.Remove(path))
+	assert.Error(t, checker.Refresh())
+	assert.True(t, checker.Revoked(revoked))
+}
+
+func TestChecker_URL(t *testing.T) {
+	ca := newTestCA(t)
+	revoked := ca.issue(t, 10)
+
+	crl := ca.crl(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(crl)
+	}))
+	defer srv.Close()
+
+	checker := NewChecker("", srv.URL, time.Hour, func() []*x509.Certificate { return []*x509.Certificate{ca.cert} }, testLogger())
+	checker.Start()
+	defer checker.Stop()
+	assert.False(t, checker.Revoked(revoked))
+
+	crl = ca.crl(t, 10)
+	require.NoError(t, checker.Refresh())
+	assert.True(t, checker.Revoked(revoked))
+}
+
+func TestChecker_NilRevokesNothing(t *testing.T) {
+	var checker *Checker
+	assert.False(t, checker.Revoked(newTestCA(t).issue(t, 10)))
+}
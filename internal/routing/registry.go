@@ -0,0 +1,301 @@
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	stateKey        = "routing-rules"
+	janitorInterval = 5 * time.Second
+)
+
+var ErrInvalidSource = errors.New("invalid IP address or CIDR range")
+
+// Rule is a routing rule added through the node API. Source is an IP
+// address or CIDR range, or a comma-separated list of them mixing IPv4 and
+// IPv6.
+type Rule struct {
+	Tag         string     `json:"ruleTag"`
+	Source      string     `json:"sourceIp"`
+	OutboundTag string     `json:"outboundTag"`
+	Creator     string     `json:"creator,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (r Rule) expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// Registry keeps the routing rules added through the node API, persists
+// them in the state store and re-applies them after every xray (re)start,
+// since rules added at runtime do not survive one. Rules with a TTL are
+// removed once it elapses.
+type Registry struct {
+	mu    sync.RWMutex
+	rules map[string]Rule
+	core  *xray.Core
+	store state.Store
+	log   *logger.Logger
+
+	// saveMu orders the saves, so that a snapshot never overwrites a newer
+	// one.
+	saveMu sync.Mutex
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRegistry restores persisted rules and registers a core start hook
+// that re-applies them.
+func NewRegistry(core *xray.Core, store state.Store, log *logger.Logger) *Registry {
+	r := &Registry{
+		rules: make(map[string]Rule),
+		core:  core,
+		store: store,
+		log:   log,
+	}
+
+	r.restore()
+	core.OnStart(r.reapply)
+
+	return r
+}
+
+// Add registers rule and installs it when xray is running; otherwise it is
+// applied on next start. A ttl of zero keeps the rule until removed. Adding
+// a rule with the tag of a registered one replaces it.
+// Returns the rule as registered.
+func (r *Registry) Add(rule Rule, ttl time.Duration) (Rule, error) {
+	normalized, err := xray.NormalizeSources(rule.Source)
+	if err != nil {
+		return Rule{}, fmt.Errorf("%w: %s", ErrInvalidSource, rule.Source)
+	}
+	rule.Source = normalized
+	rule.CreatedAt = time.Now().UTC()
+	rule.ExpiresAt = nil
+	if ttl > 0 {
+		expiresAt := rule.CreatedAt.Add(ttl)
+		rule.ExpiresAt = &expiresAt
+	}
+
+	r.mu.Lock()
+	previous, replaced := r.rules[rule.Tag]
+	r.rules[rule.Tag] = rule
+	r.mu.Unlock()
+
+	if r.core.IsRunning() {
+		if replaced {
+			r.removeRule(previous.Tag)
+		}
+		if err := r.core.AddRoutingRule(rule.Tag, rule.Source, rule.OutboundTag); err != nil {
+			r.mu.Lock()
+			if replaced {
+				r.rules[rule.Tag] = previous
+			} else {
+				delete(r.rules, rule.Tag)
+			}
+			r.mu.Unlock()
+			if replaced {
+				r.apply(previous)
+			}
+			return Rule{}, err
+		}
+	}
+
+	r.save()
+
+	r.log.WithField("ruleTag", rule.Tag).
+		WithField("source", rule.Source).
+		WithField("outboundTag", rule.OutboundTag).
+		Info("Routing rule added")
+
+	return rule, nil
+}
+
+// Remove unregisters the rule tagged tag and removes it from xray. Returns
+// false if no such rule was registered.
+func (r *Registry) Remove(tag string) bool {
+	r.mu.Lock()
+	_, found := r.rules[tag]
+	delete(r.rules, tag)
+	r.mu.Unlock()
+
+	if !found {
+		return false
+	}
+
+	r.save()
+	r.removeRule(tag)
+
+	r.log.WithField("ruleTag", tag).Info("Routing rule removed")
+
+	return true
+}
+
+// List returns the active rules sorted by tag.
+func (r *Registry) List() []Rule {
+	now := time.Now()
+
+	r.mu.RLock()
+	rules := make([]Rule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		if !rule.expired(now) {
+			rules = append(rules, rule)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Tag < rules[j].Tag
+	})
+	return rules
+}
+
+// Start launches the janitor goroutine that drops expired rules.
+func (r *Registry) Start() {
+	r.mu.Lock()
+	if r.stopCh != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.stopCh = make(chan struct{})
+	stopCh := r.stopCh
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				r.removeExpired(now)
+			}
+		}
+	}()
+}
+
+// Stop terminates the janitor goroutine.
+func (r *Registry) Stop() {
+	r.mu.Lock()
+	stopCh := r.stopCh
+	r.stopCh = nil
+	r.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		r.wg.Wait()
+	}
+}
+
+// removeExpired deletes rules whose TTL elapsed and removes them from xray.
+func (r *Registry) removeExpired(now time.Time) int {
+	r.mu.Lock()
+	var expired []string
+	for tag, rule := range r.rules {
+		if rule.expired(now) {
+			expired = append(expired, tag)
+			delete(r.rules, tag)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(expired) == 0 {
+		return 0
+	}
+
+	for _, tag := range expired {
+		r.removeRule(tag)
+	}
+	r.save()
+
+	r.log.WithField("count", len(expired)).Info("Expired routing rules removed")
+
+	return len(expired)
+}
+
+func (r *Registry) apply(rule Rule) {
+	if err := r.core.AddRoutingRule(rule.Tag, rule.Source, rule.OutboundTag); err != nil {
+		r.log.WithError(err).WithField("ruleTag", rule.Tag).Error("Failed to apply routing rule")
+	}
+}
+
+func (r *Registry) removeRule(tag string) {
+	if !r.core.IsRunning() {
+		return
+	}
+	if err := r.core.RemoveRoutingRule(tag); err != nil {
+		r.log.WithError(err).WithField("ruleTag", tag).Warn("Failed to remove routing rule")
+	}
+}
+
+// reapply installs all registered rules. Registered as a core start hook.
+func (r *Registry) reapply() {
+	active := r.List()
+	for _, rule := range active {
+		r.apply(rule)
+	}
+
+	if len(active) > 0 {
+		r.log.WithField("count", len(active)).Info("Re-applied routing rules after xray start")
+	}
+}
+
+// restore loads the rules persisted by a previous run.
+func (r *Registry) restore() {
+	var stored map[string]Rule
+	found, err := r.store.Load(stateKey, &stored)
+	if err != nil {
+		r.log.WithError(err).Error("Failed to restore routing rules")
+		return
+	}
+	if !found {
+		return
+	}
+
+	now := time.Now()
+
+	r.mu.Lock()
+	for tag, rule := range stored {
+		if rule.expired(now) {
+			continue
+		}
+		rule.Tag = tag
+		r.rules[tag] = rule
+	}
+	count := len(r.rules)
+	r.mu.Unlock()
+
+	r.log.WithField("count", count).Info("Restored routing rules from state")
+}
+
+// save persists the current rules.
+func (r *Registry) save() {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.RLock()
+	snapshot := make(map[string]Rule, len(r.rules))
+	for tag, rule := range r.rules {
+		snapshot[tag] = rule
+	}
+	r.mu.RUnlock()
+
+	if err := r.store.Save(stateKey, snapshot); err != nil {
+		r.log.WithError(err).Error("Failed to persist routing rules")
+	}
+}
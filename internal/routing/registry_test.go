@@ -0,0 +1,189 @@
+package routing
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestRegistry(t *testing.T, core *xray.Core, store state.Store) *Registry {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	if core == nil {
+		core = xray.NewCore(log)
+	}
+	return NewRegistry(core, store, log)
+}
+
+// stallingStore holds the first save of a single entry until a save of
+// more entries is done, or for a while.
+type stallingStore struct {
+	state.Store
+	stalled chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newStallingStore() *stallingStore {
+	return &stallingStore{Store: state.NewMemoryStore(), stalled: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (s *stallingStore) Save(key string, v interface{}) error {
+	entries := reflect.ValueOf(v).Len()
+	if entries == 1 {
+		close(s.stalled)
+		select {
+		case <-s.done:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	err := s.Store.Save(key, v)
+	if entries > 1 {
+		s.once.Do(func() { close(s.done) })
+	}
+	return err
+}
+
+func TestRegistry_AddRemove(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+
+	rule, err := r.Add(Rule{Tag: "office", Source: "10.1.2.3/24", OutboundTag: "direct", Creator: "admin"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.2.0/24", rule.Source)
+	assert.False(t, rule.CreatedAt.IsZero())
+	assert.Nil(t, rule.ExpiresAt)
+
+	rules := r.List()
+	require.Len(t, rules, 1)
+	assert.Equal(t, rule, rules[0])
+
+	assert.True(t, r.Remove("office"))
+	assert.False(t, r.Remove("office"))
+	assert.Empty(t, r.List())
+}
+
+func TestRegistry_InvalidSource(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+
+	_, err := r.Add(Rule{Tag: "bad", Source: "not-an-ip", OutboundTag: "direct"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidSource)
+	assert.Empty(t, r.List())
+}
+
+func TestRegistry_MixedFamilySources(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+
+	rule, err := r.Add(Rule{Tag: "office", Source: "2001:db8::1/64, 10.0.0.5/24,::ffff:10.0.0.9/120", OutboundTag: "direct"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/24,2001:db8::/64", rule.Source)
+
+	_, err = r.Add(Rule{Tag: "bad", Source: "10.0.0.1,2001:db8::/129", OutboundTag: "direct"}, 0)
+	assert.ErrorIs(t, err, ErrInvalidSource)
+}
+
+func TestRegistry_Replace(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+
+	_, err := r.Add(Rule{Tag: "rule", Source: "192.0.2.1", OutboundTag: "direct"}, time.Hour)
+	require.NoError(t, err)
+	_, err = r.Add(Rule{Tag: "rule", Source: "192.0.2.2", OutboundTag: "BLOCK"}, 0)
+	require.NoError(t, err)
+
+	rules := r.List()
+	require.Len(t, rules, 1)
+	assert.Equal(t, "192.0.2.2", rules[0].Source)
+	assert.Equal(t, "BLOCK", rules[0].OutboundTag)
+	assert.Nil(t, rules[0].ExpiresAt)
+}
+
+func TestRegistry_TTLExpiry(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+
+	_, err := r.Add(Rule{Tag: "a", Source: "192.0.2.1", OutboundTag: "direct"}, time.Minute)
+	require.NoError(t, err)
+	_, err = r.Add(Rule{Tag: "b", Source: "192.0.2.2", OutboundTag: "direct"}, 0)
+	require.NoError(t, err)
+
+	rules := r.List()
+	require.Len(t, rules, 2)
+	assert.NotNil(t, rules[0].ExpiresAt)
+	assert.Nil(t, rules[1].ExpiresAt)
+
+	assert.Equal(t, 0, r.removeExpired(time.Now()))
+	assert.Equal(t, 1, r.removeExpired(time.Now().Add(2*time.Minute)))
+
+	rules = r.List()
+	require.Len(t, rules, 1)
+	assert.Equal(t, "b", rules[0].Tag)
+}
+
+func TestRegistry_PersistAndRestore(t *testing.T) {
+	store := state.NewMemoryStore()
+	r := newTestRegistry(t, nil, store)
+
+	_, err := r.Add(Rule{Tag: "a", Source: "203.0.113.5", OutboundTag: "direct", Creator: "admin"}, 0)
+	require.NoError(t, err)
+	_, err = r.Add(Rule{Tag: "b", Source: "2001:db8::/32", OutboundTag: "BLOCK"}, time.Hour)
+	require.NoError(t, err)
+
+	restored := newTestRegistry(t, nil, store)
+	assert.Equal(t, r.List(), restored.List())
+}
+
+func TestRegistry_ReappliedOnStart(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	r := newTestRegistry(t, core, state.NewMemoryStore())
+
+	// Added while xray is down, applied once it starts.
+	_, err := r.Add(Rule{Tag: "office", Source: "10.0.0.1,2001:db8:1::/48", OutboundTag: "direct"}, 0)
+	require.NoError(t, err)
+
+	config := []byte(`{"log":{"loglevel":"none"},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}],"routing":{"rules":[]}}`)
+	require.NoError(t, core.Start(config))
+	defer core.Stop()
+
+	rules, err := core.ListRoutingRules()
+	require.NoError(t, err)
+	assert.Contains(t, rules, xray.RoutingRule{RuleTag: "office", OutboundTag: "direct"})
+
+	assert.True(t, r.Remove("office"))
+	rules, err = core.ListRoutingRules()
+	require.NoError(t, err)
+	assert.NotContains(t, rules, xray.RoutingRule{RuleTag: "office", OutboundTag: "direct"})
+}
+
+func TestRegistry_StartStop(t *testing.T) {
+	r := newTestRegistry(t, nil, state.NewMemoryStore())
+	r.Start()
+	r.Start()
+	r.Stop()
+	r.Stop()
+}
+
+func TestRegistry_ConcurrentSaves(t *testing.T) {
+	store := newStallingStore()
+	r := newTestRegistry(t, nil, store)
+
+	added := make(chan error)
+	go func() {
+		_, err := r.Add(Rule{Tag: "a", Source: "192.0.2.1", OutboundTag: "direct"}, 0)
+		added <- err
+	}()
+	<-store.stalled
+	_, err := r.Add(Rule{Tag: "b", Source: "192.0.2.2", OutboundTag: "direct"}, 0)
+	require.NoError(t, err)
+	require.NoError(t, <-added)
+
+	// The snapshot of the first add must not overwrite the later one.
+	restored := newTestRegistry(t, nil, store.Store)
+	assert.Len(t, restored.List(), 2)
+}
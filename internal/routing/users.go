@@ -0,0 +1,255 @@
+package routing
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	userStateKey = "user-outbounds"
+
+	// userRuleTagPrefix prefixes the tag of the routing rule sending the
+	// users mapped to an outbound through it.
+	userRuleTagPrefix = "user-outbound:"
+)
+
+// UserOutbound maps a user to the outbound their traffic goes through.
+type UserOutbound struct {
+	Username    string `json:"username"`
+	OutboundTag string `json:"outboundTag"`
+}
+
+// UserRoutes routes the traffic of specific users through a designated
+// outbound, e.g. WARP, a relay or BLOCK, with one email-matching routing
+// rule per outbound. The mapping is persisted in the state store and
+// re-applied after every xray (re)start. The rules are appended after those
+// of the config, so a config rule matching the traffic first wins.
+type UserRoutes struct {
+	mu        sync.RWMutex
+	outbounds map[string]string
+	core      *xray.Core
+	store     state.Store
+	log       *logger.Logger
+
+	// applyMu serializes rebuilding the rules, which takes several router
+	// calls.
+	applyMu sync.Mutex
+
+	// saveMu orders the saves, so that a snapshot never overwrites a newer
+	// one.
+	saveMu sync.Mutex
+}
+
+// NewUserRoutes restores the persisted mapping and registers a core start
+// hook that re-applies it.
+func NewUserRoutes(core *xray.Core, store state.Store, log *logger.Logger) *UserRoutes {
+	u := &UserRoutes{
+		outbounds: make(map[string]string),
+		core:      core,
+		store:     store,
+		log:       log,
+	}
+
+	u.restore()
+	core.OnStart(u.reapply)
+
+	return u
+}
+
+func userRuleTag(outboundTag string) string {
+	return userRuleTagPrefix + outboundTag
+}
+
+// Set routes username through outboundTag, replacing its previous
+// outbound. While xray is down the mapping is only recorded and applied on
+// next start.
+func (u *UserRoutes) Set(username, outboundTag string) error {
+	u.applyMu.Lock()
+	defer u.applyMu.Unlock()
+
+	u.mu.Lock()
+	previous, mapped := u.outbounds[username]
+	u.outbounds[username] = outboundTag
+	u.mu.Unlock()
+
+	if mapped && previous == outboundTag {
+		return nil
+	}
+
+	if u.core.IsRunning() {
+		if err := u.apply(outboundTag); err != nil {
+			u.mu.Lock()
+			if mapped {
+				u.outbounds[username] = previous
+			} else {
+				delete(u.outbounds, username)
+			}
+			u.mu.Unlock()
+			u.logError(u.apply(outboundTag), outboundTag)
+			return err
+		}
+		if mapped {
+			u.logError(u.apply(previous), previous)
+		}
+	}
+
+	u.save()
+
+	u.log.WithField("username", username).WithField("outboundTag", outboundTag).Info("User outbound set")
+
+	return nil
+}
+
+// Remove restores the default routing of username. Returns false if it had
+// no outbound set.
+func (u *UserRoutes) Remove(username string) bool {
+	u.applyMu.Lock()
+	defer u.applyMu.Unlock()
+
+	u.mu.Lock()
+	outboundTag, mapped := u.outbounds[username]
+	delete(u.outbounds, username)
+	u.mu.Unlock()
+
+	if !mapped {
+		return false
+	}
+
+	if u.core.IsRunning() {
+		u.logError(u.apply(outboundTag), outboundTag)
+	}
+	u.save()
+
+	u.log.WithField("username", username).WithField("outboundTag", outboundTag).Info("User outbound removed")
+
+	return true
+}
+
+// List returns the mapping sorted by username.
+func (u *UserRoutes) List() []UserOutbound {
+	u.mu.RLock()
+	list := make([]UserOutbound, 0, len(u.outbounds))
+	for username, outboundTag := range u.outbounds {
+		list = append(list, UserOutbound{Username: username, OutboundTag: outboundTag})
+	}
+	u.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Username < list[j].Username
+	})
+	return list
+}
+
+// usersOf returns the users mapped to outboundTag, sorted.
+func (u *UserRoutes) usersOf(outboundTag string) []string {
+	u.mu.RLock()
+	var users []string
+	for username, tag := range u.outbounds {
+		if tag == outboundTag {
+			users = append(users, username)
+		}
+	}
+	u.mu.RUnlock()
+
+	sort.Strings(users)
+	return users
+}
+
+// apply replaces the routing rule of outboundTag by one matching the users
+// currently mapped to it, or just removes it when there are none.
+func (u *UserRoutes) apply(outboundTag string) error {
+	ruleTag := userRuleTag(outboundTag)
+	if u.hasRule(ruleTag) {
+		if err := u.core.RemoveRoutingRule(ruleTag); err != nil {
+			return err
+		}
+	}
+
+	users := u.usersOf(outboundTag)
+	if len(users) == 0 {
+		return nil
+	}
+	return u.core.AddUserRoutingRule(ruleTag, users, outboundTag)
+}
+
+func (u *UserRoutes) hasRule(ruleTag string) bool {
+	rules, _ := u.core.ListRoutingRules()
+	for _, rule := range rules {
+		if rule.RuleTag == ruleTag {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *UserRoutes) logError(err error, outboundTag string) {
+	if err != nil {
+		u.log.WithError(err).WithField("outboundTag", outboundTag).Error("Failed to update user routing rule")
+	}
+}
+
+// reapply installs the rules of all mapped outbounds. Registered as a core
+// start hook, since rules do not survive a restart.
+func (u *UserRoutes) reapply() {
+	u.applyMu.Lock()
+	defer u.applyMu.Unlock()
+
+	u.mu.RLock()
+	outbounds := make(map[string]struct{})
+	for _, outboundTag := range u.outbounds {
+		outbounds[outboundTag] = struct{}{}
+	}
+	count := len(u.outbounds)
+	u.mu.RUnlock()
+
+	for outboundTag := range outbounds {
+		u.logError(u.apply(outboundTag), outboundTag)
+	}
+
+	if count > 0 {
+		u.log.WithField("count", count).Info("Re-applied user outbounds after xray start")
+	}
+}
+
+// restore loads the mapping persisted by a previous run.
+func (u *UserRoutes) restore() {
+	var stored map[string]string
+	found, err := u.store.Load(userStateKey, &stored)
+	if err != nil {
+		u.log.WithError(err).Error("Failed to restore user outbounds")
+		return
+	}
+	if !found {
+		return
+	}
+
+	u.mu.Lock()
+	for username, outboundTag := range stored {
+		u.outbounds[username] = outboundTag
+	}
+	count := len(u.outbounds)
+	u.mu.Unlock()
+
+	u.log.WithField("count", count).Info("Restored user outbounds from state")
+}
+
+// save persists the current mapping.
+func (u *UserRoutes) save() {
+	u.saveMu.Lock()
+	defer u.saveMu.Unlock()
+
+	u.mu.RLock()
+	snapshot := make(map[string]string, len(u.outbounds))
+	for username, outboundTag := range u.outbounds {
+		snapshot[username] = outboundTag
+	}
+	u.mu.RUnlock()
+
+	if err := u.store.Save(userStateKey, snapshot); err != nil {
+		u.log.WithError(err).Error("Failed to persist user outbounds")
+	}
+}
@@ -0,0 +1,105 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestUserRoutes(t *testing.T, core *xray.Core, store state.Store) *UserRoutes {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	if core == nil {
+		core = xray.NewCore(log)
+	}
+	return NewUserRoutes(core, store, log)
+}
+
+func TestUserRoutes_SetRemove(t *testing.T) {
+	u := newTestUserRoutes(t, nil, state.NewMemoryStore())
+
+	require.NoError(t, u.Set("bob", "warp"))
+	require.NoError(t, u.Set("alice", "relay"))
+	require.NoError(t, u.Set("bob", "BLOCK"))
+
+	assert.Equal(t, []UserOutbound{
+		{Username: "alice", OutboundTag: "relay"},
+		{Username: "bob", OutboundTag: "BLOCK"},
+	}, u.List())
+
+	assert.True(t, u.Remove("bob"))
+	assert.False(t, u.Remove("bob"))
+	assert.Equal(t, []UserOutbound{{Username: "alice", OutboundTag: "relay"}}, u.List())
+}
+
+func TestUserRoutes_PersistAndRestore(t *testing.T) {
+	store := state.NewMemoryStore()
+	u := newTestUserRoutes(t, nil, store)
+
+	require.NoError(t, u.Set("alice", "warp"))
+	require.NoError(t, u.Set("bob", "warp"))
+
+	restored := newTestUserRoutes(t, nil, store)
+	assert.Equal(t, u.List(), restored.List())
+}
+
+func TestUserRoutes_ConcurrentSaves(t *testing.T) {
+	store := newStallingStore()
+	u := newTestUserRoutes(t, nil, store)
+
+	set := make(chan error)
+	go func() {
+		set <- u.Set("alice", "warp")
+	}()
+	<-store.stalled
+	require.NoError(t, u.Set("bob", "relay"))
+	require.NoError(t, <-set)
+
+	// The snapshot of the first set must not overwrite the later one.
+	restored := newTestUserRoutes(t, nil, store.Store)
+	assert.Len(t, restored.List(), 2)
+}
+
+func TestUserRoutes_Rules(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	core := xray.NewCore(log)
+	u := newTestUserRoutes(t, core, state.NewMemoryStore())
+
+	// Set while xray is down, applied once it starts.
+	require.NoError(t, u.Set("alice", "warp"))
+
+	config := []byte(`{"log":{"loglevel":"none"},"inbounds":[],"outbounds":[` +
+		`{"tag":"direct","protocol":"freedom"},{"tag":"warp","protocol":"freedom"},{"tag":"BLOCK","protocol":"blackhole"}],` +
+		`"routing":{"rules":[]}}`)
+	require.NoError(t, core.Start(config))
+	defer core.Stop()
+
+	assert.True(t, core.HasOutbound("warp"))
+	assert.False(t, core.HasOutbound("relay"))
+
+	ruleTags := func() []string {
+		rules, err := core.ListRoutingRules()
+		require.NoError(t, err)
+		tags := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			tags = append(tags, rule.RuleTag)
+		}
+		return tags
+	}
+	assert.Equal(t, []string{"user-outbound:warp"}, ruleTags())
+
+	require.NoError(t, u.Set("bob", "warp"))
+	require.NoError(t, u.Set("alice", "BLOCK"))
+	assert.ElementsMatch(t, []string{"user-outbound:warp", "user-outbound:BLOCK"}, ruleTags())
+
+	assert.True(t, u.Remove("alice"))
+	assert.Equal(t, []string{"user-outbound:warp"}, ruleTags())
+
+	assert.True(t, u.Remove("bob"))
+	assert.Empty(t, ruleTags())
+}
@@ -0,0 +1,93 @@
+package secretsource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	gcpTokenURL         = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+)
+
+// GCPSecretManager reads a secret version from Google Secret Manager with
+// the credentials of the service account attached to the instance, taken
+// from the metadata server.
+type GCPSecretManager struct {
+	name     string
+	client   *http.Client
+	tokenURL string
+	apiURL   string
+}
+
+// NewGCPSecretManager returns a source reading the secret version name,
+// e.g. projects/my-project/secrets/node-1/versions/3. A name without a
+// version reads the latest one.
+func NewGCPSecretManager(name string) *GCPSecretManager {
+	name = strings.Trim(name, "/")
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	return &GCPSecretManager{
+		name:     name,
+		client:   &http.Client{Timeout: requestTimeout},
+		tokenURL: gcpTokenURL,
+		apiURL:   gcpSecretManagerURL,
+	}
+}
+
+// Fetch reads the payload of the secret version.
+func (g *GCPSecretManager) Fetch(ctx context.Context) (string, error) {
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err := g.get(ctx, g.tokenURL, map[string]string{"Metadata-Flavor": "Google"}, &token)
+	if err != nil {
+		return "", fmt.Errorf("gcp: failed to get access token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("gcp: metadata server returned no access token")
+	}
+
+	var version struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	err = g.get(ctx, g.apiURL+g.name+":access", map[string]string{"Authorization": "Bearer " + token.AccessToken}, &version)
+	if err != nil {
+		return "", fmt.Errorf("gcp: failed to access secret %s: %w", g.name, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("gcp: invalid payload of secret %s: %w", g.name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (g *GCPSecretManager) get(ctx context.Context, url string, header map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(out)
+}
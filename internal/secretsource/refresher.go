@@ -0,0 +1,119 @@
+package secretsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// Refresher renews the credential of a Source and fetches the secret again
+// every interval, calling onChange when it changed, so a key rotated in the
+// secret manager reaches the node without a restart. Only a digest of the
+// secret is kept between fetches.
+//
+// When a fetch or onChange fails the current secret stays in use and the
+// change is retried on the next tick.
+type Refresher struct {
+	source   Source
+	interval time.Duration
+	onChange func() error
+	log      *logger.Logger
+
+	mu     sync.Mutex
+	digest [sha256.Size]byte
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRefresher creates a refresher for source, whose secret was current
+// when fetched at startup.
+func NewRefresher(source Source, interval time.Duration, current string, onChange func() error, log *logger.Logger) *Refresher {
+	return &Refresher{
+		source:   source,
+		interval: interval,
+		onChange: onChange,
+		log:      log,
+		digest:   sha256.Sum256([]byte(current)),
+	}
+}
+
+// Start launches the refresh goroutine. The secret was fetched at startup,
+// so the first refresh waits for the interval.
+func (r *Refresher) Start() {
+	r.mu.Lock()
+	if r.stopCh != nil {
+		r.mu.Unlock()
+		return
+	}
+	r.stopCh = make(chan struct{})
+	stopCh := r.stopCh
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := r.Refresh(context.Background()); err != nil {
+					r.log.WithError(err).Warn("Failed to refresh secret key, keeping current credentials")
+				}
+			}
+		}
+	}()
+}
+
+// Stop terminates the refresh goroutine.
+func (r *Refresher) Stop() {
+	r.mu.Lock()
+	stopCh := r.stopCh
+	r.stopCh = nil
+	r.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		r.wg.Wait()
+	}
+}
+
+// Refresh renews the source credential, fetches the secret and calls
+// onChange if it differs from the last one seen. A failed renewal is
+// logged; the fetch tells whether the credential still works.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	if renewer, ok := r.source.(Renewer); ok {
+		if err := renewer.Renew(ctx); err != nil {
+			r.log.WithError(err).Warn("Failed to renew secret source credential")
+		}
+	}
+
+	secret, err := r.source.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(secret))
+
+	r.mu.Lock()
+	changed := digest != r.digest
+	r.mu.Unlock()
+	if !changed {
+		return nil
+	}
+
+	if err := r.onChange(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.digest = digest
+	r.mu.Unlock()
+	r.log.Info("Secret key changed in the secret manager, credentials reloaded")
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package secretsource fetches the node SECRET_KEY from a secret manager,
+// at startup and periodically afterwards, so the key does not have to be
+// kept in unit files or compose environments.
+package secretsource
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	maxBodySize    = 1 << 20
+)
+
+// Source fetches a secret.
+type Source interface {
+	Fetch(ctx context.Context) (string, error)
+}
+
+// Renewer is implemented by sources holding a credential that expires
+// unless it is renewed.
+type Renewer interface {
+	Renew(ctx context.Context) error
+}
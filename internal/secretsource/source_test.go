@@ -0,0 +1,232 @@
+package secretsource
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVaultServer serves secret at secret/data/node with KV v2 layout, for
+// the token "s.token" or an AppRole login with role-id and secret-id, which
+// gets "s.token" for an hour.
+func newVaultServer(t *testing.T, secret *string, renewed, logins *int) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["role_id"] != "role-id" || req["secret_id"] != "secret-id" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+			return
+		}
+		*logins++
+		w.Write([]byte(`{"auth":{"client_token":"s.token","lease_duration":3600}}`))
+	})
+	mux.HandleFunc("POST /v1/auth/token/renew-self", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		*renewed++
+		w.Write([]byte(`{"auth":{"client_token":"s.token","lease_duration":3600}}`))
+	})
+	mux.HandleFunc("GET /v1/secret/data/node", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]string{"secretKey": *secret},
+				"metadata": map[string]int{"version": 1},
+			},
+		})
+	})
+	mux.HandleFunc("GET /v1/kv/node", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"secretKey":"kv1-secret","port":2222}}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVault_Fetch(t *testing.T) {
+	secret := "secret-v1"
+	var renewed, logins int
+	server := newVaultServer(t, &secret, &renewed, &logins)
+	ctx := context.Background()
+
+	t.Run("token", func(t *testing.T) {
+		v := NewVault(VaultOptions{Addr: server.URL + "/", Token: "s.token", Path: "/secret/data/node", Field: "secretKey"})
+		value, err := v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-v1", value)
+
+		require.NoError(t, v.Renew(ctx))
+		assert.Equal(t, 1, renewed)
+	})
+
+	t.Run("token file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(file, []byte("s.stale\n"), 0o600))
+		v := NewVault(VaultOptions{Addr: server.URL, TokenFile: file, Path: "secret/data/node", Field: "secretKey"})
+
+		_, err := v.Fetch(ctx)
+		assert.ErrorContains(t, err, "status 403: permission denied")
+
+		// Rotated by Vault Agent.
+		require.NoError(t, os.WriteFile(file, []byte("s.token\n"), 0o600))
+		value, err := v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-v1", value)
+	})
+
+	t.Run("approle", func(t *testing.T) {
+		now := time.Now()
+		v := NewVault(VaultOptions{Addr: server.URL, RoleID: "role-id", SecretID: "secret-id", Path: "secret/data/node", Field: "secretKey"})
+		v.now = func() time.Time { return now }
+
+		renewed = 0
+		require.NoError(t, v.Renew(ctx))
+		assert.Zero(t, renewed, "nothing to renew before the first login")
+
+		value, err := v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "secret-v1", value)
+		_, err = v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, logins, "the login token is kept")
+
+		// Renewed tokens last an hour from the renewal.
+		now = now.Add(50 * time.Minute)
+		require.NoError(t, v.Renew(ctx))
+		assert.Equal(t, 1, renewed)
+		now = now.Add(50 * time.Minute)
+		_, err = v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, logins)
+
+		// Unrenewed, it is replaced before it expires.
+		now = now.Add(time.Hour)
+		_, err = v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, logins)
+
+		v = NewVault(VaultOptions{Addr: server.URL, RoleID: "role-id", SecretID: "wrong", Path: "secret/data/node", Field: "secretKey"})
+		_, err = v.Fetch(ctx)
+		assert.ErrorContains(t, err, "invalid role or secret ID")
+	})
+
+	t.Run("kv v1", func(t *testing.T) {
+		v := NewVault(VaultOptions{Addr: server.URL, Token: "s.token", Path: "kv/node", Field: "secretKey"})
+		value, err := v.Fetch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "kv1-secret", value)
+
+		v = NewVault(VaultOptions{Addr: server.URL, Token: "s.token", Path: "kv/node", Field: "missing"})
+		_, err = v.Fetch(ctx)
+		assert.EqualError(t, err, `vault: secret kv/node has no field "missing"`)
+
+		v = NewVault(VaultOptions{Addr: server.URL, Token: "s.token", Path: "kv/node", Field: "port"})
+		_, err = v.Fetch(ctx)
+		assert.EqualError(t, err, `vault: field "port" of secret kv/node is not a string`)
+	})
+
+	t.Run("no credentials", func(t *testing.T) {
+		v := NewVault(VaultOptions{Addr: server.URL, Path: "secret/data/node", Field: "secretKey"})
+		_, err := v.Fetch(ctx)
+		assert.EqualError(t, err, "vault: no token or AppRole configured")
+	})
+}
+
+func TestGCPSecretManager_Fetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`))
+	})
+	mux.HandleFunc("GET /v1/projects/p/secrets/node/versions/{version}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.token" || r.PathValue("version") != "latest:access" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data := base64.StdEncoding.EncodeToString([]byte("gcp-secret\n"))
+		w.Write([]byte(`{"name":"projects/p/secrets/node/versions/1","payload":{"data":"` + data + `"}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	g := NewGCPSecretManager("projects/p/secrets/node")
+	g.tokenURL = server.URL + "/token"
+	g.apiURL = server.URL + "/v1/"
+	value, err := g.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "gcp-secret", value)
+
+	g = NewGCPSecretManager("projects/p/secrets/node/versions/2")
+	g.tokenURL = server.URL + "/token"
+	g.apiURL = server.URL + "/v1/"
+	_, err = g.Fetch(context.Background())
+	assert.ErrorContains(t, err, "unexpected status 404")
+}
+
+type fakeSource struct {
+	secret  string
+	err     error
+	renewed int
+}
+
+func (s *fakeSource) Fetch(context.Context) (string, error) { return s.secret, s.err }
+
+func (s *fakeSource) Renew(context.Context) error {
+	s.renewed++
+	return nil
+}
+
+func TestRefresher_Refresh(t *testing.T) {
+	source := &fakeSource{secret: "v1"}
+	var changes int
+	var reloadErr error
+	r := NewRefresher(source, time.Hour, "v1", func() error {
+		changes++
+		return reloadErr
+	}, logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+	ctx := context.Background()
+
+	require.NoError(t, r.Refresh(ctx))
+	assert.Zero(t, changes)
+	assert.Equal(t, 1, source.renewed)
+
+	source.secret = "v2"
+	reloadErr = errors.New("invalid secret key")
+	assert.Error(t, r.Refresh(ctx))
+	assert.Equal(t, 1, changes)
+
+	// Retried until the reload succeeds.
+	reloadErr = nil
+	require.NoError(t, r.Refresh(ctx))
+	require.NoError(t, r.Refresh(ctx))
+	assert.Equal(t, 2, changes)
+
+	source.err = errors.New("vault sealed")
+	assert.Error(t, r.Refresh(ctx))
+	assert.Equal(t, 2, changes)
+}
@@ -0,0 +1,277 @@
+package secretsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loginExpiryMargin is how long before its expiry an AppRole token is
+// replaced by a new login rather than used.
+const loginExpiryMargin = 30 * time.Second
+
+// VaultOptions locates a secret in HashiCorp Vault and the credentials to
+// read it with.
+type VaultOptions struct {
+	// Addr is the Vault server URL, e.g. https://vault.example.com:8200.
+	Addr string
+	// Namespace is the Vault Enterprise namespace, if any.
+	Namespace string
+	// Token authenticates the requests. TokenFile is read for it on every
+	// fetch instead, so a token rotated by Vault Agent is picked up.
+	Token     string
+	TokenFile string
+	// RoleID and SecretID log in with AppRole instead of using a token.
+	// The token of the login is kept and renewed, and replaced by a new
+	// login once it expires.
+	RoleID   string
+	SecretID string
+	// Path is the secret path after /v1/, e.g. secret/data/remnawave/node-1
+	// on a KV v2 mount, and Field the key holding the secret in it.
+	Path  string
+	Field string
+}
+
+// Vault reads a secret from a KV v1 or v2 secrets engine over the Vault
+// HTTP API.
+type Vault struct {
+	opts   VaultOptions
+	client *http.Client
+	now    func() time.Time
+
+	// mu guards the token of the last AppRole login and its expiry, zero
+	// for a token that does not expire.
+	mu          sync.Mutex
+	loginToken  string
+	loginExpiry time.Time
+}
+
+// NewVault returns a source reading the secret described by opts.
+func NewVault(opts VaultOptions) *Vault {
+	opts.Addr = strings.TrimRight(opts.Addr, "/")
+	opts.Path = strings.Trim(opts.Path, "/")
+	return &Vault{
+		opts:   opts,
+		client: &http.Client{Timeout: requestTimeout},
+		now:    time.Now,
+	}
+}
+
+// Fetch reads the field of the secret.
+func (v *Vault) Fetch(ctx context.Context) (string, error) {
+	token, err := v.token(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, v.opts.Path, token, nil, &resp); err != nil {
+		if isForbidden(err) {
+			// The login token was revoked: log in again next time.
+			v.forgetLogin(token)
+		}
+		return "", err
+	}
+	data := resp.Data
+	// KV v2 nests the secret under data.data, next to its metadata.
+	if nested, ok := data["data"]; ok && data["metadata"] != nil {
+		data = nil
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", fmt.Errorf("vault: invalid secret %s: %w", v.opts.Path, err)
+		}
+	}
+
+	raw, ok := data[v.opts.Field]
+	if !ok {
+		return "", fmt.Errorf("vault: secret %s has no field %q", v.opts.Path, v.opts.Field)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("vault: field %q of secret %s is not a string", v.opts.Field, v.opts.Path)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// Renew extends the lease of the configured token, or of the token of the
+// last AppRole login. Without such a login yet, the next fetch logs in. A
+// login token Vault no longer accepts is dropped so the next fetch logs in
+// again; one at its maximum TTL is replaced shortly before it expires.
+func (v *Vault) Renew(ctx context.Context) error {
+	if v.opts.RoleID == "" {
+		token, err := v.token(ctx)
+		if err != nil {
+			return err
+		}
+		return v.do(ctx, http.MethodPost, "auth/token/renew-self", token, struct{}{}, nil)
+	}
+
+	v.mu.Lock()
+	token := v.loginToken
+	v.mu.Unlock()
+	if token == "" {
+		return nil
+	}
+
+	var resp authResponse
+	if err := v.do(ctx, http.MethodPost, "auth/token/renew-self", token, struct{}{}, &resp); err != nil {
+		if isForbidden(err) {
+			v.forgetLogin(token)
+		}
+		return err
+	}
+	v.mu.Lock()
+	if v.loginToken == token {
+		v.loginExpiry = resp.expiry(v.now())
+	}
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Vault) token(ctx context.Context) (string, error) {
+	switch {
+	case v.opts.RoleID != "":
+		return v.approleToken(ctx)
+	case v.opts.TokenFile != "":
+		data, err := os.ReadFile(v.opts.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("vault: failed to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case v.opts.Token != "":
+		return v.opts.Token, nil
+	default:
+		return "", errors.New("vault: no token or AppRole configured")
+	}
+}
+
+// authResponse is the response of a login or a token renewal.
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// expiry returns when the token expires given the response arrived at now,
+// zero for a token without a lease.
+func (r authResponse) expiry(now time.Time) time.Time {
+	if r.Auth.LeaseDuration <= 0 {
+		return time.Time{}
+	}
+	return now.Add(time.Duration(r.Auth.LeaseDuration) * time.Second)
+}
+
+// approleToken returns the token of the last AppRole login, logging in
+// again when there is none or it is about to expire.
+func (v *Vault) approleToken(ctx context.Context) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.loginToken != "" && (v.loginExpiry.IsZero() || v.now().Before(v.loginExpiry.Add(-loginExpiryMargin))) {
+		return v.loginToken, nil
+	}
+
+	req := struct {
+		RoleID   string `json:"role_id"`
+		SecretID string `json:"secret_id,omitempty"`
+	}{v.opts.RoleID, v.opts.SecretID}
+	var resp authResponse
+	if err := v.do(ctx, http.MethodPost, "auth/approle/login", "", req, &resp); err != nil {
+		return "", err
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("vault: AppRole login returned no token")
+	}
+	v.loginToken = resp.Auth.ClientToken
+	v.loginExpiry = resp.expiry(v.now())
+	return v.loginToken, nil
+}
+
+// forgetLogin drops token if it is the token of the last AppRole login.
+func (v *Vault) forgetLogin(token string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.loginToken == token {
+		v.loginToken = ""
+		v.loginExpiry = time.Time{}
+	}
+}
+
+// do sends a request to the Vault API and decodes the response into out,
+// unless out is nil.
+func (v *Vault) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.opts.Addr+"/v1/"+path, body)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&apiErr)
+		return &statusError{method: method, path: path, status: resp.StatusCode, errors: apiErr.Errors}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(out); err != nil {
+		return fmt.Errorf("vault: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// statusError is a request the Vault API answered with an error status.
+type statusError struct {
+	method, path string
+	status       int
+	errors       []string
+}
+
+func (e *statusError) Error() string {
+	if len(e.errors) > 0 {
+		return fmt.Sprintf("vault: %s %s: status %d: %s", e.method, e.path, e.status, strings.Join(e.errors, "; "))
+	}
+	return fmt.Sprintf("vault: %s %s: status %d", e.method, e.path, e.status)
+}
+
+// isForbidden reports whether Vault refused the token of a request.
+func isForbidden(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.status == http.StatusForbidden
+}
@@ -0,0 +1,288 @@
+// Package startsession assembles a start request uploaded in parts: the base
+// config first, then the users of its inbounds in numbered batches. It
+// keeps the body of any single request small for nodes with many users.
+package startsession
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	// ttl is how long a session stays open after its last upload.
+	ttl = 30 * time.Minute
+	// maxSessions bounds the open sessions, the least recently used is
+	// dropped first.
+	maxSessions = 8
+)
+
+var (
+	ErrSessionNotFound = errors.New("start session not found")
+	ErrUnknownInbound  = errors.New("inbound not in base config")
+	ErrMissingBatches  = errors.New("batches missing")
+)
+
+// InboundUsers are clients to add to the settings of the inbound tagged Tag.
+type InboundUsers struct {
+	Tag     string                   `json:"tag"`
+	Clients []map[string]interface{} `json:"clients"`
+}
+
+// Info is a snapshot of a session. Batches are the sequence numbers
+// received so far, so an uploader that lost track can tell which to resend.
+type Info struct {
+	ID        string    `json:"sessionId"`
+	Batches   []int     `json:"batches"`
+	Users     int       `json:"users"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type session struct {
+	id         string
+	xrayConfig map[string]interface{}
+	internals  xray.Internals
+	tags       map[string]struct{}
+	batches    map[int][]InboundUsers
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+// Manager keeps the open sessions in memory. Sessions do not survive a
+// restart; the uploader starts over.
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	log      *logger.Logger
+}
+
+// NewManager creates a manager without sessions.
+func NewManager(log *logger.Logger) *Manager {
+	return &Manager{
+		sessions: make(map[string]*session),
+		log:      log,
+	}
+}
+
+// Create opens a session for a start with xrayConfig and internals. The
+// users uploaded in batches are added to the clients already in xrayConfig.
+func (m *Manager) Create(xrayConfig map[string]interface{}, internals xray.Internals) Info {
+	now := time.Now()
+	s := &session{
+		id:         newID(),
+		xrayConfig: xrayConfig,
+		internals:  internals,
+		tags:       make(map[string]struct{}),
+		batches:    make(map[int][]InboundUsers),
+		createdAt:  now,
+		updatedAt:  now,
+	}
+	inbounds, _ := xrayConfig["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		if inbound, ok := inboundRaw.(map[string]interface{}); ok {
+			if tag, _ := inbound["tag"].(string); tag != "" {
+				s.tags[tag] = struct{}{}
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(now)
+	if len(m.sessions) >= maxSessions {
+		m.evictOldestLocked()
+	}
+	m.sessions[s.id] = s
+
+	m.log.WithField("sessionId", s.id).Info("Start session opened")
+
+	return s.info()
+}
+
+// AddBatch stores batch seq of a session. Uploading a seq again replaces
+// the batch, so a failed upload can simply be retried.
+func (m *Manager) AddBatch(id string, seq int, users []InboundUsers) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getLocked(id)
+	if err != nil {
+		return Info{}, err
+	}
+	for _, inbound := range users {
+		if _, ok := s.tags[inbound.Tag]; !ok {
+			return Info{}, fmt.Errorf("%w: %s", ErrUnknownInbound, inbound.Tag)
+		}
+	}
+
+	s.batches[seq] = users
+	s.updatedAt = time.Now()
+
+	return s.info(), nil
+}
+
+// Get returns the state of a session.
+func (m *Manager) Get(id string) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.getLocked(id)
+	if err != nil {
+		return Info{}, err
+	}
+	return s.info(), nil
+}
+
+// Build assembles the start request of a session, with the clients of the
+// batches appended in sequence order. If expectedBatches is positive,
+// batches 0 to expectedBatches-1 must all have been received. The session
+// stays open, so a failed start can be retried; Delete it once done.
+func (m *Manager) Build(id string, expectedBatches int) (map[string]interface{}, xray.Internals, error) {
+	m.mu.Lock()
+	s, err := m.getLocked(id)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, xray.Internals{}, err
+	}
+
+	var missing []int
+	for seq := 0; seq < expectedBatches; seq++ {
+		if _, ok := s.batches[seq]; !ok {
+			missing = append(missing, seq)
+		}
+	}
+	if len(missing) > 0 {
+		m.mu.Unlock()
+		return nil, xray.Internals{}, fmt.Errorf("%w: %v", ErrMissingBatches, missing)
+	}
+
+	seqs := s.seqs()
+	clients := make(map[string][]interface{})
+	for _, seq := range seqs {
+		for _, inbound := range s.batches[seq] {
+			for _, client := range inbound.Clients {
+				clients[inbound.Tag] = append(clients[inbound.Tag], client)
+			}
+		}
+	}
+	base, internals := s.xrayConfig, s.internals
+	s.updatedAt = time.Now()
+	m.mu.Unlock()
+
+	// Work on a copy, the base config is reused by a retry.
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, xray.Internals{}, err
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, xray.Internals{}, err
+	}
+
+	inbounds, _ := config["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		inbound, ok := inboundRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tag, _ := inbound["tag"].(string)
+		if len(clients[tag]) == 0 {
+			continue
+		}
+		settings, ok := inbound["settings"].(map[string]interface{})
+		if !ok {
+			settings = make(map[string]interface{})
+			inbound["settings"] = settings
+		}
+		existing, _ := settings["clients"].([]interface{})
+		settings["clients"] = append(existing, clients[tag]...)
+	}
+
+	return config, internals, nil
+}
+
+// Delete closes a session. Returns false if there was no such session.
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sessions[id]; !exists {
+		return false
+	}
+	delete(m.sessions, id)
+	return true
+}
+
+func (m *Manager) getLocked(id string) (*session, error) {
+	m.pruneLocked(time.Now())
+
+	s, exists := m.sessions[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return s, nil
+}
+
+// pruneLocked drops the sessions idle for longer than ttl.
+func (m *Manager) pruneLocked(now time.Time) {
+	for id, s := range m.sessions {
+		if now.Sub(s.updatedAt) > ttl {
+			delete(m.sessions, id)
+			m.log.WithField("sessionId", id).Info("Start session expired")
+		}
+	}
+}
+
+func (m *Manager) evictOldestLocked() {
+	var oldest *session
+	for _, s := range m.sessions {
+		if oldest == nil || s.updatedAt.Before(oldest.updatedAt) {
+			oldest = s
+		}
+	}
+	if oldest != nil {
+		delete(m.sessions, oldest.id)
+		m.log.WithField("sessionId", oldest.id).Warn("Too many start sessions, dropped the least recently used")
+	}
+}
+
+func (s *session) seqs() []int {
+	seqs := make([]int, 0, len(s.batches))
+	for seq := range s.batches {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	return seqs
+}
+
+func (s *session) info() Info {
+	users := 0
+	for _, batch := range s.batches {
+		for _, inbound := range batch {
+			users += len(inbound.Clients)
+		}
+	}
+	return Info{
+		ID:        s.id,
+		Batches:   s.seqs(),
+		Users:     users,
+		CreatedAt: s.createdAt,
+		ExpiresAt: s.updatedAt.Add(ttl),
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
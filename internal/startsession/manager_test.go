@@ -0,0 +1,133 @@
+package startsession
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestManager() *Manager {
+	return NewManager(logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON}))
+}
+
+func baseConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"settings": map[string]interface{}{"clients": []interface{}{map[string]interface{}{"id": "a"}}},
+			},
+			map[string]interface{}{"tag": "trojan-in"},
+		},
+	}
+}
+
+func clients(ids ...string) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, map[string]interface{}{"id": id})
+	}
+	return list
+}
+
+func inboundClientIDs(t *testing.T, config map[string]interface{}, index int) []string {
+	t.Helper()
+	inbound := config["inbounds"].([]interface{})[index].(map[string]interface{})
+	settings, _ := inbound["settings"].(map[string]interface{})
+	list, _ := settings["clients"].([]interface{})
+	ids := make([]string, 0, len(list))
+	for _, client := range list {
+		ids = append(ids, client.(map[string]interface{})["id"].(string))
+	}
+	return ids
+}
+
+func TestManager_BuildAssemblesBatchesInOrder(t *testing.T) {
+	m := newTestManager()
+	internals := xray.Internals{Hashes: xray.Hashes{EmptyConfig: "hash123"}}
+	info := m.Create(baseConfig(), internals)
+
+	_, err := m.AddBatch(info.ID, 1, []InboundUsers{{Tag: "vless-in", Clients: clients("c")}, {Tag: "trojan-in", Clients: clients("t")}})
+	require.NoError(t, err)
+	info, err = m.AddBatch(info.ID, 0, []InboundUsers{{Tag: "vless-in", Clients: clients("b")}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, info.Batches)
+	assert.Equal(t, 3, info.Users)
+
+	config, gotInternals, err := m.Build(info.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, internals, gotInternals)
+	assert.Equal(t, []string{"a", "b", "c"}, inboundClientIDs(t, config, 0))
+	assert.Equal(t, []string{"t"}, inboundClientIDs(t, config, 1))
+
+	// Building again, e.g. to retry a failed start, yields the same config.
+	again, _, err := m.Build(info.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, config, again)
+}
+
+func TestManager_BatchRetryReplaces(t *testing.T) {
+	m := newTestManager()
+	info := m.Create(baseConfig(), xray.Internals{})
+
+	_, err := m.AddBatch(info.ID, 0, []InboundUsers{{Tag: "vless-in", Clients: clients("b")}})
+	require.NoError(t, err)
+	info, err = m.AddBatch(info.ID, 0, []InboundUsers{{Tag: "vless-in", Clients: clients("b", "c")}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Users)
+
+	config, _, err := m.Build(info.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, inboundClientIDs(t, config, 0))
+}
+
+func TestManager_Errors(t *testing.T) {
+	m := newTestManager()
+	info := m.Create(baseConfig(), xray.Internals{})
+
+	_, err := m.AddBatch(info.ID, 0, []InboundUsers{{Tag: "missing-in", Clients: clients("b")}})
+	assert.ErrorIs(t, err, ErrUnknownInbound)
+
+	_, err = m.AddBatch("unknown", 0, nil)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = m.AddBatch(info.ID, 1, []InboundUsers{{Tag: "vless-in", Clients: clients("b")}})
+	require.NoError(t, err)
+	_, _, err = m.Build(info.ID, 3)
+	assert.ErrorIs(t, err, ErrMissingBatches)
+	assert.Contains(t, err.Error(), "[0 2]")
+
+	assert.True(t, m.Delete(info.ID))
+	assert.False(t, m.Delete(info.ID))
+	_, err = m.Get(info.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestManager_ExpiresIdleSessions(t *testing.T) {
+	m := newTestManager()
+	info := m.Create(baseConfig(), xray.Internals{})
+
+	m.mu.Lock()
+	m.sessions[info.ID].updatedAt = time.Now().Add(-ttl - time.Second)
+	m.mu.Unlock()
+
+	_, err := m.Get(info.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestManager_EvictsLeastRecentlyUsed(t *testing.T) {
+	m := newTestManager()
+	first := m.Create(baseConfig(), xray.Internals{})
+	for range maxSessions {
+		m.Create(baseConfig(), xray.Internals{})
+	}
+
+	_, err := m.Get(first.ID)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.Len(t, m.sessions, maxSessions)
+}
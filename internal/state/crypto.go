@@ -0,0 +1,87 @@
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of state encryption keys, for AES-256.
+const KeySize = 32
+
+// encryptedMagic starts the files of encrypted documents, followed by the
+// GCM nonce and the sealed JSON.
+var encryptedMagic = []byte("RWNSTATE1")
+
+// ErrNoKey is returned when loading an encrypted document without a key.
+var ErrNoKey = errors.New("state is encrypted and no key is configured")
+
+// ParseKey decodes a base64 encryption key of KeySize bytes, such as the
+// output of "openssl rand -base64 32".
+func ParseKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("state encryption key is not base64: %w", err)
+	}
+	if len(key) != KeySize {
+		clear(key)
+		return nil, fmt.Errorf("state encryption key is %d bytes, expected %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// newAEAD returns the AES-256-GCM cipher of key and wipes key, which the
+// cipher no longer needs.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	defer clear(key)
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("state encryption key is %d bytes, expected %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the document of key. The key is authenticated too, so a
+// file renamed to another key fails to open.
+func seal(aead cipher.AEAD, key string, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(key)), nil
+}
+
+// open decrypts a sealed document of key. Documents written without
+// encryption are returned as they are.
+func open(aead cipher.AEAD, key string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedMagic) {
+		return data, nil
+	}
+	if aead == nil {
+		return nil, ErrNoKey
+	}
+
+	sealed := data[len(encryptedMagic):]
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted state is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, errors.New("failed to decrypt state, wrong key or corrupted file")
+	}
+	return plain, nil
+}
@@ -0,0 +1,525 @@
+package state
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout    = 5 * time.Second
+	redisCommandTimeout = 5 * time.Second
+)
+
+// errRedisNil is the reply of Redis to a GET of a missing key.
+var errRedisNil = errors.New("redis: nil")
+
+// RedisOptions are the connection settings of a RedisStore.
+type RedisOptions struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	TLS      bool
+}
+
+// ParseRedisURL parses redis://[[user]:password@]host[:port][/db], or
+// rediss:// for TLS. The port defaults to 6379.
+func ParseRedisURL(value string) (RedisOptions, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return RedisOptions{}, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	var opts RedisOptions
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		opts.TLS = true
+	default:
+		return RedisOptions{}, fmt.Errorf("invalid redis URL scheme %q, expected redis or rediss", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return RedisOptions{}, errors.New("redis URL has no host")
+	}
+	port := u.Port()
+	if port == "" {
+		port = "6379"
+	}
+	opts.Addr = net.JoinHostPort(u.Hostname(), port)
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		opts.DB, err = strconv.Atoi(db)
+		if err != nil || opts.DB < 0 {
+			return RedisOptions{}, fmt.Errorf("invalid redis database %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// RedisStore keeps each key under a prefix in Redis, so that the replicas
+// of a node, e.g. behind one anycast address, share their state. A replica
+// reads the documents when it starts.
+//
+// A document that is a JSON object, such as the blocked IPs by rule, is
+// kept in a Redis hash with one field per entry. A save writes the entries
+// that changed since the replica last loaded or saved the document and
+// deletes those it removed, so replicas saving the same document merge
+// their entries; two saves of the same entry are last-writer-wins. Other
+// documents are kept in Redis strings and replaced whole. Documents of a
+// single replica go to the store of ForReplica.
+type RedisStore struct {
+	conn   *redisConn
+	prefix string
+	// aead encrypts the documents, or the entries of the hashes; nil keeps
+	// them in plain JSON.
+	aead cipher.AEAD
+
+	mu sync.Mutex
+	// entries holds, by key, the entries of the hashes as this replica
+	// last loaded or saved them.
+	entries map[string]map[string]string
+}
+
+// redisConn speaks the Redis protocol over a single connection, dialed
+// again after an error.
+type redisConn struct {
+	mu     sync.Mutex
+	opts   RedisOptions
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisStore connects to the Redis server of rawURL and returns a
+// RedisStore keeping its keys under prefix, encrypted with key when it is
+// not nil. The key is wiped.
+func NewRedisStore(rawURL, prefix string, key []byte) (*RedisStore, error) {
+	opts, err := ParseRedisURL(rawURL)
+	if err != nil {
+		clear(key)
+		return nil, err
+	}
+
+	s := &RedisStore{conn: &redisConn{opts: opts}, prefix: prefix, entries: make(map[string]map[string]string)}
+	if key != nil {
+		if s.aead, err = newAEAD(key); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.conn.do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return s, nil
+}
+
+// ForReplica returns a store on the same connection keeping its keys under
+// <prefix><id>:, apart from those of the other replicas, for the documents
+// that only make sense to one replica such as its traffic counters.
+func (s *RedisStore) ForReplica(id string) *RedisStore {
+	return &RedisStore{
+		conn:    s.conn,
+		prefix:  s.prefix + id + ":",
+		aead:    s.aead,
+		entries: make(map[string]map[string]string),
+	}
+}
+
+func (s *RedisStore) key(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return s.prefix + key, nil
+}
+
+func (s *RedisStore) Load(key string, v interface{}) (bool, error) {
+	redisKey, err := s.key(key)
+	if err != nil {
+		return false, err
+	}
+
+	items, err := s.conn.doArray("HGETALL", redisKey)
+	if err == nil {
+		return s.loadEntries(key, items, v)
+	}
+	if !isWrongType(err) {
+		return false, fmt.Errorf("failed to read state %q: %w", key, err)
+	}
+
+	reply, err := s.conn.do("GET", redisKey)
+	if errors.Is(err, errRedisNil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %q: %w", key, err)
+	}
+	data, err := open(s.aead, key, reply)
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %q: %w", key, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *RedisStore) Save(key string, v interface{}) error {
+	redisKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %q: %w", key, err)
+	}
+	var entries map[string]json.RawMessage
+	if len(data) > 0 && data[0] == '{' && json.Unmarshal(data, &entries) == nil {
+		return s.saveEntries(key, redisKey, entries)
+	}
+	if s.aead != nil {
+		if data, err = seal(s.aead, key, data); err != nil {
+			return fmt.Errorf("failed to encrypt state %q: %w", key, err)
+		}
+	}
+
+	if _, err := s.conn.do("SET", redisKey, string(data)); err != nil {
+		return fmt.Errorf("failed to write state %q: %w", key, err)
+	}
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// loadEntries decodes the field-value pairs of the hash of key into v. An
+// empty reply is a missing key.
+func (s *RedisStore) loadEntries(key string, items [][]byte, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(items) == 0 {
+		delete(s.entries, key)
+		return false, nil
+	}
+	doc := make(map[string]json.RawMessage, len(items)/2)
+	entries := make(map[string]string, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		field := string(items[i])
+		data, err := open(s.aead, entryKey(key, field), items[i+1])
+		if err != nil {
+			return false, fmt.Errorf("failed to read state %q: %w", key, err)
+		}
+		doc[field] = data
+		entries[field] = string(data)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	s.entries[key] = entries
+	return true, nil
+}
+
+// saveEntries writes the entries that changed since the last load or save
+// of key and deletes those removed meanwhile. Entries that other replicas
+// saved and this one never saw are left alone.
+func (s *RedisStore) saveEntries(key, redisKey string, doc map[string]json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.entries[key]
+	entries := make(map[string]string, len(doc))
+	fields := make([]string, 0, len(doc))
+	for field, value := range doc {
+		entries[field] = string(value)
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	set := []string{"HSET", redisKey}
+	for _, field := range fields {
+		if value, ok := previous[field]; ok && value == entries[field] {
+			continue
+		}
+		data := []byte(entries[field])
+		if s.aead != nil {
+			var err error
+			if data, err = seal(s.aead, entryKey(key, field), data); err != nil {
+				return fmt.Errorf("failed to encrypt state %q: %w", key, err)
+			}
+		}
+		set = append(set, field, string(data))
+	}
+	del := []string{"HDEL", redisKey}
+	for field := range previous {
+		if _, ok := entries[field]; !ok {
+			del = append(del, field)
+		}
+	}
+	sort.Strings(del[2:])
+
+	if len(set) > 2 {
+		if _, err := s.conn.do(set...); err != nil {
+			return fmt.Errorf("failed to write state %q: %w", key, err)
+		}
+	}
+	if len(del) > 2 {
+		if _, err := s.conn.do(del...); err != nil {
+			return fmt.Errorf("failed to write state %q: %w", key, err)
+		}
+	}
+	s.entries[key] = entries
+	return nil
+}
+
+// entryKey is what an entry of the hash of key is sealed with, so that an
+// entry moved to another field or hash fails to open.
+func entryKey(key, field string) string {
+	return key + "\x00" + field
+}
+
+func (s *RedisStore) Delete(key string) error {
+	redisKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	if _, err := s.conn.do("DEL", redisKey); err != nil {
+		return fmt.Errorf("failed to delete state %q: %w", key, err)
+	}
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// Close closes the connection to Redis, shared with the stores of
+// ForReplica.
+func (s *RedisStore) Close() error {
+	s.conn.mu.Lock()
+	defer s.conn.mu.Unlock()
+	return s.conn.disconnect()
+}
+
+// do sends a command and returns its reply.
+func (s *redisConn) do(args ...string) ([]byte, error) {
+	return exec(s, args, readReply)
+}
+
+// doArray sends a command replying with an array and returns its elements.
+func (s *redisConn) doArray(args ...string) ([][]byte, error) {
+	return exec(s, args, readArrayReply)
+}
+
+// exec sends a command and reads its reply with read. A command failing on
+// a connection that Redis may have closed meanwhile is sent once more on a
+// new one.
+func exec[T any](s *redisConn, args []string, read func(*bufio.Reader) (T, error)) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reused := s.conn != nil
+	reply, err := send(s, args, read)
+	if err != nil && reused && isConnError(err) {
+		reply, err = send(s, args, read)
+	}
+	return reply, err
+}
+
+func send[T any](s *redisConn, args []string, read func(*bufio.Reader) (T, error)) (T, error) {
+	var zero T
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return zero, err
+		}
+	}
+	if err := s.write(args); err != nil {
+		s.disconnect()
+		return zero, err
+	}
+	reply, err := read(s.reader)
+	if isConnError(err) {
+		s.disconnect()
+	}
+	return reply, err
+}
+
+// connect dials Redis, then authenticates and selects the database.
+func (s *redisConn) connect() error {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if s.opts.TLS {
+		host, _, _ := net.SplitHostPort(s.opts.Addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.opts.Addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", s.opts.Addr)
+	}
+	if err != nil {
+		return &connError{err}
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+
+	if s.opts.Password != "" {
+		args := []string{"AUTH", s.opts.Password}
+		if s.opts.Username != "" {
+			args = []string{"AUTH", s.opts.Username, s.opts.Password}
+		}
+		if _, err := s.roundTrip(args); err != nil {
+			s.disconnect()
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	if s.opts.DB != 0 {
+		if _, err := s.roundTrip([]string{"SELECT", strconv.Itoa(s.opts.DB)}); err != nil {
+			s.disconnect()
+			return fmt.Errorf("failed to select database %d: %w", s.opts.DB, err)
+		}
+	}
+	return nil
+}
+
+func (s *redisConn) disconnect() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+	return err
+}
+
+func (s *redisConn) roundTrip(args []string) ([]byte, error) {
+	if err := s.write(args); err != nil {
+		return nil, err
+	}
+	return readReply(s.reader)
+}
+
+func (s *redisConn) write(args []string) error {
+	if err := s.conn.SetDeadline(time.Now().Add(redisCommandTimeout)); err != nil {
+		return &connError{err}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(s.conn, b.String()); err != nil {
+		return &connError{err}
+	}
+	return nil
+}
+
+// readReply reads a simple string, error, integer or bulk string reply.
+// Simple strings and integers are returned as their text.
+func readReply(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, &connError{err}
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, &connError{errors.New("empty redis reply")}
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, &connError{fmt.Errorf("invalid redis reply %q", line)}
+		}
+		if size < 0 {
+			return nil, errRedisNil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, &connError{err}
+		}
+		return data[:size], nil
+	default:
+		return nil, &connError{fmt.Errorf("unexpected redis reply %q", line)}
+	}
+}
+
+// readArrayReply reads an array reply of bulk strings, a missing one being
+// empty.
+func readArrayReply(r *bufio.Reader) ([][]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, &connError{err}
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, &connError{errors.New("empty redis reply")}
+	}
+
+	switch line[0] {
+	case '-':
+		return nil, errors.New(line[1:])
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, &connError{fmt.Errorf("invalid redis reply %q", line)}
+		}
+		items := make([][]byte, 0, max(count, 0))
+		for range count {
+			item, err := readReply(r)
+			if err != nil && !errors.Is(err, errRedisNil) {
+				if !isConnError(err) {
+					err = &connError{err}
+				}
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, &connError{fmt.Errorf("unexpected redis reply %q", line)}
+	}
+}
+
+// isWrongType reports whether err is the reply of Redis to a command on a
+// key holding another type.
+func isWrongType(err error) bool {
+	return err != nil && !isConnError(err) && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// connError marks the errors after which the connection is unusable, as
+// opposed to the error replies of Redis.
+type connError struct {
+	err error
+}
+
+func (e *connError) Error() string { return e.err.Error() }
+func (e *connError) Unwrap() error { return e.err }
+
+func isConnError(err error) bool {
+	var ce *connError
+	return errors.As(err, &ce)
+}
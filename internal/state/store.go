@@ -0,0 +1,244 @@
+package state
+
+import (
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrInvalidKey = errors.New("invalid state key")
+
+var keyPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Store persists named JSON documents holding node runtime state
+// (blocked IPs, config snapshots, counters) across process restarts, in
+// a directory, in memory or in Redis.
+type Store interface {
+	// Load decodes the document stored under key into v.
+	// Returns false if nothing is stored under key.
+	Load(key string, v interface{}) (bool, error)
+	// Save replaces the document stored under key.
+	Save(key string, v interface{}) error
+	// Delete removes the document stored under key, if any.
+	Delete(key string) error
+}
+
+// New returns a FileStore rooted at dir, encrypted with key when it is not
+// nil, or a MemoryStore if dir is empty.
+func New(dir string, key []byte) (Store, error) {
+	if dir == "" {
+		clear(key)
+		return NewMemoryStore(), nil
+	}
+	if key != nil {
+		return NewEncryptedFileStore(dir, key)
+	}
+	return NewFileStore(dir)
+}
+
+// FileStore keeps each key in its own <key>.json file inside a directory.
+// Writes are atomic (temp file + rename).
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+	// aead encrypts the files; nil keeps them in plain JSON.
+	aead cipher.AEAD
+}
+
+// NewFileStore creates the state directory if needed and returns a FileStore.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// NewEncryptedFileStore returns a FileStore encrypting its files with the
+// AES-256-GCM key, which it wipes. Files written without encryption are
+// still read, and encrypted when saved again.
+func NewEncryptedFileStore(dir string, key []byte) (*FileStore, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.aead = aead
+	return s, nil
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.dir, key+".json"), nil
+}
+
+func (s *FileStore) Load(key string, v interface{}) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	data, err := os.ReadFile(path)
+	s.mu.Unlock()
+
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %q: %w", key, err)
+	}
+	data, err = open(s.aead, key, data)
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %q: %w", key, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *FileStore) Save(key string, v interface{}) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %q: %w", key, err)
+	}
+	if s.aead != nil {
+		if data, err = seal(s.aead, key, data); err != nil {
+			return fmt.Errorf("failed to encrypt state %q: %w", key, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state %q: %w", key, err)
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write state %q: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write state %q: %w", key, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write state %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete state %q: %w", key, err)
+	}
+	return nil
+}
+
+// MemoryStore is a non-persistent Store, used when no state directory is configured.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Load(key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	data, ok := s.data[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to decode state %q: %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *MemoryStore) Save(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %q: %w", key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = data
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// FreezableStore passes through to a Store until frozen, after which saves
+// and deletes are dropped: a process that handed its work over to another
+// must not overwrite the state the other one keeps.
+type FreezableStore struct {
+	Store
+	frozen atomic.Bool
+}
+
+func NewFreezableStore(store Store) *FreezableStore {
+	return &FreezableStore{Store: store}
+}
+
+// Freeze drops all later writes, until Unfreeze.
+func (s *FreezableStore) Freeze() {
+	s.frozen.Store(true)
+}
+
+func (s *FreezableStore) Unfreeze() {
+	s.frozen.Store(false)
+}
+
+func (s *FreezableStore) Save(key string, v interface{}) error {
+	if s.frozen.Load() {
+		return nil
+	}
+	return s.Store.Save(key, v)
+}
+
+func (s *FreezableStore) Delete(key string) error {
+	if s.frozen.Load() {
+		return nil
+	}
+	return s.Store.Delete(key)
+}
@@ -0,0 +1,183 @@
+package state
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_EmptyDirUsesMemoryStore(t *testing.T) {
+	s, err := New("", nil)
+	require.NoError(t, err)
+	_, ok := s.(*MemoryStore)
+	assert.True(t, ok)
+}
+
+func TestFileStore_SaveLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	in := map[string]string{"a": "1.2.3.4"}
+	require.NoError(t, s.Save("blocked-ips", in))
+
+	var out map[string]string
+	found, err := s.Load("blocked-ips", &out)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, in, out)
+
+	info, err := os.Stat(filepath.Join(dir, "blocked-ips.json"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestFileStore_LoadMissing(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	var out map[string]string
+	found, err := s.Load("missing", &out)
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, out)
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Save("key", []int{1, 2}))
+	require.NoError(t, s.Delete("key"))
+	require.NoError(t, s.Delete("key"))
+
+	var out []int
+	found, err := s.Load("key", &out)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFileStore_InvalidKey(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	err = s.Save("../escape", 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestFileStore_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.json"), []byte("{not json"), 0o600))
+
+	var out map[string]string
+	_, err = s.Load("bad", &out)
+	assert.Error(t, err)
+}
+
+func testKey(t *testing.T, fill byte) []byte {
+	t.Helper()
+
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, KeySize)))
+	require.NoError(t, err)
+	return key
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(t, 7)
+	assert.Len(t, key, KeySize)
+
+	_, err := ParseKey("not base64!")
+	assert.Error(t, err)
+	_, err = ParseKey(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	dir := t.TempDir()
+
+	// A document saved before encryption was enabled.
+	plain, err := NewFileStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, plain.Save("legacy", []string{"192.0.2.1"}))
+
+	key := testKey(t, 1)
+	s, err := NewEncryptedFileStore(dir, key)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, KeySize), key, "the key is wiped")
+
+	var legacy []string
+	found, err := s.Load("legacy", &legacy)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []string{"192.0.2.1"}, legacy)
+
+	require.NoError(t, s.Save("blocked-ips", map[string]string{"a": "198.51.100.7"}))
+	data, err := os.ReadFile(filepath.Join(dir, "blocked-ips.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "198.51.100.7")
+
+	var out map[string]string
+	found, err = s.Load("blocked-ips", &out)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "198.51.100.7", out["a"])
+
+	_, err = plain.Load("blocked-ips", &out)
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	other, err := NewEncryptedFileStore(dir, testKey(t, 2))
+	require.NoError(t, err)
+	_, err = other.Load("blocked-ips", &out)
+	assert.Error(t, err)
+
+	// A file moved to another key does not decrypt.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "checkpoint.json"), data, 0o600))
+	_, err = s.Load("checkpoint", &out)
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_SaveLoadDelete(t *testing.T) {
+	s := NewMemoryStore()
+
+	require.NoError(t, s.Save("key", map[string]int{"x": 1}))
+
+	var out map[string]int
+	found, err := s.Load("key", &out)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, out["x"])
+
+	require.NoError(t, s.Delete("key"))
+	found, err = s.Load("key", &out)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestFreezableStore_DropsWritesOnceFrozen(t *testing.T) {
+	s := NewFreezableStore(NewMemoryStore())
+
+	require.NoError(t, s.Save("key", map[string]int{"x": 1}))
+	s.Freeze()
+	require.NoError(t, s.Save("key", map[string]int{"x": 2}))
+	require.NoError(t, s.Delete("key"))
+
+	var out map[string]int
+	found, err := s.Load("key", &out)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 1, out["x"])
+
+	s.Unfreeze()
+	require.NoError(t, s.Save("key", map[string]int{"x": 3}))
+	_, err = s.Load("key", &out)
+	require.NoError(t, err)
+	assert.Equal(t, 3, out["x"])
+}
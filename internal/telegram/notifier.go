@@ -0,0 +1,245 @@
+// Package telegram sends alerts for critical node events to a Telegram
+// chat through a bot.
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	defaultAPIURL  = "https://api.telegram.org"
+	requestTimeout = 10 * time.Second
+)
+
+// Notifier formats the critical events of a bus as Telegram messages. Each
+// event type is sent at most once per rate limit window; alerts suppressed
+// in the meantime are counted in the next message of that type.
+type Notifier struct {
+	apiURL string
+	token  string
+	chatID string
+	bus    *events.Bus
+	client *http.Client
+	node   string
+	log    *logger.Logger
+	now    func() time.Time
+
+	rateMu    sync.Mutex
+	rateLimit time.Duration
+
+	// lastSent and suppressed are per event type, owned by the
+	// delivery goroutine.
+	lastSent   map[string]time.Time
+	suppressed map[string]int
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewNotifier creates a notifier sending as the bot with the given token
+// to chatID, at most one message per event type every rateLimit.
+func NewNotifier(token, chatID string, rateLimit time.Duration, bus *events.Bus, log *logger.Logger) *Notifier {
+	hostname, _ := os.Hostname()
+
+	return &Notifier{
+		apiURL:     defaultAPIURL,
+		token:      token,
+		chatID:     chatID,
+		rateLimit:  rateLimit,
+		bus:        bus,
+		client:     &http.Client{Timeout: requestTimeout},
+		node:       hostname,
+		log:        log,
+		now:        time.Now,
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// SetRateLimit changes how often messages of one event type are sent at
+// most. Alerts already suppressed are kept for the next message.
+func (n *Notifier) SetRateLimit(rateLimit time.Duration) {
+	n.rateMu.Lock()
+	defer n.rateMu.Unlock()
+
+	n.rateLimit = rateLimit
+}
+
+// Start subscribes to the bus and launches the delivery goroutine.
+func (n *Notifier) Start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopCh != nil {
+		return
+	}
+
+	n.stopCh = make(chan struct{})
+	stopCh := n.stopCh
+	ch, unsubscribe := n.bus.Subscribe()
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case event := <-ch:
+				n.handle(event)
+			}
+		}
+	}()
+}
+
+// Stop terminates the delivery goroutine.
+func (n *Notifier) Stop() {
+	n.mu.Lock()
+	stopCh := n.stopCh
+	n.stopCh = nil
+	n.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		n.wg.Wait()
+	}
+}
+
+func (n *Notifier) handle(event events.Event) {
+	title, details, ok := format(event)
+	if !ok {
+		return
+	}
+
+	n.rateMu.Lock()
+	rateLimit := n.rateLimit
+	n.rateMu.Unlock()
+
+	now := n.now()
+	if last, sent := n.lastSent[event.Type]; sent && now.Sub(last) < rateLimit {
+		n.suppressed[event.Type]++
+		return
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "<b>%s</b>\nNode: <code>%s</code>", html.EscapeString(title), html.EscapeString(n.node))
+	for _, line := range details {
+		text.WriteString("\n")
+		text.WriteString(line)
+	}
+	if count := n.suppressed[event.Type]; count > 0 {
+		fmt.Fprintf(&text, "\n<i>%d similar alert(s) suppressed</i>", count)
+	}
+
+	// A failed send still counts against the rate limit, so an unreachable
+	// API is not hammered by a burst of events.
+	n.lastSent[event.Type] = now
+	n.suppressed[event.Type] = 0
+	if err := n.send(text.String()); err != nil {
+		n.log.WithError(err).WithField("event", event.Type).Warn("Failed to send Telegram alert")
+	}
+}
+
+// format returns the title and HTML detail lines of a critical event; ok
+// is false for the other events.
+func format(event events.Event) (title string, details []string, ok bool) {
+	switch data := event.Data.(type) {
+	case events.XrayEvent:
+		if event.Type != events.TypeXrayCrashed {
+			return "", nil, false
+		}
+		title = "Xray failed to start"
+		if data.Error != nil {
+			details = append(details, "<pre>"+html.EscapeString(*data.Error)+"</pre>")
+		}
+	case events.CertificateEvent:
+		if data.Expired {
+			title = fmt.Sprintf("Certificate %s has expired", data.Name)
+		} else {
+			title = fmt.Sprintf("Certificate %s expires in %d day(s)", data.Name, data.DaysLeft)
+		}
+		details = append(details,
+			"Subject: "+html.EscapeString(data.Subject),
+			"Not after: "+data.NotAfter.UTC().Format(time.RFC3339))
+	case events.DiskEvent:
+		title = "Disk space is running low"
+		details = append(details, fmt.Sprintf("Path: <code>%s</code>\nFree: %d%% (%d MiB of %d MiB)",
+			html.EscapeString(data.Path), data.FreePercent, data.FreeBytes>>20, data.TotalBytes>>20))
+	case events.MemoryEvent:
+		title = "Xray memory usage exceeds the threshold"
+		restart := "Restarting xray now"
+		if data.RestartDeferred {
+			restart = "Restarting xray in the maintenance window"
+		}
+		details = append(details, fmt.Sprintf("RSS: %d MiB (threshold %d MiB)\n%s",
+			data.RSSBytes>>20, data.ThresholdBytes>>20, restart))
+	case events.LimitEvent:
+		title = "Resource limit is nearly exhausted"
+		details = append(details, fmt.Sprintf("Resource: <code>%s</code>\nUsed: %d of %d (%d%%)",
+			html.EscapeString(data.Resource), data.Used, data.Limit, data.UsedPercent))
+	default:
+		return "", nil, false
+	}
+	return title, details, true
+}
+
+type sendMessageRequest struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+func (n *Notifier) send(text string) error {
+	body, err := json.Marshal(sendMessageRequest{
+		ChatID:                n.chatID,
+		Text:                  text,
+		ParseMode:             "HTML",
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := n.apiURL + "/bot" + n.token + "/sendMessage"
+	resp, err := n.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL holds the bot token; keep it out of the logs.
+		return fmt.Errorf("request to Telegram API failed: %w", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Description string `json:"description"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("Telegram API responded with status %d: %s", resp.StatusCode, apiErr.Description)
+	}
+	return nil
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
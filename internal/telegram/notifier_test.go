@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func newTestNotifier(t *testing.T, status int) (*Notifier, *[]sendMessageRequest, *[]string) {
+	t.Helper()
+
+	var messages []sendMessageRequest
+	var paths []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg sendMessageRequest
+		json.NewDecoder(r.Body).Decode(&msg)
+		messages = append(messages, msg)
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	n := NewNotifier("123:token", "-10042", 10*time.Minute, events.NewBus(), log)
+	n.apiURL = api.URL
+	n.node = "node-1"
+	return n, &messages, &paths
+}
+
+func TestNotifier_FormatsCriticalEvents(t *testing.T) {
+	n, messages, paths := newTestNotifier(t, http.StatusOK)
+
+	errMsg := "failed to parse <config>"
+	n.handle(events.Event{Type: events.TypeXrayCrashed, Data: events.XrayEvent{Error: &errMsg}})
+	n.handle(events.Event{Type: events.TypeXrayStarted, Data: events.XrayEvent{Version: "25.1.1"}})
+	n.handle(events.Event{Type: events.TypeUserAdded, Data: events.UserEvent{Username: "alice"}})
+	n.handle(events.Event{Type: events.TypeCertExpiring, Data: events.CertificateEvent{
+		Name: "node", Subject: "CN=node", NotAfter: time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), DaysLeft: 7,
+	}})
+	n.handle(events.Event{Type: events.TypeDiskLow, Data: events.DiskEvent{
+		Path: "/var/lib/remnawave", FreeBytes: 100 << 20, TotalBytes: 4096 << 20, FreePercent: 2,
+	}})
+	n.handle(events.Event{Type: events.TypeMemoryHigh, Data: events.MemoryEvent{
+		RSSBytes: 1100 << 20, ThresholdBytes: 1024 << 20, RestartDeferred: true,
+	}})
+
+	require.Len(t, *messages, 4)
+	assert.Equal(t, "/bot123:token/sendMessage", (*paths)[0])
+	assert.Equal(t, "-10042", (*messages)[0].ChatID)
+	assert.Equal(t, "HTML", (*messages)[0].ParseMode)
+	assert.Equal(t, "<b>Xray failed to start</b>\nNode: <code>node-1</code>\n<pre>failed to parse &lt;config&gt;</pre>", (*messages)[0].Text)
+	assert.Contains(t, (*messages)[1].Text, "<b>Certificate node expires in 7 day(s)</b>")
+	assert.Contains(t, (*messages)[1].Text, "Not after: 2030-01-02T00:00:00Z")
+	assert.Contains(t, (*messages)[2].Text, "Free: 2% (100 MiB of 4096 MiB)")
+	assert.Contains(t, (*messages)[3].Text, "RSS: 1100 MiB (threshold 1024 MiB)\nRestarting xray in the maintenance window")
+}
+
+func TestNotifier_RateLimitsPerType(t *testing.T) {
+	n, messages, _ := newTestNotifier(t, http.StatusOK)
+	now := time.Now()
+	n.now = func() time.Time { return now }
+
+	crash := events.Event{Type: events.TypeXrayCrashed, Data: events.XrayEvent{}}
+	n.handle(crash)
+	n.handle(crash)
+	n.handle(crash)
+	n.handle(events.Event{Type: events.TypeDiskLow, Data: events.DiskEvent{Path: "/"}})
+	assert.Len(t, *messages, 2, "other event types are not limited")
+
+	now = now.Add(10 * time.Minute)
+	n.handle(crash)
+	require.Len(t, *messages, 3)
+	assert.Contains(t, (*messages)[2].Text, "2 similar alert(s) suppressed")
+
+	now = now.Add(10 * time.Minute)
+	n.handle(crash)
+	require.Len(t, *messages, 4)
+	assert.NotContains(t, (*messages)[3].Text, "suppressed")
+
+	n.SetRateLimit(time.Minute)
+	now = now.Add(time.Minute)
+	n.handle(crash)
+	assert.Len(t, *messages, 5)
+}
+
+func TestNotifier_SendError(t *testing.T) {
+	n, _, _ := newTestNotifier(t, http.StatusBadRequest)
+
+	err := n.send("test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+	assert.NotContains(t, err.Error(), "123:token")
+}
+
+func TestNotifier_StartStop(t *testing.T) {
+	sent := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg sendMessageRequest
+		json.NewDecoder(r.Body).Decode(&msg)
+		sent <- msg.Text
+	}))
+	defer api.Close()
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	bus := events.NewBus()
+	n := NewNotifier("123:token", "-10042", time.Minute, bus, log)
+	n.apiURL = api.URL
+
+	n.Start()
+	n.Start()
+	bus.Publish(events.TypeXrayCrashed, events.XrayEvent{})
+	select {
+	case text := <-sent:
+		assert.Contains(t, text, "Xray failed to start")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert sent")
+	}
+	n.Stop()
+	n.Stop()
+	assert.Equal(t, 0, bus.SubscriberCount())
+}
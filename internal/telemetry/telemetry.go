@@ -0,0 +1,168 @@
+// Package telemetry exports OpenTelemetry traces and metrics of the node
+// to an OTLP collector. Spans continue the trace of the panel request that
+// caused them, so a multi-node deployment gets traces running from the
+// panel through each node down to the xray operation.
+//
+// Instrumented code uses the global providers, which are no-ops until
+// Setup is called.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName names the tracer and meter of the node.
+	InstrumentationName = "github.com/remnawave/node-go"
+	// ServiceName is the default service.name, overridden by
+	// OTEL_SERVICE_NAME.
+	ServiceName = "remnawave-node"
+
+	metricsInterval = 30 * time.Second
+)
+
+// Provider holds the SDK providers installed by Setup.
+type Provider struct {
+	traces  *sdktrace.TracerProvider
+	metrics *sdkmetric.MeterProvider
+}
+
+// Setup installs global tracer and meter providers exporting over OTLP/HTTP
+// to endpoint, the base URL of the collector such as
+// http://otel-collector:4318, and the W3C trace context propagator.
+// Headers and other exporter settings are read from the standard
+// OTEL_EXPORTER_OTLP_* variables.
+func Setup(ctx context.Context, endpoint, version string) (*Provider, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", endpoint)
+	}
+
+	traceOpts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path.Join("/", u.Path, "v1/traces")),
+	}
+	metricOpts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(u.Host),
+		otlpmetrichttp.WithURLPath(path.Join("/", u.Path, "v1/metrics")),
+	}
+	if u.Scheme == "http" {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	traceExporter, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", ServiceName),
+			attribute.String("service.version", version),
+		),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build resource: %w", err)
+	}
+
+	p := &Provider{
+		traces: sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(traceExporter),
+			sdktrace.WithResource(res),
+		),
+		metrics: sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(metricsInterval))),
+			sdkmetric.WithResource(res),
+		),
+	}
+	otel.SetTracerProvider(p.traces)
+	otel.SetMeterProvider(p.metrics)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return p, nil
+}
+
+// Shutdown flushes the pending spans and metrics and stops the exporters.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return errors.Join(p.traces.Shutdown(ctx), p.metrics.Shutdown(ctx))
+}
+
+// Tracer returns the tracer of the node.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Instruments are created on the global meter provider, which forwards
+// them to the one installed by Setup.
+var (
+	meter = otel.Meter(InstrumentationName)
+
+	operations, _ = meter.Int64Counter("node.operations",
+		metric.WithDescription("Node operations by operation and result"))
+	operationDuration, _ = meter.Float64Histogram("node.operation.duration",
+		metric.WithDescription("Duration of node operations"), metric.WithUnit("s"))
+	requestDuration, _ = meter.Float64Histogram("node.request.duration",
+		metric.WithDescription("Duration of panel requests by API, route and status"), metric.WithUnit("s"))
+)
+
+// Track starts a span for a node operation, such as xray.start or
+// user.add, and returns a function ending it with the HTTP status the
+// operation mapped to. The operation is counted by result, ok for 2xx
+// statuses and error otherwise.
+func Track(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, func(status int)) {
+	start := time.Now()
+	ctx, span := Tracer().Start(ctx, operation, trace.WithAttributes(attrs...))
+
+	return ctx, func(status int) {
+		result := "ok"
+		if status < 200 || status >= 300 {
+			result = "error"
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.SetAttributes(attribute.Int("status", status))
+		span.End()
+
+		set := metric.WithAttributes(attribute.String("operation", operation), attribute.String("result", result))
+		operations.Add(ctx, 1, set)
+		operationDuration.Record(ctx, time.Since(start).Seconds(), set)
+	}
+}
+
+// RecordRequest records the duration of a panel request; api is http or
+// grpc and status the HTTP status or gRPC code.
+func RecordRequest(ctx context.Context, api, route, status string, duration time.Duration) {
+	requestDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.String("api", api),
+		attribute.String("route", route),
+		attribute.String("status", status),
+	))
+}
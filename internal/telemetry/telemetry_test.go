@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetup_InvalidEndpoint(t *testing.T) {
+	for _, endpoint := range []string{"otel-collector:4318", "grpc://otel-collector:4317", "http://"} {
+		_, err := Setup(context.Background(), endpoint, "test")
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func TestSetup_ExportsToCollector(t *testing.T) {
+	var mu sync.Mutex
+	paths := make(map[string]int)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	p, err := Setup(context.Background(), collector.URL+"/otlp", "test")
+	require.NoError(t, err)
+
+	_, end := Track(context.Background(), "xray.start")
+	end(http.StatusOK)
+	_, end = Track(context.Background(), "user.add")
+	end(http.StatusServiceUnavailable)
+	RecordRequest(context.Background(), "http", "/node/xray/start", "200", 0)
+
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, paths["/otlp/v1/traces"])
+	assert.Equal(t, 1, paths["/otlp/v1/metrics"])
+}
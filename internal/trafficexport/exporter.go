@@ -0,0 +1,301 @@
+// Package trafficexport writes per-user traffic to ClickHouse or to an
+// InfluxDB-compatible time-series database, so long-term usage analytics
+// do not depend on the panel polling the node.
+package trafficexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// Export formats.
+const (
+	// FormatClickHouse inserts JSONEachRow rows through the ClickHouse
+	// HTTP interface.
+	FormatClickHouse = "clickhouse"
+	// FormatInflux writes InfluxDB line protocol, also accepted by
+	// VictoriaMetrics and QuestDB.
+	FormatInflux = "influx"
+
+	// DefaultTable is the ClickHouse table rows are inserted into.
+	DefaultTable = "remnawave_traffic"
+	// measurement is the InfluxDB measurement of the points.
+	measurement = "remnawave_traffic"
+
+	requestTimeout = 30 * time.Second
+)
+
+// Options configure an Exporter.
+type Options struct {
+	// URL is the ClickHouse HTTP endpoint (credentials as user info) or
+	// the full line protocol write URL, query included.
+	URL      string
+	Format   string
+	Interval time.Duration
+	// Table is the ClickHouse table, DefaultTable when empty.
+	Table string
+	// Token is sent as "Authorization: Token <token>" to InfluxDB.
+	Token string
+}
+
+// Sample is the traffic of one user during one history interval.
+type Sample struct {
+	Time     time.Time
+	Node     string
+	User     string
+	Uplink   int64
+	Downlink int64
+}
+
+// Source provides the traffic snapshots to export; it is implemented by
+// history.Recorder.
+type Source interface {
+	Range(from, to time.Time) []history.Snapshot
+}
+
+// Exporter periodically writes the per-user traffic recorded by the
+// history recorder since the last export. Samples that fail to be written
+// are retried on the next interval, as long as the history still holds
+// them.
+type Exporter struct {
+	opts   Options
+	source Source
+	client *http.Client
+	node   string
+	log    *logger.Logger
+
+	mu sync.Mutex
+	// exported is the time of the last snapshot written.
+	exported time.Time
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// New creates an exporter reading from source.
+func New(opts Options, source Source, log *logger.Logger) (*Exporter, error) {
+	switch opts.Format {
+	case FormatClickHouse, FormatInflux:
+	default:
+		return nil, fmt.Errorf("unknown format %q", opts.Format)
+	}
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", opts.URL)
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	hostname, _ := os.Hostname()
+
+	return &Exporter{
+		opts:   opts,
+		source: source,
+		client: &http.Client{Timeout: requestTimeout},
+		node:   hostname,
+		log:    log,
+	}, nil
+}
+
+// Start launches the goroutine exporting every interval.
+func (e *Exporter) Start() {
+	e.mu.Lock()
+	if e.stopCh != nil {
+		e.mu.Unlock()
+		return
+	}
+	e.stopCh = make(chan struct{})
+	stopCh := e.stopCh
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(e.opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				if err := e.Export(); err != nil {
+					e.log.WithError(err).WithField("format", e.opts.Format).Warn("Failed to export traffic, will retry")
+				}
+			}
+		}
+	}()
+}
+
+// Stop terminates the export goroutine.
+func (e *Exporter) Stop() {
+	e.mu.Lock()
+	stopCh := e.stopCh
+	e.stopCh = nil
+	e.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		e.wg.Wait()
+	}
+}
+
+// Export writes the samples recorded since the last successful export.
+func (e *Exporter) Export() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var samples []Sample
+	last := e.exported
+	for _, snapshot := range e.source.Range(e.exported, time.Time{}) {
+		if !snapshot.Time.After(e.exported) {
+			continue
+		}
+		samples = append(samples, e.samples(snapshot)...)
+		last = snapshot.Time
+	}
+	if len(samples) == 0 {
+		e.exported = last
+		return nil
+	}
+
+	var err error
+	switch e.opts.Format {
+	case FormatClickHouse:
+		err = e.writeClickHouse(samples)
+	case FormatInflux:
+		err = e.writeInflux(samples)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.exported = last
+	e.log.WithField("samples", len(samples)).Debug("Traffic exported")
+	return nil
+}
+
+// samples returns the users of snapshot sorted by name.
+func (e *Exporter) samples(snapshot history.Snapshot) []Sample {
+	users := make([]string, 0, len(snapshot.Users))
+	for user := range snapshot.Users {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	samples := make([]Sample, 0, len(users))
+	for _, user := range users {
+		traffic := snapshot.Users[user]
+		samples = append(samples, Sample{
+			Time:     snapshot.Time,
+			Node:     e.node,
+			User:     user,
+			Uplink:   traffic.Uplink,
+			Downlink: traffic.Downlink,
+		})
+	}
+	return samples
+}
+
+type clickHouseRow struct {
+	// Time is in unix seconds, which a DateTime column accepts.
+	Time     int64  `json:"time"`
+	Node     string `json:"node"`
+	User     string `json:"user"`
+	Uplink   int64  `json:"uplink"`
+	Downlink int64  `json:"downlink"`
+}
+
+func (e *Exporter) writeClickHouse(samples []Sample) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, s := range samples {
+		if err := encoder.Encode(clickHouseRow{
+			Time:     s.Time.Unix(),
+			Node:     s.Node,
+			User:     s.User,
+			Uplink:   s.Uplink,
+			Downlink: s.Downlink,
+		}); err != nil {
+			return err
+		}
+	}
+
+	u, _ := url.Parse(e.opts.URL)
+	query := u.Query()
+	query.Set("query", "INSERT INTO "+e.opts.Table+" FORMAT JSONEachRow")
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	return e.do(req)
+}
+
+func (e *Exporter) writeInflux(samples []Sample) error {
+	var body bytes.Buffer
+	for _, s := range samples {
+		fmt.Fprintf(&body, "%s,node=%s,user=%s uplink=%di,downlink=%di %d\n",
+			measurement, escapeTag(s.Node), escapeTag(s.User), s.Uplink, s.Downlink, s.Time.Unix())
+	}
+
+	endpoint, err := url.Parse(e.opts.URL)
+	if err != nil {
+		return err
+	}
+	// Timestamps are in seconds, for both the 2.x and the 1.x write API.
+	query := endpoint.Query()
+	query.Set("precision", "s")
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.opts.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.opts.Token)
+	}
+	return e.do(req)
+}
+
+func (e *Exporter) do(req *http.Request) error {
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s responded with status %d: %s", e.opts.Format, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+var tagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// escapeTag escapes a line protocol tag value.
+func escapeTag(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return tagEscaper.Replace(value)
+}
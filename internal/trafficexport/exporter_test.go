@@ -0,0 +1,126 @@
+package trafficexport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/history"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type fakeSource []history.Snapshot
+
+func (f *fakeSource) Range(from, to time.Time) []history.Snapshot {
+	var result []history.Snapshot
+	for _, s := range *f {
+		if !s.Time.Before(from) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+type request struct {
+	url   string
+	auth  string
+	user  string
+	body  string
+	query map[string][]string
+}
+
+func newTestExporter(t *testing.T, format, path string, status *int) (*Exporter, *fakeSource, *[]request) {
+	t.Helper()
+
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, _, _ := r.BasicAuth()
+		requests = append(requests, request{
+			url:   r.URL.Path,
+			auth:  r.Header.Get("Authorization"),
+			user:  user,
+			body:  string(body),
+			query: r.URL.Query(),
+		})
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	source := &fakeSource{}
+	target := server.URL + path
+	if format == FormatClickHouse {
+		target = "http://default:secret@" + server.Listener.Addr().String() + path
+	}
+	e, err := New(Options{URL: target, Format: format, Token: "influx-token", Interval: time.Minute}, source, log)
+	require.NoError(t, err)
+	e.node = "node-1"
+	return e, source, &requests
+}
+
+var (
+	t0 = time.Date(2026, 1, 1, 0, 1, 0, 0, time.UTC)
+	t1 = t0.Add(time.Minute)
+)
+
+func TestExporter_ClickHouse(t *testing.T) {
+	status := http.StatusOK
+	e, source, requests := newTestExporter(t, FormatClickHouse, "/", &status)
+
+	*source = append(*source, history.Snapshot{Time: t0, Users: map[string]history.Traffic{
+		"bob":   {Uplink: 3, Downlink: 4},
+		"alice": {Uplink: 1, Downlink: 2},
+	}})
+	require.NoError(t, e.Export())
+
+	require.Len(t, *requests, 1)
+	r := (*requests)[0]
+	assert.Equal(t, "default", r.user)
+	assert.Equal(t, "INSERT INTO remnawave_traffic FORMAT JSONEachRow", r.query["query"][0])
+	assert.Equal(t, `{"time":1767225660,"node":"node-1","user":"alice","uplink":1,"downlink":2}
+{"time":1767225660,"node":"node-1","user":"bob","uplink":3,"downlink":4}
+`, r.body)
+
+	require.NoError(t, e.Export())
+	assert.Len(t, *requests, 1, "nothing new to export")
+}
+
+func TestExporter_InfluxRetriesFailedSamples(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	e, source, requests := newTestExporter(t, FormatInflux, "/api/v2/write?org=o&bucket=b", &status)
+
+	*source = append(*source, history.Snapshot{Time: t0, Users: map[string]history.Traffic{
+		"alice smith": {Uplink: 1, Downlink: 2},
+	}})
+	assert.Error(t, e.Export())
+
+	status = http.StatusNoContent
+	*source = append(*source,
+		history.Snapshot{Time: t1, Users: map[string]history.Traffic{"bob": {Uplink: 5}}},
+	)
+	require.NoError(t, e.Export())
+
+	require.Len(t, *requests, 2)
+	r := (*requests)[1]
+	assert.Equal(t, "/api/v2/write", r.url)
+	assert.Equal(t, "Token influx-token", r.auth)
+	assert.Equal(t, []string{"s"}, r.query["precision"])
+	assert.Equal(t, []string{"b"}, r.query["bucket"])
+	assert.Equal(t, `remnawave_traffic,node=node-1,user=alice\ smith uplink=1i,downlink=2i 1767225660
+remnawave_traffic,node=node-1,user=bob uplink=5i,downlink=0i 1767225720
+`, r.body)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	_, err := New(Options{URL: "http://clickhouse:8123", Format: "csv"}, &fakeSource{}, log)
+	assert.Error(t, err)
+	_, err = New(Options{URL: "clickhouse:8123", Format: FormatClickHouse}, &fakeSource{}, log)
+	assert.Error(t, err)
+}
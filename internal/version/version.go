@@ -0,0 +1,55 @@
+// Package version holds the build metadata of the node, set at build time
+// with -ldflags "-X github.com/remnawave/node-go/internal/version.Version=...",
+// and likewise for Commit and BuildTime.
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+const unknown = "unknown"
+
+var (
+	// Version is the release of the node, such as v1.2.0.
+	Version = "dev"
+	// Commit is the VCS revision the node was built from. When not set at
+	// build time, it is taken from the build info Go records.
+	Commit = unknown
+	// BuildTime is when the node was built, in RFC 3339. When not set at
+	// build time, the commit time from the build info is used.
+	BuildTime = unknown
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == unknown {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if BuildTime == unknown {
+				BuildTime = setting.Value
+			}
+		}
+	}
+}
+
+// ShortCommit returns the first 12 characters of Commit.
+func ShortCommit() string {
+	if len(Commit) > 12 {
+		return Commit[:12]
+	}
+	return Commit
+}
+
+// String describes the build, e.g. "v1.2.0 (commit 0123456789ab, built
+// 2024-01-02T03:04:05Z)".
+func String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", Version, ShortCommit(), BuildTime)
+}
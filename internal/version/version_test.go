@@ -0,0 +1,20 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	defer func(v, c, b string) { Version, Commit, BuildTime = v, c, b }(Version, Commit, BuildTime)
+
+	Version = "v1.2.0"
+	Commit = "0123456789abcdef0123456789abcdef01234567"
+	BuildTime = "2024-01-02T03:04:05Z"
+	assert.Equal(t, "0123456789ab", ShortCommit())
+	assert.Equal(t, "v1.2.0 (commit 0123456789ab, built 2024-01-02T03:04:05Z)", String())
+
+	Commit = unknown
+	assert.Equal(t, unknown, ShortCommit())
+}
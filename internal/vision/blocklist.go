@@ -0,0 +1,414 @@
+package vision
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+const (
+	// BlockOutboundTag is the blackhole outbound injected by generateAPIConfig.
+	BlockOutboundTag = "BLOCK"
+
+	stateKey        = "blocked-ips"
+	janitorInterval = 5 * time.Second
+)
+
+var ErrInvalidSource = errors.New("invalid IP address or CIDR range")
+
+// Entry is a single blocked address or range.
+type Entry struct {
+	IP        string     `json:"ip"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// UnmarshalJSON also accepts the legacy format where an entry was a bare IP string.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var ip string
+	if err := json.Unmarshal(data, &ip); err == nil {
+		*e = Entry{IP: ip}
+		return nil
+	}
+
+	type plain Entry
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*e = Entry(p)
+	return nil
+}
+
+func (e Entry) expired(now time.Time) bool {
+	return e.ExpiresAt != nil && !now.Before(*e.ExpiresAt)
+}
+
+// Firewall drops blocked sources in the kernel, so xray does not spend
+// work on their connections. See internal/firewall.
+type Firewall interface {
+	Add(source string, expiresAt *time.Time) error
+	Remove(source string) error
+}
+
+// Blocklist tracks blocked IPs/CIDRs, mirrors them into xray routing rules
+// pointing at the BLOCK outbound and, when set, into a firewall, and
+// persists them in the state store.
+type Blocklist struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+	core    *xray.Core
+	store   state.Store
+	log     *logger.Logger
+
+	firewall Firewall
+
+	// saveMu orders the saves, so that a snapshot never overwrites a newer
+	// one.
+	saveMu sync.Mutex
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewBlocklist restores persisted entries and registers a core start hook
+// that re-applies them after every xray (re)start.
+func NewBlocklist(core *xray.Core, store state.Store, log *logger.Logger) *Blocklist {
+	b := &Blocklist{
+		entries: make(map[string]Entry),
+		core:    core,
+		store:   store,
+		log:     log,
+	}
+
+	b.restore()
+	core.OnStart(b.reapply)
+
+	return b
+}
+
+// RuleTag returns the routing rule tag used for a normalized source.
+func RuleTag(source string) string {
+	hash := md5.Sum([]byte(source))
+	return hex.EncodeToString(hash[:])
+}
+
+// Block blocks an IP or CIDR range. A ttl of zero blocks permanently;
+// re-blocking an existing entry replaces its expiry.
+// Returns the normalized source.
+func (b *Blocklist) Block(source string, ttl time.Duration) (string, error) {
+	normalized, err := xray.NormalizeSource(source)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidSource, source)
+	}
+
+	entry := Entry{IP: normalized}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl).UTC()
+		entry.ExpiresAt = &expiresAt
+	}
+
+	ruleTag := RuleTag(normalized)
+
+	b.mu.Lock()
+	_, alreadyBlocked := b.entries[ruleTag]
+	b.entries[ruleTag] = entry
+	b.mu.Unlock()
+
+	// Blocks requested while xray is down are kept and applied on next start.
+	if !alreadyBlocked && b.core.IsRunning() {
+		if err := b.core.AddRoutingRule(ruleTag, normalized, BlockOutboundTag); err != nil {
+			b.mu.Lock()
+			delete(b.entries, ruleTag)
+			b.mu.Unlock()
+			return "", err
+		}
+	}
+
+	b.save()
+	b.firewallAdd(entry)
+
+	b.log.WithField("ip", normalized).WithField("ruleTag", ruleTag).Info("IP blocked")
+
+	return normalized, nil
+}
+
+// Unblock removes an IP or CIDR block. Returns false if it was not blocked.
+func (b *Blocklist) Unblock(source string) (bool, error) {
+	normalized, err := xray.NormalizeSource(source)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidSource, source)
+	}
+
+	ruleTag := RuleTag(normalized)
+
+	b.mu.Lock()
+	_, wasBlocked := b.entries[ruleTag]
+	delete(b.entries, ruleTag)
+	b.mu.Unlock()
+
+	if !wasBlocked {
+		return false, nil
+	}
+
+	b.save()
+	b.removeRule(ruleTag, normalized)
+	b.firewallRemove(normalized)
+
+	b.log.WithField("ip", normalized).WithField("ruleTag", ruleTag).Info("IP unblocked")
+
+	return true, nil
+}
+
+// List returns all active entries sorted by address, IPv4 first.
+func (b *Blocklist) List() []Entry {
+	now := time.Now()
+
+	b.mu.RLock()
+	entries := make([]Entry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		if !entry.expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+	b.mu.RUnlock()
+
+	// Entries are normalized, so they always parse.
+	sort.Slice(entries, func(i, j int) bool {
+		a, _ := xray.ParsePrefix(entries[i].IP)
+		b, _ := xray.ParsePrefix(entries[j].IP)
+		return xray.ComparePrefixes(a, b) < 0
+	})
+	return entries
+}
+
+// IsBlocked reports whether source, an IP or a CIDR range, is blocked as a
+// whole, directly or by a wider CIDR entry of the same address family.
+func (b *Blocklist) IsBlocked(source string) bool {
+	prefix, err := xray.ParsePrefix(source)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, entry := range b.entries {
+		if entry.expired(now) {
+			continue
+		}
+		blocked, err := xray.ParsePrefix(entry.IP)
+		if err == nil && blocked.Bits() <= prefix.Bits() && blocked.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start launches the janitor goroutine that drops expired entries.
+func (b *Blocklist) Start() {
+	b.mu.Lock()
+	if b.stopCh != nil {
+		b.mu.Unlock()
+		return
+	}
+	b.stopCh = make(chan struct{})
+	stopCh := b.stopCh
+	b.mu.Unlock()
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		ticker := time.NewTicker(janitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case now := <-ticker.C:
+				b.removeExpired(now)
+			}
+		}
+	}()
+}
+
+// Stop terminates the janitor goroutine.
+func (b *Blocklist) Stop() {
+	b.mu.Lock()
+	stopCh := b.stopCh
+	b.stopCh = nil
+	b.mu.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+		b.wg.Wait()
+	}
+}
+
+// removeExpired deletes entries whose TTL elapsed and their routing rules.
+func (b *Blocklist) removeExpired(now time.Time) int {
+	b.mu.Lock()
+	expired := make(map[string]string)
+	for ruleTag, entry := range b.entries {
+		if entry.expired(now) {
+			expired[ruleTag] = entry.IP
+			delete(b.entries, ruleTag)
+		}
+	}
+	b.mu.Unlock()
+
+	if len(expired) == 0 {
+		return 0
+	}
+
+	for ruleTag, ip := range expired {
+		b.removeRule(ruleTag, ip)
+		b.firewallRemove(ip)
+	}
+	b.save()
+
+	b.log.WithField("count", len(expired)).Info("Expired IP blocks removed")
+
+	return len(expired)
+}
+
+func (b *Blocklist) removeRule(ruleTag, ip string) {
+	if !b.core.IsRunning() {
+		return
+	}
+	if err := b.core.RemoveRoutingRule(ruleTag); err != nil {
+		b.log.WithError(err).WithField("ip", ip).Warn("Failed to remove routing rule")
+	}
+}
+
+// SetFirewall mirrors the blocked sources into fw from now on, starting
+// with the current ones. A nil fw stops mirroring; the caller cleans up
+// the firewall it set before.
+func (b *Blocklist) SetFirewall(fw Firewall) {
+	now := time.Now()
+
+	b.mu.Lock()
+	b.firewall = fw
+	active := make([]Entry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		if !entry.expired(now) {
+			active = append(active, entry)
+		}
+	}
+	b.mu.Unlock()
+
+	if fw == nil {
+		return
+	}
+	for _, entry := range active {
+		b.firewallAdd(entry)
+	}
+}
+
+func (b *Blocklist) firewallAdd(entry Entry) {
+	b.mu.RLock()
+	fw := b.firewall
+	b.mu.RUnlock()
+
+	if fw == nil {
+		return
+	}
+	if err := fw.Add(entry.IP, entry.ExpiresAt); err != nil {
+		b.log.WithError(err).WithField("ip", entry.IP).Warn("Failed to add firewall block")
+	}
+}
+
+func (b *Blocklist) firewallRemove(ip string) {
+	b.mu.RLock()
+	fw := b.firewall
+	b.mu.RUnlock()
+
+	if fw == nil {
+		return
+	}
+	if err := fw.Remove(ip); err != nil {
+		b.log.WithError(err).WithField("ip", ip).Warn("Failed to remove firewall block")
+	}
+}
+
+// reapply installs routing rules for all tracked entries.
+// Registered as a core start hook, since rules do not survive a restart.
+func (b *Blocklist) reapply() {
+	now := time.Now()
+
+	b.mu.RLock()
+	active := make(map[string]string, len(b.entries))
+	for ruleTag, entry := range b.entries {
+		if !entry.expired(now) {
+			active[ruleTag] = entry.IP
+		}
+	}
+	b.mu.RUnlock()
+
+	for ruleTag, ip := range active {
+		if err := b.core.AddRoutingRule(ruleTag, ip, BlockOutboundTag); err != nil {
+			b.log.WithError(err).WithField("ip", ip).Error("Failed to re-apply IP block")
+		}
+	}
+
+	if len(active) > 0 {
+		b.log.WithField("count", len(active)).Info("Re-applied blocked IPs after xray start")
+	}
+}
+
+// restore loads the entries persisted by a previous run.
+func (b *Blocklist) restore() {
+	var stored map[string]Entry
+	found, err := b.store.Load(stateKey, &stored)
+	if err != nil {
+		b.log.WithError(err).Error("Failed to restore blocked IPs")
+		return
+	}
+	if !found {
+		return
+	}
+
+	now := time.Now()
+
+	b.mu.Lock()
+	for _, entry := range stored {
+		normalized, err := xray.NormalizeSource(entry.IP)
+		if err != nil || entry.expired(now) {
+			continue
+		}
+		entry.IP = normalized
+		b.entries[RuleTag(normalized)] = entry
+	}
+	count := len(b.entries)
+	b.mu.Unlock()
+
+	b.log.WithField("count", count).Info("Restored blocked IPs from state")
+}
+
+// save persists the current entries.
+func (b *Blocklist) save() {
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+
+	b.mu.RLock()
+	snapshot := make(map[string]Entry, len(b.entries))
+	for ruleTag, entry := range b.entries {
+		snapshot[ruleTag] = entry
+	}
+	b.mu.RUnlock()
+
+	if err := b.store.Save(stateKey, snapshot); err != nil {
+		b.log.WithError(err).Error("Failed to persist blocked IPs")
+	}
+}
@@ -0,0 +1,244 @@
+package vision
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
+	"github.com/remnawave/node-go/internal/xray"
+)
+
+func newTestBlocklist(t *testing.T, store state.Store) *Blocklist {
+	t.Helper()
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	return NewBlocklist(xray.NewCore(log), store, log)
+}
+
+func TestBlocklist_BlockUnblock(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+
+	normalized, err := b.Block("10.0.0.1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", normalized)
+	assert.True(t, b.IsBlocked("10.0.0.1"))
+
+	removed, err := b.Unblock("10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.False(t, b.IsBlocked("10.0.0.1"))
+
+	removed, err = b.Unblock("10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, removed)
+}
+
+func TestBlocklist_InvalidSource(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+
+	_, err := b.Block("not-an-ip", 0)
+	assert.ErrorIs(t, err, ErrInvalidSource)
+
+	_, err = b.Block("10.0.0.0/33", 0)
+	assert.ErrorIs(t, err, ErrInvalidSource)
+}
+
+func TestBlocklist_CIDR(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+
+	normalized, err := b.Block("10.1.2.3/24", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "10.1.2.0/24", normalized)
+
+	assert.True(t, b.IsBlocked("10.1.2.200"))
+	assert.False(t, b.IsBlocked("10.1.3.1"))
+
+	removed, err := b.Unblock("10.1.2.0/24")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.False(t, b.IsBlocked("10.1.2.200"))
+}
+
+func TestBlocklist_TTLExpiry(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+
+	_, err := b.Block("192.0.2.1", time.Minute)
+	require.NoError(t, err)
+	_, err = b.Block("192.0.2.2", 0)
+	require.NoError(t, err)
+
+	entries := b.List()
+	require.Len(t, entries, 2)
+	assert.NotNil(t, entries[0].ExpiresAt)
+	assert.Nil(t, entries[1].ExpiresAt)
+
+	assert.Equal(t, 0, b.removeExpired(time.Now()))
+	assert.Equal(t, 1, b.removeExpired(time.Now().Add(2*time.Minute)))
+
+	entries = b.List()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "192.0.2.2", entries[0].IP)
+}
+
+func TestBlocklist_IPv6(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+
+	normalized, err := b.Block("2001:DB8:0:1::7/64", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:0:1::/64", normalized)
+	_, err = b.Block("::ffff:198.51.100.0/120", 0)
+	require.NoError(t, err)
+	_, err = b.Block("192.0.2.1", 0)
+	require.NoError(t, err)
+
+	assert.True(t, b.IsBlocked("2001:db8:0:1:ffff::1"))
+	assert.True(t, b.IsBlocked("2001:db8:0:1:8000::/65"), "narrower range inside the entry")
+	assert.False(t, b.IsBlocked("2001:db8::/48"), "wider range than the entry")
+	assert.False(t, b.IsBlocked("2001:db8:0:2::1"))
+	assert.True(t, b.IsBlocked("198.51.100.9"))
+	assert.True(t, b.IsBlocked("::ffff:198.51.100.9"), "IPv4-mapped address")
+	assert.False(t, b.IsBlocked("::ffff:c000:201:0/112"), "IPv6 range is not an IPv4 entry")
+
+	var ips []string
+	for _, entry := range b.List() {
+		ips = append(ips, entry.IP)
+	}
+	assert.Equal(t, []string{"192.0.2.1", "198.51.100.0/24", "2001:db8:0:1::/64"}, ips)
+
+	removed, err := b.Unblock("::ffff:198.51.100.0/120")
+	require.NoError(t, err)
+	assert.True(t, removed)
+}
+
+func TestBlocklist_PersistAndRestore(t *testing.T) {
+	store := state.NewMemoryStore()
+	b := newTestBlocklist(t, store)
+
+	_, err := b.Block("203.0.113.5", 0)
+	require.NoError(t, err)
+	_, err = b.Block("2001:db8::/32", time.Hour)
+	require.NoError(t, err)
+
+	restored := newTestBlocklist(t, store)
+	entries := restored.List()
+	require.Len(t, entries, 2)
+	assert.True(t, restored.IsBlocked("203.0.113.5"))
+	assert.True(t, restored.IsBlocked("2001:db8::1"))
+}
+
+// stallingStore holds the first save of a single entry until a save of
+// more entries is done, or for a while.
+type stallingStore struct {
+	state.Store
+	stalled chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (s *stallingStore) Save(key string, v interface{}) error {
+	if entries, ok := v.(map[string]Entry); ok && len(entries) == 1 {
+		close(s.stalled)
+		select {
+		case <-s.done:
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	err := s.Store.Save(key, v)
+	if entries, ok := v.(map[string]Entry); ok && len(entries) > 1 {
+		s.once.Do(func() { close(s.done) })
+	}
+	return err
+}
+
+func TestBlocklist_ConcurrentSaves(t *testing.T) {
+	store := &stallingStore{Store: state.NewMemoryStore(), stalled: make(chan struct{}), done: make(chan struct{})}
+	b := newTestBlocklist(t, store)
+
+	blocked := make(chan error)
+	go func() {
+		_, err := b.Block("203.0.113.5", 0)
+		blocked <- err
+	}()
+	<-store.stalled
+	_, err := b.Block("203.0.113.6", 0)
+	require.NoError(t, err)
+	require.NoError(t, <-blocked)
+
+	// The snapshot of the first block must not overwrite the later one.
+	restored := newTestBlocklist(t, store.Store)
+	assert.Len(t, restored.List(), 2)
+}
+
+func TestBlocklist_RestoreLegacyFormat(t *testing.T) {
+	store := state.NewMemoryStore()
+	require.NoError(t, store.Save(stateKey, map[string]string{
+		RuleTag("198.51.100.1"): "198.51.100.1",
+	}))
+
+	b := newTestBlocklist(t, store)
+	assert.True(t, b.IsBlocked("198.51.100.1"))
+}
+
+func TestEntry_UnmarshalJSON(t *testing.T) {
+	var e Entry
+	require.NoError(t, json.Unmarshal([]byte(`"10.0.0.1"`), &e))
+	assert.Equal(t, "10.0.0.1", e.IP)
+	assert.Nil(t, e.ExpiresAt)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"ip":"10.0.0.0/8","expiresAt":"2030-01-01T00:00:00Z"}`), &e))
+	assert.Equal(t, "10.0.0.0/8", e.IP)
+	require.NotNil(t, e.ExpiresAt)
+}
+
+func TestBlocklist_StartStop(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+	b.Start()
+	b.Start()
+	b.Stop()
+	b.Stop()
+}
+
+type fakeFirewall struct {
+	blocked map[string]*time.Time
+}
+
+func (f *fakeFirewall) Add(source string, expiresAt *time.Time) error {
+	f.blocked[source] = expiresAt
+	return nil
+}
+
+func (f *fakeFirewall) Remove(source string) error {
+	delete(f.blocked, source)
+	return nil
+}
+
+func TestBlocklist_Firewall(t *testing.T) {
+	b := newTestBlocklist(t, state.NewMemoryStore())
+	_, err := b.Block("10.0.0.1", 0)
+	require.NoError(t, err)
+
+	fw := &fakeFirewall{blocked: make(map[string]*time.Time)}
+	b.SetFirewall(fw)
+	assert.Contains(t, fw.blocked, "10.0.0.1", "existing blocks are synced")
+
+	_, err = b.Block("10.0.0.2", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, fw.blocked["10.0.0.2"])
+
+	_, err = b.Unblock("10.0.0.1")
+	require.NoError(t, err)
+	assert.NotContains(t, fw.blocked, "10.0.0.1")
+
+	b.removeExpired(time.Now().Add(2 * time.Minute))
+	assert.Empty(t, fw.blocked)
+
+	b.SetFirewall(nil)
+	_, err = b.Block("10.0.0.3", 0)
+	require.NoError(t, err)
+	assert.Empty(t, fw.blocked)
+}
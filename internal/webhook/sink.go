@@ -0,0 +1,275 @@
+// Package webhook delivers node events to an HTTP endpoint, so operators
+// are notified of crashes, expiring certificates or blocked IPs without
+// polling the node.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+	// request body keyed with the webhook secret.
+	SignatureHeader = "X-Webhook-Signature"
+	// EventHeader carries the event type.
+	EventHeader = "X-Webhook-Event"
+
+	requestTimeout = 10 * time.Second
+	// queueSize is the number of events waiting for delivery before new
+	// ones are dropped.
+	queueSize = 256
+	// maxAttempts bounds the deliveries of one event; between them the
+	// wait doubles from initialBackoff up to maxBackoff.
+	maxAttempts    = 6
+	initialBackoff = time.Second
+	maxBackoff     = time.Minute
+	// drainTimeout bounds the delivery of queued events on Stop.
+	drainTimeout = 5 * time.Second
+)
+
+// DefaultEvents are the event types delivered when none are configured:
+// the ones worth notifying someone of, not the per-user and traffic stream.
+var DefaultEvents = []string{
+	events.TypeXrayStarted,
+	events.TypeXrayStopped,
+	events.TypeXrayCrashed,
+	events.TypeCertExpiring,
+	events.TypeIPAutoBlocked,
+	events.TypeDiskLow,
+	events.TypeMemoryHigh,
+	events.TypeLimitHigh,
+}
+
+// Payload is the body POSTed for an event.
+type Payload struct {
+	events.Event
+	// Node is the hostname of the node, to tell nodes apart on a shared
+	// endpoint.
+	Node string `json:"node"`
+}
+
+// Sink POSTs the selected events of a bus to a URL, one at a time and in
+// order. A delivery that fails is retried with exponential backoff; an
+// event is dropped after maxAttempts or when the queue is full.
+type Sink struct {
+	target  atomic.Pointer[target]
+	bus     *events.Bus
+	client  *http.Client
+	node    string
+	log     *logger.Logger
+	backoff time.Duration
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// target is where and what a sink delivers.
+type target struct {
+	url    string
+	secret []byte
+	types  map[string]struct{}
+}
+
+// NewSink creates a sink for the given event types, DefaultEvents when
+// empty. With a secret, each request is signed in SignatureHeader.
+func NewSink(url, secret string, types []string, bus *events.Bus, log *logger.Logger) *Sink {
+	hostname, _ := os.Hostname()
+
+	s := &Sink{
+		bus:     bus,
+		client:  &http.Client{Timeout: requestTimeout},
+		node:    hostname,
+		log:     log,
+		backoff: initialBackoff,
+	}
+	s.SetTarget(url, secret, types)
+	return s
+}
+
+// SetTarget changes the URL, secret and event types, as NewSink takes
+// them, of the deliveries from now on, including those of queued events.
+func (s *Sink) SetTarget(url, secret string, types []string) {
+	if len(types) == 0 {
+		types = DefaultEvents
+	}
+	selected := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		selected[t] = struct{}{}
+	}
+
+	s.target.Store(&target{url: url, secret: []byte(secret), types: selected})
+}
+
+// ParseEvents splits a comma-separated list of event types.
+func ParseEvents(value string) []string {
+	var types []string
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// Start subscribes to the bus and launches the delivery goroutine.
+func (s *Sink) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopCh != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.stopCh = make(chan struct{})
+	stopCh := s.stopCh
+	ch, unsubscribe := s.bus.Subscribe()
+	queue := make(chan events.Event, queueSize)
+
+	enqueue := func(event events.Event) {
+		if _, selected := s.target.Load().types[event.Type]; !selected {
+			return
+		}
+		select {
+		case queue <- event:
+		default:
+			s.log.WithField("event", event.Type).Warn("Webhook queue full, dropping event")
+		}
+	}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		defer close(queue)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-stopCh:
+				// Events published right before Stop, such as xray.stopped
+				// on shutdown, are still delivered.
+				for {
+					select {
+					case event := <-ch:
+						enqueue(event)
+					default:
+						return
+					}
+				}
+			case event := <-ch:
+				enqueue(event)
+			}
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		for event := range queue {
+			s.deliver(ctx, stopCh, event)
+		}
+	}()
+}
+
+// Stop ends the subscription and delivers the queued events with a last
+// attempt each, for up to drainTimeout.
+func (s *Sink) Stop() {
+	s.mu.Lock()
+	stopCh, cancel := s.stopCh, s.cancel
+	s.stopCh, s.cancel = nil, nil
+	s.mu.Unlock()
+
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	timer := time.AfterFunc(drainTimeout, cancel)
+	s.wg.Wait()
+	timer.Stop()
+	cancel()
+}
+
+// deliver sends event until it succeeds or maxAttempts is reached. Once
+// stopCh is closed, a failed attempt is not retried; once ctx is done,
+// none is made.
+func (s *Sink) deliver(ctx context.Context, stopCh <-chan struct{}, event events.Event) {
+	body, err := json.Marshal(Payload{Event: event, Node: s.node})
+	if err != nil {
+		s.log.WithError(err).WithField("event", event.Type).Error("Failed to serialize webhook event")
+		return
+	}
+
+	wait := s.backoff
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			return
+		}
+		err := s.post(ctx, event.Type, body)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts {
+			s.log.WithError(err).WithField("event", event.Type).WithField("url", s.target.Load().url).
+				Error("Failed to deliver webhook, giving up")
+			return
+		}
+		s.log.WithError(err).WithField("event", event.Type).WithField("attempt", attempt).
+			Warn("Failed to deliver webhook, will retry")
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+func (s *Sink) post(ctx context.Context, eventType string, body []byte) error {
+	target := s.target.Load()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	if len(target.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(target.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body keyed with secret, as sent in
+// SignatureHeader.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/events"
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type received struct {
+	payload   Payload
+	event     string
+	signature string
+	body      []byte
+}
+
+func newTestEndpoint(t *testing.T, failures int32) (*httptest.Server, <-chan received, *atomic.Int32) {
+	t.Helper()
+
+	var attempts atomic.Int32
+	ch := make(chan received, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var payload Payload
+		json.Unmarshal(body, &payload)
+		ch <- received{
+			payload:   payload,
+			event:     r.Header.Get(EventHeader),
+			signature: r.Header.Get(SignatureHeader),
+			body:      body,
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, ch, &attempts
+}
+
+func newTestSink(url, secret string, types []string, bus *events.Bus) *Sink {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	s := NewSink(url, secret, types, bus, log)
+	s.backoff = 10 * time.Millisecond
+	return s
+}
+
+func waitReceived(t *testing.T, ch <-chan received) received {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received")
+		return received{}
+	}
+}
+
+func TestSink_DeliversSignedSelectedEvents(t *testing.T) {
+	server, ch, _ := newTestEndpoint(t, 0)
+	bus := events.NewBus()
+	s := newTestSink(server.URL, "secret", nil, bus)
+	s.Start()
+	defer s.Stop()
+
+	bus.Publish(events.TypeUserAdded, events.UserEvent{Username: "alice"})
+	bus.Publish(events.TypeIPAutoBlocked, events.IPBlockEvent{IP: "198.51.100.2", BanSeconds: 3600})
+
+	r := waitReceived(t, ch)
+	assert.Equal(t, events.TypeIPAutoBlocked, r.event)
+	assert.Equal(t, events.TypeIPAutoBlocked, r.payload.Type)
+	assert.NotEmpty(t, r.payload.Node)
+	assert.Equal(t, "sha256="+Sign([]byte("secret"), r.body), r.signature)
+	assert.JSONEq(t, `{"ip":"198.51.100.2","banSeconds":3600}`, mustMarshal(t, r.payload.Data))
+
+	select {
+	case r := <-ch:
+		t.Fatalf("unselected event %s delivered", r.event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSink_SetTarget(t *testing.T) {
+	first, firstCh, _ := newTestEndpoint(t, 0)
+	second, secondCh, _ := newTestEndpoint(t, 0)
+	bus := events.NewBus()
+	s := newTestSink(first.URL, "secret", nil, bus)
+	s.Start()
+	defer s.Stop()
+
+	s.SetTarget(second.URL, "other", []string{events.TypeUserAdded})
+	bus.Publish(events.TypeIPAutoBlocked, events.IPBlockEvent{IP: "198.51.100.2"})
+	bus.Publish(events.TypeUserAdded, events.UserEvent{Username: "alice"})
+
+	r := waitReceived(t, secondCh)
+	assert.Equal(t, events.TypeUserAdded, r.event)
+	assert.Equal(t, "sha256="+Sign([]byte("other"), r.body), r.signature)
+
+	select {
+	case r := <-firstCh:
+		t.Fatalf("event %s delivered to the old URL", r.event)
+	case r := <-secondCh:
+		t.Fatalf("unselected event %s delivered", r.event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSink_RetriesWithBackoff(t *testing.T) {
+	server, ch, attempts := newTestEndpoint(t, 2)
+	bus := events.NewBus()
+	s := newTestSink(server.URL, "", []string{events.TypeXrayCrashed}, bus)
+	s.Start()
+	defer s.Stop()
+
+	bus.Publish(events.TypeXrayCrashed, events.XrayEvent{})
+
+	r := waitReceived(t, ch)
+	assert.Equal(t, events.TypeXrayCrashed, r.event)
+	assert.Empty(t, r.signature, "no signature without a secret")
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestSink_StopAbortsRetries(t *testing.T) {
+	server, _, attempts := newTestEndpoint(t, 1000)
+	bus := events.NewBus()
+	s := newTestSink(server.URL, "", nil, bus)
+	s.backoff = time.Hour
+	s.Start()
+
+	bus.Publish(events.TypeXrayStopped, events.XrayEvent{})
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for the retry backoff")
+	}
+	assert.Equal(t, 0, bus.SubscriberCount())
+}
+
+func TestParseEvents(t *testing.T) {
+	assert.Equal(t, []string{"xray.crashed", "ip.autoblocked"}, ParseEvents(" xray.crashed, ,ip.autoblocked"))
+	assert.Empty(t, ParseEvents(""))
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSink_StopDeliversQueuedEvents(t *testing.T) {
+	server, ch, _ := newTestEndpoint(t, 0)
+	bus := events.NewBus()
+	s := newTestSink(server.URL, "", nil, bus)
+	s.Start()
+
+	bus.Publish(events.TypeXrayStopped, events.XrayEvent{})
+	s.Stop()
+
+	select {
+	case r := <-ch:
+		assert.Equal(t, events.TypeXrayStopped, r.event)
+	default:
+		t.Fatal("event published before Stop was not delivered")
+	}
+}
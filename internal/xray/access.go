@@ -0,0 +1,168 @@
+package xray
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	applog "github.com/xtls/xray-core/app/log"
+	xlog "github.com/xtls/xray-core/common/log"
+	xnet "github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+)
+
+// Access is a connection xray accepted for a user.
+type Access struct {
+	Email string
+	// IP is the client address, empty when xray did not record one.
+	IP   string
+	Time time.Time
+}
+
+// AuthFailure is a connection xray turned away: a client failing the
+// authentication of its inbound protocol, or a REALITY handshake that was
+// not from a client, typically an active probe.
+type AuthFailure struct {
+	IP     string
+	Reason string
+	Time   time.Time
+}
+
+// realityInvalidPrefix starts the message of the REALITY server for a
+// handshake that did not authenticate, followed by "<addr>: <reason>".
+const realityInvalidPrefix = "REALITY: processed invalid connection from "
+
+// OnAccess registers a hook receiving every connection xray accepts for a
+// user, whatever the access log settings of the config. Hooks run on the
+// connection path and must not block.
+func (c *Core) OnAccess(fn func(Access)) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onAccessHooks = append(c.onAccessHooks, fn)
+}
+
+// OnAuthFailure registers a hook receiving every connection xray turns away
+// with a known source address, whatever the log settings of the config.
+// Hooks run on the connection path and must not block.
+func (c *Core) OnAuthFailure(fn func(AuthFailure)) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onAuthFailureHooks = append(c.onAuthFailureHooks, fn)
+}
+
+// teeAccessLog puts accessTee in front of the log handler instance
+// registered when it was created.
+func (c *Core) teeAccessLog(instance *core.Instance) {
+	next, _ := instance.GetFeature((*applog.Instance)(nil)).(xlog.Handler)
+	xlog.RegisterHandler(&accessTee{core: c, next: next})
+}
+
+// accessTee passes the accepted access messages to the OnAccess hooks and
+// the rejected ones to the OnAuthFailure hooks, then every message to the
+// log instance of the config.
+type accessTee struct {
+	core *Core
+	next xlog.Handler
+}
+
+func (t *accessTee) Handle(msg xlog.Message) {
+	switch m := msg.(type) {
+	case *xlog.AccessMessage:
+		switch {
+		case m.Status == xlog.AccessAccepted && m.Email != "":
+			t.access(Access{Email: m.Email, IP: sourceIP(m.From), Time: time.Now()})
+		case m.Status == xlog.AccessRejected:
+			if ip := sourceIP(m.From); ip != "" {
+				t.authFailure(AuthFailure{IP: ip, Reason: serial.ToString(m.Reason), Time: time.Now()})
+			}
+		}
+	case *xlog.GeneralMessage:
+		// REALITY logs failed handshakes at info; skip formatting the other
+		// messages for nothing.
+		if m.Severity != xlog.Severity_Info || !t.hasAuthFailureHooks() {
+			break
+		}
+		if failure, ok := realityFailure(serial.ToString(m.Content)); ok {
+			t.authFailure(failure)
+		}
+	}
+
+	if t.next != nil {
+		t.next.Handle(msg)
+	}
+}
+
+func (t *accessTee) access(access Access) {
+	t.core.hooksMu.RLock()
+	hooks := t.core.onAccessHooks
+	t.core.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(access)
+	}
+}
+
+func (t *accessTee) hasAuthFailureHooks() bool {
+	t.core.hooksMu.RLock()
+	defer t.core.hooksMu.RUnlock()
+	return len(t.core.onAuthFailureHooks) > 0
+}
+
+func (t *accessTee) authFailure(failure AuthFailure) {
+	t.core.hooksMu.RLock()
+	hooks := t.core.onAuthFailureHooks
+	t.core.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(failure)
+	}
+}
+
+// realityFailure parses the message of a REALITY handshake that did not
+// authenticate.
+func realityFailure(content string) (AuthFailure, bool) {
+	_, rest, found := strings.Cut(content, realityInvalidPrefix)
+	if !found {
+		return AuthFailure{}, false
+	}
+	addr, reason, _ := strings.Cut(rest, " ")
+	ip := hostOf(strings.TrimSuffix(addr, ":"))
+	if ip == "" {
+		return AuthFailure{}, false
+	}
+	return AuthFailure{IP: ip, Reason: "REALITY: " + reason, Time: time.Now()}, true
+}
+
+// sourceIP returns the IP of the From field of an access message, which
+// inbounds fill with a destination, an address or a string.
+func sourceIP(from interface{}) string {
+	switch from := from.(type) {
+	case nil:
+		return ""
+	case xnet.Destination:
+		switch {
+		case from.Address == nil:
+			return ""
+		case from.Address.Family().IsIP():
+			return from.Address.IP().String()
+		default:
+			return from.Address.Domain()
+		}
+	case net.Addr:
+		return hostOf(from.String())
+	default:
+		s := serial.ToString(from)
+		if network, rest, ok := strings.Cut(s, ":"); ok && (network == "tcp" || network == "udp") {
+			s = rest
+		}
+		return hostOf(s)
+	}
+}
+
+func hostOf(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
@@ -0,0 +1,82 @@
+package xray
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	xlog "github.com/xtls/xray-core/common/log"
+	xnet "github.com/xtls/xray-core/common/net"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func TestSourceIP(t *testing.T) {
+	for _, tc := range []struct {
+		from interface{}
+		want string
+	}{
+		{nil, ""},
+		{xnet.TCPDestination(xnet.ParseAddress("203.0.113.10"), 5000), "203.0.113.10"},
+		{xnet.UDPDestination(xnet.ParseAddress("2001:db8::1"), 5000), "2001:db8::1"},
+		{&net.TCPAddr{IP: net.ParseIP("198.51.100.7"), Port: 443}, "198.51.100.7"},
+		{"203.0.113.11:5000", "203.0.113.11"},
+		{"tcp:[2001:db8::2]:5000", "2001:db8::2"},
+		{"203.0.113.12", "203.0.113.12"},
+	} {
+		assert.Equal(t, tc.want, sourceIP(tc.from), "%v", tc.from)
+	}
+}
+
+func TestOnAccess(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	var accesses []Access
+	c.OnAccess(func(access Access) { accesses = append(accesses, access) })
+
+	// The access log is off: hooks are fed all the same.
+	config := []byte(`{"log":{"access":"none","loglevel":"none"},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]}`)
+	require.NoError(t, c.Start(config))
+	defer c.Stop()
+
+	from := xnet.TCPDestination(xnet.ParseAddress("203.0.113.10"), 5000)
+	xlog.Record(&xlog.AccessMessage{From: from, To: "tcp:example.com:443", Status: xlog.AccessAccepted, Email: "alice"})
+	xlog.Record(&xlog.AccessMessage{From: from, To: "tcp:example.com:443", Status: xlog.AccessRejected, Email: "bob"})
+	xlog.Record(&xlog.AccessMessage{From: from, To: "tcp:example.com:443", Status: xlog.AccessAccepted})
+
+	require.Len(t, accesses, 1)
+	assert.Equal(t, "alice", accesses[0].Email)
+	assert.Equal(t, "203.0.113.10", accesses[0].IP)
+	assert.False(t, accesses[0].Time.IsZero())
+}
+
+func TestOnAuthFailure(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	var failures []AuthFailure
+	c.OnAuthFailure(func(failure AuthFailure) { failures = append(failures, failure) })
+
+	config := []byte(`{"log":{"access":"none","loglevel":"none"},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]}`)
+	require.NoError(t, c.Start(config))
+	defer c.Stop()
+
+	xlog.Record(&xlog.AccessMessage{
+		From:   &net.TCPAddr{IP: net.ParseIP("198.51.100.7"), Port: 443},
+		Status: xlog.AccessRejected,
+		Reason: "invalid request user id",
+	})
+	xlog.Record(&xlog.GeneralMessage{
+		Severity: xlog.Severity_Info,
+		Content:  "REALITY: processed invalid connection from [2001:db8::5]:40000: client hello did not match",
+	})
+	xlog.Record(&xlog.GeneralMessage{Severity: xlog.Severity_Info, Content: "unrelated message"})
+
+	require.Len(t, failures, 2)
+	assert.Equal(t, "198.51.100.7", failures[0].IP)
+	assert.Equal(t, "invalid request user id", failures[0].Reason)
+	assert.Equal(t, "2001:db8::5", failures[1].IP)
+	assert.Equal(t, "REALITY: client hello did not match", failures[1].Reason)
+}
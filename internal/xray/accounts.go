@@ -1,15 +1,22 @@
 package xray
 
 import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"github.com/xtls/xray-core/proxy/shadowsocks_2022"
 	"github.com/xtls/xray-core/proxy/trojan"
 	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vmess"
 )
 
 // CipherType represents shadowsocks cipher types.
-// Values match xray-core's shadowsocks.CipherType.
+// Values match xray-core's shadowsocks.CipherType. Shadowsocks-2022 ciphers
+// are served by a separate xray-core proxy and use local values.
 type CipherType int32
 
 const (
@@ -19,8 +26,38 @@ const (
 	CipherTypeCHACHA20POLY1305  CipherType = 7
 	CipherTypeXCHACHA20POLY1305 CipherType = 8
 	CipherTypeNone              CipherType = 9
+
+	CipherType2022BLAKE3AES128GCM        CipherType = 100
+	CipherType2022BLAKE3AES256GCM        CipherType = 101
+	CipherType2022BLAKE3CHACHA20POLY1305 CipherType = 102
 )
 
+// IsShadowsocks2022 reports whether the cipher is a Shadowsocks-2022 method.
+func (c CipherType) IsShadowsocks2022() bool {
+	return c >= CipherType2022BLAKE3AES128GCM && c <= CipherType2022BLAKE3CHACHA20POLY1305
+}
+
+// keySize returns the PSK length in bytes required by a Shadowsocks-2022 cipher.
+func (c CipherType) keySize() int {
+	if c == CipherType2022BLAKE3AES128GCM {
+		return 16
+	}
+	return 32
+}
+
+// ValidateShadowsocks2022Key checks that key is a usable per-user PSK
+// (identity key) for a Shadowsocks-2022 multi-user inbound: standard base64 of
+// exactly the key size of the cipher.
+func ValidateShadowsocks2022Key(key string, cipherType CipherType) error {
+	size := cipherType.keySize()
+
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != size {
+		return fmt.Errorf("shadowsocks-2022 password must be a base64-encoded %d-byte key", size)
+	}
+	return nil
+}
+
 // BuildVlessUser creates a protocol.User for VLESS protocol.
 // Parameters:
 //   - email: User identifier (used as email field in xray-core)
@@ -40,6 +77,23 @@ func BuildVlessUser(email, uuid, flow string, level uint32) *protocol.User {
 	}
 }
 
+// BuildVmessUser creates a protocol.User for VMess protocol.
+// Parameters:
+//   - email: User identifier (used as email field in xray-core)
+//   - uuid: VMess client ID (UUID format)
+//   - level: User permission level (typically 0)
+func BuildVmessUser(email, uuid string, level uint32) *protocol.User {
+	vmessAccount := &vmess.Account{
+		Id: uuid,
+	}
+
+	return &protocol.User{
+		Level:   level,
+		Email:   email,
+		Account: serial.ToTypedMessage(vmessAccount),
+	}
+}
+
 // BuildTrojanUser creates a protocol.User for Trojan protocol.
 // Parameters:
 //   - email: User identifier (used as email field in xray-core)
@@ -58,6 +112,9 @@ func BuildTrojanUser(email, password string, level uint32) *protocol.User {
 }
 
 // BuildShadowsocksUser creates a protocol.User for Shadowsocks protocol.
+// Shadowsocks-2022 ciphers produce a multi-user (EIH) account keyed by the
+// password, which must pass ValidateShadowsocks2022Key; ivCheck does not apply
+// to them.
 // Parameters:
 //   - email: User identifier (used as email field in xray-core)
 //   - password: Shadowsocks password
@@ -65,6 +122,18 @@ func BuildTrojanUser(email, password string, level uint32) *protocol.User {
 //   - ivCheck: Whether to enable IV check (for replay attack protection)
 //   - level: User permission level (typically 0)
 func BuildShadowsocksUser(email, password string, cipherType CipherType, ivCheck bool, level uint32) *protocol.User {
+	if cipherType.IsShadowsocks2022() {
+		ss2022Account := &shadowsocks_2022.Account{
+			Key: password,
+		}
+
+		return &protocol.User{
+			Level:   level,
+			Email:   email,
+			Account: serial.ToTypedMessage(ss2022Account),
+		}
+	}
+
 	ssAccount := &shadowsocks.Account{
 		Password:   password,
 		CipherType: shadowsocks.CipherType(cipherType),
@@ -84,13 +153,14 @@ type UserData struct {
 	UserID         string // Username/email for identification
 	HashUUID       string // UUID used for hash tracking
 	VlessUUID      string // UUID for VLESS protocol
+	VmessUUID      string // UUID for VMess protocol (falls back to VlessUUID)
 	TrojanPassword string // Password for Trojan
 	SSPassword     string // Password for Shadowsocks
 }
 
 // InboundUserData represents protocol-specific data for a single inbound.
 type InboundUserData struct {
-	Type string // "vless", "trojan", "shadowsocks"
+	Type string // "vless", "vmess", "trojan", "shadowsocks"
 	Tag  string // Inbound tag
 
 	// VLESS-specific
@@ -101,13 +171,28 @@ type InboundUserData struct {
 	IVCheck    bool
 }
 
+// ManagedProtocols are the inbound protocols BuildUserForInbound builds
+// users for, named as in add-user requests.
+var ManagedProtocols = []string{"vless", "vmess", "trojan", "shadowsocks"}
+
 // BuildUserForInbound creates a protocol.User based on inbound type and user data.
+// Returns nil for unsupported types. Hysteria2 and TUIC are among them: the
+// embedded xray-core only ships a hysteria outbound and no TUIC at all, so
+// there is no inbound user manager to add such users to. SOCKS and HTTP
+// inbounds exist but keep their accounts in config only, so they are
+// unsupported as well.
 func BuildUserForInbound(inbound InboundUserData, user UserData) *protocol.User {
 	const level uint32 = 0
 
 	switch inbound.Type {
 	case "vless":
 		return BuildVlessUser(user.UserID, user.VlessUUID, inbound.Flow, level)
+	case "vmess":
+		uuid := user.VmessUUID
+		if uuid == "" {
+			uuid = user.VlessUUID
+		}
+		return BuildVmessUser(user.UserID, uuid, level)
 	case "trojan":
 		return BuildTrojanUser(user.UserID, user.TrojanPassword, level)
 	case "shadowsocks":
@@ -117,6 +202,18 @@ func BuildUserForInbound(inbound InboundUserData, user UserData) *protocol.User
 	}
 }
 
+// ValidateUserForInbound reports why BuildUserForInbound cannot build a usable
+// user for the inbound, or nil when it can.
+func ValidateUserForInbound(inbound InboundUserData, user UserData) error {
+	if !slices.Contains(ManagedProtocols, inbound.Type) {
+		return fmt.Errorf("unsupported inbound type: %s", inbound.Type)
+	}
+	if inbound.Type == "shadowsocks" && inbound.CipherType.IsShadowsocks2022() {
+		return ValidateShadowsocks2022Key(user.SSPassword, inbound.CipherType)
+	}
+	return nil
+}
+
 // ParseCipherType converts a cipher type string to CipherType.
 func ParseCipherType(s string) CipherType {
 	switch s {
@@ -130,6 +227,12 @@ func ParseCipherType(s string) CipherType {
 		return CipherTypeXCHACHA20POLY1305
 	case "none", "NONE":
 		return CipherTypeNone
+	case "2022-blake3-aes-128-gcm":
+		return CipherType2022BLAKE3AES128GCM
+	case "2022-blake3-aes-256-gcm":
+		return CipherType2022BLAKE3AES256GCM
+	case "2022-blake3-chacha20-poly1305":
+		return CipherType2022BLAKE3CHACHA20POLY1305
 	default:
 		return CipherTypeUnknown
 	}
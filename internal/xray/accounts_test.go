@@ -1,9 +1,12 @@
 package xray
 
 import (
+	"encoding/base64"
 	"testing"
 
 	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/proxy/shadowsocks_2022"
+	"github.com/xtls/xray-core/proxy/vmess"
 )
 
 func TestBuildVlessUser(t *testing.T) {
@@ -39,6 +42,22 @@ func TestBuildVlessUser_EmptyFlow(t *testing.T) {
 	}
 }
 
+func TestBuildVmessUser(t *testing.T) {
+	user := BuildVmessUser("test@example.com", "550e8400-e29b-41d4-a716-446655440000", 0)
+
+	if user == nil {
+		t.Fatal("BuildVmessUser returned nil")
+	}
+
+	if user.Email != "test@example.com" {
+		t.Errorf("Email = %q, want %q", user.Email, "test@example.com")
+	}
+
+	if user.Account == nil {
+		t.Error("Account is nil")
+	}
+}
+
 func TestBuildTrojanUser(t *testing.T) {
 	user := BuildTrojanUser("test@example.com", "secret-password", 0)
 
@@ -113,6 +132,42 @@ func TestBuildUserForInbound_Vless(t *testing.T) {
 	}
 }
 
+func TestBuildUserForInbound_Vmess(t *testing.T) {
+	inbound := InboundUserData{
+		Type: "vmess",
+		Tag:  "vmess-in",
+	}
+	userData := UserData{
+		UserID:    "user1",
+		VlessUUID: "550e8400-e29b-41d4-a716-446655440000",
+	}
+
+	user := BuildUserForInbound(inbound, userData)
+
+	if user == nil {
+		t.Fatal("BuildUserForInbound returned nil")
+	}
+
+	account, err := user.Account.GetInstance()
+	if err != nil {
+		t.Fatalf("GetInstance() failed: %v", err)
+	}
+	vmessAccount, ok := account.(*vmess.Account)
+	if !ok {
+		t.Fatalf("Account type = %T, want *vmess.Account", account)
+	}
+	if vmessAccount.Id != userData.VlessUUID {
+		t.Errorf("Id = %q, want fallback to VlessUUID %q", vmessAccount.Id, userData.VlessUUID)
+	}
+
+	userData.VmessUUID = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	user = BuildUserForInbound(inbound, userData)
+	account, _ = user.Account.GetInstance()
+	if got := account.(*vmess.Account).Id; got != userData.VmessUUID {
+		t.Errorf("Id = %q, want %q", got, userData.VmessUUID)
+	}
+}
+
 func TestBuildUserForInbound_Trojan(t *testing.T) {
 	inbound := InboundUserData{
 		Type: "trojan",
@@ -155,6 +210,94 @@ func TestBuildUserForInbound_Shadowsocks(t *testing.T) {
 	}
 }
 
+func TestBuildShadowsocksUser_2022(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	user := BuildShadowsocksUser("test@example.com", key, CipherType2022BLAKE3AES128GCM, true, 0)
+
+	if user == nil {
+		t.Fatal("BuildShadowsocksUser returned nil")
+	}
+
+	account, err := user.Account.GetInstance()
+	if err != nil {
+		t.Fatalf("GetInstance() failed: %v", err)
+	}
+	ss2022Account, ok := account.(*shadowsocks_2022.Account)
+	if !ok {
+		t.Fatalf("Account type = %T, want *shadowsocks_2022.Account", account)
+	}
+	if ss2022Account.Key != key {
+		t.Errorf("Key = %q, want %q", ss2022Account.Key, key)
+	}
+}
+
+func TestValidateShadowsocks2022Key(t *testing.T) {
+	key128 := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	key256 := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	testCases := []struct {
+		name       string
+		key        string
+		cipherType CipherType
+		valid      bool
+	}{
+		{"valid 128-bit key", key128, CipherType2022BLAKE3AES128GCM, true},
+		{"valid 256-bit key", key256, CipherType2022BLAKE3AES256GCM, true},
+		{"valid chacha20 key", key256, CipherType2022BLAKE3CHACHA20POLY1305, true},
+		{"256-bit key for 128-bit cipher", key256, CipherType2022BLAKE3AES128GCM, false},
+		{"128-bit key for 256-bit cipher", key128, CipherType2022BLAKE3AES256GCM, false},
+		{"plain password", "ss-password", CipherType2022BLAKE3CHACHA20POLY1305, false},
+		{"empty password", "", CipherType2022BLAKE3AES128GCM, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateShadowsocks2022Key(tc.key, tc.cipherType)
+			if tc.valid && err != nil {
+				t.Errorf("ValidateShadowsocks2022Key() = %v, want nil", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("ValidateShadowsocks2022Key() = nil, want error")
+			}
+		})
+	}
+}
+
+func TestValidateUserForInbound(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	ss2022 := InboundUserData{Type: "shadowsocks", Tag: "ss-in", CipherType: CipherType2022BLAKE3AES256GCM}
+
+	if err := ValidateUserForInbound(ss2022, UserData{UserID: "user1", SSPassword: key}); err != nil {
+		t.Errorf("valid key: %v", err)
+	}
+	if err := ValidateUserForInbound(ss2022, UserData{UserID: "user1", SSPassword: "ss-password"}); err == nil {
+		t.Error("plain password for shadowsocks-2022 should be rejected")
+	}
+
+	classic := InboundUserData{Type: "shadowsocks", Tag: "ss-in", CipherType: CipherTypeAES256GCM}
+	if err := ValidateUserForInbound(classic, UserData{UserID: "user1", SSPassword: "ss-password"}); err != nil {
+		t.Errorf("classic shadowsocks password: %v", err)
+	}
+
+	if err := ValidateUserForInbound(InboundUserData{Type: "socks", Tag: "socks-in"}, UserData{UserID: "user1"}); err == nil {
+		t.Error("socks inbound should be rejected")
+	}
+}
+
+func TestBuildUserForInbound_ConfigOnlyProtocols(t *testing.T) {
+	userData := UserData{
+		UserID:    "user1",
+		VlessUUID: "550e8400-e29b-41d4-a716-446655440000",
+	}
+
+	for _, inboundType := range []string{"socks", "http"} {
+		user := BuildUserForInbound(InboundUserData{Type: inboundType, Tag: inboundType + "-in"}, userData)
+		if user != nil {
+			t.Errorf("BuildUserForInbound(%q) should return nil, the inbound has no user manager", inboundType)
+		}
+	}
+}
+
 func TestBuildUserForInbound_Unknown(t *testing.T) {
 	inbound := InboundUserData{
 		Type: "unknown",
@@ -169,6 +312,20 @@ func TestBuildUserForInbound_Unknown(t *testing.T) {
 	}
 }
 
+func TestBuildUserForInbound_UnsupportedUDPProtocols(t *testing.T) {
+	userData := UserData{
+		UserID:    "user1",
+		VlessUUID: "550e8400-e29b-41d4-a716-446655440000",
+	}
+
+	for _, inboundType := range []string{"hysteria2", "hysteria", "tuic"} {
+		user := BuildUserForInbound(InboundUserData{Type: inboundType, Tag: inboundType + "-in"}, userData)
+		if user != nil {
+			t.Errorf("BuildUserForInbound(%q) should return nil, no inbound exists in xray-core", inboundType)
+		}
+	}
+}
+
 func TestParseCipherType(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -186,6 +343,9 @@ func TestParseCipherType(t *testing.T) {
 		{"XCHACHA20_POLY1305", CipherTypeXCHACHA20POLY1305},
 		{"none", CipherTypeNone},
 		{"NONE", CipherTypeNone},
+		{"2022-blake3-aes-128-gcm", CipherType2022BLAKE3AES128GCM},
+		{"2022-blake3-aes-256-gcm", CipherType2022BLAKE3AES256GCM},
+		{"2022-blake3-chacha20-poly1305", CipherType2022BLAKE3CHACHA20POLY1305},
 		{"invalid", CipherTypeUnknown},
 		{"", CipherTypeUnknown},
 	}
@@ -212,6 +372,10 @@ func TestUserToMemoryUser(t *testing.T) {
 			name: "VLESS user",
 			user: BuildVlessUser("vless@test.com", "550e8400-e29b-41d4-a716-446655440000", "", 0),
 		},
+		{
+			name: "VMess user",
+			user: BuildVmessUser("vmess@test.com", "550e8400-e29b-41d4-a716-446655440000", 0),
+		},
 		{
 			name: "Trojan user",
 			user: BuildTrojanUser("trojan@test.com", "password123", 0),
@@ -220,6 +384,10 @@ func TestUserToMemoryUser(t *testing.T) {
 			name: "Shadowsocks user",
 			user: BuildShadowsocksUser("ss@test.com", "password123", CipherTypeCHACHA20POLY1305, false, 0),
 		},
+		{
+			name: "Shadowsocks-2022 user",
+			user: BuildShadowsocksUser("ss2022@test.com", base64.StdEncoding.EncodeToString(make([]byte, 32)), CipherType2022BLAKE3AES256GCM, false, 0),
+		},
 	}
 
 	for _, tc := range testCases {
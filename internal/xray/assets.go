@@ -0,0 +1,113 @@
+package xray
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/xtls/xray-core/common/platform"
+	"github.com/xtls/xray-core/common/platform/filesystem"
+)
+
+// ErrAssetNotFound is returned when the routing or DNS rules of a config
+// reference a geodata file that is not in the asset directory.
+var ErrAssetNotFound = errors.New("geodata asset not found")
+
+// SetAssetPath sets the directory xray loads geoip.dat, geosite.dat and
+// other "ext:" files from, in place of the XRAY_LOCATION_ASSET environment
+// variable. It applies from the next start. Empty keeps the environment.
+func (c *Core) SetAssetPath(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.assetPath = path
+}
+
+// prepareAssets points xray at the asset path and checks the assets of
+// configJSON are there.
+func (c *Core) prepareAssets(configJSON []byte) error {
+	c.mu.RLock()
+	assetPath := c.assetPath
+	c.mu.RUnlock()
+
+	if assetPath != "" {
+		os.Setenv("XRAY_LOCATION_ASSET", assetPath)
+	}
+	return checkAssets(configJSON)
+}
+
+// checkAssets verifies that the geodata files the routing and DNS rules of
+// configJSON reference can be opened, so a missing file fails the start
+// with an error naming it rather than deep inside the xray config loader.
+func checkAssets(configJSON []byte) error {
+	var config map[string]interface{}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		// Reported by the config loader.
+		return nil
+	}
+
+	refs := make(map[string]string)
+	collectAssetRefs(config["routing"], refs)
+	collectAssetRefs(config["dns"], refs)
+
+	files := make([]string, 0, len(refs))
+	for file := range refs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		path := platform.GetAssetLocation(file)
+		reader, err := filesystem.NewFileReader(path)
+		if err != nil {
+			return fmt.Errorf("%w: %s, needed by %q, is not at %s; set the node's assetPath or XRAY_LOCATION_ASSET to the directory holding it",
+				ErrAssetNotFound, file, refs[file], path)
+		}
+		reader.Close()
+	}
+	return nil
+}
+
+// collectAssetRefs records, by file, the first rule found in value that
+// loads a geodata file.
+func collectAssetRefs(value interface{}, refs map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			// DNS hosts may be keyed by geosite lists.
+			addAssetRef(key, refs)
+			collectAssetRefs(item, refs)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectAssetRefs(item, refs)
+		}
+	case string:
+		addAssetRef(v, refs)
+	}
+}
+
+func addAssetRef(rule string, refs map[string]string) {
+	var file string
+	switch {
+	case strings.HasPrefix(rule, "geosite:"):
+		file = "geosite.dat"
+	case strings.HasPrefix(rule, "geoip:"):
+		file = "geoip.dat"
+	default:
+		for _, prefix := range []string{"ext:", "ext-domain:", "ext-ip:"} {
+			if rest, ok := strings.CutPrefix(rule, prefix); ok {
+				file, _, _ = strings.Cut(rest, ":")
+				break
+			}
+		}
+	}
+	if file == "" {
+		return
+	}
+	if _, seen := refs[file]; !seen {
+		refs[file] = rule
+	}
+}
@@ -0,0 +1,94 @@
+package xray
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xtls/xray-core/app/router"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func makeExtAssetConfig(rule string) []byte {
+	cfg := map[string]interface{}{
+		"log":       map[string]interface{}{"loglevel": "none"},
+		"inbounds":  []interface{}{},
+		"outbounds": []interface{}{map[string]interface{}{"tag": "direct", "protocol": "freedom"}},
+		"routing": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"domain": []string{rule}, "outboundTag": "direct"},
+			},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+	return data
+}
+
+func TestCollectAssetRefs(t *testing.T) {
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"routing": {"rules": [
+			{"domain": ["geosite:cn", "example.com"], "outboundTag": "direct"},
+			{"ip": ["geoip:!private", "ext-ip:office.dat:lan"], "outboundTag": "direct"}
+		]},
+		"dns": {
+			"hosts": {"ext:hosts.dat:ads": "127.0.0.1"},
+			"servers": [{"address": "1.1.1.1", "domains": ["ext-domain:custom.dat:office"]}]
+		}
+	}`), &config))
+
+	refs := make(map[string]string)
+	collectAssetRefs(config["routing"], refs)
+	collectAssetRefs(config["dns"], refs)
+
+	assert.Equal(t, map[string]string{
+		"geosite.dat": "geosite:cn",
+		"geoip.dat":   "geoip:!private",
+		"office.dat":  "ext-ip:office.dat:lan",
+		"hosts.dat":   "ext:hosts.dat:ads",
+		"custom.dat":  "ext-domain:custom.dat:office",
+	}, refs)
+}
+
+func TestCore_StartMissingAsset(t *testing.T) {
+	t.Setenv("XRAY_LOCATION_ASSET", t.TempDir())
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	defer c.Stop()
+
+	err := c.Start(makeExtAssetConfig("ext:missing.dat:office"))
+	require.ErrorIs(t, err, ErrAssetNotFound)
+	assert.Contains(t, err.Error(), "missing.dat")
+	assert.Contains(t, err.Error(), "ext:missing.dat:office")
+	assert.True(t, c.IsRunning(), "the running instance is kept")
+}
+
+func TestCore_SetAssetPath(t *testing.T) {
+	t.Setenv("XRAY_LOCATION_ASSET", t.TempDir())
+
+	dir := t.TempDir()
+	data, err := proto.Marshal(&router.GeoSiteList{Entry: []*router.GeoSite{{
+		CountryCode: "OFFICE",
+		Domain:      []*router.Domain{{Type: router.Domain_Domain, Value: "example.com"}},
+	}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "office.dat"), data, 0644))
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	require.ErrorIs(t, c.Start(makeExtAssetConfig("ext:office.dat:office")), ErrAssetNotFound)
+
+	c.SetAssetPath(dir)
+	require.NoError(t, c.Start(makeExtAssetConfig("ext:office.dat:office")))
+	defer c.Stop()
+	assert.True(t, c.IsRunning())
+}
@@ -0,0 +1,170 @@
+package xray
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// ConfigDiff is the structural difference between the applied xray config
+// and another one.
+type ConfigDiff struct {
+	Identical bool `json:"identical"`
+	// RestartRequired is set when more than the users of existing inbounds
+	// changed, so applying the config takes a full core restart rather than
+	// reloading the changed inbounds.
+	RestartRequired bool `json:"restartRequired"`
+	// Sections are the changed top-level sections other than inbounds.
+	Sections        []string      `json:"sections"`
+	InboundsAdded   []string      `json:"inboundsAdded"`
+	InboundsRemoved []string      `json:"inboundsRemoved"`
+	InboundsChanged []InboundDiff `json:"inboundsChanged"`
+}
+
+// InboundDiff is the difference in an inbound present in both configs.
+type InboundDiff struct {
+	Tag string `json:"tag"`
+	// Fields are the changed keys of the inbound definition, not counting
+	// its users.
+	Fields []string `json:"fields"`
+	// UsersAdded and UsersRemoved are client IDs.
+	UsersAdded   []string `json:"usersAdded"`
+	UsersRemoved []string `json:"usersRemoved"`
+}
+
+// DiffConfigs compares the applied config with an incoming one. Both are
+// normalized through JSON first, so that e.g. an int and a float64 port
+// compare equal.
+func DiffConfigs(applied, incoming map[string]interface{}) ConfigDiff {
+	applied = normalizeConfig(applied)
+	incoming = normalizeConfig(incoming)
+
+	diff := ConfigDiff{
+		Sections:        []string{},
+		InboundsAdded:   []string{},
+		InboundsRemoved: []string{},
+		InboundsChanged: []InboundDiff{},
+	}
+
+	for _, section := range unionKeys(applied, incoming) {
+		if section != "inbounds" && !reflect.DeepEqual(applied[section], incoming[section]) {
+			diff.Sections = append(diff.Sections, section)
+		}
+	}
+
+	appliedInbounds := inboundsByTag(applied)
+	incomingInbounds := inboundsByTag(incoming)
+	for _, tag := range unionKeys(appliedInbounds, incomingInbounds) {
+		before, wasApplied := appliedInbounds[tag]
+		after, isIncoming := incomingInbounds[tag]
+		switch {
+		case !wasApplied:
+			diff.InboundsAdded = append(diff.InboundsAdded, tag)
+		case !isIncoming:
+			diff.InboundsRemoved = append(diff.InboundsRemoved, tag)
+		default:
+			if inboundDiff, changed := diffInbound(tag, before, after); changed {
+				diff.InboundsChanged = append(diff.InboundsChanged, inboundDiff)
+			}
+		}
+	}
+
+	diff.RestartRequired = len(diff.Sections) > 0 || len(diff.InboundsAdded) > 0 || len(diff.InboundsRemoved) > 0
+	for _, inboundDiff := range diff.InboundsChanged {
+		if len(inboundDiff.Fields) > 0 {
+			diff.RestartRequired = true
+		}
+	}
+	diff.Identical = !diff.RestartRequired && len(diff.InboundsChanged) == 0
+
+	return diff
+}
+
+func diffInbound(tag string, before, after map[string]interface{}) (InboundDiff, bool) {
+	inboundDiff := InboundDiff{
+		Tag:          tag,
+		Fields:       []string{},
+		UsersAdded:   []string{},
+		UsersRemoved: []string{},
+	}
+
+	beforeUsers := inboundUsers(before)
+	afterUsers := inboundUsers(after)
+	for _, id := range afterUsers.Items() {
+		if !beforeUsers.Has(id) {
+			inboundDiff.UsersAdded = append(inboundDiff.UsersAdded, id)
+		}
+	}
+	for _, id := range beforeUsers.Items() {
+		if !afterUsers.Has(id) {
+			inboundDiff.UsersRemoved = append(inboundDiff.UsersRemoved, id)
+		}
+	}
+	sort.Strings(inboundDiff.UsersAdded)
+	sort.Strings(inboundDiff.UsersRemoved)
+
+	before = withoutClients(before)
+	after = withoutClients(after)
+	for _, field := range unionKeys(before, after) {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			inboundDiff.Fields = append(inboundDiff.Fields, field)
+		}
+	}
+
+	changed := len(inboundDiff.Fields) > 0 || len(inboundDiff.UsersAdded) > 0 || len(inboundDiff.UsersRemoved) > 0
+	return inboundDiff, changed
+}
+
+// withoutClients returns a shallow copy of inbound without its clients.
+func withoutClients(inbound map[string]interface{}) map[string]interface{} {
+	stripped := make(map[string]interface{}, len(inbound))
+	for k, v := range inbound {
+		stripped[k] = v
+	}
+	if settings, ok := inbound["settings"].(map[string]interface{}); ok {
+		strippedSettings := make(map[string]interface{}, len(settings))
+		for k, v := range settings {
+			if k != "clients" {
+				strippedSettings[k] = v
+			}
+		}
+		stripped["settings"] = strippedSettings
+	}
+	return stripped
+}
+
+func inboundsByTag(config map[string]interface{}) map[string]map[string]interface{} {
+	byTag := make(map[string]map[string]interface{})
+	inbounds, _ := config["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		if inbound, ok := inboundRaw.(map[string]interface{}); ok {
+			if tag, _ := inbound["tag"].(string); tag != "" {
+				byTag[tag] = inbound
+			}
+		}
+	}
+	return byTag
+}
+
+func normalizeConfig(config map[string]interface{}) map[string]interface{} {
+	normalized := map[string]interface{}{}
+	if data, err := json.Marshal(config); err == nil {
+		_ = json.Unmarshal(data, &normalized)
+	}
+	return normalized
+}
+
+// unionKeys returns the keys of a and b, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package xray
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diffTestConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "warning"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"port":     443,
+				"protocol": "vless",
+				"settings": map[string]interface{}{
+					"clients":    []interface{}{map[string]interface{}{"id": "user1"}, map[string]interface{}{"id": "user2"}},
+					"decryption": "none",
+				},
+			},
+			map[string]interface{}{"tag": "trojan-in", "port": 8443, "protocol": "trojan"},
+		},
+		"outbounds": []interface{}{map[string]interface{}{"tag": "direct", "protocol": "freedom"}},
+	}
+}
+
+func TestDiffConfigs_Identical(t *testing.T) {
+	applied := diffTestConfig()
+	incoming := diffTestConfig()
+	// Ports decoded from JSON are float64; they compare equal to ints.
+	incoming["inbounds"].([]interface{})[0].(map[string]interface{})["port"] = 443.0
+
+	diff := DiffConfigs(applied, incoming)
+	assert.True(t, diff.Identical)
+	assert.False(t, diff.RestartRequired)
+	assert.Empty(t, diff.Sections)
+	assert.Empty(t, diff.InboundsChanged)
+}
+
+func TestDiffConfigs_UsersOnly(t *testing.T) {
+	incoming := diffTestConfig()
+	incoming["inbounds"].([]interface{})[0].(map[string]interface{})["settings"].(map[string]interface{})["clients"] = []interface{}{
+		map[string]interface{}{"id": "user2"},
+		map[string]interface{}{"id": "user3"},
+	}
+
+	diff := DiffConfigs(diffTestConfig(), incoming)
+	assert.False(t, diff.Identical)
+	assert.False(t, diff.RestartRequired)
+	assert.Equal(t, []InboundDiff{{
+		Tag:          "vless-in",
+		Fields:       []string{},
+		UsersAdded:   []string{"user3"},
+		UsersRemoved: []string{"user1"},
+	}}, diff.InboundsChanged)
+}
+
+func TestDiffConfigs_Structural(t *testing.T) {
+	incoming := diffTestConfig()
+	incoming["log"] = map[string]interface{}{"loglevel": "debug"}
+	incoming["routing"] = map[string]interface{}{"rules": []interface{}{}}
+	vlessIn := incoming["inbounds"].([]interface{})[0].(map[string]interface{})
+	vlessIn["port"] = 8444
+	vlessIn["settings"].(map[string]interface{})["decryption"] = "mlkem768x25519plus"
+	incoming["inbounds"] = []interface{}{vlessIn, map[string]interface{}{"tag": "vmess-in", "port": 9443, "protocol": "vmess"}}
+
+	diff := DiffConfigs(diffTestConfig(), incoming)
+	assert.False(t, diff.Identical)
+	assert.True(t, diff.RestartRequired)
+	assert.Equal(t, []string{"log", "routing"}, diff.Sections)
+	assert.Equal(t, []string{"vmess-in"}, diff.InboundsAdded)
+	assert.Equal(t, []string{"trojan-in"}, diff.InboundsRemoved)
+	if assert.Len(t, diff.InboundsChanged, 1) {
+		assert.Equal(t, []string{"port", "settings"}, diff.InboundsChanged[0].Fields)
+		assert.Empty(t, diff.InboundsChanged[0].UsersAdded)
+	}
+}
+
+func TestDiffConfigs_NothingApplied(t *testing.T) {
+	diff := DiffConfigs(nil, diffTestConfig())
+	assert.True(t, diff.RestartRequired)
+	assert.Equal(t, []string{"trojan-in", "vless-in"}, diff.InboundsAdded)
+	assert.Equal(t, []string{"log", "outbounds"}, diff.Sections)
+}
@@ -1,13 +1,30 @@
 package xray
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/state"
 )
 
+const (
+	configStateKey = "config-manager"
+
+	// patchedConfigHashPrefix marks an empty config hash computed by the
+	// node for a patched config rather than sent by the panel.
+	patchedConfigHashPrefix = "patched-"
+)
+
+// ErrInboundNotFound is returned for an inbound that is not part of the
+// applied config or the running core.
+var ErrInboundNotFound = errors.New("inbound not found")
+
 // InboundHash represents the hash information for a single inbound.
 type InboundHash struct {
 	Tag        string `json:"tag"`
@@ -54,9 +71,24 @@ type ConfigManager struct {
 	emptyConfigHash    string
 	inboundsHashMap    map[string]*HashedSet
 	xtlsConfigInbounds map[string]struct{}
+	configChanged      chan struct{}
+	store              state.Store
 	log                *logger.Logger
 }
 
+// persistedConfigState is the on-disk form of the ConfigManager state as of
+// the last applied config. Users added or removed at runtime are not part of
+// xrayConfig, so the hashes are computed from xrayConfig rather than taken
+// from the live hash sets: a node resumed from it then reports hashes that
+// no longer match the panel's, and the panel restarts the core with its
+// users.
+type persistedConfigState struct {
+	EmptyConfigHash    string                 `json:"emptyConfigHash"`
+	Inbounds           map[string][]string    `json:"inbounds"`
+	XtlsConfigInbounds []string               `json:"xtlsConfigInbounds"`
+	XrayConfig         map[string]interface{} `json:"xrayConfig"`
+}
+
 // NewConfigManager creates a new ConfigManager instance.
 func NewConfigManager(log *logger.Logger) *ConfigManager {
 	return &ConfigManager{
@@ -64,10 +96,93 @@ func NewConfigManager(log *logger.Logger) *ConfigManager {
 		emptyConfigHash:    "",
 		inboundsHashMap:    make(map[string]*HashedSet),
 		xtlsConfigInbounds: make(map[string]struct{}),
+		configChanged:      make(chan struct{}),
 		log:                log,
 	}
 }
 
+// AttachStore restores the state persisted by a previous run from store and
+// persists the state on every applied config from then on.
+func (m *ConfigManager) AttachStore(store state.Store) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.store = store
+
+	var persisted persistedConfigState
+	found, err := store.Load(configStateKey, &persisted)
+	if err != nil {
+		return fmt.Errorf("failed to restore config state: %w", err)
+	}
+	if !found || persisted.EmptyConfigHash == "" {
+		return nil
+	}
+
+	m.cleanup()
+	m.emptyConfigHash = persisted.EmptyConfigHash
+	m.xrayConfig = persisted.XrayConfig
+	m.notifyConfigChanged()
+	for tag, users := range persisted.Inbounds {
+		usersSet := NewHashedSet()
+		for _, user := range users {
+			usersSet.Add(user)
+		}
+		m.inboundsHashMap[tag] = usersSet
+	}
+	for _, tag := range persisted.XtlsConfigInbounds {
+		m.xtlsConfigInbounds[tag] = struct{}{}
+	}
+
+	if m.log != nil {
+		m.log.WithField("inbounds", len(m.inboundsHashMap)).Info("Restored config state from previous run")
+	}
+
+	return nil
+}
+
+// save persists the current state (no lock, internal use).
+func (m *ConfigManager) save() {
+	if m.store == nil {
+		return
+	}
+
+	if m.emptyConfigHash == "" {
+		if err := m.store.Delete(configStateKey); err != nil && m.log != nil {
+			m.log.WithError(err).Error("Failed to clear persisted config state")
+		}
+		return
+	}
+
+	persisted := persistedConfigState{
+		EmptyConfigHash:    m.emptyConfigHash,
+		Inbounds:           make(map[string][]string, len(m.inboundsHashMap)),
+		XtlsConfigInbounds: make([]string, 0, len(m.xtlsConfigInbounds)),
+		XrayConfig:         m.xrayConfig,
+	}
+	inbounds, _ := m.xrayConfig["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		inbound, ok := inboundRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tag, _ := inbound["tag"].(string)
+		_, hashed := m.inboundsHashMap[tag]
+		_, tracked := m.xtlsConfigInbounds[tag]
+		if !hashed && !tracked {
+			continue
+		}
+		users := inboundUsers(inbound).Items()
+		sort.Strings(users)
+		persisted.Inbounds[tag] = users
+		persisted.XtlsConfigInbounds = append(persisted.XtlsConfigInbounds, tag)
+	}
+	sort.Strings(persisted.XtlsConfigInbounds)
+
+	if err := m.store.Save(configStateKey, persisted); err != nil && m.log != nil {
+		m.log.WithError(err).Error("Failed to persist config state")
+	}
+}
+
 // GetXrayConfig returns the current xray configuration.
 func (m *ConfigManager) GetXrayConfig() map[string]interface{} {
 	m.mu.RLock()
@@ -79,11 +194,31 @@ func (m *ConfigManager) GetXrayConfig() map[string]interface{} {
 	return m.xrayConfig
 }
 
+// WatchXrayConfig returns the current xray configuration together with a
+// channel that is closed on its next change.
+func (m *ConfigManager) WatchXrayConfig() (map[string]interface{}, <-chan struct{}) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.xrayConfig == nil {
+		return map[string]interface{}{}, m.configChanged
+	}
+	return m.xrayConfig, m.configChanged
+}
+
 // SetXrayConfig sets the xray configuration.
 func (m *ConfigManager) SetXrayConfig(config map[string]interface{}) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.xrayConfig = config
+	m.notifyConfigChanged()
+}
+
+// notifyConfigChanged wakes the watchers of the xray config (no lock,
+// internal use).
+func (m *ConfigManager) notifyConfigChanged() {
+	close(m.configChanged)
+	m.configChanged = make(chan struct{})
 }
 
 // IsNeedRestartCore determines if xray-core needs to be restarted based on hash comparison.
@@ -159,6 +294,37 @@ func (m *ConfigManager) IsNeedRestartCore(incomingHashes Hashes) bool {
 	return false
 }
 
+// InboundsToReload returns the tags of inbounds whose users changed, for a
+// restart that can be served by reloading just those inbounds.
+// Returns false when anything beyond per-inbound users changed (first start,
+// base config, inbound set) and a full core restart is required.
+func (m *ConfigManager) InboundsToReload(incomingHashes Hashes) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.emptyConfigHash == "" || incomingHashes.EmptyConfig != m.emptyConfigHash {
+		return nil, false
+	}
+
+	if len(incomingHashes.Inbounds) != len(m.inboundsHashMap) {
+		return nil, false
+	}
+
+	var tags []string
+	for _, incoming := range incomingHashes.Inbounds {
+		usersSet, exists := m.inboundsHashMap[incoming.Tag]
+		if !exists {
+			return nil, false
+		}
+		if usersSet.Hash64String() != incoming.Hash {
+			tags = append(tags, incoming.Tag)
+		}
+	}
+	sort.Strings(tags)
+
+	return tags, true
+}
+
 // ExtractUsersFromConfig extracts users from the xray config and updates hash maps.
 // This should be called after a successful xray-core start.
 func (m *ConfigManager) ExtractUsersFromConfig(hashes Hashes, newConfig map[string]interface{}) error {
@@ -170,6 +336,7 @@ func (m *ConfigManager) ExtractUsersFromConfig(hashes Hashes, newConfig map[stri
 
 	m.emptyConfigHash = hashes.EmptyConfig
 	m.xrayConfig = newConfig
+	m.notifyConfigChanged()
 
 	if m.log != nil {
 		hashJSON, _ := json.Marshal(hashes)
@@ -210,21 +377,7 @@ func (m *ConfigManager) ExtractUsersFromConfig(hashes Hashes, newConfig map[stri
 			continue
 		}
 
-		usersSet := NewHashedSet()
-
-		// Extract clients
-		if settings, ok := inbound["settings"].(map[string]interface{}); ok {
-			if clients, ok := settings["clients"].([]interface{}); ok {
-				for _, clientRaw := range clients {
-					if client, ok := clientRaw.(map[string]interface{}); ok {
-						if id, ok := client["id"].(string); ok && id != "" {
-							usersSet.Add(id)
-						}
-					}
-				}
-			}
-		}
-
+		usersSet := inboundUsers(inbound)
 		m.inboundsHashMap[tag] = usersSet
 		m.xtlsConfigInbounds[tag] = struct{}{}
 
@@ -233,6 +386,205 @@ func (m *ConfigManager) ExtractUsersFromConfig(hashes Hashes, newConfig map[stri
 		}
 	}
 
+	m.save()
+
+	return nil
+}
+
+// inboundUsers returns the set of client IDs of an inbound config.
+func inboundUsers(inbound map[string]interface{}) *HashedSet {
+	usersSet := NewHashedSet()
+	settings, _ := inbound["settings"].(map[string]interface{})
+	clients, _ := settings["clients"].([]interface{})
+	for _, clientRaw := range clients {
+		if client, ok := clientRaw.(map[string]interface{}); ok {
+			if id, ok := client["id"].(string); ok && id != "" {
+				usersSet.Add(id)
+			}
+		}
+	}
+	return usersSet
+}
+
+// HasInbound reports whether the applied config has an inbound tagged
+// inboundTag.
+func (m *ConfigManager) HasInbound(inboundTag string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.inboundIndex(inboundTag) >= 0
+}
+
+// inboundIndex returns the position of an inbound in the applied config, or
+// -1 (no lock, internal use).
+func (m *ConfigManager) inboundIndex(inboundTag string) int {
+	inbounds, _ := m.xrayConfig["inbounds"].([]interface{})
+	for i, inboundRaw := range inbounds {
+		if inbound, ok := inboundRaw.(map[string]interface{}); ok && inbound["tag"] == inboundTag {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpdateInbound changes the inbound tagged inboundTag in the applied config:
+// update edits a copy of its definition, then apply puts the resulting
+// config into effect. The change is kept only if both succeed. Users are
+// not affected.
+func (m *ConfigManager) UpdateInbound(inboundTag string, update func(inbound map[string]interface{}) error, apply func(config map[string]interface{}) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.inboundIndex(inboundTag)
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrInboundNotFound, inboundTag)
+	}
+
+	// Copy the definition through JSON so that update cannot touch the
+	// config shared with watchers.
+	inbounds, _ := m.xrayConfig["inbounds"].([]interface{})
+	data, err := json.Marshal(inbounds[index])
+	if err != nil {
+		return err
+	}
+	var inbound map[string]interface{}
+	if err := json.Unmarshal(data, &inbound); err != nil {
+		return err
+	}
+	if err := update(inbound); err != nil {
+		return err
+	}
+
+	config := make(map[string]interface{}, len(m.xrayConfig))
+	for k, v := range m.xrayConfig {
+		config[k] = v
+	}
+	updated := append([]interface{}{}, inbounds...)
+	updated[index] = inbound
+	config["inbounds"] = updated
+
+	if err := apply(config); err != nil {
+		return err
+	}
+
+	m.xrayConfig = config
+	m.notifyConfigChanged()
+	m.save()
+
+	return nil
+}
+
+// AddInbound records an inbound added to the running core: it becomes part
+// of the applied config and its users are tracked like those of the panel's
+// inbounds. The hashes no longer match the panel's, so its next start
+// command restarts the core with the panel config, dropping the inbound.
+func (m *ConfigManager) AddInbound(inbound map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tag, _ := inbound["tag"].(string)
+
+	// The config is shared with its watchers, so replace it rather than
+	// appending in place.
+	config := make(map[string]interface{}, len(m.xrayConfig)+1)
+	for k, v := range m.xrayConfig {
+		config[k] = v
+	}
+	inbounds, _ := config["inbounds"].([]interface{})
+	config["inbounds"] = append(append([]interface{}{}, inbounds...), inbound)
+	m.xrayConfig = config
+	m.notifyConfigChanged()
+
+	m.inboundsHashMap[tag] = inboundUsers(inbound)
+	m.xtlsConfigInbounds[tag] = struct{}{}
+
+	m.save()
+}
+
+// RemoveInbound removes an inbound from the applied config and stops
+// tracking its users. Returns false if the config has no such inbound.
+func (m *ConfigManager) RemoveInbound(inboundTag string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := m.inboundIndex(inboundTag)
+	if index < 0 {
+		return false
+	}
+
+	config := make(map[string]interface{}, len(m.xrayConfig))
+	for k, v := range m.xrayConfig {
+		config[k] = v
+	}
+	inbounds, _ := config["inbounds"].([]interface{})
+	remaining := make([]interface{}, 0, len(inbounds)-1)
+	remaining = append(remaining, inbounds[:index]...)
+	config["inbounds"] = append(remaining, inbounds[index+1:]...)
+	m.xrayConfig = config
+	m.notifyConfigChanged()
+
+	delete(m.inboundsHashMap, inboundTag)
+	delete(m.xtlsConfigInbounds, inboundTag)
+
+	m.save()
+
+	return true
+}
+
+// ApplyPatchedConfig records config, the applied config with a patch put
+// into effect, as the applied config. The users of the tracked inbounds
+// still in config and of the inbounds in addedTags are re-extracted, so
+// their hashes follow the patch. Unless keepEmptyConfigHash is set, i.e.
+// only users changed, the empty config hash is replaced by one derived from
+// config that no panel hash matches: the panel's next start command then
+// restarts the core with its own config.
+func (m *ConfigManager) ApplyPatchedConfig(config map[string]interface{}, addedTags []string, keepEmptyConfigHash bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tracked := make(map[string]struct{}, len(m.inboundsHashMap)+len(addedTags))
+	for tag := range m.inboundsHashMap {
+		tracked[tag] = struct{}{}
+	}
+	for tag := range m.xtlsConfigInbounds {
+		tracked[tag] = struct{}{}
+	}
+	for _, tag := range addedTags {
+		tracked[tag] = struct{}{}
+	}
+
+	emptyConfigHash := m.emptyConfigHash
+	if !keepEmptyConfigHash {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		emptyConfigHash = patchedConfigHashPrefix + hex.EncodeToString(sum[:8])
+	}
+
+	m.xrayConfig = config
+	m.emptyConfigHash = emptyConfigHash
+	m.inboundsHashMap = make(map[string]*HashedSet)
+	m.xtlsConfigInbounds = make(map[string]struct{})
+	m.notifyConfigChanged()
+
+	inbounds, _ := config["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		inbound, ok := inboundRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tag, _ := inbound["tag"].(string)
+		if _, ok := tracked[tag]; !ok {
+			continue
+		}
+		m.inboundsHashMap[tag] = inboundUsers(inbound)
+		m.xtlsConfigInbounds[tag] = struct{}{}
+	}
+
+	m.save()
+
 	return nil
 }
 
@@ -280,6 +632,69 @@ func (m *ConfigManager) RemoveUserFromInbound(inboundTag, userID string) {
 	}
 }
 
+// HasUserInInbound reports whether the inbound's hash set contains userID.
+func (m *ConfigManager) HasUserInInbound(inboundTag, userID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usersSet, exists := m.inboundsHashMap[inboundTag]
+	return exists && usersSet.Has(userID)
+}
+
+// SetInboundUsers replaces the inbound's hash set with userIDs. An empty
+// list clears an inbound that had users, like removing its last user does.
+func (m *ConfigManager) SetInboundUsers(inboundTag string, userIDs []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(userIDs) == 0 {
+		if usersSet, exists := m.inboundsHashMap[inboundTag]; exists && usersSet.Size() > 0 {
+			delete(m.xtlsConfigInbounds, inboundTag)
+			delete(m.inboundsHashMap, inboundTag)
+		}
+		return
+	}
+
+	usersSet := NewHashedSet()
+	for _, userID := range userIDs {
+		usersSet.Add(userID)
+	}
+	m.inboundsHashMap[inboundTag] = usersSet
+}
+
+// GetInboundUsers returns the user IDs in the inbound's hash set and whether
+// the inbound is tracked at all.
+func (m *ConfigManager) GetInboundUsers(inboundTag string) ([]string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usersSet, exists := m.inboundsHashMap[inboundTag]
+	if !exists {
+		return nil, false
+	}
+	return usersSet.Items(), true
+}
+
+// InboundProtocol returns the protocol of an inbound in the applied config,
+// or an empty string if the inbound is not part of it.
+func (m *ConfigManager) InboundProtocol(inboundTag string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	inbounds, _ := m.xrayConfig["inbounds"].([]interface{})
+	for _, inboundRaw := range inbounds {
+		inbound, ok := inboundRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if tag, _ := inbound["tag"].(string); tag == inboundTag {
+			protocol, _ := inbound["protocol"].(string)
+			return protocol
+		}
+	}
+	return ""
+}
+
 // GetXtlsConfigInbounds returns the set of inbound tags.
 func (m *ConfigManager) GetXtlsConfigInbounds() []string {
 	m.mu.RLock()
@@ -292,6 +707,30 @@ func (m *ConfigManager) GetXtlsConfigInbounds() []string {
 	return tags
 }
 
+// CurrentHashes returns the hashes of the current state in the format the
+// panel sends them with the start command.
+func (m *ConfigManager) CurrentHashes() Hashes {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hashes := Hashes{
+		EmptyConfig: m.emptyConfigHash,
+		Inbounds:    make([]InboundHash, 0, len(m.inboundsHashMap)),
+	}
+	for tag, usersSet := range m.inboundsHashMap {
+		hashes.Inbounds = append(hashes.Inbounds, InboundHash{
+			Tag:        tag,
+			Hash:       usersSet.Hash64String(),
+			UsersCount: usersSet.Size(),
+		})
+	}
+	sort.Slice(hashes.Inbounds, func(i, j int) bool {
+		return hashes.Inbounds[i].Tag < hashes.Inbounds[j].Tag
+	})
+
+	return hashes
+}
+
 // GetInboundHash returns the current hash for an inbound, or empty string if not found.
 func (m *ConfigManager) GetInboundHash(inboundTag string) string {
 	m.mu.RLock()
@@ -308,6 +747,7 @@ func (m *ConfigManager) Cleanup() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.cleanup()
+	m.save()
 }
 
 // cleanup clears all internal state (no lock, internal use).
@@ -320,4 +760,5 @@ func (m *ConfigManager) cleanup() {
 	m.xtlsConfigInbounds = make(map[string]struct{})
 	m.xrayConfig = nil
 	m.emptyConfigHash = ""
+	m.notifyConfigChanged()
 }
@@ -1,7 +1,11 @@
 package xray
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/remnawave/node-go/internal/state"
 )
 
 func TestConfigManager_IsNeedRestartCore_FirstStart(t *testing.T) {
@@ -173,6 +177,70 @@ func TestConfigManager_IsNeedRestartCore_NoRestartNeeded(t *testing.T) {
 	}
 }
 
+func TestConfigManager_InboundsToReload(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	initialHashes := Hashes{
+		EmptyConfig: "hash123",
+		Inbounds: []InboundHash{
+			{Tag: "vless-in", Hash: "0000000000000000", UsersCount: 0},
+			{Tag: "trojan-in", Hash: "0000000000000000", UsersCount: 0},
+		},
+	}
+
+	if _, ok := m.InboundsToReload(initialHashes); ok {
+		t.Error("First start should not allow a partial reload")
+	}
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"settings": map[string]interface{}{"clients": []interface{}{}},
+			},
+			map[string]interface{}{
+				"tag":      "trojan-in",
+				"settings": map[string]interface{}{"clients": []interface{}{}},
+			},
+		},
+	}
+	_ = m.ExtractUsersFromConfig(initialHashes, config)
+
+	tags, ok := m.InboundsToReload(initialHashes)
+	if !ok || len(tags) != 0 {
+		t.Errorf("Unchanged config: got tags=%v ok=%v, want none/true", tags, ok)
+	}
+
+	changedHashes := Hashes{
+		EmptyConfig: "hash123",
+		Inbounds: []InboundHash{
+			{Tag: "vless-in", Hash: "0000000000000000", UsersCount: 0},
+			{Tag: "trojan-in", Hash: "differenthash123", UsersCount: 1},
+		},
+	}
+	tags, ok = m.InboundsToReload(changedHashes)
+	if !ok || len(tags) != 1 || tags[0] != "trojan-in" {
+		t.Errorf("User change: got tags=%v ok=%v, want [trojan-in]/true", tags, ok)
+	}
+
+	baseChanged := changedHashes
+	baseChanged.EmptyConfig = "otherhash"
+	if _, ok := m.InboundsToReload(baseChanged); ok {
+		t.Error("Base config change should require full restart")
+	}
+
+	renamed := Hashes{
+		EmptyConfig: "hash123",
+		Inbounds: []InboundHash{
+			{Tag: "vless-in", Hash: "0000000000000000", UsersCount: 0},
+			{Tag: "ss-in", Hash: "0000000000000000", UsersCount: 0},
+		},
+	}
+	if _, ok := m.InboundsToReload(renamed); ok {
+		t.Error("Inbound set change should require full restart")
+	}
+}
+
 func TestConfigManager_ExtractUsersFromConfig(t *testing.T) {
 	m := NewConfigManager(nil)
 
@@ -287,6 +355,35 @@ func TestConfigManager_AddUserToNewInbound(t *testing.T) {
 	}
 }
 
+func TestConfigManager_SetInboundUsers(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	m.AddUserToInbound("vless-in", "old-uuid")
+	m.SetInboundUsers("vless-in", []string{"uuid-1", "uuid-2"})
+
+	if m.HasUserInInbound("vless-in", "old-uuid") {
+		t.Error("Replaced user should be gone")
+	}
+	if !m.HasUserInInbound("vless-in", "uuid-1") || !m.HasUserInInbound("vless-in", "uuid-2") {
+		t.Error("New users should be present")
+	}
+
+	expected := NewHashedSet()
+	expected.Add("uuid-2")
+	expected.Add("uuid-1")
+	if hash := m.GetInboundHash("vless-in"); hash != expected.Hash64String() {
+		t.Errorf("Expected hash %s, got %s", expected.Hash64String(), hash)
+	}
+
+	m.SetInboundUsers("vless-in", nil)
+	if hash := m.GetInboundHash("vless-in"); hash != "" {
+		t.Error("Inbound without users should be removed from map")
+	}
+	if m.HasUserInInbound("missing-inbound", "uuid-1") {
+		t.Error("Missing inbound has no users")
+	}
+}
+
 func TestConfigManager_Cleanup(t *testing.T) {
 	m := NewConfigManager(nil)
 
@@ -346,3 +443,265 @@ func TestConfigManager_GetXrayConfig(t *testing.T) {
 		t.Error("Config should be retrievable")
 	}
 }
+
+func TestConfigManager_PersistedState(t *testing.T) {
+	store, err := state.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() failed: %v", err)
+	}
+
+	m := NewConfigManager(nil)
+	if err := m.AttachStore(store); err != nil {
+		t.Fatalf("AttachStore() on empty store failed: %v", err)
+	}
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag": "vless-in",
+				"settings": map[string]interface{}{"clients": []interface{}{
+					map[string]interface{}{"id": "user-1"},
+					map[string]interface{}{"id": "user-2"},
+				}},
+			},
+		},
+	}
+	_ = m.ExtractUsersFromConfig(Hashes{
+		EmptyConfig: "hash123",
+		Inbounds:    []InboundHash{{Tag: "vless-in"}},
+	}, config)
+	hashes := m.CurrentHashes()
+
+	// Runtime changes are not part of the persisted state, even when it is
+	// saved again after them.
+	m.AddUserToInbound("vless-in", "user-3")
+	runtimeHashes := m.CurrentHashes()
+	err = m.UpdateInbound("vless-in", func(map[string]interface{}) error { return nil }, func(map[string]interface{}) error { return nil })
+	if err != nil {
+		t.Fatalf("UpdateInbound() failed: %v", err)
+	}
+
+	restored := NewConfigManager(nil)
+	if err := restored.AttachStore(store); err != nil {
+		t.Fatalf("AttachStore() failed: %v", err)
+	}
+
+	if restored.IsNeedRestartCore(hashes) {
+		t.Error("Restored state should match the hashes of the applied config")
+	}
+	if !restored.IsNeedRestartCore(runtimeHashes) {
+		t.Error("Restored state should not include users added at runtime")
+	}
+	if len(restored.GetXrayConfig()) == 0 {
+		t.Error("Restored state should include the xray config")
+	}
+	if tags := restored.GetXtlsConfigInbounds(); len(tags) != 1 || tags[0] != "vless-in" {
+		t.Errorf("GetXtlsConfigInbounds() = %v, want [vless-in]", tags)
+	}
+
+	restored.Cleanup()
+
+	fresh := NewConfigManager(nil)
+	if err := fresh.AttachStore(store); err != nil {
+		t.Fatalf("AttachStore() failed: %v", err)
+	}
+	if !fresh.IsNeedRestartCore(hashes) {
+		t.Error("Cleanup should clear the persisted state")
+	}
+}
+
+func TestConfigManager_CurrentHashes(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	m.AddUserToInbound("b-in", "user-1")
+	m.AddUserToInbound("a-in", "user-2")
+
+	hashes := m.CurrentHashes()
+	if len(hashes.Inbounds) != 2 {
+		t.Fatalf("len(Inbounds) = %d, want 2", len(hashes.Inbounds))
+	}
+	if hashes.Inbounds[0].Tag != "a-in" || hashes.Inbounds[1].Tag != "b-in" {
+		t.Errorf("Inbounds not sorted by tag: %v", hashes.Inbounds)
+	}
+	if hashes.Inbounds[0].Hash != m.GetInboundHash("a-in") || hashes.Inbounds[0].UsersCount != 1 {
+		t.Errorf("Inbounds[0] = %+v, want current hash and count", hashes.Inbounds[0])
+	}
+}
+
+func TestConfigManager_WatchXrayConfig(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	config, changed := m.WatchXrayConfig()
+	if len(config) != 0 {
+		t.Errorf("initial config = %v, want empty", config)
+	}
+
+	select {
+	case <-changed:
+		t.Fatal("watch fired before any change")
+	default:
+	}
+
+	m.SetXrayConfig(map[string]interface{}{"log": map[string]interface{}{}})
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("watch did not fire on SetXrayConfig")
+	}
+
+	_, changed = m.WatchXrayConfig()
+	m.Cleanup()
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("watch did not fire on Cleanup")
+	}
+}
+
+func TestConfigManager_AddRemoveInbound(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag": "vless-in",
+				"settings": map[string]interface{}{
+					"clients": []interface{}{map[string]interface{}{"id": "user1"}},
+				},
+			},
+		},
+	}
+	_ = m.ExtractUsersFromConfig(Hashes{EmptyConfig: "hash123", Inbounds: []InboundHash{{Tag: "vless-in"}}}, config)
+	hashes := m.CurrentHashes()
+	before, _ := m.WatchXrayConfig()
+
+	m.AddInbound(map[string]interface{}{
+		"tag":      "test-in",
+		"protocol": "vless",
+		"settings": map[string]interface{}{
+			"clients": []interface{}{map[string]interface{}{"id": "tester"}},
+		},
+	})
+
+	if !m.HasInbound("test-in") || m.InboundProtocol("test-in") != "vless" {
+		t.Error("Added inbound should be part of the config")
+	}
+	if !m.HasUserInInbound("test-in", "tester") {
+		t.Error("Users of the added inbound should be tracked")
+	}
+	if inbounds, _ := before["inbounds"].([]interface{}); len(inbounds) != 1 {
+		t.Error("Previous config should be left untouched")
+	}
+	if !m.IsNeedRestartCore(hashes) {
+		t.Error("Panel hashes should no longer match after adding an inbound")
+	}
+
+	if !m.RemoveInbound("test-in") {
+		t.Error("RemoveInbound should report the inbound was removed")
+	}
+	if m.RemoveInbound("test-in") || m.HasInbound("test-in") {
+		t.Error("Removed inbound should be gone")
+	}
+	if !m.HasInbound("vless-in") {
+		t.Error("Other inbounds should be kept")
+	}
+	if m.IsNeedRestartCore(hashes) {
+		t.Error("Panel hashes should match again after removing the inbound")
+	}
+}
+
+func TestConfigManager_UpdateInbound(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{"tag": "vless-in", "port": 443.0},
+		},
+	}
+	_ = m.ExtractUsersFromConfig(Hashes{EmptyConfig: "hash123", Inbounds: []InboundHash{{Tag: "vless-in"}}}, config)
+
+	setPort := func(port float64) func(map[string]interface{}) error {
+		return func(inbound map[string]interface{}) error {
+			inbound["port"] = port
+			return nil
+		}
+	}
+	port := func() interface{} {
+		inbounds, _ := m.GetXrayConfig()["inbounds"].([]interface{})
+		return inbounds[0].(map[string]interface{})["port"]
+	}
+	applied := func(map[string]interface{}) error { return nil }
+
+	if err := m.UpdateInbound("missing-in", setPort(8443), applied); !errors.Is(err, ErrInboundNotFound) {
+		t.Errorf("Expected ErrInboundNotFound, got %v", err)
+	}
+
+	failed := errors.New("failed")
+	if err := m.UpdateInbound("vless-in", setPort(8443), func(map[string]interface{}) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Expected apply error, got %v", err)
+	}
+	if port() != 443.0 {
+		t.Error("Config should be kept when apply fails")
+	}
+
+	if err := m.UpdateInbound("vless-in", setPort(8443), applied); err != nil {
+		t.Fatalf("UpdateInbound failed: %v", err)
+	}
+	if port() != 8443.0 {
+		t.Error("Config should be updated")
+	}
+	if config["inbounds"].([]interface{})[0].(map[string]interface{})["port"] != 443.0 {
+		t.Error("Previous config should be left untouched")
+	}
+}
+
+func TestConfigManager_ApplyPatchedConfig(t *testing.T) {
+	m := NewConfigManager(nil)
+
+	inbound := func(tag string, ids ...string) map[string]interface{} {
+		clients := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			clients = append(clients, map[string]interface{}{"id": id})
+		}
+		return map[string]interface{}{"tag": tag, "settings": map[string]interface{}{"clients": clients}}
+	}
+	config := map[string]interface{}{
+		"inbounds": []interface{}{inbound("vless-in", "user1"), inbound("api")},
+	}
+	_ = m.ExtractUsersFromConfig(Hashes{EmptyConfig: "hash123", Inbounds: []InboundHash{{Tag: "vless-in"}}}, config)
+
+	// Users only: the empty config hash is kept, the user hash follows.
+	patched := map[string]interface{}{
+		"inbounds": []interface{}{inbound("vless-in", "user1", "user2"), inbound("api")},
+	}
+	if err := m.ApplyPatchedConfig(patched, nil, true); err != nil {
+		t.Fatalf("ApplyPatchedConfig failed: %v", err)
+	}
+	hashes := m.CurrentHashes()
+	if hashes.EmptyConfig != "hash123" {
+		t.Errorf("Expected empty config hash to be kept, got %s", hashes.EmptyConfig)
+	}
+	if len(hashes.Inbounds) != 1 || hashes.Inbounds[0].UsersCount != 2 {
+		t.Errorf("Expected vless-in with 2 users, got %+v", hashes.Inbounds)
+	}
+
+	// An added inbound is tracked, the untracked api inbound is not.
+	patched = map[string]interface{}{
+		"inbounds": []interface{}{inbound("vless-in", "user1"), inbound("trojan-in", "user3"), inbound("api")},
+	}
+	if err := m.ApplyPatchedConfig(patched, []string{"trojan-in"}, false); err != nil {
+		t.Fatalf("ApplyPatchedConfig failed: %v", err)
+	}
+	hashes = m.CurrentHashes()
+	if !strings.HasPrefix(hashes.EmptyConfig, patchedConfigHashPrefix) {
+		t.Errorf("Expected a node computed empty config hash, got %s", hashes.EmptyConfig)
+	}
+	if len(hashes.Inbounds) != 2 || hashes.Inbounds[0].Tag != "trojan-in" || hashes.Inbounds[1].Tag != "vless-in" {
+		t.Errorf("Expected trojan-in and vless-in to be tracked, got %+v", hashes.Inbounds)
+	}
+	if !m.IsNeedRestartCore(Hashes{EmptyConfig: "hash123", Inbounds: []InboundHash{{Tag: "vless-in", Hash: m.GetInboundHash("vless-in")}}}) {
+		t.Error("Panel hashes should no longer match a patched base config")
+	}
+}
@@ -2,26 +2,53 @@ package xray
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/xtls/xray-core/app/commander"
 	"github.com/xtls/xray-core/app/router"
+	xnet "github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/common/session"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
 	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/infra/conf"
 	_ "github.com/xtls/xray-core/main/distro/all"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/remnawave/node-go/internal/logger"
 )
 
+// DefaultAssetDir is where geodata is downloaded to when no asset
+// directory is configured or found.
+const DefaultAssetDir = "/usr/local/share/xray"
+
+// configMu serializes the builds of xray configs: they read and fill the
+// geodata caches of xray, package globals that ReloadRouting resets.
+var configMu sync.Mutex
+
+// commanderStartTimeout bounds the wait for the gRPC API of a started
+// instance.
+const commanderStartTimeout = 5 * time.Second
+
 func init() {
 	if os.Getenv("XRAY_LOCATION_ASSET") == "" {
 		for _, path := range []string{
-			"/usr/local/share/xray",
+			DefaultAssetDir,
 			"/usr/share/xray",
 			"/opt/xray",
 			".",
@@ -35,19 +62,152 @@ func init() {
 }
 
 type Core struct {
-	mu       sync.RWMutex
-	instance *core.Instance
-	logger   *logger.Logger
-	running  bool
+	mu        sync.RWMutex
+	instance  *core.Instance
+	logger    *logger.Logger
+	running   bool
+	assetPath string
+
+	// external is set for a core running xray as a child process, and
+	// process while that process runs.
+	external *externalBinary
+	process  *externalProcess
+
+	hooksMu       sync.RWMutex
+	onStartHooks  []func()
+	onStopHooks   []func()
+	onFailedHooks []func(error)
+	onAccessHooks []func(Access)
+
+	onAuthFailureHooks []func(AuthFailure)
+
+	// runtimeRules are the routing rules added through the API to
+	// rulesInstance, kept so that reloading the routing of the config does
+	// not drop them.
+	rulesMu       sync.Mutex
+	runtimeRules  []*router.RoutingRule
+	rulesInstance *core.Instance
+
+	// startedAt is when the running instance started, starts counts the
+	// successful starts and lastErr is the last start failure or crash,
+	// all guarded by mu.
+	startedAt time.Time
+	starts    int
+	lastErr   error
+	lastErrAt time.Time
+
+	// apiPending is the config of the running instance while its gRPC API
+	// has not answered yet, guarded by mu. Stop waits for the API before
+	// closing such an instance.
+	apiPending *core.Config
+}
+
+// StartInfo describes the starts of a core.
+type StartInfo struct {
+	// StartedAt is when the running instance started, zero while stopped.
+	StartedAt time.Time
+	// Restarts counts the successful starts after the first one.
+	Restarts int
+	// LastError is the last start failure or crash, kept across later
+	// successful starts, and LastErrorAt when it happened.
+	LastError   error
+	LastErrorAt time.Time
 }
 
+// NewCore creates a stopped core. xray logs written to the console are
+// emitted through log.
 func NewCore(log *logger.Logger) *Core {
+	captureLogs(log)
+
 	return &Core{
 		logger: log,
 	}
 }
 
+// sibling creates a stopped core of the same kind as c, logging through
+// log, with the log handler of the process left to c.
+func (c *Core) sibling(log *logger.Logger) *Core {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Core{
+		logger:    log,
+		assetPath: c.assetPath,
+		external:  c.external,
+	}
+}
+
+// reclaimLogs makes the running embedded instance of c the receiver of the
+// xray logs again, after another instance was created in the process.
+func (c *Core) reclaimLogs() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.instance != nil && c.external == nil {
+		c.teeAccessLog(c.instance)
+	}
+}
+
+// OnStart registers a hook that runs after every successful start of the core,
+// including restarts. Hooks are used to re-apply runtime state (e.g. routing
+// rules) that is lost when the xray instance is recreated.
+func (c *Core) OnStart(fn func()) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onStartHooks = append(c.onStartHooks, fn)
+}
+
+// OnStop registers a hook that runs after the core is explicitly stopped.
+// Restarts do not trigger it.
+func (c *Core) OnStop(fn func()) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onStopHooks = append(c.onStopHooks, fn)
+}
+
+// OnStartFailed registers a hook that runs when a start or restart fails.
+// After a failed restart the previous instance is gone, so the node is left
+// without a running core.
+func (c *Core) OnStartFailed(fn func(error)) {
+	c.hooksMu.Lock()
+	defer c.hooksMu.Unlock()
+	c.onFailedHooks = append(c.onFailedHooks, fn)
+}
+
 func (c *Core) Start(configJSON []byte) error {
+	// A config missing its assets is rejected before anything changes, the
+	// running instance, if any, is kept.
+	if err := c.prepareAssets(configJSON); err != nil {
+		return err
+	}
+
+	if err := c.start(configJSON); err != nil {
+		c.mu.Lock()
+		c.recordFailureLocked(err)
+		c.mu.Unlock()
+
+		c.hooksMu.RLock()
+		hooks := append([]func(error){}, c.onFailedHooks...)
+		c.hooksMu.RUnlock()
+
+		for _, hook := range hooks {
+			hook(err)
+		}
+		return err
+	}
+
+	c.hooksMu.RLock()
+	hooks := append([]func(){}, c.onStartHooks...)
+	c.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	return nil
+}
+
+func (c *Core) start(configJSON []byte) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -57,7 +217,11 @@ func (c *Core) Start(configJSON []byte) error {
 		}
 	}
 
-	config, err := core.LoadConfig("json", bytes.NewReader(configJSON))
+	if c.external != nil {
+		return c.startExternal(configJSON)
+	}
+
+	config, err := loadConfig(configJSON)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -72,17 +236,91 @@ func (c *Core) Start(configJSON []byte) error {
 		return fmt.Errorf("failed to start xray: %w", err)
 	}
 
+	c.apiPending = nil
+	if err := waitCommander(instance, config); err != nil {
+		c.logger.WithError(err).Warn("xray API did not come up")
+		c.apiPending = config
+	}
+
+	c.teeAccessLog(instance)
 	c.instance = instance
 	c.running = true
+	c.recordStartLocked()
 	c.logger.Info("xray-core started successfully")
 
 	return nil
 }
 
+// waitCommander returns once the commander of instance, the gRPC API of
+// the config if it has one, serves its first connection. Its Start reads
+// the server to serve from a goroutine, and Close clears it: an instance
+// closed before that goroutine runs crashes the process (an upstream
+// xray-core issue).
+func waitCommander(instance *core.Instance, config *core.Config) error {
+	var api *commander.Config
+	for _, app := range config.App {
+		if message, err := app.GetInstance(); err == nil {
+			if cfg, ok := message.(*commander.Config); ok {
+				api = cfg
+				break
+			}
+		}
+	}
+	if api == nil {
+		return nil
+	}
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) {
+		if api.Listen != "" {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", api.Listen)
+		}
+		return dialOutbound(ctx, instance, api.Tag, "tcp", "127.0.0.1:0")
+	}
+	conn, err := grpc.NewClient("passthrough:///xray-api",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dial))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), commanderStartTimeout)
+	defer cancel()
+
+	// The client is ready once the server answered the HTTP/2 preface,
+	// which it does from Serve only.
+	conn.Connect()
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("no answer within %s", commanderStartTimeout)
+		}
+	}
+}
+
 func (c *Core) Stop() error {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.stopLocked()
+	wasRunning := c.instance != nil
+	err := c.stopLocked()
+	c.mu.Unlock()
+
+	if err != nil || !wasRunning {
+		return err
+	}
+
+	c.hooksMu.RLock()
+	hooks := append([]func(){}, c.onStopHooks...)
+	c.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	return nil
 }
 
 func (c *Core) stopLocked() error {
@@ -90,12 +328,25 @@ func (c *Core) stopLocked() error {
 		return nil
 	}
 
+	if c.process != nil {
+		c.stopExternal(c.process)
+		c.process = nil
+	}
+
+	if c.apiPending != nil {
+		if err := waitCommander(c.instance, c.apiPending); err != nil {
+			return fmt.Errorf("xray API still not serving, not closing the instance: %w", err)
+		}
+		c.apiPending = nil
+	}
+
 	if err := c.instance.Close(); err != nil {
 		return fmt.Errorf("failed to close xray instance: %w", err)
 	}
 
 	c.instance = nil
 	c.running = false
+	c.startedAt = time.Time{}
 	c.logger.Info("xray-core stopped")
 
 	return nil
@@ -108,9 +359,40 @@ func (c *Core) IsRunning() bool {
 }
 
 func (c *Core) GetVersion() string {
+	if c.external != nil {
+		return c.external.version
+	}
 	return core.Version()
 }
 
+// StartInfo returns the uptime, restart count and last failure of c.
+func (c *Core) StartInfo() StartInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info := StartInfo{
+		StartedAt:   c.startedAt,
+		LastError:   c.lastErr,
+		LastErrorAt: c.lastErrAt,
+	}
+	if c.starts > 1 {
+		info.Restarts = c.starts - 1
+	}
+	return info
+}
+
+// recordStartLocked records a successful start. c.mu must be held.
+func (c *Core) recordStartLocked() {
+	c.startedAt = time.Now()
+	c.starts++
+}
+
+// recordFailureLocked records a failed start or a crash. c.mu must be held.
+func (c *Core) recordFailureLocked(err error) {
+	c.lastErr = err
+	c.lastErrAt = time.Now()
+}
+
 func (c *Core) Instance() *core.Instance {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -121,6 +403,244 @@ func (c *Core) Restart(configJSON []byte) error {
 	return c.Start(configJSON)
 }
 
+// RestartKeepingUsers restarts the core with configJSON, like Restart, and
+// adds back the users the inbounds had before, including those added at
+// runtime. Users of inbounds missing from configJSON are dropped.
+func (c *Core) RestartKeepingUsers(configJSON []byte) error {
+	ctx := context.Background()
+	users := make(map[string][]*protocol.MemoryUser)
+	if instance := c.Instance(); instance != nil {
+		if ibm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Manager); ok {
+			for _, handler := range ibm.ListHandlers(ctx) {
+				if userManager, err := proxyUserManager(ctx, ibm, handler.Tag()); err == nil {
+					users[handler.Tag()] = userManager.GetUsers(ctx)
+				}
+			}
+		}
+	}
+
+	if err := c.Start(configJSON); err != nil {
+		return err
+	}
+
+	instance := c.Instance()
+	if instance == nil {
+		return nil
+	}
+	ibm, ok := instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager not available")
+	}
+
+	restored := 0
+	for tag, tagUsers := range users {
+		userManager, err := proxyUserManager(ctx, ibm, tag)
+		if err != nil {
+			continue
+		}
+		for _, user := range tagUsers {
+			if user == nil || userManager.GetUser(ctx, user.Email) != nil {
+				continue
+			}
+			if err := userManager.AddUser(ctx, user); err != nil {
+				c.logger.WithError(err).WithField("tag", tag).WithField("email", user.Email).
+					Warn("Failed to restore user after core restart")
+				continue
+			}
+			restored++
+		}
+	}
+	c.logger.WithField("users", restored).Info("xray-core restarted, runtime users restored")
+
+	return nil
+}
+
+// ReloadInbounds replaces the inbound handlers with the given tags by the
+// definitions in configJSON, leaving every other handler and all active
+// connections on them untouched. Users added at runtime to reloaded inbounds
+// are dropped in favour of the ones listed in configJSON.
+func (c *Core) ReloadInbounds(configJSON []byte, tags []string) error {
+	if err := c.reloadInbounds(configJSON, tags, false); err != nil {
+		return err
+	}
+
+	c.logger.WithField("inbounds", len(tags)).Info("xray-core inbounds reloaded")
+
+	return nil
+}
+
+// RestartInbound re-creates the inbound handler with the given tag from its
+// definition in configJSON, e.g. to apply new REALITY keys, and adds back
+// the users it had, including those added at runtime.
+func (c *Core) RestartInbound(configJSON []byte, tag string) error {
+	if err := c.reloadInbounds(configJSON, []string{tag}, true); err != nil {
+		return err
+	}
+
+	c.logger.WithField("tag", tag).Info("xray-core inbound restarted")
+
+	return nil
+}
+
+func (c *Core) reloadInbounds(configJSON []byte, tags []string, keepUsers bool) error {
+	config, err := loadConfig(configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	byTag := make(map[string]*core.InboundHandlerConfig, len(config.Inbound))
+	for _, inboundConfig := range config.Inbound {
+		byTag[inboundConfig.Tag] = inboundConfig
+	}
+	for _, tag := range tags {
+		if _, ok := byTag[tag]; !ok {
+			return fmt.Errorf("inbound '%s' not found in config", tag)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.instance == nil {
+		return fmt.Errorf("xray core not running")
+	}
+
+	ibm, ok := c.instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager not available")
+	}
+
+	ctx := context.Background()
+	for _, tag := range tags {
+		var users []*protocol.MemoryUser
+		if keepUsers {
+			if userManager, err := proxyUserManager(ctx, ibm, tag); err == nil {
+				users = userManager.GetUsers(ctx)
+			}
+		}
+
+		if err := ibm.RemoveHandler(ctx, tag); err != nil {
+			c.logger.WithError(err).WithField("tag", tag).Warn("Inbound not present before reload")
+		}
+		if err := addInboundHandler(c.instance, byTag[tag]); err != nil {
+			return fmt.Errorf("failed to add inbound '%s': %w", tag, err)
+		}
+
+		if len(users) == 0 {
+			continue
+		}
+		userManager, err := proxyUserManager(ctx, ibm, tag)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user == nil || userManager.GetUser(ctx, user.Email) != nil {
+				continue
+			}
+			if err := userManager.AddUser(ctx, user); err != nil {
+				c.logger.WithError(err).WithField("tag", tag).WithField("email", user.Email).
+					Warn("Failed to restore user after inbound restart")
+			}
+		}
+	}
+
+	return nil
+}
+
+// AddInbound adds the inbound defined by inboundJSON, an xray inbound
+// object, to the running core without touching the other handlers.
+func (c *Core) AddInbound(inboundJSON []byte) error {
+	configJSON := append(append([]byte(`{"inbounds":[`), inboundJSON...), ']', '}')
+	config, err := loadConfig(configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to load inbound: %w", err)
+	}
+	if len(config.Inbound) != 1 {
+		return fmt.Errorf("expected a single inbound, got %d", len(config.Inbound))
+	}
+	inboundConfig := config.Inbound[0]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.instance == nil {
+		return fmt.Errorf("xray core not running")
+	}
+
+	ibm, ok := c.instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager not available")
+	}
+	if _, err := ibm.GetHandler(context.Background(), inboundConfig.Tag); err == nil {
+		return fmt.Errorf("inbound '%s' already exists", inboundConfig.Tag)
+	}
+
+	if err := addInboundHandler(c.instance, inboundConfig); err != nil {
+		return fmt.Errorf("failed to add inbound '%s': %w", inboundConfig.Tag, err)
+	}
+
+	c.logger.WithField("tag", inboundConfig.Tag).Info("xray-core inbound added")
+
+	return nil
+}
+
+// RemoveInbound removes the inbound handler with the given tag, closing its
+// listener and the connections on it.
+func (c *Core) RemoveInbound(tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.instance == nil {
+		return fmt.Errorf("xray core not running")
+	}
+
+	ibm, ok := c.instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager not available")
+	}
+
+	if err := ibm.RemoveHandler(context.Background(), tag); err != nil {
+		return fmt.Errorf("failed to remove inbound '%s': %w", tag, err)
+	}
+
+	c.logger.WithField("tag", tag).Info("xray-core inbound removed")
+
+	return nil
+}
+
+// CloseInbounds closes the listeners of all tagged inbounds so that the core
+// takes no new connections. Connections already accepted are served until
+// they end or the core stops.
+func (c *Core) CloseInbounds() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.instance == nil {
+		return fmt.Errorf("xray core not running")
+	}
+
+	ibm, ok := c.instance.GetFeature(inbound.ManagerType()).(inbound.Manager)
+	if !ok {
+		return fmt.Errorf("inbound manager not available")
+	}
+
+	ctx := context.Background()
+	var errs []error
+	for _, handler := range ibm.ListHandlers(ctx) {
+		tag := handler.Tag()
+		if tag == "" {
+			continue
+		}
+		if err := ibm.RemoveHandler(ctx, tag); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close inbound '%s': %w", tag, err))
+		}
+	}
+
+	c.logger.Info("xray-core inbounds closed")
+
+	return errors.Join(errs...)
+}
+
 type routerWithRules interface {
 	routing.Router
 	AddRule(msg *serial.TypedMessage, shouldAppend bool) error
@@ -128,10 +648,10 @@ type routerWithRules interface {
 }
 
 func (c *Core) getRouter() (routerWithRules, error) {
-	c.mu.RLock()
-	instance := c.instance
-	c.mu.RUnlock()
+	return routerOf(c.Instance())
+}
 
+func routerOf(instance *core.Instance) (routerWithRules, error) {
 	if instance == nil {
 		return nil, fmt.Errorf("xray instance not running")
 	}
@@ -149,66 +669,309 @@ func (c *Core) getRouter() (routerWithRules, error) {
 	return r, nil
 }
 
+// AddRoutingRule routes the traffic from sourceIP through outboundTag.
+// sourceIP is an IP address or CIDR range, or a comma-separated list of
+// them that may mix IPv4 and IPv6.
 func (c *Core) AddRoutingRule(ruleTag string, sourceIP string, outboundTag string) error {
-	r, err := c.getRouter()
+	cidrs, err := parseSourceCIDRs(sourceIP)
 	if err != nil {
 		return err
 	}
 
-	ip := net.ParseIP(sourceIP)
-	if ip == nil {
-		return fmt.Errorf("invalid IP address: %s", sourceIP)
+	err = c.addRule(&router.RoutingRule{
+		RuleTag: ruleTag,
+		TargetTag: &router.RoutingRule_Tag{
+			Tag: outboundTag,
+		},
+		SourceGeoip: []*router.GeoIP{
+			{
+				Cidr: cidrs,
+			},
+		},
+	})
+	if err != nil {
+		return err
 	}
 
-	var ipBytes []byte
-	var prefix uint32
-	if ip4 := ip.To4(); ip4 != nil {
-		ipBytes = ip4
-		prefix = 32
-	} else {
-		ipBytes = ip.To16()
-		prefix = 128
-	}
+	c.logger.WithField("ruleTag", ruleTag).WithField("sourceIP", sourceIP).
+		WithField("outbound", outboundTag).Info("Added routing rule")
 
-	routerConfig := &router.Config{
-		Rule: []*router.RoutingRule{
-			{
-				RuleTag: ruleTag,
-				TargetTag: &router.RoutingRule_Tag{
-					Tag: outboundTag,
-				},
-				SourceGeoip: []*router.GeoIP{
-					{
-						Cidr: []*router.CIDR{
-							{
-								Ip:     ipBytes,
-								Prefix: prefix,
-							},
-						},
-					},
-				},
-			},
+	return nil
+}
+
+// AddUserRoutingRule routes the traffic of the users with the given emails
+// through outboundTag.
+func (c *Core) AddUserRoutingRule(ruleTag string, emails []string, outboundTag string) error {
+	err := c.addRule(&router.RoutingRule{
+		RuleTag: ruleTag,
+		TargetTag: &router.RoutingRule_Tag{
+			Tag: outboundTag,
 		},
+		UserEmail: emails,
+	})
+	if err != nil {
+		return err
 	}
 
-	typedMsg := serial.ToTypedMessage(routerConfig)
+	c.logger.WithField("ruleTag", ruleTag).WithField("users", len(emails)).
+		WithField("outbound", outboundTag).Info("Added user routing rule")
+
+	return nil
+}
+
+// addRule appends rule to the rules of the running router.
+func (c *Core) addRule(rule *router.RoutingRule) error {
+	c.rulesMu.Lock()
+	defer c.rulesMu.Unlock()
+
+	instance := c.Instance()
+	r, err := routerOf(instance)
+	if err != nil {
+		return err
+	}
+
+	typedMsg := serial.ToTypedMessage(&router.Config{
+		Rule: []*router.RoutingRule{rule},
+	})
 
 	if err := r.AddRule(typedMsg, true); err != nil {
 		return fmt.Errorf("failed to add routing rule: %w", err)
 	}
 
-	c.logger.WithField("ruleTag", ruleTag).WithField("sourceIP", sourceIP).
-		WithField("outbound", outboundTag).Info("Added routing rule")
+	// Rules added to a previous instance did not survive its restart.
+	if c.rulesInstance != instance {
+		c.runtimeRules = nil
+		c.rulesInstance = instance
+	}
+	c.runtimeRules = append(c.runtimeRules, rule)
 
 	return nil
 }
 
+// ReloadRouting replaces the routing rules and balancers of the running
+// router by those of configJSON, e.g. to pick up updated geoip.dat and
+// geosite.dat files, without restarting the core. Rules added at runtime
+// are kept, after the config rules as before. Other routing settings, such
+// as the domain strategy, only change on restart.
+func (c *Core) ReloadRouting(configJSON []byte) error {
+	// The geodata loaded by previous builds is cached by xray; drop it so
+	// the rules are built from the files as they are now.
+	configMu.Lock()
+	conf.IPCache = make(map[string]*router.GeoIP)
+	conf.SiteCache = make(map[string]*router.GeoSite)
+	config, err := core.LoadConfig("json", bytes.NewReader(configJSON))
+	configMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	routerConfig := &router.Config{}
+	for _, app := range config.App {
+		instance, err := app.GetInstance()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		if rc, ok := instance.(*router.Config); ok {
+			routerConfig = rc
+			break
+		}
+	}
+
+	c.rulesMu.Lock()
+	defer c.rulesMu.Unlock()
+
+	instance := c.Instance()
+	r, err := routerOf(instance)
+	if err != nil {
+		return err
+	}
+
+	if c.rulesInstance == instance {
+		routerConfig.Rule = append(routerConfig.Rule, c.runtimeRules...)
+	}
+	if err := r.AddRule(serial.ToTypedMessage(routerConfig), false); err != nil {
+		return fmt.Errorf("failed to reload routing rules: %w", err)
+	}
+
+	c.logger.WithField("rules", len(routerConfig.Rule)).Info("xray-core routing reloaded")
+
+	return nil
+}
+
+// HasOutbound reports whether the running core has an outbound tagged tag.
+func (c *Core) HasOutbound(tag string) bool {
+	c.mu.RLock()
+	instance := c.instance
+	c.mu.RUnlock()
+
+	if instance == nil {
+		return false
+	}
+
+	ohm, ok := instance.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	return ok && ohm.GetHandler(tag) != nil
+}
+
+// ErrOutboundNotFound is returned by DialOutbound for a tag the running
+// core has no outbound for.
+var ErrOutboundNotFound = errors.New("outbound not found")
+
+// DialOutbound opens a connection to address, host:port, through the
+// outbound tagged tag of the running core, bypassing the routing rules.
+// The outbound connects in the background: its errors show as the
+// connection is read. Closing the connection interrupts a read, which
+// deadlines do not.
+func (c *Core) DialOutbound(ctx context.Context, tag, network, address string) (net.Conn, error) {
+	if c.IsExternal() {
+		return nil, errors.New("dialing through an outbound needs the embedded core")
+	}
+	c.mu.RLock()
+	instance := c.instance
+	c.mu.RUnlock()
+	if instance == nil {
+		return nil, errors.New("xray is not running")
+	}
+	ohm, ok := instance.GetFeature(outbound.ManagerType()).(outbound.Manager)
+	if !ok || ohm.GetHandler(tag) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOutboundNotFound, tag)
+	}
+
+	return dialOutbound(ctx, instance, tag, network, address)
+}
+
+// dialOutbound opens a connection through the outbound tagged tag of
+// instance, bypassing the routing rules.
+func dialOutbound(ctx context.Context, instance *core.Instance, tag, network, address string) (net.Conn, error) {
+	dest, err := xnet.ParseDestination(network + ":" + address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	ctx = session.ContextWithContent(ctx, &session.Content{SkipDNSResolve: true})
+	ctx = session.SetForcedOutboundTagToContext(ctx, tag)
+	return core.Dial(ctx, instance, dest)
+}
+
+// ParsePrefix parses an IP address or CIDR range into a masked prefix.
+// Single addresses become /32 (IPv4) or /128 (IPv6), and IPv4-mapped IPv6
+// addresses and ranges their IPv4 equivalent ("::ffff:10.0.0.0/104" is
+// 10.0.0.0/8), so both forms match the same connections.
+func ParsePrefix(source string) (netip.Prefix, error) {
+	if strings.Contains(source, "/") {
+		prefix, err := netip.ParsePrefix(source)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR range: %s", source)
+		}
+		if addr := prefix.Addr(); addr.Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(source)
+	if err != nil || addr.Zone() != "" {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address: %s", source)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// NormalizeSource validates an IP address or CIDR range and returns its
+// canonical form ("10.0.0.5/24" becomes "10.0.0.0/24", "2001:DB8::1/128"
+// becomes "2001:db8::1").
+func NormalizeSource(source string) (string, error) {
+	prefix, err := ParsePrefix(source)
+	if err != nil {
+		return "", err
+	}
+	return formatPrefix(prefix), nil
+}
+
+// NormalizeSources validates a comma-separated list of IP addresses and
+// CIDR ranges of either family and returns it in canonical form, without
+// duplicates and sorted with IPv4 first.
+func NormalizeSources(sources string) (string, error) {
+	prefixes, err := parseSources(sources)
+	if err != nil {
+		return "", err
+	}
+	normalized := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		normalized = append(normalized, formatPrefix(prefix))
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+func formatPrefix(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+// parseSources parses a comma-separated list of sources into sorted,
+// deduplicated prefixes.
+func parseSources(sources string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, source := range strings.Split(sources, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		prefix, err := ParsePrefix(source)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("invalid IP address: %s", sources)
+	}
+
+	slices.SortFunc(prefixes, ComparePrefixes)
+	return slices.Compact(prefixes), nil
+}
+
+// ComparePrefixes orders prefixes by address, IPv4 first, then by length.
+func ComparePrefixes(a, b netip.Prefix) int {
+	if c := a.Addr().Compare(b.Addr()); c != 0 {
+		return c
+	}
+	return a.Bits() - b.Bits()
+}
+
+// parseSourceCIDRs converts a list of sources into router CIDRs.
+func parseSourceCIDRs(sources string) ([]*router.CIDR, error) {
+	prefixes, err := parseSources(sources)
+	if err != nil {
+		return nil, err
+	}
+	cidrs := make([]*router.CIDR, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		cidrs = append(cidrs, &router.CIDR{Ip: prefix.Addr().AsSlice(), Prefix: uint32(prefix.Bits())})
+	}
+	return cidrs, nil
+}
+
 func (c *Core) RemoveRoutingRule(ruleTag string) error {
-	r, err := c.getRouter()
+	c.rulesMu.Lock()
+	defer c.rulesMu.Unlock()
+
+	instance := c.Instance()
+	r, err := routerOf(instance)
 	if err != nil {
 		return err
 	}
 
+	if c.rulesInstance == instance {
+		kept := c.runtimeRules[:0]
+		for _, rule := range c.runtimeRules {
+			if rule.RuleTag != ruleTag {
+				kept = append(kept, rule)
+			}
+		}
+		c.runtimeRules = kept
+	}
+
 	if err := r.RemoveRule(ruleTag); err != nil {
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "empty tag") {
 			c.logger.WithField("ruleTag", ruleTag).Warn("Rule not found, may already be removed")
@@ -222,13 +985,44 @@ func (c *Core) RemoveRoutingRule(ruleTag string) error {
 	return nil
 }
 
+// RoutingRule describes a single rule currently installed in the xray router.
+type RoutingRule struct {
+	RuleTag     string `json:"ruleTag"`
+	OutboundTag string `json:"outboundTag"`
+}
+
+func (c *Core) ListRoutingRules() ([]RoutingRule, error) {
+	r, err := c.getRouter()
+	if err != nil {
+		return nil, err
+	}
+
+	routes := r.ListRule()
+	rules := make([]RoutingRule, 0, len(routes))
+	for _, route := range routes {
+		rules = append(rules, RoutingRule{
+			RuleTag:     route.GetRuleTag(),
+			OutboundTag: route.GetOutboundTag(),
+		})
+	}
+
+	return rules, nil
+}
+
+// loadConfig builds an xray config from JSON, one build at a time.
+func loadConfig(configJSON []byte) (*core.Config, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return core.LoadConfig("json", bytes.NewReader(configJSON))
+}
+
 func ValidateConfig(configJSON []byte) error {
 	var cfg map[string]interface{}
 	if err := json.Unmarshal(configJSON, &cfg); err != nil {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	_, err := core.LoadConfig("json", bytes.NewReader(configJSON))
+	_, err := loadConfig(configJSON)
 	if err != nil {
 		return fmt.Errorf("invalid xray config: %w", err)
 	}
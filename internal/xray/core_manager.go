@@ -0,0 +1,197 @@
+package xray
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// DefaultInstanceID addresses the core the node always runs, the one
+// served by the top-level endpoints.
+const DefaultInstanceID = "default"
+
+var (
+	ErrInstanceExists    = errors.New("instance already exists")
+	ErrInstanceNotFound  = errors.New("instance not found")
+	ErrInvalidInstanceID = errors.New("invalid instance id")
+	ErrDefaultInstance   = errors.New("the default instance cannot be removed")
+)
+
+var instanceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// CoreInstance is a core and the config it runs, addressed by ID.
+type CoreInstance struct {
+	ID            string
+	Core          *Core
+	ConfigManager *ConfigManager
+}
+
+// CoreManager keeps the default core and additional isolated cores, e.g.
+// one per config profile or customer group. Each has its own inbounds,
+// users and stats; the configs must not share listening ports.
+//
+// Additional cores run like the default one, embedded or as an external
+// process. xray has one log handler per process, so the embedded cores
+// log through the log settings of the default core.
+type CoreManager struct {
+	mu        sync.RWMutex
+	instances map[string]*CoreInstance
+	primary   *Core
+	logger    *logger.Logger
+
+	hooksMu       sync.RWMutex
+	onCreateHooks []func(*CoreInstance)
+	onRemoveHooks []func(*CoreInstance)
+}
+
+// NewCoreManager creates a manager holding core and configManager as the
+// default instance.
+func NewCoreManager(core *Core, configManager *ConfigManager, log *logger.Logger) *CoreManager {
+	return &CoreManager{
+		instances: map[string]*CoreInstance{
+			DefaultInstanceID: {ID: DefaultInstanceID, Core: core, ConfigManager: configManager},
+		},
+		primary: core,
+		logger:  log,
+	}
+}
+
+// OnCreate registers a hook that runs for every instance created, before
+// Create returns it.
+func (m *CoreManager) OnCreate(fn func(*CoreInstance)) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.onCreateHooks = append(m.onCreateHooks, fn)
+}
+
+// OnRemove registers a hook that runs for every instance removed, after
+// its core stopped.
+func (m *CoreManager) OnRemove(fn func(*CoreInstance)) {
+	m.hooksMu.Lock()
+	defer m.hooksMu.Unlock()
+	m.onRemoveHooks = append(m.onRemoveHooks, fn)
+}
+
+// Create adds a stopped instance. IDs are 1 to 64 letters, digits, "-" or
+// "_".
+func (m *CoreManager) Create(id string) (*CoreInstance, error) {
+	if !instanceIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidInstanceID, id)
+	}
+
+	m.mu.Lock()
+	if _, exists := m.instances[id]; exists {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrInstanceExists, id)
+	}
+
+	log := m.logger.WithField("instance", id)
+	instance := &CoreInstance{
+		ID:            id,
+		Core:          m.primary.sibling(log),
+		ConfigManager: NewConfigManager(log),
+	}
+	// Creating an embedded xray instance takes over the log handler of the
+	// process; hand it back to the default core.
+	instance.Core.OnStart(m.primary.reclaimLogs)
+	instance.Core.OnStartFailed(func(error) { m.primary.reclaimLogs() })
+	m.instances[id] = instance
+	m.mu.Unlock()
+
+	m.hooksMu.RLock()
+	hooks := append([]func(*CoreInstance){}, m.onCreateHooks...)
+	m.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(instance)
+	}
+
+	m.logger.WithField("instance", id).Info("Core instance created")
+
+	return instance, nil
+}
+
+// Get returns the instance with the given ID.
+func (m *CoreManager) Get(id string) (*CoreInstance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instance, exists := m.instances[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, id)
+	}
+	return instance, nil
+}
+
+// List returns the instances sorted by ID, the default one included.
+func (m *CoreManager) List() []*CoreInstance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	instances := make([]*CoreInstance, 0, len(m.instances))
+	for _, instance := range m.instances {
+		instances = append(instances, instance)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances
+}
+
+// Remove stops the core of an instance and drops the instance.
+func (m *CoreManager) Remove(id string) error {
+	if id == DefaultInstanceID {
+		return ErrDefaultInstance
+	}
+
+	m.mu.Lock()
+	instance, exists := m.instances[id]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrInstanceNotFound, id)
+	}
+	delete(m.instances, id)
+	m.mu.Unlock()
+
+	m.remove(instance)
+	return nil
+}
+
+// StopAll stops and drops the additional instances, e.g. on shutdown. The
+// default core is left to its owner.
+func (m *CoreManager) StopAll() {
+	m.mu.Lock()
+	var removed []*CoreInstance
+	for id, instance := range m.instances {
+		if id != DefaultInstanceID {
+			removed = append(removed, instance)
+			delete(m.instances, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, instance := range removed {
+		m.remove(instance)
+	}
+}
+
+func (m *CoreManager) remove(instance *CoreInstance) {
+	log := m.logger.WithField("instance", instance.ID)
+	if err := instance.Core.Stop(); err != nil {
+		log.WithError(err).Warn("Failed to stop core instance")
+	}
+	instance.ConfigManager.Cleanup()
+	m.primary.reclaimLogs()
+
+	m.hooksMu.RLock()
+	hooks := append([]func(*CoreInstance){}, m.onRemoveHooks...)
+	m.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(instance)
+	}
+
+	log.Info("Core instance removed")
+}
@@ -0,0 +1,102 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xtls/xray-core/features/inbound"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+func makeInboundConfig(tag string) []byte {
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      tag,
+				"listen":   "127.0.0.1",
+				"port":     0,
+				"protocol": "vless",
+				"settings": map[string]interface{}{"clients": []interface{}{}, "decryption": "none"},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+	return data
+}
+
+func TestCoreManager_CreateRemove(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	m := NewCoreManager(NewCore(log), NewConfigManager(log), log)
+
+	var created, removed []string
+	m.OnCreate(func(instance *CoreInstance) { created = append(created, instance.ID) })
+	m.OnRemove(func(instance *CoreInstance) { removed = append(removed, instance.ID) })
+
+	instance, err := m.Create("group-a")
+	require.NoError(t, err)
+	assert.Equal(t, "group-a", instance.ID)
+	assert.False(t, instance.Core.IsRunning())
+
+	_, err = m.Create("group-a")
+	assert.ErrorIs(t, err, ErrInstanceExists)
+	_, err = m.Create(DefaultInstanceID)
+	assert.ErrorIs(t, err, ErrInstanceExists)
+	_, err = m.Create("../etc")
+	assert.ErrorIs(t, err, ErrInvalidInstanceID)
+
+	ids := []string{}
+	for _, instance := range m.List() {
+		ids = append(ids, instance.ID)
+	}
+	assert.Equal(t, []string{DefaultInstanceID, "group-a"}, ids)
+
+	assert.ErrorIs(t, m.Remove(DefaultInstanceID), ErrDefaultInstance)
+	require.NoError(t, m.Remove("group-a"))
+	assert.ErrorIs(t, m.Remove("group-a"), ErrInstanceNotFound)
+	_, err = m.Get("group-a")
+	assert.ErrorIs(t, err, ErrInstanceNotFound)
+
+	assert.Equal(t, []string{"group-a"}, created)
+	assert.Equal(t, []string{"group-a"}, removed)
+}
+
+func TestCoreManager_InstancesAreIsolated(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	primary := NewCore(log)
+	m := NewCoreManager(primary, NewConfigManager(log), log)
+	defer m.StopAll()
+
+	require.NoError(t, primary.Start(makeInboundConfig("default-in")))
+	defer primary.Stop()
+
+	instance, err := m.Create("group-a")
+	require.NoError(t, err)
+	require.NoError(t, instance.Core.Start(makeInboundConfig("group-in")))
+	assert.True(t, primary.IsRunning())
+	assert.True(t, instance.Core.IsRunning())
+
+	ctx := context.Background()
+	defaultUsers := NewUserManager(primary.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), log)
+	groupUsers := NewUserManager(instance.Core.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), log)
+
+	require.NoError(t, groupUsers.AddUser(ctx, "group-in", BuildVlessUser("alice", "550e8400-e29b-41d4-a716-446655440000", "", 0)))
+	_, err = defaultUsers.GetUsers(ctx, "group-in")
+	assert.Error(t, err, "the default core does not see the inbounds of the instance")
+
+	emails, err := groupUsers.GetUsers(ctx, "group-in")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, emails)
+
+	m.StopAll()
+	assert.False(t, instance.Core.IsRunning())
+	assert.True(t, primary.IsRunning())
+	assert.Len(t, m.List(), 1)
+}
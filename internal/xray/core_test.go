@@ -1,11 +1,17 @@
 package xray
 
 import (
+	"context"
 	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xtls/xray-core/features/inbound"
 
 	"github.com/remnawave/node-go/internal/logger"
 )
@@ -67,6 +73,62 @@ func TestCore_StartStop(t *testing.T) {
 	assert.Nil(t, c.Instance())
 }
 
+func TestCore_StopRightAfterStartWithAPI(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	var cfg map[string]interface{}
+	require.NoError(t, json.Unmarshal(makeMinimalConfig(), &cfg))
+	cfg["api"] = map[string]interface{}{"tag": "api", "services": []interface{}{"HandlerService", "StatsService"}}
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+
+	// Closing an instance before its commander serves crashes the process.
+	for range 300 {
+		require.NoError(t, c.Start(data))
+		require.NoError(t, c.Stop())
+	}
+}
+
+func TestCore_StopWaitsForPendingAPI(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	withAPI := func(api map[string]interface{}) []byte {
+		var cfg map[string]interface{}
+		require.NoError(t, json.Unmarshal(makeMinimalConfig(), &cfg))
+		cfg["api"] = api
+		data, err := json.Marshal(cfg)
+		require.NoError(t, err)
+		return data
+	}
+	data := withAPI(map[string]interface{}{"tag": "api", "services": []interface{}{"StatsService"}})
+	require.NoError(t, c.Start(data))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	deadAddr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	// An API that never answers keeps the instance running rather than
+	// closing it under its commander.
+	silent, err := loadConfig(withAPI(map[string]interface{}{"tag": "api", "listen": deadAddr, "services": []interface{}{"StatsService"}}))
+	require.NoError(t, err)
+	c.mu.Lock()
+	c.apiPending = silent
+	c.mu.Unlock()
+	assert.Error(t, c.Stop())
+	assert.True(t, c.IsRunning())
+
+	pending, err := loadConfig(data)
+	require.NoError(t, err)
+	c.mu.Lock()
+	c.apiPending = pending
+	c.mu.Unlock()
+	require.NoError(t, c.Stop())
+	assert.False(t, c.IsRunning())
+}
+
 func TestCore_StartInvalidConfig(t *testing.T) {
 	log := logger.New(logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON})
 	c := NewCore(log)
@@ -100,6 +162,31 @@ func TestCore_Restart(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestCore_StartInfo(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+	assert.Equal(t, StartInfo{}, c.StartInfo())
+
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	info := c.StartInfo()
+	assert.False(t, info.StartedAt.IsZero())
+	assert.Equal(t, 0, info.Restarts)
+	assert.NoError(t, info.LastError)
+
+	require.NoError(t, c.Restart(makeMinimalConfig()))
+	assert.Equal(t, 1, c.StartInfo().Restarts)
+
+	require.Error(t, c.Start(makeInvalidConfig()))
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	info = c.StartInfo()
+	assert.Equal(t, 2, info.Restarts)
+	assert.Error(t, info.LastError, "the last failure is kept after a successful start")
+	assert.False(t, info.LastErrorAt.IsZero())
+
+	require.NoError(t, c.Stop())
+	assert.True(t, c.StartInfo().StartedAt.IsZero())
+}
+
 func TestValidateConfig_Valid(t *testing.T) {
 	err := ValidateConfig(makeMinimalConfig())
 	assert.NoError(t, err)
@@ -129,3 +216,404 @@ func TestCore_DoubleStart(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, c.IsRunning())
 }
+
+func TestCore_OnStartHooks(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	calls := 0
+	c.OnStart(func() {
+		calls++
+		assert.True(t, c.IsRunning())
+	})
+
+	err := c.Start(makeInvalidJSON())
+	assert.Error(t, err)
+	assert.Equal(t, 0, calls)
+
+	err = c.Start(makeMinimalConfig())
+	require.NoError(t, err)
+	defer c.Stop()
+	assert.Equal(t, 1, calls)
+
+	err = c.Restart(makeMinimalConfig())
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCore_OnStopAndStartFailedHooks(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	stops := 0
+	var failures []error
+	c.OnStop(func() { stops++ })
+	c.OnStartFailed(func(err error) { failures = append(failures, err) })
+
+	require.NoError(t, c.Stop())
+	assert.Equal(t, 0, stops, "stopping an idle core is not an event")
+
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	require.NoError(t, c.Restart(makeMinimalConfig()))
+	assert.Equal(t, 0, stops, "restarts do not count as stops")
+
+	err := c.Restart(makeInvalidJSON())
+	assert.Error(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, err, failures[0])
+
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	require.NoError(t, c.Stop())
+	assert.Equal(t, 1, stops)
+}
+
+func TestCore_ReloadInbounds(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "socks-in",
+				"listen":   "127.0.0.1",
+				"port":     0,
+				"protocol": "socks",
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+
+	err := c.ReloadInbounds(data, []string{"socks-in"})
+	assert.Error(t, err, "reload requires a running core")
+
+	require.NoError(t, c.Start(data))
+	defer c.Stop()
+
+	err = c.ReloadInbounds(data, []string{"socks-in"})
+	require.NoError(t, err)
+	assert.True(t, c.IsRunning())
+
+	err = c.ReloadInbounds(data, []string{"missing-in"})
+	assert.Error(t, err)
+}
+
+func TestCore_ReloadRoutingKeepsRuntimeRules(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	makeConfig := func(ruleTags ...string) []byte {
+		rules := []interface{}{}
+		for _, tag := range ruleTags {
+			rules = append(rules, map[string]interface{}{
+				"ruleTag":     tag,
+				"ip":          []string{"10.0.0.0/8"},
+				"outboundTag": "direct",
+			})
+		}
+		cfg := map[string]interface{}{
+			"log":       map[string]interface{}{"loglevel": "none"},
+			"inbounds":  []interface{}{},
+			"outbounds": []interface{}{map[string]interface{}{"tag": "direct", "protocol": "freedom"}},
+			"routing":   map[string]interface{}{"rules": rules},
+		}
+		data, _ := json.Marshal(cfg)
+		return data
+	}
+
+	assert.Error(t, c.ReloadRouting(makeConfig()), "reload requires a running core")
+
+	require.NoError(t, c.Start(makeConfig("config-a")))
+	defer c.Stop()
+
+	require.NoError(t, c.AddRoutingRule("runtime-1", "192.0.2.1", "direct"))
+	require.NoError(t, c.AddRoutingRule("runtime-2", "192.0.2.2", "direct"))
+	require.NoError(t, c.RemoveRoutingRule("runtime-1"))
+
+	require.NoError(t, c.ReloadRouting(makeConfig("config-b")))
+
+	rules, err := c.ListRoutingRules()
+	require.NoError(t, err)
+	var tags []string
+	for _, rule := range rules {
+		tags = append(tags, rule.RuleTag)
+	}
+	assert.Equal(t, []string{"config-b", "runtime-2"}, tags)
+
+	// Runtime rules do not outlive the instance they were added to.
+	require.NoError(t, c.Restart(makeConfig("config-a")))
+	require.NoError(t, c.ReloadRouting(makeConfig("config-b")))
+	rules, err = c.ListRoutingRules()
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "config-b", rules[0].RuleTag)
+}
+
+func TestCore_AddRemoveInbound(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	inbound := []byte(`{"tag":"test-in","listen":"127.0.0.1","port":0,"protocol":"socks"}`)
+	assert.Error(t, c.AddInbound(inbound), "adding requires a running core")
+
+	cfg := map[string]interface{}{
+		"log":       map[string]interface{}{"loglevel": "none"},
+		"inbounds":  []interface{}{},
+		"outbounds": []interface{}{map[string]interface{}{"tag": "direct", "protocol": "freedom"}},
+	}
+	data, _ := json.Marshal(cfg)
+	require.NoError(t, c.Start(data))
+	defer c.Stop()
+
+	require.NoError(t, c.AddInbound(inbound))
+	assert.Error(t, c.AddInbound(inbound), "tags are unique")
+	assert.Error(t, c.AddInbound([]byte(`{"tag":"bad-in","port":0,"protocol":"nope"}`)))
+
+	require.NoError(t, c.RemoveInbound("test-in"))
+	assert.Error(t, c.RemoveInbound("test-in"))
+}
+
+func TestCore_CloseInboundsKeepsConnections(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	port, err := freePort()
+	require.NoError(t, err)
+	echoAddr := echo.Addr().(*net.TCPAddr)
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "forward-in",
+				"listen":   "127.0.0.1",
+				"port":     port,
+				"protocol": "dokodemo-door",
+				"settings": map[string]interface{}{"address": "127.0.0.1", "port": echoAddr.Port, "network": "tcp"},
+			},
+		},
+		"outbounds": []interface{}{map[string]interface{}{"tag": "direct", "protocol": "freedom"}},
+	}
+	data, _ := json.Marshal(cfg)
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+	assert.Error(t, c.CloseInbounds(), "closing requires a running core")
+	require.NoError(t, c.Start(data))
+	defer c.Stop()
+
+	inboundAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	conn, err := net.Dial("tcp", inboundAddr)
+	require.NoError(t, err)
+	defer conn.Close()
+	roundTrip := func(msg string) {
+		t.Helper()
+		_, err := io.WriteString(conn, msg)
+		require.NoError(t, err)
+		reply := make([]byte, len(msg))
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		assert.Equal(t, msg, string(reply))
+	}
+	roundTrip("before")
+
+	require.NoError(t, c.CloseInbounds())
+
+	_, err = net.Dial("tcp", inboundAddr)
+	assert.Error(t, err, "the listener is closed")
+	roundTrip("after")
+}
+
+func TestCore_RestartInboundKeepsUsers(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"listen":   "127.0.0.1",
+				"port":     0,
+				"protocol": "vless",
+				"settings": map[string]interface{}{"clients": []interface{}{}, "decryption": "none"},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+	require.NoError(t, c.Start(data))
+	defer c.Stop()
+
+	users := NewUserManager(c.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), log)
+	ctx := context.Background()
+	require.NoError(t, users.AddUser(ctx, "vless-in", BuildVlessUser("alice", "550e8400-e29b-41d4-a716-446655440000", "", 0)))
+
+	require.NoError(t, c.RestartInbound(data, "vless-in"))
+	emails, err := users.GetUsers(ctx, "vless-in")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, emails)
+
+	// A reload resets the users to the config.
+	require.NoError(t, c.ReloadInbounds(data, []string{"vless-in"}))
+	emails, err = users.GetUsers(ctx, "vless-in")
+	require.NoError(t, err)
+	assert.Empty(t, emails)
+}
+
+func TestCore_RestartKeepingUsers(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"listen":   "127.0.0.1",
+				"port":     0,
+				"protocol": "vless",
+				"settings": map[string]interface{}{"clients": []interface{}{}, "decryption": "none"},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+		},
+	}
+	data, _ := json.Marshal(cfg)
+	require.NoError(t, c.Start(data))
+	defer c.Stop()
+	assert.Equal(t, os.Getpid(), c.ProcessID())
+
+	users := NewUserManager(c.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), log)
+	ctx := context.Background()
+	require.NoError(t, users.AddUser(ctx, "vless-in", BuildVlessUser("alice", "550e8400-e29b-41d4-a716-446655440000", "", 0)))
+
+	require.NoError(t, c.RestartKeepingUsers(data))
+	assert.Equal(t, 1, c.StartInfo().Restarts)
+
+	users = NewUserManager(c.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), log)
+	emails, err := users.GetUsers(ctx, "vless-in")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, emails)
+
+	require.NoError(t, c.Stop())
+	assert.Zero(t, c.ProcessID())
+}
+
+func TestCore_DialOutbound(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "hello")
+	}()
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c := NewCore(log)
+	_, err = c.DialOutbound(context.Background(), "direct", "tcp", listener.Addr().String())
+	assert.EqualError(t, err, "xray is not running")
+
+	require.NoError(t, c.Start(makeMinimalConfig()))
+	defer c.Stop()
+
+	_, err = c.DialOutbound(context.Background(), "missing", "tcp", listener.Addr().String())
+	assert.ErrorIs(t, err, ErrOutboundNotFound)
+
+	conn, err := c.DialOutbound(context.Background(), "direct", "tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestNormalizeSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"10.0.0.1", "10.0.0.1", false},
+		{"10.0.0.5/24", "10.0.0.0/24", false},
+		{"2001:db8::1", "2001:db8::1", false},
+		{"2001:db8::1/64", "2001:db8::/64", false},
+		{"2001:DB8::1/128", "2001:db8::1", false},
+		{"::ffff:10.0.0.1", "10.0.0.1", false},
+		{"::ffff:10.1.2.3/120", "10.1.2.0/24", false},
+		{"::/0", "::/0", false},
+		{"not-an-ip", "", true},
+		{"10.0.0.0/40", "", true},
+		{"2001:db8::/129", "", true},
+		{"fe80::1%eth0", "", true},
+		{"10.0.0.1,10.0.0.2", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeSource(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestNormalizeSources(t *testing.T) {
+	got, err := NormalizeSources(" 2001:db8::/32,10.0.0.5/8, 192.0.2.1,,::ffff:10.0.0.0/104 ,2001:db8::1")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8,192.0.2.1,2001:db8::/32,2001:db8::1", got)
+
+	_, err = NormalizeSources("10.0.0.1,not-an-ip")
+	assert.Error(t, err)
+	_, err = NormalizeSources(" , ")
+	assert.Error(t, err)
+}
+
+func TestParseSourceCIDRs(t *testing.T) {
+	cidrs, err := parseSourceCIDRs("10.0.0.1")
+	require.NoError(t, err)
+	require.Len(t, cidrs, 1)
+	assert.Equal(t, uint32(32), cidrs[0].Prefix)
+	assert.Len(t, cidrs[0].Ip, 4)
+
+	cidrs, err = parseSourceCIDRs("2001:db8::/48,10.1.0.0/16")
+	require.NoError(t, err)
+	require.Len(t, cidrs, 2)
+	assert.Equal(t, uint32(16), cidrs[0].Prefix)
+	assert.Len(t, cidrs[0].Ip, 4)
+	assert.Equal(t, uint32(48), cidrs[1].Prefix)
+	assert.Len(t, cidrs[1].Ip, 16)
+
+	// The prefix length of IPv4-mapped ranges applies to the IPv4 address.
+	cidrs, err = parseSourceCIDRs("::ffff:192.0.2.0/120")
+	require.NoError(t, err)
+	assert.Equal(t, []byte{192, 0, 2, 0}, cidrs[0].Ip)
+	assert.Equal(t, uint32(24), cidrs[0].Prefix)
+}
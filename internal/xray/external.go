@@ -0,0 +1,455 @@
+package xray
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	appstats "github.com/xtls/xray-core/app/stats"
+	statscmd "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+const (
+	// externalStartTimeout bounds the wait for the API of a started xray.
+	externalStartTimeout = 15 * time.Second
+	// externalStopTimeout is how long xray gets to exit after an interrupt
+	// before it is killed.
+	externalStopTimeout = 5 * time.Second
+	// externalSyncInterval is how often the counters of xray are mirrored.
+	externalSyncInterval = 2 * time.Second
+	// outputTailLines is how many lines of xray output a failed start
+	// reports.
+	outputTailLines = 10
+)
+
+// ErrXrayVersion is returned for an xray binary of another version than
+// the one required.
+var ErrXrayVersion = errors.New("unsupported xray version")
+
+// ExternalOptions configures a core running xray as a child process.
+type ExternalOptions struct {
+	// Binary is the xray executable, looked up in PATH if it has no
+	// slash. Defaults to "xray".
+	Binary string
+	// Version, if set, is the version Binary must have, e.g. "25.1" for
+	// any 25.1.x release.
+	Version string
+}
+
+type externalBinary struct {
+	path    string
+	version string
+}
+
+// externalProcess is a running xray child process and the API connection
+// to it.
+type externalProcess struct {
+	cmd    *exec.Cmd
+	output *processOutput
+	conn   *grpc.ClientConn
+	stats  *appstats.Manager
+
+	// done is closed once the process exited, with err its exit status.
+	done chan struct{}
+	err  error
+
+	stopping atomic.Bool
+	stopSync chan struct{}
+	syncWg   sync.WaitGroup
+}
+
+// NewExternalCore creates a stopped core that runs xray as a separate
+// process of the binary in opts and drives it through its gRPC API, so
+// that xray can be upgraded without the node and a crash of xray leaves
+// the node API up.
+//
+// Features fed by the access log of xray (access and auth failure hooks)
+// are not available with an external xray.
+func NewExternalCore(log *logger.Logger, opts ExternalOptions) (*Core, error) {
+	binary := opts.Binary
+	if binary == "" {
+		binary = "xray"
+	}
+
+	version, err := binaryVersion(binary)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Version != "" && version != opts.Version && !strings.HasPrefix(version, opts.Version+".") {
+		return nil, fmt.Errorf("%w: %s is %s, %s required", ErrXrayVersion, binary, version, opts.Version)
+	}
+
+	// The node still builds parts of configs with its own xray-core.
+	captureLogs(log)
+	log.WithField("binary", binary).WithField("version", version).Info("Using external xray")
+
+	return &Core{
+		logger:   log,
+		external: &externalBinary{path: binary, version: version},
+	}, nil
+}
+
+// IsExternal reports whether xray runs as a separate process.
+func (c *Core) IsExternal() bool {
+	return c.external != nil
+}
+
+// ProcessID returns the PID of the process running xray: the node itself
+// for the embedded core, the child process for an external one. It is 0
+// while the core is stopped.
+func (c *Core) ProcessID() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch {
+	case !c.running:
+		return 0
+	case c.process != nil:
+		return c.process.cmd.Process.Pid
+	default:
+		return os.Getpid()
+	}
+}
+
+// binaryVersion returns the version printed by "xray version", e.g.
+// "25.1.30" for "Xray 25.1.30 (Xray, Penetrates Everything.) ...".
+func binaryVersion(binary string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, binary, "version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s version: %w", binary, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "Xray" {
+		line, _, _ := strings.Cut(string(out), "\n")
+		return "", fmt.Errorf("unexpected output of %s version: %q", binary, line)
+	}
+	return fields[1], nil
+}
+
+// startExternal runs configJSON in a new xray process. c.mu must be held.
+func (c *Core) startExternal(configJSON []byte) error {
+	configJSON, apiAddress, err := externalConfig(configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	output := newProcessOutput(c.logger.WithField("source", "xray"))
+	cmd := exec.Command(c.external.path, "run", "-format", "json", "-config", "stdin:")
+	cmd.Stdin = bytes.NewReader(configJSON)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	setProcessAttrs(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start xray: %w", err)
+	}
+
+	p := &externalProcess{
+		cmd:      cmd,
+		output:   output,
+		done:     make(chan struct{}),
+		stopSync: make(chan struct{}),
+	}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+
+	instance, err := p.connect(apiAddress)
+	if err != nil {
+		p.kill()
+		return fmt.Errorf("failed to start xray: %w", err)
+	}
+
+	p.syncWg.Add(1)
+	go c.syncExternal(p)
+	go c.watchExternal(p)
+
+	c.instance = instance
+	c.process = p
+	c.running = true
+	c.recordStartLocked()
+	c.logger.WithField("pid", cmd.Process.Pid).Info("xray started successfully")
+
+	return nil
+}
+
+// connect waits for the API of the process at address and returns the
+// instance serving its features.
+func (p *externalProcess) connect(address string) (*core.Instance, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to API: %w", err)
+	}
+
+	client := statscmd.NewStatsServiceClient(conn)
+	deadline := time.After(externalStartTimeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err = client.GetSysStats(ctx, &statscmd.SysStatsRequest{})
+		cancel()
+		if err == nil {
+			break
+		}
+
+		select {
+		case <-p.done:
+			conn.Close()
+			return nil, fmt.Errorf("xray exited: %v%s", p.err, p.output.tail())
+		case <-deadline:
+			conn.Close()
+			return nil, fmt.Errorf("API at %s not ready: %w", address, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	stats, err := appstats.NewManager(context.Background(), &appstats.Config{})
+	if err == nil {
+		err = stats.Start()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stats manager: %w", err)
+	}
+	instance, err := newRemoteInstance(conn, stats)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	p.conn = conn
+	p.stats = stats
+	return instance, nil
+}
+
+// syncExternal mirrors the stats of p until it is stopped.
+func (c *Core) syncExternal(p *externalProcess) {
+	defer p.syncWg.Done()
+
+	client := statscmd.NewStatsServiceClient(p.conn)
+	ticker := time.NewTicker(externalSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopSync:
+			return
+		case <-ticker.C:
+			if err := syncStats(context.Background(), client, p.stats); err != nil && !p.stopping.Load() {
+				c.logger.WithError(err).Debug("Failed to sync xray stats")
+			}
+		}
+	}
+}
+
+// watchExternal reports an exit of p the node did not ask for as a failed
+// start: the node is left without a running core, as after a failed
+// restart.
+func (c *Core) watchExternal(p *externalProcess) {
+	<-p.done
+	if p.stopping.Load() {
+		return
+	}
+
+	c.mu.Lock()
+	if c.process != p {
+		c.mu.Unlock()
+		return
+	}
+	p.stopping.Store(true)
+	close(p.stopSync)
+	p.syncWg.Wait()
+	p.conn.Close()
+	c.instance.Close()
+	c.instance = nil
+	c.process = nil
+	c.running = false
+	c.startedAt = time.Time{}
+	err := fmt.Errorf("xray exited unexpectedly: %v%s", p.err, p.output.tail())
+	c.recordFailureLocked(err)
+	c.mu.Unlock()
+
+	c.logger.WithError(err).Error("xray stopped")
+
+	c.hooksMu.RLock()
+	hooks := append([]func(error){}, c.onFailedHooks...)
+	c.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(err)
+	}
+}
+
+// stopExternal stops p, mirroring its last stats first. c.mu must be held.
+func (c *Core) stopExternal(p *externalProcess) {
+	p.stopping.Store(true)
+	close(p.stopSync)
+	p.syncWg.Wait()
+
+	// Traffic since the last sync would be lost with the process.
+	if err := syncStats(context.Background(), statscmd.NewStatsServiceClient(p.conn), p.stats); err != nil {
+		c.logger.WithError(err).Warn("Failed to sync xray stats before stop")
+	}
+	p.conn.Close()
+
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+		p.kill()
+		return
+	}
+	select {
+	case <-p.done:
+	case <-time.After(externalStopTimeout):
+		c.logger.Warn("xray did not exit in time, killing it")
+		p.kill()
+	}
+}
+
+func (p *externalProcess) kill() {
+	p.stopping.Store(true)
+	p.cmd.Process.Kill()
+	<-p.done
+}
+
+// externalConfig points the API of configJSON at a free local port,
+// enabling the services the node drives xray through, and returns the
+// config and the API address. The API tag of the config, if any, is kept;
+// routing to it through an inbound is not possible while the API listens
+// on its own.
+func externalConfig(configJSON []byte) ([]byte, string, error) {
+	var config map[string]interface{}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, "", err
+	}
+
+	api, _ := config["api"].(map[string]interface{})
+	if api == nil {
+		api = map[string]interface{}{}
+	}
+	if tag, _ := api["tag"].(string); tag == "" {
+		api["tag"] = "REMNANODE_API"
+	}
+
+	services, _ := api["services"].([]interface{})
+	for _, required := range []string{"HandlerService", "StatsService", "RoutingService"} {
+		found := false
+		for _, s := range services {
+			if name, _ := s.(string); strings.EqualFold(name, required) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			services = append(services, required)
+		}
+	}
+	api["services"] = services
+
+	address, _ := api["listen"].(string)
+	if address == "" {
+		port, err := freePort()
+		if err != nil {
+			return nil, "", err
+		}
+		address = net.JoinHostPort("127.0.0.1", fmt.Sprint(port))
+		api["listen"] = address
+	}
+	config["api"] = api
+
+	if _, ok := config["stats"]; !ok {
+		config["stats"] = map[string]interface{}{}
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, address, nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port for the API: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// processOutput logs the output of xray line by line, at the level of its
+// severity, and keeps the last lines for error reports.
+type processOutput struct {
+	log *logger.Logger
+
+	mu    sync.Mutex
+	buf   []byte
+	lines []string
+}
+
+func newProcessOutput(log *logger.Logger) *processOutput {
+	return &processOutput{log: log}
+}
+
+func (o *processOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.buf = append(o.buf, p...)
+	for {
+		i := bytes.IndexByte(o.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(o.buf[:i]), "\r")
+		o.buf = o.buf[i+1:]
+		if line != "" {
+			o.writeLine(line)
+		}
+	}
+	return len(p), nil
+}
+
+func (o *processOutput) writeLine(line string) {
+	o.lines = append(o.lines, line)
+	if len(o.lines) > outputTailLines {
+		o.lines = o.lines[1:]
+	}
+
+	// Lines look like "2025/01/02 15:04:05.000000 [Warning] message".
+	switch {
+	case strings.Contains(line, "[Error]"):
+		o.log.Error(line)
+	case strings.Contains(line, "[Warning]"):
+		o.log.Warn(line)
+	case strings.Contains(line, "[Debug]"):
+		o.log.Debug(line)
+	default:
+		o.log.Info(line)
+	}
+}
+
+// tail returns the last lines of output, for appending to an error.
+func (o *processOutput) tail() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.lines) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(o.lines, "; ")
+}
@@ -0,0 +1,12 @@
+package xray
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessAttrs has the kernel terminate xray if the node dies, so a
+// crashed node does not leave xray holding its ports.
+func setProcessAttrs(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package xray
+
+import "os/exec"
+
+// setProcessAttrs does nothing: only Linux can tie the lifetime of xray to
+// the node.
+func setProcessAttrs(*exec.Cmd) {}
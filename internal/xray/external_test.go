@@ -0,0 +1,205 @@
+package xray
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appstats "github.com/xtls/xray-core/app/stats"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/stats"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// TestMain lets the test binary stand in for the xray binary of an
+// external core when XRAY_TEST_HELPER is set.
+func TestMain(m *testing.M) {
+	if os.Getenv("XRAY_TEST_HELPER") == "1" && len(os.Args) > 1 {
+		os.Exit(runXrayHelper(os.Args[1]))
+	}
+	os.Exit(m.Run())
+}
+
+func runXrayHelper(command string) int {
+	switch command {
+	case "version":
+		fmt.Println("Xray 99.1.2 (test helper)")
+		return 0
+	case "run":
+		config, err := core.LoadConfig("json", os.Stdin)
+		if err != nil {
+			fmt.Println("[Error] failed to load config:", err)
+			return 23
+		}
+		instance, err := core.New(config)
+		if err == nil {
+			err = instance.Start()
+		}
+		if err != nil {
+			fmt.Println("[Error] failed to start:", err)
+			return 23
+		}
+		fmt.Println("[Warning] core: Xray 99.1.2 started")
+
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		instance.Close()
+		return 0
+	default:
+		return 2
+	}
+}
+
+func newTestExternalCore(t *testing.T) *Core {
+	t.Helper()
+	t.Setenv("XRAY_TEST_HELPER", "1")
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	c, err := NewExternalCore(log, ExternalOptions{Binary: os.Args[0]})
+	require.NoError(t, err)
+	return c
+}
+
+func makeExternalConfig() []byte {
+	cfg := map[string]interface{}{
+		"log": map[string]interface{}{"loglevel": "none"},
+		"policy": map[string]interface{}{
+			"system": map[string]interface{}{"statsInboundUplink": true, "statsInboundDownlink": true},
+		},
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"listen":   "127.0.0.1",
+				"port":     0,
+				"protocol": "vless",
+				"settings": map[string]interface{}{"clients": []interface{}{}, "decryption": "none"},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"tag": "direct", "protocol": "freedom"},
+			map[string]interface{}{"tag": "blocked", "protocol": "blackhole"},
+		},
+		"routing": map[string]interface{}{"rules": []interface{}{}},
+	}
+	data, _ := json.Marshal(cfg)
+	return data
+}
+
+func TestNewExternalCore_Version(t *testing.T) {
+	t.Setenv("XRAY_TEST_HELPER", "1")
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+
+	c, err := NewExternalCore(log, ExternalOptions{Binary: os.Args[0], Version: "99.1"})
+	require.NoError(t, err)
+	assert.Equal(t, "99.1.2", c.GetVersion())
+	assert.False(t, c.IsRunning())
+
+	_, err = NewExternalCore(log, ExternalOptions{Binary: os.Args[0], Version: "99.1.2"})
+	assert.NoError(t, err)
+
+	_, err = NewExternalCore(log, ExternalOptions{Binary: os.Args[0], Version: "99.10"})
+	assert.ErrorIs(t, err, ErrXrayVersion)
+
+	_, err = NewExternalCore(log, ExternalOptions{Binary: "/nonexistent/xray"})
+	assert.Error(t, err)
+}
+
+func TestExternalCore_StartStop(t *testing.T) {
+	c := newTestExternalCore(t)
+
+	require.NoError(t, c.Start(makeExternalConfig()))
+	assert.True(t, c.IsRunning())
+	require.NotNil(t, c.Instance())
+
+	ctx := context.Background()
+	users := NewUserManager(c.Instance().GetFeature(inbound.ManagerType()).(inbound.Manager), c.logger)
+	require.NoError(t, users.AddUser(ctx, "vless-in", BuildVlessUser("alice", "550e8400-e29b-41d4-a716-446655440000", "", 0)))
+	emails, err := users.GetUsers(ctx, "vless-in")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, emails)
+	require.NoError(t, users.RemoveUser(ctx, "vless-in", "alice"))
+	emails, err = users.GetUsers(ctx, "vless-in")
+	require.NoError(t, err)
+	assert.Empty(t, emails)
+
+	require.NoError(t, c.AddRoutingRule("block-test", "203.0.113.7", "blocked"))
+	rules, err := c.ListRoutingRules()
+	require.NoError(t, err)
+	assert.Contains(t, rules, RoutingRule{RuleTag: "block-test", OutboundTag: "blocked"})
+	require.NoError(t, c.RemoveRoutingRule("block-test"))
+
+	require.NoError(t, c.AddInbound([]byte(`{"tag":"socks-in","listen":"127.0.0.1","port":0,"protocol":"socks"}`)))
+	require.NoError(t, c.RemoveInbound("socks-in"))
+	assert.True(t, c.HasOutbound("blocked"))
+
+	// The counters of xray show up in the local stats manager.
+	stm := c.Instance().GetFeature(stats.ManagerType()).(*appstats.Manager)
+	assert.Eventually(t, func() bool {
+		return stm.GetCounter("inbound>>>vless-in>>>traffic>>>uplink") != nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	require.NoError(t, c.Stop())
+	assert.False(t, c.IsRunning())
+	assert.Nil(t, c.Instance())
+}
+
+func TestExternalCore_StartFailure(t *testing.T) {
+	c := newTestExternalCore(t)
+
+	err := c.Start([]byte(`{"inbounds":[{"tag":"bad","port":0,"protocol":"nope"}]}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load config")
+	assert.False(t, c.IsRunning())
+}
+
+func TestExternalCore_ExitFiresFailedHook(t *testing.T) {
+	c := newTestExternalCore(t)
+
+	failed := make(chan error, 1)
+	c.OnStartFailed(func(err error) { failed <- err })
+
+	require.NoError(t, c.Start(makeExternalConfig()))
+	c.mu.RLock()
+	process := c.process
+	c.mu.RUnlock()
+	require.NoError(t, process.cmd.Process.Kill())
+
+	select {
+	case err := <-failed:
+		assert.Contains(t, err.Error(), "exited unexpectedly")
+	case <-time.After(10 * time.Second):
+		t.Fatal("failed hook not called")
+	}
+	assert.False(t, c.IsRunning())
+	assert.Nil(t, c.Instance())
+	assert.NoError(t, c.Stop())
+}
+
+func TestExternalConfig(t *testing.T) {
+	data, address, err := externalConfig([]byte(`{"api":{"tag":"PANEL_API","services":["statsservice"]}}`))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(address, "127.0.0.1:"))
+
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &config))
+	api := config["api"].(map[string]interface{})
+	assert.Equal(t, "PANEL_API", api["tag"])
+	assert.Equal(t, address, api["listen"])
+	assert.Equal(t, []interface{}{"statsservice", "HandlerService", "RoutingService"}, api["services"])
+	assert.NotNil(t, config["stats"])
+
+	_, address, err = externalConfig([]byte(`{"api":{"listen":"127.0.0.1:10085"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:10085", address)
+}
@@ -0,0 +1,74 @@
+package xray
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	applog "github.com/xtls/xray-core/app/log"
+	"github.com/xtls/xray-core/common"
+	xlog "github.com/xtls/xray-core/common/log"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+var (
+	registerLogHandler sync.Once
+	// logSink receives the console logs of xray, tagged with source=xray.
+	logSink atomic.Pointer[logger.Logger]
+)
+
+// captureLogs sends the console output of xray, its error log and its
+// access log when the config logs to the console, through log instead of
+// stdout. Logs written to files by the config are left alone. xray keeps
+// one log handler per process, so the last core created receives them.
+func captureLogs(log *logger.Logger) {
+	logSink.Store(log.WithField("source", "xray"))
+
+	registerLogHandler.Do(func() {
+		common.Must(applog.RegisterHandlerCreator(applog.LogType_Console,
+			func(applog.LogType, applog.HandlerCreatorOptions) (xlog.Handler, error) {
+				return logHandler{}, nil
+			}))
+	})
+}
+
+// logHandler forwards xray log messages to logSink.
+type logHandler struct{}
+
+func (logHandler) Handle(msg xlog.Message) {
+	log := logSink.Load()
+	if log == nil {
+		return
+	}
+
+	// With maskAddress set the message comes wrapped; its String masks
+	// the addresses, the wrapped message tells the kind.
+	text := msg.String()
+	inner := msg
+	if masked, ok := msg.(*applog.MaskedMsgWrapper); ok {
+		inner = masked.Message
+	}
+
+	switch m := inner.(type) {
+	case *xlog.AccessMessage:
+		log.WithField("type", "access").Info(text)
+	case *xlog.DNSLog:
+		log.WithField("type", "dns").Info(text)
+	case *xlog.GeneralMessage:
+		text = strings.TrimPrefix(text, "["+m.Severity.String()+"] ")
+		log = log.WithField("type", "error")
+		switch m.Severity {
+		case xlog.Severity_Error:
+			log.Error(text)
+		case xlog.Severity_Warning:
+			log.Warn(text)
+		case xlog.Severity_Debug:
+			log.Debug(text)
+		default:
+			log.Info(text)
+		}
+	default:
+		log.Info(text)
+	}
+}
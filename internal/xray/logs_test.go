@@ -0,0 +1,83 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	applog "github.com/xtls/xray-core/app/log"
+	xlog "github.com/xtls/xray-core/common/log"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+type capturedLog struct {
+	Level   string `json:"level"`
+	Source  string `json:"source"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func readCapturedLogs(t *testing.T, buf *bytes.Buffer) []capturedLog {
+	t.Helper()
+
+	var entries []capturedLog
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry capturedLog
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry.Source == "xray" {
+			entries = append(entries, entry)
+		}
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestCaptureLogs_CoreOutput(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: logger.LevelDebug, Format: logger.FormatJSON, Output: &buf})
+	c := NewCore(log)
+
+	config := []byte(`{"log":{"loglevel":"warning"},"inbounds":[],"outbounds":[{"tag":"direct","protocol":"freedom"}]}`)
+	require.NoError(t, c.Start(config))
+	require.NoError(t, c.Stop())
+
+	var started bool
+	for _, entry := range readCapturedLogs(t, &buf) {
+		assert.Equal(t, "error", entry.Type)
+		assert.False(t, strings.HasPrefix(entry.Message, "["), entry.Message)
+		if strings.Contains(entry.Message, "started") {
+			started = true
+			assert.Equal(t, "warn", entry.Level)
+		}
+	}
+	assert.True(t, started, "xray start message not captured")
+}
+
+func TestCaptureLogs_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	captureLogs(logger.New(logger.Config{Level: logger.LevelInfo, Format: logger.FormatJSON, Output: &buf}))
+
+	handler := logHandler{}
+	handler.Handle(&xlog.GeneralMessage{Severity: xlog.Severity_Error, Content: "dial failed"})
+	handler.Handle(&xlog.GeneralMessage{Severity: xlog.Severity_Debug, Content: "hidden"})
+	handler.Handle(&applog.MaskedMsgWrapper{
+		Message: &xlog.AccessMessage{From: "203.0.113.10:5000", To: "tcp:example.com:443", Status: xlog.AccessAccepted, Email: "alice"},
+		Mask4:   16,
+		Mask6:   32,
+	})
+
+	entries := readCapturedLogs(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, capturedLog{Level: "error", Source: "xray", Type: "error", Message: "dial failed"}, entries[0])
+	assert.Equal(t, "access", entries[1].Type)
+	assert.Equal(t, "info", entries[1].Level)
+	assert.Contains(t, entries[1].Message, "email: alice")
+	assert.NotContains(t, entries[1].Message, "203.0.113.10")
+}
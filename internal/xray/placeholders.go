@@ -0,0 +1,191 @@
+package xray
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrUnresolvedPlaceholder is returned for a placeholder the node has no
+// value for.
+var ErrUnresolvedPlaceholder = errors.New("unresolved placeholder")
+
+// placeholderPattern matches "${NAME}" and "${ENV:NAME}", and the escaped
+// form "$${...}" standing for a literal "${...}".
+var placeholderPattern = regexp.MustCompile(`\$?\$\{(ENV:)?([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var placeholderNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Placeholders resolves the placeholders of a panel config from the node's
+// own settings, so one config template can serve nodes that differ in e.g.
+// their public IP or WARP license:
+//
+//   - ${NAME} is a node variable;
+//   - ${ENV:NAME} is the environment variable NAME, if allowed;
+//   - $${...} is a literal ${...}.
+//
+// Placeholders are replaced inside string values only. A placeholder the
+// node cannot resolve is an error rather than left in place, so a node that
+// lacks a variable refuses the config instead of running it broken.
+type Placeholders struct {
+	vars     map[string]string
+	envAllow []string
+	getenv   func(string) (string, bool)
+}
+
+// NewPlaceholders creates a resolver for vars. envAllow lists the
+// environment variables ${ENV:...} may read; an entry ending in "*" allows
+// every name with that prefix. None are readable by default, as the
+// environment holds the node's own secrets.
+func NewPlaceholders(vars map[string]string, envAllow []string) *Placeholders {
+	return &Placeholders{
+		vars:     vars,
+		envAllow: envAllow,
+		getenv:   os.LookupEnv,
+	}
+}
+
+// ParsePlaceholders creates a resolver from the node settings: vars in the
+// form "NAME=value,NAME=value" and envAllowlist as a comma-separated list.
+func ParsePlaceholders(vars, envAllowlist string) (*Placeholders, error) {
+	parsed := make(map[string]string)
+	for _, item := range strings.Split(vars, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !placeholderNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable %q", item)
+		}
+		parsed[name] = value
+	}
+
+	var envAllow []string
+	for _, item := range strings.Split(envAllowlist, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			envAllow = append(envAllow, item)
+		}
+	}
+
+	return NewPlaceholders(parsed, envAllow), nil
+}
+
+// Resolve returns a copy of config with its placeholders replaced. config
+// itself is left untouched. A nil Placeholders has no variables.
+func (p *Placeholders) Resolve(config map[string]interface{}) (map[string]interface{}, error) {
+	resolved, err := p.resolveValue(config)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := resolved.(map[string]interface{})
+	return result, nil
+}
+
+// ResolveJSON replaces the placeholders of a JSON value.
+func (p *Placeholders) ResolveJSON(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	resolved, err := p.resolveValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(resolved)
+}
+
+func (p *Placeholders) resolveValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			r, err := p.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			r, err := p.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	case string:
+		return p.resolveString(v)
+	default:
+		return value, nil
+	}
+}
+
+func (p *Placeholders) resolveString(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var resolveErr error
+	resolved := placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		if resolveErr != nil {
+			return match
+		}
+
+		groups := placeholderPattern.FindStringSubmatch(match)
+		value, err := p.lookup(groups[2], groups[1] != "")
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return value
+	})
+	return resolved, resolveErr
+}
+
+func (p *Placeholders) lookup(name string, fromEnv bool) (string, error) {
+	if p == nil {
+		return "", fmt.Errorf("%w: %s", ErrUnresolvedPlaceholder, name)
+	}
+
+	if !fromEnv {
+		if value, ok := p.vars[name]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("%w: node variable %s is not set", ErrUnresolvedPlaceholder, name)
+	}
+
+	if !p.envAllowed(name) {
+		return "", fmt.Errorf("%w: environment variable %s is not allowed", ErrUnresolvedPlaceholder, name)
+	}
+	if value, ok := p.getenv(name); ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w: environment variable %s is not set", ErrUnresolvedPlaceholder, name)
+}
+
+func (p *Placeholders) envAllowed(name string) bool {
+	for _, allowed := range p.envAllow {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if allowed == name {
+			return true
+		}
+	}
+	return false
+}
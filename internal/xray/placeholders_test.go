@@ -0,0 +1,90 @@
+package xray
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPlaceholders(t *testing.T, vars, envAllowlist string, env map[string]string) *Placeholders {
+	t.Helper()
+	p, err := ParsePlaceholders(vars, envAllowlist)
+	require.NoError(t, err)
+	p.getenv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+	return p
+}
+
+func TestPlaceholders_Resolve(t *testing.T) {
+	p := newTestPlaceholders(t, "NODE_PUBLIC_IP=203.0.113.7, SNI=example.com", "WARP_*,REGION", map[string]string{
+		"WARP_LICENSE": "abc-123",
+		"SECRET_KEY":   "secret",
+	})
+
+	config := map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"listen": "${NODE_PUBLIC_IP}",
+				"port":   443.0,
+				"streamSettings": map[string]interface{}{
+					"serverNames": []interface{}{"${SNI}", "www.${SNI}"},
+				},
+			},
+		},
+		"outbounds": []interface{}{
+			map[string]interface{}{"license": "${ENV:WARP_LICENSE}", "note": "$${SNI} stays"},
+		},
+	}
+
+	resolved, err := p.Resolve(config)
+	require.NoError(t, err)
+
+	inbound := resolved["inbounds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "203.0.113.7", inbound["listen"])
+	assert.Equal(t, 443.0, inbound["port"])
+	assert.Equal(t, []interface{}{"example.com", "www.example.com"}, inbound["streamSettings"].(map[string]interface{})["serverNames"])
+	outbound := resolved["outbounds"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "abc-123", outbound["license"])
+	assert.Equal(t, "${SNI} stays", outbound["note"])
+
+	assert.Equal(t, "${NODE_PUBLIC_IP}", config["inbounds"].([]interface{})[0].(map[string]interface{})["listen"], "the input is left untouched")
+}
+
+func TestPlaceholders_Unresolved(t *testing.T) {
+	p := newTestPlaceholders(t, "", "WARP_*", map[string]string{"SECRET_KEY": "secret"})
+
+	for _, value := range []string{"${MISSING}", "${ENV:SECRET_KEY}", "${ENV:WARP_LICENSE}"} {
+		_, err := p.Resolve(map[string]interface{}{"value": value})
+		assert.ErrorIs(t, err, ErrUnresolvedPlaceholder, value)
+	}
+
+	var nilPlaceholders *Placeholders
+	resolved, err := nilPlaceholders.Resolve(map[string]interface{}{"value": "plain"})
+	require.NoError(t, err)
+	assert.Equal(t, "plain", resolved["value"])
+	_, err = nilPlaceholders.Resolve(map[string]interface{}{"value": "${NODE_PUBLIC_IP}"})
+	assert.ErrorIs(t, err, ErrUnresolvedPlaceholder)
+}
+
+func TestPlaceholders_ResolveJSON(t *testing.T) {
+	p := newTestPlaceholders(t, "IP=203.0.113.7", "", nil)
+
+	resolved, err := p.ResolveJSON(json.RawMessage(`{"listen":"${IP}","port":443}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"listen":"203.0.113.7","port":443}`, string(resolved))
+
+	resolved, err = p.ResolveJSON(nil)
+	require.NoError(t, err)
+	assert.Empty(t, resolved)
+}
+
+func TestParsePlaceholders_Invalid(t *testing.T) {
+	for _, vars := range []string{"NO_VALUE", "1BAD=x", "=x"} {
+		_, err := ParsePlaceholders(vars, "")
+		assert.Error(t, err, vars)
+	}
+}
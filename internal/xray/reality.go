@@ -0,0 +1,30 @@
+package xray
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateRealityKeys returns a new x25519 key pair in the encoding
+// `xray x25519` uses for REALITY privateKey and publicKey settings.
+func GenerateRealityKeys() (privateKey, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate x25519 key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// GenerateShortID returns a random REALITY shortId of the maximum length,
+// 16 hex digits.
+func GenerateShortID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate short id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,31 @@
+package xray
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRealityKeys(t *testing.T) {
+	privateKey, publicKey, err := GenerateRealityKeys()
+	require.NoError(t, err)
+
+	raw, err := base64.RawURLEncoding.DecodeString(privateKey)
+	require.NoError(t, err)
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()))
+
+	otherKey, _, err := GenerateRealityKeys()
+	require.NoError(t, err)
+	assert.NotEqual(t, privateKey, otherKey)
+}
+
+func TestGenerateShortID(t *testing.T) {
+	shortID, err := GenerateShortID()
+	require.NoError(t, err)
+	assert.Regexp(t, `^[0-9a-f]{16}$`, shortID)
+}
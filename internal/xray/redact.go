@@ -0,0 +1,71 @@
+package xray
+
+// RedactedValue replaces secrets in a redacted config.
+const RedactedValue = "[REDACTED]"
+
+// secretFields are config keys holding credentials or key material wherever
+// they appear: reality and wireguard keys, TLS keys, proxy passwords.
+var secretFields = map[string]struct{}{
+	"privateKey":   {},
+	"secretKey":    {},
+	"preSharedKey": {},
+	"key":          {},
+	"keyFile":      {},
+	"password":     {},
+	"pass":         {},
+	"seed":         {},
+}
+
+// RedactConfig returns a copy of an xray config with credentials and key
+// material replaced by RedactedValue. The input is left untouched.
+func RedactConfig(config map[string]interface{}) map[string]interface{} {
+	return redactValue(config, false).(map[string]interface{})
+}
+
+func redactValue(value interface{}, inClients bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			result[key] = redactField(key, field, inClients)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item, inClients)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+func redactField(key string, value interface{}, inClients bool) interface{} {
+	if _, secret := secretFields[key]; secret && isSet(value) {
+		return RedactedValue
+	}
+	if key == "decryption" && value != "none" && isSet(value) {
+		return RedactedValue
+	}
+	// The ID of a vless or vmess client is its credential.
+	if inClients && key == "id" && isSet(value) {
+		return RedactedValue
+	}
+	return redactValue(value, key == "clients")
+}
+
+// isSet reports whether a field holds something worth hiding, so empty
+// values keep showing that nothing is configured.
+func isSet(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,75 @@
+package xray
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const redactTestConfig = `{
+	"inbounds": [
+		{
+			"tag": "vless-in",
+			"protocol": "vless",
+			"settings": {
+				"clients": [{"id": "550e8400-e29b-41d4-a716-446655440000", "email": "alice", "flow": "xtls-rprx-vision"}],
+				"decryption": "none"
+			},
+			"streamSettings": {
+				"security": "reality",
+				"realitySettings": {"privateKey": "reality-private-key", "shortIds": ["abcd"]}
+			}
+		},
+		{
+			"tag": "trojan-in",
+			"protocol": "trojan",
+			"settings": {"clients": [{"password": "trojan-secret", "email": "bob"}]},
+			"streamSettings": {
+				"tlsSettings": {"certificates": [{"certificateFile": "/etc/cert.pem", "keyFile": "/etc/key.pem"}]}
+			}
+		},
+		{
+			"tag": "socks-in",
+			"protocol": "socks",
+			"settings": {"accounts": [{"user": "carol", "pass": "socks-secret"}], "password": ""}
+		}
+	],
+	"outbounds": [{"protocol": "wireguard", "settings": {"secretKey": "wg-secret"}}]
+}`
+
+func TestRedactConfig(t *testing.T) {
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(redactTestConfig), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	redacted, err := json.Marshal(RedactConfig(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(redacted)
+
+	for _, secret := range []string{
+		"550e8400-e29b-41d4-a716-446655440000",
+		"reality-private-key",
+		"trojan-secret",
+		"/etc/key.pem",
+		"socks-secret",
+		"wg-secret",
+	} {
+		if strings.Contains(out, secret) {
+			t.Errorf("redacted config still contains %q", secret)
+		}
+	}
+
+	for _, kept := range []string{`"email":"alice"`, `"decryption":"none"`, `"certificateFile":"/etc/cert.pem"`, `"user":"carol"`, `"password":""`, `"shortIds":["abcd"]`} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("redacted config lost %s", kept)
+		}
+	}
+
+	original, _ := json.Marshal(config)
+	if !strings.Contains(string(original), "reality-private-key") {
+		t.Error("RedactConfig modified its input")
+	}
+}
@@ -0,0 +1,389 @@
+package xray
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	handlercmd "github.com/xtls/xray-core/app/proxyman/command"
+	routercmd "github.com/xtls/xray-core/app/router/command"
+	appstats "github.com/xtls/xray-core/app/stats"
+	statscmd "github.com/xtls/xray-core/app/stats/command"
+	"github.com/xtls/xray-core/common/net"
+	"github.com/xtls/xray-core/common/protocol"
+	"github.com/xtls/xray-core/common/serial"
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/features/inbound"
+	"github.com/xtls/xray-core/features/outbound"
+	"github.com/xtls/xray-core/features/routing"
+	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/transport"
+	"github.com/xtls/xray-core/transport/internet/stat"
+	"google.golang.org/grpc"
+)
+
+// The features of an external xray process are served by its gRPC API,
+// wrapped in the feature interfaces of xray so the rest of the node reaches
+// them through Core.Instance() as it does the embedded core.
+
+// rpcTimeout bounds a call to the API of an external xray.
+const rpcTimeout = 10 * time.Second
+
+var errNotSupportedRemote = errors.New("not supported by an external xray")
+
+var (
+	_ proxy.GetInbound  = (*remoteInbound)(nil)
+	_ proxy.UserManager = (*remoteInbound)(nil)
+	_ inbound.Manager   = (*remoteInboundManager)(nil)
+	_ outbound.Manager  = (*remoteOutboundManager)(nil)
+	_ outbound.Handler  = remoteOutbound{}
+	_ routerWithRules   = (*remoteRouter)(nil)
+)
+
+func rpcContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, rpcTimeout)
+}
+
+// newRemoteInstance returns an instance holding the features of the xray
+// process behind conn. stats is the local mirror of its counters.
+func newRemoteInstance(conn *grpc.ClientConn, stats *appstats.Manager) (*core.Instance, error) {
+	instance := &core.Instance{}
+	handlers := handlercmd.NewHandlerServiceClient(conn)
+	for _, feature := range []interface {
+		Type() interface{}
+		Start() error
+		Close() error
+	}{
+		stats,
+		&remoteInboundManager{client: handlers},
+		&remoteOutboundManager{client: handlers},
+		&remoteRouter{client: routercmd.NewRoutingServiceClient(conn)},
+	} {
+		if err := instance.AddFeature(feature); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+// addInboundHandler adds an inbound to instance, through the API for an
+// external xray.
+func addInboundHandler(instance *core.Instance, config *core.InboundHandlerConfig) error {
+	if remote, ok := instance.GetFeature(inbound.ManagerType()).(*remoteInboundManager); ok {
+		ctx, cancel := rpcContext(context.Background())
+		defer cancel()
+		_, err := remote.client.AddInbound(ctx, &handlercmd.AddInboundRequest{Inbound: config})
+		return err
+	}
+	return core.AddInboundHandler(instance, config)
+}
+
+type remoteInboundManager struct {
+	client handlercmd.HandlerServiceClient
+}
+
+func (*remoteInboundManager) Type() interface{} { return inbound.ManagerType() }
+func (*remoteInboundManager) Start() error      { return nil }
+func (*remoteInboundManager) Close() error      { return nil }
+
+func (m *remoteInboundManager) tags(ctx context.Context) ([]string, error) {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	resp, err := m.client.ListInbounds(ctx, &handlercmd.ListInboundsRequest{IsOnlyTags: true})
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(resp.Inbounds))
+	for _, config := range resp.Inbounds {
+		tags = append(tags, config.Tag)
+	}
+	return tags, nil
+}
+
+func (m *remoteInboundManager) GetHandler(ctx context.Context, tag string) (inbound.Handler, error) {
+	tags, err := m.tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		if t == tag {
+			return &remoteInbound{tag: tag, client: m.client}, nil
+		}
+	}
+	return nil, fmt.Errorf("handler not found: %s", tag)
+}
+
+func (m *remoteInboundManager) AddHandler(context.Context, inbound.Handler) error {
+	return errNotSupportedRemote
+}
+
+func (m *remoteInboundManager) RemoveHandler(ctx context.Context, tag string) error {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	_, err := m.client.RemoveInbound(ctx, &handlercmd.RemoveInboundRequest{Tag: tag})
+	return err
+}
+
+func (m *remoteInboundManager) ListHandlers(ctx context.Context) []inbound.Handler {
+	tags, err := m.tags(ctx)
+	if err != nil {
+		return nil
+	}
+	handlers := make([]inbound.Handler, 0, len(tags))
+	for _, tag := range tags {
+		handlers = append(handlers, &remoteInbound{tag: tag, client: m.client})
+	}
+	return handlers
+}
+
+// remoteInbound is an inbound handler of an external xray, along with its
+// proxy and the users of the proxy.
+type remoteInbound struct {
+	tag    string
+	client handlercmd.HandlerServiceClient
+}
+
+func (h *remoteInbound) Tag() string                            { return h.tag }
+func (h *remoteInbound) Start() error                           { return nil }
+func (h *remoteInbound) Close() error                           { return nil }
+func (h *remoteInbound) ReceiverSettings() *serial.TypedMessage { return nil }
+func (h *remoteInbound) ProxySettings() *serial.TypedMessage    { return nil }
+
+// GetInbound implements proxy.GetInbound.
+func (h *remoteInbound) GetInbound() proxy.Inbound {
+	return h
+}
+
+func (h *remoteInbound) Network() []net.Network { return nil }
+
+func (h *remoteInbound) Process(context.Context, net.Network, stat.Connection, routing.Dispatcher) error {
+	return errNotSupportedRemote
+}
+
+func (h *remoteInbound) alter(ctx context.Context, operation *serial.TypedMessage) error {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	_, err := h.client.AlterInbound(ctx, &handlercmd.AlterInboundRequest{Tag: h.tag, Operation: operation})
+	return err
+}
+
+func (h *remoteInbound) AddUser(ctx context.Context, user *protocol.MemoryUser) error {
+	return h.alter(ctx, serial.ToTypedMessage(&handlercmd.AddUserOperation{User: protocol.ToProtoUser(user)}))
+}
+
+func (h *remoteInbound) RemoveUser(ctx context.Context, email string) error {
+	return h.alter(ctx, serial.ToTypedMessage(&handlercmd.RemoveUserOperation{Email: email}))
+}
+
+func (h *remoteInbound) users(ctx context.Context, email string) []*protocol.MemoryUser {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	resp, err := h.client.GetInboundUsers(ctx, &handlercmd.GetInboundUserRequest{Tag: h.tag, Email: email})
+	if err != nil {
+		return nil
+	}
+	users := make([]*protocol.MemoryUser, 0, len(resp.Users))
+	for _, user := range resp.Users {
+		if user == nil {
+			continue
+		}
+		if memoryUser, err := user.ToMemoryUser(); err == nil {
+			users = append(users, memoryUser)
+		}
+	}
+	return users
+}
+
+func (h *remoteInbound) GetUser(ctx context.Context, email string) *protocol.MemoryUser {
+	if email == "" {
+		return nil
+	}
+	if users := h.users(ctx, email); len(users) > 0 {
+		return users[0]
+	}
+	return nil
+}
+
+func (h *remoteInbound) GetUsers(ctx context.Context) []*protocol.MemoryUser {
+	return h.users(ctx, "")
+}
+
+func (h *remoteInbound) GetUsersCount(ctx context.Context) int64 {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	resp, err := h.client.GetInboundUsersCount(ctx, &handlercmd.GetInboundUserRequest{Tag: h.tag})
+	if err != nil {
+		return 0
+	}
+	return resp.Count
+}
+
+type remoteOutboundManager struct {
+	client handlercmd.HandlerServiceClient
+}
+
+func (*remoteOutboundManager) Type() interface{} { return outbound.ManagerType() }
+func (*remoteOutboundManager) Start() error      { return nil }
+func (*remoteOutboundManager) Close() error      { return nil }
+
+func (m *remoteOutboundManager) ListHandlers(ctx context.Context) []outbound.Handler {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	resp, err := m.client.ListOutbounds(ctx, &handlercmd.ListOutboundsRequest{})
+	if err != nil {
+		return nil
+	}
+	handlers := make([]outbound.Handler, 0, len(resp.Outbounds))
+	for _, config := range resp.Outbounds {
+		handlers = append(handlers, remoteOutbound{tag: config.Tag})
+	}
+	return handlers
+}
+
+func (m *remoteOutboundManager) GetHandler(tag string) outbound.Handler {
+	for _, handler := range m.ListHandlers(context.Background()) {
+		if handler.Tag() == tag {
+			return handler
+		}
+	}
+	return nil
+}
+
+func (m *remoteOutboundManager) GetDefaultHandler() outbound.Handler {
+	if handlers := m.ListHandlers(context.Background()); len(handlers) > 0 {
+		return handlers[0]
+	}
+	return nil
+}
+
+func (m *remoteOutboundManager) AddHandler(context.Context, outbound.Handler) error {
+	return errNotSupportedRemote
+}
+
+func (m *remoteOutboundManager) RemoveHandler(ctx context.Context, tag string) error {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	_, err := m.client.RemoveOutbound(ctx, &handlercmd.RemoveOutboundRequest{Tag: tag})
+	return err
+}
+
+type remoteOutbound struct {
+	tag string
+}
+
+func (h remoteOutbound) Tag() string                          { return h.tag }
+func (h remoteOutbound) Start() error                         { return nil }
+func (h remoteOutbound) Close() error                         { return nil }
+func (h remoteOutbound) SenderSettings() *serial.TypedMessage { return nil }
+func (h remoteOutbound) ProxySettings() *serial.TypedMessage  { return nil }
+
+func (h remoteOutbound) Dispatch(ctx context.Context, link *transport.Link) {}
+
+type remoteRouter struct {
+	client routercmd.RoutingServiceClient
+}
+
+func (*remoteRouter) Type() interface{} { return routing.RouterType() }
+func (*remoteRouter) Start() error      { return nil }
+func (*remoteRouter) Close() error      { return nil }
+
+func (r *remoteRouter) PickRoute(routing.Context) (routing.Route, error) {
+	return nil, errNotSupportedRemote
+}
+
+func (r *remoteRouter) AddRule(config *serial.TypedMessage, shouldAppend bool) error {
+	ctx, cancel := rpcContext(context.Background())
+	defer cancel()
+
+	_, err := r.client.AddRule(ctx, &routercmd.AddRuleRequest{Config: config, ShouldAppend: shouldAppend})
+	return err
+}
+
+func (r *remoteRouter) RemoveRule(tag string) error {
+	ctx, cancel := rpcContext(context.Background())
+	defer cancel()
+
+	_, err := r.client.RemoveRule(ctx, &routercmd.RemoveRuleRequest{RuleTag: tag})
+	return err
+}
+
+func (r *remoteRouter) ListRule() []routing.Route {
+	ctx, cancel := rpcContext(context.Background())
+	defer cancel()
+
+	resp, err := r.client.ListRule(ctx, &routercmd.ListRuleRequest{})
+	if err != nil {
+		return nil
+	}
+	routes := make([]routing.Route, 0, len(resp.Rules))
+	for _, rule := range resp.Rules {
+		routes = append(routes, remoteRoute{outboundTag: rule.Tag, ruleTag: rule.RuleTag})
+	}
+	return routes
+}
+
+// remoteRoute is a rule listed by an external xray. Only its tags are
+// known; it carries no routing context.
+type remoteRoute struct {
+	routing.Context
+	outboundTag string
+	ruleTag     string
+}
+
+func (r remoteRoute) GetOutboundGroupTags() []string { return nil }
+func (r remoteRoute) GetOutboundTag() string         { return r.outboundTag }
+func (r remoteRoute) GetRuleTag() string             { return r.ruleTag }
+
+// syncStats moves the counters of an external xray into stats, resetting
+// them there, and refreshes the online IPs of its users, so the mirror
+// reads like the stats manager of an embedded core.
+func syncStats(ctx context.Context, client statscmd.StatsServiceClient, stats *appstats.Manager) error {
+	ctx, cancel := rpcContext(ctx)
+	defer cancel()
+
+	resp, err := client.QueryStats(ctx, &statscmd.QueryStatsRequest{Reset_: true})
+	if err != nil {
+		return err
+	}
+	for _, stat := range resp.Stat {
+		counter := stats.GetCounter(stat.Name)
+		if counter == nil {
+			if counter, err = stats.RegisterCounter(stat.Name); err != nil {
+				continue
+			}
+		}
+		counter.Add(stat.Value)
+	}
+
+	online, err := client.GetAllOnlineUsers(ctx, &statscmd.GetAllOnlineUsersRequest{})
+	if err != nil {
+		return err
+	}
+	for _, name := range online.Users {
+		ips, err := client.GetStatsOnlineIpList(ctx, &statscmd.GetStatsRequest{Name: name})
+		if err != nil {
+			continue
+		}
+		onlineMap := stats.GetOnlineMap(name)
+		if onlineMap == nil {
+			if onlineMap, err = stats.RegisterOnlineMap(name); err != nil {
+				continue
+			}
+		}
+		for ip := range ips.Ips {
+			onlineMap.AddIP(ip)
+		}
+	}
+	return nil
+}
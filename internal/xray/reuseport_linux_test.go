@@ -0,0 +1,32 @@
+package xray
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/logger"
+)
+
+// A graceful upgrade relies on xray listening with SO_REUSEPORT: the core of
+// the new node process binds the inbounds while the old one still serves.
+func TestCore_InboundPortsAreShared(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	var cfg map[string]interface{}
+	require.NoError(t, json.Unmarshal(makeInboundConfig("shared-in"), &cfg))
+	cfg["inbounds"].([]interface{})[0].(map[string]interface{})["port"] = port
+	configJSON, _ := json.Marshal(cfg)
+
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	old, next := NewCore(log), NewCore(log)
+	require.NoError(t, old.Start(configJSON))
+	defer old.Stop()
+	require.NoError(t, next.Start(configJSON), "the new core binds the port the old one listens on")
+	defer next.Stop()
+}
@@ -3,11 +3,19 @@ package xray
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/xtls/xray-core/common/protocol"
 	"github.com/xtls/xray-core/features/inbound"
 	"github.com/xtls/xray-core/proxy"
+	"github.com/xtls/xray-core/proxy/http"
+	"github.com/xtls/xray-core/proxy/shadowsocks"
+	"github.com/xtls/xray-core/proxy/shadowsocks_2022"
+	"github.com/xtls/xray-core/proxy/socks"
+	"github.com/xtls/xray-core/proxy/trojan"
+	"github.com/xtls/xray-core/proxy/vless"
+	"github.com/xtls/xray-core/proxy/vmess"
 
 	"github.com/remnawave/node-go/internal/logger"
 )
@@ -34,9 +42,13 @@ func NewUserManager(ibm inbound.Manager, log *logger.Logger) *UserManager {
 // 2. Cast to proxy.GetInbound
 // 3. Get inbound and cast to proxy.UserManager
 func (m *UserManager) getProxyUserManager(ctx context.Context, tag string) (proxy.UserManager, error) {
-	handler, err := m.ibm.GetHandler(ctx, tag)
+	return proxyUserManager(ctx, m.ibm, tag)
+}
+
+func proxyUserManager(ctx context.Context, ibm inbound.Manager, tag string) (proxy.UserManager, error) {
+	handler, err := ibm.GetHandler(ctx, tag)
 	if err != nil {
-		return nil, fmt.Errorf("no such inbound tag '%s': %w", tag, err)
+		return nil, fmt.Errorf("%w: %s", ErrInboundNotFound, tag)
 	}
 
 	// Cast to proxy.GetInbound interface
@@ -57,6 +69,13 @@ func (m *UserManager) getProxyUserManager(ctx context.Context, tag string) (prox
 // AddUser adds a single user to the specified inbound.
 // The user must have Account set via serial.ToTypedMessage().
 func (m *UserManager) AddUser(ctx context.Context, tag string, user *protocol.User) error {
+	// Convert to MemoryUser before locking: parsing the account is the
+	// expensive part and can run concurrently for bulk operations.
+	mUser, err := user.ToMemoryUser()
+	if err != nil {
+		return fmt.Errorf("failed to convert user to memory user: %w", err)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -65,12 +84,6 @@ func (m *UserManager) AddUser(ctx context.Context, tag string, user *protocol.Us
 		return err
 	}
 
-	// Convert to MemoryUser before adding
-	mUser, err := user.ToMemoryUser()
-	if err != nil {
-		return fmt.Errorf("failed to convert user to memory user: %w", err)
-	}
-
 	if err := userManager.AddUser(ctx, mUser); err != nil {
 		return fmt.Errorf("failed to add user '%s' to inbound '%s': %w", user.Email, tag, err)
 	}
@@ -175,3 +188,148 @@ func (m *UserManager) RemoveUserFromAllInbounds(ctx context.Context, tags []stri
 	}
 	return nil
 }
+
+// GetUsers returns the emails of all users currently registered in the specified inbound.
+func (m *UserManager) GetUsers(ctx context.Context, tag string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userManager, err := m.getProxyUserManager(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	users := userManager.GetUsers(ctx)
+	emails := make([]string, 0, len(users))
+	for _, user := range users {
+		if user != nil {
+			emails = append(emails, user.Email)
+		}
+	}
+	sort.Strings(emails)
+
+	return emails, nil
+}
+
+// GetUserIDs returns the account ID of each user in the specified inbound,
+// keyed by email. Users whose account has no ID (trojan, shadowsocks, ...)
+// are left out.
+func (m *UserManager) GetUserIDs(ctx context.Context, tag string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userManager, err := m.getProxyUserManager(ctx, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]string)
+	for _, user := range userManager.GetUsers(ctx) {
+		if user == nil {
+			continue
+		}
+		switch account := user.Account.(type) {
+		case *vless.MemoryAccount:
+			ids[user.Email] = account.ID.String()
+		case *vmess.MemoryAccount:
+			ids[user.Email] = account.ID.String()
+		}
+	}
+
+	return ids, nil
+}
+
+// InboundUsers returns the sorted emails of the users of every inbound
+// that manages users, keyed by inbound tag.
+func (m *UserManager) InboundUsers(ctx context.Context) map[string][]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make(map[string][]string)
+	for _, handler := range m.ibm.ListHandlers(ctx) {
+		userManager, err := m.getProxyUserManager(ctx, handler.Tag())
+		if err != nil {
+			continue
+		}
+
+		users := userManager.GetUsers(ctx)
+		emails := make([]string, 0, len(users))
+		for _, user := range users {
+			if user != nil {
+				emails = append(emails, user.Email)
+			}
+		}
+		sort.Strings(emails)
+		result[handler.Tag()] = emails
+	}
+
+	return result
+}
+
+// UserInbound is an inbound a user is registered in.
+type UserInbound struct {
+	Tag  string `json:"tag"`
+	Type string `json:"type"`
+	Flow string `json:"flow,omitempty"`
+}
+
+// FindUser returns every inbound the user with the given email is
+// registered in, sorted by tag.
+func (m *UserManager) FindUser(ctx context.Context, email string) []UserInbound {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	found := make([]UserInbound, 0)
+	for _, handler := range m.ibm.ListHandlers(ctx) {
+		userManager, err := m.getProxyUserManager(ctx, handler.Tag())
+		if err != nil {
+			continue
+		}
+		user := userManager.GetUser(ctx, email)
+		if user == nil {
+			continue
+		}
+
+		userInbound := UserInbound{Tag: handler.Tag(), Type: accountType(user.Account)}
+		if account, ok := user.Account.(*vless.MemoryAccount); ok {
+			userInbound.Flow = account.Flow
+		}
+		found = append(found, userInbound)
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Tag < found[j].Tag })
+	return found
+}
+
+// accountType names the protocol of an account like add-user requests do.
+func accountType(account protocol.Account) string {
+	switch account.(type) {
+	case *vless.MemoryAccount:
+		return "vless"
+	case *vmess.MemoryAccount:
+		return "vmess"
+	case *trojan.MemoryAccount:
+		return "trojan"
+	case *shadowsocks.MemoryAccount, *shadowsocks_2022.MemoryAccount:
+		return "shadowsocks"
+	case *socks.Account:
+		return "socks"
+	case *http.Account:
+		return "http"
+	default:
+		return "unknown"
+	}
+}
+
+// GetUsersCount returns the number of users currently registered in the specified inbound.
+func (m *UserManager) GetUsersCount(ctx context.Context, tag string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	userManager, err := m.getProxyUserManager(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+
+	return userManager.GetUsersCount(ctx), nil
+}
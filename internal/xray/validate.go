@@ -0,0 +1,128 @@
+package xray
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// ConfigError is a problem found in one section of an xray config.
+type ConfigError struct {
+	// Section is the top-level key the problem is in, with the index of the
+	// handler for inbounds and outbounds ("inbounds[0]"). It is "config"
+	// for problems spanning sections and "json" for unparseable input.
+	Section string `json:"section"`
+	// Tag is the tag of the inbound or outbound, if it has one.
+	Tag     string `json:"tag,omitempty"`
+	Message string `json:"message"`
+}
+
+// CheckConfig validates configJSON like starting the core would, without
+// starting it, and returns every problem found, attributed to the section it
+// is in where possible. A valid config yields no errors.
+func CheckConfig(configJSON []byte) []ConfigError {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(configJSON, &sections); err != nil {
+		return []ConfigError{{Section: "json", Message: err.Error()}}
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	var errs []ConfigError
+	errs = append(errs, checkHandlers(sections["inbounds"], "inbounds", func(raw json.RawMessage) (string, error) {
+		var inbound conf.InboundDetourConfig
+		if err := json.Unmarshal(raw, &inbound); err != nil {
+			return "", err
+		}
+		_, err := inbound.Build()
+		return inbound.Tag, err
+	})...)
+	errs = append(errs, checkHandlers(sections["outbounds"], "outbounds", func(raw json.RawMessage) (string, error) {
+		var outbound conf.OutboundDetourConfig
+		if err := json.Unmarshal(raw, &outbound); err != nil {
+			return "", err
+		}
+		_, err := outbound.Build()
+		return outbound.Tag, err
+	})...)
+	errs = appendSectionError(errs, sections, "routing", func(raw json.RawMessage) error {
+		var routing conf.RouterConfig
+		if err := json.Unmarshal(raw, &routing); err != nil {
+			return err
+		}
+		_, err := routing.Build()
+		return err
+	})
+	errs = appendSectionError(errs, sections, "dns", func(raw json.RawMessage) error {
+		var dns conf.DNSConfig
+		if err := json.Unmarshal(raw, &dns); err != nil {
+			return err
+		}
+		_, err := dns.Build()
+		return err
+	})
+	errs = appendSectionError(errs, sections, "policy", func(raw json.RawMessage) error {
+		var policy conf.PolicyConfig
+		if err := json.Unmarshal(raw, &policy); err != nil {
+			return err
+		}
+		_, err := policy.Build()
+		return err
+	})
+	errs = appendSectionError(errs, sections, "api", func(raw json.RawMessage) error {
+		var api conf.APIConfig
+		if err := json.Unmarshal(raw, &api); err != nil {
+			return err
+		}
+		_, err := api.Build()
+		return err
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// The remaining sections, and whatever only fails once the sections are
+	// put together, e.g. duplicate tags.
+	if _, err := core.LoadConfig("json", bytes.NewReader(configJSON)); err != nil {
+		return []ConfigError{{Section: "config", Message: err.Error()}}
+	}
+	return nil
+}
+
+func checkHandlers(raw json.RawMessage, section string, check func(json.RawMessage) (string, error)) []ConfigError {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+
+	var handlers []json.RawMessage
+	if err := json.Unmarshal(raw, &handlers); err != nil {
+		return []ConfigError{{Section: section, Message: err.Error()}}
+	}
+
+	var errs []ConfigError
+	for i, handler := range handlers {
+		if tag, err := check(handler); err != nil {
+			errs = append(errs, ConfigError{
+				Section: fmt.Sprintf("%s[%d]", section, i),
+				Tag:     tag,
+				Message: err.Error(),
+			})
+		}
+	}
+	return errs
+}
+
+func appendSectionError(errs []ConfigError, sections map[string]json.RawMessage, section string, check func(json.RawMessage) error) []ConfigError {
+	raw, ok := sections[section]
+	if !ok || string(raw) == "null" {
+		return errs
+	}
+	if err := check(raw); err != nil {
+		errs = append(errs, ConfigError{Section: section, Message: err.Error()})
+	}
+	return errs
+}
@@ -0,0 +1,40 @@
+package xray
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	assert.Empty(t, CheckConfig(makeMinimalConfig()))
+
+	errs := CheckConfig(makeInvalidJSON())
+	require.Len(t, errs, 1)
+	assert.Equal(t, "json", errs[0].Section)
+
+	errs = CheckConfig([]byte(`{
+		"inbounds": [
+			{"tag": "ok-in", "port": 10000, "protocol": "socks"},
+			{"tag": "bad-in", "port": 10001, "protocol": "no-such-protocol"}
+		],
+		"outbounds": [{"tag": "direct", "protocol": "freedom"}],
+		"routing": {"rules": [{"type": "field", "ip": ["not-an-ip"], "outboundTag": "direct"}]}
+	}`))
+	require.Len(t, errs, 2)
+	assert.Equal(t, "inbounds[1]", errs[0].Section)
+	assert.Equal(t, "bad-in", errs[0].Tag)
+	assert.NotEmpty(t, errs[0].Message)
+	assert.Equal(t, "routing", errs[1].Section)
+
+	// Sections without a check of their own are covered by loading the
+	// whole config.
+	errs = CheckConfig([]byte(`{
+		"inbounds": [],
+		"outbounds": [{"tag": "direct", "protocol": "freedom"}],
+		"log": {"loglevel": 5}
+	}`))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "config", errs[0].Section)
+}
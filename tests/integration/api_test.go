@@ -2,20 +2,28 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	xlog "github.com/xtls/xray-core/common/log"
+	xnet "github.com/xtls/xray-core/common/net"
 
 	"github.com/remnawave/node-go/internal/api"
 	"github.com/remnawave/node-go/internal/api/controller"
 	"github.com/remnawave/node-go/internal/config"
 	"github.com/remnawave/node-go/internal/logger"
+	"github.com/remnawave/node-go/internal/version"
 	"github.com/remnawave/node-go/internal/xray"
 )
 
@@ -25,6 +33,13 @@ func init() {
 
 func setupTestServer(t *testing.T, creds *TestCredentials) *api.Server {
 	t.Helper()
+	return setupTestServerWithConfig(t, creds, nil)
+}
+
+// setupTestServerWithConfig builds a test server, letting the caller adjust
+// the node config (e.g. StateDir) before the server is created.
+func setupTestServerWithConfig(t *testing.T, creds *TestCredentials, configure func(*config.Config)) *api.Server {
+	t.Helper()
 
 	payload := &config.NodePayload{
 		CACertPEM:    string(creds.CACert),
@@ -36,11 +51,22 @@ func setupTestServer(t *testing.T, creds *TestCredentials) *api.Server {
 	cfg := &config.Config{
 		NodePort:         2222,
 		InternalRestPort: 61001,
+		APIPort:          config.DefaultAPIPort,
 		LogLevel:         "error",
 		Payload:          payload,
 	}
+	if configure != nil {
+		configure(cfg)
+	}
 
-	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	level, err := logger.ParseLevel(cfg.LogLevel)
+	require.NoError(t, err)
+	var buffer *logger.Buffer
+	if cfg.LogBufferSize > 0 {
+		buffer = logger.NewBuffer(cfg.LogBufferSize)
+	}
+
+	log := logger.New(logger.Config{Level: level, Format: logger.FormatJSON, Buffer: buffer})
 	core := xray.NewCore(log)
 	configMgr := xray.NewConfigManager(log)
 
@@ -94,6 +120,31 @@ func makeInternalRequest(t *testing.T, server *api.Server, method, path string,
 	return w
 }
 
+// makeLocalInternalRequest issues a request to the internal router as if it
+// arrived on the loopback internal port, so PortGuardMiddleware lets it through.
+func makeLocalInternalRequest(t *testing.T, server *api.Server, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		require.NoError(t, err)
+		reqBody = bytes.NewReader(jsonBody)
+	}
+
+	req := httptest.NewRequest(method, path, reqBody)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	localAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 61001}
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, localAddr))
+
+	w := httptest.NewRecorder()
+	server.InternalRouter().ServeHTTP(w, req)
+	return w
+}
+
 func TestXrayStatus(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -132,13 +183,130 @@ func TestXrayHealthcheck(t *testing.T) {
 			IsXrayRunning bool    `json:"isXrayRunning"`
 			XrayVersion   *string `json:"xrayVersion"`
 			NodeVersion   string  `json:"nodeVersion"`
+			NodeCommit    string  `json:"nodeCommit"`
+			Certificates  []struct {
+				Name     string    `json:"name"`
+				NotAfter time.Time `json:"notAfter"`
+				DaysLeft int       `json:"daysLeft"`
+			} `json:"certificates"`
 		} `json:"response"`
 	}
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err)
 	assert.True(t, response.Response.IsHealthy)
 	assert.False(t, response.Response.IsXrayRunning)
-	assert.Equal(t, "1.0.0", response.Response.NodeVersion)
+	assert.Equal(t, version.Version, response.Response.NodeVersion)
+	assert.Equal(t, version.Commit, response.Response.NodeCommit)
+	require.Len(t, response.Response.Certificates, 2)
+	assert.Equal(t, "node", response.Response.Certificates[0].Name)
+	assert.Equal(t, "ca", response.Response.Certificates[1].Name)
+	assert.True(t, response.Response.Certificates[0].NotAfter.After(time.Now()))
+}
+
+func TestInternalMetrics(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "GET", "/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), "# TYPE remnawave_node_certificate_expiry_timestamp_seconds gauge")
+	assert.Contains(t, w.Body.String(), `remnawave_node_certificate_expiry_timestamp_seconds{certificate="node",`)
+	assert.Contains(t, w.Body.String(), `remnawave_node_certificate_expiry_timestamp_seconds{certificate="ca",`)
+	assert.Contains(t, w.Body.String(), "remnawave_node_xray_running 0")
+	if runtime.GOOS == "linux" {
+		assert.Contains(t, w.Body.String(), `remnawave_node_resource_limit{resource="openFiles"}`)
+	}
+}
+
+func TestInternalSetLogLevel(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "POST", "/internal/set-log-level", map[string]string{"format": "pretty"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"error","format":"pretty"}`, w.Body.String())
+
+	w = makeLocalInternalRequest(t, server, "POST", "/internal/set-log-level", map[string]string{"level": "warn", "format": "json"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"warn","format":"json"}`, w.Body.String())
+
+	for _, body := range []map[string]string{{}, {"level": "verbose"}, {"level": "debug", "format": "xml"}} {
+		w = makeLocalInternalRequest(t, server, "POST", "/internal/set-log-level", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// A rejected request changes nothing.
+	w = makeLocalInternalRequest(t, server, "POST", "/internal/set-log-level", map[string]string{"level": "error"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level":"error","format":"json"}`, w.Body.String())
+}
+
+func TestXrayHealthcheckProbesRunningCore(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/healthcheck", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			IsHealthy     bool `json:"isHealthy"`
+			IsXrayRunning bool `json:"isXrayRunning"`
+			Checks        []struct {
+				Name    string  `json:"name"`
+				Healthy bool    `json:"healthy"`
+				Skipped bool    `json:"skipped"`
+				Error   *string `json:"error"`
+			} `json:"checks"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Response.IsHealthy)
+	assert.True(t, response.Response.IsXrayRunning)
+
+	require.Len(t, response.Response.Checks, 3)
+	for _, check := range response.Response.Checks {
+		assert.True(t, check.Healthy, "check %s", check.Name)
+		assert.False(t, check.Skipped, "check %s", check.Name)
+		assert.Nil(t, check.Error, "check %s", check.Name)
+	}
+}
+
+func TestXrayStartUsesConfiguredAPIPort(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	apiPort := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.APIPort = apiPort
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(apiPort)), time.Second)
+	require.NoError(t, err, "api inbound listens on the configured port")
+	conn.Close()
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/healthcheck", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"isHealthy":true`)
 }
 
 func TestXrayStartWithMinimalConfig(t *testing.T) {
@@ -166,7 +334,9 @@ func TestXrayStartWithMinimalConfig(t *testing.T) {
 				NumGoroutine int    `json:"numGoroutine"`
 			} `json:"systemInfo"`
 			NodeInfo struct {
-				Version string `json:"version"`
+				Version   string `json:"version"`
+				Commit    string `json:"commit"`
+				BuildTime string `json:"buildTime"`
 			} `json:"nodeInfo"`
 		} `json:"response"`
 	}
@@ -175,7 +345,9 @@ func TestXrayStartWithMinimalConfig(t *testing.T) {
 	assert.True(t, response.Response.IsStarted)
 	assert.NotEmpty(t, response.Response.Version)
 	assert.NotNil(t, response.Response.SystemInfo)
-	assert.Equal(t, "1.0.0", response.Response.NodeInfo.Version)
+	assert.Equal(t, version.Version, response.Response.NodeInfo.Version)
+	assert.Equal(t, version.Commit, response.Response.NodeInfo.Commit)
+	assert.Equal(t, version.BuildTime, response.Response.NodeInfo.BuildTime)
 }
 
 func TestXrayStop(t *testing.T) {
@@ -239,6 +411,37 @@ func TestHandlerAddUserWithoutXrayRunning(t *testing.T) {
 	assert.NotNil(t, response.Response.Error)
 }
 
+func TestHandlerAddUserCodedError(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.ErrorResponseVersion = config.ErrorResponseCoded
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", &AddUserRequest{
+		Data: []AddUserInboundData{
+			{Tag: "vless-in", Username: "testuser@example.com", Type: "vless", UUID: "550e8400-e29b-41d4-a716-446655440000"},
+		},
+	})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var response struct {
+		Path      string `json:"path"`
+		Message   string `json:"message"`
+		ErrorCode string `json:"errorCode"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "/node/handler/add-user", response.Path)
+	assert.Equal(t, "A022", response.ErrorCode)
+	assert.Contains(t, response.Message, "xray core not available")
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/stats/history?from=yesterday", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "A018", response.ErrorCode)
+}
+
 func TestStatsGetSystemStats(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -292,6 +495,32 @@ func TestStatsGetUsersStats(t *testing.T) {
 	assert.NotNil(t, response.Response.Users)
 }
 
+func TestStatsGetHistory(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/stats/history?from=0&to=4102444800", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Snapshots []struct {
+				Time  string                      `json:"time"`
+				Users map[string]map[string]int64 `json:"users"`
+			} `json:"snapshots"`
+		} `json:"response"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.NotNil(t, response.Response.Snapshots)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/stats/history?from=yesterday", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestInternalGetConfigSocketDestroyedInHttptest(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -308,7 +537,7 @@ func TestInternalControllerReturnsRawJSON(t *testing.T) {
 	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
 	configMgr := xray.NewConfigManager(log)
 
-	internalController := controller.NewInternalController(configMgr, log)
+	internalController := controller.NewInternalController(xray.NewCore(log), configMgr, nil, nil, nil, log)
 
 	router := gin.New()
 	group := router.Group("/internal")
@@ -329,6 +558,168 @@ func TestInternalControllerReturnsRawJSON(t *testing.T) {
 	assert.False(t, hasResponse, "internal get-config should NOT have response wrapper")
 }
 
+func TestInternalStatusStatsAndUsers(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "GET", "/internal/xray-status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"isRunning":false,"version":null,"emptyConfigHash":"","inbounds":[],"uptime":0,"restarts":0,"lastStartError":null,"lastStartErrorAt":null}`, w.Body.String())
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/healthcheck", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var health struct {
+		IsHealthy     bool `json:"isHealthy"`
+		IsXrayRunning bool `json:"isXrayRunning"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.True(t, health.IsHealthy)
+	assert.False(t, health.IsXrayRunning)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/inbound-users", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", map[string]interface{}{
+		"data": []map[string]interface{}{{
+			"tag": "vless-in", "username": "alice", "type": "vless", "uuid": "550e8400-e29b-41d4-a716-446655440000",
+		}},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/xray-status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var status struct {
+		IsRunning bool    `json:"isRunning"`
+		Version   *string `json:"version"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(t, status.IsRunning)
+	assert.NotNil(t, status.Version)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/stats", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		Inbounds  []json.RawMessage `json:"inbounds"`
+		Outbounds []json.RawMessage `json:"outbounds"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.NotNil(t, stats.Inbounds)
+	assert.NotNil(t, stats.Outbounds)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/inbound-users?tag=vless-in", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"inbounds":[{"tag":"vless-in","users":["alice"]}]}`, w.Body.String())
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var nodeStatus struct {
+		IsXrayRunning bool              `json:"isXrayRunning"`
+		XrayVersion   *string           `json:"xrayVersion"`
+		Inbounds      []json.RawMessage `json:"inbounds"`
+		RecentErrors  []json.RawMessage `json:"recentErrors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nodeStatus))
+	assert.True(t, nodeStatus.IsXrayRunning)
+	assert.NotNil(t, nodeStatus.XrayVersion)
+	assert.Contains(t, w.Body.String(), `{"tag":"vless-in","users":1}`)
+	assert.NotNil(t, nodeStatus.RecentErrors)
+
+	w = makeInternalRequest(t, server, "GET", "/internal/inbound-users", nil)
+	assert.Empty(t, w.Body.String(), "requests not on the internal port are still rejected")
+}
+
+func TestInternalGetConfigRedactsSecrets(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	configMgr := xray.NewConfigManager(log)
+	configMgr.SetXrayConfig(map[string]interface{}{
+		"inbounds": []interface{}{
+			map[string]interface{}{
+				"tag":      "vless-in",
+				"protocol": "vless",
+				"settings": map[string]interface{}{
+					"clients": []interface{}{
+						map[string]interface{}{"id": "550e8400-e29b-41d4-a716-446655440000", "email": "alice"},
+					},
+				},
+			},
+		},
+	})
+
+	internalController := controller.NewInternalController(xray.NewCore(log), configMgr, nil, nil, nil, log)
+
+	router := gin.New()
+	internalController.RegisterRoutes(router.Group("/internal"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/internal/get-config", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "550e8400-e29b-41d4-a716-446655440000")
+	assert.Contains(t, w.Body.String(), `"email":"alice"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/internal/get-config?full=true", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "550e8400-e29b-41d4-a716-446655440000")
+}
+
+func TestInternalGetConfigETag(t *testing.T) {
+	log := logger.New(logger.Config{Level: logger.LevelError, Format: logger.FormatJSON})
+	configMgr := xray.NewConfigManager(log)
+	configMgr.SetXrayConfig(map[string]interface{}{"log": map[string]interface{}{"loglevel": "warning"}})
+
+	internalController := controller.NewInternalController(xray.NewCore(log), configMgr, nil, nil, nil, log)
+
+	router := gin.New()
+	internalController.RegisterRoutes(router.Group("/internal"))
+
+	getConfig := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := getConfig("/internal/get-config", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = getConfig("/internal/get-config", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- getConfig("/internal/get-config?wait=true", etag)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("long-poll returned before the config changed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	configMgr.SetXrayConfig(map[string]interface{}{"log": map[string]interface{}{"loglevel": "debug"}})
+
+	select {
+	case w = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("long-poll did not return after the config changed")
+	}
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"loglevel":"debug"`)
+}
+
 func TestJWTAuthFailureMissingHeader(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -379,6 +770,42 @@ func TestJWTAuthFailureExpiredToken(t *testing.T) {
 	assert.Empty(t, w.Body.String())
 }
 
+func TestJWTScopes(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.JWTScopes = "monitor=/node/stats/*,/node/xray/status"
+	})
+
+	monitorJWT, err := creds.GenerateScopedJWT("monitor")
+	require.NoError(t, err)
+	otherJWT, err := creds.GenerateScopedJWT("billing")
+	require.NoError(t, err)
+
+	request := func(token, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.MainRouter().ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request(monitorJWT, "GET", "/node/xray/status").Code)
+	assert.Equal(t, http.StatusOK, request(monitorJWT, "GET", "/node/stats/get-system-stats").Code)
+
+	w := request(monitorJWT, "GET", "/node/xray/stop")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "A004")
+
+	assert.Equal(t, http.StatusForbidden, request(monitorJWT, "POST", "/node/handler/get-inbound-users").Code)
+	assert.Equal(t, http.StatusForbidden, request(otherJWT, "GET", "/node/xray/status").Code)
+
+	// Unscoped panel tokens keep full access.
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestNotFoundDestroysSocket(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -441,6 +868,34 @@ func TestValidationErrorAddUserMissingFields(t *testing.T) {
 	assert.Contains(t, *response.Response.Error, "invalid request body")
 }
 
+func TestValidationErrorResponseWithCodedErrors(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.ErrorResponseVersion = config.ErrorResponseCoded
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", map[string]interface{}{
+		"data": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		StatusCode int    `json:"statusCode"`
+		Message    string `json:"message"`
+		Errors     []struct {
+			Path    []string `json:"path"`
+			Message string   `json:"message"`
+		} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, []string{"data", "0", "username"}, response.Errors[0].Path)
+	assert.Equal(t, "is required", response.Errors[0].Message)
+}
+
 func TestValidationErrorInvalidJSON(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -489,7 +944,7 @@ func TestXrayStartValidationError(t *testing.T) {
 	assert.NotNil(t, response.Response.Error)
 }
 
-func TestHandlerGetInboundUsers(t *testing.T) {
+func TestHandlerGetInboundUsersWithoutXrayRunning(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
 
@@ -499,19 +954,11 @@ func TestHandlerGetInboundUsers(t *testing.T) {
 		"tag": "vless-in",
 	})
 
-	assert.Equal(t, http.StatusOK, w.Code)
-
-	var response struct {
-		Response struct {
-			Users []string `json:"users"`
-		} `json:"response"`
-	}
-	err = json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
-	assert.NotNil(t, response.Response.Users)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "xray core not available")
 }
 
-func TestHandlerGetInboundUsersCount(t *testing.T) {
+func TestHandlerGetInboundUsersCountWithoutXrayRunning(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
 
@@ -521,16 +968,8 @@ func TestHandlerGetInboundUsersCount(t *testing.T) {
 		"tag": "vless-in",
 	})
 
-	assert.Equal(t, http.StatusOK, w.Code)
-
-	var response struct {
-		Response struct {
-			Count int `json:"count"`
-		} `json:"response"`
-	}
-	err = json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
-	assert.Equal(t, 0, response.Response.Count)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "xray core not available")
 }
 
 func TestStatsGetUserOnlineStatus(t *testing.T) {
@@ -555,6 +994,56 @@ func TestStatsGetUserOnlineStatus(t *testing.T) {
 	assert.False(t, response.Response.Online)
 }
 
+func TestStatsGetUsersLastSeen(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	xlog.Record(&xlog.AccessMessage{
+		From:   xnet.TCPDestination(xnet.ParseAddress("203.0.113.10"), 5000),
+		To:     "tcp:example.com:443",
+		Status: xlog.AccessAccepted,
+		Email:  "testuser@example.com",
+	})
+
+	var response struct {
+		Response struct {
+			Users []struct {
+				Username string    `json:"username"`
+				LastSeen time.Time `json:"lastSeen"`
+				LastIP   string    `json:"lastIp"`
+				IPs      []struct {
+					IP string `json:"ip"`
+				} `json:"ips"`
+			} `json:"users"`
+		} `json:"response"`
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/stats/get-users-last-seen", map[string]string{
+		"username": "testuser@example.com",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Response.Users, 1)
+	user := response.Response.Users[0]
+	assert.Equal(t, "testuser@example.com", user.Username)
+	assert.WithinDuration(t, time.Now(), user.LastSeen, time.Minute)
+	assert.Equal(t, "203.0.113.10", user.LastIP)
+	require.Len(t, user.IPs, 1)
+	assert.Equal(t, "203.0.113.10", user.IPs[0].IP)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/stats/get-users-last-seen", map[string]string{
+		"username": "other@example.com",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"users":[]`)
+}
+
 func TestStatsGetInboundStats(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -580,6 +1069,27 @@ func TestStatsGetInboundStats(t *testing.T) {
 	assert.Equal(t, "vless-in", response.Response.Inbound)
 }
 
+func TestStatsGetOutboundHealth(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/stats/get-outbound-health", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Outbounds []json.RawMessage `json:"outbounds"`
+		} `json:"response"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	assert.NotNil(t, response.Response.Outbounds)
+	assert.Empty(t, response.Response.Outbounds)
+}
+
 func TestStatsGetOutboundStats(t *testing.T) {
 	creds, err := GenerateTestCredentials()
 	require.NoError(t, err)
@@ -785,3 +1295,371 @@ func TestXrayStartDuplicateRequestRejected(t *testing.T) {
 
 	assert.True(t, hasSuccess, "at least one request should succeed")
 }
+
+func TestXrayGenRealityKeys(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	type keysResponse struct {
+		Response struct {
+			PrivateKey string   `json:"privateKey"`
+			PublicKey  string   `json:"publicKey"`
+			ShortIDs   []string `json:"shortIds"`
+			Rotated    bool     `json:"rotated"`
+		} `json:"response"`
+	}
+	genKeys := func(body interface{}) (*httptest.ResponseRecorder, keysResponse) {
+		w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/gen-reality-keys", body)
+		var keys keysResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+		return w, keys
+	}
+
+	w, generated := genKeys(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, generated.Response.PrivateKey)
+	assert.NotEmpty(t, generated.Response.PublicKey)
+	assert.False(t, generated.Response.Rotated)
+
+	config := CreateMinimalXrayConfig()
+	vlessIn := config.XrayConfig["inbounds"].([]interface{})[0].(map[string]interface{})
+	vlessIn["streamSettings"] = map[string]interface{}{
+		"network":  "tcp",
+		"security": "reality",
+		"realitySettings": map[string]interface{}{
+			"dest":        "example.com:443",
+			"serverNames": []interface{}{"example.com"},
+			"privateKey":  generated.Response.PrivateKey,
+			"shortIds":    []interface{}{"0123456789abcdef"},
+		},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", AddUserRequest{
+		Data: []AddUserInboundData{
+			{Tag: "vless-in", Username: "alice", Type: "vless", UUID: "550e8400-e29b-41d4-a716-446655440000"},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w, rotated := genKeys(map[string]interface{}{"inboundTag": "vless-in"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, rotated.Response.Rotated)
+	assert.NotEqual(t, generated.Response.PublicKey, rotated.Response.PublicKey)
+	assert.Equal(t, []string{"0123456789abcdef"}, rotated.Response.ShortIDs)
+
+	w, rotated = genKeys(map[string]interface{}{"inboundTag": "vless-in", "shortIdCount": 2})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, rotated.Response.ShortIDs, 2)
+
+	// Runtime users survive the inbound restart.
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{"tag": "vless-in"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var users struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &users))
+	assert.Equal(t, []string{"alice"}, users.Response.Users)
+
+	w, _ = genKeys(map[string]interface{}{"inboundTag": "api"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = genKeys(map[string]interface{}{"inboundTag": "missing-in"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestXrayValidate(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	type validateResponse struct {
+		Response struct {
+			Valid  bool `json:"valid"`
+			Errors []struct {
+				Section string `json:"section"`
+				Tag     string `json:"tag"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"response"`
+	}
+
+	config := CreateMinimalXrayConfig()
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/validate", map[string]interface{}{
+		"xrayConfig": config.XrayConfig,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var valid validateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &valid))
+	assert.True(t, valid.Response.Valid)
+	assert.Empty(t, valid.Response.Errors)
+
+	config.XrayConfig["outbounds"] = []interface{}{
+		map[string]interface{}{"tag": "broken", "protocol": "no-such-protocol"},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/validate", map[string]interface{}{
+		"xrayConfig": config.XrayConfig,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var invalid validateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invalid))
+	assert.False(t, invalid.Response.Valid)
+	require.Len(t, invalid.Response.Errors, 1)
+	assert.Equal(t, "outbounds[0]", invalid.Response.Errors[0].Section)
+	assert.Equal(t, "broken", invalid.Response.Errors[0].Tag)
+
+	// Validating does not start xray.
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"isRunning":false`)
+}
+
+func TestXrayDiffConfig(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	config := CreateMinimalXrayConfig()
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	type diffResponse struct {
+		Response struct {
+			Identical       bool     `json:"identical"`
+			RestartRequired bool     `json:"restartRequired"`
+			Sections        []string `json:"sections"`
+			InboundsAdded   []string `json:"inboundsAdded"`
+			InboundsChanged []struct {
+				Tag        string   `json:"tag"`
+				UsersAdded []string `json:"usersAdded"`
+			} `json:"inboundsChanged"`
+		} `json:"response"`
+	}
+	diffConfig := func() diffResponse {
+		w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/diff-config", map[string]interface{}{
+			"xrayConfig": config.XrayConfig,
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+		var diff diffResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+		return diff
+	}
+
+	diff := diffConfig()
+	assert.True(t, diff.Response.Identical, "the injected api inbound and rules are not a difference")
+
+	vlessIn := config.XrayConfig["inbounds"].([]interface{})[0].(map[string]interface{})
+	vlessIn["settings"].(map[string]interface{})["clients"] = []interface{}{
+		map[string]interface{}{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "email": "bob"},
+	}
+	diff = diffConfig()
+	assert.False(t, diff.Response.Identical)
+	assert.False(t, diff.Response.RestartRequired)
+	require.Len(t, diff.Response.InboundsChanged, 1)
+	assert.Equal(t, "vless-in", diff.Response.InboundsChanged[0].Tag)
+	assert.Equal(t, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, diff.Response.InboundsChanged[0].UsersAdded)
+
+	config.XrayConfig["log"] = map[string]interface{}{"loglevel": "debug"}
+	diff = diffConfig()
+	assert.True(t, diff.Response.RestartRequired)
+	assert.Equal(t, []string{"log"}, diff.Response.Sections)
+}
+
+func TestXrayPatchConfig(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	type patchResponse struct {
+		Response struct {
+			Applied bool   `json:"applied"`
+			Action  string `json:"action"`
+			Hashes  *struct {
+				EmptyConfig string `json:"emptyConfig"`
+				Inbounds    []struct {
+					Tag        string `json:"tag"`
+					UsersCount int    `json:"usersCount"`
+				} `json:"inbounds"`
+			} `json:"hashes"`
+			Errors []struct {
+				Section string `json:"section"`
+			} `json:"errors"`
+			Error *string `json:"error"`
+		} `json:"response"`
+	}
+	patchConfig := func(expectedStatus int, ops ...map[string]interface{}) patchResponse {
+		w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/patch-config", ops)
+		require.Equal(t, expectedStatus, w.Code, w.Body.String())
+		var resp patchResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	addClient := map[string]interface{}{
+		"op":    "add",
+		"path":  "/inbounds/0/settings/clients/-",
+		"value": map[string]interface{}{"id": "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "email": "bob"},
+	}
+
+	resp := patchConfig(http.StatusConflict, addClient)
+	assert.False(t, resp.Response.Applied)
+
+	config := CreateMinimalXrayConfig()
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	resp = patchConfig(http.StatusBadRequest, map[string]interface{}{"op": "test", "path": "/log/loglevel", "value": "debug"})
+	assert.NotNil(t, resp.Response.Error)
+
+	resp = patchConfig(http.StatusBadRequest, map[string]interface{}{"op": "replace", "path": "/inbounds/0/protocol", "value": "nope"})
+	require.NotEmpty(t, resp.Response.Errors)
+	assert.Equal(t, "inbounds[0]", resp.Response.Errors[0].Section)
+
+	resp = patchConfig(http.StatusOK, map[string]interface{}{"op": "test", "path": "/log/loglevel", "value": "warning"})
+	assert.Equal(t, "none", resp.Response.Action)
+
+	resp = patchConfig(http.StatusOK, addClient)
+	assert.True(t, resp.Response.Applied)
+	assert.Equal(t, "reload", resp.Response.Action)
+	require.NotNil(t, resp.Response.Hashes)
+	assert.Equal(t, "a1b2c3d4e5f67890", resp.Response.Hashes.EmptyConfig)
+	require.Len(t, resp.Response.Hashes.Inbounds, 1)
+	assert.Equal(t, 1, resp.Response.Hashes.Inbounds[0].UsersCount)
+
+	resp = patchConfig(http.StatusOK, map[string]interface{}{"op": "replace", "path": "/log/loglevel", "value": "error"})
+	assert.Equal(t, "restart", resp.Response.Action)
+	require.NotNil(t, resp.Response.Hashes)
+	assert.NotEqual(t, "a1b2c3d4e5f67890", resp.Response.Hashes.EmptyConfig)
+	assert.Equal(t, 1, resp.Response.Hashes.Inbounds[0].UsersCount)
+
+	status := makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/status", nil)
+	assert.Contains(t, status.Body.String(), `"isRunning":true`)
+}
+
+func TestXrayStartSession(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	type sessionResponse struct {
+		Response struct {
+			SessionID string  `json:"sessionId"`
+			Batches   []int   `json:"batches"`
+			Users     int     `json:"users"`
+			Error     *string `json:"error"`
+		} `json:"response"`
+	}
+	request := func(method, path string, body interface{}, expectedStatus int) sessionResponse {
+		w := makeAuthorizedRequest(t, server, creds, method, path, body)
+		require.Equal(t, expectedStatus, w.Code, w.Body.String())
+		var resp sessionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	batch := func(seq int, ids ...string) map[string]interface{} {
+		clients := make([]map[string]interface{}, 0, len(ids))
+		for _, id := range ids {
+			clients = append(clients, map[string]interface{}{"id": id, "email": id})
+		}
+		return map[string]interface{}{
+			"seq":      seq,
+			"inbounds": []map[string]interface{}{{"tag": "vless-in", "clients": clients}},
+		}
+	}
+
+	session := request("POST", "/node/xray/start-session", CreateMinimalXrayConfig(), http.StatusOK)
+	id := session.Response.SessionID
+	require.NotEmpty(t, id)
+	base := "/node/xray/start-session/" + id
+
+	request("POST", base+"/batch", batch(0, "6ba7b810-9dad-11d1-80b4-00c04fd430c8"), http.StatusOK)
+	request("POST", base+"/batch", map[string]interface{}{
+		"seq":      1,
+		"inbounds": []map[string]interface{}{{"tag": "missing-in", "clients": []interface{}{}}},
+	}, http.StatusBadRequest)
+
+	resp := request("POST", base+"/commit", map[string]interface{}{"batches": 2}, http.StatusBadRequest)
+	require.NotNil(t, resp.Response.Error)
+	assert.Contains(t, *resp.Response.Error, "[1]")
+
+	// Resume: the session tells which batches arrived.
+	resp = request("GET", base, nil, http.StatusOK)
+	assert.Equal(t, []int{0}, resp.Response.Batches)
+
+	request("POST", base+"/batch", batch(1, "6ba7b811-9dad-11d1-80b4-00c04fd430c8", "6ba7b812-9dad-11d1-80b4-00c04fd430c8"), http.StatusOK)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", base+"/commit", map[string]interface{}{"batches": 2})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+	assert.Contains(t, w.Body.String(), `"isStarted":true`)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users-count", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var countResponse struct {
+		Response struct {
+			Count int `json:"count"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &countResponse))
+	assert.Equal(t, 3, countResponse.Response.Count)
+
+	request("GET", base, nil, http.StatusNotFound)
+	request("DELETE", base, nil, http.StatusNotFound)
+}
+
+func TestXrayConfigPlaceholders(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	t.Setenv("TEST_WARP_LICENSE", "abc-123")
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.ConfigVars = "NODE_LISTEN_IP=127.0.0.1"
+		cfg.ConfigEnvAllowlist = "TEST_WARP_*"
+	})
+
+	config := CreateMinimalXrayConfig()
+	vlessIn := config.XrayConfig["inbounds"].([]interface{})[0].(map[string]interface{})
+	vlessIn["listen"] = "${NODE_LISTEN_IP}"
+	config.XrayConfig["outbounds"] = append(config.XrayConfig["outbounds"].([]interface{}), map[string]interface{}{
+		"tag":         "warp",
+		"protocol":    "freedom",
+		"sendThrough": "origin",
+		"settings":    map[string]interface{}{"redirect": "${ENV:TEST_WARP_LICENSE}.example.com:443"},
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/validate", map[string]interface{}{
+		"xrayConfig": map[string]interface{}{"log": map[string]interface{}{"loglevel": "${MISSING}"}},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"section":"placeholders"`)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	// The template diffs as identical to the applied config it resolved to.
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/diff-config", map[string]interface{}{
+		"xrayConfig": config.XrayConfig,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"identical":true`)
+
+	vlessIn["listen"] = "${ENV:SECRET_KEY}"
+	config.Internals.ForceRestart = true
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "not allowed")
+}
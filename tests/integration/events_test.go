@@ -0,0 +1,177 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamedEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// subscribeEvents opens the events stream of the node at url and returns a
+// function waiting for the next event. The stream is closed on cleanup, so
+// a test server must be closed with t.Cleanup too, before the call.
+func subscribeEvents(t *testing.T, url string, creds *TestCredentials) func() streamedEvent {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", url+"/node/events", nil)
+	require.NoError(t, err)
+	jwt, err := creds.GenerateJWT()
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	received := make(chan streamedEvent, 16)
+	go func() {
+		defer close(received)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var event streamedEvent
+			if err := json.Unmarshal([]byte(data), &event); err == nil {
+				received <- event
+			}
+		}
+	}()
+
+	return func() streamedEvent {
+		t.Helper()
+		select {
+		case event, ok := <-received:
+			require.True(t, ok, "stream closed")
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return streamedEvent{}
+		}
+	}
+}
+
+func TestEventsStreamPushesNodeEvents(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	ts := httptest.NewServer(server.MainRouter())
+	t.Cleanup(ts.Close)
+
+	next := subscribeEvents(t, ts.URL, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "xray.started", next().Type)
+
+	addReq := AddUserRequest{
+		Data: []AddUserInboundData{
+			{
+				Tag:      "vless-in",
+				Username: "alice",
+				Type:     "vless",
+				UUID:     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", addReq)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	event := next()
+	assert.Equal(t, "user.added", event.Type)
+	assert.JSONEq(t, `{"username":"alice","inbounds":["vless-in"]}`, string(event.Data))
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/remove-user", map[string]string{
+		"username": "alice",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	event = next()
+	assert.Equal(t, "user.removed", event.Type)
+	assert.JSONEq(t, `{"username":"alice"}`, string(event.Data))
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "xray.stopped", next().Type)
+}
+
+func TestEventsStreamOutlivesServerTimeouts(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	ts := httptest.NewUnstartedServer(server.MainRouter())
+	ts.Config.ReadTimeout = time.Second
+	ts.Config.WriteTimeout = time.Second
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	next := subscribeEvents(t, ts.URL, creds)
+	time.Sleep(2 * time.Second)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "xray.started", next().Type)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "xray.stopped", next().Type)
+}
+
+func TestEventsHistoryRecordsUserBatches(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+
+	addReq := AddUserRequest{
+		Data: []AddUserInboundData{
+			{
+				Tag:      "vless-in",
+				Username: "alice",
+				Type:     "vless",
+				UUID:     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", addReq)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/events/history?type=users.added", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Events []streamedEvent `json:"events"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Response.Events, 1)
+	assert.Equal(t, "users.added", response.Response.Events[0].Type)
+	assert.JSONEq(t, `{"operation":"add-user","count":1,"usernames":["alice"]}`, string(response.Response.Events[0].Data))
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/events/history?since=soon", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+}
@@ -0,0 +1,696 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+func TestHandlerGetInboundUsersReflectsRuntime(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	addReq := AddUserRequest{
+		Data: []AddUserInboundData{
+			{
+				Tag:      "vless-in",
+				Username: "alice",
+				Type:     "vless",
+				UUID:     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+		HashData: AddUserHashData{VlessUUID: "550e8400-e29b-41d4-a716-446655440000"},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", addReq)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usersResponse struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usersResponse))
+	assert.Equal(t, []string{"alice"}, usersResponse.Response.Users)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users-count", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var countResponse struct {
+		Response struct {
+			Count int `json:"count"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &countResponse))
+	assert.Equal(t, 1, countResponse.Response.Count)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "missing-inbound",
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "inbound not found: missing-inbound")
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users-count", map[string]string{
+		"tag": "missing-inbound",
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlerAddUserRejectsNegativeIPLimit(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	body := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"tag":      "vless-in",
+				"username": "alice",
+				"type":     "vless",
+				"uuid":     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+		"ipLimit": -1,
+	}
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandlerAddShadowsocks2022User(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	config := CreateMinimalXrayConfig()
+	inbounds := config.XrayConfig["inbounds"].([]interface{})
+	config.XrayConfig["inbounds"] = append(inbounds, map[string]interface{}{
+		"tag":      "ss2022-in",
+		"port":     10001,
+		"protocol": "shadowsocks",
+		"settings": map[string]interface{}{
+			"method":   "2022-blake3-aes-128-gcm",
+			"password": "AAAAAAAAAAAAAAAAAAAAAA==",
+			"network":  "tcp,udp",
+			"clients": []interface{}{
+				map[string]interface{}{
+					"email":    "seed",
+					"password": "AQEBAQEBAQEBAQEBAQEBAQ==",
+				},
+			},
+		},
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"tag":        "ss2022-in",
+				"username":   "alice",
+				"type":       "shadowsocks",
+				"password":   "plain-password",
+				"cipherType": "2022-blake3-aes-128-gcm",
+			},
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "base64-encoded 16-byte key")
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"tag":        "ss2022-in",
+				"username":   "alice",
+				"type":       "shadowsocks",
+				"password":   "AgICAgICAgICAgICAgICAg==",
+				"cipherType": "2022-blake3-aes-128-gcm",
+			},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "ss2022-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usersResponse struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usersResponse))
+	assert.Equal(t, []string{"alice", "seed"}, usersResponse.Response.Users)
+}
+
+func TestXrayStartReloadsOnlyChangedInbounds(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	config := CreateMinimalXrayConfig()
+	inbounds := config.XrayConfig["inbounds"].([]interface{})
+	config.XrayConfig["inbounds"] = append(inbounds, map[string]interface{}{
+		"tag":      "vless-b",
+		"port":     10002,
+		"protocol": "vless",
+		"settings": map[string]interface{}{
+			"clients":    []interface{}{},
+			"decryption": "none",
+		},
+	})
+	config.Internals.Hashes.Inbounds = append(config.Internals.Hashes.Inbounds, InboundHashEntry{
+		Tag:  "vless-b",
+		Hash: "0000000000000000",
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	// Runtime user on the inbound that does not change; a full restart would drop it.
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", AddUserRequest{
+		Data: []AddUserInboundData{
+			{
+				Tag:      "vless-b",
+				Username: "alice",
+				Type:     "vless",
+				UUID:     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	vlessIn := config.XrayConfig["inbounds"].([]interface{})[0].(map[string]interface{})
+	vlessIn["settings"].(map[string]interface{})["clients"] = []interface{}{
+		map[string]interface{}{
+			"id":    "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+			"email": "bob",
+		},
+	}
+	config.Internals.Hashes.Inbounds[0].Hash = "1111111111111111"
+	config.Internals.Hashes.Inbounds[0].UsersCount = 1
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", config)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	getUsers := func(tag string) []string {
+		w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+			"tag": tag,
+		})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Response struct {
+				Users []string `json:"users"`
+			} `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Response.Users
+	}
+
+	assert.Equal(t, []string{"bob"}, getUsers("vless-in"))
+	assert.Equal(t, []string{"alice"}, getUsers("vless-b"))
+}
+
+func TestHandlerAddUserExpireAt(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	body := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"tag":      "vless-in",
+				"username": "alice",
+				"type":     "vless",
+				"uuid":     "550e8400-e29b-41d4-a716-446655440000",
+			},
+		},
+		"expireAt": "not-a-date",
+	}
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	body["expireAt"] = "2030-01-01T00:00:00Z"
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+type bulkUsersResponse struct {
+	Response struct {
+		Success bool    `json:"success"`
+		Error   *string `json:"error"`
+		Results []struct {
+			Username string  `json:"username"`
+			Inbound  string  `json:"inbound"`
+			Success  bool    `json:"success"`
+			Error    *string `json:"error"`
+		} `json:"results"`
+	} `json:"response"`
+}
+
+func TestHandlerAddUsersReportsPerUserResults(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	body := map[string]interface{}{
+		"users": []map[string]interface{}{
+			{
+				"userData": map[string]interface{}{"userId": "alice", "vlessUuid": "550e8400-e29b-41d4-a716-446655440000"},
+				"inboundData": []map[string]interface{}{
+					{"tag": "missing-inbound", "type": "vless"},
+					{"tag": "vless-in", "type": "vless"},
+				},
+			},
+			{
+				"userData":    map[string]interface{}{"userId": "bob"},
+				"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "hysteria2"}},
+			},
+			{
+				"userData":    map[string]interface{}{"userId": "carol", "vlessUuid": "550e8400-e29b-41d4-a716-446655440001"},
+				"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+			},
+		},
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-users", body)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp bulkUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Response.Success)
+	require.NotNil(t, resp.Response.Error)
+	require.Len(t, resp.Response.Results, 4)
+
+	for _, result := range resp.Response.Results {
+		failing := result.Inbound == "missing-inbound" || result.Username == "bob"
+		assert.Equal(t, !failing, result.Success, "%s in %s", result.Username, result.Inbound)
+		assert.Equal(t, failing, result.Error != nil, "%s in %s", result.Username, result.Inbound)
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usersResponse struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usersResponse))
+	assert.Equal(t, []string{"alice", "carol"}, usersResponse.Response.Users)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/remove-users", map[string]interface{}{
+		"users": []map[string]string{{"userId": "alice"}, {"userId": "carol"}},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp = bulkUsersResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Response.Success)
+	assert.Nil(t, resp.Response.Error)
+	require.Len(t, resp.Response.Results, 2)
+	assert.Equal(t, "alice", resp.Response.Results[0].Username)
+	assert.True(t, resp.Response.Results[0].Success)
+}
+
+func TestHandlerAddUsersInParallel(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, func(cfg *config.Config) {
+		cfg.BulkWorkers = 8
+	})
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	const count = 200
+	users := make([]map[string]interface{}, 0, count+1)
+	expected := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		username := fmt.Sprintf("user-%03d", i)
+		expected = append(expected, username)
+		users = append(users, map[string]interface{}{
+			"userData":    map[string]interface{}{"userId": username, "vlessUuid": fmt.Sprintf("550e8400-e29b-41d4-a716-446655%06d", i)},
+			"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+		})
+	}
+	// A repeated entry for the same user is applied after the first one.
+	users = append(users, map[string]interface{}{
+		"userData":    map[string]interface{}{"userId": "user-000", "vlessUuid": "550e8400-e29b-41d4-a716-446655999999"},
+		"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+	})
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-users", map[string]interface{}{"users": users})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp bulkUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Response.Success)
+	require.Len(t, resp.Response.Results, count+1)
+	for i, result := range resp.Response.Results[:count] {
+		assert.Equal(t, expected[i], result.Username, "results keep request order")
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usersResponse struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usersResponse))
+	assert.Equal(t, expected, usersResponse.Response.Users)
+}
+
+func TestHandlerSyncUsersAppliesOnlyDifferences(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	entry := func(username, uuid string) map[string]interface{} {
+		return map[string]interface{}{
+			"userData":    map[string]interface{}{"userId": username, "hashUuid": uuid, "vlessUuid": uuid},
+			"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+		}
+	}
+	alice := entry("alice", "550e8400-e29b-41d4-a716-446655440000")
+	bob := entry("bob", "550e8400-e29b-41d4-a716-446655440001")
+	carol := entry("carol", "550e8400-e29b-41d4-a716-446655440002")
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-users", map[string]interface{}{
+		"users": []map[string]interface{}{alice, bob},
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	type syncResponse struct {
+		Response struct {
+			Success   bool `json:"success"`
+			Added     int  `json:"added"`
+			Removed   int  `json:"removed"`
+			Unchanged int  `json:"unchanged"`
+			Results   []struct {
+				Username string `json:"username"`
+			} `json:"results"`
+		} `json:"response"`
+	}
+
+	syncBody := map[string]interface{}{"users": []map[string]interface{}{alice, carol}}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/sync-users", syncBody)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp syncResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Response.Success)
+	assert.Equal(t, 1, resp.Response.Added)
+	assert.Equal(t, 1, resp.Response.Removed)
+	assert.Equal(t, 1, resp.Response.Unchanged)
+	require.Len(t, resp.Response.Results, 2)
+	assert.Equal(t, "carol", resp.Response.Results[0].Username)
+	assert.Equal(t, "bob", resp.Response.Results[1].Username)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var usersResponse struct {
+		Response struct {
+			Users []string `json:"users"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usersResponse))
+	assert.Equal(t, []string{"alice", "carol"}, usersResponse.Response.Users)
+
+	// A changed uuid makes the user out of sync; an identical set is a no-op.
+	alice = entry("alice", "550e8400-e29b-41d4-a716-446655440009")
+	syncBody = map[string]interface{}{"users": []map[string]interface{}{alice, carol}}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/sync-users", syncBody)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = syncResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Response.Added)
+	assert.Equal(t, 1, resp.Response.Unchanged)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/sync-users", syncBody)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = syncResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Response.Added)
+	assert.Equal(t, 0, resp.Response.Removed)
+	assert.Equal(t, 2, resp.Response.Unchanged)
+	assert.Empty(t, resp.Response.Results)
+}
+
+func TestHandlerCheckConsistency(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/check-consistency", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	// Added without hash data, so the config state does not know the user.
+	addReq := AddUserRequest{
+		Data: []AddUserInboundData{
+			{Tag: "vless-in", Username: "alice", Type: "vless", UUID: "550e8400-e29b-41d4-a716-446655440000"},
+		},
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", addReq)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	type consistencyResponse struct {
+		Response struct {
+			Report struct {
+				Consistent bool     `json:"consistent"`
+				Repaired   bool     `json:"repaired"`
+				Checked    []string `json:"checked"`
+				Inbounds   []struct {
+					Tag      string   `json:"tag"`
+					Orphaned []string `json:"orphaned"`
+					Missing  []string `json:"missing"`
+				} `json:"inbounds"`
+			} `json:"report"`
+		} `json:"response"`
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/check-consistency", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp consistencyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.False(t, resp.Response.Report.Consistent)
+	assert.False(t, resp.Response.Report.Repaired)
+	assert.Equal(t, []string{"vless-in"}, resp.Response.Report.Checked)
+	require.Len(t, resp.Response.Report.Inbounds, 1)
+	assert.Equal(t, []string{"alice"}, resp.Response.Report.Inbounds[0].Orphaned)
+	assert.Empty(t, resp.Response.Report.Inbounds[0].Missing)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/check-consistency", map[string]bool{"repair": true})
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = consistencyResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Response.Report.Repaired)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/check-consistency", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = consistencyResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Response.Report.Consistent)
+	assert.Empty(t, resp.Response.Report.Inbounds)
+}
+
+func TestHandlerGetUser(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-user", map[string]string{"username": "alice"})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	body := map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"tag":      "vless-in",
+				"username": "alice",
+				"type":     "vless",
+				"uuid":     "550e8400-e29b-41d4-a716-446655440000",
+				"flow":     "xtls-rprx-vision",
+			},
+		},
+		"ipLimit":  2,
+		"expireAt": "2030-01-01T00:00:00Z",
+	}
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-user", body)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-user", map[string]string{"username": "alice"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Response struct {
+			Username string `json:"username"`
+			Inbounds []struct {
+				Tag  string `json:"tag"`
+				Type string `json:"type"`
+				Flow string `json:"flow"`
+			} `json:"inbounds"`
+			Uplink   int64   `json:"uplink"`
+			Downlink int64   `json:"downlink"`
+			IPLimit  int     `json:"ipLimit"`
+			ExpireAt *string `json:"expireAt"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "alice", resp.Response.Username)
+	require.Len(t, resp.Response.Inbounds, 1)
+	assert.Equal(t, "vless-in", resp.Response.Inbounds[0].Tag)
+	assert.Equal(t, "vless", resp.Response.Inbounds[0].Type)
+	assert.Equal(t, "xtls-rprx-vision", resp.Response.Inbounds[0].Flow)
+	assert.Equal(t, int64(0), resp.Response.Uplink)
+	assert.Equal(t, 2, resp.Response.IPLimit)
+	require.NotNil(t, resp.Response.ExpireAt)
+	assert.Equal(t, "2030-01-01T00:00:00Z", *resp.Response.ExpireAt)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-user", map[string]string{"username": "bob"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-user", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandlerAddRemoveInbound(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	inbound := map[string]interface{}{
+		"tag":      "test-in",
+		"listen":   "127.0.0.1",
+		"port":     freePort(t),
+		"protocol": "vless",
+		"settings": map[string]interface{}{
+			"clients":    []interface{}{map[string]interface{}{"id": "550e8400-e29b-41d4-a716-446655440001", "email": "tester"}},
+			"decryption": "none",
+		},
+		"streamSettings": map[string]interface{}{"network": "tcp"},
+	}
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-inbound", inbound)
+	assert.Equal(t, http.StatusConflict, w.Code, "xray is not running")
+
+	startReq := CreateMinimalXrayConfig()
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", startReq)
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-inbound", inbound)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-inbound", inbound)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", inbound["port"]))
+	require.NoError(t, err, "the added inbound should be listening")
+	conn.Close()
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users-count", map[string]string{
+		"tag": "test-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	var countResponse struct {
+		Response struct {
+			Count int `json:"count"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &countResponse))
+	assert.Equal(t, 1, countResponse.Response.Count)
+
+	// The panel's hashes no longer match, so its next start restarts xray.
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", startReq)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/remove-inbound", map[string]string{
+		"tag": "test-in",
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/add-inbound", inbound)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/remove-inbound", map[string]string{
+		"tag": "test-in",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/remove-inbound", map[string]string{
+		"tag": "api",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
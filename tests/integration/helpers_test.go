@@ -147,6 +147,19 @@ func (tc *TestCredentials) GenerateJWT() (string, error) {
 	return token.SignedString(tc.JWTKey)
 }
 
+// GenerateScopedJWT creates a valid JWT token carrying a scope claim
+func (tc *TestCredentials) GenerateScopedJWT(scope string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":   "test-node",
+		"scope": scope,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(1 * time.Hour).Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	return token.SignedString(tc.JWTKey)
+}
+
 // GenerateExpiredJWT creates an expired JWT token
 func (tc *TestCredentials) GenerateExpiredJWT() (string, error) {
 	claims := jwt.MapClaims{
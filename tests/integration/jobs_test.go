@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/api"
+)
+
+type jobResponse struct {
+	Response struct {
+		ID     string          `json:"id"`
+		Type   string          `json:"type"`
+		Status string          `json:"status"`
+		Done   int             `json:"done"`
+		Total  int             `json:"total"`
+		Result json.RawMessage `json:"result"`
+		Error  *string         `json:"error"`
+	} `json:"response"`
+}
+
+func submitAsync(t *testing.T, server *api.Server, creds *TestCredentials, path string, body interface{}) string {
+	t.Helper()
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", path+"?async=true", body)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	var accepted struct {
+		Response struct {
+			JobID string `json:"jobId"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accepted))
+	require.NotEmpty(t, accepted.Response.JobID)
+	return accepted.Response.JobID
+}
+
+func waitForJob(t *testing.T, server *api.Server, creds *TestCredentials, id string) jobResponse {
+	t.Helper()
+
+	var job jobResponse
+	require.Eventually(t, func() bool {
+		w := makeAuthorizedRequest(t, server, creds, "GET", "/node/jobs/"+id, nil)
+		job = jobResponse{}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &job) != nil {
+			return false
+		}
+		return job.Response.Status == "completed" || job.Response.Status == "failed"
+	}, 10*time.Second, 20*time.Millisecond)
+	return job
+}
+
+func TestJobsAsyncStartAndBulkAdd(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	id := submitAsync(t, server, creds, "/node/xray/start", CreateMinimalXrayConfig())
+	job := waitForJob(t, server, creds, id)
+	assert.Equal(t, "completed", job.Response.Status)
+	assert.Equal(t, "start", job.Response.Type)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	var started struct {
+		IsStarted bool `json:"isStarted"`
+	}
+	require.NoError(t, json.Unmarshal(job.Response.Result, &started))
+	assert.True(t, started.IsStarted)
+
+	const count = 20
+	users := make([]map[string]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		users = append(users, map[string]interface{}{
+			"userData":    map[string]interface{}{"userId": fmt.Sprintf("user-%02d", i), "vlessUuid": fmt.Sprintf("550e8400-e29b-41d4-a716-4466554400%02d", i)},
+			"inboundData": []map[string]interface{}{{"tag": "vless-in", "type": "vless"}},
+		})
+	}
+
+	id = submitAsync(t, server, creds, "/node/handler/add-users", map[string]interface{}{"users": users})
+	job = waitForJob(t, server, creds, id)
+	assert.Equal(t, "completed", job.Response.Status)
+	assert.Equal(t, count, job.Response.Done)
+	assert.Equal(t, count, job.Response.Total)
+
+	var result struct {
+		Success bool              `json:"success"`
+		Results []json.RawMessage `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(job.Response.Result, &result))
+	assert.True(t, result.Success)
+	assert.Len(t, result.Results, count)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/handler/get-inbound-users-count", map[string]string{
+		"tag": "vless-in",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`"count":%d`, count))
+}
+
+func TestJobsFailedJobKeepsResult(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	id := submitAsync(t, server, creds, "/node/handler/remove-users", map[string]interface{}{
+		"users": []map[string]string{{"userId": "alice"}},
+	})
+	job := waitForJob(t, server, creds, id)
+	assert.Equal(t, "failed", job.Response.Status)
+	require.NotNil(t, job.Response.Error)
+	assert.Contains(t, *job.Response.Error, "xray core not available")
+}
+
+func TestJobsUnknownJob(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/jobs/missing", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,146 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+type logLine struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Path    string `json:"path"`
+}
+
+type logsResponse struct {
+	Response struct {
+		Entries []logLine `json:"entries"`
+	} `json:"response"`
+}
+
+func withLogBuffer(cfg *config.Config) {
+	cfg.LogLevel = "info"
+	cfg.LogBufferSize = 100
+}
+
+func TestGetLogs(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, withLogBuffer)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/jobs/missing", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/logs?level=warn", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp logsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Response.Entries, 1)
+	assert.Equal(t, "warn", resp.Response.Entries[0].Level)
+	assert.Equal(t, "/node/jobs/missing", resp.Response.Entries[0].Path)
+
+	// The previous request logged itself at info.
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/logs?lines=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = logsResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Response.Entries, 1)
+	assert.Equal(t, "/node/logs", resp.Response.Entries[0].Path)
+
+	since := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	for path, entries := range map[string]int{
+		"/node/logs?source=xray":    0,
+		"/node/logs?since=" + since: 0,
+	} {
+		w = makeAuthorizedRequest(t, server, creds, "GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code, path)
+		resp = logsResponse{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Response.Entries, entries, path)
+	}
+
+	for _, path := range []string{"/node/logs?lines=-1", "/node/logs?level=trace", "/node/logs?source=other", "/node/logs?since=yesterday"} {
+		w = makeAuthorizedRequest(t, server, creds, "GET", path, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+}
+
+func TestGetLogsWithoutBuffer(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/logs", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/logs/stream", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestStreamLogs(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, withLogBuffer)
+
+	ts := httptest.NewServer(server.MainRouter())
+	t.Cleanup(ts.Close)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/jobs/before", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	req, err := http.NewRequest("GET", ts.URL+"/node/logs/stream?level=warn&lines=10", nil)
+	require.NoError(t, err)
+	jwt, err := creds.GenerateJWT()
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	received := make(chan logLine, 16)
+	go func() {
+		defer close(received)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var line logLine
+			if err := json.Unmarshal([]byte(data), &line); err == nil {
+				received <- line
+			}
+		}
+	}()
+	next := func() logLine {
+		t.Helper()
+		select {
+		case line, ok := <-received:
+			require.True(t, ok, "stream closed")
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for log line")
+			return logLine{}
+		}
+	}
+
+	assert.Equal(t, "/node/jobs/before", next().Path)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/jobs/after", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "/node/jobs/after", next().Path)
+}
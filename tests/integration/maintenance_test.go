@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+// withClosedMaintenanceWindow configures a window that never opens, on
+// February 31st.
+func withClosedMaintenanceWindow(cfg *config.Config) {
+	cfg.MaintenanceWindow = "0 0 31 2 *"
+	cfg.MaintenanceWindowDuration = 3600
+}
+
+type maintenanceStatus struct {
+	Window  string `json:"window"`
+	Open    bool   `json:"open"`
+	Pending []struct {
+		Name string `json:"name"`
+	} `json:"pending"`
+}
+
+func TestMaintenanceWindowDefersRestart(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServerWithConfig(t, creds, withClosedMaintenanceWindow)
+
+	startReq := CreateMinimalXrayConfig()
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", startReq)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "restartDeferred", "a first start is not deferred")
+
+	changed := CreateMinimalXrayConfig()
+	changed.Internals.Hashes.EmptyConfig = "0fedcba987654321"
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", changed)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var startResp struct {
+		Response struct {
+			IsStarted       bool `json:"isStarted"`
+			RestartDeferred bool `json:"restartDeferred"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &startResp))
+	assert.True(t, startResp.Response.IsStarted)
+	assert.True(t, startResp.Response.RestartDeferred)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/status", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"emptyConfigHash":"a1b2c3d4e5f67890"`, "the running core keeps the previous config")
+
+	var status maintenanceStatus
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/maintenance", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "0 0 31 2 *", status.Window)
+	assert.False(t, status.Open)
+	require.Len(t, status.Pending, 1)
+	assert.Equal(t, "xray-restart", status.Pending[0].Name)
+
+	changed.Internals.ForceRestart = true
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", changed)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "restartDeferred", "forced restarts are not deferred")
+
+	w = makeLocalInternalRequest(t, server, "GET", "/internal/maintenance", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Empty(t, status.Pending)
+
+	makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+}
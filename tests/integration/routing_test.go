@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingListRulesWithoutXrayRunning(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var response struct {
+		Response struct {
+			Error *string `json:"error"`
+		} `json:"response"`
+	}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+	require.NotNil(t, response.Response.Error)
+	assert.Contains(t, *response.Response.Error, "failed to list routing rules")
+}
+
+func TestRoutingAddRuleInvalidIP(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/add-rule", map[string]string{
+		"ruleTag":     "test-rule",
+		"sourceIp":    "not-an-ip",
+		"outboundTag": "BLOCK",
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRoutingAddListRemoveRule(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/add-rule", map[string]string{
+		"ruleTag":     "test-rule",
+		"sourceIp":    "10.0.0.1",
+		"outboundTag": "BLOCK",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	type listResponse struct {
+		Response struct {
+			Rules []struct {
+				RuleTag     string `json:"ruleTag"`
+				OutboundTag string `json:"outboundTag"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var listed listResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+
+	found := false
+	for _, rule := range listed.Response.Rules {
+		if rule.RuleTag == "test-rule" {
+			found = true
+			assert.Equal(t, "BLOCK", rule.OutboundTag)
+		}
+	}
+	assert.True(t, found, "added rule should be listed")
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/remove-rule", map[string]string{
+		"ruleTag": "test-rule",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	listed = listResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	for _, rule := range listed.Response.Rules {
+		assert.NotEqual(t, "test-rule", rule.RuleTag)
+	}
+}
+
+func TestRoutingNodeRulesPersistAcrossRestart(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	// Added while xray is down, the rule is kept and applied on start.
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/add-rule", map[string]interface{}{
+		"ruleTag":     "office",
+		"sourceIp":    "10.1.2.3/24",
+		"outboundTag": "direct",
+		"ttlSeconds":  3600,
+		"creator":     "admin",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	type nodeRulesResponse struct {
+		Response struct {
+			Rules []struct {
+				RuleTag     string  `json:"ruleTag"`
+				SourceIP    string  `json:"sourceIp"`
+				OutboundTag string  `json:"outboundTag"`
+				Creator     string  `json:"creator"`
+				ExpiresAt   *string `json:"expiresAt"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var nodeRules nodeRulesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nodeRules))
+	require.Len(t, nodeRules.Response.Rules, 1)
+	assert.Equal(t, "office", nodeRules.Response.Rules[0].RuleTag)
+	assert.Equal(t, "10.1.2.0/24", nodeRules.Response.Rules[0].SourceIP)
+	assert.Equal(t, "direct", nodeRules.Response.Rules[0].OutboundTag)
+	assert.Equal(t, "admin", nodeRules.Response.Rules[0].Creator)
+	assert.NotNil(t, nodeRules.Response.Rules[0].ExpiresAt)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	var listed struct {
+		Response struct {
+			Rules []struct {
+				RuleTag string `json:"ruleTag"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	tags := make([]string, 0, len(listed.Response.Rules))
+	for _, rule := range listed.Response.Rules {
+		tags = append(tags, rule.RuleTag)
+	}
+	assert.Contains(t, tags, "office")
+
+	w = makeAuthorizedRequest(t, server, creds, "DELETE", "/node/routing/rules/office", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "DELETE", "/node/routing/rules/office", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	nodeRules = nodeRulesResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nodeRules))
+	assert.Empty(t, nodeRules.Response.Rules)
+}
+
+func TestRoutingUserOutbounds(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/set-user-outbound", map[string]string{
+		"username":    "alice",
+		"outboundTag": "missing",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/set-user-outbound", map[string]string{
+		"username":    "alice",
+		"outboundTag": "BLOCK",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var users struct {
+		Response struct {
+			Users []struct {
+				Username    string `json:"username"`
+				OutboundTag string `json:"outboundTag"`
+			} `json:"users"`
+		} `json:"response"`
+	}
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/user-outbounds", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &users))
+	require.Len(t, users.Response.Users, 1)
+	assert.Equal(t, "alice", users.Response.Users[0].Username)
+	assert.Equal(t, "BLOCK", users.Response.Users[0].OutboundTag)
+
+	var listed struct {
+		Response struct {
+			Rules []struct {
+				RuleTag     string `json:"ruleTag"`
+				OutboundTag string `json:"outboundTag"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	found := false
+	for _, rule := range listed.Response.Rules {
+		if rule.RuleTag == "user-outbound:BLOCK" {
+			found = true
+			assert.Equal(t, "BLOCK", rule.OutboundTag)
+		}
+	}
+	assert.True(t, found, "user routing rule should be installed")
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/remove-user-outbound", map[string]string{
+		"username": "alice",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/routing/remove-user-outbound", map[string]string{
+		"username": "alice",
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,186 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/remnawave/node-go/internal/config"
+)
+
+func TestVisionBlockIPReappliedAfterStart(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "POST", "/vision/block-ip", map[string]string{
+		"ip": "192.0.2.10",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeAuthorizedRequest(t, server, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, server, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, server, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Rules []struct {
+				RuleTag     string `json:"ruleTag"`
+				OutboundTag string `json:"outboundTag"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	blockRules := 0
+	for _, rule := range response.Response.Rules {
+		if rule.OutboundTag == "BLOCK" {
+			blockRules++
+		}
+	}
+	assert.Equal(t, 1, blockRules)
+
+	w = makeLocalInternalRequest(t, server, "POST", "/vision/unblock-ip", map[string]string{
+		"ip": "192.0.2.10",
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVisionBlockIPInvalidAddress(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "POST", "/vision/block-ip", map[string]string{
+		"ip": "not-an-ip",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestVisionBlockedIPsPersistAcrossRestart(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	stateDir := t.TempDir()
+	withStateDir := func(cfg *config.Config) {
+		cfg.StateDir = stateDir
+	}
+
+	server := setupTestServerWithConfig(t, creds, withStateDir)
+
+	w := makeLocalInternalRequest(t, server, "POST", "/vision/block-ip", map[string]string{
+		"ip": "198.51.100.7",
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	restarted := setupTestServerWithConfig(t, creds, withStateDir)
+
+	w = makeAuthorizedRequest(t, restarted, creds, "POST", "/node/xray/start", CreateMinimalXrayConfig())
+	require.Equal(t, http.StatusOK, w.Code)
+	defer makeAuthorizedRequest(t, restarted, creds, "GET", "/node/xray/stop", nil)
+
+	w = makeAuthorizedRequest(t, restarted, creds, "GET", "/node/routing/list-rules", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Rules []struct {
+				OutboundTag string `json:"outboundTag"`
+			} `json:"rules"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	blockRules := 0
+	for _, rule := range response.Response.Rules {
+		if rule.OutboundTag == "BLOCK" {
+			blockRules++
+		}
+	}
+	assert.Equal(t, 1, blockRules)
+}
+
+func TestVisionListBlockedIPs(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	for _, ip := range []string{"10.0.0.3", "10.0.0.1", "10.0.0.2", "192.0.2.1"} {
+		w := makeLocalInternalRequest(t, server, "POST", "/vision/block-ip", map[string]string{"ip": ip})
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	type listResponse struct {
+		Response struct {
+			Total int      `json:"total"`
+			Count int      `json:"count"`
+			IPs   []string `json:"ips"`
+		} `json:"response"`
+	}
+
+	w := makeLocalInternalRequest(t, server, "GET", "/vision/blocked-ips", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var all listResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+	assert.Equal(t, 4, all.Response.Total)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "192.0.2.1"}, all.Response.IPs)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/vision/blocked-ips?prefix=10.&limit=2&offset=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var page listResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Equal(t, 3, page.Response.Total)
+	assert.Equal(t, 2, page.Response.Count)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3"}, page.Response.IPs)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/vision/blocked-ips?limit=9223372036854775807&offset=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var rest listResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rest))
+	assert.Equal(t, 4, rest.Response.Total)
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.3", "192.0.2.1"}, rest.Response.IPs)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/vision/blocked-ips?limit=0&offset=-1", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestVisionBlockCIDRWithTTL(t *testing.T) {
+	creds, err := GenerateTestCredentials()
+	require.NoError(t, err)
+
+	server := setupTestServer(t, creds)
+
+	w := makeLocalInternalRequest(t, server, "POST", "/vision/block-ip", map[string]interface{}{
+		"ip":         "10.20.30.40/24",
+		"ttlSeconds": 3600,
+	})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = makeLocalInternalRequest(t, server, "GET", "/vision/blocked-ips", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Response struct {
+			Entries []struct {
+				IP        string  `json:"ip"`
+				ExpiresAt *string `json:"expiresAt"`
+			} `json:"entries"`
+		} `json:"response"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Response.Entries, 1)
+	assert.Equal(t, "10.20.30.0/24", response.Response.Entries[0].IP)
+	assert.NotNil(t, response.Response.Entries[0].ExpiresAt)
+}